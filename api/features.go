@@ -143,6 +143,147 @@ const (
 	// Note: The instruction list is too long to enumerate in godoc.
 	// See https://github.com/WebAssembly/spec/blob/wg-2.0.draft1/proposals/simd/SIMD.md
 	CoreFeatureSIMD
+
+	// CoreFeatureThreads enables shared memories and atomic instructions
+	// ("threads"). This is not included in CoreFeaturesV2 as the proposal is
+	// not yet part of the WebAssembly Core Specification.
+	//
+	// Here are the notable effects:
+	//   - Memories can be declared "shared", which requires a maximum size.
+	//   - Adds atomic loads, stores and read-modify-write instructions, as well
+	//     as `memory.atomic.wait32`, `memory.atomic.wait64`,
+	//     `memory.atomic.notify` and `atomic.fence`.
+	//
+	// Note: This doesn't spawn threads by itself. Threads are created by the
+	// host, for example via "wasi-threads".
+	//
+	// See https://github.com/WebAssembly/threads/blob/main/proposals/threads/Overview.md
+	CoreFeatureThreads
+
+	// CoreFeatureTailCall enables tail calls ("tail-call"). This is not
+	// included in CoreFeaturesV2 as the proposal is not part of the
+	// WebAssembly Core Specification 2.0.
+	//
+	// Adds instructions, which return the results of the call from the
+	// calling function, reusing its frame:
+	//   - `return_call`
+	//   - `return_call_indirect`
+	//
+	// See https://github.com/WebAssembly/tail-call/blob/main/proposals/tail-call/Overview.md
+	CoreFeatureTailCall
+
+	// CoreFeatureMultiMemory enables multiple memories per module
+	// ("multi-memory"). This is not included in CoreFeaturesV2 as the
+	// proposal is not part of the WebAssembly Core Specification 2.0.
+	//
+	// Here are the notable effects:
+	//   - A module can import and define more than one memory, and export
+	//     each of them.
+	//   - Load, store and atomic instructions encode a memory index in their
+	//     memory argument when bit 6 of its alignment is set.
+	//   - `memory.size`, `memory.grow`, `memory.fill`, `memory.init` and
+	//     `memory.copy` take memory indexes instead of reserved zero bytes,
+	//     and `memory.copy` can copy between two memories.
+	//   - Active data segments can target a memory other than zero.
+	//
+	// See https://github.com/WebAssembly/multi-memory/blob/main/proposals/multi-memory/Overview.md
+	CoreFeatureMultiMemory
+
+	// CoreFeatureMemory64 enables 64-bit memories ("memory64"). This is not
+	// included in CoreFeaturesV2 as the proposal is not part of the
+	// WebAssembly Core Specification 2.0.
+	//
+	// Here are the notable effects:
+	//   - Memories can be declared with an i64 address type, and their limits
+	//     are encoded as 64-bit values.
+	//   - Load, store and atomic instructions on such memories take an i64
+	//     address, and their memory argument encodes a 64-bit offset.
+	//   - `memory.size`, `memory.grow`, `memory.fill`, `memory.init` and
+	//     `memory.copy` use i64 for addresses and page counts of such memories.
+	//   - Active data segments targeting such memories use an i64 offset.
+	//
+	// Note: The maximum size of a 64-bit memory is still bounded by
+	// RuntimeConfig.WithMemoryLimitPages, and api.Memory has 64-bit variants of
+	// its accessors, such as Read64, to access data beyond 4GiB.
+	//
+	// See https://github.com/WebAssembly/memory64/blob/main/proposals/memory64/Overview.md
+	CoreFeatureMemory64
+
+	// CoreFeatureExceptionHandling enables tags and exception handling
+	// instructions ("exception-handling"). This is not included in
+	// CoreFeaturesV2 as the proposal is not part of the WebAssembly Core
+	// Specification 2.0.
+	//
+	// Here are the notable effects:
+	//   - Adds the tag section, and tags can be imported and exported.
+	//   - Adds `throw`, which throws an exception with the values of a tag,
+	//     and `rethrow`, which throws a caught exception again.
+	//   - Adds `try` blocks, whose `catch` and `catch_all` clauses handle
+	//     exceptions thrown in the block, and `delegate`, which lets an outer
+	//     block handle them instead.
+	//
+	// Note: This implements the instructions emitted by current toolchains,
+	// such as Emscripten with "-fwasm-exceptions", which are called "legacy"
+	// in the proposal. `try_table` and `exnref` are not supported yet.
+	// Exceptions which are not caught by the guest are returned as
+	// *api.Exception errors, and host functions can panic with one to throw.
+	//
+	// See https://github.com/WebAssembly/exception-handling/blob/main/proposals/exception-handling/legacy/Exceptions.md
+	CoreFeatureExceptionHandling
+
+	// CoreFeatureExtendedConst enables arithmetic in constant expressions
+	// ("extended-const"). This is not included in CoreFeaturesV2 as the
+	// proposal is not part of the WebAssembly Core Specification 2.0.
+	//
+	// Constant expressions, such as the initial values of globals and the
+	// offsets of segments, can consist of multiple instructions, and use
+	// `i32.add`, `i32.sub`, `i32.mul`, `i64.add`, `i64.sub` and `i64.mul` in
+	// addition to constants and `global.get`. For example, this is used by
+	// position independent code and dynamic linking in LLVM.
+	//
+	// See https://github.com/WebAssembly/extended-const/blob/main/proposals/extended-const/Overview.md
+	CoreFeatureExtendedConst
+
+	// CoreFeatureFunctionReferences enables typed function references
+	// ("function-references"). This is not included in CoreFeaturesV2 as the
+	// proposal is not part of the WebAssembly Core Specification 2.0.
+	//
+	// Here are the notable effects:
+	//   - Value types include references typed with a function type or an
+	//     abstract heap type, which can be non-nullable, such as
+	//     `(ref null $t)` or `(ref func)`.
+	//   - Adds `call_ref`, which calls the function reference on the stack.
+	//   - Adds `ref.as_non_null`, which traps on a null reference, as well as
+	//     `br_on_null` and `br_on_non_null`, which branch on it.
+	//
+	// Note: Typed references are validated as funcref or externref, without
+	// their function type and nullability. Instead, `call_ref` checks the
+	// type of the function at runtime like `call_indirect` does.
+	// `return_call_ref` is not supported yet.
+	//
+	// See https://github.com/WebAssembly/function-references/blob/main/proposals/function-references/Overview.md
+	CoreFeatureFunctionReferences
+
+	// CoreFeatureRelaxedSIMD enables relaxed vector instructions
+	// ("relaxed-simd"). This is not included in CoreFeaturesV2 as the proposal
+	// is not part of the WebAssembly Core Specification 2.0, and requires
+	// CoreFeatureSIMD.
+	//
+	// Here are the notable effects:
+	//   - Adds `i8x16.relaxed_swizzle`, `relaxed_trunc` conversions,
+	//     `relaxed_madd` and `relaxed_nmadd`, `relaxed_laneselect`,
+	//     `relaxed_min` and `relaxed_max`, `i16x8.relaxed_q15mulr_s` and
+	//     `relaxed_dot` products.
+	//
+	// Note: The proposal allows these instructions to return results which
+	// depend on the host CPU. wazero returns the same result regardless of
+	// the engine or the platform: `relaxed_madd` and `relaxed_nmadd` are not
+	// fused, and the others behave like their non-relaxed counterparts, e.g.
+	// `i8x16.relaxed_swizzle` like `i8x16.swizzle`. The dot products treat
+	// both operands as signed.
+	//
+	// See https://github.com/WebAssembly/relaxed-simd/blob/main/proposals/relaxed-simd/Overview.md
+	CoreFeatureRelaxedSIMD
 )
 
 // SetEnabled enables or disables the feature or group of features.
@@ -207,6 +348,30 @@ func featureName(f CoreFeatures) string {
 	case CoreFeatureSIMD:
 		// match https://github.com/WebAssembly/spec/blob/wg-2.0.draft1/proposals/simd/SIMD.md
 		return "simd"
+	case CoreFeatureThreads:
+		// match https://github.com/WebAssembly/threads/blob/main/proposals/threads/Overview.md
+		return "threads"
+	case CoreFeatureTailCall:
+		// match https://github.com/WebAssembly/tail-call/blob/main/proposals/tail-call/Overview.md
+		return "tail-call"
+	case CoreFeatureMultiMemory:
+		// match https://github.com/WebAssembly/multi-memory/blob/main/proposals/multi-memory/Overview.md
+		return "multi-memory"
+	case CoreFeatureMemory64:
+		// match https://github.com/WebAssembly/memory64/blob/main/proposals/memory64/Overview.md
+		return "memory64"
+	case CoreFeatureExceptionHandling:
+		// match https://github.com/WebAssembly/exception-handling/blob/main/proposals/exception-handling/legacy/Exceptions.md
+		return "exception-handling"
+	case CoreFeatureExtendedConst:
+		// match https://github.com/WebAssembly/extended-const/blob/main/proposals/extended-const/Overview.md
+		return "extended-const"
+	case CoreFeatureFunctionReferences:
+		// match https://github.com/WebAssembly/function-references/blob/main/proposals/function-references/Overview.md
+		return "function-references"
+	case CoreFeatureRelaxedSIMD:
+		// match https://github.com/WebAssembly/relaxed-simd/blob/main/proposals/relaxed-simd/Overview.md
+		return "relaxed-simd"
 	}
 	return ""
 }
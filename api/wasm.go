@@ -19,6 +19,8 @@ const (
 	ExternTypeTable  ExternType = 0x01
 	ExternTypeMemory ExternType = 0x02
 	ExternTypeGlobal ExternType = 0x03
+	// ExternTypeTag is a tag, introduced in CoreFeatureExceptionHandling.
+	ExternTypeTag ExternType = 0x04
 )
 
 // The below are exported to consolidate parsing behavior for external types.
@@ -31,6 +33,8 @@ const (
 	ExternTypeMemoryName = "memory"
 	// ExternTypeGlobalName is the name of the WebAssembly 1.0 (20191205) Text Format field for ExternTypeGlobal.
 	ExternTypeGlobalName = "global"
+	// ExternTypeTagName is the name of the Text Format field for ExternTypeTag.
+	ExternTypeTagName = "tag"
 )
 
 // ExternTypeName returns the name of the WebAssembly 1.0 (20191205) Text Format field of the given type.
@@ -46,6 +50,8 @@ func ExternTypeName(et ExternType) string {
 		return ExternTypeMemoryName
 	case ExternTypeGlobal:
 		return ExternTypeGlobalName
+	case ExternTypeTag:
+		return ExternTypeTagName
 	}
 	return fmt.Sprintf("%#x", et)
 }
@@ -129,6 +135,47 @@ func ValueTypeName(t ValueType) string {
 	return "unknown"
 }
 
+// RefType describes the type of the elements in a Table.
+//
+// The following describes how to convert between Wasm and Golang types:
+//
+//   - RefTypeFuncref - an opaque reference to a function, read from a
+//     Table or passed by the guest. Zero is the null reference.
+//   - RefTypeExternref - EncodeExternref DecodeExternref for uintptr
+//
+// Note: This is a type alias as it is easier to encode and decode in the
+// binary format.
+//
+// See https://www.w3.org/TR/2022/WD-wasm-core-2-20220419/syntax/types.html#reference-types
+type RefType = byte
+
+const (
+	// RefTypeFuncref is a reference to a function.
+	//
+	// Note: in wazero, funcref values are opaque and only meaningful to the
+	// Runtime that produced them. They can be copied between tables of the
+	// same Runtime, but must not be fabricated by the host.
+	RefTypeFuncref RefType = 0x70
+	// RefTypeExternref is a reference to a host object.
+	//
+	// Note: This is the same as ValueTypeExternref.
+	RefTypeExternref RefType = ValueTypeExternref
+)
+
+// RefTypeName returns the type name of the given RefType as a string.
+// These type names match the names used in the WebAssembly text format.
+//
+// Note: This returns "unknown", if an undefined RefType value is passed.
+func RefTypeName(t RefType) string {
+	switch t {
+	case RefTypeFuncref:
+		return "funcref"
+	case RefTypeExternref:
+		return "externref"
+	}
+	return "unknown"
+}
+
 // Module is a sandboxed, ready to execute Wasm module. This can be used to get exported functions, etc.
 //
 // In WebAssembly terminology, this corresponds to a "Module Instance", but wazero calls pre-instantiation module as
@@ -147,6 +194,8 @@ type Module interface {
 	Name() string
 
 	// Memory returns a memory defined in this module or nil if there are none wasn't.
+	//
+	// When CoreFeatureMultiMemory is enabled, this is the memory at index zero. Use ExportedMemory to access others.
 	Memory() Memory
 
 	// ExportedFunction returns a function exported from this module or nil if it wasn't.
@@ -160,7 +209,12 @@ type Module interface {
 	// definitions in this module, keyed on export name.
 	ExportedFunctionDefinitions() map[string]FunctionDefinition
 
-	// TODO: Table
+	// ExportedTable returns a table exported from this module or nil if it wasn't.
+	ExportedTable(name string) Table
+
+	// ExportedTableDefinitions returns all the exported table definitions
+	// in this module, keyed on export name.
+	ExportedTableDefinitions() map[string]TableDefinition
 
 	// ExportedMemory returns a memory exported from this module or nil if it wasn't.
 	//
@@ -180,6 +234,11 @@ type Module interface {
 	// ExportedGlobal a global exported from this module or nil if it wasn't.
 	ExportedGlobal(name string) Global
 
+	// ExportedTag returns a tag exported from this module or nil if it wasn't.
+	//
+	// Note: Tags are introduced in CoreFeatureExceptionHandling.
+	ExportedTag(name string) Tag
+
 	// CloseWithExitCode releases resources allocated for this Module. Use a non-zero exitCode parameter to indicate a
 	// failure to ExportedFunction callers.
 	//
@@ -285,6 +344,31 @@ type MemoryDefinition interface {
 	internalapi.WazeroOnly
 }
 
+// TableDefinition is a WebAssembly table exported in a module
+// (wazero.CompiledModule). Units are in elements.
+//
+// See https://www.w3.org/TR/2022/WD-wasm-core-2-20220419/syntax/modules.html#tables
+//
+// # Notes
+//
+//   - This is an interface for decoupling, not third-party implementations.
+//     All implementations are in wazero.
+type TableDefinition interface {
+	ExportDefinition
+
+	// Type returns the type of elements in this table.
+	Type() RefType
+
+	// Min returns the possibly zero initial count of elements.
+	Min() uint32
+
+	// Max returns the possibly zero max count of elements, or false if
+	// unbounded.
+	Max() (uint32, bool)
+
+	internalapi.WazeroOnly
+}
+
 // FunctionDefinition is a WebAssembly function exported in a module
 // (wazero.CompiledModule).
 //
@@ -669,9 +753,180 @@ type Memory interface {
 	// WriteString writes the string to the underlying buffer at the offset or returns false if out of range.
 	WriteString(offset uint32, v string) bool
 
+	// Size64 is like Size, except it doesn't truncate sizes of 4GiB or larger,
+	// which are possible when CoreFeatureMemory64 is enabled.
+	//
+	// The methods below are like the ones of the same name without the "64"
+	// suffix, except offsets are 64-bit to address memories larger than 4GiB.
+	Size64() uint64
+
+	// ReadByte64 is like ReadByte, except the offset is 64-bit.
+	ReadByte64(offset uint64) (byte, bool)
+
+	// ReadUint16Le64 is like ReadUint16Le, except the offset is 64-bit.
+	ReadUint16Le64(offset uint64) (uint16, bool)
+
+	// ReadUint32Le64 is like ReadUint32Le, except the offset is 64-bit.
+	ReadUint32Le64(offset uint64) (uint32, bool)
+
+	// ReadFloat32Le64 is like ReadFloat32Le, except the offset is 64-bit.
+	ReadFloat32Le64(offset uint64) (float32, bool)
+
+	// ReadUint64Le64 is like ReadUint64Le, except the offset is 64-bit.
+	ReadUint64Le64(offset uint64) (uint64, bool)
+
+	// ReadFloat64Le64 is like ReadFloat64Le, except the offset is 64-bit.
+	ReadFloat64Le64(offset uint64) (float64, bool)
+
+	// Read64 is like Read, except the offset and byteCount are 64-bit. The
+	// returned slice is a write-through view in the same way.
+	Read64(offset, byteCount uint64) ([]byte, bool)
+
+	// WriteByte64 is like WriteByte, except the offset is 64-bit.
+	WriteByte64(offset uint64, v byte) bool
+
+	// WriteUint16Le64 is like WriteUint16Le, except the offset is 64-bit.
+	WriteUint16Le64(offset uint64, v uint16) bool
+
+	// WriteUint32Le64 is like WriteUint32Le, except the offset is 64-bit.
+	WriteUint32Le64(offset uint64, v uint32) bool
+
+	// WriteFloat32Le64 is like WriteFloat32Le, except the offset is 64-bit.
+	WriteFloat32Le64(offset uint64, v float32) bool
+
+	// WriteUint64Le64 is like WriteUint64Le, except the offset is 64-bit.
+	WriteUint64Le64(offset uint64, v uint64) bool
+
+	// WriteFloat64Le64 is like WriteFloat64Le, except the offset is 64-bit.
+	WriteFloat64Le64(offset uint64, v float64) bool
+
+	// Write64 is like Write, except the offset is 64-bit.
+	Write64(offset uint64, v []byte) bool
+
+	// WriteString64 is like WriteString, except the offset is 64-bit.
+	WriteString64(offset uint64, v string) bool
+
 	internalapi.WazeroOnly
 }
 
+// Table allows restricted access to a module's table.
+//
+// Elements are opaque 64-bit references whose interpretation depends on
+// TableDefinition.Type. See RefType for how to convert them to Go types.
+//
+// For example, to swap two entries of a funcref table used by
+// "call_indirect":
+//
+//	table := module.ExportedTable("__indirect_function_table")
+//	a, _ := table.Get(1)
+//	b, _ := table.Get(2)
+//	table.Set(1, b)
+//	table.Set(2, a)
+//
+// See https://www.w3.org/TR/2022/WD-wasm-core-2-20220419/exec/runtime.html#table-instances
+//
+// # Notes
+//
+//   - This is an interface for decoupling, not third-party implementations.
+//     All implementations are in wazero.
+type Table interface {
+	// Definition is metadata about this table from its defining module.
+	Definition() TableDefinition
+
+	// Size returns the current count of elements in this table.
+	//
+	// See https://www.w3.org/TR/2022/WD-wasm-core-2-20220419/exec/instructions.html#xref-syntax-instructions-syntax-instr-table-mathsf-table-size-x
+	Size() uint32
+
+	// Grow increases the table by the delta in elements, initializing each
+	// new element to initialRef. The return val is the previous size in
+	// elements, or false if the delta was ignored as it exceeds
+	// TableDefinition.Max, or if initialRef is invalid like in Set.
+	//
+	// Note: This is the same as the "table.grow" instruction defined in the
+	// WebAssembly Core Specification, except returns false instead of -1.
+	Grow(delta uint32, initialRef uint64) (previousSize uint32, ok bool)
+
+	// Get returns the reference at the offset or false if out of range.
+	Get(offset uint32) (ref uint64, ok bool)
+
+	// Set writes the reference at the offset or returns false if out of
+	// range.
+	//
+	// For RefTypeFuncref tables, this also returns false unless ref is zero
+	// or a funcref of a module instantiated in the same Runtime, such as one
+	// read via Get.
+	Set(offset uint32, ref uint64) bool
+
+	// Function returns the function referenced at the offset of a
+	// RefTypeFuncref table, so that the host can call it. This fails if the
+	// offset is out of range, the element is null, or the signature of the
+	// function isn't params and results.
+	//
+	// For example, a host function can call a callback the guest registered
+	// as an index into its "call_indirect" table:
+	//
+	//	fn, err := table.Function(callbackIdx, []api.ValueType{api.ValueTypeI32}, nil)
+	//	if err != nil {
+	//		return err
+	//	}
+	//	_, err = fn.Call(ctx, api.EncodeU32(arg))
+	Function(offset uint32, params, results []ValueType) (Function, error)
+
+	internalapi.WazeroOnly
+}
+
+// Tag identifies the exceptions thrown with it, and declares the types of
+// the values they carry. Tags are introduced in CoreFeatureExceptionHandling.
+//
+// Two tags are the same if and only if they are the same instance, e.g. a tag
+// exported by one module and imported by another.
+//
+// See https://github.com/WebAssembly/exception-handling/blob/main/proposals/exception-handling/legacy/Exceptions.md
+//
+// # Notes
+//
+//   - This is an interface for decoupling, not third-party implementations.
+//     All implementations are in wazero.
+type Tag interface {
+	// ParamTypes are the types of Exception.Values of exceptions thrown with
+	// this tag.
+	ParamTypes() []ValueType
+
+	internalapi.WazeroOnly
+}
+
+// Exception is a WebAssembly exception, introduced in
+// CoreFeatureExceptionHandling.
+//
+// An exception thrown by a guest, which isn't caught by it, is returned as the
+// error of Function.Call. Use errors.As to retrieve it:
+//
+//	var exc *api.Exception
+//	if _, err := fn.Call(ctx); errors.As(err, &exc) && exc.Tag == cppTag {
+//		ptr := api.DecodeU32(exc.Values[0])
+//		// handle the C++ exception at ptr
+//	}
+//
+// A host function throws an exception by panicking with one, which the guest
+// can catch. For example:
+//
+//	panic(&api.Exception{Tag: mod.ExportedTag("my_tag"), Values: []uint64{42}})
+type Exception struct {
+	// Tag is the tag of the exception, which guests match in `catch` clauses.
+	Tag Tag
+
+	// Values are the values carried by the exception, encoded as the
+	// parameters of a function with Tag.ParamTypes, e.g. two elements for a
+	// ValueTypeV128.
+	Values []uint64
+}
+
+// Error implements error.
+func (e *Exception) Error() string {
+	return fmt.Sprintf("uncaught exception with values %v", e.Values)
+}
+
 // CustomSection contains the name and raw data of a custom section.
 //
 // # Notes
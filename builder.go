@@ -2,6 +2,7 @@ package wazero
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/tetratelabs/wazero/api"
 	"github.com/tetratelabs/wazero/internal/wasm"
@@ -185,6 +186,54 @@ type HostModuleBuilder interface {
 	// NewFunctionBuilder begins the definition of a host function.
 	NewFunctionBuilder() HostFunctionBuilder
 
+	// ExportMemory adds a linear memory, which a WebAssembly module can
+	// import, e.g. as "env" "memory". minPages and maxPages are in 64KB pages.
+	//
+	// Here's an example of a memory imported by an Emscripten module:
+	//
+	//	env, _ := r.NewHostModuleBuilder("env").
+	//		ExportMemory("memory", 256, 32768).
+	//		Instantiate(ctx)
+	//
+	// # Notes
+	//
+	//   - There can be at most one memory: calling this again replaces it.
+	//   - maxPages must not be less than minPages or exceed the limit set by
+	//     RuntimeConfig.WithMemoryLimitPages.
+	//   - Host functions still act on the memory of the calling module. When
+	//     the caller imported this memory, that is the same memory.
+	ExportMemory(name string, minPages, maxPages uint32) HostModuleBuilder
+
+	// ExportGlobal adds a global of the given type, which a WebAssembly module
+	// can import, e.g. as "env" "__stack_pointer".
+	//
+	// initial is encoded the same as api.Global Get. For example, a mutable
+	// global holding a stack pointer could be defined like this:
+	//
+	//	builder.ExportGlobal("__stack_pointer", api.ValueTypeI32, true, api.EncodeU32(65536))
+	//
+	// # Notes
+	//
+	//   - Mutable globals require api.CoreFeatureMutableGlobal.
+	//   - A global of api.ValueTypeExternref must be initialized to zero (null).
+	ExportGlobal(name string, valueType api.ValueType, mutable bool, initial uint64) HostModuleBuilder
+
+	// ExportTable adds a table of the given type, which a WebAssembly module
+	// can import, e.g. as "env" "__indirect_function_table". All elements are
+	// initialized to null.
+	//
+	// Here's an example of a table which can grow without bound:
+	//
+	//	builder.ExportTable("__indirect_function_table", api.RefTypeFuncref, 1, nil)
+	//
+	// # Notes
+	//
+	//   - min and max are in elements. max is nil when the table is unbounded,
+	//     and otherwise must not be less than min.
+	//   - More than one table, or a table of api.RefTypeExternref, requires
+	//     api.CoreFeatureReferenceTypes.
+	ExportTable(name string, refType api.RefType, min uint32, max *uint32) HostModuleBuilder
+
 	// Compile returns a CompiledModule that can be instantiated by Runtime.
 	Compile(context.Context) (CompiledModule, error)
 
@@ -218,6 +267,9 @@ type hostModuleBuilder struct {
 	moduleName     string
 	exportNames    []string
 	nameToHostFunc map[string]*wasm.HostFunc
+	memory         *wasm.HostMemory
+	globals        []*wasm.HostGlobal
+	tables         []*wasm.HostTable
 }
 
 // NewHostModuleBuilder implements Runtime.NewHostModuleBuilder
@@ -312,22 +364,73 @@ func (b *hostModuleBuilder) NewFunctionBuilder() HostFunctionBuilder {
 	return &hostFunctionBuilder{b: b}
 }
 
+// ExportMemory implements HostModuleBuilder.ExportMemory
+func (b *hostModuleBuilder) ExportMemory(name string, minPages, maxPages uint32) HostModuleBuilder {
+	capacity := minPages
+	if b.r.memoryCapacityFromMax {
+		capacity = maxPages
+	}
+	b.memory = &wasm.HostMemory{
+		ExportName: name,
+		Memory:     &wasm.Memory{Min: minPages, Cap: capacity, Max: maxPages, IsMaxEncoded: true},
+	}
+	return b
+}
+
+// ExportGlobal implements HostModuleBuilder.ExportGlobal
+func (b *hostModuleBuilder) ExportGlobal(name string, valueType api.ValueType, mutable bool, initial uint64) HostModuleBuilder {
+	b.globals = append(b.globals, &wasm.HostGlobal{
+		ExportName: name,
+		Type:       wasm.GlobalType{ValType: valueType, Mutable: mutable},
+		Init:       initial,
+	})
+	return b
+}
+
+// ExportTable implements HostModuleBuilder.ExportTable
+func (b *hostModuleBuilder) ExportTable(name string, refType api.RefType, min uint32, max *uint32) HostModuleBuilder {
+	table := wasm.Table{Min: min, Type: refType}
+	if max != nil {
+		m := *max // copy so the caller can't change the limit later.
+		table.Max = &m
+	}
+	b.tables = append(b.tables, &wasm.HostTable{ExportName: name, Table: table})
+	return b
+}
+
+// externs returns the non-function exports, or nil if there are none.
+func (b *hostModuleBuilder) externs() *wasm.HostExterns {
+	if b.memory == nil && len(b.globals) == 0 && len(b.tables) == 0 {
+		return nil
+	}
+	return &wasm.HostExterns{Memory: b.memory, Globals: b.globals, Tables: b.tables}
+}
+
 // Compile implements HostModuleBuilder.Compile
 func (b *hostModuleBuilder) Compile(ctx context.Context) (CompiledModule, error) {
-	module, err := wasm.NewHostModule(b.moduleName, b.exportNames, b.nameToHostFunc, b.r.enabledFeatures)
+	if mem := b.memory; mem != nil {
+		if err := mem.Memory.Validate(b.r.memoryLimitPages); err != nil {
+			return nil, fmt.Errorf("memory[%s.%s] %w", b.moduleName, mem.ExportName, err)
+		}
+	}
+
+	module, err := wasm.NewHostModule(b.moduleName, b.exportNames, b.nameToHostFunc, b.externs(), b.r.enabledFeatures)
 	if err != nil {
 		return nil, err
 	} else if err = module.Validate(b.r.enabledFeatures); err != nil {
 		return nil, err
 	}
 
+	// Now that the module is validated, cache the memory definitions.
+	module.BuildMemoryDefinitions()
+
 	c := &compiledModule{module: module, compiledEngine: b.r.store.Engine}
 	listeners, err := buildFunctionListeners(ctx, module)
 	if err != nil {
 		return nil, err
 	}
 
-	if err = b.r.store.Engine.CompileModule(ctx, module, listeners, false); err != nil {
+	if err = b.r.store.Engine.CompileModule(ctx, module, listeners, false, false, false); err != nil {
 		return nil, err
 	}
 
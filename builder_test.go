@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
 	"github.com/tetratelabs/wazero/internal/testing/require"
 	"github.com/tetratelabs/wazero/internal/wasm"
 )
@@ -12,6 +13,7 @@ import (
 // TestNewHostModuleBuilder_Compile only covers a few scenarios to avoid duplicating tests in internal/wasm/host_test.go
 func TestNewHostModuleBuilder_Compile(t *testing.T) {
 	i32, i64 := api.ValueTypeI32, api.ValueTypeI64
+	four := uint32(4)
 
 	uint32_uint32 := func(context.Context, uint32) uint32 {
 		return 0
@@ -302,6 +304,45 @@ func TestNewHostModuleBuilder_Compile(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "ExportMemory ExportGlobal ExportTable",
+			input: func(r Runtime) HostModuleBuilder {
+				return r.NewHostModuleBuilder("env").
+					NewFunctionBuilder().WithFunc(uint32_uint32).Export("1").
+					ExportMemory("memory", 1, 2).
+					ExportGlobal("__stack_pointer", i32, true, 1024).
+					ExportTable("__indirect_function_table", api.RefTypeFuncref, 3, &four)
+			},
+			expected: &wasm.Module{
+				TypeSection: []wasm.FunctionType{
+					{Params: []api.ValueType{i32}, Results: []api.ValueType{i32}},
+				},
+				FunctionSection: []wasm.Index{0},
+				CodeSection:     []wasm.Code{wasm.MustParseGoReflectFuncCode(uint32_uint32)},
+				MemorySection:   []wasm.Memory{{Min: 1, Cap: 1, Max: 2, IsMaxEncoded: true}},
+				GlobalSection: []wasm.Global{{
+					Type: wasm.GlobalType{ValType: i32, Mutable: true},
+					Init: wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: []byte{0x80, 0x08}},
+				}},
+				TableSection: []wasm.Table{{Min: 3, Max: &four, Type: wasm.RefTypeFuncref}},
+				ExportSection: []wasm.Export{
+					{Name: "1", Type: wasm.ExternTypeFunc, Index: 0},
+					{Name: "memory", Type: wasm.ExternTypeMemory, Index: 0},
+					{Name: "__stack_pointer", Type: wasm.ExternTypeGlobal, Index: 0},
+					{Name: "__indirect_function_table", Type: wasm.ExternTypeTable, Index: 0},
+				},
+				Exports: map[string]*wasm.Export{
+					"1":                         {Name: "1", Type: wasm.ExternTypeFunc, Index: 0},
+					"memory":                    {Name: "memory", Type: wasm.ExternTypeMemory, Index: 0},
+					"__stack_pointer":           {Name: "__stack_pointer", Type: wasm.ExternTypeGlobal, Index: 0},
+					"__indirect_function_table": {Name: "__indirect_function_table", Type: wasm.ExternTypeTable, Index: 0},
+				},
+				NameSection: &wasm.NameSection{
+					FunctionNames: wasm.NameMap{{Index: 0, Name: "1"}},
+					ModuleName:    "env",
+				},
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -336,6 +377,8 @@ func TestNewHostModuleBuilder_Compile(t *testing.T) {
 // TestNewHostModuleBuilder_Compile_Errors only covers a few scenarios to avoid
 // duplicating tests in internal/wasm/host_test.go
 func TestNewHostModuleBuilder_Compile_Errors(t *testing.T) {
+	one := uint32(1)
+
 	tests := []struct {
 		name        string
 		input       func(Runtime) HostModuleBuilder
@@ -350,6 +393,36 @@ func TestNewHostModuleBuilder_Compile_Errors(t *testing.T) {
 			},
 			expectedErr: `func[host.fn] param[0] is unsupported: string`,
 		},
+		{
+			name: "memory over limit",
+			input: func(rt Runtime) HostModuleBuilder {
+				return rt.NewHostModuleBuilder("host").ExportMemory("memory", 1, 70000)
+			},
+			expectedErr: `memory[host.memory] max 70000 pages (4 Gi) over limit of 65536 pages (4 Gi)`,
+		},
+		{
+			name: "duplicate export name",
+			input: func(rt Runtime) HostModuleBuilder {
+				return rt.NewHostModuleBuilder("host").
+					NewFunctionBuilder().WithFunc(func() {}).Export("fn").
+					ExportGlobal("fn", api.ValueTypeI32, false, 0)
+			},
+			expectedErr: `global[host.fn] duplicates export name "fn"`,
+		},
+		{
+			name: "externref global not null",
+			input: func(rt Runtime) HostModuleBuilder {
+				return rt.NewHostModuleBuilder("host").ExportGlobal("g", api.ValueTypeExternref, false, 1)
+			},
+			expectedErr: `global[host.g] externref must be initialized to null`,
+		},
+		{
+			name: "table min > max",
+			input: func(rt Runtime) HostModuleBuilder {
+				return rt.NewHostModuleBuilder("host").ExportTable("t", api.RefTypeFuncref, 2, &one)
+			},
+			expectedErr: `table[host.t] min 2 > max 1`,
+		},
 	}
 
 	for _, tt := range tests {
@@ -386,6 +459,90 @@ func TestNewHostModuleBuilder_Instantiate_Errors(t *testing.T) {
 	require.EqualError(t, err, "module[env] has already been instantiated")
 }
 
+// TestNewHostModuleBuilder_Exports ensures a guest can import a memory, global
+// and table defined by a host module.
+func TestNewHostModuleBuilder_Exports(t *testing.T) {
+	r := NewRuntime(testCtx)
+	defer r.Close(testCtx)
+
+	env, err := r.NewHostModuleBuilder("env").
+		ExportMemory("memory", 1, 1).
+		ExportGlobal("__stack_pointer", api.ValueTypeI32, true, api.EncodeU32(16)).
+		ExportTable("__indirect_function_table", api.RefTypeFuncref, 1, nil).
+		Instantiate(testCtx)
+	require.NoError(t, err)
+
+	// (module
+	//   (import "env" "memory" (memory 1))
+	//   (import "env" "__stack_pointer" (global $sp (mut i32)))
+	//   (import "env" "__indirect_function_table" (table 1 funcref))
+	//   (func $seven (result i32) i32.const 7)
+	//   (elem (i32.const 0) $seven)
+	//   (func (export "store") (result i32)
+	//     global.get $sp
+	//     i32.const 0
+	//     call_indirect (result i32)
+	//     i32.store
+	//     global.get $sp
+	//     i32.const 4
+	//     i32.add
+	//     global.set $sp
+	//     global.get $sp))
+	guest, err := r.Instantiate(testCtx, binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection: []wasm.FunctionType{{Results: []wasm.ValueType{wasm.ValueTypeI32}}},
+		ImportSection: []wasm.Import{
+			{Type: wasm.ExternTypeMemory, Module: "env", Name: "memory", DescMem: &wasm.Memory{Min: 1}},
+			{Type: wasm.ExternTypeGlobal, Module: "env", Name: "__stack_pointer", DescGlobal: wasm.GlobalType{ValType: wasm.ValueTypeI32, Mutable: true}},
+			{Type: wasm.ExternTypeTable, Module: "env", Name: "__indirect_function_table", DescTable: wasm.Table{Min: 1, Type: wasm.RefTypeFuncref}},
+		},
+		FunctionSection: []wasm.Index{0, 0},
+		CodeSection: []wasm.Code{
+			{Body: []byte{wasm.OpcodeI32Const, 7, wasm.OpcodeEnd}},
+			{Body: []byte{
+				wasm.OpcodeGlobalGet, 0,
+				wasm.OpcodeI32Const, 0,
+				wasm.OpcodeCallIndirect, 0, 0,
+				wasm.OpcodeI32Store, 2, 0,
+				wasm.OpcodeGlobalGet, 0,
+				wasm.OpcodeI32Const, 4,
+				wasm.OpcodeI32Add,
+				wasm.OpcodeGlobalSet, 0,
+				wasm.OpcodeGlobalGet, 0,
+				wasm.OpcodeEnd,
+			}},
+		},
+		ElementSection: []wasm.ElementSegment{{
+			OffsetExpr: wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: []byte{0}},
+			Init:       []wasm.Index{0},
+			Type:       wasm.RefTypeFuncref,
+		}},
+		ExportSection: []wasm.Export{{Name: "store", Type: wasm.ExternTypeFunc, Index: 1}},
+	}))
+	require.NoError(t, err)
+
+	results, err := guest.ExportedFunction("store").Call(testCtx)
+	require.NoError(t, err)
+	require.Equal(t, uint64(20), results[0])
+
+	// The guest wrote through to the host module's memory and global.
+	v, ok := env.ExportedMemory("memory").ReadUint32Le(16)
+	require.True(t, ok)
+	require.Equal(t, uint32(7), v)
+	require.Equal(t, uint64(20), env.ExportedGlobal("__stack_pointer").Get())
+
+	// The element segment initialized the host module's table.
+	table := env.ExportedTable("__indirect_function_table")
+	ref, ok := table.Get(0)
+	require.True(t, ok)
+	require.NotEqual(t, uint64(0), ref)
+
+	// The table was defined without a max, so it is unbounded.
+	_, ok = table.Definition().Max()
+	require.False(t, ok)
+	_, ok = table.Grow(1, 0)
+	require.True(t, ok)
+}
+
 // requireHostModuleEquals is redefined from internal/wasm/host_test.go to avoid an import cycle extracting it.
 func requireHostModuleEquals(t *testing.T, expected, actual *wasm.Module) {
 	// `require.Equal(t, expected, actual)` fails reflect pointers don't match, so brute compare:
@@ -223,7 +223,7 @@ func requireChdirToTemp(t *testing.T) (string, string) {
 
 func TestCache_Close(t *testing.T) {
 	t.Run("all engines", func(t *testing.T) {
-		c := &cache{engs: [engineKindCount]wasm.Engine{&mockEngine{}, &mockEngine{}}}
+		c := &cache{engs: [engineKindCount]wasm.Engine{&mockEngine{}, &mockEngine{}, &mockEngine{}}}
 		err := c.Close(testCtx)
 		require.NoError(t, err)
 		for i := engineKind(0); i < engineKindCount; i++ {
@@ -3,7 +3,6 @@ package wazero
 import (
 	"context"
 	"errors"
-	"fmt"
 	"io"
 	"io/fs"
 	"math"
@@ -11,9 +10,11 @@ import (
 	"time"
 
 	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
 	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
 	"github.com/tetratelabs/wazero/internal/engine/compiler"
 	"github.com/tetratelabs/wazero/internal/engine/interpreter"
+	"github.com/tetratelabs/wazero/internal/engine/tiered"
 	"github.com/tetratelabs/wazero/internal/filecache"
 	"github.com/tetratelabs/wazero/internal/internalapi"
 	"github.com/tetratelabs/wazero/internal/platform"
@@ -56,14 +57,20 @@ type RuntimeConfig interface {
 
 	// WithMemoryLimitPages overrides the maximum pages allowed per memory. The
 	// default is 65536, allowing 4GB total memory per instance if the maximum is
-	// not encoded in a Wasm binary. Setting a value larger than default will panic.
+	// not encoded in a Wasm binary.
 	//
 	// This example reduces the largest possible memory size from 4GB to 128KB:
 	//	rConfig = wazero.NewRuntimeConfig().WithMemoryLimitPages(2)
 	//
-	// Note: Wasm has 32-bit memory and each page is 65536 (2^16) bytes. This
-	// implies a max of 65536 (2^16) addressable pages.
-	// See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#grow-mem
+	// # Notes
+	//
+	//   - Wasm has 32-bit memory and each page is 65536 (2^16) bytes. This
+	//     implies a max of 65536 (2^16) addressable pages, so values larger
+	//     than the default only apply to 64-bit memories.
+	//     See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#grow-mem
+	//   - 64-bit memories are introduced in api.CoreFeatureMemory64, and can
+	//     exceed 4GB when this is set larger than the default, e.g. 131072
+	//     pages for 8GB.
 	WithMemoryLimitPages(memoryLimitPages uint32) RuntimeConfig
 
 	// WithMemoryCapacityFromMax eagerly allocates max memory, unless max is
@@ -79,6 +86,36 @@ type RuntimeConfig interface {
 	// results in allocating 4GB. See the doc on WithMemoryLimitPages for detail.
 	WithMemoryCapacityFromMax(memoryCapacityFromMax bool) RuntimeConfig
 
+	// WithMemoryAllocator allocates the memories defined by modules with the
+	// given allocator, instead of Go slices. The default is nil, which means
+	// Go slices are used.
+	//
+	// This example reserves virtual memory up to the max of each memory, so
+	// that memory.grow never copies:
+	//	rConfig = wazero.NewRuntimeConfig().WithMemoryAllocator(experimental.NewMmapMemoryAllocator())
+	//
+	// Note: experimental.MemoryAllocator is experimental, and likely to change.
+	WithMemoryAllocator(experimental.MemoryAllocator) RuntimeConfig
+
+	// WithMaxCallStackDepth limits the number of nested function calls, including
+	// host functions, in a call from Go. Exceeding it fails the call with a stack
+	// overflow error. The default is zero, which means the default of the engine.
+	//
+	// For example, this limits the recursion of functions to 1000 calls:
+	//	rConfig = wazero.NewRuntimeConfig().WithMaxCallStackDepth(1000)
+	//
+	// Note: The interpreter defaults to 2000 calls and the compiler to no limit
+	// other than WithMaxStackBytes.
+	WithMaxCallStackDepth(depth uint32) RuntimeConfig
+
+	// WithMaxStackBytes limits the size of the stack holding the values and call
+	// frames of a call from Go. Exceeding it fails the call with a stack overflow
+	// error. The default is zero, which means 40MB.
+	//
+	// Note: The stack layout differs between engines, so unlike
+	// WithMaxCallStackDepth, the same limit can be reached at different depths.
+	WithMaxStackBytes(bytes uint64) RuntimeConfig
+
 	// WithDebugInfoEnabled toggles DWARF based stack traces in the face of
 	// runtime errors. Defaults to true.
 	//
@@ -138,6 +175,17 @@ type RuntimeConfig interface {
 	// To avoid this issue, you can pass -ldflags "-X github.com/tetratelabs/wazero/internal/version.version=foo" when running tests.
 	WithCompilationCache(CompilationCache) RuntimeConfig
 
+	// WithCompilationWorkers sets the maximum count of goroutines compiling the functions of a module in
+	// Runtime.CompileModule. Defaults to one, which compiles functions one after another on the calling goroutine.
+	//
+	// For example, this compiles large modules with all CPUs:
+	//
+	//	config := wazero.NewRuntimeConfig().WithCompilationWorkers(runtime.NumCPU())
+	//
+	// The compiled code doesn't depend on the count of workers, so neither do the files of a CompilationCache.
+	// This has no effect on NewRuntimeConfigInterpreter, which doesn't compile into assembly.
+	WithCompilationWorkers(workers int) RuntimeConfig
+
 	// WithCustomSections toggles parsing of "custom sections". Defaults to false.
 	//
 	// When enabled, it is possible to retrieve custom sections from a CompiledModule:
@@ -182,12 +230,16 @@ type runtimeConfig struct {
 	enabledFeatures       api.CoreFeatures
 	memoryLimitPages      uint32
 	memoryCapacityFromMax bool
+	memoryAllocator       experimental.MemoryAllocator
+	maxCallStackDepth     uint32
+	maxStackBytes         uint64
 	engineKind            engineKind
 	dwarfDisabled         bool // negative as defaults to enabled
 	newEngine             newEngine
 	cache                 CompilationCache
 	storeCustomSections   bool
 	ensureTermination     bool
+	compilationWorkers    int
 }
 
 // engineLessConfig helps avoid copy/pasting the wrong defaults.
@@ -203,6 +255,7 @@ type engineKind int
 const (
 	engineKindCompiler engineKind = iota
 	engineKindInterpreter
+	engineKindTiered
 	engineKindCount
 )
 
@@ -235,6 +288,23 @@ func NewRuntimeConfigInterpreter() RuntimeConfig {
 	return ret
 }
 
+// NewRuntimeConfigTiered interprets WebAssembly modules at first, and compiles them into assembly in the background
+// once they are hot. This avoids the latency of compiling large modules before their first call, at the cost of
+// slower calls until then.
+//
+// A module is hot once one of its functions was called, or looped, a thousand times. Instances created after it was
+// compiled use the assembly. In existing instances, new calls of hot functions run the assembly, unless the module
+// has tables or reference types. Modules which import or export tables or reference type globals remain
+// interpreted, as their references can't be shared with compiled modules.
+//
+// Note: This interprets all modules if the runtime.GOOS or runtime.GOARCH does not support Compiler.
+func NewRuntimeConfigTiered() RuntimeConfig {
+	ret := engineLessConfig.clone()
+	ret.engineKind = engineKindTiered
+	ret.newEngine = tiered.NewEngine
+	return ret
+}
+
 // clone makes a deep copy of this runtime config.
 func (c *runtimeConfig) clone() *runtimeConfig {
 	ret := *c // copy except maps which share a ref
@@ -258,10 +328,6 @@ func (c *runtimeConfig) WithCloseOnContextDone(ensure bool) RuntimeConfig {
 // WithMemoryLimitPages implements RuntimeConfig.WithMemoryLimitPages
 func (c *runtimeConfig) WithMemoryLimitPages(memoryLimitPages uint32) RuntimeConfig {
 	ret := c.clone()
-	// This panics instead of returning an error as it is unlikely.
-	if memoryLimitPages > wasm.MemoryLimitPages {
-		panic(fmt.Errorf("memoryLimitPages invalid: %d > %d", memoryLimitPages, wasm.MemoryLimitPages))
-	}
 	ret.memoryLimitPages = memoryLimitPages
 	return ret
 }
@@ -280,6 +346,27 @@ func (c *runtimeConfig) WithMemoryCapacityFromMax(memoryCapacityFromMax bool) Ru
 	return ret
 }
 
+// WithMemoryAllocator implements RuntimeConfig.WithMemoryAllocator
+func (c *runtimeConfig) WithMemoryAllocator(allocator experimental.MemoryAllocator) RuntimeConfig {
+	ret := c.clone()
+	ret.memoryAllocator = allocator
+	return ret
+}
+
+// WithMaxCallStackDepth implements RuntimeConfig.WithMaxCallStackDepth
+func (c *runtimeConfig) WithMaxCallStackDepth(depth uint32) RuntimeConfig {
+	ret := c.clone()
+	ret.maxCallStackDepth = depth
+	return ret
+}
+
+// WithMaxStackBytes implements RuntimeConfig.WithMaxStackBytes
+func (c *runtimeConfig) WithMaxStackBytes(bytes uint64) RuntimeConfig {
+	ret := c.clone()
+	ret.maxStackBytes = bytes
+	return ret
+}
+
 // WithDebugInfoEnabled implements RuntimeConfig.WithDebugInfoEnabled
 func (c *runtimeConfig) WithDebugInfoEnabled(dwarfEnabled bool) RuntimeConfig {
 	ret := c.clone()
@@ -294,6 +381,13 @@ func (c *runtimeConfig) WithCustomSections(storeCustomSections bool) RuntimeConf
 	return ret
 }
 
+// WithCompilationWorkers implements RuntimeConfig.WithCompilationWorkers
+func (c *runtimeConfig) WithCompilationWorkers(workers int) RuntimeConfig {
+	ret := c.clone()
+	ret.compilationWorkers = workers
+	return ret
+}
+
 // CompiledModule is a WebAssembly module ready to be instantiated (Runtime.InstantiateModule) as an api.Module.
 //
 // In WebAssembly terminology, this is a decoded, validated, and possibly also compiled module. wazero avoids using
@@ -483,6 +577,45 @@ type ModuleConfig interface {
 	// (e.g. syscall.ENOSYS).
 	WithFSConfig(FSConfig) ModuleConfig
 
+	// WithImportResolver configures a function to resolve each import of the
+	// module before looking up the imported module by name in the Runtime.
+	// Defaults to nil, which only looks up modules by name.
+	//
+	// This allows instantiating the same guest against different instances
+	// of a host module, without renaming modules or creating a Runtime each.
+	// For example, this resolves "env" to a host module per tenant:
+	//
+	//	_, _ = r.NewHostModuleBuilder("env-" + tenant).
+	//		NewFunctionBuilder().WithFunc(log).Export("log").
+	//		Instantiate(ctx)
+	//	mod, _ := r.InstantiateModule(ctx, guest, wazero.NewModuleConfig().
+	//		WithImportResolver(func(moduleName, _ string, _ api.ExternType) api.Module {
+	//			if moduleName == "env" {
+	//				return r.Module("env-" + tenant)
+	//			}
+	//			return nil
+	//		}))
+	//
+	// # Notes
+	//
+	//   - The resolver returns nil to look up the module by name as usual.
+	//   - The returned module must be instantiated in the same Runtime, not be
+	//     closed, and export the import with the given name and a compatible
+	//     type.
+	WithImportResolver(ImportResolver) ModuleConfig
+
+	// WithMaxThreads limits the number of threads a guest can spawn which
+	// run at the same time, for example via "thread-spawn" in the "wasi"
+	// module of wasi-threads. Defaults to zero, which disallows threads.
+	//
+	// # Notes
+	//
+	//   - The limit applies to the module instantiated with this config and
+	//     all the threads it spawns, not including itself.
+	//   - Threads require api.CoreFeatureThreads, and the guest to import a
+	//     shared memory.
+	WithMaxThreads(uint32) ModuleConfig
+
 	// WithName configures the module name. Defaults to what was decoded from
 	// the name section. Empty string ("") clears any name.
 	WithName(string) ModuleConfig
@@ -640,6 +773,8 @@ type ModuleConfig interface {
 type moduleConfig struct {
 	name               string
 	nameSet            bool
+	importResolver     wasm.ImportResolver
+	maxThreads         uint32
 	startFunctions     []string
 	stdin              io.Reader
 	stdout             io.Writer
@@ -662,6 +797,12 @@ type moduleConfig struct {
 	sockConfig *internalsock.Config
 }
 
+// ImportResolver returns the module which exports the import of the given
+// module name, name and type, or nil to look up the module by name.
+//
+// See ModuleConfig.WithImportResolver
+type ImportResolver func(moduleName, name string, kind api.ExternType) api.Module
+
 // NewModuleConfig returns a ModuleConfig that can be used for configuring module instantiation.
 func NewModuleConfig() ModuleConfig {
 	return &moduleConfig{
@@ -727,6 +868,20 @@ func (c *moduleConfig) WithFSConfig(config FSConfig) ModuleConfig {
 	return ret
 }
 
+// WithImportResolver implements ModuleConfig.WithImportResolver
+func (c *moduleConfig) WithImportResolver(resolver ImportResolver) ModuleConfig {
+	ret := c.clone()
+	ret.importResolver = wasm.ImportResolver(resolver)
+	return ret
+}
+
+// WithMaxThreads implements ModuleConfig.WithMaxThreads
+func (c *moduleConfig) WithMaxThreads(maxThreads uint32) ModuleConfig {
+	ret := c.clone()
+	ret.maxThreads = maxThreads
+	return ret
+}
+
 // WithName implements ModuleConfig.WithName
 func (c *moduleConfig) WithName(name string) ModuleConfig {
 	ret := c.clone()
@@ -43,6 +43,15 @@ func TestRuntimeConfig(t *testing.T) {
 				memoryLimitPages: 10,
 			},
 		},
+		{
+			name: "memoryLimitPages over the limit of 32-bit memories",
+			with: func(c RuntimeConfig) RuntimeConfig {
+				return c.WithMemoryLimitPages(wasm.MemoryLimitPages + 1)
+			},
+			expected: &runtimeConfig{
+				memoryLimitPages: wasm.MemoryLimitPages + 1,
+			},
+		},
 		{
 			name: "memoryCapacityFromMax",
 			with: func(c RuntimeConfig) RuntimeConfig {
@@ -52,6 +61,24 @@ func TestRuntimeConfig(t *testing.T) {
 				memoryCapacityFromMax: true,
 			},
 		},
+		{
+			name: "WithMaxCallStackDepth",
+			with: func(c RuntimeConfig) RuntimeConfig {
+				return c.WithMaxCallStackDepth(100)
+			},
+			expected: &runtimeConfig{
+				maxCallStackDepth: 100,
+			},
+		},
+		{
+			name: "WithMaxStackBytes",
+			with: func(c RuntimeConfig) RuntimeConfig {
+				return c.WithMaxStackBytes(1 << 20)
+			},
+			expected: &runtimeConfig{
+				maxStackBytes: 1 << 20,
+			},
+		},
 		{
 			name: "WithDebugInfoEnabled",
 			with: func(c RuntimeConfig) RuntimeConfig {
@@ -75,6 +102,11 @@ func TestRuntimeConfig(t *testing.T) {
 			with:     func(c RuntimeConfig) RuntimeConfig { return c.WithCloseOnContextDone(true) },
 			expected: &runtimeConfig{ensureTermination: true},
 		},
+		{
+			name:     "WithCompilationWorkers",
+			with:     func(c RuntimeConfig) RuntimeConfig { return c.WithCompilationWorkers(4) },
+			expected: &runtimeConfig{compilationWorkers: 4},
+		},
 	}
 
 	for _, tt := range tests {
@@ -88,14 +120,6 @@ func TestRuntimeConfig(t *testing.T) {
 			require.Equal(t, &runtimeConfig{}, input)
 		})
 	}
-
-	t.Run("memoryLimitPages invalid panics", func(t *testing.T) {
-		err := require.CapturePanic(func() {
-			input := &runtimeConfig{}
-			input.WithMemoryLimitPages(wasm.MemoryLimitPages + 1)
-		})
-		require.EqualError(t, err, "memoryLimitPages invalid: 65537 > 65536")
-	})
 }
 
 func TestModuleConfig(t *testing.T) {
@@ -682,7 +706,7 @@ func Test_compiledModule_Close(t *testing.T) {
 		var cs []*compiledModule
 		for i := 0; i < 10; i++ {
 			m := &wasm.Module{}
-			err := e.CompileModule(ctx, m, nil, false)
+			err := e.CompileModule(ctx, m, nil, false, false, false)
 			require.NoError(t, err)
 			cs = append(cs, &compiledModule{module: m, compiledEngine: e})
 		}
@@ -711,3 +735,9 @@ func TestNewRuntimeConfig(t *testing.T) {
 		require.Equal(t, engineKindInterpreter, c.engineKind)
 	}
 }
+
+func TestNewRuntimeConfigTiered(t *testing.T) {
+	c, ok := NewRuntimeConfigTiered().(*runtimeConfig)
+	require.True(t, ok)
+	require.Equal(t, engineKindTiered, c.engineKind)
+}
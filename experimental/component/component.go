@@ -0,0 +1,221 @@
+// Package component instantiates WebAssembly components, which embed core modules and describe their imports and
+// exports with WIT types, passed between the host and the core modules by the canonical ABI.
+//
+// Component functions are called, and host functions defined, with Go values of the following types:
+//
+//   - bool, integers and floats map to the Go kind of the same size, char to rune and string to string.
+//   - list<T> maps to a slice of T.
+//   - record and tuple map to a struct of the same count of exported fields, matched in order.
+//   - variant and result map to a struct with a pointer field per case, in order, of which exactly one is non-nil.
+//     Cases without payload are pointers to an empty struct. Result is such a struct for result<T, E>.
+//   - option<T> maps to *T, nil being none.
+//   - enum maps to an integer holding the index of the case, and flags to an unsigned integer holding a bit per
+//     label, the first label being the least significant bit.
+//   - own<R> and borrow<R> map to the representation of R: any Go value for a resource imported from the host, or
+//     uint32 for a resource defined by the component.
+//
+// Where the Go type is interface{}, values are lifted to a type following the above. For example, a record is
+// lifted to a struct whose fields are named after the labels in CamelCase.
+//
+// For example, a host function implementing `log: func(level: level, msg: string) -> result<u32, string>`, where
+// level is an enum, can be defined like this:
+//
+//	host := component.NewHost().
+//		WithFunc("example:logging/logger#log", func(level uint8, msg string) component.Result[uint32, string] {
+//			n := uint32(len(msg))
+//			return component.Result[uint32, string]{Ok: &n}
+//		})
+//
+// # Notes
+//
+//   - This is experimental, and likely to change. Do not expose this in shared libraries as it can cause version
+//     locks.
+//   - Nested components, component values, start functions and async are not supported.
+//
+// See https://github.com/WebAssembly/component-model
+package component
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/internal/component"
+)
+
+// Result is a Go type for result<T, E>, of which either Ok or Err is non-nil.
+type Result[T, E any] struct {
+	Ok  *T
+	Err *E
+}
+
+// IsComponent returns true if the binary is a component, as opposed to a core module.
+func IsComponent(binary []byte) bool {
+	return component.IsComponent(binary)
+}
+
+// CompiledComponent is a component whose core modules are compiled by a wazero.Runtime.
+type CompiledComponent struct {
+	r       wazero.Runtime
+	c       *component.Component
+	modules []wazero.CompiledModule
+}
+
+// Compile decodes the component binary and compiles its core modules with the runtime, which needs to enable the
+// core features they use.
+func Compile(ctx context.Context, r wazero.Runtime, binary []byte) (*CompiledComponent, error) {
+	c, err := component.DecodeComponent(binary)
+	if err != nil {
+		return nil, err
+	}
+	cc := &CompiledComponent{r: r, c: c}
+	for i, m := range c.Modules {
+		compiled, err := r.CompileModule(ctx, m)
+		if err != nil {
+			_ = cc.Close(ctx)
+			return nil, fmt.Errorf("core module %d: %w", i, err)
+		}
+		cc.modules = append(cc.modules, compiled)
+	}
+	return cc, nil
+}
+
+// Imports returns the names of the functions the component imports, which must be defined by the Host to
+// instantiate it. Functions of imported instances are named by the instance and function names separated by "#".
+func (cc *CompiledComponent) Imports() []string {
+	var ret []string
+	for _, i := range cc.c.Imports {
+		switch i.Extern.Kind {
+		case component.ExternKindFunc:
+			ret = append(ret, i.Name)
+		case component.ExternKindInstance:
+			ret = append(ret, funcNames(i.Name+"#", i.Extern.Instance)...)
+		}
+	}
+	return ret
+}
+
+// Exports returns the names of the functions the component exports. Functions of exported instances are named by
+// the instance and function names separated by "#".
+func (cc *CompiledComponent) Exports() []string {
+	var ret []string
+	for _, e := range cc.c.Exports {
+		switch e.Kind {
+		case component.ExternKindFunc:
+			ret = append(ret, e.Name)
+		case component.ExternKindInstance:
+			ret = append(ret, funcNames(e.Name+"#", cc.c.Instances[e.Index].Type)...)
+		}
+	}
+	return ret
+}
+
+// funcNames returns the sorted names of the functions exported by an instance of type it, with the given prefix.
+func funcNames(prefix string, it *component.InstanceType) []string {
+	var ret []string
+	for name, e := range it.Exports {
+		if e.Kind == component.ExternKindFunc {
+			ret = append(ret, prefix+name)
+		}
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// FunctionType returns the WIT type of the imported or exported function name, or false if there is none.
+func (cc *CompiledComponent) FunctionType(name string) (string, bool) {
+	if ft := cc.funcType(name); ft != nil {
+		return ft.String(), true
+	}
+	return "", false
+}
+
+func (cc *CompiledComponent) funcType(name string) *component.FuncType {
+	instance, fn := splitName(name)
+	for _, i := range cc.c.Imports {
+		if i.Name != instance {
+			continue
+		}
+		switch {
+		case i.Extern.Kind == component.ExternKindFunc && fn == "":
+			return i.Extern.Func
+		case i.Extern.Kind == component.ExternKindInstance:
+			if e, ok := i.Extern.Instance.Exports[fn]; ok && e.Kind == component.ExternKindFunc {
+				return e.Func
+			}
+		}
+	}
+	if f := cc.exportedFunc(name); f != nil {
+		return f.Type
+	}
+	return nil
+}
+
+// exportedFunc returns the exported function name, or nil if there is none.
+func (cc *CompiledComponent) exportedFunc(name string) *component.Func {
+	instance, fn := splitName(name)
+	for _, e := range cc.c.Exports {
+		if e.Name != instance {
+			continue
+		}
+		switch {
+		case e.Kind == component.ExternKindFunc && fn == "":
+			return cc.c.Funcs[e.Index]
+		case e.Kind == component.ExternKindInstance:
+			if ie, ok := cc.c.Instances[e.Index].Exports[fn]; ok && ie.Kind == component.ExternKindFunc {
+				return cc.c.Funcs[ie.Index]
+			}
+		}
+	}
+	return nil
+}
+
+// splitName splits a function name into the names of the instance and of the function in it, or returns the name
+// and an empty string if it isn't in an instance.
+func splitName(name string) (instance, fn string) {
+	for i := len(name) - 1; i >= 0; i-- {
+		if name[i] == '#' {
+			return name[:i], name[i+1:]
+		}
+	}
+	return name, ""
+}
+
+// Close releases the compiled core modules. Instances of the component must be closed first.
+func (cc *CompiledComponent) Close(ctx context.Context) (err error) {
+	for _, m := range cc.modules {
+		if e := m.Close(ctx); e != nil && err == nil {
+			err = e
+		}
+	}
+	cc.modules = nil
+	return
+}
+
+// Host defines the host functions imported by components.
+type Host struct {
+	funcs map[string]reflect.Value
+}
+
+// NewHost returns a Host which doesn't define any function.
+func NewHost() *Host {
+	return &Host{funcs: map[string]reflect.Value{}}
+}
+
+// WithFunc defines the imported function name, or the function of an imported instance named by the instance and
+// function names separated by "#", as a Go func. Its parameter and result types must correspond to the WIT type of
+// the import, which is checked when instantiating a component. It may have a context.Context as its first
+// parameter, and an error as its last result, which traps when non-nil.
+//
+// For example, this defines "get-arguments" of the instance "wasi:cli/environment@0.2.0":
+//
+//	host.WithFunc("wasi:cli/environment@0.2.0#get-arguments", func() []string { return os.Args })
+//
+// When a component drops an owned handle to a resource imported from the host, its representation is closed if
+// it implements api.Closer.
+func (h *Host) WithFunc(name string, fn interface{}) *Host {
+	h.funcs[name] = reflect.ValueOf(fn)
+	return h
+}
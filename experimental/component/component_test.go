@@ -0,0 +1,409 @@
+package component_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/experimental/component"
+	"github.com/tetratelabs/wazero/internal/leb128"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+var testCtx = context.WithValue(context.Background(), struct{}{}, "arbitrary")
+
+func cat(parts ...[]byte) (ret []byte) {
+	for _, p := range parts {
+		ret = append(ret, p...)
+	}
+	return
+}
+
+func vec(items ...[]byte) []byte {
+	return cat(leb128.EncodeUint32(uint32(len(items))), cat(items...))
+}
+
+func name(s string) []byte {
+	return cat(leb128.EncodeUint32(uint32(len(s))), []byte(s))
+}
+
+func section(id byte, items ...[]byte) []byte {
+	contents := vec(items...)
+	return cat([]byte{id}, leb128.EncodeUint32(uint32(len(contents))), contents)
+}
+
+func moduleSection(m *wasm.Module) []byte {
+	bin := binaryencoding.EncodeModule(m)
+	return cat([]byte{1}, leb128.EncodeUint32(uint32(len(bin))), bin)
+}
+
+var (
+	i32              = wasm.ValueTypeI32
+	v_v              = wasm.FunctionType{}
+	i32_v            = wasm.FunctionType{Params: []wasm.ValueType{i32}}
+	i32_i32          = wasm.FunctionType{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32}}
+	v_i32            = wasm.FunctionType{Results: []wasm.ValueType{i32}}
+	i32i32_v         = wasm.FunctionType{Params: []wasm.ValueType{i32, i32}}
+	i32i32_i32       = wasm.FunctionType{Params: []wasm.ValueType{i32, i32}, Results: []wasm.ValueType{i32}}
+	i32i32i32i32_i32 = wasm.FunctionType{Params: []wasm.ValueType{i32, i32, i32, i32}, Results: []wasm.ValueType{i32}}
+)
+
+// libcWasm exports a memory and a bump allocator as "realloc".
+var libcWasm = &wasm.Module{
+	TypeSection:     []wasm.FunctionType{i32i32i32i32_i32},
+	FunctionSection: []wasm.Index{0},
+	MemorySection:   []wasm.Memory{{Min: 1}},
+	GlobalSection: []wasm.Global{{
+		Type: wasm.GlobalType{ValType: i32, Mutable: true},
+		Init: wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: leb128.EncodeInt32(1024)},
+	}},
+	CodeSection: []wasm.Code{{LocalTypes: []wasm.ValueType{i32}, Body: []byte{
+		// ptr = (next + align - 1) & -align; next = ptr + size; ptr
+		wasm.OpcodeGlobalGet, 0, wasm.OpcodeLocalGet, 2, wasm.OpcodeI32Add, wasm.OpcodeI32Const, 1, wasm.OpcodeI32Sub,
+		wasm.OpcodeI32Const, 0, wasm.OpcodeLocalGet, 2, wasm.OpcodeI32Sub, wasm.OpcodeI32And,
+		wasm.OpcodeLocalTee, 4, wasm.OpcodeLocalGet, 3, wasm.OpcodeI32Add, wasm.OpcodeGlobalSet, 0,
+		wasm.OpcodeLocalGet, 4, wasm.OpcodeEnd,
+	}}},
+	ExportSection: []wasm.Export{
+		{Name: "memory", Type: wasm.ExternTypeMemory, Index: 0},
+		{Name: "realloc", Type: wasm.ExternTypeFunc, Index: 0},
+	},
+}
+
+// mainWasm imports functions of the host, lowered by the component, and the memory of libcWasm.
+var mainWasm = &wasm.Module{
+	TypeSection: []wasm.FunctionType{i32i32_v, i32_v, i32_i32, i32i32_i32, v_i32, v_v},
+	ImportSection: []wasm.Import{
+		{Type: wasm.ExternTypeFunc, Module: "host", Name: "log", DescFunc: 0},
+		{Type: wasm.ExternTypeFunc, Module: "host", Name: "get-name", DescFunc: 1},
+		{Type: wasm.ExternTypeFunc, Module: "host", Name: "new-counter", DescFunc: 2},
+		{Type: wasm.ExternTypeFunc, Module: "host", Name: "next", DescFunc: 2},
+		{Type: wasm.ExternTypeFunc, Module: "host", Name: "drop-counter", DescFunc: 1},
+		{Type: wasm.ExternTypeMemory, Module: "host", Name: "mem", DescMem: &wasm.Memory{}},
+	},
+	FunctionSection: []wasm.Index{3, 3, 3, 3, 0, 4, 2},
+	CodeSection: []wasm.Code{
+		// echo(ptr, len): the result is the same (ptr, len), returned in memory.
+		{Body: []byte{
+			wasm.OpcodeI32Const, 16, wasm.OpcodeLocalGet, 0, wasm.OpcodeI32Store, 2, 0,
+			wasm.OpcodeI32Const, 16, wasm.OpcodeLocalGet, 1, wasm.OpcodeI32Store, 2, 4,
+			wasm.OpcodeI32Const, 16, wasm.OpcodeEnd,
+		}},
+		// add(a, b)
+		{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 1, wasm.OpcodeI32Add, wasm.OpcodeEnd}},
+		// swap(x, y): the result is (y, x), returned in memory.
+		{Body: []byte{
+			wasm.OpcodeI32Const, 16, wasm.OpcodeLocalGet, 1, wasm.OpcodeI32Store, 2, 0,
+			wasm.OpcodeI32Const, 16, wasm.OpcodeLocalGet, 0, wasm.OpcodeI32Store, 2, 4,
+			wasm.OpcodeI32Const, 16, wasm.OpcodeEnd,
+		}},
+		// maybe(disc, val): the result is the same option, returned in memory.
+		{Body: []byte{
+			wasm.OpcodeI32Const, 16, wasm.OpcodeLocalGet, 0, wasm.OpcodeI32Store8, 0, 0,
+			wasm.OpcodeI32Const, 16, wasm.OpcodeLocalGet, 1, wasm.OpcodeI32Store, 2, 4,
+			wasm.OpcodeI32Const, 16, wasm.OpcodeEnd,
+		}},
+		// call-log(ptr, len)
+		{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 1, wasm.OpcodeCall, 0, wasm.OpcodeEnd}},
+		// hello(): get-name returns the string in memory at 32, which is returned as is.
+		{Body: []byte{wasm.OpcodeI32Const, 32, wasm.OpcodeCall, 1, wasm.OpcodeI32Const, 32, wasm.OpcodeEnd}},
+		// count(start): c = new-counter(start); next(c); n = next(c); drop-counter(c); n
+		{LocalTypes: []wasm.ValueType{i32}, Body: []byte{
+			wasm.OpcodeLocalGet, 0, wasm.OpcodeCall, 2, wasm.OpcodeLocalSet, 1,
+			wasm.OpcodeLocalGet, 1, wasm.OpcodeCall, 3, wasm.OpcodeDrop,
+			wasm.OpcodeLocalGet, 1, wasm.OpcodeCall, 3,
+			wasm.OpcodeLocalGet, 1, wasm.OpcodeCall, 4,
+			wasm.OpcodeEnd,
+		}},
+	},
+	ExportSection: []wasm.Export{
+		{Name: "echo", Type: wasm.ExternTypeFunc, Index: 5},
+		{Name: "add", Type: wasm.ExternTypeFunc, Index: 6},
+		{Name: "swap", Type: wasm.ExternTypeFunc, Index: 7},
+		{Name: "maybe", Type: wasm.ExternTypeFunc, Index: 8},
+		{Name: "call-log", Type: wasm.ExternTypeFunc, Index: 9},
+		{Name: "hello", Type: wasm.ExternTypeFunc, Index: 10},
+		{Name: "count", Type: wasm.ExternTypeFunc, Index: 11},
+	},
+}
+
+const (
+	u32    = 0x79
+	s32    = 0x7a
+	str    = 0x73
+	export = 0x04
+	decl   = 0x01
+	sorted = 0x00
+)
+
+// testComponent imports the instance "example:test/host", and exports functions lifted from mainWasm.
+var testComponent = cat(
+	[]byte{0x00, 0x61, 0x73, 0x6d, 0x0d, 0x00, 0x01, 0x00},
+	moduleSection(mainWasm), // core module 0
+	moduleSection(libcWasm), // core module 1
+	section(7, // type 0
+		cat([]byte{0x42}, vec(
+			cat([]byte{export}, []byte{0x00}, name("counter"), []byte{0x03, 0x01}), // type 0: (sub resource)
+			[]byte{decl, 0x69, 0}, // type 1: own<counter>
+			[]byte{decl, 0x68, 0}, // type 2: borrow<counter>
+			cat([]byte{decl, 0x40}, vec(cat(name("start"), []byte{u32})), []byte{0x00, 1}), // type 3
+			cat([]byte{export, 0x00}, name("[constructor]counter"), []byte{0x01, 3}),
+			cat([]byte{decl, 0x40}, vec(cat(name("self"), []byte{2})), []byte{0x00, u32}), // type 4
+			cat([]byte{export, 0x00}, name("[method]counter.next"), []byte{0x01, 4}),
+			cat([]byte{decl, 0x40}, vec(cat(name("msg"), []byte{str})), []byte{0x01, 0x00}), // type 5
+			cat([]byte{export, 0x00}, name("log"), []byte{0x01, 5}),
+			cat([]byte{decl, 0x40}, vec(), []byte{0x00, str}), // type 6
+			cat([]byte{export, 0x00}, name("get-name"), []byte{0x01, 6}),
+		)),
+	),
+	section(10, cat([]byte{0x00}, name("example:test/host"), []byte{0x05, 0})), // instance 0
+	section(6,
+		cat([]byte{0x03, 0x00, 0}, name("counter")),              // type 1
+		cat([]byte{0x01, 0x00, 0}, name("[constructor]counter")), // func 0
+		cat([]byte{0x01, 0x00, 0}, name("[method]counter.next")), // func 1
+		cat([]byte{0x01, 0x00, 0}, name("log")),                  // func 2
+		cat([]byte{0x01, 0x00, 0}, name("get-name")),             // func 3
+	),
+	section(2, cat([]byte{0x00, 1}, vec())), // core instance 0: libc
+	section(6,
+		cat([]byte{0x00, 0x02, 0x01, 0}, name("memory")),  // core memory 0
+		cat([]byte{0x00, 0x00, 0x01, 0}, name("realloc")), // core func 0
+	),
+	section(8,
+		cat([]byte{0x01, 0x00, 2}, vec([]byte{0x03, 0}, []byte{0x00})),    // core func 1: lower log
+		cat([]byte{0x01, 0x00, 3}, vec([]byte{0x03, 0}, []byte{0x04, 0})), // core func 2: lower get-name
+		cat([]byte{0x01, 0x00, 0}, vec()),                                 // core func 3: lower [constructor]counter
+		cat([]byte{0x01, 0x00, 1}, vec()),                                 // core func 4: lower [method]counter.next
+		[]byte{0x03, 1},                                                   // core func 5: resource.drop counter
+	),
+	section(2, cat([]byte{0x01}, vec( // core instance 1: the imports of main
+		cat(name("log"), []byte{0x00, 1}),
+		cat(name("get-name"), []byte{0x00, 2}),
+		cat(name("new-counter"), []byte{0x00, 3}),
+		cat(name("next"), []byte{0x00, 4}),
+		cat(name("drop-counter"), []byte{0x00, 5}),
+		cat(name("mem"), []byte{0x02, 0}),
+	))),
+	section(2, cat([]byte{0x00, 0}, vec(cat(name("host"), []byte{0x12, 1})))), // core instance 2: main
+	section(6,
+		cat([]byte{0x00, 0x00, 0x01, 2}, name("echo")),     // core func 6
+		cat([]byte{0x00, 0x00, 0x01, 2}, name("add")),      // core func 7
+		cat([]byte{0x00, 0x00, 0x01, 2}, name("swap")),     // core func 8
+		cat([]byte{0x00, 0x00, 0x01, 2}, name("maybe")),    // core func 9
+		cat([]byte{0x00, 0x00, 0x01, 2}, name("call-log")), // core func 10
+		cat([]byte{0x00, 0x00, 0x01, 2}, name("hello")),    // core func 11
+		cat([]byte{0x00, 0x00, 0x01, 2}, name("count")),    // core func 12
+	),
+	section(7,
+		cat([]byte{0x40}, vec(cat(name("s"), []byte{str})), []byte{0x00, str}), // type 2
+		[]byte{0x70, u32}, // type 3: list<u32>
+		cat([]byte{0x40}, vec(cat(name("xs"), []byte{3})), []byte{0x00, 3}),                                 // type 4
+		cat([]byte{0x40}, vec(cat(name("a"), []byte{u32}), cat(name("b"), []byte{u32})), []byte{0x00, u32}), // type 5
+		cat([]byte{0x72}, vec(cat(name("x"), []byte{s32}), cat(name("y"), []byte{s32}))),                    // type 6: point
+		cat([]byte{0x40}, vec(cat(name("p"), []byte{6})), []byte{0x00, 6}),                                  // type 7
+		[]byte{0x6b, u32}, // type 8: option<u32>
+		cat([]byte{0x40}, vec(cat(name("x"), []byte{8})), []byte{0x00, 8}),         // type 9
+		cat([]byte{0x40}, vec(cat(name("s"), []byte{str})), []byte{0x01, 0x00}),    // type 10
+		cat([]byte{0x40}, vec(), []byte{0x00, str}),                                // type 11
+		cat([]byte{0x40}, vec(cat(name("start"), []byte{u32})), []byte{0x00, u32}), // type 12
+	),
+	section(8,
+		cat([]byte{0x00, 0x00, 6}, vec([]byte{0x03, 0}, []byte{0x04, 0}), []byte{2}),   // func 4: echo
+		cat([]byte{0x00, 0x00, 6}, vec([]byte{0x03, 0}, []byte{0x04, 0}), []byte{4}),   // func 5: echo-list
+		cat([]byte{0x00, 0x00, 7}, vec(), []byte{5}),                                   // func 6: add
+		cat([]byte{0x00, 0x00, 8}, vec([]byte{0x03, 0}), []byte{7}),                    // func 7: swap
+		cat([]byte{0x00, 0x00, 9}, vec([]byte{0x03, 0}), []byte{9}),                    // func 8: maybe
+		cat([]byte{0x00, 0x00, 10}, vec([]byte{0x03, 0}, []byte{0x04, 0}), []byte{10}), // func 9: call-log
+		cat([]byte{0x00, 0x00, 11}, vec([]byte{0x03, 0}), []byte{11}),                  // func 10: hello
+		cat([]byte{0x00, 0x00, 12}, vec(), []byte{12}),                                 // func 11: count
+	),
+	section(5, cat([]byte{0x01}, vec( // instance 1
+		cat([]byte{0x00}, name("add"), []byte{0x01, 6}),
+		cat([]byte{0x00}, name("swap"), []byte{0x01, 7}),
+	))),
+	section(11,
+		cat([]byte{0x00}, name("echo"), []byte{0x01, 4, 0x00}),
+		cat([]byte{0x00}, name("echo-list"), []byte{0x01, 5, 0x00}),
+		cat([]byte{0x01}, name("example:test/api"), []byte{0x05, 1, 0x00}),
+		cat([]byte{0x00}, name("maybe"), []byte{0x01, 8, 0x00}),
+		cat([]byte{0x00}, name("call-log"), []byte{0x01, 9, 0x00}),
+		cat([]byte{0x00}, name("hello"), []byte{0x01, 10, 0x00}),
+		cat([]byte{0x00}, name("count"), []byte{0x01, 11, 0x00}),
+	),
+)
+
+// counter is the representation of the resource "counter" imported from the host.
+type counter struct {
+	n      uint32
+	closed bool
+}
+
+// Close implements api.Closer
+func (c *counter) Close(context.Context) error {
+	c.closed = true
+	return nil
+}
+
+func TestCompiledComponent(t *testing.T) {
+	r := wazero.NewRuntime(testCtx)
+	defer r.Close(testCtx)
+
+	cc, err := component.Compile(testCtx, r, testComponent)
+	require.NoError(t, err)
+	defer cc.Close(testCtx)
+
+	require.Equal(t, []string{
+		"example:test/host#[constructor]counter",
+		"example:test/host#[method]counter.next",
+		"example:test/host#get-name",
+		"example:test/host#log",
+	}, cc.Imports())
+	require.Equal(t, []string{
+		"echo", "echo-list", "example:test/api#add", "example:test/api#swap", "maybe", "call-log", "hello", "count",
+	}, cc.Exports())
+
+	ft, ok := cc.FunctionType("example:test/api#swap")
+	require.True(t, ok)
+	require.Equal(t, "func(p: record) -> record", ft)
+	ft, ok = cc.FunctionType("example:test/host#[method]counter.next")
+	require.True(t, ok)
+	require.Equal(t, "func(self: borrow<counter>) -> u32", ft)
+	_, ok = cc.FunctionType("missing")
+	require.False(t, ok)
+}
+
+func TestInstance(t *testing.T) {
+	r := wazero.NewRuntime(testCtx)
+	defer r.Close(testCtx)
+
+	cc, err := component.Compile(testCtx, r, testComponent)
+	require.NoError(t, err)
+	defer cc.Close(testCtx)
+
+	var logs []string
+	var counters []*counter
+	host := component.NewHost().
+		WithFunc("example:test/host#log", func(msg string) { logs = append(logs, msg) }).
+		WithFunc("example:test/host#get-name", func(context.Context) (string, error) { return "wazero", nil }).
+		WithFunc("example:test/host#[constructor]counter", func(start uint32) *counter {
+			c := &counter{n: start}
+			counters = append(counters, c)
+			return c
+		}).
+		WithFunc("example:test/host#[method]counter.next", func(c *counter) uint32 {
+			c.n++
+			return c.n
+		})
+
+	inst, err := cc.Instantiate(testCtx, host)
+	require.NoError(t, err)
+	defer inst.Close(testCtx)
+
+	call := func(t *testing.T, name string, params ...interface{}) interface{} {
+		fn := inst.Function(name)
+		require.NotNil(t, fn)
+		results, err := fn.Call(testCtx, params...)
+		require.NoError(t, err)
+		if len(results) == 0 {
+			return nil
+		}
+		require.Equal(t, 1, len(results))
+		return results[0]
+	}
+
+	t.Run("string", func(t *testing.T) {
+		require.Equal(t, "héllo", call(t, "echo", "héllo"))
+		require.Equal(t, "", call(t, "echo", ""))
+	})
+
+	t.Run("list", func(t *testing.T) {
+		require.Equal(t, []uint32{1, 2, 3}, call(t, "echo-list", []uint32{1, 2, 3}))
+		require.Equal(t, []uint32{}, call(t, "echo-list", []uint32(nil)))
+	})
+
+	t.Run("instance export", func(t *testing.T) {
+		require.Equal(t, uint32(3), call(t, "example:test/api#add", 1, 2))
+	})
+
+	t.Run("record", func(t *testing.T) {
+		type point struct{ X, Y int32 }
+		require.Equal(t, "{2 -1}", fmt.Sprint(call(t, "example:test/api#swap", point{X: -1, Y: 2})))
+	})
+
+	t.Run("option", func(t *testing.T) {
+		require.Nil(t, call(t, "maybe", nil).(*uint32))
+		v := uint32(42)
+		require.Equal(t, &v, call(t, "maybe", &v))
+	})
+
+	t.Run("host function", func(t *testing.T) {
+		require.Nil(t, call(t, "call-log", "hello world"))
+		require.Equal(t, []string{"hello world"}, logs)
+		require.Equal(t, "wazero", call(t, "hello"))
+	})
+
+	t.Run("resource", func(t *testing.T) {
+		require.Equal(t, uint32(12), call(t, "count", 10))
+		require.Equal(t, 1, len(counters))
+		require.Equal(t, &counter{n: 12, closed: true}, counters[0])
+	})
+
+	t.Run("invalid param", func(t *testing.T) {
+		_, err := inst.Function("echo").Call(testCtx, 1)
+		require.EqualError(t, err, "param s: int can't be used as string")
+		_, err = inst.Function("echo").Call(testCtx)
+		require.EqualError(t, err, "expected 1 params, but passed 0")
+	})
+
+	require.Nil(t, inst.Function("missing"))
+}
+
+func TestCompiledComponent_Instantiate_Errors(t *testing.T) {
+	r := wazero.NewRuntime(testCtx)
+	defer r.Close(testCtx)
+
+	cc, err := component.Compile(testCtx, r, testComponent)
+	require.NoError(t, err)
+	defer cc.Close(testCtx)
+
+	host := func() *component.Host {
+		return component.NewHost().
+			WithFunc("example:test/host#log", func(string) {}).
+			WithFunc("example:test/host#get-name", func() string { return "" }).
+			WithFunc("example:test/host#[constructor]counter", func(uint32) *counter { return nil })
+	}
+
+	_, err = cc.Instantiate(testCtx, host())
+	require.EqualError(t, err, "import example:test/host#[method]counter.next is not defined")
+
+	_, err = cc.Instantiate(testCtx, host().
+		WithFunc("example:test/host#[method]counter.next", func(*counter) int32 { return 0 }))
+	require.EqualError(t, err, "import example:test/host#[method]counter.next: result: int32 can't be used as u32")
+
+	_, err = cc.Instantiate(testCtx, host().
+		WithFunc("example:test/host#[method]counter.next", func(*counter, uint32) uint32 { return 0 }))
+	require.EqualError(t, err, "import example:test/host#[method]counter.next: "+
+		"func(*component_test.counter, uint32) uint32 doesn't match func(self: borrow<counter>) -> u32")
+}
+
+func TestCompile_Errors(t *testing.T) {
+	r := wazero.NewRuntime(testCtx)
+	defer r.Close(testCtx)
+
+	_, err := component.Compile(testCtx, r, binaryencoding.EncodeModule(&wasm.Module{}))
+	require.EqualError(t, err, "invalid magic number or layer of component")
+
+	_, err = component.Compile(testCtx, r, cat(
+		[]byte{0x00, 0x61, 0x73, 0x6d, 0x0d, 0x00, 0x01, 0x00},
+		[]byte{1, 8, 0x00, 0x61, 0x73, 0x6d, 0x02, 0x00, 0x00, 0x00},
+	))
+	require.EqualError(t, err, "core module 0: invalid version header")
+}
+
+func TestIsComponent(t *testing.T) {
+	require.True(t, component.IsComponent(testComponent))
+	require.False(t, component.IsComponent(binaryencoding.EncodeModule(&wasm.Module{})))
+}
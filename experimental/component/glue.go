@@ -0,0 +1,98 @@
+package component
+
+import (
+	"strconv"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/leb128"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// glueImport is an import of a glue module, re-exported as name.
+type glueImport struct {
+	name string
+	// module is the index of the imported module, which is also its name in the import.
+	module int
+	field  string
+	et     api.ExternType
+
+	// params and results are set for functions.
+	params, results []api.ValueType
+	// refType is set for tables.
+	refType api.RefType
+	// memory is set for memories.
+	memory *wasm.MemoryInstance
+	// global is set for globals.
+	global api.Global
+}
+
+// encodeGlueModule encodes a core module which only imports definitions and re-exports them. This bundles
+// definitions of different core instances, possibly under different names, into a core instance which can be
+// imported by the instantiation of a core module.
+func encodeGlueModule(imports []glueImport) []byte {
+	var types, importSection, exports []byte
+	var typeCount, exportCount uint32
+	var indices [4]uint32 // next index per api.ExternType
+	for _, i := range imports {
+		importSection = appendName(importSection, strconv.Itoa(i.module))
+		importSection = appendName(importSection, i.field)
+		importSection = append(importSection, i.et)
+		switch i.et {
+		case api.ExternTypeFunc:
+			types = append(types, 0x60)
+			types = appendValueTypes(types, i.params)
+			types = appendValueTypes(types, i.results)
+			importSection = append(importSection, leb128.EncodeUint32(typeCount)...)
+			typeCount++
+		case api.ExternTypeTable:
+			// A minimum of zero and no maximum is satisfied by any table of the same type.
+			importSection = append(importSection, i.refType, 0x00, 0x00)
+		case api.ExternTypeMemory:
+			var flags byte
+			if i.memory.Shared {
+				flags |= 0x03
+			}
+			if i.memory.Is64 {
+				flags |= 0x04
+			}
+			importSection = append(importSection, flags, 0x00)
+			if i.memory.Shared {
+				importSection = append(importSection, leb128.EncodeUint32(i.memory.Max)...)
+			}
+		case api.ExternTypeGlobal:
+			var mutable byte
+			if _, ok := i.global.(api.MutableGlobal); ok {
+				mutable = 1
+			}
+			importSection = append(importSection, i.global.Type(), mutable)
+		}
+
+		exports = appendName(exports, i.name)
+		exports = append(exports, i.et)
+		exports = append(exports, leb128.EncodeUint32(indices[i.et])...)
+		indices[i.et]++
+		exportCount++
+	}
+
+	bin := append([]byte{0x00, 0x61, 0x73, 0x6d}, 0x01, 0x00, 0x00, 0x00)
+	bin = appendSection(bin, wasm.SectionIDType, typeCount, types)
+	bin = appendSection(bin, wasm.SectionIDImport, uint32(len(imports)), importSection)
+	return appendSection(bin, wasm.SectionIDExport, exportCount, exports)
+}
+
+func appendName(b []byte, name string) []byte {
+	b = append(b, leb128.EncodeUint32(uint32(len(name)))...)
+	return append(b, name...)
+}
+
+func appendValueTypes(b []byte, vts []api.ValueType) []byte {
+	b = append(b, leb128.EncodeUint32(uint32(len(vts)))...)
+	return append(b, vts...)
+}
+
+func appendSection(b []byte, id wasm.SectionID, count uint32, contents []byte) []byte {
+	contents = append(leb128.EncodeUint32(count), contents...)
+	b = append(b, id)
+	b = append(b, leb128.EncodeUint32(uint32(len(contents)))...)
+	return append(b, contents...)
+}
@@ -0,0 +1,83 @@
+package component
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/tetratelabs/wazero/internal/component"
+)
+
+var (
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// hostFunc is a Go func implementing an imported function.
+type hostFunc struct {
+	fn               reflect.Value
+	withCtx, withErr bool
+	params, results  []reflect.Type
+}
+
+// newHostFunc returns an error if fn doesn't implement a function of type ft.
+func newHostFunc(fn reflect.Value, ft *component.FuncType) (*hostFunc, error) {
+	if fn.Kind() != reflect.Func {
+		return nil, fmt.Errorf("%s is not a func", fn.Type())
+	}
+	t := fn.Type()
+	h := &hostFunc{fn: fn}
+	for i := 0; i < t.NumIn(); i++ {
+		if i == 0 && t.In(0) == contextType {
+			h.withCtx = true
+			continue
+		}
+		h.params = append(h.params, t.In(i))
+	}
+	for i := 0; i < t.NumOut(); i++ {
+		if i == t.NumOut()-1 && t.Out(i) == errorType {
+			h.withErr = true
+			continue
+		}
+		h.results = append(h.results, t.Out(i))
+	}
+
+	if len(h.params) != len(ft.Params) || len(h.results) != len(ft.Results) {
+		return nil, fmt.Errorf("%s doesn't match %s", t, ft)
+	}
+	for i, p := range ft.Params {
+		if err := component.CheckGoType(p.Type, h.params[i]); err != nil {
+			return nil, fmt.Errorf("param %s: %w", p.Name, err)
+		}
+	}
+	for i, r := range ft.Results {
+		if err := component.CheckGoType(r.Type, h.results[i]); err != nil {
+			return nil, fmt.Errorf("result: %w", err)
+		}
+	}
+	return h, nil
+}
+
+// newParams returns zero values of the parameters, to lift arguments into.
+func (h *hostFunc) newParams() []reflect.Value {
+	ret := make([]reflect.Value, len(h.params))
+	for i, p := range h.params {
+		ret[i] = reflect.New(p).Elem()
+	}
+	return ret
+}
+
+// call calls the Go func, panicking when it returns an error so that the calling core function traps.
+func (h *hostFunc) call(ctx context.Context, params []reflect.Value) []reflect.Value {
+	if h.withCtx {
+		params = append([]reflect.Value{reflect.ValueOf(ctx)}, params...)
+	}
+	results := h.fn.Call(params)
+	if h.withErr {
+		if err := results[len(results)-1]; !err.IsNil() {
+			panic(err.Interface())
+		}
+		results = results[:len(results)-1]
+	}
+	return results
+}
@@ -0,0 +1,461 @@
+package component
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/component"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// Instance is an instantiated component.
+//
+// Note: Like api.Module, an Instance is not safe for concurrent use.
+type Instance struct {
+	cc   *CompiledComponent
+	host map[string]*hostFunc
+
+	handles component.Handles
+
+	// coreInstances are indexed by the core instance index space of the component.
+	coreInstances []api.Module
+	// coreFuncs caches the core functions implemented by the host, by index.
+	coreFuncs map[uint32]api.Module
+	// modules are all the modules instantiated for this instance, to close them in reverse order.
+	modules []api.Module
+	// compiled are the host modules compiled for this instance, closed after modules.
+	compiled []wazero.CompiledModule
+	// empty is the instance of an empty module, which resolves missing instantiation arguments.
+	empty api.Module
+
+	funcs map[string]*Function
+}
+
+// Instantiate instantiates the component, whose imported functions are defined by host.
+func (cc *CompiledComponent) Instantiate(ctx context.Context, host *Host) (*Instance, error) {
+	i := &Instance{cc: cc, host: map[string]*hostFunc{}, coreFuncs: map[uint32]api.Module{}, funcs: map[string]*Function{}}
+	if err := i.instantiate(ctx, host); err != nil {
+		_ = i.Close(ctx)
+		return nil, err
+	}
+	return i, nil
+}
+
+func (i *Instance) instantiate(ctx context.Context, host *Host) error {
+	if host == nil {
+		host = NewHost()
+	}
+	for _, name := range i.cc.Imports() {
+		fn, ok := host.funcs[name]
+		if !ok {
+			return fmt.Errorf("import %s is not defined", name)
+		}
+		hf, err := newHostFunc(fn, i.cc.funcType(name))
+		if err != nil {
+			return fmt.Errorf("import %s: %w", name, err)
+		}
+		i.host[name] = hf
+	}
+
+	c := i.cc.c
+	for idx, ci := range c.CoreInstances {
+		var mod api.Module
+		var err error
+		if ci.FromExports {
+			mod, err = i.instantiateGlue(ctx, ci.Exports)
+		} else {
+			args := make(map[string]api.Module, len(ci.Args))
+			for _, arg := range ci.Args {
+				args[arg.Name] = i.coreInstances[arg.Instance]
+			}
+			mod, err = i.instantiateCore(ctx, i.cc.modules[ci.Module], args)
+		}
+		if err != nil {
+			return fmt.Errorf("core instance %d: %w", idx, err)
+		}
+		i.coreInstances = append(i.coreInstances, mod)
+	}
+
+	for _, name := range i.cc.Exports() {
+		f := i.cc.exportedFunc(name)
+		if f.Kind != component.FuncKindLift {
+			return fmt.Errorf("export %s: exporting an imported function is not supported", name)
+		}
+		fn, err := i.liftedFunction(f)
+		if err != nil {
+			return fmt.Errorf("export %s: %w", name, err)
+		}
+		i.funcs[name] = fn
+	}
+	return nil
+}
+
+// instantiateCore instantiates a core module whose imports are resolved by module name in args.
+func (i *Instance) instantiateCore(ctx context.Context, compiled wazero.CompiledModule, args map[string]api.Module) (api.Module, error) {
+	if i.empty == nil {
+		empty, err := i.cc.r.InstantiateWithConfig(ctx, []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00},
+			wazero.NewModuleConfig().WithName(""))
+		if err != nil {
+			return nil, err
+		}
+		i.empty = empty
+		i.modules = append(i.modules, empty)
+	}
+	// Core instances are anonymous, so that they are only reachable through the component.
+	mod, err := i.cc.r.InstantiateModule(ctx, compiled, wazero.NewModuleConfig().WithName("").WithStartFunctions().
+		WithImportResolver(func(moduleName, _ string, _ api.ExternType) api.Module {
+			if m, ok := args[moduleName]; ok {
+				return m
+			}
+			return i.empty
+		}))
+	if err != nil {
+		return nil, err
+	}
+	i.modules = append(i.modules, mod)
+	return mod, nil
+}
+
+// instantiateGlue instantiates a core instance bundling the given core definitions, possibly renamed.
+func (i *Instance) instantiateGlue(ctx context.Context, exports []component.CoreInlineExport) (api.Module, error) {
+	var sources []api.Module
+	moduleIndex := map[api.Module]int{}
+	imports := make([]glueImport, len(exports))
+	for idx, e := range exports {
+		var mod api.Module
+		var field string
+		var err error
+		if e.Type == api.ExternTypeFunc {
+			mod, field, err = i.coreFunc(ctx, e.Index)
+		} else {
+			mod, field, err = i.coreExport(e.Type, e.Index)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("export %s: %w", e.Name, err)
+		}
+
+		gi := glueImport{name: e.Name, field: field, et: e.Type}
+		switch e.Type {
+		case api.ExternTypeFunc:
+			def := mod.ExportedFunction(field).Definition()
+			gi.params, gi.results = def.ParamTypes(), def.ResultTypes()
+		case api.ExternTypeTable:
+			gi.refType = mod.ExportedTable(field).Definition().Type()
+		case api.ExternTypeMemory:
+			mem, ok := mod.ExportedMemory(field).(*wasm.MemoryInstance)
+			if !ok {
+				return nil, fmt.Errorf("export %s: unsupported memory", e.Name)
+			}
+			gi.memory = mem
+		case api.ExternTypeGlobal:
+			gi.global = mod.ExportedGlobal(field)
+		}
+
+		if mi, ok := moduleIndex[mod]; ok {
+			gi.module = mi
+		} else {
+			gi.module = len(sources)
+			moduleIndex[mod] = gi.module
+			sources = append(sources, mod)
+		}
+		imports[idx] = gi
+	}
+
+	compiled, err := i.cc.r.CompileModule(ctx, encodeGlueModule(imports))
+	if err != nil {
+		return nil, err
+	}
+	defer compiled.Close(ctx)
+
+	args := make(map[string]api.Module, len(sources))
+	for idx, mod := range sources {
+		args[fmt.Sprint(idx)] = mod
+	}
+	return i.instantiateCore(ctx, compiled, args)
+}
+
+// coreExport returns the core instance and export name of the core table, memory or global idx.
+func (i *Instance) coreExport(et api.ExternType, idx uint32) (api.Module, string, error) {
+	var e component.CoreExport
+	switch et {
+	case api.ExternTypeTable:
+		e = i.cc.c.CoreTables[idx]
+	case api.ExternTypeMemory:
+		e = i.cc.c.CoreMemories[idx]
+	default:
+		e = i.cc.c.CoreGlobals[idx]
+	}
+	mod := i.coreInstances[e.Instance]
+	if !hasExport(mod, et, e.Name) {
+		return nil, "", fmt.Errorf("core instance %d has no %s export %s", e.Instance, api.ExternTypeName(et), e.Name)
+	}
+	return mod, e.Name, nil
+}
+
+func hasExport(mod api.Module, et api.ExternType, name string) bool {
+	switch et {
+	case api.ExternTypeFunc:
+		return mod.ExportedFunction(name) != nil
+	case api.ExternTypeTable:
+		return mod.ExportedTable(name) != nil
+	case api.ExternTypeMemory:
+		return mod.ExportedMemory(name) != nil
+	default:
+		return mod.ExportedGlobal(name) != nil
+	}
+}
+
+// coreFunc returns the module and export name of the core function idx, instantiating a host module for the
+// functions implemented by the host.
+func (i *Instance) coreFunc(ctx context.Context, idx uint32) (api.Module, string, error) {
+	f := i.cc.c.CoreFuncs[idx]
+	if f.Kind == component.CoreFuncKindAlias {
+		mod := i.coreInstances[f.Alias.Instance]
+		if !hasExport(mod, api.ExternTypeFunc, f.Alias.Name) {
+			return nil, "", fmt.Errorf("core instance %d has no func export %s", f.Alias.Instance, f.Alias.Name)
+		}
+		return mod, f.Alias.Name, nil
+	}
+	if mod, ok := i.coreFuncs[idx]; ok {
+		return mod, "", nil
+	}
+
+	i32 := []api.ValueType{api.ValueTypeI32}
+	var fn api.GoModuleFunc
+	var params, results []api.ValueType
+	switch f.Kind {
+	case component.CoreFuncKindLower:
+		lowered := i.cc.c.Funcs[f.Func]
+		if lowered.Kind != component.FuncKindImport {
+			return nil, "", errors.New("lowering a lifted function is not supported")
+		}
+		opts, err := i.options(ctx, &f.Options)
+		if err != nil {
+			return nil, "", err
+		}
+		name := lowered.Import
+		if lowered.Name != "" {
+			name += "#" + lowered.Name
+		}
+		fn = i.lower(i.host[name], lowered.Type, opts)
+		params, results = lowered.Type.CoreParams(true), lowered.Type.CoreResults(true)
+	case component.CoreFuncKindResourceNew:
+		fn, params, results = func(_ context.Context, _ api.Module, stack []uint64) {
+			stack[0] = uint64(i.handles.Add(f.Resource, uint32(stack[0]), true))
+		}, i32, i32
+	case component.CoreFuncKindResourceRep:
+		fn, params, results = func(_ context.Context, _ api.Module, stack []uint64) {
+			rep, err := i.handles.Rep(f.Resource, uint32(stack[0]))
+			if err != nil {
+				panic(err)
+			}
+			stack[0] = uint64(rep.(uint32))
+		}, i32, i32
+	case component.CoreFuncKindResourceDrop:
+		fn, params = func(ctx context.Context, _ api.Module, stack []uint64) {
+			if err := i.dropResource(ctx, f.Resource, uint32(stack[0])); err != nil {
+				panic(err)
+			}
+		}, i32
+	}
+
+	// Host modules need a name, but are instantiated anonymously like other core instances.
+	compiled, err := i.cc.r.NewHostModuleBuilder("component").NewFunctionBuilder().
+		WithGoModuleFunction(fn, params, results).Export("").Compile(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	i.compiled = append(i.compiled, compiled)
+	mod, err := i.cc.r.InstantiateModule(ctx, compiled, wazero.NewModuleConfig().WithName(""))
+	if err != nil {
+		return nil, "", err
+	}
+	i.modules = append(i.modules, mod)
+	i.coreFuncs[idx] = mod
+	return mod, "", nil
+}
+
+// coreFunction returns the core function idx.
+func (i *Instance) coreFunction(ctx context.Context, idx uint32) (api.Function, error) {
+	mod, name, err := i.coreFunc(ctx, idx)
+	if err != nil {
+		return nil, err
+	}
+	return mod.ExportedFunction(name), nil
+}
+
+// options resolves the canonical options in this instance.
+func (i *Instance) options(ctx context.Context, o *component.CanonOptions) (*component.Options, error) {
+	opts := &component.Options{StringEncoding: o.StringEncoding}
+	if o.Memory != nil {
+		mod, name, err := i.coreExport(api.ExternTypeMemory, *o.Memory)
+		if err != nil {
+			return nil, err
+		}
+		opts.Memory = mod.ExportedMemory(name)
+	}
+	if o.Realloc != nil {
+		fn, err := i.coreFunction(ctx, *o.Realloc)
+		if err != nil {
+			return nil, err
+		}
+		i32 := api.ValueTypeI32
+		if err = checkSignature("realloc", fn, []api.ValueType{i32, i32, i32, i32}, []api.ValueType{i32}); err != nil {
+			return nil, err
+		}
+		opts.Realloc = fn
+	}
+	return opts, nil
+}
+
+func checkSignature(name string, fn api.Function, params, results []api.ValueType) error {
+	def := fn.Definition()
+	actual := &wasm.FunctionType{Params: def.ParamTypes(), Results: def.ResultTypes()}
+	if !actual.EqualsSignature(params, results) {
+		return fmt.Errorf("%s has signature %s but expected %s", name, actual,
+			&wasm.FunctionType{Params: params, Results: results})
+	}
+	return nil
+}
+
+// lower returns the core function lowering the host function hf of type ft.
+func (i *Instance) lower(hf *hostFunc, ft *component.FuncType, opts *component.Options) api.GoModuleFunc {
+	return func(ctx context.Context, _ api.Module, stack []uint64) {
+		call := component.NewCall(ctx, opts, &i.handles)
+		params := hf.newParams()
+		if err := call.LiftParams(ft, stack, params); err != nil {
+			panic(err)
+		}
+		results := hf.call(ctx, params)
+		if err := call.LowerResults(ft, stack, results); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// dropResource drops the handle idx to a resource of type r, calling its destructor if it was owned.
+func (i *Instance) dropResource(ctx context.Context, r *component.ResourceType, idx uint32) error {
+	rep, own, err := i.handles.Remove(r, idx)
+	if err != nil || !own {
+		return err
+	}
+	if r.Imported {
+		if closer, ok := rep.(api.Closer); ok {
+			return closer.Close(ctx)
+		}
+	} else if r.Dtor != nil {
+		dtor, err := i.coreFunction(ctx, *r.Dtor)
+		if err != nil {
+			return err
+		}
+		_, err = dtor.Call(ctx, uint64(rep.(uint32)))
+		return err
+	}
+	return nil
+}
+
+// liftedFunction returns the Function lifting the core function of f.
+func (i *Instance) liftedFunction(f *component.Func) (*Function, error) {
+	ctx := context.Background()
+	core, err := i.coreFunction(ctx, f.CoreFunc)
+	if err != nil {
+		return nil, err
+	}
+	if err = checkSignature("core function", core, f.Type.CoreParams(false), f.Type.CoreResults(false)); err != nil {
+		return nil, err
+	}
+	opts, err := i.options(ctx, &f.Options)
+	if err != nil {
+		return nil, err
+	}
+	if f.Options.PostReturn != nil {
+		if opts.PostReturn, err = i.coreFunction(ctx, *f.Options.PostReturn); err != nil {
+			return nil, err
+		}
+		if err = checkSignature("post-return", opts.PostReturn, f.Type.CoreResults(false), nil); err != nil {
+			return nil, err
+		}
+	}
+	return &Function{i: i, ft: f.Type, core: core, opts: opts}, nil
+}
+
+// Function returns the exported function name, or nil if there is none. Functions of exported instances are named
+// by the instance and function names separated by "#".
+func (i *Instance) Function(name string) *Function {
+	return i.funcs[name]
+}
+
+// Close closes the core instances of the component.
+func (i *Instance) Close(ctx context.Context) (err error) {
+	for idx := len(i.modules) - 1; idx >= 0; idx-- {
+		if e := i.modules[idx].Close(ctx); e != nil && err == nil {
+			err = e
+		}
+	}
+	i.modules = nil
+	for _, c := range i.compiled {
+		if e := c.Close(ctx); e != nil && err == nil {
+			err = e
+		}
+	}
+	i.compiled = nil
+	return
+}
+
+// Function is a function exported by a component.
+type Function struct {
+	i    *Instance
+	ft   *component.FuncType
+	core api.Function
+	opts *component.Options
+}
+
+// Type returns the WIT type of the function.
+func (f *Function) Type() string {
+	return f.ft.String()
+}
+
+// Call calls the function with the given parameters, and returns its results, which are of the default Go types
+// described in the package documentation.
+func (f *Function) Call(ctx context.Context, params ...interface{}) ([]interface{}, error) {
+	call := component.NewCall(ctx, f.opts, &f.i.handles)
+	defer call.End()
+
+	vals := make([]reflect.Value, len(params))
+	for idx, p := range params {
+		vals[idx] = reflect.ValueOf(p)
+	}
+	coreParams, err := call.LowerParams(f.ft, vals)
+	if err != nil {
+		return nil, err
+	}
+	coreResults, err := f.core.Call(ctx, coreParams...)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]reflect.Value, len(f.ft.Results))
+	for idx := range results {
+		results[idx] = reflect.New(emptyInterface).Elem()
+	}
+	if err = call.LiftResults(f.ft, coreResults, results); err != nil {
+		return nil, err
+	}
+	if f.opts.PostReturn != nil {
+		if _, err = f.opts.PostReturn.Call(ctx, coreResults...); err != nil {
+			return nil, err
+		}
+	}
+
+	ret := make([]interface{}, len(results))
+	for idx, r := range results {
+		ret[idx] = r.Interface()
+	}
+	return ret, nil
+}
+
+var emptyInterface = reflect.TypeOf((*interface{})(nil)).Elem()
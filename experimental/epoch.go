@@ -0,0 +1,90 @@
+package experimental
+
+import (
+	"context"
+
+	"github.com/tetratelabs/wazero/internal/wasmruntime"
+)
+
+// EpochInterruptionKey is a context.Context Value key. Its associated value
+// should be a bool.
+//
+// See WithEpochInterruption
+type EpochInterruptionKey struct{}
+
+// EpochDeadlineKey is a context.Context Value key. Its associated value should
+// be a uint64.
+//
+// See WithEpochDeadline
+type EpochDeadlineKey struct{}
+
+// ErrEpochInterrupted is returned by api.Function Call when the epoch deadline
+// of the call was reached. Use errors.Is to check for it, as the returned error
+// includes the stack trace.
+//
+// Unlike context cancellation with RuntimeConfig.WithCloseOnContextDone, this
+// doesn't close the module, so it can be called again.
+var ErrEpochInterrupted error = wasmruntime.ErrRuntimeEpochInterrupted
+
+// EpochCounter is implemented by wazero.Runtime. Its epoch is shared by all
+// modules in the runtime, and is usually incremented by a ticker:
+//
+//	epoch := r.(experimental.EpochCounter)
+//	ticker := time.NewTicker(10 * time.Millisecond)
+//	defer ticker.Stop()
+//	go func() {
+//		for range ticker.C {
+//			epoch.IncrementEpoch()
+//		}
+//	}()
+//
+// See WithEpochInterruption
+type EpochCounter interface {
+	// IncrementEpoch increments the epoch by one. This is safe to call
+	// concurrently with function calls.
+	IncrementEpoch()
+
+	// Epoch returns the current epoch, which starts at zero.
+	Epoch() uint64
+}
+
+// WithEpochInterruption enables epoch checks in functions of modules compiled
+// with the returned context. For example:
+//
+//	ctx = experimental.WithEpochInterruption(ctx)
+//	compiled, _ := r.CompileModule(ctx, wasm)
+//	mod, _ := r.InstantiateModule(ctx, compiled, wazero.NewModuleConfig())
+//
+//	// Interrupt the call after 10 increments of the epoch.
+//	_, err := mod.ExportedFunction("run").Call(experimental.WithEpochDeadline(ctx, 10))
+//	if errors.Is(err, experimental.ErrEpochInterrupted) {
+//		// mod can still be used.
+//	}
+//
+// Functions check the epoch on entry and at each loop header. This is cheaper
+// than RuntimeConfig.WithCloseOnContextDone, which doesn't need any ticker but
+// closes the module when the context is done.
+//
+// Notes:
+//   - Calls without WithEpochDeadline are never interrupted.
+//   - Host functions aren't interrupted, but the call traps once the host
+//     function returns to a function which checks the epoch.
+//   - The compiler doesn't yield to the Go scheduler while executing a loop,
+//     so the goroutine incrementing the epoch needs another thread to run on,
+//     i.e. GOMAXPROCS must be greater than one.
+//
+// Note: This is experimental, and likely to change. Do not expose this in
+// shared libraries as it can cause version locks.
+func WithEpochInterruption(ctx context.Context) context.Context {
+	return context.WithValue(ctx, EpochInterruptionKey{}, true)
+}
+
+// WithEpochDeadline returns a context, which interrupts calls with
+// ErrEpochInterrupted once the epoch was incremented the given number of times
+// since the call started. This only has an effect when the called function is
+// from a module compiled with WithEpochInterruption.
+//
+// See EpochCounter
+func WithEpochDeadline(ctx context.Context, ticks uint64) context.Context {
+	return context.WithValue(ctx, EpochDeadlineKey{}, ticks)
+}
@@ -0,0 +1,180 @@
+package experimental_test
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/internal/platform"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// epochWasm imports a host function "env.tick", and exports "tick_forever"
+// which calls it in an infinite loop, and "forever" which loops infinitely.
+var epochWasm = binaryencoding.EncodeModule(&wasm.Module{
+	TypeSection: []wasm.FunctionType{{}},
+	ImportSection: []wasm.Import{
+		{Module: "env", Name: "tick", Type: wasm.ExternTypeFunc, DescFunc: 0},
+	},
+	FunctionSection: []wasm.Index{0, 0},
+	CodeSection: []wasm.Code{
+		{Body: []byte{
+			wasm.OpcodeLoop, 0x40,
+			wasm.OpcodeCall, 0,
+			wasm.OpcodeBr, 0,
+			wasm.OpcodeEnd,
+			wasm.OpcodeEnd,
+		}},
+		{Body: []byte{
+			wasm.OpcodeLoop, 0x40,
+			wasm.OpcodeBr, 0,
+			wasm.OpcodeEnd,
+			wasm.OpcodeEnd,
+		}},
+	},
+	ExportSection: []wasm.Export{
+		{Name: "tick_forever", Type: wasm.ExternTypeFunc, Index: 1},
+		{Name: "forever", Type: wasm.ExternTypeFunc, Index: 2},
+	},
+})
+
+func TestEpochInterruption(t *testing.T) {
+	configs := map[string]wazero.RuntimeConfig{"interpreter": wazero.NewRuntimeConfigInterpreter()}
+	if platform.CompilerSupported() {
+		configs["compiler"] = wazero.NewRuntimeConfigCompiler()
+	}
+
+	for name, config := range configs {
+		config := config
+		t.Run(name, func(t *testing.T) {
+			r := wazero.NewRuntimeWithConfig(testCtx, config)
+			defer r.Close(testCtx)
+			epoch := r.(experimental.EpochCounter)
+
+			var ticks uint64
+			_, err := r.NewHostModuleBuilder("env").NewFunctionBuilder().
+				WithFunc(func(context.Context) {
+					ticks++
+					epoch.IncrementEpoch()
+				}).Export("tick").Instantiate(testCtx)
+			require.NoError(t, err)
+
+			compiled, err := r.CompileModule(experimental.WithEpochInterruption(testCtx), epochWasm)
+			require.NoError(t, err)
+			mod, err := r.InstantiateModule(testCtx, compiled, wazero.NewModuleConfig())
+			require.NoError(t, err)
+			tickForever := mod.ExportedFunction("tick_forever")
+
+			// The deadline is relative to the epoch when the call starts, and
+			// the module can be called again after an interruption.
+			for _, deadline := range []uint64{1, 3, 3} {
+				ticks = 0
+				_, err = tickForever.Call(experimental.WithEpochDeadline(testCtx, deadline))
+				require.ErrorIs(t, err, experimental.ErrEpochInterrupted)
+				require.Equal(t, deadline, ticks)
+				require.False(t, mod.IsClosed())
+			}
+			require.Equal(t, uint64(7), epoch.Epoch())
+
+			// The epoch can be incremented concurrently, like from a ticker.
+			if name == "compiler" && runtime.GOMAXPROCS(0) < 2 {
+				return // native code isn't preempted, so the ticker couldn't run.
+			}
+			done := make(chan struct{})
+			defer close(done)
+			go func() {
+				ticker := time.NewTicker(time.Millisecond)
+				defer ticker.Stop()
+				for {
+					select {
+					case <-done:
+						return
+					case <-ticker.C:
+						epoch.IncrementEpoch()
+					}
+				}
+			}()
+			_, err = mod.ExportedFunction("forever").Call(experimental.WithEpochDeadline(testCtx, 2))
+			require.ErrorIs(t, err, experimental.ErrEpochInterrupted)
+			require.False(t, mod.IsClosed())
+		})
+	}
+}
+
+// epochCallerWasm imports "guest.tick_forever" and exports "call" which calls it.
+var epochCallerWasm = binaryencoding.EncodeModule(&wasm.Module{
+	TypeSection: []wasm.FunctionType{{}},
+	ImportSection: []wasm.Import{
+		{Module: "guest", Name: "tick_forever", Type: wasm.ExternTypeFunc, DescFunc: 0},
+	},
+	FunctionSection: []wasm.Index{0},
+	CodeSection:     []wasm.Code{{Body: []byte{wasm.OpcodeCall, 0, wasm.OpcodeEnd}}},
+	ExportSection:   []wasm.Export{{Name: "call", Type: wasm.ExternTypeFunc, Index: 1}},
+})
+
+func TestEpochInterruption_CrossModule(t *testing.T) {
+	configs := map[string]wazero.RuntimeConfig{"interpreter": wazero.NewRuntimeConfigInterpreter()}
+	if platform.CompilerSupported() {
+		configs["compiler"] = wazero.NewRuntimeConfigCompiler()
+	}
+
+	for name, config := range configs {
+		config := config
+		t.Run(name, func(t *testing.T) {
+			r := wazero.NewRuntimeWithConfig(testCtx, config)
+			defer r.Close(testCtx)
+			epoch := r.(experimental.EpochCounter)
+
+			var ticks uint64
+			_, err := r.NewHostModuleBuilder("env").NewFunctionBuilder().
+				WithFunc(func(context.Context) {
+					ticks++
+					epoch.IncrementEpoch()
+				}).Export("tick").Instantiate(testCtx)
+			require.NoError(t, err)
+
+			compiled, err := r.CompileModule(experimental.WithEpochInterruption(testCtx), epochWasm)
+			require.NoError(t, err)
+			_, err = r.InstantiateModule(testCtx, compiled, wazero.NewModuleConfig().WithName("guest"))
+			require.NoError(t, err)
+
+			// The caller doesn't check the epoch, but the deadline of its call
+			// applies to the imported function which does.
+			caller, err := r.Instantiate(testCtx, epochCallerWasm)
+			require.NoError(t, err)
+			_, err = caller.ExportedFunction("call").Call(experimental.WithEpochDeadline(testCtx, 3))
+			require.ErrorIs(t, err, experimental.ErrEpochInterrupted)
+			require.Equal(t, uint64(3), ticks)
+			require.False(t, caller.IsClosed())
+		})
+	}
+}
+
+func TestEpochInterruption_Disabled(t *testing.T) {
+	r := wazero.NewRuntimeWithConfig(testCtx, wazero.NewRuntimeConfigInterpreter())
+	defer r.Close(testCtx)
+	epoch := r.(experimental.EpochCounter)
+
+	var ticks int
+	_, err := r.NewHostModuleBuilder("env").NewFunctionBuilder().
+		WithFunc(func(ctx context.Context) {
+			if ticks++; ticks == 5 {
+				panic("stop")
+			}
+			epoch.IncrementEpoch()
+		}).Export("tick").Instantiate(testCtx)
+	require.NoError(t, err)
+
+	mod, err := r.Instantiate(testCtx, epochWasm)
+	require.NoError(t, err)
+
+	// Functions of modules compiled without epoch interruption ignore the deadline.
+	_, err = mod.ExportedFunction("tick_forever").Call(experimental.WithEpochDeadline(testCtx, 1))
+	require.Contains(t, err.Error(), "stop")
+	require.Equal(t, 5, ticks)
+}
@@ -19,6 +19,22 @@ type InternalModule interface {
 	//
 	// The methods panics if i is out of bounds.
 	Global(i int) api.Global
+
+	// NumTable returns the count of all tables in the module, imports first.
+	NumTable() int
+
+	// Table provides access to a given table index, regardless of whether it
+	// is exported. For example, a host function can use this to resolve an
+	// index into the caller's "call_indirect" table via api.Table Function.
+	//
+	// The methods panics if i is out of bounds.
+	Table(i int) api.Table
+
+	// LookupFunction returns the function of a funcref value, such as one read
+	// via api.Table Get. This fails if ref is null or wasn't produced by a
+	// module of the same Runtime, or the signature of the function isn't
+	// params and results.
+	LookupFunction(ref uint64, params, results []api.ValueType) (api.Function, error)
 }
 
 // ProgramCounter is an opaque value representing a specific execution point in
@@ -0,0 +1,86 @@
+package experimental
+
+import (
+	"context"
+	"errors"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/wasmruntime"
+)
+
+// FuelMeteringKey is a context.Context Value key. Its associated value should
+// be a bool.
+//
+// See WithFuelMetering
+type FuelMeteringKey struct{}
+
+// ErrOutOfFuel is returned by api.Function Call when the module ran out of
+// fuel. Use errors.Is to check for it, as the returned error includes the
+// stack trace.
+//
+// Unlike context cancellation, running out of fuel doesn't close the module,
+// so the call can be retried after AddFuel.
+var ErrOutOfFuel error = wasmruntime.ErrRuntimeOutOfFuel
+
+// WithFuelMetering enables fuel metering in functions of modules compiled
+// with the returned context. For example:
+//
+//	ctx = experimental.WithFuelMetering(ctx)
+//	compiled, _ := r.CompileModule(ctx, wasm)
+//	mod, _ := r.InstantiateModule(ctx, compiled, wazero.NewModuleConfig())
+//	_ = experimental.AddFuel(mod, 10_000)
+//
+// Metered functions consume one unit of fuel per executed Wasm instruction,
+// except the ones which only delimit blocks: nop, block, loop, else, end, try,
+// catch, catch_all and delegate. The fuel is consumed at the start of each
+// straight-line sequence of instructions, for all of them at once. If the
+// remaining fuel is less than their cost, the call fails with ErrOutOfFuel and
+// none of them is executed. The cost is the same for all engines and
+// platforms, so the fuel consumed by a call is deterministic.
+//
+// Notes:
+//   - Modules start with no fuel, so AddFuel must be called before any
+//     metered function can execute.
+//   - A call consumes the fuel of the module whose function was called, even
+//     when it calls functions imported from other modules.
+//   - Host functions don't consume fuel.
+//   - The remaining fuel is synchronized with the module when a call starts,
+//     returns or calls a host function. Concurrent calls into the same module
+//     are not deterministic.
+//
+// Note: This is experimental, and likely to change. Do not expose this in
+// shared libraries as it can cause version locks.
+func WithFuelMetering(ctx context.Context) context.Context {
+	return context.WithValue(ctx, FuelMeteringKey{}, true)
+}
+
+// fueled is implemented by modules instantiated by wazero.
+type fueled interface {
+	AddFuel(fuel uint64)
+	RemainingFuel() uint64
+}
+
+// AddFuel adds fuel to the given module, saturating at math.MaxInt64. This is
+// safe to call from a host function called by the module.
+//
+// See WithFuelMetering
+func AddFuel(mod api.Module, fuel uint64) error {
+	f, ok := mod.(fueled)
+	if !ok {
+		return errors.New("module does not support fuel")
+	}
+	f.AddFuel(fuel)
+	return nil
+}
+
+// RemainingFuel returns the fuel of the given module. When called from a host
+// function, this includes the fuel consumed by the current call so far.
+//
+// See WithFuelMetering
+func RemainingFuel(mod api.Module) (uint64, error) {
+	f, ok := mod.(fueled)
+	if !ok {
+		return 0, errors.New("module does not support fuel")
+	}
+	return f.RemainingFuel(), nil
+}
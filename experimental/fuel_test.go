@@ -0,0 +1,346 @@
+package experimental_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/experimental/wazerotest"
+	"github.com/tetratelabs/wazero/internal/platform"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// fuelWasm imports a host function "env.observe", and exports "count" which
+// loops the number of times given by its parameter, then calls observe.
+var fuelWasm = binaryencoding.EncodeModule(&wasm.Module{
+	TypeSection: []wasm.FunctionType{{}, {Params: []wasm.ValueType{wasm.ValueTypeI32}}},
+	ImportSection: []wasm.Import{
+		{Module: "env", Name: "observe", Type: wasm.ExternTypeFunc, DescFunc: 0},
+	},
+	FunctionSection: []wasm.Index{1},
+	CodeSection: []wasm.Code{{Body: []byte{
+		wasm.OpcodeLoop, 0x40,
+		wasm.OpcodeLocalGet, 0,
+		wasm.OpcodeI32Const, 1,
+		wasm.OpcodeI32Sub,
+		wasm.OpcodeLocalTee, 0,
+		wasm.OpcodeBrIf, 0,
+		wasm.OpcodeEnd,
+		wasm.OpcodeCall, 0,
+		wasm.OpcodeEnd,
+	}}},
+	ExportSection: []wasm.Export{{Name: "count", Type: wasm.ExternTypeFunc, Index: 1}},
+})
+
+func TestFuelMetering(t *testing.T) {
+	configs := map[string]wazero.RuntimeConfig{"interpreter": wazero.NewRuntimeConfigInterpreter()}
+	if platform.CompilerSupported() {
+		configs["compiler"] = wazero.NewRuntimeConfigCompiler()
+	}
+
+	// consumed is the fuel consumed by count: one unit per instruction of the
+	// five in the loop body, and one for the call. The loop and end
+	// instructions are free. This must be the same for all engines.
+	consumed := func(n uint64) uint64 { return 5*n + 1 }
+	for name, config := range configs {
+		config := config
+		t.Run(name, func(t *testing.T) {
+			r := wazero.NewRuntimeWithConfig(testCtx, config)
+			defer r.Close(testCtx)
+
+			var observed uint64
+			_, err := r.NewHostModuleBuilder("env").NewFunctionBuilder().
+				WithFunc(func(_ context.Context, mod api.Module) {
+					var err error
+					observed, err = experimental.RemainingFuel(mod)
+					require.NoError(t, err)
+				}).Export("observe").Instantiate(testCtx)
+			require.NoError(t, err)
+
+			compiled, err := r.CompileModule(experimental.WithFuelMetering(testCtx), fuelWasm)
+			require.NoError(t, err)
+			mod, err := r.InstantiateModule(testCtx, compiled, wazero.NewModuleConfig())
+			require.NoError(t, err)
+			count := mod.ExportedFunction("count")
+
+			// A module starts without fuel, and isn't closed when out of fuel.
+			_, err = count.Call(testCtx, 1)
+			require.ErrorIs(t, err, experimental.ErrOutOfFuel)
+			require.False(t, mod.IsClosed())
+
+			require.NoError(t, experimental.AddFuel(mod, 1000))
+			for _, n := range []uint64{1, 10, 20} {
+				before, err := experimental.RemainingFuel(mod)
+				require.NoError(t, err)
+				_, err = count.Call(testCtx, n)
+				require.NoError(t, err)
+				after, err := experimental.RemainingFuel(mod)
+				require.NoError(t, err)
+
+				// The host function observes the fuel consumed before it was called.
+				require.Equal(t, after, observed)
+
+				require.Equal(t, consumed(n), before-after)
+			}
+
+			// Running out of fuel leaves less than the cost of the next block.
+			_, err = count.Call(testCtx, 1000)
+			require.ErrorIs(t, err, experimental.ErrOutOfFuel)
+			remaining, err := experimental.RemainingFuel(mod)
+			require.NoError(t, err)
+			require.True(t, remaining < consumed(1))
+
+			// Adding fuel allows calls to succeed again.
+			require.NoError(t, experimental.AddFuel(mod, consumed(1)))
+			_, err = count.Call(testCtx, 1)
+			require.NoError(t, err)
+		})
+	}
+}
+
+// TestFuelMetering_blocks ensures that the fuel of a block of instructions is
+// consumed before any of them is executed, where blocks start at the function
+// entry and at each branch target, which must be the same for all engines.
+func TestFuelMetering_blocks(t *testing.T) {
+	configs := map[string]wazero.RuntimeConfig{"interpreter": wazero.NewRuntimeConfigInterpreter()}
+	if platform.CompilerSupported() {
+		configs["compiler"] = wazero.NewRuntimeConfigCompiler()
+	}
+
+	tests := []struct {
+		name string
+		// body is the body of a function with an i32 param.
+		body []byte
+		// param is the param of the call, and fuel the fuel before it.
+		param, fuel uint64
+		// expRemaining is the fuel after the call.
+		expRemaining uint64
+		expOutOfFuel bool
+	}{
+		{
+			// The end of a block which isn't the target of a branch doesn't start another block.
+			name: "block end",
+			body: []byte{
+				wasm.OpcodeBlock, 0x40,
+				wasm.OpcodeLocalGet, 0, wasm.OpcodeDrop,
+				wasm.OpcodeEnd,
+				wasm.OpcodeI32Const, 1, wasm.OpcodeDrop,
+				wasm.OpcodeEnd,
+			},
+			fuel:         4,
+			expRemaining: 0,
+		},
+		{
+			name: "block end out of fuel",
+			body: []byte{
+				wasm.OpcodeBlock, 0x40,
+				wasm.OpcodeLocalGet, 0, wasm.OpcodeDrop,
+				wasm.OpcodeEnd,
+				wasm.OpcodeI32Const, 1, wasm.OpcodeDrop,
+				wasm.OpcodeEnd,
+			},
+			fuel:         3,
+			expRemaining: 3,
+			expOutOfFuel: true,
+		},
+		{
+			// The loop header starts a block, which continues after the end of the loop.
+			name: "loop end out of fuel",
+			body: []byte{
+				wasm.OpcodeLoop, 0x40,
+				wasm.OpcodeLocalGet, 0, wasm.OpcodeDrop,
+				wasm.OpcodeEnd,
+				wasm.OpcodeI32Const, 1, wasm.OpcodeDrop,
+				wasm.OpcodeEnd,
+			},
+			fuel:         3,
+			expRemaining: 3,
+			expOutOfFuel: true,
+		},
+		{
+			name: "if",
+			body: []byte{
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeIf, 0x40,
+				wasm.OpcodeI32Const, 1, wasm.OpcodeDrop,
+				wasm.OpcodeElse,
+				wasm.OpcodeNop,
+				wasm.OpcodeEnd,
+				wasm.OpcodeI32Const, 2, wasm.OpcodeDrop,
+				wasm.OpcodeEnd,
+			},
+			param:        1,
+			fuel:         10,
+			expRemaining: 4,
+		},
+		{
+			name: "else",
+			body: []byte{
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeIf, 0x40,
+				wasm.OpcodeI32Const, 1, wasm.OpcodeDrop,
+				wasm.OpcodeElse,
+				wasm.OpcodeNop,
+				wasm.OpcodeEnd,
+				wasm.OpcodeI32Const, 2, wasm.OpcodeDrop,
+				wasm.OpcodeEnd,
+			},
+			param:        0,
+			fuel:         10,
+			expRemaining: 6,
+		},
+		{
+			name: "br_if taken",
+			body: []byte{
+				wasm.OpcodeBlock, 0x40,
+				wasm.OpcodeLocalGet, 0, wasm.OpcodeBrIf, 0,
+				wasm.OpcodeI32Const, 1, wasm.OpcodeDrop,
+				wasm.OpcodeEnd,
+				wasm.OpcodeI32Const, 2, wasm.OpcodeDrop,
+				wasm.OpcodeEnd,
+			},
+			param:        1,
+			fuel:         10,
+			expRemaining: 6,
+		},
+		{
+			name: "br_if not taken",
+			body: []byte{
+				wasm.OpcodeBlock, 0x40,
+				wasm.OpcodeLocalGet, 0, wasm.OpcodeBrIf, 0,
+				wasm.OpcodeI32Const, 1, wasm.OpcodeDrop,
+				wasm.OpcodeEnd,
+				wasm.OpcodeI32Const, 2, wasm.OpcodeDrop,
+				wasm.OpcodeEnd,
+			},
+			param:        0,
+			fuel:         10,
+			expRemaining: 4,
+		},
+		{
+			// The instructions before the end of the block are consumed, but not the ones after it.
+			name: "br_if out of fuel",
+			body: []byte{
+				wasm.OpcodeBlock, 0x40,
+				wasm.OpcodeLocalGet, 0, wasm.OpcodeBrIf, 0,
+				wasm.OpcodeI32Const, 1, wasm.OpcodeDrop,
+				wasm.OpcodeEnd,
+				wasm.OpcodeI32Const, 2, wasm.OpcodeDrop,
+				wasm.OpcodeEnd,
+			},
+			param:        0,
+			fuel:         5,
+			expRemaining: 1,
+			expOutOfFuel: true,
+		},
+		{
+			name: "br_table",
+			body: []byte{
+				wasm.OpcodeBlock, 0x40,
+				wasm.OpcodeBlock, 0x40,
+				wasm.OpcodeLocalGet, 0, wasm.OpcodeBrTable, 1, 0, 1,
+				wasm.OpcodeEnd,
+				wasm.OpcodeI32Const, 1, wasm.OpcodeDrop,
+				wasm.OpcodeEnd,
+				wasm.OpcodeI32Const, 2, wasm.OpcodeDrop,
+				wasm.OpcodeEnd,
+			},
+			param:        0,
+			fuel:         10,
+			expRemaining: 4,
+		},
+		{
+			name: "br_table default",
+			body: []byte{
+				wasm.OpcodeBlock, 0x40,
+				wasm.OpcodeBlock, 0x40,
+				wasm.OpcodeLocalGet, 0, wasm.OpcodeBrTable, 1, 0, 1,
+				wasm.OpcodeEnd,
+				wasm.OpcodeI32Const, 1, wasm.OpcodeDrop,
+				wasm.OpcodeEnd,
+				wasm.OpcodeI32Const, 2, wasm.OpcodeDrop,
+				wasm.OpcodeEnd,
+			},
+			param:        1,
+			fuel:         10,
+			expRemaining: 6,
+		},
+	}
+
+	for name, config := range configs {
+		config := config
+		t.Run(name, func(t *testing.T) {
+			r := wazero.NewRuntimeWithConfig(testCtx, config)
+			defer r.Close(testCtx)
+
+			for _, tt := range tests {
+				tc := tt
+				t.Run(tc.name, func(t *testing.T) {
+					bin := binaryencoding.EncodeModule(&wasm.Module{
+						TypeSection:     []wasm.FunctionType{{Params: []wasm.ValueType{wasm.ValueTypeI32}}},
+						FunctionSection: []wasm.Index{0},
+						CodeSection:     []wasm.Code{{Body: tc.body}},
+						ExportSection:   []wasm.Export{{Name: "f", Type: wasm.ExternTypeFunc, Index: 0}},
+					})
+					compiled, err := r.CompileModule(experimental.WithFuelMetering(testCtx), bin)
+					require.NoError(t, err)
+					mod, err := r.InstantiateModule(testCtx, compiled, wazero.NewModuleConfig().WithName(tc.name))
+					require.NoError(t, err)
+
+					require.NoError(t, experimental.AddFuel(mod, tc.fuel))
+					_, err = mod.ExportedFunction("f").Call(testCtx, tc.param)
+					if tc.expOutOfFuel {
+						require.ErrorIs(t, err, experimental.ErrOutOfFuel)
+					} else {
+						require.NoError(t, err)
+					}
+					remaining, err := experimental.RemainingFuel(mod)
+					require.NoError(t, err)
+					require.Equal(t, tc.expRemaining, remaining)
+				})
+			}
+		})
+	}
+}
+
+func TestFuelMetering_Disabled(t *testing.T) {
+	r := wazero.NewRuntimeWithConfig(testCtx, wazero.NewRuntimeConfigInterpreter())
+	defer r.Close(testCtx)
+
+	_, err := r.NewHostModuleBuilder("env").NewFunctionBuilder().
+		WithFunc(func() {}).Export("observe").Instantiate(testCtx)
+	require.NoError(t, err)
+
+	mod, err := r.Instantiate(testCtx, fuelWasm)
+	require.NoError(t, err)
+
+	// Functions of modules compiled without fuel metering don't consume fuel.
+	_, err = mod.ExportedFunction("count").Call(testCtx, 10)
+	require.NoError(t, err)
+	remaining, err := experimental.RemainingFuel(mod)
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), remaining)
+}
+
+func TestAddFuel(t *testing.T) {
+	r := wazero.NewRuntime(testCtx)
+	defer r.Close(testCtx)
+
+	mod, err := r.Instantiate(testCtx, binaryencoding.EncodeModule(&wasm.Module{}))
+	require.NoError(t, err)
+
+	require.NoError(t, experimental.AddFuel(mod, 1<<62))
+	require.NoError(t, experimental.AddFuel(mod, 1<<62))
+	remaining, err := experimental.RemainingFuel(mod)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1<<63-1), remaining) // saturated
+
+	err = experimental.AddFuel(wazerotest.NewModule(nil), 1)
+	require.EqualError(t, err, "module does not support fuel")
+	_, err = experimental.RemainingFuel(wazerotest.NewModule(nil))
+	require.EqualError(t, err, "module does not support fuel")
+}
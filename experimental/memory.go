@@ -0,0 +1,78 @@
+package experimental
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+)
+
+// MemoryAllocator allocates the backing of each memory defined by a module.
+// Configure it with wazero.RuntimeConfig WithMemoryAllocator.
+//
+// Note: This is experimental, and likely to change. Do not expose this in
+// shared libraries as it can cause version locks.
+type MemoryAllocator interface {
+	// Allocate returns a new LinearMemory, which will never be reallocated to
+	// more than max bytes. capacity is the number of bytes the memory is
+	// expected to grow to, which allocators can use to reserve space.
+	//
+	// An error fails the instantiation of the module defining the memory.
+	Allocate(capacity, max uint64) (LinearMemory, error)
+}
+
+// LinearMemory is the backing of a memory allocated by a MemoryAllocator.
+type LinearMemory interface {
+	// Reallocate returns the memory resized to size bytes, which must preserve
+	// the existing contents and zero the rest. It returns nil if the memory
+	// cannot grow, which fails memory.grow.
+	//
+	// The returned slice is used until the next call to Reallocate, so it can
+	// either be a view of the same backing or a copy.
+	Reallocate(size uint64) []byte
+
+	// Free releases the memory once it is unreachable. This can be after the
+	// module defining it is closed, as modules importing the memory and host
+	// functions can still use it.
+	Free()
+}
+
+// NewMmapMemoryAllocator returns a MemoryAllocator, which reserves virtual
+// memory up to the max of each memory, so 4GiB for a 32-bit memory without
+// max, or more for a 64-bit memory. The reservation is made accessible as the
+// memory grows, so memory.grow never copies, and the inaccessible rest of the
+// reservation acts as guard pages. As the memory isn't allocated by Go, it
+// doesn't add to the GC pressure either.
+//
+// Allocate returns an error if the address space can't fit the reservation,
+// so set the max of 64-bit memories, or lower it with
+// wazero.RuntimeConfig WithMemoryLimitPages.
+//
+// This is only supported on 64-bit Linux, macOS and FreeBSD. Elsewhere,
+// Allocate returns an error.
+func NewMmapMemoryAllocator() MemoryAllocator {
+	return mmapMemoryAllocator{}
+}
+
+type mmapMemoryAllocator struct{}
+
+// mmapMinReservation is the size reserved for a memory whose max is zero, as
+// mmap can't reserve zero bytes.
+const mmapMinReservation = 1 << 16
+
+// Allocate implements MemoryAllocator.Allocate
+func (mmapMemoryAllocator) Allocate(_, max uint64) (LinearMemory, error) {
+	if runtime.GOARCH != "amd64" && runtime.GOARCH != "arm64" {
+		return nil, errors.New("mmap memory is only supported on 64-bit platforms")
+	}
+	reservation := max
+	if reservation < mmapMinReservation {
+		reservation = mmapMinReservation
+	}
+	mem, err := platform.ReserveLinearMemory(reservation)
+	if err != nil {
+		return nil, fmt.Errorf("reserve %d bytes: %w", reservation, err)
+	}
+	return mem, nil
+}
@@ -0,0 +1,94 @@
+package experimental_test
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/internal/platform"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// growWasm exports a memory, and "grow" which grows it by the given number of
+// pages.
+var growWasm = binaryencoding.EncodeModule(&wasm.Module{
+	TypeSection:     []wasm.FunctionType{{Params: []wasm.ValueType{wasm.ValueTypeI32}, Results: []wasm.ValueType{wasm.ValueTypeI32}}},
+	FunctionSection: []wasm.Index{0},
+	MemorySection:   []wasm.Memory{{Min: 1, Max: 10, IsMaxEncoded: true}},
+	CodeSection: []wasm.Code{{Body: []byte{
+		wasm.OpcodeLocalGet, 0,
+		wasm.OpcodeMemoryGrow, 0,
+		wasm.OpcodeEnd,
+	}}},
+	ExportSection: []wasm.Export{
+		{Name: "grow", Type: wasm.ExternTypeFunc, Index: 0},
+		{Name: "memory", Type: wasm.ExternTypeMemory, Index: 0},
+	},
+})
+
+func TestNewMmapMemoryAllocator(t *testing.T) {
+	switch runtime.GOOS {
+	case "linux", "darwin", "freebsd":
+	default:
+		t.Skip()
+	}
+	if runtime.GOARCH != "amd64" && runtime.GOARCH != "arm64" {
+		t.Skip()
+	}
+
+	configs := map[string]wazero.RuntimeConfig{"interpreter": wazero.NewRuntimeConfigInterpreter()}
+	if platform.CompilerSupported() {
+		configs["compiler"] = wazero.NewRuntimeConfigCompiler()
+	}
+
+	for name, config := range configs {
+		config := config.WithMemoryAllocator(experimental.NewMmapMemoryAllocator())
+		t.Run(name, func(t *testing.T) {
+			r := wazero.NewRuntimeWithConfig(testCtx, config)
+			defer r.Close(testCtx)
+
+			mod, err := r.Instantiate(testCtx, growWasm)
+			require.NoError(t, err)
+			mem := mod.Memory()
+			require.True(t, mem.WriteByte(wasm.MemoryPageSize-1, 1))
+			before, ok := mem.Read(0, 1)
+			require.True(t, ok)
+
+			res, err := mod.ExportedFunction("grow").Call(testCtx, 2)
+			require.NoError(t, err)
+			require.Equal(t, uint64(1), res[0])
+			require.Equal(t, 3*wasm.MemoryPageSize, mem.Size())
+
+			// Growing neither moved nor lost the contents of the memory.
+			after, ok := mem.Read(0, 1)
+			require.True(t, ok)
+			require.Equal(t, &before[0], &after[0])
+			v, ok := mem.ReadByte(wasm.MemoryPageSize - 1)
+			require.True(t, ok)
+			require.Equal(t, byte(1), v)
+
+			// Growing beyond the max fails as usual.
+			res, err = mod.ExportedFunction("grow").Call(testCtx, 8)
+			require.NoError(t, err)
+			require.Equal(t, uint64(0xffffffff), res[0])
+
+			// The memory can still be used after the module is closed, as it
+			// is only freed once unreachable.
+			require.NoError(t, mod.Close(testCtx))
+			require.True(t, mem.WriteByte(wasm.MemoryPageSize-1, 2))
+			v, ok = mem.ReadByte(wasm.MemoryPageSize - 1)
+			require.True(t, ok)
+			require.Equal(t, byte(2), v)
+		})
+	}
+
+	// The reservation is sized from the max, so it can back a 64-bit memory.
+	mem, err := experimental.NewMmapMemoryAllocator().Allocate(0, 8<<30)
+	require.NoError(t, err)
+	defer mem.Free()
+	require.Equal(t, int(wasm.MemoryPageSize), len(mem.Reallocate(uint64(wasm.MemoryPageSize))))
+	require.Nil(t, mem.Reallocate(8<<30+uint64(wasm.MemoryPageSize)))
+}
@@ -0,0 +1,82 @@
+package experimental
+
+import (
+	"context"
+	"errors"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/snapshot"
+)
+
+// ModuleSnapshot is the state of an instantiated module, as returned by
+// Snapshot. It includes the contents of the memory, mutable globals and tables
+// defined by the module. Imported memory, globals and tables are not included.
+//
+// A ModuleSnapshot implements encoding.BinaryMarshaler and
+// encoding.BinaryUnmarshaler, so it can be persisted, for example next to a
+// compilation cache.
+//
+// Note: This is experimental, and likely to change. Do not expose this in
+// shared libraries as it can cause version locks.
+type ModuleSnapshot struct {
+	s *snapshot.Snapshot
+}
+
+// Snapshot captures the state of the given module, so that it can later be
+// instantiated with WithSnapshot without re-running its start functions.
+//
+// An error is returned if the module is closed, isn't a module instantiated
+// by wazero, or holds state which cannot be restored, such as a non-null
+// externref.
+//
+// Note: Do not call this while a function of the module is executing, as the
+// snapshot may be inconsistent.
+func Snapshot(mod api.Module) (*ModuleSnapshot, error) {
+	s, ok := mod.(snapshot.Snapshotter)
+	if !ok {
+		return nil, errors.New("module does not support snapshots")
+	}
+	snap, err := s.Snapshot()
+	if err != nil {
+		return nil, err
+	}
+	return &ModuleSnapshot{s: snap}, nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (s *ModuleSnapshot) MarshalBinary() ([]byte, error) {
+	if s.s == nil {
+		return nil, errors.New("empty snapshot")
+	}
+	return s.s.Encode(), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (s *ModuleSnapshot) UnmarshalBinary(data []byte) error {
+	snap, err := snapshot.Decode(data)
+	if err != nil {
+		return err
+	}
+	s.s = snap
+	return nil
+}
+
+// WithSnapshot registers the given snapshot into the given context.Context.
+// When the returned context is passed to wazero.Runtime InstantiateModule,
+// the module is restored from the snapshot instead of running its start
+// section and any start functions configured by wazero.ModuleConfig.
+//
+// Instantiation fails if the compiled module isn't the one the snapshot was
+// taken from, so only use the returned context to instantiate that module.
+//
+// Notes:
+//   - Imports are resolved as usual, and active data and element segments
+//     are applied to imported memory and tables as usual.
+//   - Passive data and element segments are restored as they were at
+//     instantiation, even if they were dropped before the snapshot.
+func WithSnapshot(ctx context.Context, s *ModuleSnapshot) context.Context {
+	if s != nil && s.s != nil {
+		return context.WithValue(ctx, snapshot.Key{}, s.s)
+	}
+	return ctx
+}
@@ -0,0 +1,31 @@
+package experimental_test
+
+import (
+	"testing"
+
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/experimental/wazerotest"
+	"github.com/tetratelabs/wazero/internal/snapshot"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestWithSnapshot(t *testing.T) {
+	require.Same(t, testCtx, experimental.WithSnapshot(testCtx, nil))
+	require.Same(t, testCtx, experimental.WithSnapshot(testCtx, &experimental.ModuleSnapshot{}))
+
+	var s experimental.ModuleSnapshot
+	require.NoError(t, s.UnmarshalBinary((&snapshot.Snapshot{}).Encode()))
+	decorated := experimental.WithSnapshot(testCtx, &s)
+	require.NotNil(t, snapshot.FromContext(decorated))
+}
+
+func TestSnapshot_Errors(t *testing.T) {
+	_, err := experimental.Snapshot(wazerotest.NewModule(nil))
+	require.EqualError(t, err, "module does not support snapshots")
+
+	_, err = (&experimental.ModuleSnapshot{}).MarshalBinary()
+	require.EqualError(t, err, "empty snapshot")
+
+	var s experimental.ModuleSnapshot
+	require.EqualError(t, s.UnmarshalBinary([]byte("wasm")), "invalid snapshot header")
+}
@@ -0,0 +1,46 @@
+package experimental
+
+import (
+	"errors"
+
+	"github.com/tetratelabs/wazero/api"
+)
+
+// ModuleStats describes the resources held by an instantiated module, as
+// returned by Stats.
+//
+// Note: This is experimental, and likely to change. Do not expose this in
+// shared libraries as it can cause version locks.
+type ModuleStats struct {
+	// MemorySizes are the current sizes of the memories in pages, including
+	// imported ones.
+	MemorySizes []uint32
+
+	// TableSizes are the current sizes of the tables, including imported ones.
+	TableSizes []uint32
+
+	// GlobalCount is the number of globals, including imported ones.
+	GlobalCount int
+
+	// OpenFiles is the number of open file descriptors, including the
+	// standard I/O and pre-opened directories. This is zero for closed
+	// modules.
+	OpenFiles int
+
+	// Closed is true if the module is closed, so no longer usable.
+	Closed bool
+}
+
+// Stats returns the resources held by the given module, for example one
+// returned by wazero.Runtime Modules. An error is returned if the module isn't
+// a module instantiated by wazero.
+//
+// Note: The result can be inconsistent if the module is used concurrently,
+// such as when one of its functions opens a file.
+func Stats(mod api.Module) (*ModuleStats, error) {
+	s, ok := mod.(interface{ Stats() *ModuleStats })
+	if !ok {
+		return nil, errors.New("module does not support stats")
+	}
+	return s.Stats(), nil
+}
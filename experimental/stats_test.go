@@ -0,0 +1,68 @@
+package experimental_test
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/experimental/wazerotest"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// statsWasm defines two memories, a table and two globals.
+var statsWasm = binaryencoding.EncodeModule(&wasm.Module{
+	MemorySection: []wasm.Memory{{Min: 2, Max: 3, IsMaxEncoded: true}, {Min: 1, Max: 1, IsMaxEncoded: true}},
+	TableSection:  []wasm.Table{{Min: 5, Type: wasm.RefTypeFuncref}},
+	GlobalSection: []wasm.Global{
+		{Type: wasm.GlobalType{ValType: wasm.ValueTypeI32}, Init: wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: []byte{0}}},
+		{Type: wasm.GlobalType{ValType: wasm.ValueTypeI64}, Init: wasm.ConstantExpression{Opcode: wasm.OpcodeI64Const, Data: []byte{0}}},
+	},
+})
+
+func TestStats(t *testing.T) {
+	r := wazero.NewRuntimeWithConfig(testCtx, wazero.NewRuntimeConfig().
+		WithCoreFeatures(api.CoreFeaturesV2|api.CoreFeatureMultiMemory))
+	defer r.Close(testCtx)
+
+	host, err := r.NewHostModuleBuilder("host").Instantiate(testCtx)
+	require.NoError(t, err)
+	stats, err := experimental.Stats(host)
+	require.NoError(t, err)
+	require.Equal(t, &experimental.ModuleStats{MemorySizes: []uint32{}, TableSizes: []uint32{}, OpenFiles: 3}, stats)
+
+	mod, err := r.InstantiateWithConfig(testCtx, statsWasm, wazero.NewModuleConfig().
+		WithFSConfig(wazero.NewFSConfig().WithFSMount(fstest.MapFS{}, "/")))
+	require.NoError(t, err)
+
+	// The standard I/O and the pre-opened root are open.
+	stats, err = experimental.Stats(mod)
+	require.NoError(t, err)
+	require.Equal(t, &experimental.ModuleStats{
+		MemorySizes: []uint32{2, 1},
+		TableSizes:  []uint32{5},
+		GlobalCount: 2,
+		OpenFiles:   4,
+	}, stats)
+
+	_, ok := mod.Memory().Grow(1)
+	require.True(t, ok)
+	require.NoError(t, mod.Close(testCtx))
+
+	stats, err = experimental.Stats(mod)
+	require.NoError(t, err)
+	require.Equal(t, &experimental.ModuleStats{
+		MemorySizes: []uint32{3, 1},
+		TableSizes:  []uint32{5},
+		GlobalCount: 2,
+		Closed:      true,
+	}, stats)
+}
+
+func TestStats_Errors(t *testing.T) {
+	_, err := experimental.Stats(wazerotest.NewModule(nil))
+	require.EqualError(t, err, "module does not support stats")
+}
@@ -99,12 +99,27 @@ func (m *Module) ExportedMemoryDefinitions() map[string]api.MemoryDefinition {
 	return m.exportedMemoryDefinitions
 }
 
+// ExportedTable implements the same method as documented on api.Module.
+func (m *Module) ExportedTable(string) api.Table {
+	return nil
+}
+
+// ExportedTableDefinitions implements the same method as documented on api.Module.
+func (m *Module) ExportedTableDefinitions() map[string]api.TableDefinition {
+	return map[string]api.TableDefinition{}
+}
+
 // ExportedGlobal implements the same method as documented on api.Module.
 func (m *Module) ExportedGlobal(name string) api.Global {
 	m.once.Do(m.initialize)
 	return m.exportedGlobals[name]
 }
 
+// ExportedTag implements the same method as documented on api.Module.
+func (m *Module) ExportedTag(string) api.Tag {
+	return nil
+}
+
 // Close implements the same method as documented on api.Closer.
 func (m *Module) Close(ctx context.Context) error {
 	return m.CloseWithExitCode(ctx, 0)
@@ -133,6 +148,21 @@ func (m *Module) Global(i int) api.Global {
 	return m.Globals[i]
 }
 
+// NumTable implements the same method as documented on experimental.InternalModule.
+func (m *Module) NumTable() int {
+	return 0
+}
+
+// Table implements the same method as documented on experimental.InternalModule.
+func (m *Module) Table(i int) api.Table {
+	panic(fmt.Errorf("table index out of bounds: %d", i))
+}
+
+// LookupFunction implements the same method as documented on experimental.InternalModule.
+func (m *Module) LookupFunction(uint64, []api.ValueType, []api.ValueType) (api.Function, error) {
+	return nil, errors.New("tables are not supported")
+}
+
 // Below are undocumented extensions
 
 func (m *Module) NumFunction() int {
@@ -624,11 +654,121 @@ func (m *Memory) WriteString(offset uint32, value string) bool {
 	return true
 }
 
+func (m *Memory) Size64() uint64 {
+	return uint64(len(m.Bytes))
+}
+
+func (m *Memory) ReadByte64(offset uint64) (byte, bool) {
+	if m.isOutOfRange64(offset, 1) {
+		return 0, false
+	}
+	return m.Bytes[offset], true
+}
+
+func (m *Memory) ReadUint16Le64(offset uint64) (uint16, bool) {
+	if m.isOutOfRange64(offset, 2) {
+		return 0, false
+	}
+	return binary.LittleEndian.Uint16(m.Bytes[offset:]), true
+}
+
+func (m *Memory) ReadUint32Le64(offset uint64) (uint32, bool) {
+	if m.isOutOfRange64(offset, 4) {
+		return 0, false
+	}
+	return binary.LittleEndian.Uint32(m.Bytes[offset:]), true
+}
+
+func (m *Memory) ReadUint64Le64(offset uint64) (uint64, bool) {
+	if m.isOutOfRange64(offset, 8) {
+		return 0, false
+	}
+	return binary.LittleEndian.Uint64(m.Bytes[offset:]), true
+}
+
+func (m *Memory) ReadFloat32Le64(offset uint64) (float32, bool) {
+	v, ok := m.ReadUint32Le64(offset)
+	return math.Float32frombits(v), ok
+}
+
+func (m *Memory) ReadFloat64Le64(offset uint64) (float64, bool) {
+	v, ok := m.ReadUint64Le64(offset)
+	return math.Float64frombits(v), ok
+}
+
+func (m *Memory) Read64(offset, length uint64) ([]byte, bool) {
+	if m.isOutOfRange64(offset, length) {
+		return nil, false
+	}
+	return m.Bytes[offset : offset+length : offset+length], true
+}
+
+func (m *Memory) WriteByte64(offset uint64, value byte) bool {
+	if m.isOutOfRange64(offset, 1) {
+		return false
+	}
+	m.Bytes[offset] = value
+	return true
+}
+
+func (m *Memory) WriteUint16Le64(offset uint64, value uint16) bool {
+	if m.isOutOfRange64(offset, 2) {
+		return false
+	}
+	binary.LittleEndian.PutUint16(m.Bytes[offset:], value)
+	return true
+}
+
+func (m *Memory) WriteUint32Le64(offset uint64, value uint32) bool {
+	if m.isOutOfRange64(offset, 4) {
+		return false
+	}
+	binary.LittleEndian.PutUint32(m.Bytes[offset:], value)
+	return true
+}
+
+func (m *Memory) WriteUint64Le64(offset uint64, value uint64) bool {
+	if m.isOutOfRange64(offset, 8) {
+		return false
+	}
+	binary.LittleEndian.PutUint64(m.Bytes[offset:], value)
+	return true
+}
+
+func (m *Memory) WriteFloat32Le64(offset uint64, value float32) bool {
+	return m.WriteUint32Le64(offset, math.Float32bits(value))
+}
+
+func (m *Memory) WriteFloat64Le64(offset uint64, value float64) bool {
+	return m.WriteUint64Le64(offset, math.Float64bits(value))
+}
+
+func (m *Memory) Write64(offset uint64, value []byte) bool {
+	if m.isOutOfRange64(offset, uint64(len(value))) {
+		return false
+	}
+	copy(m.Bytes[offset:], value)
+	return true
+}
+
+func (m *Memory) WriteString64(offset uint64, value string) bool {
+	if m.isOutOfRange64(offset, uint64(len(value))) {
+		return false
+	}
+	copy(m.Bytes[offset:], value)
+	return true
+}
+
 func (m *Memory) isOutOfRange(offset, length uint32) bool {
 	size := m.Size()
 	return offset >= size || length > size || offset > (size-length)
 }
 
+func (m *Memory) isOutOfRange64(offset, length uint64) bool {
+	size := m.Size64()
+	return offset >= size || length > size || offset > (size-length)
+}
+
 type memoryDefinition struct {
 	internalapi.WazeroOnlyType
 	memory *Memory
@@ -376,7 +376,8 @@ func Test_readAssemblyScriptString(t *testing.T) {
 		tc := tt
 
 		t.Run(tc.name, func(t *testing.T) {
-			mem := wasm.NewMemoryInstance(&wasm.Memory{Min: 1, Cap: 1, Max: 1})
+			mem, err := wasm.NewMemoryInstance(&wasm.Memory{Min: 1, Cap: 1, Max: 1}, nil)
+			require.NoError(t, err)
 			tc.memory(mem)
 
 			s, ok := readAssemblyScriptString(mem, uint32(tc.offset))
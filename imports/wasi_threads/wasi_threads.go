@@ -0,0 +1,173 @@
+// Package wasi_threads contains the Go-defined "thread-spawn" function
+// imported by guests compiled for wasi-threads, e.g. wasm32-wasi-threads,
+// under the module name ModuleName.
+//
+// Each spawned thread is a new instance of the same guest, which shares its
+// imports, notably the shared memory, and its system context such as open
+// files. The new instance calls ThreadStartName in a new goroutine.
+//
+// e.g. Call Instantiate before instantiating any wasm binary that imports
+// "wasi" "thread-spawn", and configure how many threads can run at the same
+// time via wazero.ModuleConfig WithMaxThreads.
+//
+//	ctx := context.Background()
+//	r := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().
+//		WithCoreFeatures(api.CoreFeaturesV2|api.CoreFeatureThreads))
+//	defer r.Close(ctx) // This closes everything this Runtime created.
+//
+//	wasi_snapshot_preview1.MustInstantiate(ctx, r)
+//	wasi_threads.MustInstantiate(ctx, r)
+//	// Instantiate the module exporting the shared memory the guest imports.
+//	--snip--
+//	mod, _ := r.InstantiateWithConfig(ctx, wasm, wazero.NewModuleConfig().
+//		WithMaxThreads(8))
+//
+// # Notes
+//
+//   - Closing any thread, for example via "proc_exit" in
+//     "wasi_snapshot_preview1", or trapping in any thread closes all of them
+//     with the same exit code. This is also the case when the main module
+//     returns from "_start" and is closed.
+//   - A closed thread only stops when it calls a function, unless compiled
+//     with wazero.RuntimeConfig WithCloseOnContextDone, and never stops while
+//     waiting via "memory.atomic.wait32" or "memory.atomic.wait64".
+//
+// See https://github.com/WebAssembly/wasi-threads
+package wasi_threads
+
+import (
+	"context"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// ModuleName is the module name the ThreadSpawnName function is exported
+// into.
+const ModuleName = "wasi"
+
+// ThreadSpawnName is the name of the function which spawns a thread.
+const ThreadSpawnName = "thread-spawn"
+
+// ThreadStartName is the name of the function the guest exports, which is
+// called on each spawned thread with its ID and the argument passed to
+// ThreadSpawnName.
+const ThreadStartName = "wasi_thread_start"
+
+// maxThreadID is the largest thread ID, as the upper bits are reserved.
+//
+// See https://github.com/WebAssembly/wasi-threads#design-choice-thread-ids
+const maxThreadID = 0x1fffffff
+
+const i32 = wasm.ValueTypeI32
+
+// MustInstantiate calls Instantiate or panics on error.
+//
+// This is a simpler function for those who know the module ModuleName is not
+// already instantiated, and don't need to unload it.
+func MustInstantiate(ctx context.Context, r wazero.Runtime) {
+	if _, err := Instantiate(ctx, r); err != nil {
+		panic(err)
+	}
+}
+
+// Instantiate instantiates the ModuleName module into the runtime.
+//
+// # Notes
+//
+//   - Failure cases are documented on wazero.Runtime InstantiateModule.
+//   - Closing the wazero.Runtime has the same effect as closing the result.
+func Instantiate(ctx context.Context, r wazero.Runtime) (api.Closer, error) {
+	builder := r.NewHostModuleBuilder(ModuleName)
+	NewFunctionExporter().ExportFunctions(builder)
+	return builder.Instantiate(ctx)
+}
+
+// FunctionExporter exports the ThreadSpawnName function into a
+// wazero.HostModuleBuilder.
+//
+// # Notes
+//
+//   - This is an interface for decoupling, not third-party implementations.
+//     All implementations are in wazero.
+type FunctionExporter interface {
+	// ExportFunctions builds functions to export with a wazero.HostModuleBuilder
+	// named ModuleName.
+	ExportFunctions(wazero.HostModuleBuilder)
+}
+
+// NewFunctionExporter returns a new FunctionExporter.
+func NewFunctionExporter() FunctionExporter {
+	return &functionExporter{}
+}
+
+type functionExporter struct{}
+
+// ExportFunctions implements FunctionExporter.ExportFunctions
+func (functionExporter) ExportFunctions(builder wazero.HostModuleBuilder) {
+	exporter := builder.(wasm.HostFuncExporter)
+	exporter.ExportHostFunc(threadSpawn)
+}
+
+// threadSpawn is the function named ThreadSpawnName, which instantiates the
+// calling module as a new thread, and calls ThreadStartName on it in a new
+// goroutine.
+//
+// # Parameters
+//
+//   - startArg: opaque argument passed to ThreadStartName.
+//
+// Result (i32) is the positive ID of the new thread, or negative if the
+// thread couldn't be spawned, for example due to the limit configured by
+// wazero.ModuleConfig WithMaxThreads.
+//
+// See https://github.com/WebAssembly/wasi-threads#api
+var threadSpawn = &wasm.HostFunc{
+	ExportName:  ThreadSpawnName,
+	Name:        ThreadSpawnName,
+	ParamTypes:  []api.ValueType{i32},
+	ParamNames:  []string{"start_arg"},
+	ResultTypes: []api.ValueType{i32},
+	ResultNames: []string{"tid"},
+	Code:        wasm.Code{GoFunc: api.GoModuleFunc(threadSpawnFn)},
+}
+
+func threadSpawnFn(ctx context.Context, mod api.Module, stack []uint64) {
+	startArg := uint32(stack[0])
+	stack[0] = api.EncodeI32(-1)
+
+	if !isThreadStart(mod.ExportedFunctionDefinitions()[ThreadStartName]) {
+		return
+	}
+
+	thread, tid, err := mod.(*wasm.ModuleInstance).SpawnThread(ctx)
+	if err != nil {
+		return
+	} else if tid > maxThreadID {
+		_ = thread.ExitThread(ctx)
+		return
+	}
+
+	start := thread.ExportedFunction(ThreadStartName)
+	go func() {
+		if _, err := start.Call(ctx, uint64(tid), uint64(startArg)); err != nil {
+			// Closing the thread closes all the others. When the thread
+			// exited, such as via proc_exit, they are already closed.
+			_ = thread.CloseWithExitCode(ctx, 1)
+			return
+		}
+		_ = thread.ExitThread(ctx)
+	}()
+	stack[0] = uint64(tid)
+}
+
+// isThreadStart returns true if the function has the signature of
+// ThreadStartName: (tid i32, start_arg i32) -> ().
+func isThreadStart(def api.FunctionDefinition) bool {
+	if def == nil {
+		return false
+	}
+	params := def.ParamTypes()
+	return len(params) == 2 && params[0] == i32 && params[1] == i32 && len(def.ResultTypes()) == 0
+}
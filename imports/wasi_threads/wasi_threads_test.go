@@ -0,0 +1,198 @@
+package wasi_threads
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+// testCtx is an arbitrary, non-default context. Non-nil also prevents linter errors.
+var testCtx = context.WithValue(context.Background(), struct{}{}, "arbitrary")
+
+// envWasm exports the shared memory imported by threadsWasm.
+var envWasm = binaryencoding.EncodeModule(&wasm.Module{
+	MemorySection: []wasm.Memory{{Min: 1, Cap: 1, Max: 1, IsMaxEncoded: true, IsShared: true}},
+	ExportSection: []wasm.Export{{Name: "memory", Type: wasm.ExternTypeMemory, Index: 0}},
+})
+
+// threadsWasm spawns threads which store their ID at the address passed as
+// the start argument, and then wait to be woken at the next address. A thread
+// started with the address zero traps instead.
+var threadsWasm = func() []byte {
+	const memarg = 0x2 // align=4, offset=0
+	waitForever := []byte{
+		wasm.OpcodeI32Const, 0, wasm.OpcodeI64Const, 0x7f, // -1 is no timeout.
+		wasm.OpcodeAtomicPrefix, wasm.OpcodeAtomicMemoryWait32, memarg, 0, wasm.OpcodeDrop,
+	}
+	notify := []byte{wasm.OpcodeI32Const, 1, wasm.OpcodeAtomicPrefix, wasm.OpcodeAtomicMemoryNotify, memarg, 0, wasm.OpcodeDrop}
+	concat := func(bodies ...[]byte) (ret []byte) {
+		for _, b := range bodies {
+			ret = append(ret, b...)
+		}
+		return
+	}
+
+	return binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection: []wasm.FunctionType{
+			{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32}, ParamNumInUint64: 1, ResultNumInUint64: 1},
+			{Params: []wasm.ValueType{i32, i32}, ParamNumInUint64: 2},
+			{Params: []wasm.ValueType{i32}, ParamNumInUint64: 1},
+		},
+		ImportSection: []wasm.Import{
+			{Type: wasm.ExternTypeFunc, Module: ModuleName, Name: ThreadSpawnName, DescFunc: 0},
+			{Type: wasm.ExternTypeMemory, Module: "env", Name: "memory", DescMem: &wasm.Memory{
+				Min: 1, Cap: 1, Max: 1, IsMaxEncoded: true, IsShared: true,
+			}},
+		},
+		FunctionSection: []wasm.Index{0, 1, 0, 2},
+		CodeSection: []wasm.Code{
+			{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeCall, 0, wasm.OpcodeEnd}},
+			{Body: concat(
+				[]byte{wasm.OpcodeLocalGet, 1, wasm.OpcodeI32Eqz, wasm.OpcodeIf, 0x40, wasm.OpcodeUnreachable, wasm.OpcodeEnd},
+				[]byte{wasm.OpcodeLocalGet, 1, wasm.OpcodeLocalGet, 0, wasm.OpcodeAtomicPrefix, wasm.OpcodeAtomicI32Store, memarg, 0},
+				[]byte{wasm.OpcodeLocalGet, 1}, notify,
+				[]byte{wasm.OpcodeLocalGet, 1, wasm.OpcodeI32Const, 4, wasm.OpcodeI32Add}, waitForever,
+				[]byte{wasm.OpcodeEnd},
+			)},
+			{Body: concat(
+				[]byte{wasm.OpcodeLocalGet, 0}, waitForever,
+				[]byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeAtomicPrefix, wasm.OpcodeAtomicI32Load, memarg, 0, wasm.OpcodeEnd},
+			)},
+			{Body: concat(
+				[]byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeI32Const, 1, wasm.OpcodeAtomicPrefix, wasm.OpcodeAtomicI32Store, memarg, 0},
+				[]byte{wasm.OpcodeLocalGet, 0}, notify,
+				[]byte{wasm.OpcodeEnd},
+			)},
+		},
+		ExportSection: []wasm.Export{
+			{Name: "spawn", Type: wasm.ExternTypeFunc, Index: 1},
+			{Name: ThreadStartName, Type: wasm.ExternTypeFunc, Index: 2},
+			{Name: "wait", Type: wasm.ExternTypeFunc, Index: 3},
+			{Name: "wake", Type: wasm.ExternTypeFunc, Index: 4},
+		},
+	})
+}()
+
+func TestThreadSpawn(t *testing.T) {
+	tests := []struct {
+		name   string
+		config wazero.RuntimeConfig
+	}{
+		{name: "interpreter", config: wazero.NewRuntimeConfigInterpreter()},
+		{name: "default", config: wazero.NewRuntimeConfig()},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			testThreadSpawn(t, tc.config)
+		})
+	}
+}
+
+func testThreadSpawn(t *testing.T, config wazero.RuntimeConfig) {
+	r := wazero.NewRuntimeWithConfig(testCtx, config.WithCoreFeatures(api.CoreFeaturesV2|api.CoreFeatureThreads))
+	defer r.Close(testCtx)
+
+	MustInstantiate(testCtx, r)
+	_, err := r.InstantiateWithConfig(testCtx, envWasm, wazero.NewModuleConfig().WithName("env"))
+	require.NoError(t, err)
+
+	compiled, err := r.CompileModule(testCtx, threadsWasm)
+	require.NoError(t, err)
+
+	instantiate := func(t *testing.T, maxThreads uint32) api.Module {
+		mod, err := r.InstantiateModule(testCtx, compiled, wazero.NewModuleConfig().WithName("").WithMaxThreads(maxThreads))
+		require.NoError(t, err)
+		return mod
+	}
+	call := func(t *testing.T, mod api.Module, name string, params ...uint64) int32 {
+		results, err := mod.ExportedFunction(name).Call(testCtx, params...)
+		require.NoError(t, err)
+		if len(results) == 0 {
+			return 0
+		}
+		return api.DecodeI32(results[0])
+	}
+
+	t.Run("spawn", func(t *testing.T) {
+		mod := instantiate(t, 2)
+		defer mod.Close(testCtx)
+
+		require.Equal(t, int32(1), call(t, mod, "spawn", 8))
+		require.Equal(t, int32(2), call(t, mod, "spawn", 16))
+		require.Equal(t, int32(1), call(t, mod, "wait", 8))
+		require.Equal(t, int32(2), call(t, mod, "wait", 16))
+
+		call(t, mod, "wake", 12)
+		call(t, mod, "wake", 20)
+	})
+
+	t.Run("limit", func(t *testing.T) {
+		mod := instantiate(t, 1)
+		defer mod.Close(testCtx)
+
+		require.Equal(t, int32(1), call(t, mod, "spawn", 24))
+		require.Equal(t, int32(1), call(t, mod, "wait", 24))
+		require.Equal(t, int32(-1), call(t, mod, "spawn", 32))
+
+		// Once the thread exits, another can be spawned.
+		call(t, mod, "wake", 28)
+		eventually(t, func() bool {
+			return call(t, mod, "spawn", 40) == 2
+		})
+		require.Equal(t, int32(2), call(t, mod, "wait", 40))
+		call(t, mod, "wake", 44)
+	})
+
+	t.Run("disallowed by default", func(t *testing.T) {
+		mod := instantiate(t, 0)
+		defer mod.Close(testCtx)
+
+		require.Equal(t, int32(-1), call(t, mod, "spawn", 48))
+	})
+
+	t.Run("close closes threads", func(t *testing.T) {
+		mod := instantiate(t, 1)
+		modules := len(r.Modules())
+
+		require.Equal(t, int32(1), call(t, mod, "spawn", 56))
+		require.Equal(t, int32(1), call(t, mod, "wait", 56))
+		require.Equal(t, modules+1, len(r.Modules()))
+
+		require.NoError(t, mod.Close(testCtx))
+		require.Equal(t, modules-1, len(r.Modules()))
+
+		// Let the closed thread return, as closing doesn't wake it.
+		mem := r.Module("env").Memory().(*wasm.MemoryInstance)
+		require.NoError(t, mem.AtomicStore(60, 4, 1))
+		_, err := mem.AtomicNotify(60, 1)
+		require.NoError(t, err)
+	})
+
+	t.Run("trap in thread closes all", func(t *testing.T) {
+		mod := instantiate(t, 1)
+
+		require.Equal(t, int32(1), call(t, mod, "spawn", 0))
+		eventually(t, mod.IsClosed)
+
+		_, err := mod.ExportedFunction("spawn").Call(testCtx, 64)
+		require.Equal(t, sys.NewExitError(1), err)
+	})
+}
+
+// eventually fails the test unless the condition is true within a second, as threads run in other goroutines.
+func eventually(t *testing.T, condition func() bool) {
+	for deadline := time.Now().Add(time.Second); !condition(); time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met within a second")
+		}
+	}
+}
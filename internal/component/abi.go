@@ -0,0 +1,901 @@
+package component
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/tetratelabs/wazero/api"
+)
+
+// utf16Tag is the bit set in the length of a string encoded as UTF-16 with StringEncodingLatin1UTF16.
+const utf16Tag = 1 << 31
+
+// Options are CanonOptions resolved in an instance.
+type Options struct {
+	StringEncoding StringEncoding
+	Memory         api.Memory
+	Realloc        api.Function
+	PostReturn     api.Function
+}
+
+// Call lifts and lowers the values of one call of a lifted or lowered function, as described by the canonical ABI.
+// Lifting reads values of the core instance into Go values, and lowering writes Go values into the core instance.
+//
+// See https://github.com/WebAssembly/component-model/blob/main/design/mvp/CanonicalABI.md
+type Call struct {
+	ctx     context.Context
+	opts    *Options
+	handles *Handles
+
+	// lent are the handles borrowed to the callee for the duration of the call.
+	lent []lentHandle
+}
+
+type lentHandle struct {
+	r   *ResourceType
+	idx uint32
+	h   *handle
+}
+
+// NewCall returns a Call which uses the given options and resource tables.
+func NewCall(ctx context.Context, opts *Options, handles *Handles) *Call {
+	return &Call{ctx: ctx, opts: opts, handles: handles}
+}
+
+// End releases the handles lent to the callee which it didn't drop.
+func (c *Call) End() {
+	for _, l := range c.lent {
+		if t := c.handles.table(l.r); int(l.idx) < len(t.entries) && t.entries[l.idx] == l.h {
+			_, _, _ = c.handles.Remove(l.r, l.idx)
+		}
+	}
+	c.lent = nil
+}
+
+// LiftParams lifts the parameters of a call of a lowered function from the core parameters in stack into params.
+func (c *Call) LiftParams(ft *FuncType, stack []uint64, params []reflect.Value) error {
+	if len(ft.params.flat) > maxFlatParams {
+		return c.loadFields(ft.params, uint32(stack[0]), params)
+	}
+	r := &flatReader{vals: stack}
+	for i, p := range ft.Params {
+		if err := c.liftFlat(p.Type, r, params[i]); err != nil {
+			return fmt.Errorf("param %s: %w", p.Name, err)
+		}
+	}
+	return nil
+}
+
+// LowerResults lowers the results of a call of a lowered function into the core results in stack, or into the
+// memory pointed to by the last core parameter when they don't fit.
+func (c *Call) LowerResults(ft *FuncType, stack []uint64, results []reflect.Value) error {
+	if len(ft.results.flat) > maxFlatResults {
+		retptr := uint32(stack[len(ft.CoreParams(true))-1])
+		return c.storeFields(ft.results, results, retptr)
+	}
+	flat := make([]uint64, 0, len(ft.results.flat))
+	var err error
+	for i, r := range ft.Results {
+		if flat, err = c.lowerFlat(r.Type, results[i], flat); err != nil {
+			return err
+		}
+	}
+	copy(stack, flat)
+	return nil
+}
+
+// LowerParams lowers the parameters of a call of a lifted function into its core parameters, which are written
+// into memory allocated with realloc when they don't fit.
+func (c *Call) LowerParams(ft *FuncType, params []reflect.Value) ([]uint64, error) {
+	if len(params) != len(ft.Params) {
+		return nil, fmt.Errorf("expected %d params, but passed %d", len(ft.Params), len(params))
+	}
+	if len(ft.params.flat) > maxFlatParams {
+		ptr, err := c.alloc(ft.params.align, ft.params.size)
+		if err != nil {
+			return nil, err
+		}
+		return []uint64{uint64(ptr)}, c.storeFields(ft.params, params, ptr)
+	}
+	flat := make([]uint64, 0, len(ft.params.flat))
+	var err error
+	for i, p := range ft.Params {
+		if flat, err = c.lowerFlat(p.Type, params[i], flat); err != nil {
+			return nil, fmt.Errorf("param %s: %w", p.Name, err)
+		}
+	}
+	return flat, nil
+}
+
+// LiftResults lifts the core results of a call of a lifted function into results.
+func (c *Call) LiftResults(ft *FuncType, coreResults []uint64, results []reflect.Value) error {
+	if len(ft.results.flat) > maxFlatResults {
+		return c.loadFields(ft.results, uint32(coreResults[0]), results)
+	}
+	r := &flatReader{vals: coreResults}
+	for i, res := range ft.Results {
+		if err := c.liftFlat(res.Type, r, results[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// flatReader reads flattened values, coercing them from the joined types of a variant when payload is set.
+type flatReader struct {
+	vals []uint64
+	i    int
+}
+
+func (r *flatReader) next() uint64 {
+	v := r.vals[r.i]
+	r.i++
+	return v
+}
+
+func (c *Call) liftFlat(t *ValType, r *flatReader, dst reflect.Value) error {
+	if dst.Kind() == reflect.Interface && t.Kind != KindOwn && t.Kind != KindBorrow {
+		v := reflect.New(GoType(t)).Elem()
+		if err := c.liftFlat(t, r, v); err != nil {
+			return err
+		}
+		dst.Set(v)
+		return nil
+	}
+
+	switch t.Kind {
+	case KindString, KindList:
+		ptr, n := uint32(r.next()), uint32(r.next())
+		if t.Kind == KindString {
+			return c.loadString(ptr, n, dst)
+		}
+		return c.loadList(t.Elem, ptr, n, dst)
+	case KindRecord, KindTuple:
+		if dst.Kind() != reflect.Struct || dst.NumField() != len(t.Fields) {
+			return mismatch(t, dst)
+		}
+		for i, f := range t.Fields {
+			if err := c.liftFlat(f.Type, r, dst.Field(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case KindVariant, KindEnum, KindOption, KindResult:
+		disc := uint32(r.next())
+		payloadStart := r.i
+		r.i += len(t.flat) - 1
+		return c.setCase(t, dst, disc, func(ct *ValType, payload reflect.Value) error {
+			vals := make([]uint64, len(ct.flat))
+			for i, want := range ct.flat {
+				vals[i] = r.vals[payloadStart+i]
+				if have := t.flat[1+i]; have == api.ValueTypeI64 && (want == api.ValueTypeI32 || want == api.ValueTypeF32) {
+					vals[i] = uint64(uint32(vals[i]))
+				}
+			}
+			return c.liftFlat(ct, &flatReader{vals: vals}, payload)
+		})
+	case KindOwn, KindBorrow:
+		rep, err := c.liftHandle(t, uint32(r.next()))
+		if err != nil {
+			return err
+		}
+		return setRep(t, dst, rep)
+	default:
+		return setScalar(t, dst, r.next())
+	}
+}
+
+func (c *Call) load(t *ValType, ptr uint32, dst reflect.Value) error {
+	if dst.Kind() == reflect.Interface && t.Kind != KindOwn && t.Kind != KindBorrow {
+		v := reflect.New(GoType(t)).Elem()
+		if err := c.load(t, ptr, v); err != nil {
+			return err
+		}
+		dst.Set(v)
+		return nil
+	}
+
+	switch t.Kind {
+	case KindString, KindList:
+		b, err := c.read(ptr, 8)
+		if err != nil {
+			return err
+		}
+		p, n := binary.LittleEndian.Uint32(b), binary.LittleEndian.Uint32(b[4:])
+		if t.Kind == KindString {
+			return c.loadString(p, n, dst)
+		}
+		return c.loadList(t.Elem, p, n, dst)
+	case KindRecord, KindTuple:
+		if dst.Kind() != reflect.Struct || dst.NumField() != len(t.Fields) {
+			return mismatch(t, dst)
+		}
+		var offset uint32
+		for i, f := range t.Fields {
+			offset = alignTo(offset, f.Type.align)
+			if err := c.load(f.Type, ptr+offset, dst.Field(i)); err != nil {
+				return err
+			}
+			offset += f.Type.size
+		}
+		return nil
+	case KindVariant, KindEnum, KindOption, KindResult:
+		size := discriminantSize(len(t.Cases))
+		disc, err := c.loadUint(ptr, size)
+		if err != nil {
+			return err
+		}
+		payload := ptr + alignTo(size, t.maxCaseAlign())
+		return c.setCase(t, dst, uint32(disc), func(ct *ValType, v reflect.Value) error {
+			return c.load(ct, payload, v)
+		})
+	case KindFlags, KindOwn, KindBorrow:
+		v, err := c.loadUint(ptr, t.size)
+		if err != nil {
+			return err
+		}
+		if t.Kind == KindFlags {
+			return setScalar(t, dst, v)
+		}
+		rep, err := c.liftHandle(t, uint32(v))
+		if err != nil {
+			return err
+		}
+		return setRep(t, dst, rep)
+	default:
+		v, err := c.loadUint(ptr, t.size)
+		if err != nil {
+			return err
+		}
+		return setScalar(t, dst, v)
+	}
+}
+
+func (c *Call) loadFields(tuple *ValType, ptr uint32, dsts []reflect.Value) error {
+	if ptr%tuple.align != 0 {
+		return fmt.Errorf("pointer %d is not aligned to %d", ptr, tuple.align)
+	}
+	var offset uint32
+	for i, f := range tuple.Fields {
+		offset = alignTo(offset, f.Type.align)
+		if err := c.load(f.Type, ptr+offset, dsts[i]); err != nil {
+			return err
+		}
+		offset += f.Type.size
+	}
+	return nil
+}
+
+func (c *Call) storeFields(tuple *ValType, vals []reflect.Value, ptr uint32) error {
+	if ptr%tuple.align != 0 {
+		return fmt.Errorf("pointer %d is not aligned to %d", ptr, tuple.align)
+	}
+	var offset uint32
+	for i, f := range tuple.Fields {
+		offset = alignTo(offset, f.Type.align)
+		if err := c.store(f.Type, vals[i], ptr+offset); err != nil {
+			return err
+		}
+		offset += f.Type.size
+	}
+	return nil
+}
+
+func (t *ValType) maxCaseAlign() uint32 {
+	align := uint32(1)
+	for _, c := range t.Cases {
+		if c.Type != nil && c.Type.align > align {
+			align = c.Type.align
+		}
+	}
+	return align
+}
+
+func (c *Call) lowerFlat(t *ValType, v reflect.Value, flat []uint64) ([]uint64, error) {
+	if v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+
+	switch t.Kind {
+	case KindString, KindList:
+		var ptr, n uint32
+		var err error
+		if t.Kind == KindString {
+			ptr, n, err = c.storeString(v)
+		} else {
+			ptr, n, err = c.storeList(t.Elem, v)
+		}
+		return append(flat, uint64(ptr), uint64(n)), err
+	case KindRecord, KindTuple:
+		if v.Kind() != reflect.Struct || v.NumField() != len(t.Fields) {
+			return nil, mismatch(t, v)
+		}
+		var err error
+		for i, f := range t.Fields {
+			if flat, err = c.lowerFlat(f.Type, v.Field(i), flat); err != nil {
+				return nil, err
+			}
+		}
+		return flat, nil
+	case KindVariant, KindEnum, KindOption, KindResult:
+		disc, payload, err := getCase(t, v)
+		if err != nil {
+			return nil, err
+		}
+		flat = append(flat, uint64(disc))
+		start := len(flat)
+		if ct := t.Cases[disc].Type; ct != nil {
+			if flat, err = c.lowerFlat(ct, payload, flat); err != nil {
+				return nil, err
+			}
+		}
+		// Values are already zero-extended to uint64, so coercing them to the joined types only needs padding.
+		for len(flat) < start+len(t.flat)-1 {
+			flat = append(flat, 0)
+		}
+		return flat, nil
+	case KindOwn, KindBorrow:
+		idx, err := c.lowerHandle(t, v)
+		return append(flat, uint64(idx)), err
+	default:
+		bits, err := scalarBits(t, v)
+		if err != nil {
+			return nil, err
+		}
+		if t.flat[0] == api.ValueTypeI32 {
+			bits = uint64(uint32(bits))
+		}
+		return append(flat, bits), nil
+	}
+}
+
+func (c *Call) store(t *ValType, v reflect.Value, ptr uint32) error {
+	if v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+
+	switch t.Kind {
+	case KindString, KindList:
+		var p, n uint32
+		var err error
+		if t.Kind == KindString {
+			p, n, err = c.storeString(v)
+		} else {
+			p, n, err = c.storeList(t.Elem, v)
+		}
+		if err != nil {
+			return err
+		}
+		var b [8]byte
+		binary.LittleEndian.PutUint32(b[:], p)
+		binary.LittleEndian.PutUint32(b[4:], n)
+		return c.write(ptr, b[:])
+	case KindRecord, KindTuple:
+		if v.Kind() != reflect.Struct || v.NumField() != len(t.Fields) {
+			return mismatch(t, v)
+		}
+		var offset uint32
+		for i, f := range t.Fields {
+			offset = alignTo(offset, f.Type.align)
+			if err := c.store(f.Type, v.Field(i), ptr+offset); err != nil {
+				return err
+			}
+			offset += f.Type.size
+		}
+		return nil
+	case KindVariant, KindEnum, KindOption, KindResult:
+		disc, payload, err := getCase(t, v)
+		if err != nil {
+			return err
+		}
+		size := discriminantSize(len(t.Cases))
+		if err = c.storeUint(ptr, size, uint64(disc)); err != nil {
+			return err
+		}
+		if ct := t.Cases[disc].Type; ct != nil {
+			return c.store(ct, payload, ptr+alignTo(size, t.maxCaseAlign()))
+		}
+		return nil
+	case KindOwn, KindBorrow:
+		idx, err := c.lowerHandle(t, v)
+		if err != nil {
+			return err
+		}
+		return c.storeUint(ptr, 4, uint64(idx))
+	default:
+		bits, err := scalarBits(t, v)
+		if err != nil {
+			return err
+		}
+		return c.storeUint(ptr, t.size, bits)
+	}
+}
+
+func (c *Call) memory() (api.Memory, error) {
+	if c.opts.Memory == nil {
+		return nil, errors.New("canonical option memory is required")
+	}
+	return c.opts.Memory, nil
+}
+
+func (c *Call) read(ptr, size uint32) ([]byte, error) {
+	mem, err := c.memory()
+	if err != nil {
+		return nil, err
+	}
+	b, ok := mem.Read(ptr, size)
+	if !ok {
+		return nil, fmt.Errorf("out of bounds memory access: %d bytes at %d", size, ptr)
+	}
+	return b, nil
+}
+
+func (c *Call) write(ptr uint32, b []byte) error {
+	mem, err := c.memory()
+	if err != nil {
+		return err
+	}
+	if !mem.Write(ptr, b) {
+		return fmt.Errorf("out of bounds memory access: %d bytes at %d", len(b), ptr)
+	}
+	return nil
+}
+
+func (c *Call) loadUint(ptr, size uint32) (uint64, error) {
+	if ptr%size != 0 {
+		return 0, fmt.Errorf("pointer %d is not aligned to %d", ptr, size)
+	}
+	b, err := c.read(ptr, size)
+	if err != nil {
+		return 0, err
+	}
+	switch size {
+	case 1:
+		return uint64(b[0]), nil
+	case 2:
+		return uint64(binary.LittleEndian.Uint16(b)), nil
+	case 4:
+		return uint64(binary.LittleEndian.Uint32(b)), nil
+	default:
+		return binary.LittleEndian.Uint64(b), nil
+	}
+}
+
+func (c *Call) storeUint(ptr, size uint32, v uint64) error {
+	if ptr%size != 0 {
+		return fmt.Errorf("pointer %d is not aligned to %d", ptr, size)
+	}
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], v)
+	return c.write(ptr, b[:size])
+}
+
+// alloc allocates size bytes in the memory of the core instance with its realloc function.
+func (c *Call) alloc(align, size uint32) (uint32, error) {
+	if c.opts.Realloc == nil {
+		return 0, errors.New("canonical option realloc is required")
+	}
+	results, err := c.opts.Realloc.Call(c.ctx, 0, 0, uint64(align), uint64(size))
+	if err != nil {
+		return 0, err
+	}
+	ptr := uint32(results[0])
+	if ptr%align != 0 {
+		return 0, fmt.Errorf("realloc returned pointer %d not aligned to %d", ptr, align)
+	}
+	mem, err := c.memory()
+	if err != nil {
+		return 0, err
+	}
+	if uint64(ptr)+uint64(size) > uint64(mem.Size()) {
+		return 0, fmt.Errorf("realloc returned out of bounds pointer %d for %d bytes", ptr, size)
+	}
+	return ptr, nil
+}
+
+func (c *Call) loadString(ptr, n uint32, dst reflect.Value) error {
+	if dst.Kind() != reflect.String {
+		return mismatch(Primitive(KindString), dst)
+	}
+	var s string
+	switch enc := c.opts.StringEncoding; {
+	case enc == StringEncodingUTF8:
+		b, err := c.read(ptr, n)
+		if err != nil {
+			return err
+		}
+		if !utf8.Valid(b) {
+			return errors.New("string is not valid UTF-8")
+		}
+		s = string(b)
+	case enc == StringEncodingUTF16 || n&utf16Tag != 0:
+		n &^= utf16Tag
+		if ptr%2 != 0 {
+			return fmt.Errorf("pointer %d is not aligned to 2", ptr)
+		}
+		b, err := c.read(ptr, 2*n)
+		if err != nil {
+			return err
+		}
+		units := make([]uint16, n)
+		for i := range units {
+			units[i] = binary.LittleEndian.Uint16(b[2*i:])
+		}
+		s = string(utf16.Decode(units))
+	default: // Latin-1
+		if ptr%2 != 0 {
+			return fmt.Errorf("pointer %d is not aligned to 2", ptr)
+		}
+		b, err := c.read(ptr, n)
+		if err != nil {
+			return err
+		}
+		runes := make([]rune, n)
+		for i, ch := range b {
+			runes[i] = rune(ch)
+		}
+		s = string(runes)
+	}
+	dst.SetString(s)
+	return nil
+}
+
+func (c *Call) storeString(v reflect.Value) (ptr, n uint32, err error) {
+	if v.Kind() != reflect.String {
+		return 0, 0, mismatch(Primitive(KindString), v)
+	}
+	s := v.String()
+	var b []byte
+	var align uint32 = 2
+	switch c.opts.StringEncoding {
+	case StringEncodingUTF8:
+		b, align, n = []byte(s), 1, uint32(len(s))
+	case StringEncodingLatin1UTF16:
+		latin1 := make([]byte, 0, len(s))
+		for _, r := range s {
+			if r > 0xff {
+				latin1 = nil
+				break
+			}
+			latin1 = append(latin1, byte(r))
+		}
+		if latin1 != nil {
+			b, n = latin1, uint32(len(latin1))
+			break
+		}
+		fallthrough
+	default: // UTF-16
+		units := utf16.Encode([]rune(s))
+		b = make([]byte, 2*len(units))
+		for i, u := range units {
+			binary.LittleEndian.PutUint16(b[2*i:], u)
+		}
+		n = uint32(len(units))
+		if c.opts.StringEncoding == StringEncodingLatin1UTF16 {
+			n |= utf16Tag
+		}
+	}
+	if ptr, err = c.alloc(align, uint32(len(b))); err != nil {
+		return
+	}
+	err = c.write(ptr, b)
+	return
+}
+
+func (c *Call) loadList(elem *ValType, ptr, n uint32, dst reflect.Value) error {
+	if dst.Kind() != reflect.Slice {
+		return mismatch(NewList(elem), dst)
+	}
+	if ptr%elem.align != 0 {
+		return fmt.Errorf("pointer %d is not aligned to %d", ptr, elem.align)
+	}
+	size := uint64(n) * uint64(elem.size)
+	if size > math.MaxUint32 {
+		return fmt.Errorf("list of %d elements is too large", n)
+	}
+	b, err := c.read(ptr, uint32(size))
+	if err != nil {
+		return err
+	}
+	if elem.Kind == KindU8 && dst.Type().Elem().Kind() == reflect.Uint8 {
+		dst.SetBytes(append([]byte(nil), b...))
+		return nil
+	}
+	s := reflect.MakeSlice(dst.Type(), int(n), int(n))
+	for i := 0; i < int(n); i++ {
+		if err = c.load(elem, ptr+uint32(i)*elem.size, s.Index(i)); err != nil {
+			return err
+		}
+	}
+	dst.Set(s)
+	return nil
+}
+
+func (c *Call) storeList(elem *ValType, v reflect.Value) (ptr, n uint32, err error) {
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return 0, 0, mismatch(NewList(elem), v)
+	}
+	n = uint32(v.Len())
+	size := uint64(n) * uint64(elem.size)
+	if size > math.MaxUint32 {
+		return 0, 0, fmt.Errorf("list of %d elements is too large", n)
+	}
+	if ptr, err = c.alloc(elem.align, uint32(size)); err != nil {
+		return
+	}
+	if elem.Kind == KindU8 && v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8 {
+		err = c.write(ptr, v.Bytes())
+		return
+	}
+	for i := 0; i < int(n); i++ {
+		if err = c.store(elem, v.Index(i), ptr+uint32(i)*elem.size); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (c *Call) liftHandle(t *ValType, idx uint32) (interface{}, error) {
+	if t.Kind == KindBorrow {
+		return c.handles.Rep(t.Resource, idx)
+	}
+	h, err := c.handles.get(t.Resource, idx)
+	if err != nil {
+		return nil, err
+	} else if !h.own {
+		return nil, fmt.Errorf("handle %d to resource %s is borrowed, not owned", idx, resourceName(t.Resource))
+	}
+	rep, _, err := c.handles.Remove(t.Resource, idx)
+	return rep, err
+}
+
+func (c *Call) lowerHandle(t *ValType, v reflect.Value) (uint32, error) {
+	if !v.IsValid() {
+		return 0, fmt.Errorf("nil can't be used as %s", t)
+	}
+	var rep interface{}
+	if t.Resource.Imported {
+		rep = v.Interface()
+	} else if isUint(v.Kind()) {
+		// Resources defined by a component are represented by an i32.
+		rep = uint32(v.Uint())
+	} else {
+		return 0, mismatch(t, v)
+	}
+
+	if t.Kind == KindOwn {
+		return c.handles.Add(t.Resource, rep, true), nil
+	} else if !t.Resource.Imported {
+		// A borrowed resource defined by the callee is passed as its representation.
+		return rep.(uint32), nil
+	}
+	idx := c.handles.Add(t.Resource, rep, false)
+	c.lent = append(c.lent, lentHandle{r: t.Resource, idx: idx, h: c.handles.table(t.Resource).entries[idx]})
+	return idx, nil
+}
+
+func setRep(t *ValType, dst reflect.Value, rep interface{}) error {
+	if rep == nil {
+		dst.Set(reflect.Zero(dst.Type()))
+		return nil
+	}
+	v := reflect.ValueOf(rep)
+	switch {
+	case v.Type().AssignableTo(dst.Type()):
+		dst.Set(v)
+	case !t.Resource.Imported && isUint(dst.Kind()):
+		dst.SetUint(v.Uint())
+	default:
+		return fmt.Errorf("%s of %s can't be used as %s", t, v.Type(), dst.Type())
+	}
+	return nil
+}
+
+// setCase sets dst to the case disc of the variant-like t, lifting the payload with liftPayload.
+func (c *Call) setCase(t *ValType, dst reflect.Value, disc uint32, liftPayload func(*ValType, reflect.Value) error) error {
+	if disc >= uint32(len(t.Cases)) {
+		return fmt.Errorf("invalid discriminant %d of %s", disc, t)
+	}
+	ct := t.Cases[disc].Type
+	switch t.Kind {
+	case KindEnum:
+		return setInteger(t, dst, uint64(disc))
+	case KindOption:
+		if dst.Kind() != reflect.Ptr {
+			return mismatch(t, dst)
+		}
+		if disc == 0 {
+			dst.Set(reflect.Zero(dst.Type()))
+			return nil
+		}
+		p := reflect.New(dst.Type().Elem())
+		if err := liftPayload(ct, p.Elem()); err != nil {
+			return err
+		}
+		dst.Set(p)
+		return nil
+	default: // KindVariant, KindResult
+		if dst.Kind() != reflect.Struct || dst.NumField() != len(t.Cases) {
+			return mismatch(t, dst)
+		}
+		dst.Set(reflect.Zero(dst.Type()))
+		f := dst.Field(int(disc))
+		if f.Kind() != reflect.Ptr {
+			return mismatch(t, dst)
+		}
+		p := reflect.New(f.Type().Elem())
+		if ct != nil {
+			if err := liftPayload(ct, p.Elem()); err != nil {
+				return err
+			}
+		}
+		f.Set(p)
+		return nil
+	}
+}
+
+// getCase returns the case of the variant-like t which v holds, and its payload.
+func getCase(t *ValType, v reflect.Value) (disc uint32, payload reflect.Value, err error) {
+	switch t.Kind {
+	case KindEnum:
+		var bits uint64
+		if bits, err = integerBits(t, v); err != nil {
+			return
+		} else if bits >= uint64(len(t.Cases)) {
+			err = fmt.Errorf("invalid case %d of %s", bits, t)
+		}
+		disc = uint32(bits)
+		return
+	case KindOption:
+		if !v.IsValid() || (v.Kind() == reflect.Ptr && v.IsNil()) {
+			return
+		} else if v.Kind() != reflect.Ptr {
+			err = mismatch(t, v)
+			return
+		}
+		return 1, v.Elem(), nil
+	default: // KindVariant, KindResult
+		if v.Kind() != reflect.Struct || v.NumField() != len(t.Cases) {
+			err = mismatch(t, v)
+			return
+		}
+		found := false
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Field(i)
+			if f.Kind() != reflect.Ptr {
+				err = mismatch(t, v)
+				return
+			} else if f.IsNil() {
+				continue
+			} else if found {
+				err = fmt.Errorf("%s must have exactly one case set", v.Type())
+				return
+			}
+			found, disc, payload = true, uint32(i), f.Elem()
+		}
+		if !found {
+			err = fmt.Errorf("%s must have exactly one case set", v.Type())
+		}
+		return
+	}
+}
+
+// setScalar sets dst to the primitive or flags t, whose value is in the low bits of v.
+func setScalar(t *ValType, dst reflect.Value, v uint64) error {
+	switch t.Kind {
+	case KindBool:
+		if dst.Kind() != reflect.Bool {
+			return mismatch(t, dst)
+		}
+		dst.SetBool(v != 0)
+	case KindF32, KindF64:
+		if dst.Kind() != reflect.Float32 && dst.Kind() != reflect.Float64 {
+			return mismatch(t, dst)
+		}
+		if t.Kind == KindF32 {
+			dst.SetFloat(float64(math.Float32frombits(uint32(v))))
+		} else {
+			dst.SetFloat(math.Float64frombits(v))
+		}
+	case KindChar:
+		if r := rune(uint32(v)); uint32(v) > utf8.MaxRune || (r >= 0xd800 && r <= 0xdfff) {
+			return fmt.Errorf("invalid char %#x", uint32(v))
+		}
+		return setInteger(t, dst, uint64(uint32(v)))
+	case KindString:
+		return mismatch(t, dst)
+	case KindFlags:
+		return setInteger(t, dst, v&(1<<len(t.Labels)-1))
+	default:
+		// Sign-extend signed integers, which are in the low bits.
+		switch t.Kind {
+		case KindS8:
+			v = uint64(int8(v))
+		case KindS16:
+			v = uint64(int16(v))
+		case KindS32:
+			v = uint64(int32(v))
+		case KindU8:
+			v = uint64(uint8(v))
+		case KindU16:
+			v = uint64(uint16(v))
+		case KindU32:
+			v = uint64(uint32(v))
+		}
+		return setInteger(t, dst, v)
+	}
+	return nil
+}
+
+func setInteger(t *ValType, dst reflect.Value, v uint64) error {
+	switch k := dst.Kind(); {
+	case isInt(k):
+		dst.SetInt(int64(v))
+	case isUint(k):
+		dst.SetUint(v)
+	default:
+		return mismatch(t, dst)
+	}
+	return nil
+}
+
+// scalarBits returns the bits of the primitive or flags t held by v, zero-extended for floats and sign-extended for
+// integers.
+func scalarBits(t *ValType, v reflect.Value) (uint64, error) {
+	switch t.Kind {
+	case KindBool:
+		if v.Kind() != reflect.Bool {
+			return 0, mismatch(t, v)
+		}
+		if v.Bool() {
+			return 1, nil
+		}
+		return 0, nil
+	case KindF32:
+		if v.Kind() != reflect.Float32 && v.Kind() != reflect.Float64 {
+			return 0, mismatch(t, v)
+		}
+		return uint64(math.Float32bits(float32(v.Float()))), nil
+	case KindF64:
+		if v.Kind() != reflect.Float32 && v.Kind() != reflect.Float64 {
+			return 0, mismatch(t, v)
+		}
+		return math.Float64bits(v.Float()), nil
+	case KindString:
+		return 0, mismatch(t, v)
+	case KindChar:
+		bits, err := integerBits(t, v)
+		if err != nil {
+			return 0, err
+		}
+		if r := rune(bits); bits > utf8.MaxRune || (r >= 0xd800 && r <= 0xdfff) {
+			return 0, fmt.Errorf("invalid char %#x", bits)
+		}
+		return bits, nil
+	default:
+		return integerBits(t, v)
+	}
+}
+
+func integerBits(t *ValType, v reflect.Value) (uint64, error) {
+	switch k := v.Kind(); {
+	case isInt(k):
+		return uint64(v.Int()), nil
+	case isUint(k):
+		return v.Uint(), nil
+	}
+	return 0, mismatch(t, v)
+}
+
+func mismatch(t *ValType, v reflect.Value) error {
+	if !v.IsValid() {
+		return fmt.Errorf("nil can't be used as %s", t)
+	}
+	return fmt.Errorf("%s can't be used as %s", v.Type(), t)
+}
@@ -0,0 +1,225 @@
+package component
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+var testCtx = context.WithValue(context.Background(), struct{}{}, "arbitrary")
+
+// bumpRealloc is a realloc which never frees memory.
+type bumpRealloc struct {
+	api.Function
+	next uint64
+}
+
+// Call implements the same method as documented on api.Function.
+func (r *bumpRealloc) Call(_ context.Context, params ...uint64) ([]uint64, error) {
+	align, size := params[2], params[3]
+	ptr := (r.next + align - 1) &^ (align - 1)
+	r.next = ptr + size
+	return []uint64{ptr}, nil
+}
+
+func newTestOptions(t *testing.T, enc StringEncoding) *Options {
+	mem, err := wasm.NewMemoryInstance(&wasm.Memory{Min: 1, Cap: 1, Max: 1}, nil)
+	require.NoError(t, err)
+	return &Options{StringEncoding: enc, Memory: mem, Realloc: &bumpRealloc{next: 8}}
+}
+
+// roundTrip lowers v as a parameter and a result of type vt, and returns what is lifted back from both.
+func roundTrip(t *testing.T, opts *Options, vt *ValType, v interface{}) (param, result interface{}) {
+	ft := NewFuncType([]Field{{Name: "p", Type: vt}}, []Field{{Type: vt}})
+	var handles Handles
+	c := NewCall(testCtx, opts, &handles)
+	defer c.End()
+
+	stack, err := c.LowerParams(ft, []reflect.Value{reflect.ValueOf(v)})
+	require.NoError(t, err)
+	params := []reflect.Value{reflect.New(reflect.TypeOf(v)).Elem()}
+	require.NoError(t, c.LiftParams(ft, stack, params))
+
+	retptr, err := c.alloc(ft.results.align, ft.results.size)
+	require.NoError(t, err)
+	stack = make([]uint64, len(ft.CoreParams(true)))
+	stack[len(stack)-1] = uint64(retptr)
+	require.NoError(t, c.LowerResults(ft, stack, []reflect.Value{reflect.ValueOf(v)}))
+	coreResults := stack
+	if len(ft.results.flat) > maxFlatResults {
+		coreResults = []uint64{uint64(retptr)}
+	}
+	results := []reflect.Value{reflect.New(reflect.TypeOf(v)).Elem()}
+	require.NoError(t, c.LiftResults(ft, coreResults, results))
+	return params[0].Interface(), results[0].Interface()
+}
+
+func TestCall_RoundTrip(t *testing.T) {
+	u8, s16, u64, f32 := uint8(7), int16(-3), uint64(1<<40), float32(1.5)
+	type point struct {
+		X int32
+		Y float64
+	}
+	type shape struct {
+		Circle *float32
+		Square *uint64
+		Empty  *struct{}
+	}
+
+	tests := []struct {
+		name string
+		t    *ValType
+		v    interface{}
+	}{
+		{name: "bool", t: Primitive(KindBool), v: true},
+		{name: "s8", t: Primitive(KindS8), v: int8(-128)},
+		{name: "u16", t: Primitive(KindU16), v: uint16(65535)},
+		{name: "s64", t: Primitive(KindS64), v: int64(-1 << 40)},
+		{name: "f32", t: Primitive(KindF32), v: float32(-0.5)},
+		{name: "f64", t: Primitive(KindF64), v: 3.25},
+		{name: "char", t: Primitive(KindChar), v: 'ü'},
+		{name: "string", t: Primitive(KindString), v: "héllo wörld"},
+		{name: "list<u8>", t: NewList(Primitive(KindU8)), v: []byte{1, 2, 3}},
+		{name: "list<string>", t: NewList(Primitive(KindString)), v: []string{"a", "", "bc"}},
+		{
+			name: "record",
+			t:    NewRecord(Field{Name: "x", Type: Primitive(KindS32)}, Field{Name: "y", Type: Primitive(KindF64)}),
+			v:    point{X: -1, Y: 2.5},
+		},
+		{name: "option<u8> none", t: NewOption(Primitive(KindU8)), v: (*uint8)(nil)},
+		{name: "option<u8> some", t: NewOption(Primitive(KindU8)), v: &u8},
+		{name: "option<s16>", t: NewOption(Primitive(KindS16)), v: &s16},
+		{
+			name: "variant f32",
+			t: NewVariant(
+				Case{Name: "circle", Type: Primitive(KindF32)},
+				Case{Name: "square", Type: Primitive(KindU64)},
+				Case{Name: "empty"},
+			),
+			v: shape{Circle: &f32},
+		},
+		{
+			name: "variant u64",
+			t: NewVariant(
+				Case{Name: "circle", Type: Primitive(KindF32)},
+				Case{Name: "square", Type: Primitive(KindU64)},
+				Case{Name: "empty"},
+			),
+			v: shape{Square: &u64},
+		},
+		{name: "enum", t: NewEnum("a", "b", "c"), v: uint8(2)},
+		{name: "flags", t: NewFlags("a", "b", "c"), v: uint8(5)},
+	}
+
+	for _, enc := range []StringEncoding{StringEncodingUTF8, StringEncodingUTF16, StringEncodingLatin1UTF16} {
+		for _, tt := range tests {
+			tc := tt
+			t.Run(tc.name, func(t *testing.T) {
+				param, result := roundTrip(t, newTestOptions(t, enc), tc.t, tc.v)
+				require.Equal(t, tc.v, param)
+				require.Equal(t, tc.v, result)
+			})
+		}
+	}
+}
+
+func TestCall_LowerParams_Spilled(t *testing.T) {
+	str := Primitive(KindString)
+	var params []Field
+	var vals []reflect.Value
+	for _, name := range []string{"a", "b", "c", "d", "e", "f", "g", "h", "i"} {
+		params = append(params, Field{Name: name, Type: str})
+		vals = append(vals, reflect.ValueOf(name))
+	}
+	ft := NewFuncType(params, nil)
+
+	c := NewCall(testCtx, newTestOptions(t, StringEncodingUTF8), &Handles{})
+	stack, err := c.LowerParams(ft, vals)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(stack))
+
+	lifted := make([]reflect.Value, len(params))
+	for i := range lifted {
+		lifted[i] = reflect.New(reflect.TypeOf("")).Elem()
+	}
+	require.NoError(t, c.LiftParams(ft, stack, lifted))
+	for i, v := range lifted {
+		require.Equal(t, params[i].Name, v.String())
+	}
+}
+
+func TestCall_Handles(t *testing.T) {
+	host := &ResourceType{Name: "host", Imported: true}
+	guest := &ResourceType{Name: "guest"}
+	ft := NewFuncType([]Field{
+		{Name: "a", Type: NewOwn(host)},
+		{Name: "b", Type: NewBorrow(host)},
+		{Name: "c", Type: NewBorrow(guest)},
+	}, nil)
+
+	var handles Handles
+	c := NewCall(testCtx, newTestOptions(t, StringEncodingUTF8), &handles)
+	stack, err := c.LowerParams(ft, []reflect.Value{
+		reflect.ValueOf("owned"), reflect.ValueOf("borrowed"), reflect.ValueOf(uint32(42)),
+	})
+	require.NoError(t, err)
+	require.Equal(t, []uint64{1, 2, 42}, stack)
+
+	// The borrowed handle is released when the call ends, and the owned one is transferred.
+	c.End()
+	_, err = handles.Rep(host, 2)
+	require.EqualError(t, err, "unknown handle 2 to resource host")
+	rep, err := handles.Rep(host, 1)
+	require.NoError(t, err)
+	require.Equal(t, "owned", rep)
+
+	// Lifting an owned handle removes it.
+	lifted := []reflect.Value{reflect.New(reflect.TypeOf("")).Elem()}
+	ownFt := NewFuncType([]Field{{Name: "a", Type: NewOwn(host)}}, nil)
+	require.NoError(t, NewCall(testCtx, c.opts, &handles).LiftParams(ownFt, []uint64{1}, lifted))
+	require.Equal(t, "owned", lifted[0].String())
+	err = NewCall(testCtx, c.opts, &handles).LiftParams(ownFt, []uint64{1}, lifted)
+	require.EqualError(t, err, "param a: unknown handle 1 to resource host")
+}
+
+func TestCall_Errors(t *testing.T) {
+	t.Run("invalid UTF-8", func(t *testing.T) {
+		opts := newTestOptions(t, StringEncodingUTF8)
+		require.True(t, opts.Memory.Write(16, []byte{0xff}))
+		ft := NewFuncType([]Field{{Name: "s", Type: Primitive(KindString)}}, nil)
+		params := []reflect.Value{reflect.New(reflect.TypeOf("")).Elem()}
+		err := NewCall(testCtx, opts, &Handles{}).LiftParams(ft, []uint64{16, 1}, params)
+		require.EqualError(t, err, "param s: string is not valid UTF-8")
+	})
+	t.Run("out of bounds", func(t *testing.T) {
+		ft := NewFuncType([]Field{{Name: "s", Type: NewList(Primitive(KindU32))}}, nil)
+		params := []reflect.Value{reflect.New(reflect.TypeOf([]uint32{})).Elem()}
+		err := NewCall(testCtx, newTestOptions(t, StringEncodingUTF8), &Handles{}).
+			LiftParams(ft, []uint64{65532, 2}, params)
+		require.EqualError(t, err, "param s: out of bounds memory access: 8 bytes at 65532")
+	})
+	t.Run("invalid char", func(t *testing.T) {
+		ft := NewFuncType([]Field{{Name: "c", Type: Primitive(KindChar)}}, nil)
+		params := []reflect.Value{reflect.New(reflect.TypeOf('a')).Elem()}
+		err := NewCall(testCtx, newTestOptions(t, StringEncodingUTF8), &Handles{}).
+			LiftParams(ft, []uint64{0xd800}, params)
+		require.EqualError(t, err, "param c: invalid char 0xd800")
+	})
+	t.Run("invalid discriminant", func(t *testing.T) {
+		ft := NewFuncType([]Field{{Name: "e", Type: NewEnum("a", "b")}}, nil)
+		params := []reflect.Value{reflect.New(reflect.TypeOf(uint8(0))).Elem()}
+		err := NewCall(testCtx, newTestOptions(t, StringEncodingUTF8), &Handles{}).
+			LiftParams(ft, []uint64{2}, params)
+		require.EqualError(t, err, "param e: invalid discriminant 2 of enum")
+	})
+	t.Run("type mismatch", func(t *testing.T) {
+		ft := NewFuncType([]Field{{Name: "s", Type: Primitive(KindString)}}, nil)
+		_, err := NewCall(testCtx, newTestOptions(t, StringEncodingUTF8), &Handles{}).
+			LowerParams(ft, []reflect.Value{reflect.ValueOf(1)})
+		require.EqualError(t, err, "param s: int can't be used as string")
+	})
+}
@@ -0,0 +1,155 @@
+package component
+
+import (
+	"github.com/tetratelabs/wazero/api"
+)
+
+// Component is a decoded component. Definitions are kept in their index spaces, where each entry only refers to
+// entries before it. Hence, core instances can be instantiated in order, and everything else resolved lazily.
+//
+// See https://github.com/WebAssembly/component-model/blob/main/design/mvp/Explainer.md#component-definitions
+type Component struct {
+	// Modules are the binaries of the embedded core modules.
+	Modules [][]byte
+
+	CoreInstances []*CoreInstance
+	CoreFuncs     []*CoreFunc
+	CoreTables    []CoreExport
+	CoreMemories  []CoreExport
+	CoreGlobals   []CoreExport
+
+	Funcs     []*Func
+	Instances []*Instance
+
+	Imports []*Import
+	Exports []*Export
+}
+
+// CoreExport refers to an export of a core instance.
+type CoreExport struct {
+	Instance uint32
+	Name     string
+}
+
+// CoreInstance is either an instantiation of a core module, or a bundle of existing core definitions.
+type CoreInstance struct {
+	// FromExports is true when the instance bundles Exports, instead of instantiating Module with Args.
+	FromExports bool
+
+	Module uint32
+	Args   []CoreInstantiateArg
+
+	Exports []CoreInlineExport
+}
+
+// CoreInstantiateArg provides the core instance Instance as the imported module Name.
+type CoreInstantiateArg struct {
+	Name     string
+	Instance uint32
+}
+
+// CoreInlineExport exports the core definition Index of the index space of Type as Name.
+type CoreInlineExport struct {
+	Name  string
+	Type  api.ExternType
+	Index uint32
+}
+
+// CoreFuncKind is the kind of CoreFunc.
+type CoreFuncKind byte
+
+const (
+	// CoreFuncKindAlias is a function exported by a core instance.
+	CoreFuncKindAlias CoreFuncKind = iota
+	// CoreFuncKindLower is a component function lowered with "canon lower".
+	CoreFuncKindLower
+	// CoreFuncKindResourceNew is "canon resource.new".
+	CoreFuncKindResourceNew
+	// CoreFuncKindResourceDrop is "canon resource.drop".
+	CoreFuncKindResourceDrop
+	// CoreFuncKindResourceRep is "canon resource.rep".
+	CoreFuncKindResourceRep
+)
+
+// CoreFunc is an entry of the core function index space.
+type CoreFunc struct {
+	Kind CoreFuncKind
+
+	// Alias is set for CoreFuncKindAlias.
+	Alias CoreExport
+
+	// Func and Options are set for CoreFuncKindLower.
+	Func    uint32
+	Options CanonOptions
+
+	// Resource is set for the resource built-ins.
+	Resource *ResourceType
+}
+
+// StringEncoding is the encoding of strings in linear memory.
+type StringEncoding byte
+
+const (
+	StringEncodingUTF8 StringEncoding = iota
+	StringEncodingUTF16
+	StringEncodingLatin1UTF16
+)
+
+// CanonOptions are the options of "canon lift" and "canon lower".
+type CanonOptions struct {
+	StringEncoding StringEncoding
+
+	// Memory, Realloc and PostReturn are indices of the core memory and function index spaces, if set.
+	Memory, Realloc, PostReturn *uint32
+}
+
+// FuncKind is the kind of Func.
+type FuncKind byte
+
+const (
+	// FuncKindImport is an imported function, or a function of an imported instance.
+	FuncKindImport FuncKind = iota
+	// FuncKindLift is a core function lifted with "canon lift".
+	FuncKindLift
+)
+
+// Func is an entry of the component function index space.
+type Func struct {
+	Kind FuncKind
+	Type *FuncType
+
+	// Import is the name of the imported function, or of the imported instance which exports the function as Name.
+	Import, Name string
+
+	// CoreFunc and Options are set for FuncKindLift.
+	CoreFunc uint32
+	Options  CanonOptions
+}
+
+// Instance is an entry of the component instance index space.
+type Instance struct {
+	// Import is the name of the imported instance, or empty if the instance bundles Exports.
+	Import string
+	Type   *InstanceType
+
+	Exports map[string]*InstanceExport
+}
+
+// InstanceExport exports the definition Index of the index space of Kind.
+type InstanceExport struct {
+	Kind  ExternKind
+	Index uint32
+}
+
+// Import is an import of a component.
+type Import struct {
+	Name   string
+	Extern *Extern
+}
+
+// Export is an export of a component, which refers to the definition Index of the index space of Kind.
+type Export struct {
+	Name  string
+	Kind  ExternKind
+	Index uint32
+}
@@ -0,0 +1,1038 @@
+package component
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"unicode/utf8"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/leb128"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// Section IDs of the component binary format.
+// See https://github.com/WebAssembly/component-model/blob/main/design/mvp/Binary.md#component-definitions
+const (
+	sectionIDCustom       = 0
+	sectionIDCoreModule   = 1
+	sectionIDCoreInstance = 2
+	sectionIDCoreType     = 3
+	sectionIDComponent    = 4
+	sectionIDInstance     = 5
+	sectionIDAlias        = 6
+	sectionIDType         = 7
+	sectionIDCanon        = 8
+	sectionIDStart        = 9
+	sectionIDImport       = 10
+	sectionIDExport       = 11
+	sectionIDValue        = 12
+)
+
+// Sorts of the core and component index spaces.
+const (
+	coreSortFunc     = 0x00
+	coreSortTable    = 0x01
+	coreSortMemory   = 0x02
+	coreSortGlobal   = 0x03
+	coreSortType     = 0x10
+	coreSortModule   = 0x11
+	coreSortInstance = 0x12
+
+	sortCore      = 0x00
+	sortFunc      = 0x01
+	sortValue     = 0x02
+	sortType      = 0x03
+	sortComponent = 0x04
+	sortInstance  = 0x05
+)
+
+// magic and layer distinguish a component from a core module, whose version is 1 instead.
+var (
+	magic = []byte{0x00, 0x61, 0x73, 0x6D}
+	layer = []byte{0x0d, 0x00, 0x01, 0x00}
+)
+
+// ErrNotComponent is returned when decoding a binary which isn't a component, such as a core module.
+var ErrNotComponent = errors.New("invalid magic number or layer of component")
+
+// IsComponent returns true if the binary has the preamble of a component, as opposed to a core module.
+func IsComponent(binary []byte) bool {
+	return len(binary) >= 8 && bytes.Equal(binary[:4], magic) && bytes.Equal(binary[4:8], layer)
+}
+
+// scope is a type index space. Instance types have their own, in which outer aliases refer to the parent.
+type scope struct {
+	parent *scope
+	types  []*TypeDef
+}
+
+func (s *scope) typeDef(idx uint32) (*TypeDef, error) {
+	if idx >= uint32(len(s.types)) {
+		return nil, fmt.Errorf("type index %d out of range", idx)
+	}
+	return s.types[idx], nil
+}
+
+type decoder struct {
+	r *bytes.Reader
+	c *Component
+	// types is the type index space of the component.
+	types *scope
+}
+
+// DecodeComponent decodes a component binary. Nested components, values and start functions are not supported.
+//
+// See https://github.com/WebAssembly/component-model/blob/main/design/mvp/Binary.md
+func DecodeComponent(binary []byte) (*Component, error) {
+	if !IsComponent(binary) {
+		return nil, ErrNotComponent
+	}
+	d := &decoder{r: bytes.NewReader(binary[8:]), c: &Component{}, types: &scope{}}
+	for {
+		sectionID, err := d.r.ReadByte()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("read section id: %w", err)
+		}
+
+		size, err := d.u32()
+		if err != nil {
+			return nil, fmt.Errorf("get size of section %d: %w", sectionID, err)
+		}
+		if uint64(size) > uint64(d.r.Len()) {
+			return nil, fmt.Errorf("section %d of size %d exceeds the remaining %d bytes", sectionID, size, d.r.Len())
+		}
+
+		start := d.r.Len()
+		switch sectionID {
+		case sectionIDCustom:
+			_, err = d.r.Seek(int64(size), io.SeekCurrent)
+		case sectionIDCoreModule:
+			offset := len(binary) - start
+			d.c.Modules = append(d.c.Modules, binary[offset:offset+int(size)])
+			_, err = d.r.Seek(int64(size), io.SeekCurrent)
+		case sectionIDCoreInstance:
+			err = d.vec(d.decodeCoreInstance)
+		case sectionIDCoreType:
+			err = d.vec(d.decodeCoreType)
+		case sectionIDInstance:
+			err = d.vec(d.decodeInstance)
+		case sectionIDAlias:
+			err = d.vec(d.decodeAlias)
+		case sectionIDType:
+			err = d.vec(func() error {
+				td, err := d.decodeTypeDef(d.types)
+				if err == nil {
+					d.types.types = append(d.types.types, td)
+				}
+				return err
+			})
+		case sectionIDCanon:
+			err = d.vec(d.decodeCanon)
+		case sectionIDImport:
+			err = d.vec(d.decodeImport)
+		case sectionIDExport:
+			err = d.vec(d.decodeExport)
+		case sectionIDComponent:
+			err = errors.New("nested components are not supported")
+		case sectionIDStart:
+			err = errors.New("start functions are not supported")
+		case sectionIDValue:
+			err = errors.New("values are not supported")
+		default:
+			err = errors.New("invalid section id")
+		}
+		if err == nil && start-d.r.Len() != int(size) {
+			err = fmt.Errorf("invalid section length: expected to be %d but got %d", size, start-d.r.Len())
+		}
+		if err != nil {
+			return nil, fmt.Errorf("section %d: %w", sectionID, err)
+		}
+	}
+	return d.c, nil
+}
+
+func (d *decoder) u32() (uint32, error) {
+	v, _, err := leb128.DecodeUint32(d.r)
+	return v, err
+}
+
+// index reads an index and checks it is less than count.
+func (d *decoder) index(count int, kind string) (uint32, error) {
+	idx, err := d.u32()
+	if err != nil {
+		return 0, fmt.Errorf("read %s index: %w", kind, err)
+	}
+	if idx >= uint32(count) {
+		return 0, fmt.Errorf("%s index %d out of range", kind, idx)
+	}
+	return idx, nil
+}
+
+func (d *decoder) vec(fn func() error) error {
+	n, err := d.u32()
+	if err != nil {
+		return fmt.Errorf("read vector size: %w", err)
+	}
+	for i := uint32(0); i < n; i++ {
+		if err = fn(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *decoder) name() (string, error) {
+	size, err := d.u32()
+	if err != nil {
+		return "", fmt.Errorf("read name size: %w", err)
+	}
+	if uint64(size) > uint64(d.r.Len()) {
+		return "", fmt.Errorf("name of size %d exceeds the remaining %d bytes", size, d.r.Len())
+	}
+	buf := make([]byte, size)
+	_, _ = io.ReadFull(d.r, buf)
+	if !utf8.Valid(buf) {
+		return "", errors.New("name is not valid UTF-8")
+	}
+	return string(buf), nil
+}
+
+// externName reads the name of an import or export, which is prefixed with its form.
+func (d *decoder) externName() (string, error) {
+	b, err := d.r.ReadByte()
+	if err != nil {
+		return "", fmt.Errorf("read name: %w", err)
+	}
+	if b != 0x00 && b != 0x01 {
+		return "", fmt.Errorf("invalid name form: %#x", b)
+	}
+	return d.name()
+}
+
+// optional reads the presence flag of an optional immediate.
+func (d *decoder) optional() (bool, error) {
+	b, err := d.r.ReadByte()
+	if err != nil {
+		return false, err
+	}
+	switch b {
+	case 0x00:
+		return false, nil
+	case 0x01:
+		return true, nil
+	}
+	return false, fmt.Errorf("invalid optional flag: %#x", b)
+}
+
+func (d *decoder) decodeCoreInstance() error {
+	kind, err := d.r.ReadByte()
+	if err != nil {
+		return fmt.Errorf("read core instance: %w", err)
+	}
+	ci := &CoreInstance{}
+	switch kind {
+	case 0x00:
+		if ci.Module, err = d.index(len(d.c.Modules), "core module"); err != nil {
+			return err
+		}
+		err = d.vec(func() error {
+			var arg CoreInstantiateArg
+			var err error
+			if arg.Name, err = d.name(); err != nil {
+				return err
+			}
+			if sort, err := d.r.ReadByte(); err != nil {
+				return err
+			} else if sort != coreSortInstance {
+				return fmt.Errorf("instantiation argument %s must be a core instance", arg.Name)
+			}
+			if arg.Instance, err = d.index(len(d.c.CoreInstances), "core instance"); err != nil {
+				return err
+			}
+			ci.Args = append(ci.Args, arg)
+			return nil
+		})
+	case 0x01:
+		ci.FromExports = true
+		err = d.vec(func() error {
+			var e CoreInlineExport
+			var err error
+			if e.Name, err = d.name(); err != nil {
+				return err
+			}
+			if e.Type, e.Index, err = d.coreSortIdx(); err != nil {
+				return err
+			}
+			ci.Exports = append(ci.Exports, e)
+			return nil
+		})
+	default:
+		return fmt.Errorf("invalid core instance: %#x", kind)
+	}
+	if err != nil {
+		return err
+	}
+	d.c.CoreInstances = append(d.c.CoreInstances, ci)
+	return nil
+}
+
+// coreSortIdx reads a reference to a core function, table, memory or global.
+func (d *decoder) coreSortIdx() (api.ExternType, uint32, error) {
+	sort, err := d.r.ReadByte()
+	if err != nil {
+		return 0, 0, err
+	}
+	var et api.ExternType
+	var count int
+	switch sort {
+	case coreSortFunc:
+		et, count = api.ExternTypeFunc, len(d.c.CoreFuncs)
+	case coreSortTable:
+		et, count = api.ExternTypeTable, len(d.c.CoreTables)
+	case coreSortMemory:
+		et, count = api.ExternTypeMemory, len(d.c.CoreMemories)
+	case coreSortGlobal:
+		et, count = api.ExternTypeGlobal, len(d.c.CoreGlobals)
+	default:
+		return 0, 0, fmt.Errorf("unsupported core sort: %#x", sort)
+	}
+	idx, err := d.index(count, "core "+api.ExternTypeName(et))
+	return et, idx, err
+}
+
+// decodeCoreType decodes a core function type, which is skipped as nothing supported refers to it.
+func (d *decoder) decodeCoreType() error {
+	b, err := d.r.ReadByte()
+	if err != nil {
+		return fmt.Errorf("read core type: %w", err)
+	}
+	if b != 0x60 {
+		return fmt.Errorf("unsupported core type: %#x", b)
+	}
+	for i := 0; i < 2; i++ {
+		if err = d.vec(func() error {
+			vt, err := d.r.ReadByte()
+			if err != nil {
+				return err
+			}
+			switch vt {
+			case wasm.ValueTypeI32, wasm.ValueTypeI64, wasm.ValueTypeF32, wasm.ValueTypeF64, wasm.ValueTypeV128,
+				wasm.ValueTypeFuncref, wasm.ValueTypeExternref:
+				return nil
+			}
+			return fmt.Errorf("invalid core value type: %#x", vt)
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *decoder) decodeInstance() error {
+	kind, err := d.r.ReadByte()
+	if err != nil {
+		return fmt.Errorf("read instance: %w", err)
+	}
+	if kind != 0x01 {
+		return errors.New("instantiating components is not supported")
+	}
+	inst := &Instance{Type: &InstanceType{Exports: map[string]*Extern{}}, Exports: map[string]*InstanceExport{}}
+	if err = d.vec(func() error {
+		name, err := d.externName()
+		if err != nil {
+			return err
+		}
+		kind, idx, err := d.sortIdx()
+		if err != nil {
+			return err
+		}
+		if _, ok := inst.Exports[name]; ok {
+			return fmt.Errorf("duplicate instance export %s", name)
+		}
+		inst.Exports[name] = &InstanceExport{Kind: kind, Index: idx}
+		inst.Type.Exports[name] = d.externOf(kind, idx)
+		return nil
+	}); err != nil {
+		return err
+	}
+	d.c.Instances = append(d.c.Instances, inst)
+	return nil
+}
+
+// sortIdx reads a reference to a component function, type or instance.
+func (d *decoder) sortIdx() (ExternKind, uint32, error) {
+	sort, err := d.r.ReadByte()
+	if err != nil {
+		return 0, 0, err
+	}
+	var kind ExternKind
+	var count int
+	switch sort {
+	case sortFunc:
+		kind, count = ExternKindFunc, len(d.c.Funcs)
+	case sortType:
+		kind, count = ExternKindType, len(d.types.types)
+	case sortInstance:
+		kind, count = ExternKindInstance, len(d.c.Instances)
+	default:
+		return 0, 0, fmt.Errorf("unsupported sort: %#x", sort)
+	}
+	idx, err := d.index(count, sortName(kind))
+	return kind, idx, err
+}
+
+// externOf returns the type of the definition idx of the index space of kind.
+func (d *decoder) externOf(kind ExternKind, idx uint32) *Extern {
+	switch kind {
+	case ExternKindFunc:
+		return &Extern{Kind: kind, Func: d.c.Funcs[idx].Type}
+	case ExternKindType:
+		return &Extern{Kind: kind, Type: d.types.types[idx]}
+	default: // ExternKindInstance
+		return &Extern{Kind: kind, Instance: d.c.Instances[idx].Type}
+	}
+}
+
+func sortName(kind ExternKind) string {
+	switch kind {
+	case ExternKindModule:
+		return "core module"
+	case ExternKindFunc:
+		return "func"
+	case ExternKindValue:
+		return "value"
+	case ExternKindType:
+		return "type"
+	case ExternKindComponent:
+		return "component"
+	}
+	return "instance"
+}
+
+func (d *decoder) decodeAlias() error {
+	sort, err := d.r.ReadByte()
+	if err != nil {
+		return fmt.Errorf("read alias sort: %w", err)
+	}
+	var coreSort byte
+	if sort == sortCore {
+		if coreSort, err = d.r.ReadByte(); err != nil {
+			return fmt.Errorf("read alias core sort: %w", err)
+		}
+	}
+	target, err := d.r.ReadByte()
+	if err != nil {
+		return fmt.Errorf("read alias target: %w", err)
+	}
+
+	switch target {
+	case 0x00: // export of a component instance
+		idx, err := d.index(len(d.c.Instances), "instance")
+		if err != nil {
+			return err
+		}
+		name, err := d.name()
+		if err != nil {
+			return err
+		}
+		return d.aliasInstanceExport(sort, d.c.Instances[idx], name)
+	case 0x01: // export of a core instance
+		if sort != sortCore {
+			return errors.New("core export alias must have a core sort")
+		}
+		var e CoreExport
+		if e.Instance, err = d.index(len(d.c.CoreInstances), "core instance"); err != nil {
+			return err
+		}
+		if e.Name, err = d.name(); err != nil {
+			return err
+		}
+		switch coreSort {
+		case coreSortFunc:
+			d.c.CoreFuncs = append(d.c.CoreFuncs, &CoreFunc{Kind: CoreFuncKindAlias, Alias: e})
+		case coreSortTable:
+			d.c.CoreTables = append(d.c.CoreTables, e)
+		case coreSortMemory:
+			d.c.CoreMemories = append(d.c.CoreMemories, e)
+		case coreSortGlobal:
+			d.c.CoreGlobals = append(d.c.CoreGlobals, e)
+		default:
+			return fmt.Errorf("unsupported core export alias sort: %#x", coreSort)
+		}
+		return nil
+	case 0x02: // outer definition
+		ct, err := d.u32()
+		if err != nil {
+			return err
+		}
+		idx, err := d.u32()
+		if err != nil {
+			return err
+		}
+		if sort != sortType {
+			return fmt.Errorf("unsupported outer alias sort: %#x", sort)
+		} else if ct != 0 {
+			return fmt.Errorf("outer alias count %d out of range", ct)
+		}
+		td, err := d.types.typeDef(idx)
+		if err != nil {
+			return err
+		}
+		d.types.types = append(d.types.types, td)
+		return nil
+	}
+	return fmt.Errorf("invalid alias target: %#x", target)
+}
+
+func (d *decoder) aliasInstanceExport(sort byte, inst *Instance, name string) error {
+	e, ok := inst.Type.Exports[name]
+	if !ok {
+		return fmt.Errorf("instance has no export %s", name)
+	}
+	var kind ExternKind
+	switch sort {
+	case sortFunc:
+		kind = ExternKindFunc
+	case sortType:
+		kind = ExternKindType
+	case sortInstance:
+		kind = ExternKindInstance
+	default:
+		return fmt.Errorf("unsupported export alias sort: %#x", sort)
+	}
+	if e.Kind != kind {
+		return fmt.Errorf("export %s is a %s, not a %s", name, sortName(e.Kind), sortName(kind))
+	}
+
+	switch kind {
+	case ExternKindFunc:
+		if inst.Import == "" {
+			d.c.Funcs = append(d.c.Funcs, d.c.Funcs[inst.Exports[name].Index])
+		} else {
+			d.c.Funcs = append(d.c.Funcs, &Func{Kind: FuncKindImport, Type: e.Func, Import: inst.Import, Name: name})
+		}
+	case ExternKindType:
+		d.types.types = append(d.types.types, e.Type)
+	case ExternKindInstance:
+		if inst.Import != "" {
+			return errors.New("instances nested in imported instances are not supported")
+		}
+		d.c.Instances = append(d.c.Instances, d.c.Instances[inst.Exports[name].Index])
+	}
+	return nil
+}
+
+func (d *decoder) decodeCanon() error {
+	kind, err := d.r.ReadByte()
+	if err != nil {
+		return fmt.Errorf("read canon: %w", err)
+	}
+	switch kind {
+	case 0x00: // lift
+		if b, err := d.r.ReadByte(); err != nil || b != 0x00 {
+			return errors.New("invalid canon lift")
+		}
+		f := &Func{Kind: FuncKindLift}
+		if f.CoreFunc, err = d.index(len(d.c.CoreFuncs), "core func"); err != nil {
+			return err
+		}
+		if f.Options, err = d.canonOptions(); err != nil {
+			return err
+		}
+		idx, err := d.u32()
+		if err != nil {
+			return err
+		}
+		td, err := d.types.typeDef(idx)
+		if err != nil {
+			return err
+		} else if td.Func == nil {
+			return fmt.Errorf("type %d is not a func type", idx)
+		}
+		f.Type = td.Func
+		d.c.Funcs = append(d.c.Funcs, f)
+	case 0x01: // lower
+		if b, err := d.r.ReadByte(); err != nil || b != 0x00 {
+			return errors.New("invalid canon lower")
+		}
+		f := &CoreFunc{Kind: CoreFuncKindLower}
+		if f.Func, err = d.index(len(d.c.Funcs), "func"); err != nil {
+			return err
+		}
+		if f.Options, err = d.canonOptions(); err != nil {
+			return err
+		}
+		d.c.CoreFuncs = append(d.c.CoreFuncs, f)
+	case 0x02, 0x03, 0x04: // resource.new, resource.drop, resource.rep
+		idx, err := d.u32()
+		if err != nil {
+			return err
+		}
+		td, err := d.types.typeDef(idx)
+		if err != nil {
+			return err
+		} else if td.Resource == nil {
+			return fmt.Errorf("type %d is not a resource type", idx)
+		}
+		f := &CoreFunc{Kind: CoreFuncKindResourceNew, Resource: td.Resource}
+		switch kind {
+		case 0x03:
+			f.Kind = CoreFuncKindResourceDrop
+		case 0x04:
+			f.Kind = CoreFuncKindResourceRep
+		}
+		if f.Kind != CoreFuncKindResourceDrop && td.Resource.Imported {
+			return fmt.Errorf("resource %s is not defined by the component", td.Resource.Name)
+		}
+		d.c.CoreFuncs = append(d.c.CoreFuncs, f)
+	default:
+		return fmt.Errorf("unsupported canon: %#x", kind)
+	}
+	return nil
+}
+
+func (d *decoder) canonOptions() (opts CanonOptions, err error) {
+	err = d.vec(func() error {
+		opt, err := d.r.ReadByte()
+		if err != nil {
+			return err
+		}
+		switch opt {
+		case 0x00:
+			opts.StringEncoding = StringEncodingUTF8
+		case 0x01:
+			opts.StringEncoding = StringEncodingUTF16
+		case 0x02:
+			opts.StringEncoding = StringEncodingLatin1UTF16
+		case 0x03:
+			idx, err := d.index(len(d.c.CoreMemories), "core memory")
+			if err != nil {
+				return err
+			}
+			opts.Memory = &idx
+		case 0x04, 0x05:
+			idx, err := d.index(len(d.c.CoreFuncs), "core func")
+			if err != nil {
+				return err
+			}
+			if opt == 0x04 {
+				opts.Realloc = &idx
+			} else {
+				opts.PostReturn = &idx
+			}
+		default:
+			return fmt.Errorf("unsupported canon option: %#x", opt)
+		}
+		return nil
+	})
+	return
+}
+
+func (d *decoder) decodeImport() error {
+	name, err := d.externName()
+	if err != nil {
+		return err
+	}
+	e, err := d.externDesc(d.types, name)
+	if err != nil {
+		return fmt.Errorf("import %s: %w", name, err)
+	}
+	switch e.Kind {
+	case ExternKindFunc:
+		d.c.Funcs = append(d.c.Funcs, &Func{Kind: FuncKindImport, Type: e.Func, Import: name})
+	case ExternKindInstance:
+		d.c.Instances = append(d.c.Instances, &Instance{Import: name, Type: e.Instance})
+	case ExternKindType:
+		d.types.types = append(d.types.types, e.Type)
+	default:
+		return fmt.Errorf("import %s: importing a %s is not supported", name, sortName(e.Kind))
+	}
+	d.c.Imports = append(d.c.Imports, &Import{Name: name, Extern: e})
+	return nil
+}
+
+func (d *decoder) decodeExport() error {
+	name, err := d.externName()
+	if err != nil {
+		return err
+	}
+	kind, idx, err := d.sortIdx()
+	if err != nil {
+		return fmt.Errorf("export %s: %w", name, err)
+	}
+	// An optional type ascription must be consistent with the definition, so it is only decoded.
+	if ok, err := d.optional(); err != nil {
+		return err
+	} else if ok {
+		if _, err = d.externDesc(d.types, name); err != nil {
+			return fmt.Errorf("export %s: %w", name, err)
+		}
+	}
+
+	// Exports introduce a new index referring to the exported definition.
+	switch kind {
+	case ExternKindFunc:
+		d.c.Funcs = append(d.c.Funcs, d.c.Funcs[idx])
+	case ExternKindType:
+		td := d.types.types[idx]
+		if td.Resource != nil && td.Resource.Name == "" {
+			td.Resource.Name = name
+		}
+		d.types.types = append(d.types.types, td)
+	case ExternKindInstance:
+		d.c.Instances = append(d.c.Instances, d.c.Instances[idx])
+	}
+	d.c.Exports = append(d.c.Exports, &Export{Name: name, Kind: kind, Index: idx})
+	return nil
+}
+
+// externDesc reads the type of an import or export named name, whose types are resolved in s.
+func (d *decoder) externDesc(s *scope, name string) (*Extern, error) {
+	kind, err := d.r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	switch kind {
+	case 0x01, 0x05: // func, instance
+		idx, err := d.u32()
+		if err != nil {
+			return nil, err
+		}
+		td, err := s.typeDef(idx)
+		if err != nil {
+			return nil, err
+		}
+		if kind == 0x01 {
+			if td.Func == nil {
+				return nil, fmt.Errorf("type %d is not a func type", idx)
+			}
+			return &Extern{Kind: ExternKindFunc, Func: td.Func}, nil
+		}
+		if td.Instance == nil {
+			return nil, fmt.Errorf("type %d is not an instance type", idx)
+		}
+		return &Extern{Kind: ExternKindInstance, Instance: td.Instance}, nil
+	case 0x03: // type
+		bound, err := d.r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		switch bound {
+		case 0x00:
+			idx, err := d.u32()
+			if err != nil {
+				return nil, err
+			}
+			td, err := s.typeDef(idx)
+			if err != nil {
+				return nil, err
+			}
+			return &Extern{Kind: ExternKindType, Type: td}, nil
+		case 0x01:
+			return &Extern{Kind: ExternKindType, Type: &TypeDef{Resource: &ResourceType{Name: name, Imported: true}}}, nil
+		}
+		return nil, fmt.Errorf("invalid type bound: %#x", bound)
+	case 0x00, 0x02, 0x04:
+		return nil, fmt.Errorf("%s imports and exports are not supported",
+			[]string{"core module", "", "value", "", "component"}[kind])
+	}
+	return nil, fmt.Errorf("invalid extern kind: %#x", kind)
+}
+
+// decodeTypeDef decodes a type definition whose type references are resolved in s.
+func (d *decoder) decodeTypeDef(s *scope) (*TypeDef, error) {
+	b, err := d.r.ReadByte()
+	if err != nil {
+		return nil, fmt.Errorf("read type: %w", err)
+	}
+	switch b {
+	case 0x40:
+		ft, err := d.decodeFuncType(s)
+		return &TypeDef{Func: ft}, err
+	case 0x42:
+		it, err := d.decodeInstanceType(s)
+		return &TypeDef{Instance: it}, err
+	case 0x3f:
+		if rep, err := d.r.ReadByte(); err != nil || rep != wasm.ValueTypeI32 {
+			return nil, errors.New("resource representation must be i32")
+		}
+		r := &ResourceType{}
+		if ok, err := d.optional(); err != nil {
+			return nil, err
+		} else if ok {
+			dtor, err := d.index(len(d.c.CoreFuncs), "core func")
+			if err != nil {
+				return nil, err
+			}
+			r.Dtor = &dtor
+		}
+		return &TypeDef{Resource: r}, nil
+	case 0x41:
+		return nil, errors.New("component types are not supported")
+	}
+	vt, err := d.decodeDefValType(s, b)
+	return &TypeDef{Val: vt}, err
+}
+
+func (d *decoder) decodeDefValType(s *scope, b byte) (*ValType, error) {
+	if k, ok := primitiveKind(b); ok {
+		return Primitive(k), nil
+	}
+	switch b {
+	case 0x72: // record
+		fields, err := d.labeledValTypes(s)
+		if err != nil {
+			return nil, err
+		} else if len(fields) == 0 {
+			return nil, errors.New("record must have at least one field")
+		}
+		return NewRecord(fields...), nil
+	case 0x71: // variant
+		var cases []Case
+		if err := d.vec(func() error {
+			var c Case
+			var err error
+			if c.Name, err = d.name(); err != nil {
+				return err
+			}
+			if ok, err := d.optional(); err != nil {
+				return err
+			} else if ok {
+				if c.Type, err = d.valType(s); err != nil {
+					return err
+				}
+			}
+			// Skip the deprecated refinement.
+			if ok, err := d.optional(); err != nil {
+				return err
+			} else if ok {
+				if _, err = d.u32(); err != nil {
+					return err
+				}
+			}
+			cases = append(cases, c)
+			return nil
+		}); err != nil {
+			return nil, err
+		} else if len(cases) == 0 {
+			return nil, errors.New("variant must have at least one case")
+		}
+		return NewVariant(cases...), nil
+	case 0x70, 0x6b: // list, option
+		elem, err := d.valType(s)
+		if err != nil {
+			return nil, err
+		}
+		if b == 0x70 {
+			return NewList(elem), nil
+		}
+		return NewOption(elem), nil
+	case 0x6f: // tuple
+		var elems []*ValType
+		if err := d.vec(func() error {
+			t, err := d.valType(s)
+			elems = append(elems, t)
+			return err
+		}); err != nil {
+			return nil, err
+		}
+		return NewTuple(elems...), nil
+	case 0x6e, 0x6d: // flags, enum
+		var labels []string
+		if err := d.vec(func() error {
+			l, err := d.name()
+			labels = append(labels, l)
+			return err
+		}); err != nil {
+			return nil, err
+		}
+		if b == 0x6e {
+			if len(labels) == 0 || len(labels) > 32 {
+				return nil, fmt.Errorf("flags must have between 1 and 32 labels, but had %d", len(labels))
+			}
+			return NewFlags(labels...), nil
+		}
+		if len(labels) == 0 {
+			return nil, errors.New("enum must have at least one label")
+		}
+		return NewEnum(labels...), nil
+	case 0x6a: // result
+		var ok, err *ValType
+		for _, t := range []**ValType{&ok, &err} {
+			if present, e := d.optional(); e != nil {
+				return nil, e
+			} else if present {
+				if *t, e = d.valType(s); e != nil {
+					return nil, e
+				}
+			}
+		}
+		return NewResult(ok, err), nil
+	case 0x69, 0x68: // own, borrow
+		idx, err := d.u32()
+		if err != nil {
+			return nil, err
+		}
+		td, err := s.typeDef(idx)
+		if err != nil {
+			return nil, err
+		} else if td.Resource == nil {
+			return nil, fmt.Errorf("type %d is not a resource type", idx)
+		}
+		if b == 0x69 {
+			return NewOwn(td.Resource), nil
+		}
+		return NewBorrow(td.Resource), nil
+	}
+	return nil, fmt.Errorf("unsupported type: %#x", b)
+}
+
+// primitiveKind returns the kind of a primitive value type, encoded as a single byte.
+func primitiveKind(b byte) (Kind, bool) {
+	if b >= 0x73 && b <= 0x7f {
+		return Kind(0x7f - b), true
+	}
+	return 0, false
+}
+
+// valType reads either a primitive value type or the index of a defined value type.
+func (d *decoder) valType(s *scope) (*ValType, error) {
+	v, _, err := leb128.DecodeInt33AsInt64(d.r)
+	if err != nil {
+		return nil, fmt.Errorf("read value type: %w", err)
+	}
+	if v < 0 {
+		if k, ok := primitiveKind(byte(v & 0x7f)); ok {
+			return Primitive(k), nil
+		}
+		return nil, fmt.Errorf("unsupported value type: %#x", byte(v&0x7f))
+	}
+	td, err := s.typeDef(uint32(v))
+	if err != nil {
+		return nil, err
+	} else if td.Val == nil {
+		return nil, fmt.Errorf("type %d is not a value type", v)
+	}
+	return td.Val, nil
+}
+
+func (d *decoder) labeledValTypes(s *scope) (fields []Field, err error) {
+	err = d.vec(func() error {
+		var f Field
+		var err error
+		if f.Name, err = d.name(); err != nil {
+			return err
+		}
+		if f.Type, err = d.valType(s); err != nil {
+			return err
+		}
+		fields = append(fields, f)
+		return nil
+	})
+	return
+}
+
+func (d *decoder) decodeFuncType(s *scope) (*FuncType, error) {
+	params, err := d.labeledValTypes(s)
+	if err != nil {
+		return nil, err
+	}
+	var results []Field
+	b, err := d.r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	switch b {
+	case 0x00:
+		t, err := d.valType(s)
+		if err != nil {
+			return nil, err
+		}
+		results = []Field{{Type: t}}
+	case 0x01:
+		if results, err = d.labeledValTypes(s); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("invalid result list: %#x", b)
+	}
+	return NewFuncType(params, results), nil
+}
+
+func (d *decoder) decodeInstanceType(parent *scope) (*InstanceType, error) {
+	s := &scope{parent: parent}
+	it := &InstanceType{Exports: map[string]*Extern{}}
+	err := d.vec(func() error {
+		kind, err := d.r.ReadByte()
+		if err != nil {
+			return err
+		}
+		switch kind {
+		case 0x00: // core type
+			return d.decodeCoreType()
+		case 0x01:
+			td, err := d.decodeTypeDef(s)
+			if err != nil {
+				return err
+			}
+			s.types = append(s.types, td)
+		case 0x02:
+			return d.decodeOuterAlias(s)
+		case 0x04:
+			name, err := d.externName()
+			if err != nil {
+				return err
+			}
+			e, err := d.externDesc(s, name)
+			if err != nil {
+				return fmt.Errorf("export %s: %w", name, err)
+			}
+			if e.Kind == ExternKindType {
+				s.types = append(s.types, e.Type)
+			}
+			it.Exports[name] = e
+		default:
+			return fmt.Errorf("invalid instance type declaration: %#x", kind)
+		}
+		return nil
+	})
+	return it, err
+}
+
+// decodeOuterAlias decodes an alias to a type of an enclosing scope of s.
+func (d *decoder) decodeOuterAlias(s *scope) error {
+	sort, err := d.r.ReadByte()
+	if err != nil {
+		return err
+	}
+	if target, err := d.r.ReadByte(); err != nil {
+		return err
+	} else if sort != sortType || target != 0x02 {
+		return errors.New("only outer type aliases are supported in instance types")
+	}
+	ct, err := d.u32()
+	if err != nil {
+		return err
+	}
+	idx, err := d.u32()
+	if err != nil {
+		return err
+	}
+	outer := s
+	for i := uint32(0); i < ct && outer != nil; i++ {
+		outer = outer.parent
+	}
+	if outer == nil {
+		return fmt.Errorf("outer alias count %d out of range", ct)
+	}
+	td, err := outer.typeDef(idx)
+	if err != nil {
+		return err
+	}
+	s.types = append(s.types, td)
+	return nil
+}
@@ -0,0 +1,140 @@
+package component
+
+import (
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/leb128"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func encodeComponent(sections ...[]byte) []byte {
+	ret := append(append([]byte{}, magic...), layer...)
+	for _, s := range sections {
+		ret = append(ret, s...)
+	}
+	return ret
+}
+
+func encodeSection(id byte, items ...[]byte) []byte {
+	contents := leb128.EncodeUint32(uint32(len(items)))
+	for _, i := range items {
+		contents = append(contents, i...)
+	}
+	return append(append([]byte{id}, leb128.EncodeUint32(uint32(len(contents)))...), contents...)
+}
+
+func encodeName(s string) []byte {
+	return append(leb128.EncodeUint32(uint32(len(s))), s...)
+}
+
+func join(parts ...[]byte) (ret []byte) {
+	for _, p := range parts {
+		ret = append(ret, p...)
+	}
+	return
+}
+
+func TestIsComponent(t *testing.T) {
+	require.True(t, IsComponent(encodeComponent()))
+	require.False(t, IsComponent([]byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00}))
+	require.False(t, IsComponent(magic))
+}
+
+func TestDecodeComponent(t *testing.T) {
+	c, err := DecodeComponent(encodeComponent(
+		encodeSection(sectionIDType,
+			[]byte{0x72, 0x02, 0x01, 'x', 0x7a, 0x01, 'y', 0x7a},                        // type 0: record { x: s32, y: s32 }
+			[]byte{0x71, 0x02, 0x01, 'a', 0x00, 0x00, 0x01, 'b', 0x01, 0x73, 0x00},      // type 1: variant { a, b(string) }
+			[]byte{0x6a, 0x00, 0x01, 0x00},                                              // type 2: result<_, record>
+			[]byte{0x6e, 0x03, 0x01, 'a', 0x01, 'b', 0x01, 'c'},                         // type 3: flags
+			join([]byte{0x40, 0x02, 0x01, 'p', 0x00, 0x01, 'v', 0x01}, []byte{0x00, 2}), // type 4: func
+		),
+		encodeSection(sectionIDImport, join([]byte{0x00}, encodeName("f"), []byte{0x01, 4})),
+		encodeSection(sectionIDExport, join([]byte{0x00}, encodeName("g"), []byte{0x01, 0, 0x00})),
+		encodeSection(sectionIDCustom, join(encodeName("name"), []byte{1, 2, 3})[1:]),
+	))
+	require.NoError(t, err)
+
+	require.Equal(t, 2, len(c.Funcs))
+	require.Equal(t, c.Funcs[0], c.Funcs[1])
+	require.Equal(t, FuncKindImport, c.Funcs[0].Kind)
+	require.Equal(t, "f", c.Funcs[0].Import)
+	require.Equal(t, "func(p: record, v: variant) -> result<_, record>", c.Funcs[0].Type.String())
+	require.Equal(t, []*Export{{Name: "g", Kind: ExternKindFunc, Index: 0}}, c.Exports)
+
+	v := c.Funcs[0].Type.Params[1].Type
+	require.Equal(t, []Case{{Name: "a"}, {Name: "b", Type: Primitive(KindString)}}, v.Cases)
+}
+
+func TestDecodeComponent_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  []byte
+		expErr string
+	}{
+		{
+			name:   "core module",
+			input:  []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00},
+			expErr: "invalid magic number or layer of component",
+		},
+		{
+			name:   "nested component",
+			input:  encodeComponent([]byte{sectionIDComponent, 0x00}),
+			expErr: "section 4: nested components are not supported",
+		},
+		{
+			name:   "invalid section",
+			input:  encodeComponent([]byte{13, 0x00}),
+			expErr: "section 13: invalid section id",
+		},
+		{
+			name:   "section too long",
+			input:  encodeComponent([]byte{sectionIDType, 0x05, 0x00}),
+			expErr: "section 7 of size 5 exceeds the remaining 1 bytes",
+		},
+		{
+			name:   "invalid section length",
+			input:  encodeComponent([]byte{sectionIDType, 0x02, 0x00, 0x00}),
+			expErr: "section 7: invalid section length: expected to be 2 but got 1",
+		},
+		{
+			name:   "unknown type",
+			input:  encodeComponent(encodeSection(sectionIDType, []byte{0x70, 0x00})),
+			expErr: "section 7: type index 0 out of range",
+		},
+		{
+			name: "import of non func type",
+			input: encodeComponent(
+				encodeSection(sectionIDType, []byte{0x70, 0x73}),
+				encodeSection(sectionIDImport, join([]byte{0x00}, encodeName("f"), []byte{0x01, 0})),
+			),
+			expErr: "section 10: import f: type 0 is not a func type",
+		},
+		{
+			name: "resource.new of imported resource",
+			input: encodeComponent(
+				encodeSection(sectionIDImport, join([]byte{0x00}, encodeName("r"), []byte{0x03, 0x01})),
+				encodeSection(sectionIDCanon, []byte{0x02, 0}),
+			),
+			expErr: "section 8: resource r is not defined by the component",
+		},
+		{
+			name:   "canon lift of unknown core func",
+			input:  encodeComponent(encodeSection(sectionIDCanon, []byte{0x00, 0x00, 0, 0x00, 0})),
+			expErr: "section 8: core func index 0 out of range",
+		},
+		{
+			name:   "empty flags",
+			input:  encodeComponent(encodeSection(sectionIDType, []byte{0x6e, 0x00})),
+			expErr: "section 7: flags must have between 1 and 32 labels, but had 0",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := DecodeComponent(tc.input)
+			require.EqualError(t, err, tc.expErr)
+		})
+	}
+}
@@ -0,0 +1,192 @@
+package component
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// Go types of component value types:
+//
+//   - Primitives map to the Go kind of the same size, char to rune and string to string.
+//   - list<T> maps to a slice of T.
+//   - record and tuple map to a struct of the same count of exported fields, matched in order.
+//   - variant and result map to a struct with a pointer field per case, in order, of which exactly one is non-nil.
+//     Cases without payload are pointers to an empty struct.
+//   - option<T> maps to *T, enum to an integer holding the case index, and flags to an unsigned integer holding
+//     a bit per label.
+//   - own and borrow map to the representation of the resource: any Go value for a resource implemented by the
+//     host, or uint32 for one defined by a component.
+//
+// Any of them can be an empty interface, in which case values are lifted to the type returned by GoType.
+
+var emptyInterface = reflect.TypeOf((*interface{})(nil)).Elem()
+
+// GoType returns the default Go type of t.
+func GoType(t *ValType) reflect.Type {
+	switch t.Kind {
+	case KindBool:
+		return reflect.TypeOf(false)
+	case KindS8:
+		return reflect.TypeOf(int8(0))
+	case KindU8:
+		return reflect.TypeOf(uint8(0))
+	case KindS16:
+		return reflect.TypeOf(int16(0))
+	case KindU16:
+		return reflect.TypeOf(uint16(0))
+	case KindS32, KindChar:
+		return reflect.TypeOf(int32(0))
+	case KindU32, KindEnum, KindFlags:
+		return reflect.TypeOf(uint32(0))
+	case KindS64:
+		return reflect.TypeOf(int64(0))
+	case KindU64:
+		return reflect.TypeOf(uint64(0))
+	case KindF32:
+		return reflect.TypeOf(float32(0))
+	case KindF64:
+		return reflect.TypeOf(float64(0))
+	case KindString:
+		return reflect.TypeOf("")
+	case KindList:
+		return reflect.SliceOf(GoType(t.Elem))
+	case KindOption:
+		return reflect.PtrTo(GoType(t.Elem))
+	case KindRecord, KindTuple:
+		fields := make([]reflect.StructField, len(t.Fields))
+		for i, f := range t.Fields {
+			fields[i] = reflect.StructField{Name: fmt.Sprintf("F%d", i), Type: GoType(f.Type)}
+			if f.Name != "" {
+				fields[i].Name, fields[i].Tag = goName(f.Name), reflect.StructTag(fmt.Sprintf(`wit:"%s"`, f.Name))
+			}
+		}
+		return reflect.StructOf(fields)
+	case KindVariant, KindResult:
+		fields := make([]reflect.StructField, len(t.Cases))
+		for i, c := range t.Cases {
+			payload := reflect.TypeOf(struct{}{})
+			if c.Type != nil {
+				payload = GoType(c.Type)
+			}
+			name := goName(c.Name)
+			if t.Kind == KindResult {
+				name = []string{"Ok", "Err"}[i]
+			}
+			fields[i] = reflect.StructField{
+				Name: name, Type: reflect.PtrTo(payload), Tag: reflect.StructTag(fmt.Sprintf(`wit:"%s"`, c.Name)),
+			}
+		}
+		return reflect.StructOf(fields)
+	default: // KindOwn, KindBorrow
+		return emptyInterface
+	}
+}
+
+// goName converts a kebab-case label to an exported Go identifier.
+func goName(label string) string {
+	var b strings.Builder
+	for _, part := range strings.Split(label, "-") {
+		for i, r := range part {
+			if i == 0 {
+				r = unicode.ToUpper(r)
+			}
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+var kindsOf = [...]reflect.Kind{
+	KindBool:   reflect.Bool,
+	KindS8:     reflect.Int8,
+	KindU8:     reflect.Uint8,
+	KindS16:    reflect.Int16,
+	KindU16:    reflect.Uint16,
+	KindS32:    reflect.Int32,
+	KindU32:    reflect.Uint32,
+	KindS64:    reflect.Int64,
+	KindU64:    reflect.Uint64,
+	KindF32:    reflect.Float32,
+	KindF64:    reflect.Float64,
+	KindChar:   reflect.Int32,
+	KindString: reflect.String,
+}
+
+// CheckGoType returns an error if values of t can't be lifted to or lowered from gt.
+func CheckGoType(t *ValType, gt reflect.Type) error {
+	if gt == emptyInterface {
+		return nil
+	}
+	mismatch := func() error {
+		return fmt.Errorf("%s can't be used as %s", gt, t)
+	}
+	switch t.Kind {
+	case KindList:
+		if gt.Kind() != reflect.Slice {
+			return mismatch()
+		}
+		return CheckGoType(t.Elem, gt.Elem())
+	case KindOption:
+		if gt.Kind() != reflect.Ptr {
+			return mismatch()
+		}
+		return CheckGoType(t.Elem, gt.Elem())
+	case KindRecord, KindTuple:
+		if gt.Kind() != reflect.Struct || gt.NumField() != len(t.Fields) {
+			return mismatch()
+		}
+		for i, f := range t.Fields {
+			sf := gt.Field(i)
+			if !sf.IsExported() {
+				return fmt.Errorf("%s: field %s is not exported", gt, sf.Name)
+			}
+			if err := CheckGoType(f.Type, sf.Type); err != nil {
+				return err
+			}
+		}
+	case KindVariant, KindResult:
+		if gt.Kind() != reflect.Struct || gt.NumField() != len(t.Cases) {
+			return mismatch()
+		}
+		for i, c := range t.Cases {
+			sf := gt.Field(i)
+			if !sf.IsExported() {
+				return fmt.Errorf("%s: field %s is not exported", gt, sf.Name)
+			} else if sf.Type.Kind() != reflect.Ptr {
+				return fmt.Errorf("%s: field %s of case %s must be a pointer", gt, sf.Name, c.Name)
+			}
+			if c.Type == nil {
+				if elem := sf.Type.Elem(); elem.Kind() != reflect.Struct || elem.NumField() != 0 {
+					return fmt.Errorf("%s: field %s of case %s must be a pointer to an empty struct", gt, sf.Name, c.Name)
+				}
+			} else if err := CheckGoType(c.Type, sf.Type.Elem()); err != nil {
+				return err
+			}
+		}
+	case KindEnum:
+		if !isInt(gt.Kind()) && !isUint(gt.Kind()) {
+			return mismatch()
+		}
+	case KindFlags:
+		if !isUint(gt.Kind()) || gt.Bits() < len(t.Labels) {
+			return mismatch()
+		}
+	case KindOwn, KindBorrow:
+		// The representation is checked when converted.
+	default:
+		if gt.Kind() != kindsOf[t.Kind] {
+			return mismatch()
+		}
+	}
+	return nil
+}
+
+func isInt(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Int64
+}
+
+func isUint(k reflect.Kind) bool {
+	return k >= reflect.Uint && k <= reflect.Uintptr
+}
@@ -0,0 +1,92 @@
+package component
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestGoType(t *testing.T) {
+	record := NewRecord(
+		Field{Name: "file-name", Type: Primitive(KindString)},
+		Field{Name: "size", Type: NewOption(Primitive(KindU64))},
+	)
+	require.Equal(t, reflect.TypeOf(struct {
+		FileName string  `wit:"file-name"`
+		Size     *uint64 `wit:"size"`
+	}{}), GoType(record))
+
+	result := NewResult(NewList(Primitive(KindU8)), nil)
+	require.Equal(t, reflect.TypeOf(struct {
+		Ok  *[]uint8  `wit:"ok"`
+		Err *struct{} `wit:"error"`
+	}{}), GoType(result))
+
+	require.Equal(t, reflect.TypeOf(struct{ F0, F1 int32 }{}), GoType(NewTuple(Primitive(KindS32), Primitive(KindChar))))
+	require.Equal(t, emptyInterface, GoType(NewOwn(&ResourceType{})))
+}
+
+func TestCheckGoType(t *testing.T) {
+	variant := NewVariant(Case{Name: "a", Type: Primitive(KindU32)}, Case{Name: "b"})
+	tests := []struct {
+		name   string
+		t      *ValType
+		gt     reflect.Type
+		expErr string
+	}{
+		{name: "empty interface", t: Primitive(KindString), gt: emptyInterface},
+		{name: "char", t: Primitive(KindChar), gt: reflect.TypeOf('a')},
+		{name: "enum", t: NewEnum("a", "b"), gt: reflect.TypeOf(int8(0))},
+		{name: "flags", t: NewFlags("a", "b"), gt: reflect.TypeOf(uint8(0))},
+		{name: "variant", t: variant, gt: reflect.TypeOf(struct {
+			A *uint32
+			B *struct{}
+		}{})},
+		{
+			name:   "int as u32",
+			t:      Primitive(KindU32),
+			gt:     reflect.TypeOf(0),
+			expErr: "int can't be used as u32",
+		},
+		{
+			name:   "list",
+			t:      NewList(Primitive(KindString)),
+			gt:     reflect.TypeOf([]int{}),
+			expErr: "int can't be used as string",
+		},
+		{
+			name:   "signed flags",
+			t:      NewFlags("a"),
+			gt:     reflect.TypeOf(int8(0)),
+			expErr: "int8 can't be used as flags",
+		},
+		{
+			name:   "unexported field",
+			t:      NewRecord(Field{Name: "a", Type: Primitive(KindBool)}),
+			gt:     reflect.TypeOf(struct{ a bool }{}),
+			expErr: "struct { a bool }: field a is not exported",
+		},
+		{
+			name: "case payload",
+			t:    variant,
+			gt: reflect.TypeOf(struct {
+				A *uint32
+				B *bool
+			}{}),
+			expErr: "struct { A *uint32; B *bool }: field B of case b must be a pointer to an empty struct",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			err := CheckGoType(tc.t, tc.gt)
+			if tc.expErr == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, tc.expErr)
+			}
+		})
+	}
+}
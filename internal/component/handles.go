@@ -0,0 +1,86 @@
+package component
+
+import (
+	"fmt"
+)
+
+// Handles are the resource tables of a component instance, which map the i32 handles used by its core instances to
+// resource representations.
+//
+// See https://github.com/WebAssembly/component-model/blob/main/design/mvp/CanonicalABI.md#handle-state
+type Handles struct {
+	tables map[*ResourceType]*handleTable
+}
+
+type handleTable struct {
+	// entries are indexed by handle, where 0 is reserved and nil entries are free.
+	entries []*handle
+	free    []uint32
+}
+
+type handle struct {
+	rep interface{}
+	own bool
+}
+
+func (h *Handles) table(r *ResourceType) *handleTable {
+	if h.tables == nil {
+		h.tables = map[*ResourceType]*handleTable{}
+	}
+	t, ok := h.tables[r]
+	if !ok {
+		t = &handleTable{entries: []*handle{nil}}
+		h.tables[r] = t
+	}
+	return t
+}
+
+// Add adds a handle to rep, which is owned unless it is borrowed for the duration of a call.
+func (h *Handles) Add(r *ResourceType, rep interface{}, own bool) uint32 {
+	t := h.table(r)
+	e := &handle{rep: rep, own: own}
+	if n := len(t.free); n > 0 {
+		idx := t.free[n-1]
+		t.free = t.free[:n-1]
+		t.entries[idx] = e
+		return idx
+	}
+	t.entries = append(t.entries, e)
+	return uint32(len(t.entries) - 1)
+}
+
+func (h *Handles) get(r *ResourceType, idx uint32) (*handle, error) {
+	t := h.table(r)
+	if idx == 0 || idx >= uint32(len(t.entries)) || t.entries[idx] == nil {
+		return nil, fmt.Errorf("unknown handle %d to resource %s", idx, resourceName(r))
+	}
+	return t.entries[idx], nil
+}
+
+// Rep returns the representation of the resource of handle idx.
+func (h *Handles) Rep(r *ResourceType, idx uint32) (interface{}, error) {
+	e, err := h.get(r, idx)
+	if err != nil {
+		return nil, err
+	}
+	return e.rep, nil
+}
+
+// Remove removes the handle idx, returning the representation of its resource and whether it was owned.
+func (h *Handles) Remove(r *ResourceType, idx uint32) (rep interface{}, own bool, err error) {
+	e, err := h.get(r, idx)
+	if err != nil {
+		return nil, false, err
+	}
+	t := h.table(r)
+	t.entries[idx] = nil
+	t.free = append(t.free, idx)
+	return e.rep, e.own, nil
+}
+
+func resourceName(r *ResourceType) string {
+	if r.Name == "" {
+		return "<anonymous>"
+	}
+	return r.Name
+}
@@ -0,0 +1,37 @@
+package component
+
+import (
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestHandles(t *testing.T) {
+	r1, r2 := &ResourceType{Name: "r1"}, &ResourceType{Name: "r2"}
+	var h Handles
+
+	// Handles start at 1, and are allocated per resource.
+	require.Equal(t, uint32(1), h.Add(r1, "a", true))
+	require.Equal(t, uint32(2), h.Add(r1, "b", false))
+	require.Equal(t, uint32(1), h.Add(r2, "c", true))
+
+	rep, err := h.Rep(r1, 2)
+	require.NoError(t, err)
+	require.Equal(t, "b", rep)
+
+	rep, own, err := h.Remove(r1, 1)
+	require.NoError(t, err)
+	require.Equal(t, "a", rep)
+	require.True(t, own)
+
+	_, err = h.Rep(r1, 1)
+	require.EqualError(t, err, "unknown handle 1 to resource r1")
+	_, err = h.Rep(r1, 0)
+	require.EqualError(t, err, "unknown handle 0 to resource r1")
+	_, _, err = h.Remove(&ResourceType{}, 1)
+	require.EqualError(t, err, "unknown handle 1 to resource <anonymous>")
+
+	// Removed handles are reused.
+	require.Equal(t, uint32(1), h.Add(r1, "d", true))
+	require.Equal(t, uint32(3), h.Add(r1, "e", true))
+}
@@ -0,0 +1,149 @@
+package component
+
+import (
+	"github.com/tetratelabs/wazero/api"
+)
+
+const (
+	// maxFlatParams is the maximum count of core parameters before parameters are passed in linear memory.
+	maxFlatParams = 16
+	// maxFlatResults is the maximum count of core results before results are returned in linear memory.
+	maxFlatResults = 1
+)
+
+// newValType computes the canonical ABI layout of t, which must only reference types already created with it.
+// See https://github.com/WebAssembly/component-model/blob/main/design/mvp/CanonicalABI.md#size
+func newValType(t *ValType) *ValType {
+	i32 := api.ValueTypeI32
+	switch t.Kind {
+	case KindBool, KindS8, KindU8:
+		t.size, t.align, t.flat = 1, 1, []api.ValueType{i32}
+	case KindS16, KindU16:
+		t.size, t.align, t.flat = 2, 2, []api.ValueType{i32}
+	case KindS32, KindU32, KindChar, KindOwn, KindBorrow:
+		t.size, t.align, t.flat = 4, 4, []api.ValueType{i32}
+	case KindS64, KindU64:
+		t.size, t.align, t.flat = 8, 8, []api.ValueType{api.ValueTypeI64}
+	case KindF32:
+		t.size, t.align, t.flat = 4, 4, []api.ValueType{api.ValueTypeF32}
+	case KindF64:
+		t.size, t.align, t.flat = 8, 8, []api.ValueType{api.ValueTypeF64}
+	case KindString, KindList:
+		t.size, t.align, t.flat = 8, 4, []api.ValueType{i32, i32}
+	case KindRecord, KindTuple:
+		t.align = 1
+		for _, f := range t.Fields {
+			t.size = alignTo(t.size, f.Type.align) + f.Type.size
+			if f.Type.align > t.align {
+				t.align = f.Type.align
+			}
+			t.flat = append(t.flat, f.Type.flat...)
+		}
+		t.size = alignTo(t.size, t.align)
+	case KindVariant, KindEnum, KindOption, KindResult:
+		disc := discriminantSize(len(t.Cases))
+		caseAlign, caseSize := uint32(1), uint32(0)
+		var flat []api.ValueType
+		for _, c := range t.Cases {
+			if c.Type == nil {
+				continue
+			}
+			if c.Type.align > caseAlign {
+				caseAlign = c.Type.align
+			}
+			if c.Type.size > caseSize {
+				caseSize = c.Type.size
+			}
+			for i, ft := range c.Type.flat {
+				if i < len(flat) {
+					flat[i] = joinFlat(flat[i], ft)
+				} else {
+					flat = append(flat, ft)
+				}
+			}
+		}
+		t.align = disc
+		if caseAlign > t.align {
+			t.align = caseAlign
+		}
+		t.size = alignTo(alignTo(disc, caseAlign)+caseSize, t.align)
+		t.flat = append([]api.ValueType{i32}, flat...)
+	case KindFlags:
+		switch n := len(t.Labels); {
+		case n <= 8:
+			t.size, t.align = 1, 1
+		case n <= 16:
+			t.size, t.align = 2, 2
+		default:
+			t.size, t.align = 4, 4
+		}
+		t.flat = []api.ValueType{i32}
+	}
+	return t
+}
+
+// discriminantSize returns the byte size of the discriminant of a variant with the given count of cases.
+func discriminantSize(cases int) uint32 {
+	switch {
+	case cases <= 1<<8:
+		return 1
+	case cases <= 1<<16:
+		return 2
+	default:
+		return 4
+	}
+}
+
+// joinFlat returns the core type which can hold values of both a and b in a flattened variant.
+func joinFlat(a, b api.ValueType) api.ValueType {
+	if a == b {
+		return a
+	}
+	if (a == api.ValueTypeI32 && b == api.ValueTypeF32) || (a == api.ValueTypeF32 && b == api.ValueTypeI32) {
+		return api.ValueTypeI32
+	}
+	return api.ValueTypeI64
+}
+
+func alignTo(offset, align uint32) uint32 {
+	return (offset + align - 1) / align * align
+}
+
+// Size returns the byte size of the type in linear memory.
+func (t *ValType) Size() uint32 {
+	return t.size
+}
+
+// Align returns the byte alignment of the type in linear memory.
+func (t *ValType) Align() uint32 {
+	return t.align
+}
+
+// Flat returns the core value types the type is flattened to when passed as a parameter or result.
+func (t *ValType) Flat() []api.ValueType {
+	return t.flat
+}
+
+// CoreParams returns the core parameter types of ft when lifted, or when lowered if lower is true.
+func (ft *FuncType) CoreParams(lower bool) []api.ValueType {
+	params := ft.params.flat
+	if len(params) > maxFlatParams {
+		params = []api.ValueType{api.ValueTypeI32}
+	}
+	if lower && len(ft.results.flat) > maxFlatResults {
+		params = append(params[:len(params):len(params)], api.ValueTypeI32)
+	}
+	return params
+}
+
+// CoreResults returns the core result types of ft when lifted, or when lowered if lower is true.
+func (ft *FuncType) CoreResults(lower bool) []api.ValueType {
+	results := ft.results.flat
+	if len(results) > maxFlatResults {
+		if lower {
+			return nil
+		}
+		return []api.ValueType{api.ValueTypeI32}
+	}
+	return results
+}
@@ -0,0 +1,128 @@
+package component
+
+import (
+	"testing"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestValType_Layout(t *testing.T) {
+	i32, i64, f32, f64 := api.ValueTypeI32, api.ValueTypeI64, api.ValueTypeF32, api.ValueTypeF64
+	tests := []struct {
+		name        string
+		t           *ValType
+		size, align uint32
+		flat        []api.ValueType
+	}{
+		{name: "bool", t: Primitive(KindBool), size: 1, align: 1, flat: []api.ValueType{i32}},
+		{name: "u16", t: Primitive(KindU16), size: 2, align: 2, flat: []api.ValueType{i32}},
+		{name: "char", t: Primitive(KindChar), size: 4, align: 4, flat: []api.ValueType{i32}},
+		{name: "s64", t: Primitive(KindS64), size: 8, align: 8, flat: []api.ValueType{i64}},
+		{name: "f32", t: Primitive(KindF32), size: 4, align: 4, flat: []api.ValueType{f32}},
+		{name: "string", t: Primitive(KindString), size: 8, align: 4, flat: []api.ValueType{i32, i32}},
+		{name: "list<u64>", t: NewList(Primitive(KindU64)), size: 8, align: 4, flat: []api.ValueType{i32, i32}},
+		{
+			name: "record",
+			t: NewRecord(
+				Field{Name: "a", Type: Primitive(KindU8)},
+				Field{Name: "b", Type: Primitive(KindF64)},
+				Field{Name: "c", Type: Primitive(KindU16)},
+			),
+			size: 24, align: 8, flat: []api.ValueType{i32, f64, i32},
+		},
+		{name: "empty tuple", t: NewTuple(), size: 0, align: 1, flat: nil},
+		{name: "option<u8>", t: NewOption(Primitive(KindU8)), size: 2, align: 1, flat: []api.ValueType{i32, i32}},
+		{
+			name: "result<u64, string>",
+			t:    NewResult(Primitive(KindU64), Primitive(KindString)),
+			size: 16, align: 8, flat: []api.ValueType{i32, i64, i32},
+		},
+		{
+			name: "variant of f32 and u32",
+			t: NewVariant(
+				Case{Name: "a", Type: Primitive(KindF32)},
+				Case{Name: "b", Type: Primitive(KindU32)},
+				Case{Name: "c"},
+			),
+			size: 8, align: 4, flat: []api.ValueType{i32, i32},
+		},
+		{
+			name: "variant of f32 and f64",
+			t: NewVariant(
+				Case{Name: "a", Type: Primitive(KindF32)},
+				Case{Name: "b", Type: Primitive(KindF64)},
+			),
+			size: 16, align: 8, flat: []api.ValueType{i32, i64},
+		},
+		{name: "enum", t: NewEnum("a", "b", "c"), size: 1, align: 1, flat: []api.ValueType{i32}},
+		{name: "flags of 9", t: NewFlags("a", "b", "c", "d", "e", "f", "g", "h", "i"), size: 2, align: 2, flat: []api.ValueType{i32}},
+		{name: "own", t: NewOwn(&ResourceType{Name: "r"}), size: 4, align: 4, flat: []api.ValueType{i32}},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.size, tc.t.Size())
+			require.Equal(t, tc.align, tc.t.Align())
+			require.Equal(t, tc.flat, tc.t.Flat())
+		})
+	}
+}
+
+func TestDiscriminantSize(t *testing.T) {
+	require.Equal(t, uint32(1), discriminantSize(1))
+	require.Equal(t, uint32(1), discriminantSize(256))
+	require.Equal(t, uint32(2), discriminantSize(257))
+	require.Equal(t, uint32(2), discriminantSize(65536))
+	require.Equal(t, uint32(4), discriminantSize(65537))
+}
+
+func TestFuncType_Core(t *testing.T) {
+	i32 := api.ValueTypeI32
+	str := Primitive(KindString)
+
+	tests := []struct {
+		name                      string
+		ft                        *FuncType
+		liftParams, liftResults   []api.ValueType
+		lowerParams, lowerResults []api.ValueType
+	}{
+		{
+			name:         "flat",
+			ft:           NewFuncType([]Field{{Name: "a", Type: Primitive(KindU32)}}, []Field{{Type: Primitive(KindF64)}}),
+			liftParams:   []api.ValueType{i32},
+			liftResults:  []api.ValueType{api.ValueTypeF64},
+			lowerParams:  []api.ValueType{i32},
+			lowerResults: []api.ValueType{api.ValueTypeF64},
+		},
+		{
+			name:         "results in memory",
+			ft:           NewFuncType([]Field{{Name: "s", Type: str}}, []Field{{Type: str}}),
+			liftParams:   []api.ValueType{i32, i32},
+			liftResults:  []api.ValueType{i32},
+			lowerParams:  []api.ValueType{i32, i32, i32},
+			lowerResults: nil,
+		},
+		{
+			name: "params in memory",
+			ft: NewFuncType([]Field{
+				{Name: "a", Type: str}, {Name: "b", Type: str}, {Name: "c", Type: str}, {Name: "d", Type: str},
+				{Name: "e", Type: str}, {Name: "f", Type: str}, {Name: "g", Type: str}, {Name: "h", Type: str},
+				{Name: "i", Type: Primitive(KindU8)},
+			}, nil),
+			liftParams:  []api.ValueType{i32},
+			lowerParams: []api.ValueType{i32},
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.liftParams, tc.ft.CoreParams(false))
+			require.Equal(t, tc.liftResults, tc.ft.CoreResults(false))
+			require.Equal(t, tc.lowerParams, tc.ft.CoreParams(true))
+			require.Equal(t, tc.lowerResults, tc.ft.CoreResults(true))
+		})
+	}
+}
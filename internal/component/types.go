@@ -0,0 +1,302 @@
+// Package component decodes WebAssembly components and implements the canonical ABI used to pass values between
+// components and their core modules.
+//
+// See https://github.com/WebAssembly/component-model
+package component
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tetratelabs/wazero/api"
+)
+
+// Kind is the kind of ValType.
+type Kind byte
+
+const (
+	KindBool Kind = iota
+	KindS8
+	KindU8
+	KindS16
+	KindU16
+	KindS32
+	KindU32
+	KindS64
+	KindU64
+	KindF32
+	KindF64
+	KindChar
+	KindString
+	KindList
+	KindRecord
+	KindTuple
+	KindVariant
+	KindEnum
+	KindOption
+	KindResult
+	KindFlags
+	KindOwn
+	KindBorrow
+)
+
+var kindNames = [...]string{
+	KindBool:    "bool",
+	KindS8:      "s8",
+	KindU8:      "u8",
+	KindS16:     "s16",
+	KindU16:     "u16",
+	KindS32:     "s32",
+	KindU32:     "u32",
+	KindS64:     "s64",
+	KindU64:     "u64",
+	KindF32:     "f32",
+	KindF64:     "f64",
+	KindChar:    "char",
+	KindString:  "string",
+	KindList:    "list",
+	KindRecord:  "record",
+	KindTuple:   "tuple",
+	KindVariant: "variant",
+	KindEnum:    "enum",
+	KindOption:  "option",
+	KindResult:  "result",
+	KindFlags:   "flags",
+	KindOwn:     "own",
+	KindBorrow:  "borrow",
+}
+
+// String returns the name of the kind as written in WIT.
+func (k Kind) String() string {
+	if int(k) < len(kindNames) {
+		return kindNames[k]
+	}
+	return fmt.Sprintf("unknown(%d)", k)
+}
+
+// ValType is a component value type. Instances are created by the decoder and are immutable, which allows the
+// canonical ABI layout to be computed once on creation.
+type ValType struct {
+	Kind Kind
+
+	// Elem is the element type of KindList and the payload of KindOption.
+	Elem *ValType
+
+	// Fields are the fields of KindRecord, or the unnamed elements of KindTuple.
+	Fields []Field
+
+	// Cases are the cases of KindVariant, KindEnum, KindOption and KindResult, which all share the representation
+	// of a variant in the canonical ABI. The cases of KindResult are "ok" and "error".
+	Cases []Case
+
+	// Labels are the labels of KindEnum and KindFlags.
+	Labels []string
+
+	// Resource is the resource type of KindOwn and KindBorrow.
+	Resource *ResourceType
+
+	size, align uint32
+	flat        []api.ValueType
+}
+
+// Field is a field of a record or an element of a tuple.
+type Field struct {
+	Name string
+	Type *ValType
+}
+
+// Case is a case of a variant. Type is nil when the case has no payload.
+type Case struct {
+	Name string
+	Type *ValType
+}
+
+// ResourceType is a resource type. Each definition or import of a resource type creates a distinct one, so
+// resource types are compared by pointer.
+type ResourceType struct {
+	// Name is the name the resource was imported or exported as, or empty if it is anonymous.
+	Name string
+
+	// Imported is true when the resource is implemented by the host, in which case its representation is a Go
+	// value. Otherwise, it is defined by the component with an i32 representation.
+	Imported bool
+
+	// Dtor is the core function index of the destructor of a resource defined by the component, if any.
+	Dtor *uint32
+}
+
+// FuncType is the type of a component function.
+type FuncType struct {
+	Params []Field
+
+	// Results has at most one element with an empty name, unless the results are named.
+	Results []Field
+
+	params, results *ValType
+}
+
+// InstanceType is the type of a component instance, which is only a set of named exports.
+type InstanceType struct {
+	Exports map[string]*Extern
+}
+
+// ExternKind is the kind of Extern.
+type ExternKind byte
+
+const (
+	ExternKindModule ExternKind = iota
+	ExternKindFunc
+	ExternKindValue
+	ExternKindType
+	ExternKindComponent
+	ExternKindInstance
+)
+
+// Extern describes an import or export of a component, or an export of an instance type.
+type Extern struct {
+	Kind ExternKind
+
+	// Func is set for ExternKindFunc.
+	Func *FuncType
+
+	// Instance is set for ExternKindInstance.
+	Instance *InstanceType
+
+	// Type is set for ExternKindType.
+	Type *TypeDef
+}
+
+// TypeDef is an entry of the type index space: exactly one field is set.
+type TypeDef struct {
+	Val      *ValType
+	Func     *FuncType
+	Instance *InstanceType
+	Resource *ResourceType
+}
+
+var primitives = func() (ret [KindString + 1]*ValType) {
+	for k := KindBool; k <= KindString; k++ {
+		ret[k] = newValType(&ValType{Kind: k})
+	}
+	return
+}()
+
+// Primitive returns the ValType of a primitive Kind, which is one of KindBool to KindString.
+func Primitive(k Kind) *ValType {
+	return primitives[k]
+}
+
+// NewList returns the type list<elem>.
+func NewList(elem *ValType) *ValType {
+	return newValType(&ValType{Kind: KindList, Elem: elem})
+}
+
+// NewRecord returns a record type of the given fields.
+func NewRecord(fields ...Field) *ValType {
+	return newValType(&ValType{Kind: KindRecord, Fields: fields})
+}
+
+// NewTuple returns a tuple type of the given elements.
+func NewTuple(elems ...*ValType) *ValType {
+	t := &ValType{Kind: KindTuple, Fields: make([]Field, len(elems))}
+	for i, e := range elems {
+		t.Fields[i].Type = e
+	}
+	return newValType(t)
+}
+
+// NewVariant returns a variant type of the given cases.
+func NewVariant(cases ...Case) *ValType {
+	return newValType(&ValType{Kind: KindVariant, Cases: cases})
+}
+
+// NewEnum returns an enum type of the given labels.
+func NewEnum(labels ...string) *ValType {
+	t := &ValType{Kind: KindEnum, Labels: labels, Cases: make([]Case, len(labels))}
+	for i, l := range labels {
+		t.Cases[i].Name = l
+	}
+	return newValType(t)
+}
+
+// NewOption returns the type option<elem>.
+func NewOption(elem *ValType) *ValType {
+	return newValType(&ValType{Kind: KindOption, Elem: elem, Cases: []Case{{Name: "none"}, {Name: "some", Type: elem}}})
+}
+
+// NewResult returns the type result<ok, err>, where either may be nil.
+func NewResult(ok, err *ValType) *ValType {
+	return newValType(&ValType{Kind: KindResult, Cases: []Case{{Name: "ok", Type: ok}, {Name: "error", Type: err}}})
+}
+
+// NewFlags returns a flags type of the given labels.
+func NewFlags(labels ...string) *ValType {
+	return newValType(&ValType{Kind: KindFlags, Labels: labels})
+}
+
+// NewOwn returns the type own<r>.
+func NewOwn(r *ResourceType) *ValType {
+	return newValType(&ValType{Kind: KindOwn, Resource: r})
+}
+
+// NewBorrow returns the type borrow<r>.
+func NewBorrow(r *ResourceType) *ValType {
+	return newValType(&ValType{Kind: KindBorrow, Resource: r})
+}
+
+// NewFuncType returns a function type of the given parameters and results.
+func NewFuncType(params, results []Field) *FuncType {
+	ft := &FuncType{Params: params, Results: results}
+	ft.params = newValType(&ValType{Kind: KindTuple, Fields: params})
+	ft.results = newValType(&ValType{Kind: KindTuple, Fields: results})
+	return ft
+}
+
+// String returns the type as written in WIT.
+func (t *ValType) String() string {
+	switch t.Kind {
+	case KindList, KindOption:
+		return fmt.Sprintf("%s<%s>", t.Kind, t.Elem)
+	case KindOwn, KindBorrow:
+		if t.Resource.Name != "" {
+			return fmt.Sprintf("%s<%s>", t.Kind, t.Resource.Name)
+		}
+	case KindTuple:
+		elems := make([]string, len(t.Fields))
+		for i, f := range t.Fields {
+			elems[i] = f.Type.String()
+		}
+		return fmt.Sprintf("tuple<%s>", strings.Join(elems, ", "))
+	case KindResult:
+		ok, err := t.Cases[0].Type, t.Cases[1].Type
+		switch {
+		case ok != nil && err != nil:
+			return fmt.Sprintf("result<%s, %s>", ok, err)
+		case ok != nil:
+			return fmt.Sprintf("result<%s>", ok)
+		case err != nil:
+			return fmt.Sprintf("result<_, %s>", err)
+		}
+	}
+	return t.Kind.String()
+}
+
+// String returns the type as written in WIT.
+func (ft *FuncType) String() string {
+	params := make([]string, len(ft.Params))
+	for i, p := range ft.Params {
+		params[i] = fmt.Sprintf("%s: %s", p.Name, p.Type)
+	}
+	ret := fmt.Sprintf("func(%s)", strings.Join(params, ", "))
+	switch {
+	case len(ft.Results) == 1 && ft.Results[0].Name == "":
+		ret += " -> " + ft.Results[0].Type.String()
+	case len(ft.Results) > 0:
+		results := make([]string, len(ft.Results))
+		for i, r := range ft.Results {
+			results[i] = fmt.Sprintf("%s: %s", r.Name, r.Type)
+		}
+		ret += fmt.Sprintf(" -> (%s)", strings.Join(results, ", "))
+	}
+	return ret
+}
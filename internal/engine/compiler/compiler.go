@@ -44,6 +44,12 @@ type compiler interface {
 	compileCall(o *wazeroir.UnionOperation) error
 	// compileCallIndirect adds instructions to perform wazeroir.OperationCallIndirect.
 	compileCallIndirect(o *wazeroir.UnionOperation) error
+	// compileTailCall adds instructions to perform wazeroir.OperationKindTailCall.
+	compileTailCall(o *wazeroir.UnionOperation) error
+	// compileTailCallIndirect adds instructions to perform wazeroir.OperationKindTailCallIndirect.
+	compileTailCallIndirect(o *wazeroir.UnionOperation) error
+	// compileCallRef adds instructions to perform wazeroir.NewOperationCallRef.
+	compileCallRef(o *wazeroir.UnionOperation) error
 	// compileDrop adds instructions to perform wazeroir.NewOperationDrop.
 	compileDrop(o *wazeroir.UnionOperation) error
 	// compileSelect adds instructions to perform wazeroir.OperationSelect.
@@ -153,7 +159,7 @@ type compiler interface {
 	// compileMemorySize adds instruction to perform wazeroir.OperationMemoryGrow.
 	compileMemoryGrow() error
 	// compileMemorySize adds instruction to perform wazeroir.OperationMemorySize.
-	compileMemorySize() error
+	compileMemorySize(*wazeroir.UnionOperation) error
 	// compileConstI32 adds instruction to perform wazeroir.NewOperationConstI32.
 	compileConstI32(o *wazeroir.UnionOperation) error
 	// compileConstI64 adds instruction to perform wazeroir.NewOperationConstI64.
@@ -176,10 +182,10 @@ type compiler interface {
 	compileMemoryInit(*wazeroir.UnionOperation) error
 	// compileDataDrop adds instructions to perform wazeroir.NewOperationDataDrop.
 	compileDataDrop(*wazeroir.UnionOperation) error
-	// compileMemoryCopy adds instructions to perform wazeroir.OperationMemoryCopy.
-	compileMemoryCopy() error
+	// compileMemoryCopy adds instructions to perform wazeroir.NewOperationMemoryCopy.
+	compileMemoryCopy(*wazeroir.UnionOperation) error
 	// compileMemoryFill adds instructions to perform wazeroir.OperationMemoryFill.
-	compileMemoryFill() error
+	compileMemoryFill(*wazeroir.UnionOperation) error
 	// compileTableInit adds instructions to perform wazeroir.NewOperationTableInit.
 	compileTableInit(*wazeroir.UnionOperation) error
 	// compileTableCopy adds instructions to perform wazeroir.NewOperationTableCopy.
@@ -188,6 +194,8 @@ type compiler interface {
 	compileElemDrop(*wazeroir.UnionOperation) error
 	// compileRefFunc adds instructions to perform wazeroir.NewOperationRefFunc.
 	compileRefFunc(*wazeroir.UnionOperation) error
+	// compileRefAsNonNull adds instructions to perform wazeroir.NewOperationRefAsNonNull.
+	compileRefAsNonNull(*wazeroir.UnionOperation) error
 	// compileTableGet adds instructions to perform wazeroir.NewOperationTableGet.
 	compileTableGet(*wazeroir.UnionOperation) error
 	// compileTableSet adds instructions to perform wazeroir.NewOperationTableSet.
@@ -300,9 +308,34 @@ type compiler interface {
 	compileV128Narrow(o *wazeroir.UnionOperation) error
 	// compileV128ITruncSatFromF adds instructions to perform wazeroir.NewOperationV128ITruncSatFromF.
 	compileV128ITruncSatFromF(o *wazeroir.UnionOperation) error
+	// compileV128RelaxedMadd adds instructions to perform wazeroir.NewOperationV128RelaxedMadd.
+	compileV128RelaxedMadd(o *wazeroir.UnionOperation) error
+	// compileV128RelaxedDot adds instructions to perform wazeroir.NewOperationV128RelaxedDot.
+	compileV128RelaxedDot(o *wazeroir.UnionOperation) error
 
 	// compileBuiltinFunctionCheckExitCode adds instructions to perform wazeroir.OperationBuiltinFunctionCheckExitCode.
 	compileBuiltinFunctionCheckExitCode() error
+	// compileConsumeFuel adds instructions to perform wazeroir.NewOperationConsumeFuel.
+	compileConsumeFuel(o *wazeroir.UnionOperation) error
+	// compileCheckEpoch adds instructions to perform wazeroir.NewOperationCheckEpoch.
+	compileCheckEpoch() error
+	// compileAtomic adds instructions to perform any of the atomic operations, e.g. wazeroir.NewOperationAtomicLoad.
+	compileAtomic(o *wazeroir.UnionOperation) error
+	// compileSelectMemory adds instructions to perform wazeroir.NewOperationSelectMemory.
+	compileSelectMemory(o *wazeroir.UnionOperation) error
+	// compileTry adds instructions to perform wazeroir.NewOperationTry.
+	compileTry(o *wazeroir.UnionOperation) error
+	// compileCatch notifies compilers of the beginning of a catch, which is entered from Go when an exception
+	// thrown in the body of its try block is caught.
+	// Return true if the compiler decided to skip the entire catch as its try block is never reached.
+	// See wazeroir.NewOperationCatch
+	compileCatch(o *wazeroir.UnionOperation) (skipThisCatch bool, err error)
+	// compileThrow adds instructions to perform wazeroir.NewOperationThrow.
+	compileThrow(o *wazeroir.UnionOperation) error
+	// compileRethrow adds instructions to perform wazeroir.NewOperationRethrow.
+	compileRethrow(o *wazeroir.UnionOperation) error
+	// compileThrowRef adds instructions to perform wazeroir.NewOperationThrowRef.
+	compileThrowRef() error
 
 	// compileReleaseRegisterToStack adds instructions to write the value on a register back to memory stack region.
 	compileReleaseRegisterToStack(loc *runtimeValueLocation)
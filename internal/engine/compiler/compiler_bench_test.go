@@ -49,7 +49,7 @@ func BenchmarkCompiler_compileMemoryCopy(b *testing.B) {
 				require.NoError(b, err)
 				err = compiler.compileConstI32(operationPtr(wazeroir.NewOperationConstI32(size)))
 				require.NoError(b, err)
-				err = compiler.compileMemoryCopy()
+				err = compiler.compileMemoryCopy(operationPtr(wazeroir.NewOperationMemoryCopy(0, 0, false)))
 				require.NoError(b, err)
 				err = compiler.(compilerImpl).compileReturnFunction()
 
@@ -97,7 +97,7 @@ func BenchmarkCompiler_compileMemoryFill(b *testing.B) {
 			require.NoError(b, err)
 			err = compiler.compileConstI32(operationPtr(wazeroir.NewOperationConstI32(size)))
 			require.NoError(b, err)
-			err = compiler.compileMemoryFill()
+			err = compiler.compileMemoryFill(operationPtr(wazeroir.NewOperationMemoryFill(false)))
 			require.NoError(b, err)
 			err = compiler.(compilerImpl).compileReturnFunction()
 			require.NoError(b, err)
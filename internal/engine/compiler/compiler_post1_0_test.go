@@ -215,7 +215,7 @@ func TestCompiler_compileMemoryCopy(t *testing.T) {
 			err = compiler.compileConstI32(operationPtr(wazeroir.NewOperationConstI32(tc.size)))
 			require.NoError(t, err)
 
-			err = compiler.compileMemoryCopy()
+			err = compiler.compileMemoryCopy(operationPtr(wazeroir.NewOperationMemoryCopy(0, 0, false)))
 			require.NoError(t, err)
 
 			code := asm.CodeSegment{}
@@ -302,7 +302,7 @@ func TestCompiler_compileMemoryFill(t *testing.T) {
 			err = compiler.compileConstI32(operationPtr(wazeroir.NewOperationConstI32(tc.size)))
 			require.NoError(t, err)
 
-			err = compiler.compileMemoryFill()
+			err = compiler.compileMemoryFill(operationPtr(wazeroir.NewOperationMemoryFill(false)))
 			require.NoError(t, err)
 
 			code := asm.CodeSegment{}
@@ -446,7 +446,7 @@ func TestCompiler_compileMemoryInit(t *testing.T) {
 			err = compiler.compileConstI32(operationPtr(wazeroir.NewOperationConstI32(tc.copySize)))
 			require.NoError(t, err)
 
-			err = compiler.compileMemoryInit(operationPtr(wazeroir.NewOperationMemoryInit(tc.dataIndex)))
+			err = compiler.compileMemoryInit(operationPtr(wazeroir.NewOperationMemoryInit(tc.dataIndex, false)))
 			require.NoError(t, err)
 
 			code := asm.CodeSegment{}
@@ -60,6 +60,11 @@ func init() {
 	requireEqual(int(unsafe.Offsetof(ce.builtinFunctionCallIndex)), callEngineExitContextBuiltinFunctionCallIndexOffset, "callEngineExitContextBuiltinFunctionCallIndexOffset")
 	requireEqual(int(unsafe.Offsetof(ce.returnAddress)), callEngineExitContextReturnAddressOffset, "callEngineExitContextReturnAddressOffset")
 	requireEqual(int(unsafe.Offsetof(ce.callerModuleInstance)), callEngineExitContextCallerModuleInstanceOffset, "callEngineExitContextCallerModuleInstanceOffset")
+	requireEqual(int(unsafe.Offsetof(ce.fuel)), callEngineExitContextFuelOffset, "callEngineExitContextFuelOffset")
+	requireEqual(int(unsafe.Offsetof(ce.epochDeadline)), callEngineExitContextEpochDeadlineOffset, "callEngineExitContextEpochDeadlineOffset")
+	requireEqual(int(unsafe.Offsetof(ce.epoch)), callEngineExitContextEpochOffset, "callEngineExitContextEpochOffset")
+	requireEqual(int(unsafe.Offsetof(ce.callDepth)), callEngineExitContextCallDepthOffset, "callEngineExitContextCallDepthOffset")
+	requireEqual(int(unsafe.Offsetof(ce.maxCallDepth)), callEngineExitContextMaxCallDepthOffset, "callEngineExitContextMaxCallDepthOffset")
 
 	// Size and offsets for callFrame.
 	var frame callFrame
@@ -70,13 +75,20 @@ func init() {
 	requireEqual(int(unsafe.Offsetof(f.codeInitialAddress)), functionCodeInitialAddressOffset, "functionCodeInitialAddressOffset")
 	requireEqual(int(unsafe.Offsetof(f.moduleInstance)), functionModuleInstanceOffset, "functionModuleInstanceOffset")
 	requireEqual(int(unsafe.Offsetof(f.typeID)), functionTypeIDOffset, "functionTypeIDOffset")
+	requireEqual(int(unsafe.Offsetof(f.parent)), functionParentOffset, "functionParentOffset")
+	requireEqual(int(unsafe.Offsetof(f.moduleEngine)), functionModuleEngineOffset, "functionModuleEngineOffset")
 	requireEqual(int(unsafe.Sizeof(f)), functionSize, "functionModuleInstanceOffset")
 
+	// Offsets for compiledFunction.
+	var cf compiledFunction
+	requireEqual(int(unsafe.Offsetof(cf.goFunc)), compiledFunctionGoFuncOffset, "compiledFunctionGoFuncOffset")
+
 	// Offsets for wasm.ModuleInstance.
 	var moduleInstance wasm.ModuleInstance
 	requireEqual(int(unsafe.Offsetof(moduleInstance.Globals)), moduleInstanceGlobalsOffset, "moduleInstanceGlobalsOffset")
 	requireEqual(int(unsafe.Offsetof(moduleInstance.MemoryInstance)), moduleInstanceMemoryOffset, "moduleInstanceMemoryOffset")
 	requireEqual(int(unsafe.Offsetof(moduleInstance.Tables)), moduleInstanceTablesOffset, "moduleInstanceTablesOffset")
+	requireEqual(int(unsafe.Offsetof(moduleInstance.Memories)), moduleInstanceMemoriesOffset, "moduleInstanceMemoriesOffset")
 	requireEqual(int(unsafe.Offsetof(moduleInstance.Engine)), moduleInstanceEngineOffset, "moduleInstanceEngineOffset")
 	requireEqual(int(unsafe.Offsetof(moduleInstance.TypeIDs)), moduleInstanceTypeIDsOffset, "moduleInstanceTypeIDsOffset")
 	requireEqual(int(unsafe.Offsetof(moduleInstance.DataInstances)), moduleInstanceDataInstancesOffset, "moduleInstanceDataInstancesOffset")
@@ -210,10 +222,12 @@ func (j *compilerEnv) exec(machineCode []byte) {
 	copy(executable, machineCode)
 	makeExecutable(executable)
 
+	me, _ := j.moduleInstance.Engine.(*moduleEngine)
 	f := &function{
 		parent:             &compiledFunction{parent: cm.compiledCode},
 		codeInitialAddress: uintptr(unsafe.Pointer(&executable[0])),
 		moduleInstance:     j.moduleInstance,
+		moduleEngine:       me,
 	}
 	j.ce.initialFn = f
 	j.ce.fn = f
@@ -173,6 +173,42 @@ func (v *runtimeValueLocationStack) cloneFrom(from runtimeValueLocationStack) {
 	copy(v.stack, from.stack[:from.sp])
 }
 
+// cloneOnStackFrom is the same as cloneFrom, except that all the values are located on the memory stack in self.
+// This is the state of the stack when entering a catch, as all the registers are released to the stack before
+// an exception is thrown.
+func (v *runtimeValueLocationStack) cloneOnStackFrom(from runtimeValueLocationStack) {
+	v.cloneFrom(from)
+	v.usedRegisters = 0
+	for i := uint64(0); i < v.sp; i++ {
+		loc := &v.stack[i]
+		loc.register, loc.conditionalRegister = asm.NilRegister, asm.ConditionalRegisterStateUnset
+	}
+}
+
+// pushRuntimeValueLocationsOnStack pushes the locations of the values of the given types, which are located on the
+// memory stack.
+func (v *runtimeValueLocationStack) pushRuntimeValueLocationsOnStack(types []wasm.ValueType) {
+	for _, t := range types {
+		loc := v.pushRuntimeValueLocationOnStack()
+		switch t {
+		case wasm.ValueTypeI32:
+			loc.valueType = runtimeValueTypeI32
+		case wasm.ValueTypeI64, wasm.ValueTypeFuncref, wasm.ValueTypeExternref, wasm.ValueTypeExnref:
+			loc.valueType = runtimeValueTypeI64
+		case wasm.ValueTypeF32:
+			loc.valueType = runtimeValueTypeF32
+		case wasm.ValueTypeF64:
+			loc.valueType = runtimeValueTypeF64
+		case wasm.ValueTypeV128:
+			loc.valueType = runtimeValueTypeV128Lo
+			hi := v.pushRuntimeValueLocationOnStack()
+			hi.valueType = runtimeValueTypeV128Hi
+		default:
+			panic("BUG: invalid type: " + wasm.ValueTypeName(t))
+		}
+	}
+}
+
 // pushRuntimeValueLocationOnRegister creates a new runtimeValueLocation with a given register and pushes onto
 // the location stack.
 func (v *runtimeValueLocationStack) pushRuntimeValueLocationOnRegister(reg asm.Register, vt runtimeValueType) (loc *runtimeValueLocation) {
@@ -327,7 +363,7 @@ func (v *runtimeValueLocationStack) init(sig *wasm.FunctionType) {
 		switch t {
 		case wasm.ValueTypeI32:
 			loc.valueType = runtimeValueTypeI32
-		case wasm.ValueTypeI64, wasm.ValueTypeFuncref, wasm.ValueTypeExternref:
+		case wasm.ValueTypeI64, wasm.ValueTypeFuncref, wasm.ValueTypeExternref, wasm.ValueTypeExnref:
 			loc.valueType = runtimeValueTypeI64
 		case wasm.ValueTypeF32:
 			loc.valueType = runtimeValueTypeF32
@@ -7350,3 +7350,190 @@ func TestCompiler_compileSelect_v128(t *testing.T) {
 		}
 	}
 }
+
+func TestCompiler_compileV128RelaxedMadd(t *testing.T) {
+	tests := []struct {
+		name    string
+		shape   wazeroir.Shape
+		negate  bool
+		x, y, z [16]byte
+		exp     [16]byte
+	}{
+		{
+			name:  "f32x4 madd",
+			shape: wazeroir.ShapeF32x4,
+			x:     f32x4(2, -2, 0.5, 1),
+			y:     f32x4(3, 3, 4, 0),
+			z:     f32x4(1, 1, -2, float32(math.Inf(1))),
+			exp:   f32x4(7, -5, 0, float32(math.Inf(1))),
+		},
+		{
+			// (1+2^-12)*(1+2^-12) is rounded to 1+2^-11 before the addition, so the result is zero unlike fused ones.
+			name:  "f32x4 madd not fused",
+			shape: wazeroir.ShapeF32x4,
+			x:     f32x4(1+0x1p-12, 1+0x1p-12, 1+0x1p-12, 1+0x1p-12),
+			y:     f32x4(1+0x1p-12, 1+0x1p-12, 1+0x1p-12, 1+0x1p-12),
+			z:     f32x4(-(1 + 0x1p-11), -(1 + 0x1p-11), -(1 + 0x1p-11), -(1 + 0x1p-11)),
+			exp:   f32x4(0, 0, 0, 0),
+		},
+		{
+			name:   "f32x4 nmadd",
+			shape:  wazeroir.ShapeF32x4,
+			negate: true,
+			x:      f32x4(2, -2, 1+0x1p-12, 1),
+			y:      f32x4(3, 3, 1+0x1p-12, 0),
+			z:      f32x4(1, 1, 1+0x1p-11, 5),
+			exp:    f32x4(-5, 7, 0, 5),
+		},
+		{
+			name:  "f64x2 madd",
+			shape: wazeroir.ShapeF64x2,
+			x:     f64x2(2, 1+0x1p-27),
+			y:     f64x2(3, 1+0x1p-27),
+			z:     f64x2(1, -(1 + 0x1p-26)),
+			exp:   f64x2(7, 0),
+		},
+		{
+			name:   "f64x2 nmadd",
+			shape:  wazeroir.ShapeF64x2,
+			negate: true,
+			x:      f64x2(2, 1+0x1p-27),
+			y:      f64x2(3, 1+0x1p-27),
+			z:      f64x2(1, 1+0x1p-26),
+			exp:    f64x2(-5, 0),
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			env := newCompilerEnvironment()
+			compiler := env.requireNewCompiler(t, &wasm.FunctionType{}, newCompiler,
+				&wazeroir.CompilationResult{HasMemory: true})
+
+			err := compiler.compilePreamble()
+			require.NoError(t, err)
+
+			for _, v := range [][16]byte{tc.x, tc.y, tc.z} {
+				err = compiler.compileV128Const(operationPtr(wazeroir.NewOperationV128Const(binary.LittleEndian.Uint64(v[:8]), binary.LittleEndian.Uint64(v[8:]))))
+				require.NoError(t, err)
+			}
+
+			err = compiler.compileV128RelaxedMadd(operationPtr(wazeroir.NewOperationV128RelaxedMadd(tc.shape, tc.negate)))
+			require.NoError(t, err)
+
+			requireRuntimeLocationStackPointerEqual(t, uint64(2), compiler)
+			require.Equal(t, 1, len(compiler.runtimeValueLocationStack().usedRegisters.list()))
+
+			err = compiler.compileReturnFunction()
+			require.NoError(t, err)
+
+			code := asm.CodeSegment{}
+			defer func() { require.NoError(t, code.Unmap()) }()
+
+			// Generate and run the code under test.
+			_, err = compiler.compile(code.NextCodeSection())
+			require.NoError(t, err)
+			env.exec(code.Bytes())
+
+			require.Equal(t, nativeCallStatusCodeReturned, env.callEngine().statusCode)
+
+			lo, hi := env.stackTopAsV128()
+			var actual [16]byte
+			binary.LittleEndian.PutUint64(actual[:8], lo)
+			binary.LittleEndian.PutUint64(actual[8:], hi)
+			require.Equal(t, tc.exp, actual)
+		})
+	}
+}
+
+func TestCompiler_compileV128RelaxedDot(t *testing.T) {
+	tests := []struct {
+		name      string
+		add       bool
+		x1, x2, z [16]byte
+		exp       [16]byte
+	}{
+		{
+			name: "i16x8",
+			x1:   [16]byte{1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1},
+			x2:   [16]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+			exp:  i16x8(3, 7, 11, 15, 19, 23, 27, 31),
+		},
+		{
+			name: "i16x8 negative",
+			x1:   [16]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 1, 1, 1, 1, 1, 1, 1, 1},
+			x2:   [16]byte{127, 127, 127, 127, 127, 127, 127, 127, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+			exp: i16x8(i16ToU16(-254), i16ToU16(-254), i16ToU16(-254), i16ToU16(-254),
+				i16ToU16(-2), i16ToU16(-2), i16ToU16(-2), i16ToU16(-2)),
+		},
+		{
+			name: "i16x8 saturated",
+			x1:   [16]byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80},
+			x2:   [16]byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80},
+			exp:  i16x8(0x7fff, 0x7fff, 0x7fff, 0x7fff, 0x7fff, 0x7fff, 0x7fff, 0x7fff),
+		},
+		{
+			name: "i32x4 add",
+			add:  true,
+			x1:   [16]byte{1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1},
+			x2:   [16]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+			z:    i32x4(100, 100, i32ToU32(-100), 0),
+			exp:  i32x4(110, 126, i32ToU32(-58), 58),
+		},
+		{
+			name: "i32x4 add saturated",
+			add:  true,
+			x1:   [16]byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+			x2:   [16]byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 127, 127, 127, 127, 127, 127, 127, 127},
+			z:    i32x4(0, 1, 1, 0xffffffff),
+			exp:  i32x4(65534, 65535, i32ToU32(-507), i32ToU32(-509)),
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			env := newCompilerEnvironment()
+			compiler := env.requireNewCompiler(t, &wasm.FunctionType{}, newCompiler,
+				&wazeroir.CompilationResult{HasMemory: true})
+
+			err := compiler.compilePreamble()
+			require.NoError(t, err)
+
+			vs := [][16]byte{tc.x1, tc.x2}
+			if tc.add {
+				vs = append(vs, tc.z)
+			}
+			for _, v := range vs {
+				err = compiler.compileV128Const(operationPtr(wazeroir.NewOperationV128Const(binary.LittleEndian.Uint64(v[:8]), binary.LittleEndian.Uint64(v[8:]))))
+				require.NoError(t, err)
+			}
+
+			err = compiler.compileV128RelaxedDot(operationPtr(wazeroir.NewOperationV128RelaxedDot(tc.add)))
+			require.NoError(t, err)
+
+			requireRuntimeLocationStackPointerEqual(t, uint64(2), compiler)
+			require.Equal(t, 1, len(compiler.runtimeValueLocationStack().usedRegisters.list()))
+
+			err = compiler.compileReturnFunction()
+			require.NoError(t, err)
+
+			code := asm.CodeSegment{}
+			defer func() { require.NoError(t, code.Unmap()) }()
+
+			// Generate and run the code under test.
+			_, err = compiler.compile(code.NextCodeSection())
+			require.NoError(t, err)
+			env.exec(code.Bytes())
+
+			require.Equal(t, nativeCallStatusCodeReturned, env.callEngine().statusCode)
+
+			lo, hi := env.stackTopAsV128()
+			var actual [16]byte
+			binary.LittleEndian.PutUint64(actual[:8], lo)
+			binary.LittleEndian.PutUint64(actual[8:], hi)
+			require.Equal(t, tc.exp, actual)
+		})
+	}
+}
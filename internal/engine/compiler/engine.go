@@ -4,10 +4,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math"
 	"reflect"
 	"runtime"
 	"sort"
 	"sync"
+	"sync/atomic"
 	"unsafe"
 
 	"github.com/tetratelabs/wazero/api"
@@ -52,6 +54,10 @@ type (
 		// Keep a reference to the compiled module to prevent the GC from reclaiming
 		// it while the code may still be needed.
 		module *compiledModule
+
+		// exceptionHandling is true if api.CoreFeatureExceptionHandling is enabled, in which case the calls catch
+		// the exceptions thrown by the functions, including host functions, of any module.
+		exceptionHandling bool
 	}
 
 	// callEngine holds context per moduleEngine.Call, and shared across all the
@@ -141,6 +147,31 @@ type (
 		// stackIterator provides a way to iterate over the stack for Listeners.
 		// It is setup and valid only during a call to a Listener hook.
 		stackIterator stackIterator
+
+		// fuelLoaded is the value of exitContext.fuel when it was last synchronized with the module of initialFn.
+		fuelLoaded int64
+
+		// maxStackLen is the maximum length of stack, above which builtinFunctionGrowStack traps with a stack overflow.
+		maxStackLen uint64
+
+		// exceptionHandling is the same as moduleEngine.exceptionHandling of the module of initialFn.
+		exceptionHandling bool
+
+		// exceptions are the exceptions caught by wazeroir.OperationKindCatch, whose handles on the stack are the
+		// indexes in this slice. These are released at the end of the call.
+		exceptions []caughtException
+
+		// exnrefs are the exceptions referenced by wasm.ValueTypeExnref values, which are the indexes of this slice
+		// plus one so that zero is the null reference. These are released at the end of the call.
+		exnrefs []*api.Exception
+	}
+
+	// caughtException is the exception caught by wazeroir.OperationKindCatch, which can be thrown again by
+	// wazeroir.OperationKindRethrow.
+	caughtException struct {
+		exc *api.Exception
+		// height is the index of the handle of the exception in callEngine.stack.
+		height int
 	}
 
 	// moduleContext holds the per-function call specific module information.
@@ -231,6 +262,23 @@ type (
 
 		// callerModuleInstance holds the caller's wasm.ModuleInstance, and is only valid if currently executing a host function.
 		callerModuleInstance *wasm.ModuleInstance
+
+		// fuel is the remaining fuel of the module of initialFn, consumed by wazeroir.OperationKindConsumeFuel.
+		fuel int64
+
+		// epochDeadline is the epoch at which wazeroir.OperationKindCheckEpoch traps in this call.
+		epochDeadline uint64
+
+		// epoch points to the wasm.Store Epoch of initialFn, which is read by wazeroir.OperationKindCheckEpoch in any
+		// function reached by this call, including the ones of other modules.
+		epoch *atomic.Uint64
+
+		// callDepth is the number of functions, including host functions, currently executing in this call.
+		// This is incremented on function entry and decremented on return.
+		callDepth uint64
+
+		// maxCallDepth is the maximum of callDepth, above which the call traps with a stack overflow.
+		maxCallDepth uint64
 	}
 
 	// callFrame holds the information to which the caller function can return.
@@ -267,6 +315,10 @@ type (
 		funcType *wasm.FunctionType
 		// parent holds code from which this is created.
 		parent *compiledFunction
+		// moduleEngine is the module engine of moduleInstance defining this function, whose functions are called by
+		// index. This isn't moduleInstance.Engine when the instance is interpreted, and functions are promoted to
+		// this engine.
+		moduleEngine *moduleEngine
 	}
 
 	compiledModule struct {
@@ -284,6 +336,7 @@ type (
 		functions []compiledFunction
 
 		ensureTermination bool
+		checkEpoch        bool
 	}
 
 	compiledCode struct {
@@ -305,6 +358,9 @@ type (
 		listener        experimental.FunctionListener
 		parent          *compiledCode
 		sourceOffsetMap sourceOffsetMap
+		// exceptionHandlers are the wazeroir.ExceptionHandler of this function, whose positions are translated into
+		// the offsets in the native code from the beginning of this function.
+		exceptionHandlers []wazeroir.ExceptionHandler
 	}
 
 	// sourceOffsetMap holds the information to retrieve the original offset in
@@ -368,21 +424,32 @@ const (
 	callEngineExitContextBuiltinFunctionCallIndexOffset = 124
 	callEngineExitContextReturnAddressOffset            = 128
 	callEngineExitContextCallerModuleInstanceOffset     = 136
+	callEngineExitContextFuelOffset                     = 144
+	callEngineExitContextEpochDeadlineOffset            = 152
+	callEngineExitContextEpochOffset                    = 160
+	callEngineExitContextCallDepthOffset                = 168
+	callEngineExitContextMaxCallDepthOffset             = 176
 
 	// Offsets for function.
 	functionCodeInitialAddressOffset = 0
 	functionModuleInstanceOffset     = 8
 	functionTypeIDOffset             = 16
-	functionSize                     = 40
+	functionParentOffset             = 32
+	functionModuleEngineOffset       = 40
+	functionSize                     = 48
+
+	// Offsets for compiledFunction.
+	compiledFunctionGoFuncOffset = 24
 
 	// Offsets for wasm.ModuleInstance.
 	moduleInstanceGlobalsOffset          = 24
 	moduleInstanceMemoryOffset           = 48
 	moduleInstanceTablesOffset           = 56
-	moduleInstanceEngineOffset           = 80
-	moduleInstanceTypeIDsOffset          = 96
-	moduleInstanceDataInstancesOffset    = 120
-	moduleInstanceElementInstancesOffset = 144
+	moduleInstanceMemoriesOffset         = 80
+	moduleInstanceEngineOffset           = 104
+	moduleInstanceTypeIDsOffset          = 120
+	moduleInstanceDataInstancesOffset    = 144
+	moduleInstanceElementInstancesOffset = 168
 
 	// Offsets for wasm.TableInstance.
 	tableInstanceTableOffset    = 0
@@ -395,11 +462,6 @@ const (
 	// Offsets for wasm.GlobalInstance.
 	globalInstanceValueOffset = 8
 
-	// Offsets for Go's interface.
-	// https://research.swtch.com/interfaces
-	// https://github.com/golang/go/blob/release-branch.go1.20/src/runtime/runtime2.go#L207-L210
-	interfaceDataOffset = 8
-
 	// Consts for wasm.DataInstance.
 	dataInstanceStructSize = 24
 
@@ -437,6 +499,16 @@ const (
 	nativeCallStatusCodeTypeMismatchOnIndirectCall
 	nativeCallStatusIntegerOverflow
 	nativeCallStatusIntegerDivisionByZero
+	// nativeCallStatusCodeOutOfFuel means the fuel was insufficient to execute the next block.
+	nativeCallStatusCodeOutOfFuel
+	// nativeCallStatusCodeEpochInterrupted means the epoch reached the deadline of the call.
+	nativeCallStatusCodeEpochInterrupted
+	// nativeCallStatusCodeStackOverflow means the call depth exceeded exitContext.maxCallDepth.
+	nativeCallStatusCodeStackOverflow
+	// nativeCallStatusCodeNullReference means ref.as_non_null was executed against a null reference.
+	nativeCallStatusCodeNullReference
+	// nativeCallStatusCodeNullFunctionReference means call_ref was executed against a null function reference.
+	nativeCallStatusCodeNullFunctionReference
 	nativeCallStatusModuleClosed
 )
 
@@ -458,6 +530,16 @@ func (s nativeCallStatusCode) causePanic() {
 		err = wasmruntime.ErrRuntimeInvalidTableAccess
 	case nativeCallStatusCodeTypeMismatchOnIndirectCall:
 		err = wasmruntime.ErrRuntimeIndirectCallTypeMismatch
+	case nativeCallStatusCodeOutOfFuel:
+		err = wasmruntime.ErrRuntimeOutOfFuel
+	case nativeCallStatusCodeEpochInterrupted:
+		err = wasmruntime.ErrRuntimeEpochInterrupted
+	case nativeCallStatusCodeStackOverflow:
+		err = wasmruntime.ErrRuntimeStackOverflow
+	case nativeCallStatusCodeNullReference:
+		err = wasmruntime.ErrRuntimeNullReference
+	case nativeCallStatusCodeNullFunctionReference:
+		err = wasmruntime.ErrRuntimeNullFunctionReference
 	}
 	panic(err)
 }
@@ -486,6 +568,16 @@ func (s nativeCallStatusCode) String() (ret string) {
 		ret = "integer division by zero"
 	case nativeCallStatusModuleClosed:
 		ret = "module closed"
+	case nativeCallStatusCodeOutOfFuel:
+		ret = "out of fuel"
+	case nativeCallStatusCodeEpochInterrupted:
+		ret = "epoch interrupted"
+	case nativeCallStatusCodeStackOverflow:
+		ret = "stack overflow"
+	case nativeCallStatusCodeNullReference:
+		ret = "null reference"
+	case nativeCallStatusCodeNullFunctionReference:
+		ret = "null function reference"
 	default:
 		panic("BUG")
 	}
@@ -522,38 +614,39 @@ func (e *engine) Close() (err error) {
 }
 
 // CompileModule implements the same method as documented on wasm.Engine.
-func (e *engine) CompileModule(_ context.Context, module *wasm.Module, listeners []experimental.FunctionListener, ensureTermination bool) error {
+//
+// Functions are compiled concurrently when wasm.CompilationWorkers is more than one. As functions are laid out in
+// order of index, the compiled code is the same regardless of the count of workers.
+func (e *engine) CompileModule(ctx context.Context, module *wasm.Module, listeners []experimental.FunctionListener, ensureTermination, meterFuel, checkEpoch bool) error {
 	if _, ok, err := e.getCompiledModule(module, listeners); ok { // cache hit!
 		return nil
 	} else if err != nil {
 		return err
 	}
 
-	irCompiler, err := wazeroir.NewCompiler(e.enabledFeatures, callFrameDataSizeInUint64, module, ensureTermination)
-	if err != nil {
-		return err
-	}
-
 	var withGoFunc bool
-	localFuncs, importedFuncs := len(module.FunctionSection), module.ImportFunctionCount
+	localFuncs := len(module.FunctionSection)
 	cm := &compiledModule{
 		compiledCode: &compiledCode{
 			source: module,
 		},
 		functions:         make([]compiledFunction, localFuncs),
 		ensureTermination: ensureTermination,
+		checkEpoch:        checkEpoch,
 	}
 
 	if localFuncs == 0 {
 		return e.addCompiledModule(module, cm, withGoFunc)
 	}
+	for i := range module.CodeSection {
+		if module.CodeSection[i].GoFunc != nil {
+			withGoFunc = true
+			break
+		}
+	}
 
 	// As this uses mmap, we need to munmap on the compiled machine code when it's GCed.
 	e.setFinalizer(cm, releaseCompiledModule)
-	ln := len(listeners)
-	cmp := newCompiler()
-	asmNodes := new(asmNodes)
-	offsets := new(offsets)
 
 	// The executable code is allocated in memory mappings held by the
 	// CodeSegment, which gros on demand when it exhausts its capacity.
@@ -569,39 +662,17 @@ func (e *engine) CompileModule(_ context.Context, module *wasm.Module, listeners
 		}
 	}()
 
-	for i := range module.CodeSection {
-		typ := &module.TypeSection[module.FunctionSection[i]]
-		buf := executable.NextCodeSection()
-		funcIndex := wasm.Index(i)
-		compiledFn := &cm.functions[i]
-		compiledFn.executableOffset = executable.Size()
-		compiledFn.parent = cm.compiledCode
-		compiledFn.index = importedFuncs + funcIndex
-		if i < ln {
-			compiledFn.listener = listeners[i]
-		}
-
-		if codeSeg := &module.CodeSection[i]; codeSeg.GoFunc != nil {
-			cmp.Init(typ, nil, compiledFn.listener != nil)
-			withGoFunc = true
-			if err = compileGoDefinedHostFunction(buf, cmp); err != nil {
-				def := module.FunctionDefinition(compiledFn.index)
-				return fmt.Errorf("error compiling host go func[%s]: %w", def.DebugName(), err)
-			}
-			compiledFn.goFunc = codeSeg.GoFunc
-		} else {
-			ir, err := irCompiler.Next()
-			if err != nil {
-				return fmt.Errorf("failed to lower func[%d]: %v", i, err)
-			}
-			cmp.Init(typ, ir, compiledFn.listener != nil)
-
-			compiledFn.stackPointerCeil, compiledFn.sourceOffsetMap, err = compileWasmFunction(buf, cmp, ir, asmNodes, offsets)
-			if err != nil {
-				def := module.FunctionDefinition(compiledFn.index)
-				return fmt.Errorf("error compiling wasm func[%s]: %w", def.DebugName(), err)
-			}
+	var err error
+	if workers := wasm.CompilationWorkers(ctx); workers > 1 && localFuncs > 1 {
+		if workers > localFuncs {
+			workers = localFuncs
 		}
+		err = e.compileFunctionsConcurrently(&executable, cm, listeners, ensureTermination, meterFuel, checkEpoch, workers)
+	} else {
+		err = e.compileFunctions(&executable, cm, listeners, ensureTermination, meterFuel, checkEpoch)
+	}
+	if err != nil {
+		return err
 	}
 
 	if runtime.GOARCH == "arm64" {
@@ -614,6 +685,143 @@ func (e *engine) CompileModule(_ context.Context, module *wasm.Module, listeners
 	return e.addCompiledModule(module, cm, withGoFunc)
 }
 
+// compileFunctions compiles the functions of the module one after another into the executable.
+func (e *engine) compileFunctions(executable *asm.CodeSegment, cm *compiledModule, listeners []experimental.FunctionListener, ensureTermination, meterFuel, checkEpoch bool) error {
+	fc, err := e.newFunctionCompiler(cm, listeners, ensureTermination, meterFuel, checkEpoch)
+	if err != nil {
+		return err
+	}
+	for i := range cm.functions {
+		buf := executable.NextCodeSection()
+		cm.functions[i].executableOffset = executable.Size()
+		if err = fc.compile(buf, i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// compileFunctionsConcurrently compiles the functions of the module with the given count of goroutines, each into its own code
+// segment, then copies them into the executable in order of index.
+func (e *engine) compileFunctionsConcurrently(executable *asm.CodeSegment, cm *compiledModule, listeners []experimental.FunctionListener, ensureTermination, meterFuel, checkEpoch bool, workers int) error {
+	segments := make([]asm.CodeSegment, workers)
+	defer func() {
+		for i := range segments {
+			if err := segments[i].Unmap(); err != nil {
+				panic(fmt.Errorf("compiler: failed to munmap code segment: %w", err))
+			}
+		}
+	}()
+
+	// locations are where each function was compiled in segments.
+	type location struct{ segment, begin, end int }
+	locations := make([]location, len(cm.functions))
+	errs := make([]error, len(cm.functions))
+	var next int32 // the index of the next function to compile.
+	var failed atomic.Bool
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		fc, err := e.newFunctionCompiler(cm, listeners, ensureTermination, meterFuel, checkEpoch)
+		if err != nil {
+			return err
+		}
+		wg.Add(1)
+		go func(w int, fc *functionCompiler) {
+			defer wg.Done()
+			segment := &segments[w]
+			// Once a function failed, the functions of lower index still compile, so that the error is the same as
+			// when compiling one after another.
+			for !failed.Load() {
+				i := int(atomic.AddInt32(&next, 1)) - 1
+				if i >= len(cm.functions) {
+					return
+				}
+				buf := segment.NextCodeSection()
+				begin := int(segment.Size())
+				if errs[i] = fc.compile(buf, i); errs[i] != nil {
+					failed.Store(true)
+					return
+				}
+				locations[i] = location{segment: w, begin: begin, end: begin + buf.Len()}
+			}
+		}(w, fc)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	for i, l := range locations {
+		buf := executable.NextCodeSection()
+		cm.functions[i].executableOffset = executable.Size()
+		buf.AppendBytes(segments[l.segment].Bytes()[l.begin:l.end])
+	}
+	return nil
+}
+
+// functionCompiler compiles the functions of a module, reusing its state across functions. This is used by one
+// goroutine at a time.
+type functionCompiler struct {
+	cm         *compiledModule
+	listeners  []experimental.FunctionListener
+	irCompiler *wazeroir.Compiler
+	cmp        compiler
+	asmNodes   *asmNodes
+	offsets    *offsets
+}
+
+func (e *engine) newFunctionCompiler(cm *compiledModule, listeners []experimental.FunctionListener, ensureTermination, meterFuel, checkEpoch bool) (*functionCompiler, error) {
+	irCompiler, err := wazeroir.NewCompiler(e.enabledFeatures, callFrameDataSizeInUint64, cm.source, ensureTermination, meterFuel, checkEpoch)
+	if err != nil {
+		return nil, err
+	}
+	return &functionCompiler{
+		cm:         cm,
+		listeners:  listeners,
+		irCompiler: irCompiler,
+		cmp:        newCompiler(),
+		asmNodes:   new(asmNodes),
+		offsets:    new(offsets),
+	}, nil
+}
+
+// compile compiles the function at the given index in the code section into buf.
+func (fc *functionCompiler) compile(buf asm.Buffer, i int) error {
+	module := fc.cm.source
+	typ := &module.TypeSection[module.FunctionSection[i]]
+	compiledFn := &fc.cm.functions[i]
+	compiledFn.parent = fc.cm.compiledCode
+	compiledFn.index = module.ImportFunctionCount + wasm.Index(i)
+	if i < len(fc.listeners) {
+		compiledFn.listener = fc.listeners[i]
+	}
+
+	if codeSeg := &module.CodeSection[i]; codeSeg.GoFunc != nil {
+		fc.cmp.Init(typ, nil, compiledFn.listener != nil)
+		if err := compileGoDefinedHostFunction(buf, fc.cmp); err != nil {
+			def := module.FunctionDefinition(compiledFn.index)
+			return fmt.Errorf("error compiling host go func[%s]: %w", def.DebugName(), err)
+		}
+		compiledFn.goFunc = codeSeg.GoFunc
+		return nil
+	}
+
+	ir, err := fc.irCompiler.Compile(i)
+	if err != nil {
+		return fmt.Errorf("failed to lower func[%d]: %v", i, err)
+	}
+	fc.cmp.Init(typ, ir, compiledFn.listener != nil)
+
+	compiledFn.stackPointerCeil, compiledFn.sourceOffsetMap, compiledFn.exceptionHandlers, err = compileWasmFunction(buf, fc.cmp, ir, fc.asmNodes, fc.offsets)
+	if err != nil {
+		def := module.FunctionDefinition(compiledFn.index)
+		return fmt.Errorf("error compiling wasm func[%s]: %w", def.DebugName(), err)
+	}
+	return nil
+}
+
 // NewModuleEngine implements the same method as documented on wasm.Engine.
 func (e *engine) NewModuleEngine(module *wasm.Module, instance *wasm.ModuleInstance) (wasm.ModuleEngine, error) {
 	me := &moduleEngine{
@@ -642,10 +850,12 @@ func (e *engine) NewModuleEngine(module *wasm.Module, instance *wasm.ModuleInsta
 			typeID:             instance.TypeIDs[typeIndex],
 			funcType:           &module.TypeSection[typeIndex],
 			parent:             c,
+			moduleEngine:       me,
 		}
 	}
 
 	me.module = cm
+	me.exceptionHandling = e.enabledFeatures.IsEnabled(api.CoreFeatureExceptionHandling)
 	return me, nil
 }
 
@@ -661,6 +871,22 @@ func (e *moduleEngine) FunctionInstanceReference(funcIndex wasm.Index) wasm.Refe
 	return uintptr(unsafe.Pointer(&e.functions[funcIndex]))
 }
 
+// OwnsFunctionReference implements the same method as documented on wasm.ModuleEngine.
+func (e *moduleEngine) OwnsFunctionReference(ref wasm.Reference) bool {
+	begin, end := e.FunctionReferenceRange()
+	return ref >= begin && ref < end && (ref-begin)%unsafe.Sizeof(function{}) == 0
+}
+
+// FunctionReferenceRange implements the same method as documented on wasm.ModuleEngine.
+func (e *moduleEngine) FunctionReferenceRange() (begin, end wasm.Reference) {
+	if len(e.functions) == 0 {
+		return
+	}
+	begin = uintptr(unsafe.Pointer(&e.functions[0]))
+	end = begin + uintptr(len(e.functions))*unsafe.Sizeof(e.functions[0])
+	return
+}
+
 // NewFunction implements wasm.ModuleEngine.
 func (e *moduleEngine) NewFunction(index wasm.Index) api.Function {
 	return e.newFunction(&e.functions[index])
@@ -680,13 +906,17 @@ func (e *moduleEngine) LookupFunction(t *wasm.TableInstance, typeId wasm.Functio
 		err = wasmruntime.ErrRuntimeInvalidTableAccess
 		return
 	}
-	rawPtr := t.References[tableOffset]
-	if rawPtr == 0 {
+	return e.LookupFunctionReference(t.References[tableOffset], typeId)
+}
+
+// LookupFunctionReference implements the same method as documented on wasm.ModuleEngine.
+func (e *moduleEngine) LookupFunctionReference(ref wasm.Reference, typeId wasm.FunctionTypeID) (f api.Function, err error) {
+	if ref == 0 {
 		err = wasmruntime.ErrRuntimeInvalidTableAccess
 		return
 	}
 
-	tf := functionFromUintptr(rawPtr)
+	tf := functionFromUintptr(ref)
 	if tf.typeID != typeId {
 		err = wasmruntime.ErrRuntimeIndirectCallTypeMismatch
 		return
@@ -723,20 +953,25 @@ func (ce *callEngine) Call(ctx context.Context, params ...uint64) (results []uin
 	if n := ft.ParamNumInUint64; n != len(params) {
 		return nil, fmt.Errorf("expected %d params, but passed %d", n, len(params))
 	}
-	return ce.call(ctx, params, nil)
+	return ce.call(ctx, params, nil, 0)
 }
 
 // CallWithStack implements the same method as documented on wasm.ModuleEngine.
 func (ce *callEngine) CallWithStack(ctx context.Context, stack []uint64) error {
+	return ce.CallWithStackAndDepth(ctx, stack, 0)
+}
+
+// CallWithStackAndDepth implements the same method as documented on wasm.CallDepthFunction.
+func (ce *callEngine) CallWithStackAndDepth(ctx context.Context, stack []uint64, depth uint64) error {
 	params, results, err := wasm.SplitCallStack(ce.initialFn.funcType, stack)
 	if err != nil {
 		return err
 	}
-	_, err = ce.call(ctx, params, results)
+	_, err = ce.call(ctx, params, results, depth)
 	return err
 }
 
-func (ce *callEngine) call(ctx context.Context, params, results []uint64) (_ []uint64, err error) {
+func (ce *callEngine) call(ctx context.Context, params, results []uint64, depth uint64) (_ []uint64, err error) {
 	m := ce.initialFn.moduleInstance
 	if ce.module.ensureTermination {
 		select {
@@ -753,7 +988,22 @@ func (ce *callEngine) call(ctx context.Context, params, results []uint64) (_ []u
 	// this Call method is indirectly invoked by embedders via store.CallFunction,
 	// and we have to make sure that all the runtime errors, including the one happening inside
 	// host functions, will be captured as errors, not panics.
+	// The epoch is set regardless of ce.module.checkEpoch, as the call can reach functions of other modules which
+	// check it. The deadline is math.MaxUint64 if the context has none.
+	ce.epoch, ce.epochDeadline = m.Epoch(), m.EpochDeadline(ctx)
+
+	ce.callDepth, ce.maxCallDepth, ce.maxStackLen = depth, math.MaxUint64, callStackCeiling
+	maxCallStackDepth, maxStackBytes := m.StackLimits()
+	if maxCallStackDepth > 0 {
+		ce.maxCallDepth = uint64(maxCallStackDepth)
+	}
+	if maxStackBytes > 0 {
+		ce.maxStackLen = maxStackBytes >> 3
+	}
+
+	ce.loadFuel(m)
 	defer func() {
+		ce.storeFuel(m)
 		err = ce.deferredOnCall(ctx, m, recover())
 		if err == nil {
 			// If the module closed during the call, and the call didn't err for another reason, set an ExitError.
@@ -783,6 +1033,18 @@ func (ce *callEngine) call(ctx context.Context, params, results []uint64) (_ []u
 	return results, nil
 }
 
+// loadFuel reads the remaining fuel of the module of the called function.
+func (ce *callEngine) loadFuel(m *wasm.ModuleInstance) {
+	ce.fuel = m.Fuel()
+	ce.fuelLoaded = ce.fuel
+}
+
+// storeFuel subtracts the fuel consumed since loadFuel from the module of the called function.
+func (ce *callEngine) storeFuel(m *wasm.ModuleInstance) {
+	m.ConsumeFuel(ce.fuelLoaded - ce.fuel)
+	ce.fuelLoaded = ce.fuel
+}
+
 // initializeStack initializes callEngine.stack before entering native code.
 //
 // The stack must look like, if len(params) < len(results):
@@ -888,6 +1150,7 @@ func (ce *callEngine) deferredOnCall(ctx context.Context, m *wasm.ModuleInstance
 	// Allows the reuse of CallEngine.
 	ce.stackBasePointerInBytes, ce.stackPointer, ce.moduleInstance = 0, 0, nil
 	ce.moduleContext.fn = ce.initialFn
+	ce.exceptions, ce.exnrefs = ce.exceptions[:0], ce.exnrefs[:0]
 	return
 }
 
@@ -984,6 +1247,9 @@ func (e *moduleEngine) newCallEngine(stackSize uint64, fn *function) *callEngine
 		initialFn:     fn,
 		moduleContext: moduleContext{fn: fn},
 		module:        e.module,
+		exitContext:   exitContext{maxCallDepth: math.MaxUint64},
+
+		exceptionHandling: e.exceptionHandling,
 	}
 
 	stackHeader := (*reflect.SliceHeader)(unsafe.Pointer(&ce.stack))
@@ -1016,6 +1282,11 @@ const (
 	builtinFunctionIndexFunctionListenerBefore
 	builtinFunctionIndexFunctionListenerAfter
 	builtinFunctionIndexCheckExitCode
+	builtinFunctionIndexAtomic
+	builtinFunctionIndexMemoryCopy
+	builtinFunctionIndexThrow
+	builtinFunctionIndexRethrow
+	builtinFunctionIndexThrowRef
 	// builtinFunctionIndexBreakPoint is internal (only for wazero developers). Disabled by default.
 	builtinFunctionIndexBreakPoint
 )
@@ -1023,7 +1294,119 @@ const (
 func (ce *callEngine) execWasmFunction(ctx context.Context, m *wasm.ModuleInstance) {
 	codeAddr := ce.initialFn.codeInitialAddress
 	modAddr := ce.initialFn.moduleInstance
+	if !ce.exceptionHandling {
+		ce.execNativeCode(ctx, m, codeAddr, modAddr)
+		return
+	}
+	for caught := true; caught; {
+		codeAddr, modAddr, caught = ce.execNativeCodeCatching(ctx, m, codeAddr, modAddr)
+	}
+}
+
+// execNativeCodeCatching is like execNativeCode, except it catches the exceptions thrown during the execution. When it
+// does so, this returns true with the address of the catch and the module instance of its function, from which the
+// execution resumes.
+func (ce *callEngine) execNativeCodeCatching(ctx context.Context, m *wasm.ModuleInstance, codeAddr uintptr, modAddr *wasm.ModuleInstance) (catchAddr uintptr, catchMod *wasm.ModuleInstance, caught bool) {
+	defer func() {
+		if v := recover(); v != nil {
+			if exc, ok := v.(*api.Exception); ok {
+				catchAddr, catchMod, caught = ce.catch(ctx, m, exc)
+			}
+			if !caught {
+				panic(v)
+			}
+		}
+	}()
+	ce.execNativeCode(ctx, m, codeAddr, modAddr)
+	return
+}
+
+// catch finds the catch of the exception thrown by the current function, or by the functions it was called from.
+// If found, this unwinds the call frames above the catch, writes the exception onto the stack, and returns the address
+// of the catch and the module instance of its function. Otherwise, this returns false without modifying the state.
+func (ce *callEngine) catch(ctx context.Context, m *wasm.ModuleInstance, exc *api.Exception) (uintptr, *wasm.ModuleInstance, bool) {
+	fn := ce.fn
+	pc := uint64(ce.returnAddress)
+	stackBasePointer := int(ce.stackBasePointerInBytes >> 3)
+	var functionListeners []functionListenerInvocation
+	for unwound := uint64(0); ; unwound++ {
+		if handlers := fn.parent.exceptionHandlers; handlers != nil {
+			tags := fn.moduleInstance.Tags
+			// pc is the address to which the call or the exit from the native code returns, so the one before it
+			// belongs to the instruction which threw the exception.
+			handler, c, ok := wazeroir.FindExceptionCatch(handlers, pc-1-uint64(fn.codeInitialAddress), func(index wasm.Index) bool {
+				tag := tags[index]
+				return exc.Tag == api.Tag(tag) && len(exc.Values) == tag.Type.ParamNumInUint64
+			})
+			if ok {
+				for i := range functionListeners {
+					functionListeners[i].Abort(ctx, m, functionListeners[i].def, exc)
+				}
+				ce.callDepth -= unwound
+				// Synchronize the fuel, as the exception might be thrown by a host function which added it.
+				ce.storeFuel(m)
+				ce.loadFuel(m)
+
+				height := stackBasePointer + handler.StackHeight
+				top := height
+				if c.Ref == wazeroir.ExceptionRefHandle {
+					ce.stack[top] = ce.storeException(exc, height)
+					top++
+				}
+				if !c.CatchAll {
+					top += copy(ce.stack[top:], exc.Values)
+				}
+				if c.Ref == wazeroir.ExceptionRefExnref {
+					ce.stack[top] = ce.storeExnref(exc)
+				}
+				ce.stackBasePointerInBytes = uint64(stackBasePointer) << 3
+				ce.moduleContext.fn = fn
+				return fn.codeInitialAddress + uintptr(c.Target), fn.moduleInstance, true
+			}
+		}
 
+		if fn.parent.listener != nil {
+			functionListeners = append(functionListeners, functionListenerInvocation{
+				FunctionListener: fn.parent.listener,
+				def:              fn.definition(),
+			})
+		}
+
+		if stackBasePointer == 0 { // base == 0 means that this was the last call frame stacked.
+			return 0, nil, false
+		}
+		frame := *(*callFrame)(unsafe.Pointer(&ce.stack[stackBasePointer+callFrameOffset(fn.funcType)]))
+		fn = frame.function
+		pc = uint64(frame.returnAddress)
+		stackBasePointer = int(frame.returnStackBasePointerInBytes >> 3)
+	}
+}
+
+// storeException stores the exception caught at the height of the stack, and returns its handle.
+func (ce *callEngine) storeException(exc *api.Exception, height int) uint64 {
+	// The handles at or above the height are no longer on the stack, so their indexes are reused.
+	i := len(ce.exceptions)
+	for i > 0 && ce.exceptions[i-1].height >= height {
+		i--
+	}
+	ce.exceptions = append(ce.exceptions[:i], caughtException{exc: exc, height: height})
+	return uint64(i)
+}
+
+// storeExnref returns the wasm.ValueTypeExnref referencing the exception, which is never zero.
+func (ce *callEngine) storeExnref(exc *api.Exception) uint64 {
+	// The same exception is caught again after throw_ref, so reuse its reference.
+	for i := len(ce.exnrefs) - 1; i >= 0; i-- {
+		if ce.exnrefs[i] == exc {
+			return uint64(i + 1)
+		}
+	}
+	ce.exnrefs = append(ce.exnrefs, exc)
+	return uint64(len(ce.exnrefs))
+}
+
+// execNativeCode calls into the native code at codeAddr, and serves its calls to Go until it returns.
+func (ce *callEngine) execNativeCode(ctx context.Context, m *wasm.ModuleInstance, codeAddr uintptr, modAddr *wasm.ModuleInstance) {
 entry:
 	{
 		// Call into the native code.
@@ -1045,13 +1428,18 @@ entry:
 			}
 			stack := ce.stack[base : base+stackLen]
 
+			// Synchronize the fuel, so that the host function can read or add it.
+			ce.storeFuel(m)
 			fn := calleeHostFunction.parent.goFunc
 			switch fn := fn.(type) {
+			case wasm.CallDepthGoFunction:
+				fn.CallWithDepth(ctx, ce.callerModuleInstance, stack, ce.callDepth)
 			case api.GoModuleFunction:
 				fn.Call(ctx, ce.callerModuleInstance, stack)
 			case api.GoFunction:
 				fn.Call(ctx, stack)
 			}
+			ce.loadFuel(m)
 
 			codeAddr, modAddr = ce.returnAddress, ce.moduleInstance
 			goto entry
@@ -1059,7 +1447,7 @@ entry:
 			caller := ce.moduleContext.fn
 			switch ce.exitContext.builtinFunctionCallIndex {
 			case builtinFunctionIndexMemoryGrow:
-				ce.builtinFunctionMemoryGrow(caller.moduleInstance.MemoryInstance)
+				ce.builtinFunctionMemoryGrow(ce.moduleContext.memoryInstance)
 			case builtinFunctionIndexGrowStack:
 				ce.builtinFunctionGrowStack(caller.parent.stackPointerCeil)
 			case builtinFunctionIndexTableGrow:
@@ -1075,6 +1463,16 @@ entry:
 				if err := m.FailIfClosed(); err != nil {
 					panic(err)
 				}
+			case builtinFunctionIndexAtomic:
+				ce.builtinFunctionAtomic(ce.moduleContext.memoryInstance)
+			case builtinFunctionIndexMemoryCopy:
+				ce.builtinFunctionMemoryCopy(caller.moduleInstance.Memories)
+			case builtinFunctionIndexThrow:
+				ce.builtinFunctionThrow(caller.moduleInstance.Tags)
+			case builtinFunctionIndexRethrow:
+				ce.builtinFunctionRethrow()
+			case builtinFunctionIndexThrowRef:
+				ce.builtinFunctionThrowRef()
 			}
 			if false {
 				if ce.exitContext.builtinFunctionCallIndex == builtinFunctionIndexBreakPoint {
@@ -1093,19 +1491,26 @@ entry:
 // callStackCeiling is the maximum WebAssembly call frame stack height. This allows wazero to raise
 // wasm.ErrCallStackOverflow instead of overflowing the Go runtime.
 //
-// The default value should suffice for most use cases. Those wishing to change this can via `go build -ldflags`.
-//
-// TODO: allows to configure this via context?
+// The default value should suffice for most use cases. Those wishing to change this can via `go build -ldflags`,
+// or per runtime via wasm.Store MaxStackBytes.
 var callStackCeiling = uint64(5000000) // in uint64 (8 bytes) == 40000000 bytes in total == 40mb.
 
 func (ce *callEngine) builtinFunctionGrowStack(stackPointerCeil uint64) {
-	oldLen := uint64(len(ce.stack))
-	if callStackCeiling < oldLen {
+	maxLen := ce.maxStackLen
+	if maxLen == 0 { // callEngine created without call in tests.
+		maxLen = callStackCeiling
+	}
+	minLen := ce.stackBasePointerInBytes>>3 + stackPointerCeil
+	if maxLen < minLen {
 		panic(wasmruntime.ErrRuntimeStackOverflow)
 	}
 
-	// Extends the stack's length to oldLen*2+stackPointerCeil.
+	// Extends the stack's length to oldLen*2+stackPointerCeil, but not beyond maxLen.
+	oldLen := uint64(len(ce.stack))
 	newLen := oldLen<<1 + (stackPointerCeil)
+	if newLen > maxLen {
+		newLen = maxLen
+	}
 	newStack := make([]uint64, newLen)
 	top := ce.stackTopIndex()
 	copy(newStack[:top], ce.stack[:top])
@@ -1118,7 +1523,16 @@ func (ce *callEngine) builtinFunctionGrowStack(stackPointerCeil uint64) {
 func (ce *callEngine) builtinFunctionMemoryGrow(mem *wasm.MemoryInstance) {
 	newPages := ce.popValue()
 
-	if res, ok := mem.Grow(uint32(newPages)); !ok {
+	if mem.Is64 {
+		// 64-bit memories can never grow by 2^32 pages or more, so don't truncate the delta.
+		if newPages > math.MaxUint32 {
+			ce.pushValue(math.MaxUint64) // = -1 in signed 64-bit integer.
+		} else if res, ok := mem.Grow(uint32(newPages)); !ok {
+			ce.pushValue(math.MaxUint64)
+		} else {
+			ce.pushValue(uint64(res))
+		}
+	} else if res, ok := mem.Grow(uint32(newPages)); !ok {
 		ce.pushValue(uint64(0xffffffff)) // = -1 in signed 32-bit integer.
 	} else {
 		ce.pushValue(uint64(res))
@@ -1139,6 +1553,182 @@ func (ce *callEngine) builtinFunctionTableGrow(tables []*wasm.TableInstance) {
 	ce.pushValue(uint64(res))
 }
 
+// encodeAtomicOperation encodes the atomic operation into a 64-bit descriptor, which is pushed onto the stack
+// before calling builtinFunctionIndexAtomic, and decoded by callEngine.builtinFunctionAtomic.
+//
+// The lower 32 bits are the offset, and the upper bytes are the alignment, the type, the arithmetic operation
+// and the Kind relative to wazeroir.OperationKindAtomicMemoryWait in this order.
+//
+// The offset of an operation on a 64-bit memory might not fit in 32 bits, so it is left zero here and pushed
+// onto the stack right before the descriptor instead.
+func encodeAtomicOperation(o *wazeroir.UnionOperation) uint64 {
+	offset := o.U2
+	if o.B3 {
+		offset = 0
+	}
+	return offset | o.U1<<32 | uint64(o.B1)<<40 | uint64(o.B2)<<48 |
+		uint64(o.Kind-wazeroir.OperationKindAtomicMemoryWait)<<56
+}
+
+// atomicOperationStackEffect returns the number of operands consumed by the atomic operation, and the type of its
+// result if hasResult is true.
+func atomicOperationStackEffect(o *wazeroir.UnionOperation) (operands int, result runtimeValueType, hasResult bool) {
+	result, hasResult = runtimeValueTypeI32, true
+	if wazeroir.UnsignedType(o.B1) == wazeroir.UnsignedTypeI64 {
+		result = runtimeValueTypeI64
+	}
+	switch o.Kind {
+	case wazeroir.OperationKindAtomicMemoryWait:
+		return 3, runtimeValueTypeI32, true
+	case wazeroir.OperationKindAtomicMemoryNotify:
+		return 2, runtimeValueTypeI32, true
+	case wazeroir.OperationKindAtomicFence:
+		return 0, 0, false
+	case wazeroir.OperationKindAtomicLoad:
+		return 1, result, true
+	case wazeroir.OperationKindAtomicStore:
+		return 2, 0, false
+	case wazeroir.OperationKindAtomicRMW:
+		return 2, result, true
+	default: // wazeroir.OperationKindAtomicRMWCmpxchg
+		return 3, result, true
+	}
+}
+
+// builtinFunctionAtomic performs the atomic operation described by the descriptor on the top of the stack.
+// See encodeAtomicOperation.
+//
+// Atomic operations are implemented in Go rather than native code so that they share the implementation,
+// including memory.atomic.wait32 and memory.atomic.wait64 which must block the goroutine, with the interpreter.
+func (ce *callEngine) builtinFunctionAtomic(mem *wasm.MemoryInstance) {
+	d := ce.popValue()
+	offset, size := d&math.MaxUint32, uint32(1)<<byte(d>>32)
+	typ, op := wazeroir.UnsignedType(d>>40), wazeroir.AtomicArithmeticOp(d>>48)
+	kind := wazeroir.OperationKindAtomicMemoryWait + wazeroir.OperationKind(d>>56)
+	if mem.Is64 {
+		offset = ce.popValue()
+	}
+
+	var res uint64
+	var err error
+	switch kind {
+	case wazeroir.OperationKindAtomicMemoryWait:
+		timeout := int64(ce.popValue())
+		expected := ce.popValue()
+		offset = ce.popAtomicAddress(mem, offset)
+		if typ == wazeroir.UnsignedTypeI32 {
+			res, err = mem.AtomicWait32(offset, uint32(expected), timeout, ce.initialFn.moduleInstance)
+		} else {
+			res, err = mem.AtomicWait64(offset, expected, timeout, ce.initialFn.moduleInstance)
+		}
+	case wazeroir.OperationKindAtomicMemoryNotify:
+		count := ce.popValue()
+		offset = ce.popAtomicAddress(mem, offset)
+		var woken uint32
+		woken, err = mem.AtomicNotify(offset, uint32(count))
+		res = uint64(woken)
+	case wazeroir.OperationKindAtomicFence:
+		// The exit from the native code to Go is a full barrier, so there's nothing to do.
+		return
+	case wazeroir.OperationKindAtomicLoad:
+		offset = ce.popAtomicAddress(mem, offset)
+		res, err = mem.AtomicLoad(offset, size)
+	case wazeroir.OperationKindAtomicStore:
+		val := ce.popValue()
+		offset = ce.popAtomicAddress(mem, offset)
+		if err = mem.AtomicStore(offset, size, val); err != nil {
+			panic(err)
+		}
+		return
+	case wazeroir.OperationKindAtomicRMW:
+		val := ce.popValue()
+		offset = ce.popAtomicAddress(mem, offset)
+		res, err = mem.AtomicRMW(offset, size, val, op.Apply)
+	case wazeroir.OperationKindAtomicRMWCmpxchg:
+		replacement := ce.popValue()
+		expected := ce.popValue()
+		offset = ce.popAtomicAddress(mem, offset)
+		res, err = mem.AtomicCompareExchange(offset, size, expected, replacement)
+	}
+	if err != nil {
+		panic(err)
+	}
+	ce.pushValue(res)
+}
+
+// popAtomicAddress pops the address operand of the atomic instruction on mem, and returns the effective address with
+// the static offset added. The alignment is checked by the wasm.MemoryInstance methods before the bounds, so this only
+// panics if the effective address of a 64-bit memory wraps around, which is out of bounds regardless of the alignment.
+func (ce *callEngine) popAtomicAddress(mem *wasm.MemoryInstance, offset uint64) uint64 {
+	if !mem.Is64 {
+		return offset + uint64(uint32(ce.popValue()))
+	}
+	addr := offset + ce.popValue()
+	if addr < offset {
+		panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+	}
+	return addr
+}
+
+// encodeMemoryCopy encodes the memory indexes of wazeroir.NewOperationMemoryCopy into a 64-bit descriptor, which is
+// pushed onto the stack before calling builtinFunctionIndexMemoryCopy, and decoded by callEngine.builtinFunctionMemoryCopy.
+func encodeMemoryCopy(o *wazeroir.UnionOperation) uint64 {
+	return o.U1<<32 | o.U2
+}
+
+// builtinFunctionMemoryCopy performs memory.copy between the memories described by the descriptor on the top of
+// the stack. See encodeMemoryCopy.
+//
+// This is only used when either memory is not the zero one or is 64-bit, so that the native code only has to deal
+// with the 32-bit memory held by the module context.
+func (ce *callEngine) builtinFunctionMemoryCopy(memories []*wasm.MemoryInstance) {
+	d := ce.popValue()
+	dst, src := memories[uint32(d>>32)], memories[uint32(d)]
+
+	// Operands are i64 only for 64-bit memories, and i32 otherwise. The size is i64 only when both memories are.
+	size, srcOffset, dstOffset := ce.popValue(), ce.popValue(), ce.popValue()
+	if !src.Is64 || !dst.Is64 {
+		size = uint64(uint32(size))
+	}
+	if !src.Is64 {
+		srcOffset = uint64(uint32(srcOffset))
+	}
+	if !dst.Is64 {
+		dstOffset = uint64(uint32(dstOffset))
+	}
+	if size > uint64(len(src.Buffer)) || srcOffset > uint64(len(src.Buffer))-size ||
+		size > uint64(len(dst.Buffer)) || dstOffset > uint64(len(dst.Buffer))-size {
+		panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+	}
+	copy(dst.Buffer[dstOffset:dstOffset+size], src.Buffer[srcOffset:srcOffset+size])
+}
+
+// builtinFunctionThrow throws the exception of the tag whose index is on the top of the stack, with the values of the
+// parameters of the tag below it.
+func (ce *callEngine) builtinFunctionThrow(tags []*wasm.TagInstance) {
+	tag := tags[uint32(ce.popValue())]
+	values := make([]uint64, tag.Type.ParamNumInUint64)
+	for i := len(values) - 1; i >= 0; i-- {
+		values[i] = ce.popValue()
+	}
+	panic(&api.Exception{Tag: tag, Values: values})
+}
+
+// builtinFunctionRethrow throws the exception whose handle is on the top of the stack again.
+func (ce *callEngine) builtinFunctionRethrow() {
+	panic(ce.exceptions[ce.popValue()].exc)
+}
+
+// builtinFunctionThrowRef throws the exception referenced by the wasm.ValueTypeExnref on the top of the stack, or traps
+// if it is null.
+func (ce *callEngine) builtinFunctionThrowRef() {
+	ref := ce.popValue()
+	if ref == 0 {
+		panic(wasmruntime.ErrRuntimeNullReference)
+	}
+	panic(ce.exnrefs[ref-1])
+}
+
 // stackIterator implements experimental.StackIterator.
 type stackIterator struct {
 	stack   []uint64
@@ -1247,15 +1837,17 @@ type offsets struct {
 	values []uint64
 }
 
-func compileWasmFunction(buf asm.Buffer, cmp compiler, ir *wazeroir.CompilationResult, asmNodes *asmNodes, offsets *offsets) (spCeil uint64, sm sourceOffsetMap, err error) {
+func compileWasmFunction(buf asm.Buffer, cmp compiler, ir *wazeroir.CompilationResult, asmNodes *asmNodes, offsets *offsets) (spCeil uint64, sm sourceOffsetMap, handlers []wazeroir.ExceptionHandler, err error) {
 	if err = cmp.compilePreamble(); err != nil {
 		err = fmt.Errorf("failed to emit preamble: %w", err)
 		return
 	}
 
 	needSourceOffsets := len(ir.IROperationSourceOffsetsInWasmBinary) > 0
+	// The exception handlers also need the offsets of IR operations to find the catches of the native code.
+	needIROpBegins := needSourceOffsets || len(ir.ExceptionHandlers) > 0
 	var irOpBegins []asm.Node
-	if needSourceOffsets {
+	if needIROpBegins {
 		irOpBegins = append(asmNodes.nodes[:0], make([]asm.Node, len(ir.Operations))...)
 		defer func() { asmNodes.nodes = irOpBegins }()
 	}
@@ -1263,7 +1855,7 @@ func compileWasmFunction(buf asm.Buffer, cmp compiler, ir *wazeroir.CompilationR
 	var skip bool
 	for i := range ir.Operations {
 		op := &ir.Operations[i]
-		if needSourceOffsets {
+		if needIROpBegins {
 			// If this compilation requires source offsets for DWARF based back trace,
 			// we emit a NOP node at the beginning of each IR operation to get the
 			// binary offset of the beginning of the corresponding compiled native code.
@@ -1275,6 +1867,12 @@ func compileWasmFunction(buf asm.Buffer, cmp compiler, ir *wazeroir.CompilationR
 		// we don't need to generate native code at all as we never reach the region.
 		if op.Kind == wazeroir.OperationKindLabel {
 			skip = cmp.compileLabel(op)
+		} else if op.Kind == wazeroir.OperationKindCatch {
+			// Likewise, catches are never reached by the preceding code, but only if their try block is.
+			if skip, err = cmp.compileCatch(op); err != nil {
+				err = fmt.Errorf("operation %s: %w", op.Kind.String(), err)
+				return
+			}
 		}
 		if skip {
 			continue
@@ -1284,6 +1882,10 @@ func compileWasmFunction(buf asm.Buffer, cmp compiler, ir *wazeroir.CompilationR
 			fmt.Printf("compiling op=%s: %s\n", op.Kind, cmp)
 		}
 		switch op.Kind {
+		case wazeroir.OperationKindConsumeFuel:
+			err = cmp.compileConsumeFuel(op)
+		case wazeroir.OperationKindCheckEpoch:
+			err = cmp.compileCheckEpoch()
 		case wazeroir.OperationKindUnreachable:
 			err = cmp.compileUnreachable()
 		case wazeroir.OperationKindLabel:
@@ -1298,6 +1900,12 @@ func compileWasmFunction(buf asm.Buffer, cmp compiler, ir *wazeroir.CompilationR
 			err = cmp.compileCall(op)
 		case wazeroir.OperationKindCallIndirect:
 			err = cmp.compileCallIndirect(op)
+		case wazeroir.OperationKindTailCall:
+			err = cmp.compileTailCall(op)
+		case wazeroir.OperationKindTailCallIndirect:
+			err = cmp.compileTailCallIndirect(op)
+		case wazeroir.OperationKindCallRef:
+			err = cmp.compileCallRef(op)
 		case wazeroir.OperationKindDrop:
 			err = cmp.compileDrop(op)
 		case wazeroir.OperationKindSelect:
@@ -1327,7 +1935,7 @@ func compileWasmFunction(buf asm.Buffer, cmp compiler, ir *wazeroir.CompilationR
 		case wazeroir.OperationKindStore32:
 			err = cmp.compileStore32(op)
 		case wazeroir.OperationKindMemorySize:
-			err = cmp.compileMemorySize()
+			err = cmp.compileMemorySize(op)
 		case wazeroir.OperationKindMemoryGrow:
 			err = cmp.compileMemoryGrow()
 		case wazeroir.OperationKindConstI32:
@@ -1437,9 +2045,9 @@ func compileWasmFunction(buf asm.Buffer, cmp compiler, ir *wazeroir.CompilationR
 		case wazeroir.OperationKindDataDrop:
 			err = cmp.compileDataDrop(op)
 		case wazeroir.OperationKindMemoryCopy:
-			err = cmp.compileMemoryCopy()
+			err = cmp.compileMemoryCopy(op)
 		case wazeroir.OperationKindMemoryFill:
-			err = cmp.compileMemoryFill()
+			err = cmp.compileMemoryFill(op)
 		case wazeroir.OperationKindTableInit:
 			err = cmp.compileTableInit(op)
 		case wazeroir.OperationKindElemDrop:
@@ -1448,6 +2056,8 @@ func compileWasmFunction(buf asm.Buffer, cmp compiler, ir *wazeroir.CompilationR
 			err = cmp.compileTableCopy(op)
 		case wazeroir.OperationKindRefFunc:
 			err = cmp.compileRefFunc(op)
+		case wazeroir.OperationKindRefAsNonNull:
+			err = cmp.compileRefAsNonNull(op)
 		case wazeroir.OperationKindTableGet:
 			err = cmp.compileTableGet(op)
 		case wazeroir.OperationKindTableSet:
@@ -1560,8 +2170,32 @@ func compileWasmFunction(buf asm.Buffer, cmp compiler, ir *wazeroir.CompilationR
 			err = cmp.compileV128Narrow(op)
 		case wazeroir.OperationKindV128ITruncSatFromF:
 			err = cmp.compileV128ITruncSatFromF(op)
+		case wazeroir.OperationKindV128RelaxedMadd:
+			err = cmp.compileV128RelaxedMadd(op)
+		case wazeroir.OperationKindV128RelaxedDot:
+			err = cmp.compileV128RelaxedDot(op)
 		case wazeroir.OperationKindBuiltinFunctionCheckExitCode:
 			err = cmp.compileBuiltinFunctionCheckExitCode()
+		case wazeroir.OperationKindAtomicMemoryWait,
+			wazeroir.OperationKindAtomicMemoryNotify,
+			wazeroir.OperationKindAtomicFence,
+			wazeroir.OperationKindAtomicLoad,
+			wazeroir.OperationKindAtomicStore,
+			wazeroir.OperationKindAtomicRMW,
+			wazeroir.OperationKindAtomicRMWCmpxchg:
+			err = cmp.compileAtomic(op)
+		case wazeroir.OperationKindSelectMemory:
+			err = cmp.compileSelectMemory(op)
+		case wazeroir.OperationKindTry:
+			err = cmp.compileTry(op)
+		case wazeroir.OperationKindCatch:
+		// catch op is already handled ^^.
+		case wazeroir.OperationKindThrow:
+			err = cmp.compileThrow(op)
+		case wazeroir.OperationKindRethrow:
+			err = cmp.compileRethrow(op)
+		case wazeroir.OperationKindThrowRef:
+			err = cmp.compileThrowRef()
 		default:
 			err = errors.New("unsupported")
 		}
@@ -1586,5 +2220,21 @@ func compileWasmFunction(buf asm.Buffer, cmp compiler, ir *wazeroir.CompilationR
 		sm.irOperationOffsetsInNativeBinary = bitpack.NewOffsetArray(offsetInNativeBin)
 		sm.irOperationSourceOffsetsInWasmBinary = bitpack.NewOffsetArray(ir.IROperationSourceOffsetsInWasmBinary)
 	}
+
+	// The exception handlers are allocated per function, so they are translated into native offsets in place.
+	handlers = ir.ExceptionHandlers
+	for i := range handlers {
+		h := &handlers[i]
+		h.Start = irOpBegins[h.Start].OffsetInBinary()
+		if h.End < uint64(len(irOpBegins)) {
+			h.End = irOpBegins[h.End].OffsetInBinary()
+		} else {
+			h.End = math.MaxUint64
+		}
+		for j := range h.Catches {
+			c := &h.Catches[j]
+			c.Target = irOpBegins[c.Target].OffsetInBinary()
+		}
+	}
 	return
 }
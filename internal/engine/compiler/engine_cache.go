@@ -12,6 +12,7 @@ import (
 	"github.com/tetratelabs/wazero/internal/u32"
 	"github.com/tetratelabs/wazero/internal/u64"
 	"github.com/tetratelabs/wazero/internal/wasm"
+	"github.com/tetratelabs/wazero/internal/wazeroir"
 )
 
 func (e *engine) deleteCompiledModule(module *wasm.Module) {
@@ -109,11 +110,25 @@ func serializeCompiledModule(wazeroVersion string, cm *compiledModule) io.Reader
 	buf.WriteByte(byte(len(wazeroVersion)))
 	// Version of wazero.
 	buf.WriteString(wazeroVersion)
+	// Next 1 byte: flags of ensure termination (bit 0), check epoch (bit 1) and exception handlers (bit 2).
+	var flags byte
 	if cm.ensureTermination {
-		buf.WriteByte(1)
-	} else {
-		buf.WriteByte(0)
+		flags |= 1
 	}
+	if cm.checkEpoch {
+		flags |= 2
+	}
+	hasExceptionHandlers := false
+	for i := range cm.functions {
+		if len(cm.functions[i].exceptionHandlers) > 0 {
+			hasExceptionHandlers = true
+			break
+		}
+	}
+	if hasExceptionHandlers {
+		flags |= 4
+	}
+	buf.WriteByte(flags)
 	// Number of *code (== locally defined functions in the module): 4 bytes.
 	buf.Write(u32.LeBytes(uint32(len(cm.functions))))
 	for i := 0; i < len(cm.functions); i++ {
@@ -122,6 +137,9 @@ func serializeCompiledModule(wazeroVersion string, cm *compiledModule) io.Reader
 		buf.Write(u64.LeBytes(f.stackPointerCeil))
 		// The offset of this function in the executable (8 bytes).
 		buf.Write(u64.LeBytes(uint64(f.executableOffset)))
+		if hasExceptionHandlers {
+			serializeExceptionHandlers(buf, f.exceptionHandlers)
+		}
 	}
 	// The length of code segment (8 bytes).
 	buf.Write(u64.LeBytes(uint64(cm.executable.Len())))
@@ -132,7 +150,7 @@ func serializeCompiledModule(wazeroVersion string, cm *compiledModule) io.Reader
 
 func deserializeCompiledModule(wazeroVersion string, reader io.ReadCloser, module *wasm.Module) (cm *compiledModule, staleCache bool, err error) {
 	defer reader.Close()
-	cacheHeaderSize := len(wazeroMagic) + 1 /* version size */ + len(wazeroVersion) + 1 /* ensure termination */ + 4 /* number of functions */
+	cacheHeaderSize := len(wazeroMagic) + 1 /* version size */ + len(wazeroVersion) + 1 /* flags */ + 4 /* number of functions */
 
 	// Read the header before the native code.
 	header := make([]byte, cacheHeaderSize)
@@ -157,12 +175,13 @@ func deserializeCompiledModule(wazeroVersion string, reader io.ReadCloser, modul
 		return
 	}
 
-	ensureTermination := header[cachedVersionEnd] != 0
+	flags := header[cachedVersionEnd]
 	functionsNum := binary.LittleEndian.Uint32(header[len(header)-4:])
 	cm = &compiledModule{
 		compiledCode:      new(compiledCode),
 		functions:         make([]compiledFunction, functionsNum),
-		ensureTermination: ensureTermination,
+		ensureTermination: flags&1 != 0,
+		checkEpoch:        flags&2 != 0,
 	}
 
 	imported := module.ImportFunctionCount
@@ -186,6 +205,13 @@ func deserializeCompiledModule(wazeroVersion string, reader io.ReadCloser, modul
 		}
 		f.executableOffset = uintptr(offset)
 		f.index = imported + i
+
+		if flags&4 != 0 {
+			if f.exceptionHandlers, err = deserializeExceptionHandlers(reader, &eightBytes); err != nil {
+				err = fmt.Errorf("compilationcache: error reading func[%d] exception handlers: %v", i, err)
+				return
+			}
+		}
 	}
 
 	executableLen, err := readUint64(reader, &eightBytes)
@@ -216,6 +242,72 @@ func deserializeCompiledModule(wazeroVersion string, reader io.ReadCloser, modul
 	return
 }
 
+// serializeExceptionHandlers writes the number of the handlers (8 bytes), followed by the start, end, next, stack
+// height and the number of catches of each handler, followed by the tag, kind and target of each catch (8 bytes
+// each). The kind is the wazeroir.ExceptionRef shifted by one, whose bit 0 is set for a catch all.
+func serializeExceptionHandlers(buf *bytes.Buffer, handlers []wazeroir.ExceptionHandler) {
+	buf.Write(u64.LeBytes(uint64(len(handlers))))
+	for i := range handlers {
+		h := &handlers[i]
+		buf.Write(u64.LeBytes(h.Start))
+		buf.Write(u64.LeBytes(h.End))
+		buf.Write(u64.LeBytes(uint64(int64(h.Next))))
+		buf.Write(u64.LeBytes(uint64(h.StackHeight)))
+		buf.Write(u64.LeBytes(uint64(len(h.Catches))))
+		for j := range h.Catches {
+			c := &h.Catches[j]
+			kind := uint64(c.Ref) << 1
+			if c.CatchAll {
+				kind |= 1
+			}
+			buf.Write(u64.LeBytes(uint64(c.Tag)))
+			buf.Write(u64.LeBytes(kind))
+			buf.Write(u64.LeBytes(c.Target))
+		}
+	}
+}
+
+// deserializeExceptionHandlers reads the handlers written by serializeExceptionHandlers.
+func deserializeExceptionHandlers(reader io.Reader, b *[8]byte) (handlers []wazeroir.ExceptionHandler, err error) {
+	var v [8]uint64
+	read := func(n int) error {
+		for i := 0; i < n; i++ {
+			if v[i], err = readUint64(reader, b); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err = read(1); err != nil || v[0] == 0 {
+		return
+	}
+	handlers = make([]wazeroir.ExceptionHandler, v[0])
+	for i := range handlers {
+		if err = read(5); err != nil {
+			return
+		}
+		h := &handlers[i]
+		h.Start, h.End, h.Next, h.StackHeight = v[0], v[1], int(int64(v[2])), int(v[3])
+		if v[4] == 0 {
+			continue
+		}
+		h.Catches = make([]wazeroir.ExceptionCatch, v[4])
+		for j := range h.Catches {
+			if err = read(3); err != nil {
+				return
+			}
+			h.Catches[j] = wazeroir.ExceptionCatch{
+				Tag:      wasm.Index(v[0]),
+				CatchAll: v[1]&1 == 1,
+				Ref:      wazeroir.ExceptionRef(v[1] >> 1),
+				Target:   v[2],
+			}
+		}
+	}
+	return
+}
+
 // readUint64 strictly reads an uint64 in little-endian byte order, using the
 // given array as a buffer. This returns io.EOF if less than 8 bytes were read.
 func readUint64(reader io.Reader, b *[8]byte) (uint64, error) {
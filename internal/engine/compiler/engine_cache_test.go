@@ -16,6 +16,7 @@ import (
 	"github.com/tetratelabs/wazero/internal/u32"
 	"github.com/tetratelabs/wazero/internal/u64"
 	"github.com/tetratelabs/wazero/internal/wasm"
+	"github.com/tetratelabs/wazero/internal/wazeroir"
 )
 
 var testVersion = ""
@@ -107,6 +108,42 @@ func TestSerializeCompiledModule(t *testing.T) {
 				[]byte{1, 2, 3, 4, 5, 1, 2, 3}, // code.
 			),
 		},
+		{
+			in: &compiledModule{
+				compiledCode: &compiledCode{
+					executable: makeCodeSegment(1, 2, 3, 4, 5),
+				},
+				functions: []compiledFunction{
+					{executableOffset: 0, stackPointerCeil: 12345, exceptionHandlers: []wazeroir.ExceptionHandler{{
+						Start: 2, End: 10, Next: -1, StackHeight: 3,
+						Catches: []wazeroir.ExceptionCatch{
+							{Tag: 1, Ref: wazeroir.ExceptionRefHandle, Target: 20},
+							{CatchAll: true, Ref: wazeroir.ExceptionRefExnref, Target: 30},
+						},
+					}}},
+				},
+			},
+			exp: concat(
+				[]byte(wazeroMagic),
+				[]byte{byte(len(testVersion))},
+				[]byte(testVersion),
+				[]byte{4},          // exception handlers.
+				u32.LeBytes(1),     // number of functions.
+				u64.LeBytes(12345), // stack pointer ceil.
+				u64.LeBytes(0),     // offset.
+				// Exception handlers.
+				u64.LeBytes(1),                                  // number of handlers.
+				u64.LeBytes(2),                                  // start.
+				u64.LeBytes(10),                                 // end.
+				u64.LeBytes(0xffffffffffffffff),                 // next.
+				u64.LeBytes(3),                                  // stack height.
+				u64.LeBytes(2),                                  // number of catches.
+				u64.LeBytes(1), u64.LeBytes(2), u64.LeBytes(20), // tag, kind, target.
+				u64.LeBytes(0), u64.LeBytes(5), u64.LeBytes(30), // tag, kind, target.
+				u64.LeBytes(5),        // length of code.
+				[]byte{1, 2, 3, 4, 5}, // code.
+			),
+		},
 	}
 
 	for i, tc := range tests {
@@ -280,6 +317,58 @@ func TestDeserializeCompiledModule(t *testing.T) {
 			),
 			expErr: "compilationcache: error reading executable (len=5): EOF",
 		},
+		{
+			name: "one function with exception handlers",
+			in: concat(
+				[]byte(wazeroMagic),
+				[]byte{byte(len(testVersion))},
+				[]byte(testVersion),
+				[]byte{4},          // exception handlers.
+				u32.LeBytes(1),     // number of functions.
+				u64.LeBytes(12345), // stack pointer ceil.
+				u64.LeBytes(0),     // offset.
+				// Exception handlers.
+				u64.LeBytes(1),                                  // number of handlers.
+				u64.LeBytes(2),                                  // start.
+				u64.LeBytes(10),                                 // end.
+				u64.LeBytes(0xffffffffffffffff),                 // next.
+				u64.LeBytes(3),                                  // stack height.
+				u64.LeBytes(2),                                  // number of catches.
+				u64.LeBytes(1), u64.LeBytes(2), u64.LeBytes(20), // tag, kind, target.
+				u64.LeBytes(0), u64.LeBytes(5), u64.LeBytes(30), // tag, kind, target.
+				u64.LeBytes(5),        // length of code.
+				[]byte{1, 2, 3, 4, 5}, // code.
+			),
+			expCompiledModule: &compiledModule{
+				compiledCode: &compiledCode{
+					executable: makeCodeSegment(1, 2, 3, 4, 5),
+				},
+				functions: []compiledFunction{
+					{executableOffset: 0, stackPointerCeil: 12345, index: 0, exceptionHandlers: []wazeroir.ExceptionHandler{{
+						Start: 2, End: 10, Next: -1, StackHeight: 3,
+						Catches: []wazeroir.ExceptionCatch{
+							{Tag: 1, Ref: wazeroir.ExceptionRefHandle, Target: 20},
+							{CatchAll: true, Ref: wazeroir.ExceptionRefExnref, Target: 30},
+						},
+					}}},
+				},
+			},
+		},
+		{
+			name: "reading exception handlers",
+			in: concat(
+				[]byte(wazeroMagic),
+				[]byte{byte(len(testVersion))},
+				[]byte(testVersion),
+				[]byte{4},          // exception handlers.
+				u32.LeBytes(1),     // number of functions.
+				u64.LeBytes(12345), // stack pointer ceil.
+				u64.LeBytes(0),     // offset.
+				u64.LeBytes(1),     // number of handlers.
+				u64.LeBytes(2),     // start.
+			),
+			expErr: "compilationcache: error reading func[0] exception handlers: EOF",
+		},
 	}
 
 	for _, tc := range tests {
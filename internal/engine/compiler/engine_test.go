@@ -145,6 +145,11 @@ func TestCompiler_ModuleEngine_Memory(t *testing.T) {
 	enginetest.RunTestModuleEngineMemory(t, et)
 }
 
+func TestCompiler_ModuleEngine_MaxCallStackDepth(t *testing.T) {
+	requireSupportedOSArch(t)
+	enginetest.RunTestModuleEngineMaxCallStackDepth(t, et)
+}
+
 func TestCompiler_BeforeListenerStackIterator(t *testing.T) {
 	enginetest.RunTestModuleEngineBeforeListenerStackIterator(t, et)
 }
@@ -192,11 +197,11 @@ func TestCompiler_CompileModule(t *testing.T) {
 			ID: wasm.ModuleID{},
 		}
 
-		err := e.CompileModule(testCtx, okModule, nil, false)
+		err := e.CompileModule(testCtx, okModule, nil, false, false, false)
 		require.NoError(t, err)
 
 		// Compiling same module shouldn't be compiled again, but instead should be cached.
-		err = e.CompileModule(testCtx, okModule, nil, false)
+		err = e.CompileModule(testCtx, okModule, nil, false, false, false)
 		require.NoError(t, err)
 
 		compiled, ok := e.codes[okModule.ID]
@@ -222,15 +227,62 @@ func TestCompiler_CompileModule(t *testing.T) {
 		}
 
 		e := et.NewEngine(api.CoreFeaturesV1).(*engine)
-		err := e.CompileModule(testCtx, errModule, nil, false)
+		err := e.CompileModule(testCtx, errModule, nil, false, false, false)
 		require.EqualError(t, err, "failed to lower func[2]: handling instruction: apply stack failed for call: reading immediates: EOF")
 
 		// On the compilation failure, the compiled functions must not be cached.
 		_, ok := e.codes[errModule.ID]
 		require.False(t, ok)
+
+		// Concurrent compilation fails with the same error.
+		err = e.CompileModule(wasm.WithCompilationWorkers(testCtx, 3), errModule, nil, false, false, false)
+		require.EqualError(t, err, "failed to lower func[2]: handling instruction: apply stack failed for call: reading immediates: EOF")
+		_, ok = e.codes[errModule.ID]
+		require.False(t, ok)
 	})
 }
 
+func TestCompiler_CompileModule_Workers(t *testing.T) {
+	i32 := []wasm.ValueType{wasm.ValueTypeI32}
+	m := &wasm.Module{
+		TypeSection:     []wasm.FunctionType{{Params: i32, Results: i32}},
+		FunctionSection: []wasm.Index{0, 0, 0, 0, 0},
+		CodeSection: []wasm.Code{
+			{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeEnd}},
+			{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeCall, 0, wasm.OpcodeEnd}},
+			{Body: []byte{
+				wasm.OpcodeLoop, 0x40,
+				wasm.OpcodeLocalGet, 0, wasm.OpcodeI32Const, 1, wasm.OpcodeI32Sub, wasm.OpcodeLocalTee, 0,
+				wasm.OpcodeBrIf, 0,
+				wasm.OpcodeEnd,
+				wasm.OpcodeLocalGet, 0, wasm.OpcodeEnd,
+			}},
+			{Body: []byte{wasm.OpcodeI32Const, 2, wasm.OpcodeLocalGet, 0, wasm.OpcodeI32Mul, wasm.OpcodeCall, 2, wasm.OpcodeEnd}},
+			{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeCall, 1, wasm.OpcodeCall, 3, wasm.OpcodeEnd}},
+		},
+	}
+
+	compile := func(workers int) *compiledModule {
+		e := et.NewEngine(api.CoreFeaturesV1).(*engine)
+		e.setFinalizer = fakeFinalizer{}.setFinalizer
+		err := e.CompileModule(wasm.WithCompilationWorkers(testCtx, workers), m, nil, false, false, false)
+		require.NoError(t, err)
+		return e.codes[m.ID]
+	}
+
+	// The compiled code must be the same regardless of the count of workers, as it's shared via the file cache.
+	expected := compile(1)
+	for _, workers := range []int{2, 3, 8} {
+		actual := compile(workers)
+		require.Equal(t, expected.executable.Bytes(), actual.executable.Bytes())
+		require.Equal(t, len(expected.functions), len(actual.functions))
+		for i := range expected.functions {
+			require.Equal(t, expected.functions[i].executableOffset, actual.functions[i].executableOffset)
+			require.Equal(t, expected.functions[i].stackPointerCeil, actual.functions[i].stackPointerCeil)
+		}
+	}
+}
+
 func TestCompiler_Releasecode_Panic(t *testing.T) {
 	captured := require.CapturePanic(func() {
 		releaseCompiledModule(&compiledModule{
@@ -273,17 +325,18 @@ func TestCompiler_SliceAllocatedOnHeap(t *testing.T) {
 		hostModuleName,
 		[]string{hostFnName},
 		map[string]*wasm.HostFunc{hostFnName: {ExportName: hostFnName, Code: wasm.Code{GoFunc: hostFn}}},
+		nil,
 		enabledFeatures,
 	)
 	require.NoError(t, err)
 
-	err = s.Engine.CompileModule(testCtx, hm, nil, false)
+	err = s.Engine.CompileModule(testCtx, hm, nil, false, false, false)
 	require.NoError(t, err)
 
 	typeIDs, err := s.GetFunctionTypeIDs(hm.TypeSection)
 	require.NoError(t, err)
 
-	_, err = s.Instantiate(testCtx, hm, hostModuleName, nil, typeIDs)
+	_, err = s.Instantiate(testCtx, hm, hostModuleName, nil, typeIDs, nil)
 	require.NoError(t, err)
 
 	const stackCorruption = "value_stack_corruption"
@@ -343,13 +396,13 @@ func TestCompiler_SliceAllocatedOnHeap(t *testing.T) {
 		ID: wasm.ModuleID{1},
 	}
 
-	err = s.Engine.CompileModule(testCtx, m, nil, false)
+	err = s.Engine.CompileModule(testCtx, m, nil, false, false, false)
 	require.NoError(t, err)
 
 	typeIDs, err = s.GetFunctionTypeIDs(m.TypeSection)
 	require.NoError(t, err)
 
-	mi, err := s.Instantiate(testCtx, m, t.Name(), nil, typeIDs)
+	mi, err := s.Instantiate(testCtx, m, t.Name(), nil, typeIDs, nil)
 	require.NoError(t, err)
 
 	for _, fnName := range []string{stackCorruption, callStackCorruption} {
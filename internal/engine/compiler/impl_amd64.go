@@ -102,6 +102,11 @@ type amd64Compiler struct {
 	// frameIDMax tracks the maximum value of frame id per function.
 	frameIDMax int
 	brTableTmp []runtimeValueLocation
+	// tryStacks holds the location stack at the beginning of each try block, indexed by the exception handler.
+	// nil means that the try block has never been reached.
+	tryStacks []*runtimeValueLocationStack
+	// catchStack is the location stack from which we start compiling catches.
+	catchStack runtimeValueLocationStack
 
 	fourZeros,
 	eightZeros,
@@ -280,12 +285,230 @@ func (c *amd64Compiler) compileBuiltinFunctionCheckExitCode() error {
 	return nil
 }
 
+// compileConsumeFuel implements compiler.compileConsumeFuel for the amd64 architecture.
+func (c *amd64Compiler) compileConsumeFuel(o *wazeroir.UnionOperation) error {
+	// ADDQ below clobbers the flags, so materialize any conditional value first.
+	if err := c.maybeCompileMoveTopConditionalToGeneralPurposeRegister(); err != nil {
+		return err
+	}
+
+	fuel, err := c.allocateRegister(registerTypeGeneralPurpose)
+	if err != nil {
+		return err
+	}
+
+	c.assembler.CompileMemoryToRegister(amd64.MOVQ,
+		amd64ReservedRegisterForCallEngine, callEngineExitContextFuelOffset, fuel)
+	c.assembler.CompileConstToRegister(amd64.ADDQ, -int64(o.U1), fuel)
+
+	// If the remaining fuel is negative, exit without storing it, so the fuel is unchanged.
+	c.compileMaybeExitFromNativeCode(amd64.JPL, nativeCallStatusCodeOutOfFuel)
+
+	c.assembler.CompileRegisterToMemory(amd64.MOVQ,
+		fuel, amd64ReservedRegisterForCallEngine, callEngineExitContextFuelOffset)
+	return nil
+}
+
+// compileAtomic implements compiler.compileAtomic for the amd64 architecture.
+func (c *amd64Compiler) compileAtomic(o *wazeroir.UnionOperation) error {
+	if err := c.maybeCompileMoveTopConditionalToGeneralPurposeRegister(); err != nil {
+		return err
+	}
+
+	operands, result, hasResult := atomicOperationStackEffect(o)
+	if o.B3 {
+		// The offset on 64-bit memories doesn't fit in the descriptor, so it's pushed separately. See encodeAtomicOperation.
+		offset := wazeroir.NewOperationConstI64(o.U2)
+		if err := c.compileConstI64(&offset); err != nil {
+			return err
+		}
+		operands++
+	}
+
+	// Pushes the descriptor of the operation, which is decoded by the builtin function.
+	descriptor := wazeroir.NewOperationConstI64(encodeAtomicOperation(o))
+	if err := c.compileConstI64(&descriptor); err != nil {
+		return err
+	}
+
+	if err := c.compileCallBuiltinFunction(builtinFunctionIndexAtomic); err != nil {
+		return err
+	}
+
+	// The builtin function consumes the descriptor and the operands.
+	for i := 0; i < operands+1; i++ {
+		c.locationStack.pop()
+	}
+	if hasResult {
+		loc := c.locationStack.pushRuntimeValueLocationOnStack()
+		loc.valueType = result
+	}
+
+	// After return, we re-initialize reserved registers just like preamble of functions.
+	c.compileReservedStackBasePointerInitialization()
+	c.compileReservedMemoryPointerInitialization()
+	return nil
+}
+
+// compileSelectMemory implements compiler.compileSelectMemory for the amd64 architecture.
+func (c *amd64Compiler) compileSelectMemory(o *wazeroir.UnionOperation) error {
+	memoryIndex := int64(o.U1)
+
+	tmp, err := c.allocateRegister(registerTypeGeneralPurpose)
+	if err != nil {
+		return err
+	}
+	c.locationStack.markRegisterUsed(tmp)
+	tmp2, err := c.allocateRegister(registerTypeGeneralPurpose)
+	if err != nil {
+		return err
+	}
+
+	// "tmp = &moduleInstance.Memories[0]"
+	c.assembler.CompileMemoryToRegister(amd64.MOVQ,
+		amd64ReservedRegisterForCallEngine, callEngineModuleContextModuleInstanceOffset, tmp)
+	c.assembler.CompileMemoryToRegister(amd64.MOVQ, tmp, moduleInstanceMemoriesOffset, tmp)
+	// "tmp = moduleInstance.Memories[memoryIndex]"
+	c.assembler.CompileMemoryToRegister(amd64.MOVQ, tmp, memoryIndex*8, tmp)
+
+	// Update ce.moduleContext's memory fields the same way as compileModuleContextInitialization does.
+	c.assembler.CompileRegisterToMemory(amd64.MOVQ,
+		tmp, amd64ReservedRegisterForCallEngine, callEngineModuleContextMemoryInstanceOffset)
+	c.assembler.CompileMemoryToRegister(amd64.MOVQ, tmp, memoryInstanceBufferLenOffset, tmp2)
+	c.assembler.CompileRegisterToMemory(amd64.MOVQ,
+		tmp2, amd64ReservedRegisterForCallEngine, callEngineModuleContextMemorySliceLenOffset)
+	c.assembler.CompileMemoryToRegister(amd64.MOVQ, tmp, memoryInstanceBufferOffset, tmp2)
+	c.assembler.CompileRegisterToMemory(amd64.MOVQ,
+		tmp2, amd64ReservedRegisterForCallEngine, callEngineModuleContextMemoryElement0AddressOffset)
+
+	c.locationStack.markRegisterUnused(tmp)
+	c.compileReservedMemoryPointerInitialization()
+	return nil
+}
+
+// compileTry implements compiler.compileTry for the amd64 architecture.
+func (c *amd64Compiler) compileTry(o *wazeroir.UnionOperation) error {
+	if c.tryStacks == nil {
+		c.tryStacks = make([]*runtimeValueLocationStack, len(c.ir.ExceptionHandlers))
+	}
+	stack := &runtimeValueLocationStack{}
+	stack.cloneFrom(*c.locationStack)
+	// The values above the height of the handler are not visible from the catches.
+	stack.sp = uint64(c.ir.ExceptionHandlers[o.U1].StackHeight)
+	c.tryStacks[o.U1] = stack
+	return nil
+}
+
+// compileCatch implements compiler.compileCatch for the amd64 architecture.
+func (c *amd64Compiler) compileCatch(o *wazeroir.UnionOperation) (skipThisCatch bool, err error) {
+	var tryStack *runtimeValueLocationStack
+	if c.tryStacks != nil {
+		tryStack = c.tryStacks[o.U1]
+	}
+	if tryStack == nil {
+		skipThisCatch = true
+		return
+	}
+
+	// The catch is entered from Go just like the beginning of a function, with all the values on the memory stack.
+	c.setLocationStack(&c.catchStack)
+	c.catchStack.cloneOnStackFrom(*tryStack)
+	if err = c.compileModuleContextInitialization(); err != nil {
+		return
+	}
+	c.compileReservedStackBasePointerInitialization()
+	c.compileReservedMemoryPointerInitialization()
+
+	// The values of the caught exception, along with its reference documented on wazeroir.ExceptionRef.
+	ref := wazeroir.ExceptionRef(o.B2)
+	if ref == wazeroir.ExceptionRefHandle {
+		handle := c.locationStack.pushRuntimeValueLocationOnStack()
+		handle.valueType = runtimeValueTypeI64
+	}
+	if o.B1 != 1 {
+		c.locationStack.pushRuntimeValueLocationsOnStack(c.ir.Types[c.ir.Tags[o.U2]].Params)
+	}
+	if ref == wazeroir.ExceptionRefExnref {
+		exnref := c.locationStack.pushRuntimeValueLocationOnStack()
+		exnref.valueType = runtimeValueTypeI64
+	}
+	return
+}
+
+// compileThrow implements compiler.compileThrow for the amd64 architecture.
+func (c *amd64Compiler) compileThrow(o *wazeroir.UnionOperation) error {
+	if err := c.maybeCompileMoveTopConditionalToGeneralPurposeRegister(); err != nil {
+		return err
+	}
+	// Pass the tag index to the builtin function on top of the values of the exception.
+	if err := c.compileConstI32Impl(uint32(o.U1)); err != nil {
+		return err
+	}
+	if err := c.compileCallBuiltinFunction(builtinFunctionIndexThrow); err != nil {
+		return err
+	}
+	// The builtin function never returns, but the address after the call is needed to exit from the native code.
+	return c.compileUnreachable()
+}
+
+// compileRethrow implements compiler.compileRethrow for the amd64 architecture.
+func (c *amd64Compiler) compileRethrow(o *wazeroir.UnionOperation) error {
+	// Copy the handle of the exception to the top of the stack, and pass it to the builtin function.
+	pick := wazeroir.NewOperationPick(int(o.U1), false)
+	if err := c.compilePick(&pick); err != nil {
+		return err
+	}
+	if err := c.compileCallBuiltinFunction(builtinFunctionIndexRethrow); err != nil {
+		return err
+	}
+	// The builtin function never returns, like the one for throw.
+	return c.compileUnreachable()
+}
+
+// compileThrowRef implements compiler.compileThrowRef for the amd64 architecture.
+func (c *amd64Compiler) compileThrowRef() error {
+	// The reference to the exception is already on the top of the stack, which is passed to the builtin function.
+	if err := c.compileCallBuiltinFunction(builtinFunctionIndexThrowRef); err != nil {
+		return err
+	}
+	// The builtin function never returns, like the one for throw.
+	return c.compileUnreachable()
+}
+
+// compileCheckEpoch implements compiler.compileCheckEpoch for the amd64 architecture.
+func (c *amd64Compiler) compileCheckEpoch() error {
+	// CMPQ below clobbers the flags, so materialize any conditional value first.
+	if err := c.maybeCompileMoveTopConditionalToGeneralPurposeRegister(); err != nil {
+		return err
+	}
+
+	epoch, err := c.allocateRegister(registerTypeGeneralPurpose)
+	if err != nil {
+		return err
+	}
+
+	// epoch = *callEngine.exitContext.epoch
+	c.assembler.CompileMemoryToRegister(amd64.MOVQ,
+		amd64ReservedRegisterForCallEngine, callEngineExitContextEpochOffset, epoch)
+	c.assembler.CompileMemoryToRegister(amd64.MOVQ, epoch, 0, epoch)
+
+	// Continue if epoch < callEngine.exitContext.epochDeadline.
+	c.assembler.CompileMemoryToRegister(amd64.CMPQ,
+		amd64ReservedRegisterForCallEngine, callEngineExitContextEpochDeadlineOffset, epoch)
+	c.compileMaybeExitFromNativeCode(amd64.JHI, nativeCallStatusCodeEpochInterrupted)
+	return nil
+}
+
 // compileGoDefinedHostFunction constructs the entire code to enter the host function implementation,
 // and return to the caller.
 func (c *amd64Compiler) compileGoDefinedHostFunction() error {
 	// First we must update the location stack to reflect the number of host function inputs.
 	c.locationStack.init(c.typ)
 
+	if err := c.compileIncrementCallDepth(); err != nil {
+		return err
+	}
+
 	if c.withListener {
 		if err := c.compileCallBuiltinFunction(builtinFunctionIndexFunctionListenerBefore); err != nil {
 			return err
@@ -816,6 +1039,16 @@ func (c *amd64Compiler) compileLabel(o *wazeroir.UnionOperation) (skipLabel bool
 
 // compileCall implements compiler.compileCall for the amd64 architecture.
 func (c *amd64Compiler) compileCall(o *wazeroir.UnionOperation) error {
+	return c.compileCallDirectImpl(o, false)
+}
+
+// compileTailCall implements compiler.compileTailCall for the amd64 architecture.
+func (c *amd64Compiler) compileTailCall(o *wazeroir.UnionOperation) error {
+	return c.compileCallDirectImpl(o, true)
+}
+
+// compileCallDirectImpl implements compiler.compileCall and compiler.compileTailCall for the amd64 architecture.
+func (c *amd64Compiler) compileCallDirectImpl(o *wazeroir.UnionOperation, tail bool) error {
 	if err := c.maybeCompileMoveTopConditionalToGeneralPurposeRegister(); err != nil {
 		return err
 	}
@@ -838,6 +1071,9 @@ func (c *amd64Compiler) compileCall(o *wazeroir.UnionOperation) error {
 	c.assembler.CompileMemoryToRegister(amd64.ADDQ, amd64ReservedRegisterForCallEngine,
 		callEngineModuleContextFunctionsElement0AddressOffset, targetAddressRegister)
 
+	if tail {
+		return c.compileTailCallFunctionImpl(targetAddressRegister, targetType)
+	}
 	if err := c.compileCallFunctionImpl(targetAddressRegister, targetType); err != nil {
 		return err
 	}
@@ -846,6 +1082,17 @@ func (c *amd64Compiler) compileCall(o *wazeroir.UnionOperation) error {
 
 // compileCallIndirect implements compiler.compileCallIndirect for the amd64 architecture.
 func (c *amd64Compiler) compileCallIndirect(o *wazeroir.UnionOperation) error {
+	return c.compileCallIndirectImpl(o, false)
+}
+
+// compileTailCallIndirect implements compiler.compileTailCallIndirect for the amd64 architecture.
+func (c *amd64Compiler) compileTailCallIndirect(o *wazeroir.UnionOperation) error {
+	return c.compileCallIndirectImpl(o, true)
+}
+
+// compileCallIndirectImpl implements compiler.compileCallIndirect and compiler.compileTailCallIndirect for the amd64
+// architecture.
+func (c *amd64Compiler) compileCallIndirectImpl(o *wazeroir.UnionOperation, tail bool) error {
 	offset := c.locationStack.pop()
 	if err := c.compileEnsureOnRegister(offset); err != nil {
 		return nil
@@ -907,7 +1154,12 @@ func (c *amd64Compiler) compileCallIndirect(o *wazeroir.UnionOperation) error {
 	c.assembler.CompileMemoryToRegister(amd64.CMPL, offset.register, functionTypeIDOffset, tmp2)
 	c.compileMaybeExitFromNativeCode(amd64.JEQ, nativeCallStatusCodeTypeMismatchOnIndirectCall)
 	targetFunctionType := &c.ir.Types[typeIndex]
-	if err = c.compileCallFunctionImpl(offset.register, targetFunctionType); err != nil {
+	if tail {
+		err = c.compileTailCallFunctionImpl(offset.register, targetFunctionType)
+	} else {
+		err = c.compileCallFunctionImpl(offset.register, targetFunctionType)
+	}
+	if err != nil {
 		return nil
 	}
 
@@ -916,6 +1168,47 @@ func (c *amd64Compiler) compileCallIndirect(o *wazeroir.UnionOperation) error {
 	return nil
 }
 
+// compileCallRef implements compiler.compileCallRef for the amd64 architecture.
+func (c *amd64Compiler) compileCallRef(o *wazeroir.UnionOperation) error {
+	ref := c.locationStack.pop()
+	if err := c.compileEnsureOnRegister(ref); err != nil {
+		return err
+	}
+	typeIndex := o.U1
+
+	tmp, err := c.allocateRegister(registerTypeGeneralPurpose)
+	if err != nil {
+		return err
+	}
+	c.locationStack.markRegisterUsed(tmp)
+
+	// ref.register holds the address of *function, so check if it is null.
+	c.assembler.CompileRegisterToRegister(amd64.TESTQ, ref.register, ref.register)
+
+	// Skipped if the reference is not null.
+	c.compileMaybeExitFromNativeCode(amd64.JNE, nativeCallStatusCodeNullFunctionReference)
+
+	// Next, we need to check the type matches as compileCallIndirectImpl.
+	//
+	// "tmp = [&moduleInstance.TypeIDs[0] + index * 4] (== moduleInstance.TypeIDs[index])"
+	c.assembler.CompileMemoryToRegister(amd64.MOVQ,
+		amd64ReservedRegisterForCallEngine, callEngineModuleContextTypeIDsElement0AddressOffset,
+		tmp)
+	c.assembler.CompileMemoryToRegister(amd64.MOVL, tmp, int64(typeIndex)*4, tmp)
+
+	// Skipped if the type matches.
+	c.assembler.CompileMemoryToRegister(amd64.CMPL, ref.register, functionTypeIDOffset, tmp)
+	c.compileMaybeExitFromNativeCode(amd64.JEQ, nativeCallStatusCodeTypeMismatchOnIndirectCall)
+
+	if err = c.compileCallFunctionImpl(ref.register, &c.ir.Types[typeIndex]); err != nil {
+		return err
+	}
+
+	// The ref register should be marked as un-used as we consumed in the function call.
+	c.locationStack.markRegisterUnused(ref.register, tmp)
+	return nil
+}
+
 // compileDrop implements compiler.compileDrop for the amd64 architecture.
 func (c *amd64Compiler) compileDrop(o *wazeroir.UnionOperation) error {
 	return compileDropRange(c, o.U1)
@@ -3258,7 +3551,6 @@ func (c *amd64Compiler) compileLoad(o *wazeroir.UnionOperation) error {
 	)
 
 	unsignedType := wazeroir.UnsignedType(o.B1)
-	offset := uint32(o.U2)
 
 	switch unsignedType {
 	case wazeroir.UnsignedTypeI32:
@@ -3283,7 +3575,7 @@ func (c *amd64Compiler) compileLoad(o *wazeroir.UnionOperation) error {
 		vt = runtimeValueTypeF64
 	}
 
-	reg, err := c.compileMemoryAccessCeilSetup(offset, targetSizeInBytes)
+	reg, err := c.compileMemoryAccessCeilSetup(o, targetSizeInBytes)
 	if err != nil {
 		return err
 	}
@@ -3316,8 +3608,7 @@ func (c *amd64Compiler) compileLoad(o *wazeroir.UnionOperation) error {
 // compileLoad8 implements compiler.compileLoad8 for the amd64 architecture.
 func (c *amd64Compiler) compileLoad8(o *wazeroir.UnionOperation) error {
 	const targetSizeInBytes = 1
-	offset := uint32(o.U2)
-	reg, err := c.compileMemoryAccessCeilSetup(offset, targetSizeInBytes)
+	reg, err := c.compileMemoryAccessCeilSetup(o, targetSizeInBytes)
 	if err != nil {
 		return err
 	}
@@ -3354,8 +3645,7 @@ func (c *amd64Compiler) compileLoad8(o *wazeroir.UnionOperation) error {
 // compileLoad16 implements compiler.compileLoad16 for the amd64 architecture.
 func (c *amd64Compiler) compileLoad16(o *wazeroir.UnionOperation) error {
 	const targetSizeInBytes = 16 / 8
-	offset := uint32(o.U2)
-	reg, err := c.compileMemoryAccessCeilSetup(offset, targetSizeInBytes)
+	reg, err := c.compileMemoryAccessCeilSetup(o, targetSizeInBytes)
 	if err != nil {
 		return err
 	}
@@ -3392,8 +3682,7 @@ func (c *amd64Compiler) compileLoad16(o *wazeroir.UnionOperation) error {
 // compileLoad32 implements compiler.compileLoad32 for the amd64 architecture.
 func (c *amd64Compiler) compileLoad32(o *wazeroir.UnionOperation) error {
 	const targetSizeInBytes = 32 / 8
-	offset := uint32(o.U2)
-	reg, err := c.compileMemoryAccessCeilSetup(offset, targetSizeInBytes)
+	reg, err := c.compileMemoryAccessCeilSetup(o, targetSizeInBytes)
 	if err != nil {
 		return err
 	}
@@ -3414,22 +3703,29 @@ func (c *amd64Compiler) compileLoad32(o *wazeroir.UnionOperation) error {
 	return nil
 }
 
-// compileMemoryAccessCeilSetup pops the top value from the stack (called "base"), stores "base + offsetArg + targetSizeInBytes"
+// compileMemoryAccessCeilSetup pops the top value from the stack (called "base"), stores "base + offset + targetSizeInBytes"
 // into a register, and returns the stored register. We call the result "ceil" because we access the memory
-// as memory.Buffer[ceil-targetSizeInBytes: ceil].
+// as memory.Buffer[ceil-targetSizeInBytes: ceil]. The offset is the static one of the memory access operation o.
 //
 // Note: this also emits the instructions to check the out-of-bounds memory access.
 // In other words, if the ceil exceeds the memory size, the code exits with nativeCallStatusCodeMemoryOutOfBounds status.
-func (c *amd64Compiler) compileMemoryAccessCeilSetup(offsetArg uint32, targetSizeInBytes int64) (asm.Register, error) {
+func (c *amd64Compiler) compileMemoryAccessCeilSetup(o *wazeroir.UnionOperation, targetSizeInBytes int64) (asm.Register, error) {
 	base := c.locationStack.pop()
 	if err := c.compileEnsureOnRegister(base); err != nil {
 		return asm.NilRegister, err
 	}
 
 	result := base.register
-	if offsetConst := int64(offsetArg) + targetSizeInBytes; offsetConst <= math.MaxInt32 {
-		c.assembler.CompileConstToRegister(amd64.ADDQ, offsetConst, result)
-	} else if offsetConst <= math.MaxUint32 {
+	offsetArg, memory64 := o.U2, o.B3
+	if offsetConst := offsetArg + uint64(targetSizeInBytes); offsetConst < offsetArg || offsetConst > math.MaxInt64 ||
+		(!memory64 && offsetConst > math.MaxUint32) {
+		// If the offset const is too large, we exit with nativeCallStatusCodeMemoryOutOfBounds.
+		// Note that even 64-bit memories are far smaller than 2^63 bytes, so any offset above can never be in bounds.
+		c.compileExitFromNativeCode(nativeCallStatusCodeMemoryOutOfBounds)
+		return result, nil
+	} else if offsetConst <= math.MaxInt32 {
+		c.assembler.CompileConstToRegister(amd64.ADDQ, int64(offsetConst), result)
+	} else {
 		// Note: in practice, this branch rarely happens as in this case, the wasm binary know that
 		// memory has more than 1 GBi or at least tries to access above 1 GBi memory region.
 		//
@@ -3440,12 +3736,17 @@ func (c *amd64Compiler) compileMemoryAccessCeilSetup(offsetArg uint32, targetSiz
 		if err != nil {
 			return asm.NilRegister, err
 		}
-		c.assembler.CompileConstToRegister(amd64.MOVL, int64(uint32(offsetConst)), tmp)
+		if offsetConst <= math.MaxUint32 {
+			c.assembler.CompileConstToRegister(amd64.MOVL, int64(uint32(offsetConst)), tmp)
+		} else {
+			c.assembler.CompileConstToRegister(amd64.MOVQ, int64(offsetConst), tmp)
+		}
 		c.assembler.CompileRegisterToRegister(amd64.ADDQ, tmp, result)
-	} else {
-		// If the offset const is too large, we exit with nativeCallStatusCodeMemoryOutOfBounds.
-		c.compileExitFromNativeCode(nativeCallStatusCodeMemoryOutOfBounds)
-		return result, nil
+	}
+
+	if memory64 {
+		// The base of 64-bit memories is a full 64-bit value, so the addition above can overflow. Trap if it carries.
+		c.compileMaybeExitFromNativeCode(amd64.JCC, nativeCallStatusCodeMemoryOutOfBounds)
 	}
 
 	// Now we compare the value with the memory length which is held by callEngine.
@@ -3464,7 +3765,6 @@ func (c *amd64Compiler) compileStore(o *wazeroir.UnionOperation) error {
 	var movInst asm.Instruction
 	var targetSizeInByte int64
 	unsignedType := wazeroir.UnsignedType(o.B1)
-	offset := uint32(o.U2)
 	switch unsignedType {
 	case wazeroir.UnsignedTypeI32, wazeroir.UnsignedTypeF32:
 		movInst = amd64.MOVL
@@ -3473,31 +3773,31 @@ func (c *amd64Compiler) compileStore(o *wazeroir.UnionOperation) error {
 		movInst = amd64.MOVQ
 		targetSizeInByte = 64 / 8
 	}
-	return c.compileStoreImpl(offset, movInst, targetSizeInByte)
+	return c.compileStoreImpl(o, movInst, targetSizeInByte)
 }
 
 // compileStore8 implements compiler.compileStore8 for the amd64 architecture.
 func (c *amd64Compiler) compileStore8(o *wazeroir.UnionOperation) error {
-	return c.compileStoreImpl(uint32(o.U2), amd64.MOVB, 1)
+	return c.compileStoreImpl(o, amd64.MOVB, 1)
 }
 
 // compileStore32 implements compiler.compileStore32 for the amd64 architecture.
 func (c *amd64Compiler) compileStore16(o *wazeroir.UnionOperation) error {
-	return c.compileStoreImpl(uint32(o.U2), amd64.MOVW, 16/8)
+	return c.compileStoreImpl(o, amd64.MOVW, 16/8)
 }
 
 // compileStore32 implements compiler.compileStore32 for the amd64 architecture.
 func (c *amd64Compiler) compileStore32(o *wazeroir.UnionOperation) error {
-	return c.compileStoreImpl(uint32(o.U2), amd64.MOVL, 32/8)
+	return c.compileStoreImpl(o, amd64.MOVL, 32/8)
 }
 
-func (c *amd64Compiler) compileStoreImpl(offsetConst uint32, inst asm.Instruction, targetSizeInBytes int64) error {
+func (c *amd64Compiler) compileStoreImpl(o *wazeroir.UnionOperation, inst asm.Instruction, targetSizeInBytes int64) error {
 	val := c.locationStack.pop()
 	if err := c.compileEnsureOnRegister(val); err != nil {
 		return err
 	}
 
-	reg, err := c.compileMemoryAccessCeilSetup(offsetConst, targetSizeInBytes)
+	reg, err := c.compileMemoryAccessCeilSetup(o, targetSizeInBytes)
 	if err != nil {
 		return err
 	}
@@ -3530,7 +3830,7 @@ func (c *amd64Compiler) compileMemoryGrow() error {
 }
 
 // compileMemorySize implements compiler.compileMemorySize for the amd64 architecture.
-func (c *amd64Compiler) compileMemorySize() error {
+func (c *amd64Compiler) compileMemorySize(o *wazeroir.UnionOperation) error {
 	if err := c.maybeCompileMoveTopConditionalToGeneralPurposeRegister(); err != nil {
 		return err
 	}
@@ -3539,7 +3839,11 @@ func (c *amd64Compiler) compileMemorySize() error {
 	if err != nil {
 		return err
 	}
-	loc := c.pushRuntimeValueLocationOnRegister(reg, runtimeValueTypeI32)
+	vt := runtimeValueTypeI32
+	if o.B3 { // 64-bit memories return the page size as i64.
+		vt = runtimeValueTypeI64
+	}
+	loc := c.pushRuntimeValueLocationOnRegister(reg, vt)
 
 	c.assembler.CompileMemoryToRegister(amd64.MOVQ, amd64ReservedRegisterForCallEngine, callEngineModuleContextMemorySliceLenOffset, loc.register)
 
@@ -3553,14 +3857,15 @@ func (c *amd64Compiler) compileMemorySize() error {
 // compileMemoryInit implements compiler.compileMemoryInit for the amd64 architecture.
 func (c *amd64Compiler) compileMemoryInit(o *wazeroir.UnionOperation) error {
 	dataIndex := uint32(o.U1)
-	return c.compileInitImpl(false, dataIndex, 0)
+	return c.compileInitImpl(false, dataIndex, 0, o.B3)
 }
 
-// compileInitImpl implements compileTableInit and compileMemoryInit.
+// compileInitImpl implements compileTableInit and compileMemoryInit. memory64 is true when the destination is a 64-bit
+// memory, whose offset can overflow when added to the size.
 //
 // TODO: the compiled code in this function should be reused and compile at once as
 // the code is independent of any module.
-func (c *amd64Compiler) compileInitImpl(isTable bool, index, tableIndex uint32) error {
+func (c *amd64Compiler) compileInitImpl(isTable bool, index, tableIndex uint32, memory64 bool) error {
 	outOfBoundsErrorStatus := nativeCallStatusCodeMemoryOutOfBounds
 	if isTable {
 		outOfBoundsErrorStatus = nativeCallStatusCodeInvalidTableAccess
@@ -3602,6 +3907,10 @@ func (c *amd64Compiler) compileInitImpl(isTable bool, index, tableIndex uint32)
 	c.assembler.CompileRegisterToRegister(amd64.ADDQ, copySize.register, sourceOffset.register)
 	// destinationOffset += size.
 	c.assembler.CompileRegisterToRegister(amd64.ADDQ, copySize.register, destinationOffset.register)
+	if memory64 {
+		// Exit if the addition above carries.
+		c.compileMaybeExitFromNativeCode(amd64.JCC, outOfBoundsErrorStatus)
+	}
 
 	// Check instance bounds and if exceeds the length, exit with out of bounds error.
 	c.assembler.CompileMemoryToRegister(amd64.CMPQ,
@@ -3807,7 +4116,11 @@ func (c *amd64Compiler) compileMemoryCopyLoopImpl(destinationOffset, sourceOffse
 //
 // This uses efficient `REP MOVSQ` instructions to copy in quadword (8 bytes) batches. The remaining bytes
 // are copied with a simple `MOV` loop. It uses backward copying for overlapped segments.
-func (c *amd64Compiler) compileMemoryCopy() error {
+func (c *amd64Compiler) compileMemoryCopy(o *wazeroir.UnionOperation) error {
+	if o.U1 != 0 || o.U2 != 0 || o.B3 {
+		return c.compileMultiMemoryCopy(o)
+	}
+
 	copySize := c.locationStack.pop()
 	if err := c.compileEnsureOnRegister(copySize); err != nil {
 		return err
@@ -3874,6 +4187,34 @@ func (c *amd64Compiler) compileMemoryCopy() error {
 	return nil
 }
 
+// compileMultiMemoryCopy adds instructions to perform memory.copy involving a memory other than the zero one, or a
+// 64-bit memory, which is done by the builtin function.
+func (c *amd64Compiler) compileMultiMemoryCopy(o *wazeroir.UnionOperation) error {
+	if err := c.maybeCompileMoveTopConditionalToGeneralPurposeRegister(); err != nil {
+		return err
+	}
+
+	// Pushes the descriptor of the operation, which is decoded by the builtin function.
+	descriptor := wazeroir.NewOperationConstI64(encodeMemoryCopy(o))
+	if err := c.compileConstI64(&descriptor); err != nil {
+		return err
+	}
+
+	if err := c.compileCallBuiltinFunction(builtinFunctionIndexMemoryCopy); err != nil {
+		return err
+	}
+
+	// The builtin function consumes the descriptor, the destination and source offsets, and the size.
+	for i := 0; i < 4; i++ {
+		c.locationStack.pop()
+	}
+
+	// After return, we re-initialize reserved registers just like preamble of functions.
+	c.compileReservedStackBasePointerInitialization()
+	c.compileReservedMemoryPointerInitialization()
+	return nil
+}
+
 // compileFillLoopImpl implements a REP STOSQ fill loop.
 func (c *amd64Compiler) compileFillLoopImpl(destinationOffset, value, fillSize *runtimeValueLocation, tmp asm.Register, replicateByte bool) {
 	// Skip if nothing to fill.
@@ -3917,7 +4258,7 @@ func (c *amd64Compiler) compileFillLoopImpl(destinationOffset, value, fillSize *
 //
 // TODO: the compiled code in this function should be reused and compile at once as
 // the code is independent of any module.
-func (c *amd64Compiler) compileFillImpl(isTable bool, tableIndex uint32) error {
+func (c *amd64Compiler) compileFillImpl(isTable bool, tableIndex uint32, memory64 bool) error {
 	copySize := c.locationStack.pop()
 	if err := c.compileEnsureOnRegister(copySize); err != nil {
 		return err
@@ -3941,6 +4282,10 @@ func (c *amd64Compiler) compileFillImpl(isTable bool, tableIndex uint32) error {
 
 	// destinationOffset += size.
 	c.assembler.CompileRegisterToRegister(amd64.ADDQ, copySize.register, destinationOffset.register)
+	if memory64 {
+		// Exit if the addition above carries.
+		c.compileMaybeExitFromNativeCode(amd64.JCC, nativeCallStatusCodeMemoryOutOfBounds)
+	}
 
 	// Check destination bounds and if exceeds the length, exit with out of bounds error.
 	if isTable {
@@ -4015,15 +4360,15 @@ func (c *amd64Compiler) compileFillImpl(isTable bool, tableIndex uint32) error {
 //
 // TODO: the compiled code in this function should be reused and compile at once as
 // the code is independent of any module.
-func (c *amd64Compiler) compileMemoryFill() error {
-	return c.compileFillImpl(false, 0)
+func (c *amd64Compiler) compileMemoryFill(o *wazeroir.UnionOperation) error {
+	return c.compileFillImpl(false, 0, o.B3)
 }
 
 // compileTableInit implements compiler.compileTableInit for the amd64 architecture.
 func (c *amd64Compiler) compileTableInit(o *wazeroir.UnionOperation) error {
 	elemIndex := uint32(o.U1)
 	tableIndex := uint32(o.U2)
-	return c.compileInitImpl(true, elemIndex, tableIndex)
+	return c.compileInitImpl(true, elemIndex, tableIndex, false)
 }
 
 // compileTableCopyLoopImpl is used for directly copying after bounds/direction check.
@@ -4317,7 +4662,7 @@ func (c *amd64Compiler) compileTableSize(o *wazeroir.UnionOperation) error {
 // compileTableFill implements compiler.compileTableFill for the amd64 architecture.
 func (c *amd64Compiler) compileTableFill(o *wazeroir.UnionOperation) error {
 	tableIndex := uint32(o.U1)
-	return c.compileFillImpl(true, tableIndex)
+	return c.compileFillImpl(true, tableIndex, false)
 }
 
 // compileRefFunc implements compiler.compileRefFunc for the amd64 architecture.
@@ -4345,6 +4690,20 @@ func (c *amd64Compiler) compileRefFunc(o *wazeroir.UnionOperation) error {
 	return nil
 }
 
+// compileRefAsNonNull implements compiler.compileRefAsNonNull for the amd64 architecture.
+func (c *amd64Compiler) compileRefAsNonNull(*wazeroir.UnionOperation) error {
+	ref := c.locationStack.peek()
+	if err := c.compileEnsureOnRegister(ref); err != nil {
+		return err
+	}
+
+	c.assembler.CompileRegisterToRegister(amd64.TESTQ, ref.register, ref.register)
+
+	// Skipped if the reference is not null.
+	c.compileMaybeExitFromNativeCode(amd64.JNE, nativeCallStatusCodeNullReference)
+	return nil
+}
+
 // compileConstI32 implements compiler.compileConstI32 for the amd64 architecture.
 func (c *amd64Compiler) compileConstI32(o *wazeroir.UnionOperation) error {
 	return c.compileConstI32Impl(uint32(o.U1))
@@ -4655,7 +5014,7 @@ func (c *amd64Compiler) compileCallFunctionImpl(functionAddressRegister asm.Regi
 		switch t {
 		case wasm.ValueTypeI32:
 			loc.valueType = runtimeValueTypeI32
-		case wasm.ValueTypeI64, wasm.ValueTypeFuncref, wasm.ValueTypeExternref:
+		case wasm.ValueTypeI64, wasm.ValueTypeFuncref, wasm.ValueTypeExternref, wasm.ValueTypeExnref:
 			loc.valueType = runtimeValueTypeI64
 		case wasm.ValueTypeF32:
 			loc.valueType = runtimeValueTypeF32
@@ -4672,6 +5031,122 @@ func (c *amd64Compiler) compileCallFunctionImpl(functionAddressRegister asm.Regi
 	return nil
 }
 
+// compileTailCallFunctionImpl adds instructions to replace the current function's frame with the one of the function
+// whose address is in functionAddressRegister, and jump into it. The callee returns directly to the caller of the
+// current function, so tail calls grow neither the stack nor callEngine.exitContext.callDepth.
+//
+// The arguments on top of the stack are moved to the beginning of the current frame, and the call frame of the current
+// function is moved to the location for the callee's type:
+//
+//	              reserved slots for results (if len(results) > len(args))
+//	                     |     |
+//	   ,arg0, ..., argN, ..., _, .returnAddress, .returnStackBasePointerInBytes, .function, ....
+//	     |
+//	callEngine.stackContext.stackBasePointer (unchanged)
+//
+// Note: when the function listener is enabled, or the callee is a host function, this makes a call followed by a
+// return instead. The former is so that the listener receives the results of the current function, and the latter
+// because host functions read the module of their caller from the call frame.
+func (c *amd64Compiler) compileTailCallFunctionImpl(functionAddressRegister asm.Register, functype *wasm.FunctionType) error {
+	if c.withListener {
+		return c.compileCallAndReturn(functionAddressRegister, functype)
+	}
+
+	// Release all the registers as the arguments are moved on the stack memory.
+	if err := c.compileReleaseAllRegistersToStack(); err != nil {
+		return err
+	}
+
+	c.locationStack.markRegisterUsed(functionAddressRegister)
+
+	tmpRegister, found := c.locationStack.takeFreeRegister(registerTypeGeneralPurpose)
+	if !found {
+		return fmt.Errorf("could not find enough free registers")
+	}
+	c.locationStack.markRegisterUsed(tmpRegister)
+
+	// Check if the callee is a host function, i.e. function.parent.goFunc != nil.
+	c.assembler.CompileMemoryToRegister(amd64.MOVQ, functionAddressRegister, functionParentOffset, tmpRegister)
+	c.assembler.CompileMemoryToRegister(amd64.MOVQ, tmpRegister, compiledFunctionGoFuncOffset, tmpRegister)
+	c.assembler.CompileRegisterToRegister(amd64.TESTQ, tmpRegister, tmpRegister)
+	jmpIfHostFunction := c.assembler.CompileJump(amd64.JNE)
+
+	// First, load the call frame of the current function, as moving the arguments might overwrite it.
+	returnAddress, _, _ := c.locationStack.getCallFrameLocations(c.typ)
+	var callFrameRegisters [3]asm.Register
+	for i := range callFrameRegisters {
+		reg, found := c.locationStack.takeFreeRegister(registerTypeGeneralPurpose)
+		if !found {
+			return fmt.Errorf("could not find enough free registers")
+		}
+		c.locationStack.markRegisterUsed(reg)
+		c.assembler.CompileMemoryToRegister(amd64.MOVQ,
+			amd64ReservedRegisterForStackBasePointerAddress, int64(returnAddress.stackPointer+uint64(i))*8,
+			reg)
+		callFrameRegisters[i] = reg
+	}
+
+	// Next, move the arguments to the beginning of the frame. As the arguments are above the call frame, the
+	// destination is always below the source, so copying them in order is safe.
+	argsOffset := int64(c.locationStack.sp) - int64(functype.ParamNumInUint64)
+	for i := int64(0); i < int64(functype.ParamNumInUint64); i++ {
+		c.assembler.CompileMemoryToRegister(amd64.MOVQ,
+			amd64ReservedRegisterForStackBasePointerAddress, (argsOffset+i)*8,
+			tmpRegister)
+		c.assembler.CompileRegisterToMemory(amd64.MOVQ, tmpRegister,
+			amd64ReservedRegisterForStackBasePointerAddress, i*8)
+	}
+
+	// Then, write the call frame at the location for the callee's type.
+	offset := int64(callFrameOffset(functype))
+	for i, reg := range callFrameRegisters {
+		c.assembler.CompileRegisterToMemory(amd64.MOVQ, reg,
+			amd64ReservedRegisterForStackBasePointerAddress, (offset+int64(i))*8)
+	}
+
+	// The callee increments callEngine.exitContext.callDepth in its preamble, so decrement it for the current function.
+	c.assembler.CompileNoneToMemory(amd64.DECQ, amd64ReservedRegisterForCallEngine, callEngineExitContextCallDepthOffset)
+
+	// Set callEngine.moduleContext.fn to the next *function.
+	c.assembler.CompileRegisterToMemory(amd64.MOVQ, functionAddressRegister,
+		amd64ReservedRegisterForCallEngine, callEngineModuleContextFnOffset)
+
+	// All the registers used are temporary, and the code below doesn't return, so we mark them unused.
+	c.locationStack.markRegisterUnused(callFrameRegisters[:]...)
+	c.locationStack.markRegisterUnused(tmpRegister, functionAddressRegister)
+
+	originalFunctionAddressRegister := functionAddressRegister
+	if amd64CallingConventionDestinationFunctionModuleInstanceAddressRegister == functionAddressRegister {
+		// See the comment on the same case in compileCallFunctionImpl.
+		c.assembler.CompileRegisterToRegister(amd64.MOVQ, functionAddressRegister, tmpRegister)
+		functionAddressRegister = tmpRegister
+	}
+
+	// Also, we have to put the target function's *wasm.ModuleInstance into amd64CallingConventionDestinationFunctionModuleInstanceAddressRegister.
+	c.assembler.CompileMemoryToRegister(amd64.MOVQ, functionAddressRegister, functionModuleInstanceOffset,
+		amd64CallingConventionDestinationFunctionModuleInstanceAddressRegister)
+
+	// And jump into the initial address of the target function, which never returns here.
+	c.assembler.CompileJumpToMemory(amd64.JMP, functionAddressRegister, functionCodeInitialAddressOffset)
+
+	c.assembler.SetJumpTargetOnNext(jmpIfHostFunction)
+	return c.compileCallAndReturn(originalFunctionAddressRegister, functype)
+}
+
+// compileCallAndReturn adds instructions to call the function whose address is in functionAddressRegister, and return
+// its results from the current function. This is used by compileTailCallFunctionImpl when the frame cannot be reused.
+func (c *amd64Compiler) compileCallAndReturn(functionAddressRegister asm.Register, functype *wasm.FunctionType) error {
+	if err := c.compileCallFunctionImpl(functionAddressRegister, functype); err != nil {
+		return err
+	}
+	// Drop everything below the results, as is the case for the return.
+	r := wazeroir.InclusiveRange{Start: int32(functype.ResultNumInUint64), End: int32(c.locationStack.sp) - 1}
+	if err := compileDropRange(c, r.AsU64()); err != nil {
+		return err
+	}
+	return c.compileReturnFunction()
+}
+
 // returnFunction adds instructions to return from the current callframe back to the caller's frame.
 // If this is the current one is the origin, we return to the callEngine.execWasmFunction with the Returned status.
 // Otherwise, we jump into the callers' return address stored in callFrame.returnAddress while setting
@@ -4715,6 +5190,9 @@ func (c *amd64Compiler) compileReturnFunction() error {
 
 	c.compileMaybeExitFromNativeCode(amd64.JNE, nativeCallStatusCodeReturned)
 
+	// Returning to the caller, so decrement callEngine.exitContext.callDepth.
+	c.assembler.CompileNoneToMemory(amd64.DECQ, amd64ReservedRegisterForCallEngine, callEngineExitContextCallDepthOffset)
+
 	// Alias for readability.
 	tmpRegister := amd64CallingConventionDestinationFunctionModuleInstanceAddressRegister
 
@@ -4873,7 +5351,7 @@ func (c *amd64Compiler) compileExitFromNativeCode(status nativeCallStatusCode) {
 		c.assembler.CompileRegisterToMemory(amd64.MOVQ,
 			returnAddressReg, amd64ReservedRegisterForCallEngine, callEngineExitContextReturnAddressOffset)
 	default:
-		if c.ir.IROperationSourceOffsetsInWasmBinary != nil {
+		if c.ir != nil && c.ir.IROperationSourceOffsetsInWasmBinary != nil { // c.ir is nil for host functions.
 			// This case, the execution traps and we want the top frame's source position in the stack trace.
 			// Take RegR15 and store the instruction address onto callEngine.returnAddress.
 			returnAddressReg := amd64.RegR15
@@ -4906,6 +5384,10 @@ func (c *amd64Compiler) compilePreamble() (err error) {
 		return err
 	}
 
+	if err = c.compileIncrementCallDepth(); err != nil {
+		return err
+	}
+
 	// Check if it's necessary to grow the value stack by using max stack pointer.
 	if err = c.compileMaybeGrowStack(); err != nil {
 		return err
@@ -4946,6 +5428,25 @@ func (c *amd64Compiler) compileReservedMemoryPointerInitialization() {
 	}
 }
 
+// compileIncrementCallDepth adds instructions to increment callEngine.exitContext.callDepth on function entry,
+// and exit with nativeCallStatusCodeStackOverflow if it exceeds callEngine.exitContext.maxCallDepth.
+func (c *amd64Compiler) compileIncrementCallDepth() error {
+	tmpRegister, ok := c.locationStack.takeFreeRegister(registerTypeGeneralPurpose)
+	if !ok {
+		panic("BUG: cannot take free register")
+	}
+
+	c.assembler.CompileNoneToMemory(amd64.INCQ, amd64ReservedRegisterForCallEngine, callEngineExitContextCallDepthOffset)
+	c.assembler.CompileMemoryToRegister(amd64.MOVQ,
+		amd64ReservedRegisterForCallEngine, callEngineExitContextCallDepthOffset, tmpRegister)
+
+	// Continue if callDepth <= maxCallDepth.
+	c.assembler.CompileMemoryToRegister(amd64.CMPQ,
+		amd64ReservedRegisterForCallEngine, callEngineExitContextMaxCallDepthOffset, tmpRegister)
+	c.compileMaybeExitFromNativeCode(amd64.JCC, nativeCallStatusCodeStackOverflow)
+	return nil
+}
+
 // compileMaybeGrowStack adds instructions to check the necessity to grow the value stack,
 // and if so, make the builtin function call to do so. These instructions are called in the function's
 // preamble.
@@ -5087,15 +5588,14 @@ func (c *amd64Compiler) compileModuleContextInitialization() error {
 
 	// Update moduleContext.codesElement0Address
 	{
-		// "tmpRegister = [moduleInstanceAddressRegister + moduleInstanceEngineOffset + interfaceDataOffset] (== *moduleEngine)"
+		// "tmpRegister = [callEngine + callEngineModuleContextFnOffset] (== *function)"
+		c.assembler.CompileMemoryToRegister(amd64.MOVQ, amd64ReservedRegisterForCallEngine, callEngineModuleContextFnOffset, tmpRegister)
+
+		// "tmpRegister = [tmpRegister + functionModuleEngineOffset] (== *moduleEngine)"
 		//
-		// Go's interface is laid out on memory as two quad words as struct {tab, data uintptr}
-		// where tab points to the interface table, and the latter points to the actual
-		// implementation of interface. This case, we extract "data" pointer as *moduleEngine.
-		// See the following references for detail:
-		// * https://research.swtch.com/interfaces
-		// * https://github.com/golang/go/blob/release-branch.go1.20/src/runtime/runtime2.go#L207-L210
-		c.assembler.CompileMemoryToRegister(amd64.MOVQ, amd64CallingConventionDestinationFunctionModuleInstanceAddressRegister, moduleInstanceEngineOffset+interfaceDataOffset, tmpRegister)
+		// This isn't read from the module instance, as its engine is another one when functions are promoted to this
+		// engine, see function.moduleEngine.
+		c.assembler.CompileMemoryToRegister(amd64.MOVQ, tmpRegister, functionModuleEngineOffset, tmpRegister)
 
 		// "tmpRegister = [tmpRegister + moduleEnginecodesOffset] (== &moduleEngine.codes[0])"
 		c.assembler.CompileMemoryToRegister(amd64.MOVQ, tmpRegister, moduleEngineFunctionsOffset, tmpRegister)
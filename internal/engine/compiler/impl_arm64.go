@@ -37,6 +37,11 @@ type arm64Compiler struct {
 	// frameIDMax tracks the maximum value of frame id per function.
 	frameIDMax int
 	brTableTmp []runtimeValueLocation
+	// tryStacks holds the location stack at the beginning of each try block, indexed by the exception handler.
+	// nil means that the try block has never been reached.
+	tryStacks []*runtimeValueLocationStack
+	// catchStack is the location stack from which we start compiling catches.
+	catchStack runtimeValueLocationStack
 }
 
 func newArm64Compiler() compiler {
@@ -124,11 +129,11 @@ var arm64CallingConventionModuleInstanceAddressRegister = arm64.RegR29
 
 const (
 	// arm64CallEngineArchContextCompilerCallReturnAddressOffset is the offset of archContext.nativeCallReturnAddress in callEngine.
-	arm64CallEngineArchContextCompilerCallReturnAddressOffset = 144
+	arm64CallEngineArchContextCompilerCallReturnAddressOffset = 184
 	// arm64CallEngineArchContextMinimum32BitSignedIntOffset is the offset of archContext.minimum32BitSignedIntAddress in callEngine.
-	arm64CallEngineArchContextMinimum32BitSignedIntOffset = 152
+	arm64CallEngineArchContextMinimum32BitSignedIntOffset = 192
 	// arm64CallEngineArchContextMinimum64BitSignedIntOffset is the offset of archContext.minimum64BitSignedIntAddress in callEngine.
-	arm64CallEngineArchContextMinimum64BitSignedIntOffset = 160
+	arm64CallEngineArchContextMinimum64BitSignedIntOffset = 200
 )
 
 func isZeroRegister(r asm.Register) bool {
@@ -237,6 +242,10 @@ func (c *arm64Compiler) compilePreamble() error {
 
 	c.locationStack.init(c.typ)
 
+	if err := c.compileIncrementCallDepth(); err != nil {
+		return err
+	}
+
 	// Check if it's necessary to grow the value stack before entering function body.
 	if err := c.compileMaybeGrowStack(); err != nil {
 		return err
@@ -260,6 +269,29 @@ func (c *arm64Compiler) compilePreamble() error {
 	return nil
 }
 
+// compileIncrementCallDepth adds instructions to increment callEngine.exitContext.callDepth on function entry,
+// and exit with nativeCallStatusCodeStackOverflow if it exceeds callEngine.exitContext.maxCallDepth.
+func (c *arm64Compiler) compileIncrementCallDepth() error {
+	tmp, found := c.locationStack.takeFreeRegister(registerTypeGeneralPurpose)
+	if !found {
+		panic("BUG: all the registers should be free at this point")
+	}
+
+	// "callDepth++"
+	c.assembler.CompileMemoryToRegister(arm64.LDRD,
+		arm64ReservedRegisterForCallEngine, callEngineExitContextCallDepthOffset, tmp)
+	c.assembler.CompileConstToRegister(arm64.ADD, 1, tmp)
+	c.assembler.CompileRegisterToMemory(arm64.STRD,
+		tmp, arm64ReservedRegisterForCallEngine, callEngineExitContextCallDepthOffset)
+
+	// "cmp callDepth, maxCallDepth", and continue if callDepth <= maxCallDepth.
+	c.assembler.CompileMemoryToRegister(arm64.LDRD,
+		arm64ReservedRegisterForCallEngine, callEngineExitContextMaxCallDepthOffset, arm64ReservedRegisterForTemporary)
+	c.assembler.CompileTwoRegistersToNone(arm64.CMP, arm64ReservedRegisterForTemporary, tmp)
+	c.compileMaybeExitFromNativeCode(arm64.BCONDLS, nativeCallStatusCodeStackOverflow)
+	return nil
+}
+
 // compileMaybeGrowStack adds instructions to check the necessity to grow the value stack,
 // and if so, make the builtin function call to do so. These instructions are called in the function's
 // preamble.
@@ -355,6 +387,13 @@ func (c *arm64Compiler) compileReturnFunction() error {
 	// If the address doesn't equal zero, return br into returnAddressRegister (caller's return address).
 	c.compileMaybeExitFromNativeCode(arm64.BCONDNE, nativeCallStatusCodeReturned)
 
+	// Returning to the caller, so "callDepth--".
+	c.assembler.CompileMemoryToRegister(arm64.LDRD,
+		arm64ReservedRegisterForCallEngine, callEngineExitContextCallDepthOffset, arm64ReservedRegisterForTemporary)
+	c.assembler.CompileConstToRegister(arm64.SUB, 1, arm64ReservedRegisterForTemporary)
+	c.assembler.CompileRegisterToMemory(arm64.STRD,
+		arm64ReservedRegisterForTemporary, arm64ReservedRegisterForCallEngine, callEngineExitContextCallDepthOffset)
+
 	// Alias for readability.
 	tmp := arm64CallingConventionModuleInstanceAddressRegister
 
@@ -404,7 +443,7 @@ func (c *arm64Compiler) compileExitFromNativeCode(status nativeCallStatusCode) {
 			arm64ReservedRegisterForCallEngine, callEngineExitContextReturnAddressOffset,
 		)
 	default:
-		if c.ir.IROperationSourceOffsetsInWasmBinary != nil {
+		if c.ir != nil && c.ir.IROperationSourceOffsetsInWasmBinary != nil { // c.ir is nil for host functions.
 			// This case, the execution traps, and we want the top frame's source position in the stack trace.
 			// We store the instruction address onto callEngine.returnAddress.
 			c.assembler.CompileReadInstructionAddress(arm64ReservedRegisterForTemporary, arm64.STRD)
@@ -449,6 +488,10 @@ func (c *arm64Compiler) compileGoDefinedHostFunction() error {
 	// First we must update the location stack to reflect the number of host function inputs.
 	c.locationStack.init(c.typ)
 
+	if err := c.compileIncrementCallDepth(); err != nil {
+		return err
+	}
+
 	if c.withListener {
 		if err := c.compileCallGoFunction(nativeCallStatusCodeCallBuiltInFunction,
 			builtinFunctionIndexFunctionListenerBefore); err != nil {
@@ -516,6 +559,215 @@ func (c *arm64Compiler) compileBuiltinFunctionCheckExitCode() error {
 	return nil
 }
 
+// compileConsumeFuel implements compiler.compileConsumeFuel for the arm64 architecture.
+func (c *arm64Compiler) compileConsumeFuel(o *wazeroir.UnionOperation) error {
+	// SUBS below clobbers the flags, so materialize any conditional value first.
+	if err := c.maybeCompileMoveTopConditionalToGeneralPurposeRegister(); err != nil {
+		return err
+	}
+
+	fuel, err := c.allocateRegister(registerTypeGeneralPurpose)
+	if err != nil {
+		return err
+	}
+
+	c.assembler.CompileMemoryToRegister(arm64.LDRD,
+		arm64ReservedRegisterForCallEngine, callEngineExitContextFuelOffset, fuel)
+	c.assembler.CompileConstToRegister(arm64.SUBS, int64(o.U1), fuel)
+
+	// If the remaining fuel is negative, exit without storing it, so the fuel is unchanged.
+	c.compileMaybeExitFromNativeCode(arm64.BCONDPL, nativeCallStatusCodeOutOfFuel)
+
+	c.assembler.CompileRegisterToMemory(arm64.STRD,
+		fuel, arm64ReservedRegisterForCallEngine, callEngineExitContextFuelOffset)
+	return nil
+}
+
+// compileAtomic implements compiler.compileAtomic for the arm64 architecture.
+func (c *arm64Compiler) compileAtomic(o *wazeroir.UnionOperation) error {
+	if err := c.maybeCompileMoveTopConditionalToGeneralPurposeRegister(); err != nil {
+		return err
+	}
+
+	operands, result, hasResult := atomicOperationStackEffect(o)
+	if o.B3 {
+		// The offset on 64-bit memories doesn't fit in the descriptor, so it's pushed separately. See encodeAtomicOperation.
+		if err := c.compileIntConstant(false, o.U2); err != nil {
+			return err
+		}
+		operands++
+	}
+
+	// Pushes the descriptor of the operation, which is decoded by the builtin function.
+	if err := c.compileIntConstant(false, encodeAtomicOperation(o)); err != nil {
+		return err
+	}
+
+	if err := c.compileCallGoFunction(nativeCallStatusCodeCallBuiltInFunction, builtinFunctionIndexAtomic); err != nil {
+		return err
+	}
+
+	// The builtin function consumes the descriptor and the operands.
+	for i := 0; i < operands+1; i++ {
+		c.locationStack.pop()
+	}
+	if hasResult {
+		loc := c.locationStack.pushRuntimeValueLocationOnStack()
+		loc.valueType = result
+	}
+
+	// After return, we re-initialize reserved registers just like preamble of functions.
+	c.compileReservedStackBasePointerRegisterInitialization()
+	c.compileReservedMemoryRegisterInitialization()
+	return nil
+}
+
+// compileSelectMemory implements compiler.compileSelectMemory for the arm64 architecture.
+func (c *arm64Compiler) compileSelectMemory(o *wazeroir.UnionOperation) error {
+	memoryIndex := int64(o.U1)
+
+	tmp, err := c.allocateRegister(registerTypeGeneralPurpose)
+	if err != nil {
+		return err
+	}
+
+	// "tmp = &moduleInstance.Memories[0]"
+	c.assembler.CompileMemoryToRegister(arm64.LDRD,
+		arm64ReservedRegisterForCallEngine, callEngineModuleContextModuleInstanceOffset, tmp)
+	c.assembler.CompileMemoryToRegister(arm64.LDRD, tmp, moduleInstanceMemoriesOffset, tmp)
+	// "tmp = moduleInstance.Memories[memoryIndex]"
+	c.assembler.CompileMemoryToRegister(arm64.LDRD, tmp, memoryIndex*8, tmp)
+
+	// Update ce.moduleContext's memory fields the same way as compileModuleContextInitialization does.
+	c.assembler.CompileRegisterToMemory(arm64.STRD,
+		tmp, arm64ReservedRegisterForCallEngine, callEngineModuleContextMemoryInstanceOffset)
+	c.assembler.CompileMemoryToRegister(arm64.LDRD,
+		tmp, memoryInstanceBufferLenOffset, arm64ReservedRegisterForTemporary)
+	c.assembler.CompileRegisterToMemory(arm64.STRD,
+		arm64ReservedRegisterForTemporary, arm64ReservedRegisterForCallEngine, callEngineModuleContextMemorySliceLenOffset)
+	c.assembler.CompileMemoryToRegister(arm64.LDRD,
+		tmp, memoryInstanceBufferOffset, arm64ReservedRegisterForTemporary)
+	c.assembler.CompileRegisterToMemory(arm64.STRD,
+		arm64ReservedRegisterForTemporary, arm64ReservedRegisterForCallEngine, callEngineModuleContextMemoryElement0AddressOffset)
+
+	c.compileReservedMemoryRegisterInitialization()
+	return nil
+}
+
+// compileTry implements compiler.compileTry for the arm64 architecture.
+func (c *arm64Compiler) compileTry(o *wazeroir.UnionOperation) error {
+	if c.tryStacks == nil {
+		c.tryStacks = make([]*runtimeValueLocationStack, len(c.ir.ExceptionHandlers))
+	}
+	stack := &runtimeValueLocationStack{}
+	stack.cloneFrom(*c.locationStack)
+	// The values above the height of the handler are not visible from the catches.
+	stack.sp = uint64(c.ir.ExceptionHandlers[o.U1].StackHeight)
+	c.tryStacks[o.U1] = stack
+	return nil
+}
+
+// compileCatch implements compiler.compileCatch for the arm64 architecture.
+func (c *arm64Compiler) compileCatch(o *wazeroir.UnionOperation) (skipThisCatch bool, err error) {
+	var tryStack *runtimeValueLocationStack
+	if c.tryStacks != nil {
+		tryStack = c.tryStacks[o.U1]
+	}
+	if tryStack == nil {
+		skipThisCatch = true
+		return
+	}
+
+	// The catch is entered from Go just like the beginning of a function, with all the values on the memory stack.
+	c.setLocationStack(&c.catchStack)
+	c.catchStack.cloneOnStackFrom(*tryStack)
+	if err = c.compileModuleContextInitialization(); err != nil {
+		return
+	}
+	c.compileReservedStackBasePointerRegisterInitialization()
+	c.compileReservedMemoryRegisterInitialization()
+
+	// The values of the caught exception, along with its reference documented on wazeroir.ExceptionRef.
+	ref := wazeroir.ExceptionRef(o.B2)
+	if ref == wazeroir.ExceptionRefHandle {
+		handle := c.locationStack.pushRuntimeValueLocationOnStack()
+		handle.valueType = runtimeValueTypeI64
+	}
+	if o.B1 != 1 {
+		c.locationStack.pushRuntimeValueLocationsOnStack(c.ir.Types[c.ir.Tags[o.U2]].Params)
+	}
+	if ref == wazeroir.ExceptionRefExnref {
+		exnref := c.locationStack.pushRuntimeValueLocationOnStack()
+		exnref.valueType = runtimeValueTypeI64
+	}
+	return
+}
+
+// compileThrow implements compiler.compileThrow for the arm64 architecture.
+func (c *arm64Compiler) compileThrow(o *wazeroir.UnionOperation) error {
+	if err := c.maybeCompileMoveTopConditionalToGeneralPurposeRegister(); err != nil {
+		return err
+	}
+	// Pass the tag index to the builtin function on top of the values of the exception.
+	if err := c.compileIntConstant(true, o.U1); err != nil {
+		return err
+	}
+	if err := c.compileCallGoFunction(nativeCallStatusCodeCallBuiltInFunction, builtinFunctionIndexThrow); err != nil {
+		return err
+	}
+	// The builtin function never returns, but the address after the call is needed to exit from the native code.
+	return c.compileUnreachable()
+}
+
+// compileRethrow implements compiler.compileRethrow for the arm64 architecture.
+func (c *arm64Compiler) compileRethrow(o *wazeroir.UnionOperation) error {
+	// Copy the handle of the exception to the top of the stack, and pass it to the builtin function.
+	pick := wazeroir.NewOperationPick(int(o.U1), false)
+	if err := c.compilePick(&pick); err != nil {
+		return err
+	}
+	if err := c.compileCallGoFunction(nativeCallStatusCodeCallBuiltInFunction, builtinFunctionIndexRethrow); err != nil {
+		return err
+	}
+	// The builtin function never returns, like the one for throw.
+	return c.compileUnreachable()
+}
+
+// compileThrowRef implements compiler.compileThrowRef for the arm64 architecture.
+func (c *arm64Compiler) compileThrowRef() error {
+	// The reference to the exception is already on the top of the stack, which is passed to the builtin function.
+	if err := c.compileCallGoFunction(nativeCallStatusCodeCallBuiltInFunction, builtinFunctionIndexThrowRef); err != nil {
+		return err
+	}
+	// The builtin function never returns, like the one for throw.
+	return c.compileUnreachable()
+}
+
+// compileCheckEpoch implements compiler.compileCheckEpoch for the arm64 architecture.
+func (c *arm64Compiler) compileCheckEpoch() error {
+	// CMP below clobbers the flags, so materialize any conditional value first.
+	if err := c.maybeCompileMoveTopConditionalToGeneralPurposeRegister(); err != nil {
+		return err
+	}
+
+	epoch, err := c.allocateRegister(registerTypeGeneralPurpose)
+	if err != nil {
+		return err
+	}
+
+	// epoch = *callEngine.exitContext.epoch
+	c.assembler.CompileMemoryToRegister(arm64.LDRD,
+		arm64ReservedRegisterForCallEngine, callEngineExitContextEpochOffset, epoch)
+	c.assembler.CompileMemoryToRegister(arm64.LDRD, epoch, 0, epoch)
+	c.assembler.CompileMemoryToRegister(arm64.LDRD,
+		arm64ReservedRegisterForCallEngine, callEngineExitContextEpochDeadlineOffset, arm64ReservedRegisterForTemporary)
+
+	// "cmp epoch, deadline", and continue if epoch < deadline.
+	c.assembler.CompileTwoRegistersToNone(arm64.CMP, arm64ReservedRegisterForTemporary, epoch)
+	c.compileMaybeExitFromNativeCode(arm64.BCONDLO, nativeCallStatusCodeEpochInterrupted)
+	return nil
+}
+
 // compileLabel implements compiler.compileLabel for the arm64 architecture.
 func (c *arm64Compiler) compileLabel(o *wazeroir.UnionOperation) (skipThisLabel bool) {
 	labelKey := wazeroir.Label(o.U1)
@@ -612,7 +864,7 @@ func (c *arm64Compiler) compileGlobalGet(o *wazeroir.UnionOperation) error {
 			ldr = arm64.LDRW
 			vt = runtimeValueTypeI32
 			result = globalAddressReg
-		case wasm.ValueTypeI64, wasm.ValueTypeExternref, wasm.ValueTypeFuncref:
+		case wasm.ValueTypeI64, wasm.ValueTypeExternref, wasm.ValueTypeFuncref, wasm.ValueTypeExnref:
 			ldr = arm64.LDRD
 			vt = runtimeValueTypeI64
 			result = globalAddressReg
@@ -675,7 +927,7 @@ func (c *arm64Compiler) compileGlobalSet(o *wazeroir.UnionOperation) error {
 		switch c.ir.Globals[index].ValType {
 		case wasm.ValueTypeI32:
 			str = arm64.STRW
-		case wasm.ValueTypeI64, wasm.ValueTypeExternref, wasm.ValueTypeFuncref:
+		case wasm.ValueTypeI64, wasm.ValueTypeExternref, wasm.ValueTypeFuncref, wasm.ValueTypeExnref:
 			str = arm64.STRD
 		case wasm.ValueTypeF32:
 			str = arm64.FSTRS
@@ -986,6 +1238,16 @@ func (c *arm64Compiler) getSavedTemporaryLocationStack() runtimeValueLocationSta
 
 // compileCall implements compiler.compileCall for the arm64 architecture.
 func (c *arm64Compiler) compileCall(o *wazeroir.UnionOperation) error {
+	return c.compileCallDirectImpl(o, false)
+}
+
+// compileTailCall implements compiler.compileTailCall for the arm64 architecture.
+func (c *arm64Compiler) compileTailCall(o *wazeroir.UnionOperation) error {
+	return c.compileCallDirectImpl(o, true)
+}
+
+// compileCallDirectImpl implements compiler.compileCall and compiler.compileTailCall for the arm64 architecture.
+func (c *arm64Compiler) compileCallDirectImpl(o *wazeroir.UnionOperation, tail bool) error {
 	if err := c.maybeCompileMoveTopConditionalToGeneralPurposeRegister(); err != nil {
 		return err
 	}
@@ -1014,6 +1276,9 @@ func (c *arm64Compiler) compileCall(o *wazeroir.UnionOperation) error {
 		int64(functionIndex)*functionSize, // * 8 because the size of *function equals 8 bytes.
 		targetFunctionAddressReg)
 
+	if tail {
+		return c.compileTailCallImpl(targetFunctionAddressReg, tp)
+	}
 	return c.compileCallImpl(targetFunctionAddressReg, tp)
 }
 
@@ -1105,7 +1370,7 @@ func (c *arm64Compiler) compileCallImpl(targetFunctionAddressRegister asm.Regist
 		switch t {
 		case wasm.ValueTypeI32:
 			loc.valueType = runtimeValueTypeI32
-		case wasm.ValueTypeI64, wasm.ValueTypeFuncref, wasm.ValueTypeExternref:
+		case wasm.ValueTypeI64, wasm.ValueTypeFuncref, wasm.ValueTypeExternref, wasm.ValueTypeExnref:
 			loc.valueType = runtimeValueTypeI64
 		case wasm.ValueTypeF32:
 			loc.valueType = runtimeValueTypeF32
@@ -1129,8 +1394,145 @@ func (c *arm64Compiler) compileCallImpl(targetFunctionAddressRegister asm.Regist
 	return nil
 }
 
+// compileTailCallImpl implements compiler.compileTailCall and compiler.compileTailCallIndirect for the arm64
+// architecture. This replaces the current function's frame with the one of the function whose address is in
+// targetFunctionAddressRegister, and branches into it. The callee returns directly to the caller of the current
+// function, so tail calls grow neither the stack nor callEngine.exitContext.callDepth.
+//
+// The arguments on top of the stack are moved to the beginning of the current frame, and the call frame of the current
+// function is moved to the location for the callee's type:
+//
+//	              reserved slots for results (if len(results) > len(args))
+//	                     |     |
+//	   ,arg0, ..., argN, ..., _, .returnAddress, .returnStackBasePointerInBytes, .function, ....
+//	     |
+//	callEngine.stackContext.stackBasePointer (unchanged)
+//
+// Note: when the function listener is enabled, or the callee is a host function, this makes a call followed by a
+// return instead. The former is so that the listener receives the results of the current function, and the latter
+// because host functions read the module of their caller from the call frame.
+func (c *arm64Compiler) compileTailCallImpl(targetFunctionAddressRegister asm.Register, functype *wasm.FunctionType) error {
+	if c.withListener {
+		return c.compileCallAndReturn(targetFunctionAddressRegister, functype)
+	}
+
+	// Release all the registers as the arguments are moved on the stack memory.
+	if err := c.compileReleaseAllRegistersToStack(); err != nil {
+		return err
+	}
+
+	c.markRegisterUsed(targetFunctionAddressRegister)
+
+	// Check if the callee is a host function, i.e. function.parent.goFunc != nil.
+	c.assembler.CompileMemoryToRegister(arm64.LDRD,
+		targetFunctionAddressRegister, functionParentOffset,
+		arm64ReservedRegisterForTemporary)
+	c.assembler.CompileMemoryToRegister(arm64.LDRD,
+		arm64ReservedRegisterForTemporary, compiledFunctionGoFuncOffset,
+		arm64ReservedRegisterForTemporary)
+	c.assembler.CompileTwoRegistersToNone(arm64.CMP, arm64.RegRZR, arm64ReservedRegisterForTemporary)
+	brIfHostFunction := c.assembler.CompileJump(arm64.BCONDNE)
+
+	// First, load the call frame of the current function, as moving the arguments might overwrite it.
+	returnAddress, _, _ := c.locationStack.getCallFrameLocations(c.typ)
+	var callFrameRegisters [3]asm.Register
+	for i := range callFrameRegisters {
+		reg, ok := c.locationStack.takeFreeRegister(registerTypeGeneralPurpose)
+		if !ok {
+			panic("BUG: cannot take a free register")
+		}
+		c.markRegisterUsed(reg)
+		c.assembler.CompileMemoryToRegister(arm64.LDRD,
+			arm64ReservedRegisterForStackBasePointerAddress, int64(returnAddress.stackPointer+uint64(i))*8,
+			reg)
+		callFrameRegisters[i] = reg
+	}
+
+	// Next, move the arguments to the beginning of the frame. As the arguments are above the call frame, the
+	// destination is always below the source, so copying them in order is safe.
+	argsOffset := int64(c.locationStack.sp) - int64(functype.ParamNumInUint64)
+	for i := int64(0); i < int64(functype.ParamNumInUint64); i++ {
+		c.assembler.CompileMemoryToRegister(arm64.LDRD,
+			arm64ReservedRegisterForStackBasePointerAddress, (argsOffset+i)*8,
+			arm64ReservedRegisterForTemporary)
+		c.assembler.CompileRegisterToMemory(arm64.STRD, arm64ReservedRegisterForTemporary,
+			arm64ReservedRegisterForStackBasePointerAddress, i*8)
+	}
+
+	// Then, write the call frame at the location for the callee's type.
+	offset := int64(callFrameOffset(functype))
+	for i, reg := range callFrameRegisters {
+		c.assembler.CompileRegisterToMemory(arm64.STRD, reg,
+			arm64ReservedRegisterForStackBasePointerAddress, (offset+int64(i))*8)
+	}
+
+	// The callee increments callEngine.exitContext.callDepth in its preamble, so "callDepth--" for the current function.
+	c.assembler.CompileMemoryToRegister(arm64.LDRD,
+		arm64ReservedRegisterForCallEngine, callEngineExitContextCallDepthOffset, arm64ReservedRegisterForTemporary)
+	c.assembler.CompileConstToRegister(arm64.SUB, 1, arm64ReservedRegisterForTemporary)
+	c.assembler.CompileRegisterToMemory(arm64.STRD,
+		arm64ReservedRegisterForTemporary, arm64ReservedRegisterForCallEngine, callEngineExitContextCallDepthOffset)
+
+	// Set callEngine.moduleContext.fn to the next *function.
+	c.assembler.CompileRegisterToMemory(arm64.STRD,
+		targetFunctionAddressRegister,
+		arm64ReservedRegisterForCallEngine, callEngineModuleContextFnOffset)
+
+	// All the registers used are temporary, and the code below doesn't return, so we mark them unused.
+	c.markRegisterUnused(callFrameRegisters[:]...)
+	c.markRegisterUnused(targetFunctionAddressRegister)
+
+	originalTargetFunctionAddressRegister := targetFunctionAddressRegister
+	if targetFunctionAddressRegister == arm64CallingConventionModuleInstanceAddressRegister {
+		// See the comment on the same case in compileCallImpl.
+		c.assembler.CompileRegisterToRegister(arm64.MOVD, targetFunctionAddressRegister, arm64ReservedRegisterForTemporary)
+		targetFunctionAddressRegister = arm64ReservedRegisterForTemporary
+	}
+
+	// Also, we have to put the code's moduleInstance address into arm64CallingConventionModuleInstanceAddressRegister.
+	c.assembler.CompileMemoryToRegister(arm64.LDRD,
+		targetFunctionAddressRegister, functionModuleInstanceOffset,
+		arm64CallingConventionModuleInstanceAddressRegister,
+	)
+
+	// Then, br into the target function's initial address, which never returns here.
+	c.assembler.CompileMemoryToRegister(arm64.LDRD,
+		targetFunctionAddressRegister, functionCodeInitialAddressOffset,
+		targetFunctionAddressRegister)
+
+	c.assembler.CompileJumpToRegister(arm64.B, targetFunctionAddressRegister)
+
+	c.assembler.SetJumpTargetOnNext(brIfHostFunction)
+	return c.compileCallAndReturn(originalTargetFunctionAddressRegister, functype)
+}
+
+// compileCallAndReturn adds instructions to call the function whose address is in targetFunctionAddressRegister, and
+// return its results from the current function. This is used by compileTailCallImpl when the frame cannot be reused.
+func (c *arm64Compiler) compileCallAndReturn(targetFunctionAddressRegister asm.Register, functype *wasm.FunctionType) error {
+	if err := c.compileCallImpl(targetFunctionAddressRegister, functype); err != nil {
+		return err
+	}
+	// Drop everything below the results, as is the case for the return.
+	r := wazeroir.InclusiveRange{Start: int32(functype.ResultNumInUint64), End: int32(c.locationStack.sp) - 1}
+	if err := compileDropRange(c, r.AsU64()); err != nil {
+		return err
+	}
+	return c.compileReturnFunction()
+}
+
 // compileCallIndirect implements compiler.compileCallIndirect for the arm64 architecture.
 func (c *arm64Compiler) compileCallIndirect(o *wazeroir.UnionOperation) (err error) {
+	return c.compileCallIndirectImpl(o, false)
+}
+
+// compileTailCallIndirect implements compiler.compileTailCallIndirect for the arm64 architecture.
+func (c *arm64Compiler) compileTailCallIndirect(o *wazeroir.UnionOperation) (err error) {
+	return c.compileCallIndirectImpl(o, true)
+}
+
+// compileCallIndirectImpl implements compiler.compileCallIndirect and compiler.compileTailCallIndirect for the arm64
+// architecture.
+func (c *arm64Compiler) compileCallIndirectImpl(o *wazeroir.UnionOperation, tail bool) (err error) {
 	offset := c.locationStack.pop()
 	if err = c.compileEnsureOnRegister(offset); err != nil {
 		return err
@@ -1228,7 +1630,12 @@ func (c *arm64Compiler) compileCallIndirect(o *wazeroir.UnionOperation) (err err
 	c.compileMaybeExitFromNativeCode(arm64.BCONDEQ, nativeCallStatusCodeTypeMismatchOnIndirectCall)
 
 	targetFunctionType := &c.ir.Types[typeIndex]
-	if err := c.compileCallImpl(offsetReg, targetFunctionType); err != nil {
+	if tail {
+		err = c.compileTailCallImpl(offsetReg, targetFunctionType)
+	} else {
+		err = c.compileCallImpl(offsetReg, targetFunctionType)
+	}
+	if err != nil {
 		return err
 	}
 
@@ -1237,6 +1644,71 @@ func (c *arm64Compiler) compileCallIndirect(o *wazeroir.UnionOperation) (err err
 	return nil
 }
 
+// compileCallRef implements compiler.compileCallRef for the arm64 architecture.
+func (c *arm64Compiler) compileCallRef(o *wazeroir.UnionOperation) (err error) {
+	ref := c.locationStack.pop()
+	if err = c.compileEnsureOnRegister(ref); err != nil {
+		return err
+	}
+	typeIndex := o.U1
+
+	refReg := ref.register
+	if isZeroRegister(refReg) {
+		refReg, err = c.allocateRegister(registerTypeGeneralPurpose)
+		if err != nil {
+			return err
+		}
+		c.markRegisterUsed(refReg)
+
+		// Zero the value on a picked register.
+		c.assembler.CompileRegisterToRegister(arm64.MOVD, arm64.RegRZR, refReg)
+	}
+
+	tmp, err := c.allocateRegister(registerTypeGeneralPurpose)
+	if err != nil {
+		return err
+	}
+	c.markRegisterUsed(tmp)
+
+	tmp2, err := c.allocateRegister(registerTypeGeneralPurpose)
+	if err != nil {
+		return err
+	}
+	c.markRegisterUsed(tmp2)
+
+	// refReg holds the address of *function, so check if it is null.
+	c.assembler.CompileTwoRegistersToNone(arm64.CMP, arm64.RegRZR, refReg)
+
+	// Skipped if the reference is not null.
+	c.compileMaybeExitFromNativeCode(arm64.BCONDNE, nativeCallStatusCodeNullFunctionReference)
+
+	// Next, we check the type matches as compileCallIndirectImpl.
+	// "tmp = ref.typeID"
+	c.assembler.CompileMemoryToRegister(
+		arm64.LDRD,
+		refReg, functionTypeIDOffset,
+		tmp,
+	)
+	// "tmp2 = ModuleInstance.TypeIDs[index]"
+	c.assembler.CompileMemoryToRegister(arm64.LDRD,
+		arm64ReservedRegisterForCallEngine, callEngineModuleContextTypeIDsElement0AddressOffset,
+		tmp2)
+	c.assembler.CompileMemoryToRegister(arm64.LDRW, tmp2, int64(typeIndex)*4, tmp2)
+
+	// Compare these two values, and if they equal, we are ready to make function call.
+	c.assembler.CompileTwoRegistersToNone(arm64.CMPW, tmp, tmp2)
+	// Skipped if the type matches.
+	c.compileMaybeExitFromNativeCode(arm64.BCONDEQ, nativeCallStatusCodeTypeMismatchOnIndirectCall)
+
+	if err = c.compileCallImpl(refReg, &c.ir.Types[typeIndex]); err != nil {
+		return err
+	}
+
+	// The ref register should be marked as un-used as we consumed in the function call.
+	c.markRegisterUnused(refReg, tmp, tmp2)
+	return nil
+}
+
 // compileDrop implements compiler.compileDrop for the arm64 architecture.
 func (c *arm64Compiler) compileDrop(o *wazeroir.UnionOperation) error {
 	return compileDropRange(c, o.U1)
@@ -2640,7 +3112,6 @@ func (c *arm64Compiler) compileLoad(o *wazeroir.UnionOperation) error {
 	)
 
 	unsignedType := wazeroir.UnsignedType(o.B1)
-	offset := uint32(o.U2)
 
 	switch unsignedType {
 	case wazeroir.UnsignedTypeI32:
@@ -2662,7 +3133,7 @@ func (c *arm64Compiler) compileLoad(o *wazeroir.UnionOperation) error {
 		targetSizeInBytes = 64 / 8
 		vt = runtimeValueTypeF64
 	}
-	return c.compileLoadImpl(offset, loadInst, targetSizeInBytes, isFloat, vt)
+	return c.compileLoadImpl(o, loadInst, targetSizeInBytes, isFloat, vt)
 }
 
 // compileLoad8 implements compiler.compileLoad8 for the arm64 architecture.
@@ -2671,7 +3142,6 @@ func (c *arm64Compiler) compileLoad8(o *wazeroir.UnionOperation) error {
 	var vt runtimeValueType
 
 	signedInt := wazeroir.SignedInt(o.B1)
-	offset := uint32(o.U2)
 
 	switch signedInt {
 	case wazeroir.SignedInt32:
@@ -2687,7 +3157,7 @@ func (c *arm64Compiler) compileLoad8(o *wazeroir.UnionOperation) error {
 		loadInst = arm64.LDRB
 		vt = runtimeValueTypeI64
 	}
-	return c.compileLoadImpl(offset, loadInst, 1, false, vt)
+	return c.compileLoadImpl(o, loadInst, 1, false, vt)
 }
 
 // compileLoad16 implements compiler.compileLoad16 for the arm64 architecture.
@@ -2696,7 +3166,6 @@ func (c *arm64Compiler) compileLoad16(o *wazeroir.UnionOperation) error {
 	var vt runtimeValueType
 
 	signedInt := wazeroir.SignedInt(o.B1)
-	offset := uint32(o.U2)
 
 	switch signedInt {
 	case wazeroir.SignedInt32:
@@ -2712,28 +3181,27 @@ func (c *arm64Compiler) compileLoad16(o *wazeroir.UnionOperation) error {
 		loadInst = arm64.LDRH
 		vt = runtimeValueTypeI64
 	}
-	return c.compileLoadImpl(offset, loadInst, 16/8, false, vt)
+	return c.compileLoadImpl(o, loadInst, 16/8, false, vt)
 }
 
 // compileLoad32 implements compiler.compileLoad32 for the arm64 architecture.
 func (c *arm64Compiler) compileLoad32(o *wazeroir.UnionOperation) error {
 	var loadInst asm.Instruction
 	signed := o.B1 == 1
-	offset := uint32(o.U2)
 
 	if signed {
 		loadInst = arm64.LDRSW
 	} else {
 		loadInst = arm64.LDRW
 	}
-	return c.compileLoadImpl(offset, loadInst, 32/8, false, runtimeValueTypeI64)
+	return c.compileLoadImpl(o, loadInst, 32/8, false, runtimeValueTypeI64)
 }
 
 // compileLoadImpl implements compileLoadImpl* variants for arm64 architecture.
-func (c *arm64Compiler) compileLoadImpl(offsetArg uint32, loadInst asm.Instruction,
+func (c *arm64Compiler) compileLoadImpl(o *wazeroir.UnionOperation, loadInst asm.Instruction,
 	targetSizeInBytes int64, isFloat bool, resultRuntimeValueType runtimeValueType,
 ) error {
-	offsetReg, err := c.compileMemoryAccessOffsetSetup(offsetArg, targetSizeInBytes)
+	offsetReg, err := c.compileMemoryAccessOffsetSetup(o, targetSizeInBytes)
 	if err != nil {
 		return err
 	}
@@ -2763,7 +3231,6 @@ func (c *arm64Compiler) compileStore(o *wazeroir.UnionOperation) error {
 	var movInst asm.Instruction
 	var targetSizeInBytes int64
 	unsignedType := wazeroir.UnsignedType(o.B1)
-	offset := uint32(o.U2)
 	switch unsignedType {
 	case wazeroir.UnsignedTypeI32:
 		movInst = arm64.STRW
@@ -2778,26 +3245,26 @@ func (c *arm64Compiler) compileStore(o *wazeroir.UnionOperation) error {
 		movInst = arm64.FSTRD
 		targetSizeInBytes = 64 / 8
 	}
-	return c.compileStoreImpl(offset, movInst, targetSizeInBytes)
+	return c.compileStoreImpl(o, movInst, targetSizeInBytes)
 }
 
 // compileStore8 implements compiler.compileStore8 for the arm64 architecture.
 func (c *arm64Compiler) compileStore8(o *wazeroir.UnionOperation) error {
-	return c.compileStoreImpl(uint32(o.U2), arm64.STRB, 1)
+	return c.compileStoreImpl(o, arm64.STRB, 1)
 }
 
 // compileStore16 implements compiler.compileStore16 for the arm64 architecture.
 func (c *arm64Compiler) compileStore16(o *wazeroir.UnionOperation) error {
-	return c.compileStoreImpl(uint32(o.U2), arm64.STRH, 16/8)
+	return c.compileStoreImpl(o, arm64.STRH, 16/8)
 }
 
 // compileStore32 implements compiler.compileStore32 for the arm64 architecture.
 func (c *arm64Compiler) compileStore32(o *wazeroir.UnionOperation) error {
-	return c.compileStoreImpl(uint32(o.U2), arm64.STRW, 32/8)
+	return c.compileStoreImpl(o, arm64.STRW, 32/8)
 }
 
 // compileStoreImpl implements compleStore* variants for arm64 architecture.
-func (c *arm64Compiler) compileStoreImpl(offsetArg uint32, storeInst asm.Instruction, targetSizeInBytes int64) error {
+func (c *arm64Compiler) compileStoreImpl(o *wazeroir.UnionOperation, storeInst asm.Instruction, targetSizeInBytes int64) error {
 	val, err := c.popValueOnRegister()
 	if err != nil {
 		return err
@@ -2805,7 +3272,7 @@ func (c *arm64Compiler) compileStoreImpl(offsetArg uint32, storeInst asm.Instruc
 	// Mark temporarily used as compileMemoryAccessOffsetSetup might try allocating register.
 	c.markRegisterUsed(val.register)
 
-	offsetReg, err := c.compileMemoryAccessOffsetSetup(offsetArg, targetSizeInBytes)
+	offsetReg, err := c.compileMemoryAccessOffsetSetup(o, targetSizeInBytes)
 	if err != nil {
 		return err
 	}
@@ -2823,11 +3290,11 @@ func (c *arm64Compiler) compileStoreImpl(offsetArg uint32, storeInst asm.Instruc
 
 // compileMemoryAccessOffsetSetup pops the top value from the stack (called "base"), stores "base + offsetArg"
 // into a register, and returns the stored register. We call the result "offset" because we access the memory
-// as memory.Buffer[offset: offset+targetSizeInBytes].
+// as memory.Buffer[offset: offset+targetSizeInBytes]. The offsetArg is the static offset of the memory access operation o.
 //
 // Note: this also emits the instructions to check the out of bounds memory access.
 // In other words, if the offset+targetSizeInBytes exceeds the memory size, the code exits with nativeCallStatusCodeMemoryOutOfBounds status.
-func (c *arm64Compiler) compileMemoryAccessOffsetSetup(offsetArg uint32, targetSizeInBytes int64) (offsetRegister asm.Register, err error) {
+func (c *arm64Compiler) compileMemoryAccessOffsetSetup(o *wazeroir.UnionOperation, targetSizeInBytes int64) (offsetRegister asm.Register, err error) {
 	base, err := c.popValueOnRegister()
 	if err != nil {
 		return 0, err
@@ -2842,13 +3309,22 @@ func (c *arm64Compiler) compileMemoryAccessOffsetSetup(offsetArg uint32, targetS
 		c.assembler.CompileRegisterToRegister(arm64.MOVD, arm64.RegRZR, offsetRegister)
 	}
 
-	if offsetConst := int64(offsetArg) + targetSizeInBytes; offsetConst <= math.MaxUint32 {
-		// "offsetRegister = base + offsetArg + targetSizeInBytes"
-		c.assembler.CompileConstToRegister(arm64.ADD, offsetConst, offsetRegister)
-	} else {
+	offsetArg, memory64 := o.U2, o.B3
+	if offsetConst := offsetArg + uint64(targetSizeInBytes); offsetConst < offsetArg || offsetConst > math.MaxInt64 ||
+		(!memory64 && offsetConst > math.MaxUint32) {
 		// If the offset const is too large, we exit with nativeCallStatusCodeMemoryOutOfBounds.
+		// Note that even 64-bit memories are far smaller than 2^63 bytes, so any offset above can never be in bounds.
 		c.compileExitFromNativeCode(nativeCallStatusCodeMemoryOutOfBounds)
 		return
+	} else if memory64 {
+		// "offsetRegister = base + offsetArg + targetSizeInBytes", setting the carry flag on overflow as the base of
+		// 64-bit memories is a full 64-bit value.
+		c.assembler.CompileConstToRegister(arm64.ADDS, int64(offsetConst), offsetRegister)
+		// If the addition above carries, we exit the function with nativeCallStatusCodeMemoryOutOfBounds.
+		c.compileMaybeExitFromNativeCode(arm64.BCONDLO, nativeCallStatusCodeMemoryOutOfBounds)
+	} else {
+		// "offsetRegister = base + offsetArg + targetSizeInBytes"
+		c.assembler.CompileConstToRegister(arm64.ADD, int64(offsetConst), offsetRegister)
 	}
 
 	// "arm64ReservedRegisterForTemporary = len(memory.Buffer)"
@@ -2885,7 +3361,7 @@ func (c *arm64Compiler) compileMemoryGrow() error {
 }
 
 // compileMemorySize implements compileMemorySize variants for arm64 architecture.
-func (c *arm64Compiler) compileMemorySize() error {
+func (c *arm64Compiler) compileMemorySize(o *wazeroir.UnionOperation) error {
 	if err := c.maybeCompileMoveTopConditionalToGeneralPurposeRegister(); err != nil {
 		return err
 	}
@@ -2910,7 +3386,11 @@ func (c *arm64Compiler) compileMemorySize() error {
 		reg,
 	)
 
-	c.pushRuntimeValueLocationOnRegister(reg, runtimeValueTypeI32)
+	vt := runtimeValueTypeI32
+	if o.B3 { // 64-bit memories return the page size as i64.
+		vt = runtimeValueTypeI64
+	}
+	c.pushRuntimeValueLocationOnRegister(reg, vt)
 	return nil
 }
 
@@ -3043,14 +3523,15 @@ func (c *arm64Compiler) compileFloatConstant(is32bit bool, value uint64) error {
 // compileMemoryInit implements compiler.compileMemoryInit for the arm64 architecture.
 func (c *arm64Compiler) compileMemoryInit(o *wazeroir.UnionOperation) error {
 	dataIndex := uint32(o.U1)
-	return c.compileInitImpl(false, dataIndex, 0)
+	return c.compileInitImpl(false, dataIndex, 0, o.B3)
 }
 
-// compileInitImpl implements compileTableInit and compileMemoryInit.
+// compileInitImpl implements compileTableInit and compileMemoryInit. memory64 is true when the destination is a 64-bit
+// memory, whose offset can overflow when added to the size.
 //
 // TODO: the compiled code in this function should be reused and compile at once as
 // the code is independent of any module.
-func (c *arm64Compiler) compileInitImpl(isTable bool, index, tableIndex uint32) error {
+func (c *arm64Compiler) compileInitImpl(isTable bool, index, tableIndex uint32, memory64 bool) error {
 	outOfBoundsErrorStatus := nativeCallStatusCodeMemoryOutOfBounds
 	if isTable {
 		outOfBoundsErrorStatus = nativeCallStatusCodeInvalidTableAccess
@@ -3102,6 +3583,11 @@ func (c *arm64Compiler) compileInitImpl(isTable bool, index, tableIndex uint32)
 		c.assembler.CompileRegisterToRegister(arm64.ADD, copySize.register, sourceOffset.register)
 		// destinationOffset += size.
 		c.assembler.CompileRegisterToRegister(arm64.ADD, copySize.register, destinationOffset.register)
+		if memory64 {
+			// The addition above overflowed if destinationOffset < size, so exit in that case.
+			c.assembler.CompileTwoRegistersToNone(arm64.CMP, copySize.register, destinationOffset.register)
+			c.compileMaybeExitFromNativeCode(arm64.BCONDHS, outOfBoundsErrorStatus)
+		}
 	}
 
 	instanceAddr, err := c.allocateRegister(registerTypeGeneralPurpose)
@@ -3258,10 +3744,40 @@ func (c *arm64Compiler) compileLoadDataInstanceAddress(dataIndex uint32, dst asm
 }
 
 // compileMemoryCopy implements compiler.compileMemoryCopy for the arm64 architecture.
-func (c *arm64Compiler) compileMemoryCopy() error {
+func (c *arm64Compiler) compileMemoryCopy(o *wazeroir.UnionOperation) error {
+	if o.U1 != 0 || o.U2 != 0 || o.B3 {
+		return c.compileMultiMemoryCopy(o)
+	}
 	return c.compileCopyImpl(false, 0, 0)
 }
 
+// compileMultiMemoryCopy adds instructions to perform memory.copy involving a memory other than the zero one, or a
+// 64-bit memory, which is done by the builtin function.
+func (c *arm64Compiler) compileMultiMemoryCopy(o *wazeroir.UnionOperation) error {
+	if err := c.maybeCompileMoveTopConditionalToGeneralPurposeRegister(); err != nil {
+		return err
+	}
+
+	// Pushes the descriptor of the operation, which is decoded by the builtin function.
+	if err := c.compileIntConstant(false, encodeMemoryCopy(o)); err != nil {
+		return err
+	}
+
+	if err := c.compileCallGoFunction(nativeCallStatusCodeCallBuiltInFunction, builtinFunctionIndexMemoryCopy); err != nil {
+		return err
+	}
+
+	// The builtin function consumes the descriptor, the destination and source offsets, and the size.
+	for i := 0; i < 4; i++ {
+		c.locationStack.pop()
+	}
+
+	// After return, we re-initialize reserved registers just like preamble of functions.
+	c.compileReservedStackBasePointerRegisterInitialization()
+	c.compileReservedMemoryRegisterInitialization()
+	return nil
+}
+
 // compileCopyImpl implements compileTableCopy and compileMemoryCopy.
 //
 // TODO: the compiled code in this function should be reused and compile at once as
@@ -3518,15 +4034,15 @@ func (c *arm64Compiler) compileCopyImpl(isTable bool, srcTableIndex, dstTableInd
 }
 
 // compileMemoryFill implements compiler.compileMemoryCopy for the arm64 architecture.
-func (c *arm64Compiler) compileMemoryFill() error {
-	return c.compileFillImpl(false, 0)
+func (c *arm64Compiler) compileMemoryFill(o *wazeroir.UnionOperation) error {
+	return c.compileFillImpl(false, 0, o.B3)
 }
 
 // compileFillImpl implements TableFill and MemoryFill.
 //
 // TODO: the compiled code in this function should be reused and compile at once as
 // the code is independent of any module.
-func (c *arm64Compiler) compileFillImpl(isTable bool, tableIndex uint32) error {
+func (c *arm64Compiler) compileFillImpl(isTable bool, tableIndex uint32, memory64 bool) error {
 	outOfBoundsErrorStatus := nativeCallStatusCodeMemoryOutOfBounds
 	if isTable {
 		outOfBoundsErrorStatus = nativeCallStatusCodeInvalidTableAccess
@@ -3559,6 +4075,11 @@ func (c *arm64Compiler) compileFillImpl(isTable bool, tableIndex uint32) error {
 
 	// destinationOffset += size.
 	c.assembler.CompileRegisterToRegister(arm64.ADD, fillSize.register, destinationOffset.register)
+	if memory64 {
+		// The addition above overflowed if destinationOffset < size, so exit in that case.
+		c.assembler.CompileTwoRegistersToNone(arm64.CMP, fillSize.register, destinationOffset.register)
+		c.compileMaybeExitFromNativeCode(arm64.BCONDHS, outOfBoundsErrorStatus)
+	}
 
 	if isTable {
 		// arm64ReservedRegisterForTemporary = &tables[0]
@@ -3653,7 +4174,7 @@ func (c *arm64Compiler) compileFillImpl(isTable bool, tableIndex uint32) error {
 func (c *arm64Compiler) compileTableInit(o *wazeroir.UnionOperation) error {
 	elemIndex := uint32(o.U1)
 	tableIndex := uint32(o.U2)
-	return c.compileInitImpl(true, elemIndex, tableIndex)
+	return c.compileInitImpl(true, elemIndex, tableIndex, false)
 }
 
 // compileTableCopy implements compiler.compileTableCopy for the arm64 architecture.
@@ -3726,6 +4247,20 @@ func (c *arm64Compiler) compileRefFunc(o *wazeroir.UnionOperation) error {
 	return nil
 }
 
+// compileRefAsNonNull implements compiler.compileRefAsNonNull for the arm64 architecture.
+func (c *arm64Compiler) compileRefAsNonNull(*wazeroir.UnionOperation) error {
+	ref := c.locationStack.peek()
+	if err := c.compileEnsureOnRegister(ref); err != nil {
+		return err
+	}
+
+	c.assembler.CompileTwoRegistersToNone(arm64.CMP, arm64.RegRZR, ref.register)
+
+	// Skipped if the reference is not null.
+	c.compileMaybeExitFromNativeCode(arm64.BCONDNE, nativeCallStatusCodeNullReference)
+	return nil
+}
+
 // compileTableGet implements compiler.compileTableGet for the arm64 architecture.
 func (c *arm64Compiler) compileTableGet(o *wazeroir.UnionOperation) error {
 	ref, err := c.allocateRegister(registerTypeGeneralPurpose)
@@ -3910,7 +4445,7 @@ func (c *arm64Compiler) compileTableSize(o *wazeroir.UnionOperation) error {
 // compileTableFill implements compiler.compileTableFill for the arm64 architecture.
 func (c *arm64Compiler) compileTableFill(o *wazeroir.UnionOperation) error {
 	tableIndex := uint32(o.U1)
-	return c.compileFillImpl(true, tableIndex)
+	return c.compileFillImpl(true, tableIndex, false)
 }
 
 // popTwoValuesOnRegisters pops two values from the location stacks, ensures
@@ -4262,17 +4797,20 @@ func (c *arm64Compiler) compileModuleContextInitialization() error {
 
 	// Update callEngine.moduleContext.functionsElement0Address
 	{
-		// "tmpX = [moduleInstanceAddressRegister + moduleInstanceEngineOffset + interfaceDataOffset] (== *moduleEngine)"
+		// "tmpX = [callEngine + callEngineModuleContextFnOffset] (== *function)"
+		c.assembler.CompileMemoryToRegister(
+			arm64.LDRD,
+			arm64ReservedRegisterForCallEngine, callEngineModuleContextFnOffset,
+			tmpX,
+		)
+
+		// "tmpX = [tmpX + functionModuleEngineOffset] (== *moduleEngine)"
 		//
-		// Go's interface is laid out on memory as two quad words as struct {tab, data uintptr}
-		// where tab points to the interface table, and the latter points to the actual
-		// implementation of interface. This case, we extract "data" pointer as *moduleEngine.
-		// See the following references for detail:
-		// * https://research.swtch.com/interfaces
-		// * https://github.com/golang/go/blob/release-branch.go1.20/src/runtime/runtime2.go#L207-L210
+		// This isn't read from the module instance, as its engine is another one when functions are promoted to this
+		// engine, see function.moduleEngine.
 		c.assembler.CompileMemoryToRegister(
 			arm64.LDRD,
-			arm64CallingConventionModuleInstanceAddressRegister, moduleInstanceEngineOffset+interfaceDataOffset,
+			tmpX, functionModuleEngineOffset,
 			tmpX,
 		)
 
@@ -123,26 +123,25 @@ func (c *amd64Compiler) compileV128Load(o *wazeroir.UnionOperation) error {
 		return err
 	}
 
-	offset := uint32(o.U2)
 	loadType := wazeroir.V128LoadType(o.B1)
 
 	switch loadType {
 	case wazeroir.V128LoadType128:
-		err = c.compileV128LoadImpl(amd64.MOVDQU, offset, 16, result)
+		err = c.compileV128LoadImpl(o, amd64.MOVDQU, 16, result)
 	case wazeroir.V128LoadType8x8s:
-		err = c.compileV128LoadImpl(amd64.PMOVSXBW, offset, 8, result)
+		err = c.compileV128LoadImpl(o, amd64.PMOVSXBW, 8, result)
 	case wazeroir.V128LoadType8x8u:
-		err = c.compileV128LoadImpl(amd64.PMOVZXBW, offset, 8, result)
+		err = c.compileV128LoadImpl(o, amd64.PMOVZXBW, 8, result)
 	case wazeroir.V128LoadType16x4s:
-		err = c.compileV128LoadImpl(amd64.PMOVSXWD, offset, 8, result)
+		err = c.compileV128LoadImpl(o, amd64.PMOVSXWD, 8, result)
 	case wazeroir.V128LoadType16x4u:
-		err = c.compileV128LoadImpl(amd64.PMOVZXWD, offset, 8, result)
+		err = c.compileV128LoadImpl(o, amd64.PMOVZXWD, 8, result)
 	case wazeroir.V128LoadType32x2s:
-		err = c.compileV128LoadImpl(amd64.PMOVSXDQ, offset, 8, result)
+		err = c.compileV128LoadImpl(o, amd64.PMOVSXDQ, 8, result)
 	case wazeroir.V128LoadType32x2u:
-		err = c.compileV128LoadImpl(amd64.PMOVZXDQ, offset, 8, result)
+		err = c.compileV128LoadImpl(o, amd64.PMOVZXDQ, 8, result)
 	case wazeroir.V128LoadType8Splat:
-		reg, err := c.compileMemoryAccessCeilSetup(offset, 1)
+		reg, err := c.compileMemoryAccessCeilSetup(o, 1)
 		if err != nil {
 			return err
 		}
@@ -160,7 +159,7 @@ func (c *amd64Compiler) compileV128Load(o *wazeroir.UnionOperation) error {
 		c.assembler.CompileRegisterToRegister(amd64.PXOR, tmpVReg, tmpVReg)
 		c.assembler.CompileRegisterToRegister(amd64.PSHUFB, tmpVReg, result)
 	case wazeroir.V128LoadType16Splat:
-		reg, err := c.compileMemoryAccessCeilSetup(offset, 2)
+		reg, err := c.compileMemoryAccessCeilSetup(o, 2)
 		if err != nil {
 			return err
 		}
@@ -173,7 +172,7 @@ func (c *amd64Compiler) compileV128Load(o *wazeroir.UnionOperation) error {
 		c.assembler.CompileRegisterToRegisterWithArg(amd64.PINSRW, reg, result, 1)
 		c.assembler.CompileRegisterToRegisterWithArg(amd64.PSHUFD, result, result, 0)
 	case wazeroir.V128LoadType32Splat:
-		reg, err := c.compileMemoryAccessCeilSetup(offset, 4)
+		reg, err := c.compileMemoryAccessCeilSetup(o, 4)
 		if err != nil {
 			return err
 		}
@@ -184,7 +183,7 @@ func (c *amd64Compiler) compileV128Load(o *wazeroir.UnionOperation) error {
 		c.assembler.CompileRegisterToRegisterWithArg(amd64.PINSRD, reg, result, 0)
 		c.assembler.CompileRegisterToRegisterWithArg(amd64.PSHUFD, result, result, 0)
 	case wazeroir.V128LoadType64Splat:
-		reg, err := c.compileMemoryAccessCeilSetup(offset, 8)
+		reg, err := c.compileMemoryAccessCeilSetup(o, 8)
 		if err != nil {
 			return err
 		}
@@ -195,9 +194,9 @@ func (c *amd64Compiler) compileV128Load(o *wazeroir.UnionOperation) error {
 		c.assembler.CompileRegisterToRegisterWithArg(amd64.PINSRQ, reg, result, 0)
 		c.assembler.CompileRegisterToRegisterWithArg(amd64.PINSRQ, reg, result, 1)
 	case wazeroir.V128LoadType32zero:
-		err = c.compileV128LoadImpl(amd64.MOVL, offset, 4, result)
+		err = c.compileV128LoadImpl(o, amd64.MOVL, 4, result)
 	case wazeroir.V128LoadType64zero:
-		err = c.compileV128LoadImpl(amd64.MOVQ, offset, 8, result)
+		err = c.compileV128LoadImpl(o, amd64.MOVQ, 8, result)
 	}
 
 	if err != nil {
@@ -208,8 +207,8 @@ func (c *amd64Compiler) compileV128Load(o *wazeroir.UnionOperation) error {
 	return nil
 }
 
-func (c *amd64Compiler) compileV128LoadImpl(inst asm.Instruction, offset uint32, targetSizeInBytes int64, dst asm.Register) error {
-	offsetReg, err := c.compileMemoryAccessCeilSetup(offset, targetSizeInBytes)
+func (c *amd64Compiler) compileV128LoadImpl(o *wazeroir.UnionOperation, inst asm.Instruction, targetSizeInBytes int64, dst asm.Register) error {
+	offsetReg, err := c.compileMemoryAccessCeilSetup(o, targetSizeInBytes)
 	if err != nil {
 		return err
 	}
@@ -226,7 +225,6 @@ func (c *amd64Compiler) compileV128LoadLane(o *wazeroir.UnionOperation) error {
 	}
 
 	laneSize, laneIndex := o.B1, o.B2
-	offset := uint32(o.U2)
 
 	var insertInst asm.Instruction
 	switch laneSize {
@@ -241,7 +239,7 @@ func (c *amd64Compiler) compileV128LoadLane(o *wazeroir.UnionOperation) error {
 	}
 
 	targetSizeInBytes := int64(laneSize / 8)
-	offsetReg, err := c.compileMemoryAccessCeilSetup(offset, targetSizeInBytes)
+	offsetReg, err := c.compileMemoryAccessCeilSetup(o, targetSizeInBytes)
 	if err != nil {
 		return err
 	}
@@ -260,8 +258,7 @@ func (c *amd64Compiler) compileV128Store(o *wazeroir.UnionOperation) error {
 	}
 
 	const targetSizeInBytes = 16
-	offset := uint32(o.U2)
-	offsetReg, err := c.compileMemoryAccessCeilSetup(offset, targetSizeInBytes)
+	offsetReg, err := c.compileMemoryAccessCeilSetup(o, targetSizeInBytes)
 	if err != nil {
 		return err
 	}
@@ -278,7 +275,6 @@ func (c *amd64Compiler) compileV128StoreLane(o *wazeroir.UnionOperation) error {
 	var storeInst asm.Instruction
 	laneSize := o.B1
 	laneIndex := o.B2
-	offset := uint32(o.U2)
 	switch laneSize {
 	case 8:
 		storeInst = amd64.PEXTRB
@@ -296,7 +292,7 @@ func (c *amd64Compiler) compileV128StoreLane(o *wazeroir.UnionOperation) error {
 	}
 
 	targetSizeInBytes := int64(laneSize / 8)
-	offsetReg, err := c.compileMemoryAccessCeilSetup(offset, targetSizeInBytes)
+	offsetReg, err := c.compileMemoryAccessCeilSetup(o, targetSizeInBytes)
 	if err != nil {
 		return err
 	}
@@ -2506,6 +2502,118 @@ func (c *amd64Compiler) compileV128Dot(*wazeroir.UnionOperation) error {
 	return nil
 }
 
+// compileV128RelaxedMadd implements compiler.compileV128RelaxedMadd for amd64.
+func (c *amd64Compiler) compileV128RelaxedMadd(o *wazeroir.UnionOperation) error {
+	z := c.locationStack.popV128()
+	if err := c.compileEnsureOnRegister(z); err != nil {
+		return err
+	}
+
+	y := c.locationStack.popV128()
+	if err := c.compileEnsureOnRegister(y); err != nil {
+		return err
+	}
+
+	x := c.locationStack.popV128()
+	if err := c.compileEnsureOnRegister(x); err != nil {
+		return err
+	}
+
+	var mul, add, sub asm.Instruction
+	switch o.B1 {
+	case wazeroir.ShapeF32x4:
+		mul, add, sub = amd64.MULPS, amd64.ADDPS, amd64.SUBPS
+	case wazeroir.ShapeF64x2:
+		mul, add, sub = amd64.MULPD, amd64.ADDPD, amd64.SUBPD
+	}
+
+	// Multiply and add with separate instructions instead of VFMADD, so that the result is the same as the other
+	// platforms and the interpreter, and doesn't depend on the availability of FMA.
+	c.assembler.CompileRegisterToRegister(mul, y.register, x.register)
+	if o.B3 {
+		// z = z - x*y
+		c.assembler.CompileRegisterToRegister(sub, x.register, z.register)
+		c.locationStack.markRegisterUnused(x.register, y.register)
+		c.pushVectorRuntimeValueLocationOnRegister(z.register)
+	} else {
+		// x = x*y + z
+		c.assembler.CompileRegisterToRegister(add, z.register, x.register)
+		c.locationStack.markRegisterUnused(y.register, z.register)
+		c.pushVectorRuntimeValueLocationOnRegister(x.register)
+	}
+	return nil
+}
+
+// compileV128RelaxedDot implements compiler.compileV128RelaxedDot for amd64.
+func (c *amd64Compiler) compileV128RelaxedDot(o *wazeroir.UnionOperation) error {
+	add := o.B3
+	var z *runtimeValueLocation
+	if add {
+		z = c.locationStack.popV128()
+		if err := c.compileEnsureOnRegister(z); err != nil {
+			return err
+		}
+	}
+
+	x2 := c.locationStack.popV128()
+	if err := c.compileEnsureOnRegister(x2); err != nil {
+		return err
+	}
+
+	x1 := c.locationStack.popV128()
+	if err := c.compileEnsureOnRegister(x1); err != nil {
+		return err
+	}
+
+	tmp1, err := c.allocateRegister(registerTypeVector)
+	if err != nil {
+		return err
+	}
+	c.locationStack.markRegisterUsed(tmp1)
+
+	tmp2, err := c.allocateRegister(registerTypeVector)
+	if err != nil {
+		return err
+	}
+
+	x1r, x2r := x1.register, x2.register
+
+	// Copy the higher 64-bits of the operands into the lower ones of tmp1 and tmp2.
+	// See https://www.felixcloutier.com/x86/palignr
+	c.assembler.CompileRegisterToRegister(amd64.MOVDQA, x1r, tmp1)
+	c.assembler.CompileRegisterToRegisterWithArg(amd64.PALIGNR, tmp1, tmp1, 0x8)
+	c.assembler.CompileRegisterToRegister(amd64.MOVDQA, x2r, tmp2)
+	c.assembler.CompileRegisterToRegisterWithArg(amd64.PALIGNR, tmp2, tmp2, 0x8)
+
+	// Sign-extend both operands into 16-bit lanes, as PMADDUBSW would treat the first operand as unsigned, and
+	// multiply-add adjacent lanes into exact 32-bit results. The lower lanes go into x1r, and the higher into tmp1.
+	// See https://www.felixcloutier.com/x86/pmaddwd
+	for _, pair := range [][2]asm.Register{{x1r, x2r}, {tmp1, tmp2}} {
+		c.assembler.CompileRegisterToRegister(amd64.PMOVSXBW, pair[0], pair[0])
+		c.assembler.CompileRegisterToRegister(amd64.PMOVSXBW, pair[1], pair[1])
+		c.assembler.CompileRegisterToRegister(amd64.PMADDWD, pair[1], pair[0])
+	}
+
+	// Narrow the 32-bit results into 16-bit lanes with signed saturation.
+	// See https://www.felixcloutier.com/x86/packsswb:packssdw
+	c.assembler.CompileRegisterToRegister(amd64.PACKSSDW, tmp1, x1r)
+
+	if add {
+		// Add adjacent 16-bit lanes into 32-bit lanes by multiplying them with ones, and then add z.
+		if err = c.assembler.CompileStaticConstToRegister(amd64.MOVDQU,
+			asm.NewStaticConst(allOnesI16x8[:]), tmp2); err != nil {
+			return err
+		}
+		c.assembler.CompileRegisterToRegister(amd64.PMADDWD, tmp2, x1r)
+		c.assembler.CompileRegisterToRegister(amd64.PADDD, z.register, x1r)
+		c.locationStack.markRegisterUnused(z.register)
+	}
+
+	c.locationStack.markRegisterUnused(x2r, tmp1)
+	c.pushVectorRuntimeValueLocationOnRegister(x1r)
+	return nil
+}
+
 var fConvertFromIMask = [16]byte{
 	0x00, 0x00, 0x30, 0x43, 0x00, 0x00, 0x30, 0x43, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
 }
@@ -144,12 +144,11 @@ func (c *arm64Compiler) compileV128Load(o *wazeroir.UnionOperation) (err error)
 		return err
 	}
 
-	offset := uint32(o.U2)
 	loadType := wazeroir.V128LoadType(o.B1)
 
 	switch loadType {
 	case wazeroir.V128LoadType128:
-		offset, err := c.compileMemoryAccessOffsetSetup(offset, 16)
+		offset, err := c.compileMemoryAccessOffsetSetup(o, 16)
 		if err != nil {
 			return err
 		}
@@ -157,7 +156,7 @@ func (c *arm64Compiler) compileV128Load(o *wazeroir.UnionOperation) (err error)
 			arm64ReservedRegisterForMemory, offset, result, arm64.VectorArrangementQ,
 		)
 	case wazeroir.V128LoadType8x8s:
-		offset, err := c.compileMemoryAccessOffsetSetup(offset, 8)
+		offset, err := c.compileMemoryAccessOffsetSetup(o, 8)
 		if err != nil {
 			return err
 		}
@@ -167,7 +166,7 @@ func (c *arm64Compiler) compileV128Load(o *wazeroir.UnionOperation) (err error)
 		c.assembler.CompileVectorRegisterToVectorRegister(arm64.SSHLL, result, result,
 			arm64.VectorArrangement8B, arm64.VectorIndexNone, arm64.VectorIndexNone)
 	case wazeroir.V128LoadType8x8u:
-		offset, err := c.compileMemoryAccessOffsetSetup(offset, 8)
+		offset, err := c.compileMemoryAccessOffsetSetup(o, 8)
 		if err != nil {
 			return err
 		}
@@ -177,7 +176,7 @@ func (c *arm64Compiler) compileV128Load(o *wazeroir.UnionOperation) (err error)
 		c.assembler.CompileVectorRegisterToVectorRegister(arm64.USHLL, result, result,
 			arm64.VectorArrangement8B, arm64.VectorIndexNone, arm64.VectorIndexNone)
 	case wazeroir.V128LoadType16x4s:
-		offset, err := c.compileMemoryAccessOffsetSetup(offset, 8)
+		offset, err := c.compileMemoryAccessOffsetSetup(o, 8)
 		if err != nil {
 			return err
 		}
@@ -187,7 +186,7 @@ func (c *arm64Compiler) compileV128Load(o *wazeroir.UnionOperation) (err error)
 		c.assembler.CompileVectorRegisterToVectorRegister(arm64.SSHLL, result, result,
 			arm64.VectorArrangement4H, arm64.VectorIndexNone, arm64.VectorIndexNone)
 	case wazeroir.V128LoadType16x4u:
-		offset, err := c.compileMemoryAccessOffsetSetup(offset, 8)
+		offset, err := c.compileMemoryAccessOffsetSetup(o, 8)
 		if err != nil {
 			return err
 		}
@@ -197,7 +196,7 @@ func (c *arm64Compiler) compileV128Load(o *wazeroir.UnionOperation) (err error)
 		c.assembler.CompileVectorRegisterToVectorRegister(arm64.USHLL, result, result,
 			arm64.VectorArrangement4H, arm64.VectorIndexNone, arm64.VectorIndexNone)
 	case wazeroir.V128LoadType32x2s:
-		offset, err := c.compileMemoryAccessOffsetSetup(offset, 8)
+		offset, err := c.compileMemoryAccessOffsetSetup(o, 8)
 		if err != nil {
 			return err
 		}
@@ -207,7 +206,7 @@ func (c *arm64Compiler) compileV128Load(o *wazeroir.UnionOperation) (err error)
 		c.assembler.CompileVectorRegisterToVectorRegister(arm64.SSHLL, result, result,
 			arm64.VectorArrangement2S, arm64.VectorIndexNone, arm64.VectorIndexNone)
 	case wazeroir.V128LoadType32x2u:
-		offset, err := c.compileMemoryAccessOffsetSetup(offset, 8)
+		offset, err := c.compileMemoryAccessOffsetSetup(o, 8)
 		if err != nil {
 			return err
 		}
@@ -217,35 +216,35 @@ func (c *arm64Compiler) compileV128Load(o *wazeroir.UnionOperation) (err error)
 		c.assembler.CompileVectorRegisterToVectorRegister(arm64.USHLL, result, result,
 			arm64.VectorArrangement2S, arm64.VectorIndexNone, arm64.VectorIndexNone)
 	case wazeroir.V128LoadType8Splat:
-		offset, err := c.compileMemoryAccessOffsetSetup(offset, 1)
+		offset, err := c.compileMemoryAccessOffsetSetup(o, 1)
 		if err != nil {
 			return err
 		}
 		c.assembler.CompileRegisterToRegister(arm64.ADD, arm64ReservedRegisterForMemory, offset)
 		c.assembler.CompileMemoryToVectorRegister(arm64.LD1R, offset, 0, result, arm64.VectorArrangement16B)
 	case wazeroir.V128LoadType16Splat:
-		offset, err := c.compileMemoryAccessOffsetSetup(offset, 2)
+		offset, err := c.compileMemoryAccessOffsetSetup(o, 2)
 		if err != nil {
 			return err
 		}
 		c.assembler.CompileRegisterToRegister(arm64.ADD, arm64ReservedRegisterForMemory, offset)
 		c.assembler.CompileMemoryToVectorRegister(arm64.LD1R, offset, 0, result, arm64.VectorArrangement8H)
 	case wazeroir.V128LoadType32Splat:
-		offset, err := c.compileMemoryAccessOffsetSetup(offset, 4)
+		offset, err := c.compileMemoryAccessOffsetSetup(o, 4)
 		if err != nil {
 			return err
 		}
 		c.assembler.CompileRegisterToRegister(arm64.ADD, arm64ReservedRegisterForMemory, offset)
 		c.assembler.CompileMemoryToVectorRegister(arm64.LD1R, offset, 0, result, arm64.VectorArrangement4S)
 	case wazeroir.V128LoadType64Splat:
-		offset, err := c.compileMemoryAccessOffsetSetup(offset, 8)
+		offset, err := c.compileMemoryAccessOffsetSetup(o, 8)
 		if err != nil {
 			return err
 		}
 		c.assembler.CompileRegisterToRegister(arm64.ADD, arm64ReservedRegisterForMemory, offset)
 		c.assembler.CompileMemoryToVectorRegister(arm64.LD1R, offset, 0, result, arm64.VectorArrangement2D)
 	case wazeroir.V128LoadType32zero:
-		offset, err := c.compileMemoryAccessOffsetSetup(offset, 4)
+		offset, err := c.compileMemoryAccessOffsetSetup(o, 4)
 		if err != nil {
 			return err
 		}
@@ -253,7 +252,7 @@ func (c *arm64Compiler) compileV128Load(o *wazeroir.UnionOperation) (err error)
 			arm64ReservedRegisterForMemory, offset, result, arm64.VectorArrangementS,
 		)
 	case wazeroir.V128LoadType64zero:
-		offset, err := c.compileMemoryAccessOffsetSetup(offset, 8)
+		offset, err := c.compileMemoryAccessOffsetSetup(o, 8)
 		if err != nil {
 			return err
 		}
@@ -274,10 +273,9 @@ func (c *arm64Compiler) compileV128LoadLane(o *wazeroir.UnionOperation) (err err
 	}
 
 	laneSize, laneIndex := o.B1, o.B2
-	offset := uint32(o.U2)
 
 	targetSizeInBytes := int64(laneSize / 8)
-	source, err := c.compileMemoryAccessOffsetSetup(offset, targetSizeInBytes)
+	source, err := c.compileMemoryAccessOffsetSetup(o, targetSizeInBytes)
 	if err != nil {
 		return err
 	}
@@ -315,8 +313,7 @@ func (c *arm64Compiler) compileV128Store(o *wazeroir.UnionOperation) (err error)
 	}
 
 	const targetSizeInBytes = 16
-	offset := uint32(o.U2)
-	offsetReg, err := c.compileMemoryAccessOffsetSetup(offset, targetSizeInBytes)
+	offsetReg, err := c.compileMemoryAccessOffsetSetup(o, targetSizeInBytes)
 	if err != nil {
 		return err
 	}
@@ -334,7 +331,6 @@ func (c *arm64Compiler) compileV128StoreLane(o *wazeroir.UnionOperation) (err er
 	var storeInst asm.Instruction
 	laneSize := o.B1
 	laneIndex := o.B2
-	offset := uint32(o.U2)
 	switch laneSize {
 	case 8:
 		storeInst = arm64.STRB
@@ -356,7 +352,7 @@ func (c *arm64Compiler) compileV128StoreLane(o *wazeroir.UnionOperation) (err er
 	}
 
 	targetSizeInBytes := int64(laneSize / 8)
-	offsetReg, err := c.compileMemoryAccessOffsetSetup(offset, targetSizeInBytes)
+	offsetReg, err := c.compileMemoryAccessOffsetSetup(o, targetSizeInBytes)
 	if err != nil {
 		return err
 	}
@@ -1512,6 +1508,107 @@ func (c *arm64Compiler) compileV128Dot(*wazeroir.UnionOperation) error {
 	return nil
 }
 
+// compileV128RelaxedMadd implements compiler.compileV128RelaxedMadd for arm64.
+func (c *arm64Compiler) compileV128RelaxedMadd(o *wazeroir.UnionOperation) error {
+	z := c.locationStack.popV128()
+	if err := c.compileEnsureOnRegister(z); err != nil {
+		return err
+	}
+
+	y := c.locationStack.popV128()
+	if err := c.compileEnsureOnRegister(y); err != nil {
+		return err
+	}
+
+	x := c.locationStack.popV128()
+	if err := c.compileEnsureOnRegister(x); err != nil {
+		return err
+	}
+
+	var add, sub asm.Instruction
+	var arr arm64.VectorArrangement
+	switch o.B1 {
+	case wazeroir.ShapeF32x4:
+		add, sub, arr = arm64.VFADDS, arm64.VFSUBS, arm64.VectorArrangement4S
+	case wazeroir.ShapeF64x2:
+		add, sub, arr = arm64.VFADDD, arm64.VFSUBD, arm64.VectorArrangement2D
+	}
+
+	xr, yr, zr := x.register, y.register, z.register
+
+	// Multiply and add with separate instructions instead of FMLA, so that the result is the same as the other
+	// platforms and the interpreter.
+	c.assembler.CompileVectorRegisterToVectorRegister(arm64.VFMUL, yr, xr, arr, arm64.VectorIndexNone, arm64.VectorIndexNone)
+	if o.B3 {
+		// zr = zr - xr*yr
+		c.assembler.CompileVectorRegisterToVectorRegister(sub, xr, zr, arr, arm64.VectorIndexNone, arm64.VectorIndexNone)
+	} else {
+		// zr = xr*yr + zr
+		c.assembler.CompileVectorRegisterToVectorRegister(add, xr, zr, arr, arm64.VectorIndexNone, arm64.VectorIndexNone)
+	}
+
+	c.markRegisterUnused(xr, yr)
+	c.pushVectorRuntimeValueLocationOnRegister(zr)
+	return nil
+}
+
+// compileV128RelaxedDot implements compiler.compileV128RelaxedDot for arm64.
+func (c *arm64Compiler) compileV128RelaxedDot(o *wazeroir.UnionOperation) error {
+	add := o.B3
+	var z *runtimeValueLocation
+	if add {
+		z = c.locationStack.popV128()
+		if err := c.compileEnsureOnRegister(z); err != nil {
+			return err
+		}
+	}
+
+	x2 := c.locationStack.popV128()
+	if err := c.compileEnsureOnRegister(x2); err != nil {
+		return err
+	}
+
+	x1 := c.locationStack.popV128()
+	if err := c.compileEnsureOnRegister(x1); err != nil {
+		return err
+	}
+
+	tmp, err := c.allocateRegister(registerTypeVector)
+	if err != nil {
+		return err
+	}
+
+	x1r, x2r := x1.register, x2.register
+
+	// Multiply lower signed bytes and get the 16-bit results into tmp.
+	c.assembler.CompileTwoVectorRegistersToVectorRegister(arm64.SMULL, x1r, x2r, tmp, arm64.VectorArrangement8B)
+	// Multiply higher signed bytes and get the 16-bit results into x1r.
+	c.assembler.CompileTwoVectorRegistersToVectorRegister(arm64.SMULL2, x1r, x2r, x1r, arm64.VectorArrangement16B)
+	// Add adjacent products into exact 32-bit results.
+	c.assembler.CompileVectorRegisterToVectorRegister(arm64.SADDLP, tmp, tmp, arm64.VectorArrangement8H,
+		arm64.VectorIndexNone, arm64.VectorIndexNone)
+	c.assembler.CompileVectorRegisterToVectorRegister(arm64.SADDLP, x1r, x1r, arm64.VectorArrangement8H,
+		arm64.VectorIndexNone, arm64.VectorIndexNone)
+	// Narrow them into 16-bit lanes of tmp with signed saturation.
+	c.assembler.CompileVectorRegisterToVectorRegister(arm64.SQXTN, tmp, tmp, arm64.VectorArrangement4H,
+		arm64.VectorIndexNone, arm64.VectorIndexNone)
+	c.assembler.CompileVectorRegisterToVectorRegister(arm64.SQXTN2, x1r, tmp, arm64.VectorArrangement8H,
+		arm64.VectorIndexNone, arm64.VectorIndexNone)
+
+	if add {
+		// Add adjacent 16-bit lanes into 32-bit lanes, and then add z.
+		c.assembler.CompileVectorRegisterToVectorRegister(arm64.SADDLP, tmp, tmp, arm64.VectorArrangement8H,
+			arm64.VectorIndexNone, arm64.VectorIndexNone)
+		c.assembler.CompileVectorRegisterToVectorRegister(arm64.VADD, z.register, tmp, arm64.VectorArrangement4S,
+			arm64.VectorIndexNone, arm64.VectorIndexNone)
+		c.markRegisterUnused(z.register)
+	}
+
+	c.markRegisterUnused(x1r, x2r)
+	c.pushVectorRuntimeValueLocationOnRegister(tmp)
+	return nil
+}
+
 // compileV128Narrow implements compiler.compileV128Narrow for arm64.
 func (c *arm64Compiler) compileV128Narrow(o *wazeroir.UnionOperation) error {
 	x2 := c.locationStack.popV128()
@@ -8,6 +8,7 @@ import (
 	"math"
 	"math/bits"
 	"sync"
+	"sync/atomic"
 	"unsafe"
 
 	"github.com/tetratelabs/wazero/api"
@@ -27,6 +28,9 @@ import (
 // The default value should suffice for most use cases. Those wishing to change this can via `go build -ldflags`.
 var callStackCeiling = 2000
 
+// defaultMaxStackBytes is the maximum size of callEngine.stack when wasm.Store MaxStackBytes is zero.
+const defaultMaxStackBytes = 40000000 // == 40mb.
+
 // engine is an interpreter implementation of wasm.Engine
 type engine struct {
 	enabledFeatures   api.CoreFeatures
@@ -34,6 +38,8 @@ type engine struct {
 	mux               sync.RWMutex
 	// labelAddressResolutionCache is the temporary cache used to map LabelKind -> FrameID -> the index to the body.
 	labelAddressResolutionCache [wazeroir.LabelKindNum][]uint64
+	// tiering is non-nil when created by NewTieringEngine.
+	tiering *tiering
 }
 
 func NewEngine(_ context.Context, enabledFeatures api.CoreFeatures, _ filecache.Cache) wasm.Engine {
@@ -106,12 +112,55 @@ type callEngine struct {
 
 	// stackiterator for Listeners to walk frames and stack.
 	stackIterator stackIterator
+
+	// fuel is the remaining fuel of the module of f, consumed by wazeroir.OperationKindConsumeFuel.
+	fuel int64
+	// fuelLoaded is the value of fuel when it was last synchronized with the module of f.
+	fuelLoaded int64
+
+	// epochDeadline is the epoch at which wazeroir.OperationKindCheckEpoch traps in this call.
+	epochDeadline uint64
+
+	// maxCallStackDepth and maxStackBytes are the limits of frames and stack, or zero for
+	// callStackCeiling and defaultMaxStackBytes respectively.
+	maxCallStackDepth, maxStackBytes uint64
+	// callDepth is the count of functions executing before this call, which count towards maxCallStackDepth, see
+	// wasm.CallDepthFunction.
+	callDepth uint64
+
+	// exceptions are the exceptions caught by wazeroir.OperationKindCatch, whose handles on the stack are the indexes
+	// of this slice.
+	exceptions []caughtException
+
+	// exnrefs are the exceptions referenced by wasm.ValueTypeExnref values, which are the indexes of this slice plus
+	// one so that zero is the null reference. These are released at the end of the call.
+	exnrefs []*api.Exception
+}
+
+// caughtException is the exception caught by wazeroir.OperationKindCatch, which can be thrown again by
+// wazeroir.OperationKindRethrow.
+type caughtException struct {
+	exc *api.Exception
+	// height is the length of the stack below the handle of the exception.
+	height int
 }
 
 func (e *moduleEngine) newCallEngine(compiled *function) *callEngine {
 	return &callEngine{f: compiled}
 }
 
+// loadFuel reads the remaining fuel of the module of the called function.
+func (ce *callEngine) loadFuel() {
+	ce.fuel = ce.f.moduleInstance.Fuel()
+	ce.fuelLoaded = ce.fuel
+}
+
+// storeFuel subtracts the fuel consumed since loadFuel from the module of the called function.
+func (ce *callEngine) storeFuel() {
+	ce.f.moduleInstance.ConsumeFuel(ce.fuelLoaded - ce.fuel)
+	ce.fuelLoaded = ce.fuel
+}
+
 func (ce *callEngine) pushValue(v uint64) {
 	ce.stack = append(ce.stack, v)
 }
@@ -162,7 +211,14 @@ func (ce *callEngine) drop(raw uint64) {
 }
 
 func (ce *callEngine) pushFrame(frame *callFrame) {
-	if callStackCeiling <= len(ce.frames) {
+	maxCallStackDepth, maxStackBytes := ce.maxCallStackDepth, ce.maxStackBytes
+	if maxCallStackDepth == 0 {
+		maxCallStackDepth = uint64(callStackCeiling)
+	}
+	if maxStackBytes == 0 {
+		maxStackBytes = defaultMaxStackBytes
+	}
+	if maxCallStackDepth <= ce.callDepth+uint64(len(ce.frames)) || maxStackBytes < uint64(len(ce.stack))*8 {
 		panic(wasmruntime.ErrRuntimeStackOverflow)
 	}
 	ce.frames = append(ce.frames, frame)
@@ -190,11 +246,16 @@ type callFrame struct {
 type compiledFunction struct {
 	source              *wasm.Module
 	body                []wazeroir.UnionOperation
+	exceptionHandlers   []wazeroir.ExceptionHandler
 	listener            experimental.FunctionListener
 	offsetsInWasmBinary []uint64
 	hostFn              interface{}
 	ensureTermination   bool
+	checkEpoch          bool
 	index               wasm.Index
+	// tiering is non-nil when hits are counted, which is exclusively read and updated with atomics.
+	tiering *tiering
+	hits    uint32
 }
 
 type function struct {
@@ -202,6 +263,9 @@ type function struct {
 	moduleInstance *wasm.ModuleInstance
 	typeID         wasm.FunctionTypeID
 	parent         *compiledFunction
+	// promoted is non-nil for the functions of a guest module with tiering. It is shared by the copies of the
+	// function in importing module engines, see Promote.
+	promoted *atomic.Pointer[promotion]
 }
 
 // functionFromUintptr resurrects the original *function from the given uintptr
@@ -302,13 +366,13 @@ func (f internalFunction) SourceOffsetForPC(pc experimental.ProgramCounter) uint
 const callFrameStackSize = 0
 
 // CompileModule implements the same method as documented on wasm.Engine.
-func (e *engine) CompileModule(_ context.Context, module *wasm.Module, listeners []experimental.FunctionListener, ensureTermination bool) error {
+func (e *engine) CompileModule(_ context.Context, module *wasm.Module, listeners []experimental.FunctionListener, ensureTermination, meterFuel, checkEpoch bool) error {
 	if _, ok := e.getCompiledFunctions(module); ok { // cache hit!
 		return nil
 	}
 
 	funcs := make([]compiledFunction, len(module.FunctionSection))
-	irCompiler, err := wazeroir.NewCompiler(e.enabledFeatures, callFrameStackSize, module, ensureTermination)
+	irCompiler, err := wazeroir.NewCompiler(e.enabledFeatures, callFrameStackSize, module, ensureTermination, meterFuel, checkEpoch)
 	if err != nil {
 		return err
 	}
@@ -338,8 +402,10 @@ func (e *engine) CompileModule(_ context.Context, module *wasm.Module, listeners
 		}
 		compiled.source = module
 		compiled.ensureTermination = ensureTermination
+		compiled.checkEpoch = checkEpoch
 		compiled.listener = lsn
 		compiled.index = imported + uint32(i)
+		compiled.tiering = e.tiering
 	}
 	e.addCompiledFunctions(module, funcs)
 	return nil
@@ -357,6 +423,10 @@ func (e *engine) NewModuleEngine(module *wasm.Module, instance *wasm.ModuleInsta
 		return nil, errors.New("source module must be compiled before instantiation")
 	}
 
+	var promotions []atomic.Pointer[promotion]
+	if e.tiering != nil && !module.IsHostModule {
+		promotions = make([]atomic.Pointer[promotion], len(codes))
+	}
 	for i := range codes {
 		c := &codes[i]
 		offset := i + int(module.ImportFunctionCount)
@@ -367,6 +437,9 @@ func (e *engine) NewModuleEngine(module *wasm.Module, instance *wasm.ModuleInsta
 			funcType:       &module.TypeSection[typeIndex],
 			parent:         c,
 		}
+		if promotions != nil {
+			me.functions[offset].promoted = &promotions[i]
+		}
 	}
 	return me, nil
 }
@@ -376,6 +449,8 @@ func (e *engine) lowerIR(ir *wazeroir.CompilationResult, ret *compiledFunction)
 	// Copy the body from the result.
 	ret.body = make([]wazeroir.UnionOperation, len(ir.Operations))
 	copy(ret.body, ir.Operations)
+	// The exception handlers are allocated per function, and their indexes are the same in body.
+	ret.exceptionHandlers = ir.ExceptionHandlers
 	// Also copy the offsets if necessary.
 	if offsets := ir.IROperationSourceOffsetsInWasmBinary; len(offsets) > 0 {
 		ret.offsetsInWasmBinary = make([]uint64, len(offsets))
@@ -447,6 +522,22 @@ func (e *moduleEngine) FunctionInstanceReference(funcIndex wasm.Index) wasm.Refe
 	return uintptr(unsafe.Pointer(&e.functions[funcIndex]))
 }
 
+// OwnsFunctionReference implements the same method as documented on wasm.ModuleEngine.
+func (e *moduleEngine) OwnsFunctionReference(ref wasm.Reference) bool {
+	begin, end := e.FunctionReferenceRange()
+	return ref >= begin && ref < end && (ref-begin)%unsafe.Sizeof(function{}) == 0
+}
+
+// FunctionReferenceRange implements the same method as documented on wasm.ModuleEngine.
+func (e *moduleEngine) FunctionReferenceRange() (begin, end wasm.Reference) {
+	if len(e.functions) == 0 {
+		return
+	}
+	begin = uintptr(unsafe.Pointer(&e.functions[0]))
+	end = begin + uintptr(len(e.functions))*unsafe.Sizeof(e.functions[0])
+	return
+}
+
 // NewFunction implements the same method as documented on wasm.ModuleEngine.
 func (e *moduleEngine) NewFunction(index wasm.Index) (ce api.Function) {
 	// Note: The input parameters are pre-validated, so a compiled function is only absent on close. Updates to
@@ -457,17 +548,21 @@ func (e *moduleEngine) NewFunction(index wasm.Index) (ce api.Function) {
 
 // LookupFunction implements the same method as documented on wasm.ModuleEngine.
 func (e *moduleEngine) LookupFunction(t *wasm.TableInstance, typeId wasm.FunctionTypeID, tableOffset wasm.Index) (f api.Function, err error) {
-	if tableOffset >= uint32(len(t.References)) {
+	if tableOffset >= uint32(len(t.References)) || t.Type != wasm.RefTypeFuncref {
 		err = wasmruntime.ErrRuntimeInvalidTableAccess
 		return
 	}
-	rawPtr := t.References[tableOffset]
-	if rawPtr == 0 {
+	return e.LookupFunctionReference(t.References[tableOffset], typeId)
+}
+
+// LookupFunctionReference implements the same method as documented on wasm.ModuleEngine.
+func (e *moduleEngine) LookupFunctionReference(ref wasm.Reference, typeId wasm.FunctionTypeID) (f api.Function, err error) {
+	if ref == 0 {
 		err = wasmruntime.ErrRuntimeInvalidTableAccess
 		return
 	}
 
-	tf := functionFromUintptr(rawPtr)
+	tf := functionFromUintptr(ref)
 	if tf.typeID != typeId {
 		err = wasmruntime.ErrRuntimeIndirectCallTypeMismatch
 		return
@@ -493,20 +588,25 @@ func (ce *callEngine) Call(ctx context.Context, params ...uint64) (results []uin
 	if n := ft.ParamNumInUint64; n != len(params) {
 		return nil, fmt.Errorf("expected %d params, but passed %d", n, len(params))
 	}
-	return ce.call(ctx, params, nil)
+	return ce.call(ctx, params, nil, 0)
 }
 
 // CallWithStack implements the same method as documented on api.Function.
 func (ce *callEngine) CallWithStack(ctx context.Context, stack []uint64) error {
+	return ce.CallWithStackAndDepth(ctx, stack, 0)
+}
+
+// CallWithStackAndDepth implements the same method as documented on wasm.CallDepthFunction.
+func (ce *callEngine) CallWithStackAndDepth(ctx context.Context, stack []uint64, depth uint64) error {
 	params, results, err := wasm.SplitCallStack(ce.f.funcType, stack)
 	if err != nil {
 		return err
 	}
-	_, err = ce.call(ctx, params, results)
+	_, err = ce.call(ctx, params, results, depth)
 	return err
 }
 
-func (ce *callEngine) call(ctx context.Context, params, results []uint64) (_ []uint64, err error) {
+func (ce *callEngine) call(ctx context.Context, params, results []uint64, depth uint64) (_ []uint64, err error) {
 	m := ce.f.moduleInstance
 	if ce.f.parent.ensureTermination {
 		select {
@@ -519,7 +619,17 @@ func (ce *callEngine) call(ctx context.Context, params, results []uint64) (_ []u
 		}
 	}
 
+	// The deadline is set regardless of ce.f.parent.checkEpoch, as the call can reach functions of other modules
+	// which check it.
+	ce.epochDeadline = m.EpochDeadline(ctx)
+
+	maxCallStackDepth, maxStackBytes := m.StackLimits()
+	ce.maxCallStackDepth, ce.maxStackBytes, ce.callDepth = uint64(maxCallStackDepth), maxStackBytes, depth
+
+	ce.loadFuel()
 	defer func() {
+		ce.storeFuel()
+
 		// If the module closed during the call, and the call didn't err for another reason, set an ExitError.
 		if err == nil {
 			err = m.FailIfClosed()
@@ -548,6 +658,10 @@ func (ce *callEngine) call(ctx context.Context, params, results []uint64) (_ []u
 		results = make([]uint64, ft.ResultNumInUint64)
 	}
 	ce.popValues(results)
+	if len(ce.stack) == 0 {
+		// Release the exceptions caught during the call, as their handles are no longer on the stack.
+		ce.exceptions, ce.exnrefs = ce.exceptions[:0], ce.exnrefs[:0]
+	}
 	return results, nil
 }
 
@@ -589,17 +703,30 @@ func (ce *callEngine) recoverOnCall(ctx context.Context, m *wasm.ModuleInstance,
 	}
 
 	// Allows the reuse of CallEngine.
-	ce.stack, ce.frames = ce.stack[:0], ce.frames[:0]
+	ce.stack, ce.frames, ce.exceptions, ce.exnrefs = ce.stack[:0], ce.frames[:0], ce.exceptions[:0], ce.exnrefs[:0]
 	return
 }
 
 func (ce *callEngine) callFunction(ctx context.Context, m *wasm.ModuleInstance, f *function) {
-	if f.parent.hostFn != nil {
-		ce.callGoFuncWithStack(ctx, m, f)
-	} else if lsn := f.parent.listener; lsn != nil {
-		ce.callNativeFuncWithListener(ctx, m, f, lsn)
-	} else {
-		ce.callNativeFunc(ctx, m, f)
+	// Loop instead of recursing on tail calls, so that they don't grow the Go stack.
+	for f != nil {
+		if f.promoted != nil {
+			if p := f.promoted.Load(); p != nil {
+				ce.callPromoted(ctx, m, f, p.fn)
+				return
+			}
+		}
+		if f.parent.hostFn != nil {
+			ce.callGoFuncWithStack(ctx, m, f)
+			return
+		} else if lsn := f.parent.listener; lsn != nil {
+			ce.callNativeFuncWithListener(ctx, m, f, lsn)
+			return
+		}
+		caller := f
+		if f = ce.callNativeFunc(ctx, m, f); f != nil {
+			m = caller.moduleInstance
+		}
 	}
 }
 
@@ -615,13 +742,18 @@ func (ce *callEngine) callGoFunc(ctx context.Context, m *wasm.ModuleInstance, f
 	frame := &callFrame{f: f, base: len(ce.stack)}
 	ce.pushFrame(frame)
 
+	// Synchronize the fuel, so that the host function can read or add it.
+	ce.storeFuel()
 	fn := f.parent.hostFn
 	switch fn := fn.(type) {
+	case wasm.CallDepthGoFunction:
+		fn.CallWithDepth(ctx, m, stack, ce.callDepth+uint64(len(ce.frames)))
 	case api.GoModuleFunction:
 		fn.Call(ctx, m, stack)
 	case api.GoFunction:
 		fn.Call(ctx, stack)
 	}
+	ce.loadFuel()
 
 	ce.popFrame()
 	if lsn != nil {
@@ -631,8 +763,102 @@ func (ce *callEngine) callGoFunc(ctx context.Context, m *wasm.ModuleInstance, f
 	}
 }
 
-func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance, f *function) {
+// callNativeFunc executes the function `f`, and returns the function it tail calls, if any. In that case, the frame of
+// `f` is already popped and the arguments are on top of the stack, so the caller is expected to call the result.
+func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance, f *function) *function {
+	f.parent.tick()
 	frame := &callFrame{f: f, base: len(ce.stack)}
+	ce.pushFrame(frame)
+	if f.parent.exceptionHandlers == nil {
+		return ce.execNativeFunc(ctx, m, frame)
+	}
+	for {
+		if tf, returned := ce.execNativeFuncCatching(ctx, m, frame); returned {
+			return tf
+		}
+	}
+}
+
+// execNativeFuncCatching is like execNativeFunc, except it catches the exceptions thrown while executing the frame with
+// its exception handlers. When it does so, this returns false to resume the execution at the catch.
+func (ce *callEngine) execNativeFuncCatching(ctx context.Context, m *wasm.ModuleInstance, frame *callFrame) (tf *function, returned bool) {
+	frameCount := len(ce.frames)
+	defer func() {
+		if v := recover(); v != nil {
+			if exc, ok := v.(*api.Exception); !ok || !ce.catch(ctx, frame, frameCount, exc) {
+				panic(v)
+			}
+		}
+	}()
+	return ce.execNativeFunc(ctx, m, frame), true
+}
+
+// catch finds the catch of the exception thrown while executing the frame, which is at frameCount-1 in ce.frames.
+// If found, this unwinds the frames and the stack above the catch, pushes the exception, and moves the program
+// counter to the catch. Otherwise, this returns false.
+func (ce *callEngine) catch(ctx context.Context, frame *callFrame, frameCount int, exc *api.Exception) bool {
+	f := frame.f
+	tags := f.moduleInstance.Tags
+	handler, c, ok := wazeroir.FindExceptionCatch(f.parent.exceptionHandlers, frame.pc, func(index wasm.Index) bool {
+		tag := tags[index]
+		return exc.Tag == api.Tag(tag) && len(exc.Values) == tag.Type.ParamNumInUint64
+	})
+	if !ok {
+		return false
+	}
+
+	for i := len(ce.frames) - 1; i >= frameCount; i-- {
+		if unwound := ce.frames[i].f; unwound.parent.listener != nil {
+			unwound.parent.listener.Abort(ctx, unwound.moduleInstance, unwound.definition(), exc)
+		}
+	}
+	ce.frames = ce.frames[:frameCount]
+	// Synchronize the fuel, as the exception might be thrown by a host function which added it.
+	ce.storeFuel()
+	ce.loadFuel()
+
+	height := frame.base - f.funcType.ParamNumInUint64 + handler.StackHeight
+	ce.stack = ce.stack[:height]
+	if c.Ref == wazeroir.ExceptionRefHandle {
+		ce.pushValue(ce.storeException(exc, height))
+	}
+	if !c.CatchAll {
+		ce.pushValues(exc.Values)
+	}
+	if c.Ref == wazeroir.ExceptionRefExnref {
+		ce.pushValue(ce.storeExnref(exc))
+	}
+	frame.pc = c.Target
+	return true
+}
+
+// storeException stores the exception caught at the height of the stack, and returns its handle.
+func (ce *callEngine) storeException(exc *api.Exception, height int) uint64 {
+	// The handles at or above the height are no longer on the stack, so their indexes are reused.
+	i := len(ce.exceptions)
+	for i > 0 && ce.exceptions[i-1].height >= height {
+		i--
+	}
+	ce.exceptions = append(ce.exceptions[:i], caughtException{exc: exc, height: height})
+	return uint64(i)
+}
+
+// storeExnref returns the wasm.ValueTypeExnref referencing the exception, which is never zero.
+func (ce *callEngine) storeExnref(exc *api.Exception) uint64 {
+	// The same exception is caught again after throw_ref, so reuse its reference.
+	for i := len(ce.exnrefs) - 1; i >= 0; i-- {
+		if ce.exnrefs[i] == exc {
+			return uint64(i + 1)
+		}
+	}
+	ce.exnrefs = append(ce.exnrefs, exc)
+	return uint64(len(ce.exnrefs))
+}
+
+// execNativeFunc executes the function of the frame pushed by callNativeFunc, and returns the function it tail calls
+// as documented on callNativeFunc.
+func (ce *callEngine) execNativeFunc(ctx context.Context, m *wasm.ModuleInstance, frame *callFrame) *function {
+	f := frame.f
 	moduleInst := f.moduleInstance
 	functions := moduleInst.Engine.(*moduleEngine).functions
 	memoryInst := moduleInst.MemoryInstance
@@ -641,7 +867,6 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 	typeIDs := moduleInst.TypeIDs
 	dataInstances := moduleInst.DataInstances
 	elementInstances := moduleInst.ElementInstances
-	ce.pushFrame(frame)
 	body := frame.f.parent.body
 	bodyLen := uint64(len(body))
 	for frame.pc < bodyLen {
@@ -655,12 +880,30 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 				panic(err)
 			}
 			frame.pc++
+		case wazeroir.OperationKindConsumeFuel:
+			cost := int64(op.U1)
+			if ce.fuel < cost {
+				panic(wasmruntime.ErrRuntimeOutOfFuel)
+			}
+			ce.fuel -= cost
+			frame.pc++
+		case wazeroir.OperationKindCheckEpoch:
+			if m.Epoch().Load() >= ce.epochDeadline {
+				panic(wasmruntime.ErrRuntimeEpochInterrupted)
+			}
+			frame.pc++
 		case wazeroir.OperationKindUnreachable:
 			panic(wasmruntime.ErrRuntimeUnreachable)
 		case wazeroir.OperationKindBr:
+			if op.U1 <= frame.pc { // loop
+				f.parent.tick()
+			}
 			frame.pc = op.U1
 		case wazeroir.OperationKindBrIf:
 			if ce.popValue() > 0 {
+				if op.U1 <= frame.pc { // loop
+					f.parent.tick()
+				}
 				ce.drop(op.U3)
 				frame.pc = op.U1
 			} else {
@@ -696,6 +939,37 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 
 			ce.callFunction(ctx, f.moduleInstance, tf)
 			frame.pc++
+		case wazeroir.OperationKindCallRef:
+			rawPtr := ce.popValue()
+			if rawPtr == 0 {
+				panic(wasmruntime.ErrRuntimeNullFunctionReference)
+			}
+
+			tf := functionFromUintptr(uintptr(rawPtr))
+			if tf.typeID != typeIDs[op.U1] {
+				panic(wasmruntime.ErrRuntimeIndirectCallTypeMismatch)
+			}
+
+			ce.callFunction(ctx, f.moduleInstance, tf)
+			frame.pc++
+		case wazeroir.OperationKindTailCall:
+			return ce.tailCall(frame, &functions[op.U1])
+		case wazeroir.OperationKindTailCallIndirect:
+			offset := ce.popValue()
+			table := tables[op.U2]
+			if offset >= uint64(len(table.References)) {
+				panic(wasmruntime.ErrRuntimeInvalidTableAccess)
+			}
+			rawPtr := table.References[offset]
+			if rawPtr == 0 {
+				panic(wasmruntime.ErrRuntimeInvalidTableAccess)
+			}
+
+			tf := functionFromUintptr(rawPtr)
+			if tf.typeID != typeIDs[op.U1] {
+				panic(wasmruntime.ErrRuntimeIndirectCallTypeMismatch)
+			}
+			return ce.tailCall(frame, tf)
 		case wazeroir.OperationKindDrop:
 			ce.drop(op.U1)
 			frame.pc++
@@ -752,13 +1026,13 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 			offset := ce.popMemoryOffset(op)
 			switch wazeroir.UnsignedType(op.B1) {
 			case wazeroir.UnsignedTypeI32, wazeroir.UnsignedTypeF32:
-				if val, ok := memoryInst.ReadUint32Le(offset); !ok {
+				if val, ok := memoryInst.ReadUint32Le64(offset); !ok {
 					panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
 				} else {
 					ce.pushValue(uint64(val))
 				}
 			case wazeroir.UnsignedTypeI64, wazeroir.UnsignedTypeF64:
-				if val, ok := memoryInst.ReadUint64Le(offset); !ok {
+				if val, ok := memoryInst.ReadUint64Le64(offset); !ok {
 					panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
 				} else {
 					ce.pushValue(val)
@@ -766,7 +1040,7 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 			}
 			frame.pc++
 		case wazeroir.OperationKindLoad8:
-			val, ok := memoryInst.ReadByte(ce.popMemoryOffset(op))
+			val, ok := memoryInst.ReadByte64(ce.popMemoryOffset(op))
 			if !ok {
 				panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
 			}
@@ -782,7 +1056,7 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 			frame.pc++
 		case wazeroir.OperationKindLoad16:
 
-			val, ok := memoryInst.ReadUint16Le(ce.popMemoryOffset(op))
+			val, ok := memoryInst.ReadUint16Le64(ce.popMemoryOffset(op))
 			if !ok {
 				panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
 			}
@@ -797,7 +1071,7 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 			}
 			frame.pc++
 		case wazeroir.OperationKindLoad32:
-			val, ok := memoryInst.ReadUint32Le(ce.popMemoryOffset(op))
+			val, ok := memoryInst.ReadUint32Le64(ce.popMemoryOffset(op))
 			if !ok {
 				panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
 			}
@@ -813,11 +1087,11 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 			offset := ce.popMemoryOffset(op)
 			switch wazeroir.UnsignedType(op.B1) {
 			case wazeroir.UnsignedTypeI32, wazeroir.UnsignedTypeF32:
-				if !memoryInst.WriteUint32Le(offset, uint32(val)) {
+				if !memoryInst.WriteUint32Le64(offset, uint32(val)) {
 					panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
 				}
 			case wazeroir.UnsignedTypeI64, wazeroir.UnsignedTypeF64:
-				if !memoryInst.WriteUint64Le(offset, val) {
+				if !memoryInst.WriteUint64Le64(offset, val) {
 					panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
 				}
 			}
@@ -825,21 +1099,21 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 		case wazeroir.OperationKindStore8:
 			val := byte(ce.popValue())
 			offset := ce.popMemoryOffset(op)
-			if !memoryInst.WriteByte(offset, val) {
+			if !memoryInst.WriteByte64(offset, val) {
 				panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
 			}
 			frame.pc++
 		case wazeroir.OperationKindStore16:
 			val := uint16(ce.popValue())
 			offset := ce.popMemoryOffset(op)
-			if !memoryInst.WriteUint16Le(offset, val) {
+			if !memoryInst.WriteUint16Le64(offset, val) {
 				panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
 			}
 			frame.pc++
 		case wazeroir.OperationKindStore32:
 			val := uint32(ce.popValue())
 			offset := ce.popMemoryOffset(op)
-			if !memoryInst.WriteUint32Le(offset, val) {
+			if !memoryInst.WriteUint32Le64(offset, val) {
 				panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
 			}
 			frame.pc++
@@ -848,12 +1122,21 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 			frame.pc++
 		case wazeroir.OperationKindMemoryGrow:
 			n := ce.popValue()
-			if res, ok := memoryInst.Grow(uint32(n)); !ok {
-				ce.pushValue(uint64(0xffffffff)) // = -1 in signed 32-bit integer.
+			if op.B3 && n > math.MaxUint32 { // 64-bit memory can never grow by 2^32 pages or more.
+				ce.pushValue(math.MaxUint64) // = -1 in signed 64-bit integer.
+			} else if res, ok := memoryInst.Grow(uint32(n)); !ok {
+				if op.B3 {
+					ce.pushValue(math.MaxUint64) // = -1 in signed 64-bit integer.
+				} else {
+					ce.pushValue(uint64(0xffffffff)) // = -1 in signed 32-bit integer.
+				}
 			} else {
 				ce.pushValue(uint64(res))
 			}
 			frame.pc++
+		case wazeroir.OperationKindSelectMemory:
+			memoryInst = moduleInst.Memories[op.U1]
+			frame.pc++
 		case wazeroir.OperationKindConstI32, wazeroir.OperationKindConstI64,
 			wazeroir.OperationKindConstF32, wazeroir.OperationKindConstF64:
 			ce.pushValue(op.U1)
@@ -1617,7 +1900,7 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 			inDataOffset := ce.popValue()
 			inMemoryOffset := ce.popValue()
 			if inDataOffset+copySize > uint64(len(dataInstance)) ||
-				inMemoryOffset+copySize > uint64(len(memoryInst.Buffer)) {
+				!inBounds(inMemoryOffset, copySize, memoryInst) {
 				panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
 			} else if copySize != 0 {
 				copy(memoryInst.Buffer[inMemoryOffset:inMemoryOffset+copySize], dataInstance[inDataOffset:])
@@ -1627,22 +1910,25 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 			dataInstances[op.U1] = nil
 			frame.pc++
 		case wazeroir.OperationKindMemoryCopy:
-			memLen := uint64(len(memoryInst.Buffer))
+			dst, src := memoryInst, memoryInst
+			if op.U1 != 0 || op.U2 != 0 {
+				dst, src = moduleInst.Memories[op.U1], moduleInst.Memories[op.U2]
+			}
 			copySize := ce.popValue()
 			sourceOffset := ce.popValue()
 			destinationOffset := ce.popValue()
-			if sourceOffset+copySize > memLen || destinationOffset+copySize > memLen {
+			if !inBounds(sourceOffset, copySize, src) || !inBounds(destinationOffset, copySize, dst) {
 				panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
 			} else if copySize != 0 {
-				copy(memoryInst.Buffer[destinationOffset:],
-					memoryInst.Buffer[sourceOffset:sourceOffset+copySize])
+				copy(dst.Buffer[destinationOffset:],
+					src.Buffer[sourceOffset:sourceOffset+copySize])
 			}
 			frame.pc++
 		case wazeroir.OperationKindMemoryFill:
 			fillSize := ce.popValue()
 			value := byte(ce.popValue())
 			offset := ce.popValue()
-			if fillSize+offset > uint64(len(memoryInst.Buffer)) {
+			if !inBounds(offset, fillSize, memoryInst) {
 				panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
 			} else if fillSize != 0 {
 				// Uses the copy trick for faster filling buffer.
@@ -1684,6 +1970,11 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 		case wazeroir.OperationKindRefFunc:
 			ce.pushValue(uint64(uintptr(unsafe.Pointer(&functions[op.U1]))))
 			frame.pc++
+		case wazeroir.OperationKindRefAsNonNull:
+			if ce.stack[len(ce.stack)-1] == 0 {
+				panic(wasmruntime.ErrRuntimeNullReference)
+			}
+			frame.pc++
 		case wazeroir.OperationKindTableGet:
 			table := tables[op.U1]
 
@@ -1829,18 +2120,18 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 			offset := ce.popMemoryOffset(op)
 			switch op.B1 {
 			case wazeroir.V128LoadType128:
-				lo, ok := memoryInst.ReadUint64Le(offset)
+				lo, ok := memoryInst.ReadUint64Le64(offset)
 				if !ok {
 					panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
 				}
 				ce.pushValue(lo)
-				hi, ok := memoryInst.ReadUint64Le(offset + 8)
+				hi, ok := memoryInst.ReadUint64Le64(offset + 8)
 				if !ok {
 					panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
 				}
 				ce.pushValue(hi)
 			case wazeroir.V128LoadType8x8s:
-				data, ok := memoryInst.Read(offset, 8)
+				data, ok := memoryInst.Read64(offset, 8)
 				if !ok {
 					panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
 				}
@@ -1851,7 +2142,7 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 					uint64(uint16(int8(data[7])))<<48 | uint64(uint16(int8(data[6])))<<32 | uint64(uint16(int8(data[5])))<<16 | uint64(uint16(int8(data[4]))),
 				)
 			case wazeroir.V128LoadType8x8u:
-				data, ok := memoryInst.Read(offset, 8)
+				data, ok := memoryInst.Read64(offset, 8)
 				if !ok {
 					panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
 				}
@@ -1862,7 +2153,7 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 					uint64(data[7])<<48 | uint64(data[6])<<32 | uint64(data[5])<<16 | uint64(data[4]),
 				)
 			case wazeroir.V128LoadType16x4s:
-				data, ok := memoryInst.Read(offset, 8)
+				data, ok := memoryInst.Read64(offset, 8)
 				if !ok {
 					panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
 				}
@@ -1875,7 +2166,7 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 						uint64(uint32(int16(binary.LittleEndian.Uint16(data[4:])))),
 				)
 			case wazeroir.V128LoadType16x4u:
-				data, ok := memoryInst.Read(offset, 8)
+				data, ok := memoryInst.Read64(offset, 8)
 				if !ok {
 					panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
 				}
@@ -1886,21 +2177,21 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 					uint64(binary.LittleEndian.Uint16(data[6:]))<<32 | uint64(binary.LittleEndian.Uint16(data[4:])),
 				)
 			case wazeroir.V128LoadType32x2s:
-				data, ok := memoryInst.Read(offset, 8)
+				data, ok := memoryInst.Read64(offset, 8)
 				if !ok {
 					panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
 				}
 				ce.pushValue(uint64(int32(binary.LittleEndian.Uint32(data))))
 				ce.pushValue(uint64(int32(binary.LittleEndian.Uint32(data[4:]))))
 			case wazeroir.V128LoadType32x2u:
-				data, ok := memoryInst.Read(offset, 8)
+				data, ok := memoryInst.Read64(offset, 8)
 				if !ok {
 					panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
 				}
 				ce.pushValue(uint64(binary.LittleEndian.Uint32(data)))
 				ce.pushValue(uint64(binary.LittleEndian.Uint32(data[4:])))
 			case wazeroir.V128LoadType8Splat:
-				v, ok := memoryInst.ReadByte(offset)
+				v, ok := memoryInst.ReadByte64(offset)
 				if !ok {
 					panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
 				}
@@ -1909,7 +2200,7 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 				ce.pushValue(v8)
 				ce.pushValue(v8)
 			case wazeroir.V128LoadType16Splat:
-				v, ok := memoryInst.ReadUint16Le(offset)
+				v, ok := memoryInst.ReadUint16Le64(offset)
 				if !ok {
 					panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
 				}
@@ -1917,7 +2208,7 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 				ce.pushValue(v4)
 				ce.pushValue(v4)
 			case wazeroir.V128LoadType32Splat:
-				v, ok := memoryInst.ReadUint32Le(offset)
+				v, ok := memoryInst.ReadUint32Le64(offset)
 				if !ok {
 					panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
 				}
@@ -1925,21 +2216,21 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 				ce.pushValue(vv)
 				ce.pushValue(vv)
 			case wazeroir.V128LoadType64Splat:
-				lo, ok := memoryInst.ReadUint64Le(offset)
+				lo, ok := memoryInst.ReadUint64Le64(offset)
 				if !ok {
 					panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
 				}
 				ce.pushValue(lo)
 				ce.pushValue(lo)
 			case wazeroir.V128LoadType32zero:
-				lo, ok := memoryInst.ReadUint32Le(offset)
+				lo, ok := memoryInst.ReadUint32Le64(offset)
 				if !ok {
 					panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
 				}
 				ce.pushValue(uint64(lo))
 				ce.pushValue(0)
 			case wazeroir.V128LoadType64zero:
-				lo, ok := memoryInst.ReadUint64Le(offset)
+				lo, ok := memoryInst.ReadUint64Le64(offset)
 				if !ok {
 					panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
 				}
@@ -1952,7 +2243,7 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 			offset := ce.popMemoryOffset(op)
 			switch op.B1 {
 			case 8:
-				b, ok := memoryInst.ReadByte(offset)
+				b, ok := memoryInst.ReadByte64(offset)
 				if !ok {
 					panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
 				}
@@ -1964,7 +2255,7 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 					hi = (hi & ^(0xff << s)) | uint64(b)<<s
 				}
 			case 16:
-				b, ok := memoryInst.ReadUint16Le(offset)
+				b, ok := memoryInst.ReadUint16Le64(offset)
 				if !ok {
 					panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
 				}
@@ -1976,7 +2267,7 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 					hi = (hi & ^(0xff_ff << s)) | uint64(b)<<s
 				}
 			case 32:
-				b, ok := memoryInst.ReadUint32Le(offset)
+				b, ok := memoryInst.ReadUint32Le64(offset)
 				if !ok {
 					panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
 				}
@@ -1988,7 +2279,7 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 					hi = (hi & ^(0xff_ff_ff_ff << s)) | uint64(b)<<s
 				}
 			case 64:
-				b, ok := memoryInst.ReadUint64Le(offset)
+				b, ok := memoryInst.ReadUint64Le64(offset)
 				if !ok {
 					panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
 				}
@@ -2004,10 +2295,10 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 		case wazeroir.OperationKindV128Store:
 			hi, lo := ce.popValue(), ce.popValue()
 			offset := ce.popMemoryOffset(op)
-			if ok := memoryInst.WriteUint64Le(offset, lo); !ok {
+			if ok := memoryInst.WriteUint64Le64(offset, lo); !ok {
 				panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
 			}
-			if ok := memoryInst.WriteUint64Le(offset+8, hi); !ok {
+			if ok := memoryInst.WriteUint64Le64(offset+8, hi); !ok {
 				panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
 			}
 			frame.pc++
@@ -2018,27 +2309,27 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 			switch op.B1 {
 			case 8:
 				if op.B2 < 8 {
-					ok = memoryInst.WriteByte(offset, byte(lo>>(op.B2*8)))
+					ok = memoryInst.WriteByte64(offset, byte(lo>>(op.B2*8)))
 				} else {
-					ok = memoryInst.WriteByte(offset, byte(hi>>((op.B2-8)*8)))
+					ok = memoryInst.WriteByte64(offset, byte(hi>>((op.B2-8)*8)))
 				}
 			case 16:
 				if op.B2 < 4 {
-					ok = memoryInst.WriteUint16Le(offset, uint16(lo>>(op.B2*16)))
+					ok = memoryInst.WriteUint16Le64(offset, uint16(lo>>(op.B2*16)))
 				} else {
-					ok = memoryInst.WriteUint16Le(offset, uint16(hi>>((op.B2-4)*16)))
+					ok = memoryInst.WriteUint16Le64(offset, uint16(hi>>((op.B2-4)*16)))
 				}
 			case 32:
 				if op.B2 < 2 {
-					ok = memoryInst.WriteUint32Le(offset, uint32(lo>>(op.B2*32)))
+					ok = memoryInst.WriteUint32Le64(offset, uint32(lo>>(op.B2*32)))
 				} else {
-					ok = memoryInst.WriteUint32Le(offset, uint32(hi>>((op.B2-2)*32)))
+					ok = memoryInst.WriteUint32Le64(offset, uint32(hi>>((op.B2-2)*32)))
 				}
 			case 64:
 				if op.B2 == 0 {
-					ok = memoryInst.WriteUint64Le(offset, lo)
+					ok = memoryInst.WriteUint64Le64(offset, lo)
 				} else {
-					ok = memoryInst.WriteUint64Le(offset, hi)
+					ok = memoryInst.WriteUint64Le64(offset, hi)
 				}
 			}
 			if !ok {
@@ -3825,6 +4116,99 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 					(uint64(uint32(int32(int16(x1Hi>>32))*int32(int16(x2Hi>>32))+int32(int16(x1Hi>>48))*int32(int16(x2Hi>>48)))) << 32),
 			)
 			frame.pc++
+		case wazeroir.OperationKindV128RelaxedMadd:
+			zHi, zLo := ce.popValue(), ce.popValue()
+			yHi, yLo := ce.popValue(), ce.popValue()
+			xHi, xLo := ce.popValue(), ce.popValue()
+			negate := op.B3
+			var retLo, retHi uint64
+			switch op.B1 {
+			case wazeroir.ShapeF32x4:
+				for i := 0; i < 4; i++ {
+					x, y, z, shift := xLo, yLo, zLo, i*32
+					if i >= 2 {
+						x, y, z, shift = xHi, yHi, zHi, (i-2)*32
+					}
+					// The explicit conversion rounds the product, so that Go doesn't fuse the multiplication and addition.
+					m := float32(math.Float32frombits(uint32(x>>shift)) * math.Float32frombits(uint32(y>>shift)))
+					if negate {
+						m = -m
+					}
+					v := uint64(math.Float32bits(m+math.Float32frombits(uint32(z>>shift)))) << shift
+					if i < 2 {
+						retLo |= v
+					} else {
+						retHi |= v
+					}
+				}
+			case wazeroir.ShapeF64x2:
+				for i, xyz := range [2][3]uint64{{xLo, yLo, zLo}, {xHi, yHi, zHi}} {
+					m := float64(math.Float64frombits(xyz[0]) * math.Float64frombits(xyz[1]))
+					if negate {
+						m = -m
+					}
+					v := math.Float64bits(m + math.Float64frombits(xyz[2]))
+					if i == 0 {
+						retLo = v
+					} else {
+						retHi = v
+					}
+				}
+			}
+			ce.pushValue(retLo)
+			ce.pushValue(retHi)
+			frame.pc++
+		case wazeroir.OperationKindV128RelaxedDot:
+			var zHi, zLo uint64
+			if op.B3 {
+				zHi, zLo = ce.popValue(), ce.popValue()
+			}
+			x2Hi, x2Lo := ce.popValue(), ce.popValue()
+			x1Hi, x1Lo := ce.popValue(), ce.popValue()
+
+			// Both operands are treated as signed, and adjacent products are added with signed saturation.
+			var dot [8]int16
+			for i := range dot {
+				x1, x2, shift := x1Lo, x2Lo, i*16
+				if i >= 4 {
+					x1, x2, shift = x1Hi, x2Hi, (i-4)*16
+				}
+				v := int32(int8(x1>>shift))*int32(int8(x2>>shift)) +
+					int32(int8(x1>>(shift+8)))*int32(int8(x2>>(shift+8)))
+				if v > math.MaxInt16 {
+					v = math.MaxInt16
+				} else if v < math.MinInt16 {
+					v = math.MinInt16
+				}
+				dot[i] = int16(v)
+			}
+
+			var retLo, retHi uint64
+			if op.B3 {
+				for i := 0; i < 4; i++ {
+					z, shift := zLo, i*32
+					if i >= 2 {
+						z, shift = zHi, (i-2)*32
+					}
+					v := uint64(uint32(int32(dot[2*i])+int32(dot[2*i+1])+int32(uint32(z>>shift)))) << shift
+					if i < 2 {
+						retLo |= v
+					} else {
+						retHi |= v
+					}
+				}
+			} else {
+				for i, v := range dot {
+					if i < 4 {
+						retLo |= uint64(uint16(v)) << (i * 16)
+					} else {
+						retHi |= uint64(uint16(v)) << ((i - 4) * 16)
+					}
+				}
+			}
+			ce.pushValue(retLo)
+			ce.pushValue(retHi)
+			frame.pc++
 		case wazeroir.OperationKindV128ITruncSatFromF:
 			hi, lo := ce.popValue(), ce.popValue()
 			signed := op.B3
@@ -3896,11 +4280,100 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 			ce.pushValue(retLo)
 			ce.pushValue(retHi)
 			frame.pc++
+		case wazeroir.OperationKindAtomicMemoryWait:
+			timeout := int64(ce.popValue())
+			expected := ce.popValue()
+			offset := ce.popAtomicAddress(op)
+			var res uint64
+			var err error
+			if wazeroir.UnsignedType(op.B1) == wazeroir.UnsignedTypeI32 {
+				res, err = memoryInst.AtomicWait32(offset, uint32(expected), timeout, ce.f.moduleInstance)
+			} else {
+				res, err = memoryInst.AtomicWait64(offset, expected, timeout, ce.f.moduleInstance)
+			}
+			if err != nil {
+				panic(err)
+			}
+			ce.pushValue(res)
+			frame.pc++
+		case wazeroir.OperationKindAtomicMemoryNotify:
+			count := ce.popValue()
+			offset := ce.popAtomicAddress(op)
+			res, err := memoryInst.AtomicNotify(offset, uint32(count))
+			if err != nil {
+				panic(err)
+			}
+			ce.pushValue(uint64(res))
+			frame.pc++
+		case wazeroir.OperationKindAtomicFence:
+			// All the atomic instructions are sequentially consistent, so there's nothing to do.
+			frame.pc++
+		case wazeroir.OperationKindAtomicLoad:
+			offset := ce.popAtomicAddress(op)
+			val, err := memoryInst.AtomicLoad(offset, 1<<op.U1)
+			if err != nil {
+				panic(err)
+			}
+			ce.pushValue(val)
+			frame.pc++
+		case wazeroir.OperationKindAtomicStore:
+			val := ce.popValue()
+			offset := ce.popAtomicAddress(op)
+			if err := memoryInst.AtomicStore(offset, 1<<op.U1, val); err != nil {
+				panic(err)
+			}
+			frame.pc++
+		case wazeroir.OperationKindAtomicRMW:
+			val := ce.popValue()
+			offset := ce.popAtomicAddress(op)
+			old, err := memoryInst.AtomicRMW(offset, 1<<op.U1, val, wazeroir.AtomicArithmeticOp(op.B2).Apply)
+			if err != nil {
+				panic(err)
+			}
+			ce.pushValue(old)
+			frame.pc++
+		case wazeroir.OperationKindAtomicRMWCmpxchg:
+			replacement := ce.popValue()
+			expected := ce.popValue()
+			offset := ce.popAtomicAddress(op)
+			old, err := memoryInst.AtomicCompareExchange(offset, 1<<op.U1, expected, replacement)
+			if err != nil {
+				panic(err)
+			}
+			ce.pushValue(old)
+			frame.pc++
+		case wazeroir.OperationKindThrow:
+			tag := moduleInst.Tags[op.U1]
+			values := make([]uint64, tag.Type.ParamNumInUint64)
+			ce.popValues(values)
+			panic(&api.Exception{Tag: tag, Values: values})
+		case wazeroir.OperationKindRethrow:
+			handle := ce.stack[len(ce.stack)-1-int(op.U1)]
+			panic(ce.exceptions[handle].exc)
+		case wazeroir.OperationKindThrowRef:
+			ref := ce.popValue()
+			if ref == 0 {
+				panic(wasmruntime.ErrRuntimeNullReference)
+			}
+			panic(ce.exnrefs[ref-1])
 		default:
+			// This includes wazeroir.OperationKindTry and wazeroir.OperationKindCatch, which are handled by catch.
 			frame.pc++
 		}
 	}
 	ce.popFrame()
+	return nil
+}
+
+// tailCall moves the arguments of `tf` on top of the stack to where the parameters of the current frame start, and
+// pops the frame, so that `tf` reuses its stack space.
+func (ce *callEngine) tailCall(frame *callFrame, tf *function) *function {
+	paramLen := tf.funcType.ParamNumInUint64
+	base := frame.base - frame.f.funcType.ParamNumInUint64
+	copy(ce.stack[base:], ce.stack[len(ce.stack)-paramLen:])
+	ce.stack = ce.stack[:base+paramLen]
+	ce.popFrame()
+	return tf
 }
 
 func WasmCompatMax32bits(v1, v2 uint32) uint64 {
@@ -4105,20 +4578,48 @@ func (ce *callEngine) callNativeFuncWithListener(ctx context.Context, m *wasm.Mo
 	ce.stackIterator.reset(ce.stack, ce.frames, f)
 	fnl.Before(ctx, m, def, ce.peekValues(len(typ.Params)), &ce.stackIterator)
 	ce.stackIterator.clear()
-	ce.callNativeFunc(ctx, m, f)
+	if tf := ce.callNativeFunc(ctx, m, f); tf != nil {
+		// Call the tail callee as a nested call, so that After receives the results. This grows the stack, unlike
+		// tail calls without listeners.
+		ce.callFunction(ctx, f.moduleInstance, tf)
+	}
 	fnl.After(ctx, m, def, ce.peekValues(len(typ.Results)))
 	return ctx
 }
 
 // popMemoryOffset takes a memory offset off the stack for use in load and store instructions.
-// As the top of stack value is 64-bit, this ensures it is in range before returning it.
-func (ce *callEngine) popMemoryOffset(op *wazeroir.UnionOperation) uint32 {
-	// TODO: Document what 'us' is and why we expect to look at value 1.
+// As the effective address can exceed the address space of the memory, this ensures it is in range before returning it.
+func (ce *callEngine) popMemoryOffset(op *wazeroir.UnionOperation) uint64 {
+	offset := op.U2 + ce.popValue()
+	if op.B3 { // 64-bit memory: the effective address must not wrap around.
+		if offset < op.U2 {
+			panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+		}
+	} else if offset > math.MaxUint32 {
+		panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+	}
+	return offset
+}
+
+// inBounds returns true if byteCount bytes at offset are within the memory. This is the same as
+// offset+byteCount <= len(mem.Buffer), except that it handles the addition overflowing with operands of 64-bit memories.
+func inBounds(offset, byteCount uint64, mem *wasm.MemoryInstance) bool {
+	size := uint64(len(mem.Buffer))
+	return byteCount <= size && offset <= size-byteCount
+}
+
+// popAtomicAddress pops the address operand of the atomic instruction, and returns the effective address.
+// Unlike popMemoryOffset, this doesn't check the bounds, since the alignment must be checked first. The only exception
+// is the effective address of a 64-bit memory wrapping around, which is out of bounds regardless of the alignment.
+func (ce *callEngine) popAtomicAddress(op *wazeroir.UnionOperation) uint64 {
+	if !op.B3 {
+		return op.U2 + uint64(uint32(ce.popValue()))
+	}
 	offset := op.U2 + ce.popValue()
-	if offset > math.MaxUint32 {
+	if offset < op.U2 {
 		panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
 	}
-	return uint32(offset)
+	return offset
 }
 
 func (ce *callEngine) callGoFuncWithStack(ctx context.Context, m *wasm.ModuleInstance, f *function) {
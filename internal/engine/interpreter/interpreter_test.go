@@ -177,6 +177,10 @@ func TestInterpreter_ModuleEngine_Memory(t *testing.T) {
 	enginetest.RunTestModuleEngineMemory(t, et)
 }
 
+func TestInterpreter_ModuleEngine_MaxCallStackDepth(t *testing.T) {
+	enginetest.RunTestModuleEngineMaxCallStackDepth(t, et)
+}
+
 func TestInterpreter_NonTrappingFloatToIntConversion(t *testing.T) {
 	_0x80000000 := uint32(0x80000000)
 	_0xffffffff := uint32(0xffffffff)
@@ -515,7 +519,7 @@ func TestInterpreter_Compile(t *testing.T) {
 			ID: wasm.ModuleID{},
 		}
 
-		err := e.CompileModule(testCtx, errModule, nil, false)
+		err := e.CompileModule(testCtx, errModule, nil, false, false, false)
 		require.EqualError(t, err, "handling instruction: apply stack failed for call: reading immediates: EOF")
 
 		// On the compilation failure, all the compiled functions including succeeded ones must be released.
@@ -536,7 +540,7 @@ func TestInterpreter_Compile(t *testing.T) {
 			},
 			ID: wasm.ModuleID{},
 		}
-		err := e.CompileModule(testCtx, okModule, nil, false)
+		err := e.CompileModule(testCtx, okModule, nil, false, false, false)
 		require.NoError(t, err)
 
 		compiled, ok := e.compiledFunctions[okModule.ID]
@@ -581,3 +585,68 @@ func TestCompiler_BeforeListenerStackIteratorSourceOffset(t *testing.T) {
 func TestCompiler_BeforeListenerGlobals(t *testing.T) {
 	enginetest.RunTestModuleEngineBeforeListenerGlobals(t, et)
 }
+
+func TestNewTieringEngine(t *testing.T) {
+	var hot []*wasm.Module
+	e := NewTieringEngine(testCtx, api.CoreFeaturesV2, nil, 3, func(m *wasm.Module, index wasm.Index) {
+		require.Equal(t, wasm.Index(0), index)
+		hot = append(hot, m)
+	})
+
+	// Loops until the param is zero.
+	m := &wasm.Module{
+		TypeSection:     []wasm.FunctionType{{Params: []wasm.ValueType{wasm.ValueTypeI32}}},
+		FunctionSection: []wasm.Index{0},
+		CodeSection: []wasm.Code{{Body: []byte{
+			wasm.OpcodeLoop, 0x40,
+			wasm.OpcodeLocalGet, 0, wasm.OpcodeI32Const, 1, wasm.OpcodeI32Sub, wasm.OpcodeLocalTee, 0,
+			wasm.OpcodeBrIf, 0,
+			wasm.OpcodeEnd,
+			wasm.OpcodeEnd,
+		}}},
+	}
+	require.NoError(t, m.Validate(api.CoreFeaturesV2))
+	require.NoError(t, e.CompileModule(testCtx, m, nil, false, false, false))
+	instance := &wasm.ModuleInstance{TypeIDs: []wasm.FunctionTypeID{0}}
+	me, err := e.NewModuleEngine(m, instance)
+	require.NoError(t, err)
+	instance.Engine = me
+	require.True(t, IsModuleEngine(me))
+
+	// One call and a loop.
+	_, err = me.NewFunction(0).Call(testCtx, 2)
+	require.NoError(t, err)
+	require.Zero(t, len(hot))
+
+	// The module is reported once the function is hot.
+	_, err = me.NewFunction(0).Call(testCtx, 1)
+	require.NoError(t, err)
+	require.Equal(t, []*wasm.Module{m}, hot)
+	_, err = me.NewFunction(0).Call(testCtx, 10)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(hot))
+
+	// Once promoted, calls of the function call the promoted one instead.
+	promoted := &promotedFunc{}
+	Promote(me, 0, promoted)
+	_, err = me.NewFunction(0).Call(testCtx, 4)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{4}, promoted.params)
+	require.Equal(t, []uint64{0}, promoted.depths)
+}
+
+// promotedFunc implements wasm.CallDepthGoFunction, recording the parameters and call depths it is called with.
+type promotedFunc struct {
+	params, depths []uint64
+}
+
+// Call implements api.GoModuleFunction.
+func (f *promotedFunc) Call(ctx context.Context, mod api.Module, stack []uint64) {
+	f.CallWithDepth(ctx, mod, stack, 0)
+}
+
+// CallWithDepth implements wasm.CallDepthGoFunction.
+func (f *promotedFunc) CallWithDepth(_ context.Context, _ api.Module, stack []uint64, depth uint64) {
+	f.params = append(f.params, stack[0])
+	f.depths = append(f.depths, depth)
+}
@@ -0,0 +1,69 @@
+package interpreter
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/filecache"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// tiering reports functions which ran often enough to be worth compiling.
+type tiering struct {
+	threshold uint32
+	hot       func(*wasm.Module, wasm.Index)
+}
+
+// promotion is the function called instead of an interpreted one, see Promote.
+type promotion struct {
+	fn wasm.CallDepthGoFunction
+}
+
+// NewTieringEngine is like NewEngine, except hot is called with the source module and index of a function once it was
+// called, or branched back to a loop, threshold times. This is called on the goroutine running the function, so must
+// not block.
+func NewTieringEngine(ctx context.Context, enabledFeatures api.CoreFeatures, fileCache filecache.Cache, threshold uint32, hot func(*wasm.Module, wasm.Index)) wasm.Engine {
+	e := NewEngine(ctx, enabledFeatures, fileCache).(*engine)
+	e.tiering = &tiering{threshold: threshold, hot: hot}
+	return e
+}
+
+// IsModuleEngine returns true if the wasm.ModuleEngine was created by an engine of this package.
+func IsModuleEngine(me wasm.ModuleEngine) bool {
+	_, ok := me.(*moduleEngine)
+	return ok
+}
+
+// Promote makes the calls of the function at the given index, defined by the module of the wasm.ModuleEngine created
+// by NewTieringEngine, call fn instead. This includes calls from module instances importing it, while calls already
+// executing the function keep interpreting it.
+//
+// fn is called with the parameters of the function, and the count of functions executing before it as call depth.
+func Promote(me wasm.ModuleEngine, index wasm.Index, fn wasm.CallDepthGoFunction) {
+	me.(*moduleEngine).functions[index].promoted.Store(&promotion{fn: fn})
+}
+
+// tick counts a call of, or a loop in, the function, and reports it once it is hot.
+func (f *compiledFunction) tick() {
+	if t := f.tiering; t != nil && atomic.AddUint32(&f.hits, 1) == t.threshold {
+		t.hot(f.source, f.index)
+	}
+}
+
+// callPromoted calls fn, which f was promoted to, with the parameters of f on top of the stack. Unlike for host
+// functions, no frame is pushed, as fn counts f towards the call depth.
+func (ce *callEngine) callPromoted(ctx context.Context, m *wasm.ModuleInstance, f *function, fn wasm.CallDepthGoFunction) {
+	paramLen, resultLen := f.funcType.ParamNumInUint64, f.funcType.ResultNumInUint64
+	stackLen := paramLen
+	for ; stackLen < resultLen; stackLen++ {
+		ce.stack = append(ce.stack, 0)
+	}
+
+	// Synchronize the fuel, as fn consumes it from the same module.
+	ce.storeFuel()
+	fn.CallWithDepth(ctx, m, ce.stack[len(ce.stack)-stackLen:], ce.callDepth+uint64(len(ce.frames)))
+	ce.loadFuel()
+
+	ce.stack = ce.stack[:len(ce.stack)-stackLen+resultLen]
+}
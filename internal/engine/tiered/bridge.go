@@ -0,0 +1,205 @@
+package tiered
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/engine/interpreter"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// counterpart is the module engine of an instance for the engine it wasn't instantiated with.
+type counterpart struct {
+	me wasm.ModuleEngine
+	// bridge is the host module calling the functions of a guest instance, or nil for a host instance, whose module
+	// is compiled by both engines.
+	bridge *wasm.Module
+	// indices maps the function indexes of the guest instance to those in bridge.
+	indices map[wasm.Index]wasm.Index
+}
+
+// LinkImportedFunction implements wasm.ImportLinker.
+//
+// When the imported instance was instantiated by another engine than the importing one, this returns a module engine
+// of the same engine as the importing one: For a host module, this is a module engine of the host instance itself, so
+// that host functions are called with the importing module like usual. For a guest module, this is a bridge calling
+// the function with api.Function, which is slower than a direct call.
+func (e *engine) LinkImportedFunction(importing *wasm.ModuleInstance, index wasm.Index, imported *wasm.ModuleInstance, indexInImportedModule wasm.Index) (wasm.ModuleEngine, wasm.Index, error) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	me, importedIndex, err := e.link(interpreter.IsModuleEngine(importing.Engine), imported, indexInImportedModule)
+	if err != nil {
+		return nil, 0, err
+	}
+	// Record the import, so that the compiled functions promoted in the instance can call it too.
+	if i, ok := e.instances[importing]; ok {
+		i.imports[index] = importedFunction{instance: imported, index: indexInImportedModule}
+		if i.linked++; i.linked == len(i.imports) {
+			e.promote(i, i.module.hot...)
+		}
+	}
+	return me, importedIndex, nil
+}
+
+// link returns the module engine and function index to import the function at the given index of the imported
+// instance, into an instance of the interpreter if interpreted is true, or of the compiler otherwise.
+func (e *engine) link(interpreted bool, imported *wasm.ModuleInstance, index wasm.Index) (wasm.ModuleEngine, wasm.Index, error) {
+	if interpreted == interpreter.IsModuleEngine(imported.Engine) {
+		return imported.Engine, index, nil
+	}
+	target := e.compiler
+	if interpreted {
+		target = e.interpreter
+	}
+
+	c, ok := e.counterparts[imported]
+	if !ok {
+		// Checked while holding mux, so that ReleaseModuleInstance deletes the counterpart otherwise.
+		if err := imported.FailIfClosed(); err != nil {
+			return nil, 0, err
+		}
+		var err error
+		if c, err = newCounterpart(target, imported); err != nil {
+			return nil, 0, err
+		}
+		e.counterparts[imported] = c
+	}
+	if c.bridge != nil {
+		index = c.indices[index]
+	}
+	return c.me, index, nil
+}
+
+// ReleaseModuleInstance implements wasm.ImportLinker.
+//
+// This deletes the counterpart of the instance, as it can't be imported anymore, and stops promoting its functions.
+func (e *engine) ReleaseModuleInstance(m *wasm.ModuleInstance) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	delete(e.instances, m)
+	c, ok := e.counterparts[m]
+	if !ok {
+		return
+	}
+	if c.bridge != nil {
+		if interpreter.IsModuleEngine(c.me) {
+			e.interpreter.DeleteCompiledModule(c.bridge)
+		} else {
+			e.compiler.DeleteCompiledModule(c.bridge)
+		}
+	}
+	delete(e.counterparts, m)
+}
+
+func newCounterpart(target wasm.Engine, instance *wasm.ModuleInstance) (*counterpart, error) {
+	if instance.Source.IsHostModule {
+		me, err := target.NewModuleEngine(instance.Source, instance)
+		if err != nil {
+			return nil, err
+		}
+		return &counterpart{me: me}, nil
+	}
+
+	bridge, indices := newBridge(instance)
+	if err := target.CompileModule(context.Background(), bridge, nil, false, false, false); err != nil {
+		return nil, fmt.Errorf("failed to compile bridge to %s: %w", instance.ModuleName, err)
+	}
+	me, err := target.NewModuleEngine(bridge, &wasm.ModuleInstance{
+		ModuleName: instance.ModuleName,
+		TypeIDs:    instance.TypeIDs,
+		Source:     bridge,
+	})
+	if err != nil {
+		target.DeleteCompiledModule(bridge)
+		return nil, err
+	}
+	return &counterpart{me: me, bridge: bridge, indices: indices}, nil
+}
+
+// newBridge returns a host module whose functions call the exported functions of the guest instance, and the
+// indexes of these functions in the host module.
+func newBridge(instance *wasm.ModuleInstance) (*wasm.Module, map[wasm.Index]wasm.Index) {
+	source := instance.Source
+	bridge := &wasm.Module{
+		TypeSection:  source.TypeSection,
+		NameSection:  &wasm.NameSection{ModuleName: instance.ModuleName},
+		IsHostModule: true,
+	}
+	indices := map[wasm.Index]wasm.Index{}
+	for i := range source.ExportSection {
+		exp := &source.ExportSection[i]
+		if exp.Type != wasm.ExternTypeFunc {
+			continue
+		}
+		if _, ok := indices[exp.Index]; ok {
+			continue
+		}
+		indices[exp.Index] = wasm.Index(len(bridge.FunctionSection))
+		bridge.FunctionSection = append(bridge.FunctionSection, typeIndexOf(source, exp.Index))
+		bridge.CodeSection = append(bridge.CodeSection, wasm.Code{GoFunc: newCrossing(instance.Engine, exp.Index)})
+		bridge.NameSection.FunctionNames = append(bridge.NameSection.FunctionNames, wasm.NameAssoc{
+			Index: indices[exp.Index],
+			Name:  exp.Name,
+		})
+	}
+	// Like host modules, the ID is unique per bridge, as the functions are bound to the instance.
+	bridge.AssignModuleID([]byte(fmt.Sprintf("@@@@@@@@%p", bridge)), false, false, false, false)
+	return bridge, indices
+}
+
+// typeIndexOf returns the type index of the function at the given index, which may be imported.
+func typeIndexOf(m *wasm.Module, index wasm.Index) wasm.Index {
+	if index >= m.ImportFunctionCount {
+		return m.FunctionSection[index-m.ImportFunctionCount]
+	}
+	for i := range m.ImportSection {
+		imp := &m.ImportSection[i]
+		if imp.Type == wasm.ExternTypeFunc && imp.IndexPerType == index {
+			return imp.DescFunc
+		}
+	}
+	panic(fmt.Sprintf("BUG: function %d not found", index))
+}
+
+// crossing is a host function calling the function at index of a module engine of the other engine, for a bridge or a
+// promoted function.
+type crossing struct {
+	me    wasm.ModuleEngine
+	index wasm.Index
+	// calls are the idle api.Function of the function, as calls may be concurrent.
+	calls sync.Pool
+}
+
+func newCrossing(me wasm.ModuleEngine, index wasm.Index) *crossing {
+	c := &crossing{me: me, index: index}
+	c.calls.New = func() interface{} {
+		return me.NewFunction(index)
+	}
+	return c
+}
+
+// Call implements api.GoModuleFunction.
+func (c *crossing) Call(ctx context.Context, mod api.Module, stack []uint64) {
+	c.CallWithDepth(ctx, mod, stack, 0)
+}
+
+// CallWithDepth implements wasm.CallDepthGoFunction.
+//
+// The call continues from the given depth, so that the functions executing in both engines count towards
+// wazero.RuntimeConfig WithMaxCallStackDepth.
+func (c *crossing) CallWithDepth(ctx context.Context, _ api.Module, stack []uint64, depth uint64) {
+	f := c.calls.Get().(wasm.CallDepthFunction)
+	err := f.CallWithStackAndDepth(ctx, stack, depth)
+	c.calls.Put(f)
+	if err != nil {
+		// Throw a guest exception again, instead of the error wrapping it, so that the caller can catch it.
+		var exc *api.Exception
+		if errors.As(err, &exc) {
+			panic(exc)
+		}
+		panic(err)
+	}
+}
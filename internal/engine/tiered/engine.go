@@ -0,0 +1,336 @@
+// Package tiered implements wasm.Engine by starting modules in the interpreter, and compiling hot ones with the
+// compiler in the background.
+//
+// Modules are compiled by the interpreter, which counts the calls of, and loops in, each function. Once a function
+// ran hotThreshold times, its module is compiled with the compiler on another goroutine. Instances created after
+// that compilation finished use the compiled code. In existing instances, hot functions are promoted: their calls
+// call the compiled function instead, while calls already running keep being interpreted.
+//
+// References are specific to an engine, so modules importing or exporting tables or reference globals are never
+// compiled, as they may share references with instances of the other engine. For the same reason, functions are only
+// promoted in instances of modules without tables and references, see swappable. Functions imported from an
+// instance of the other engine are called through a host function, see bridge.
+package tiered
+
+import (
+	"context"
+	"sync"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/internal/engine/compiler"
+	"github.com/tetratelabs/wazero/internal/engine/interpreter"
+	"github.com/tetratelabs/wazero/internal/filecache"
+	"github.com/tetratelabs/wazero/internal/platform"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// hotThreshold is the count of calls of, or loops in, a function after which its module is compiled.
+const hotThreshold = 1000
+
+type (
+	// engine implements wasm.Engine and wasm.ImportLinker.
+	engine struct {
+		interpreter wasm.Engine
+		// compiler is nil if the platform doesn't support it, in which case modules are only interpreted.
+		compiler wasm.Engine
+
+		// modules are the guest modules compiled by CompileModule, guarded by mux.
+		modules map[wasm.ModuleID]*module
+		// counterparts are the module engines of the other engine for imported instances, guarded by mux.
+		counterparts map[*wasm.ModuleInstance]*counterpart
+		// instances are the interpreted instances of swappable modules, whose hot functions are promoted, guarded by
+		// mux.
+		instances map[*wasm.ModuleInstance]*instance
+		mux       sync.Mutex
+		// compiling tracks the compilations in the background, which Close waits for.
+		compiling sync.WaitGroup
+	}
+
+	// module is a guest module compiled by the interpreter.
+	module struct {
+		source                                   *wasm.Module
+		listeners                                []experimental.FunctionListener
+		ensureTermination, meterFuel, checkEpoch bool
+		// workers is the count of goroutines compiling functions, see wasm.WithCompilationWorkers.
+		workers int
+		// promotable is false if the module can't be compiled by the compiler, see promotable.
+		promotable bool
+		// swappable is true if hot functions are promoted in interpreted instances, see swappable.
+		swappable bool
+		state     state
+		// hot are the indexes of the functions which are hot.
+		hot []wasm.Index
+	}
+
+	// instance is an interpreted instance of a swappable module.
+	instance struct {
+		module      *module
+		instance    *wasm.ModuleInstance
+		interpreted wasm.ModuleEngine
+		// imports are the functions imported by the instance, by function index, see LinkImportedFunction.
+		imports []importedFunction
+		// linked is the count of imports which are linked.
+		linked int
+		// compiled is the module engine of the compiler calling the promoted functions, which is created by the first
+		// promotion.
+		compiled wasm.ModuleEngine
+	}
+
+	// importedFunction is the function at index of the instance.
+	importedFunction struct {
+		instance *wasm.ModuleInstance
+		index    wasm.Index
+	}
+
+	state byte
+)
+
+const (
+	// stateInterpreted is the state of a module which didn't become hot.
+	stateInterpreted state = iota
+	// stateCompiling is the state of a module compiled by the compiler in the background.
+	stateCompiling
+	// stateCompiled is the state of a module whose new instances use the compiler, and whose hot functions are
+	// promoted in the existing ones.
+	stateCompiled
+	// stateFailed is the state of a module the compiler failed to compile, which stays interpreted.
+	stateFailed
+)
+
+// NewEngine returns an engine interpreting modules until they are hot, at which point they're compiled for new
+// instances, and their hot functions are promoted in the existing ones.
+func NewEngine(ctx context.Context, enabledFeatures api.CoreFeatures, fileCache filecache.Cache) wasm.Engine {
+	return newEngine(ctx, enabledFeatures, fileCache, hotThreshold)
+}
+
+func newEngine(ctx context.Context, enabledFeatures api.CoreFeatures, fileCache filecache.Cache, threshold uint32) *engine {
+	e := &engine{
+		modules:      map[wasm.ModuleID]*module{},
+		counterparts: map[*wasm.ModuleInstance]*counterpart{},
+		instances:    map[*wasm.ModuleInstance]*instance{},
+	}
+	e.interpreter = interpreter.NewTieringEngine(ctx, enabledFeatures, fileCache, threshold, e.hot)
+	if platform.CompilerSupported() {
+		e.compiler = compiler.NewEngine(ctx, enabledFeatures, fileCache)
+	}
+	return e
+}
+
+// Close implements the same method as documented on wasm.Engine.
+func (e *engine) Close() (err error) {
+	e.compiling.Wait()
+	if e.compiler != nil {
+		if err = e.compiler.Close(); err != nil {
+			return
+		}
+	}
+	return e.interpreter.Close()
+}
+
+// CompileModule implements the same method as documented on wasm.Engine.
+func (e *engine) CompileModule(ctx context.Context, source *wasm.Module, listeners []experimental.FunctionListener, ensureTermination, meterFuel, checkEpoch bool) error {
+	if err := e.interpreter.CompileModule(ctx, source, listeners, ensureTermination, meterFuel, checkEpoch); err != nil {
+		return err
+	}
+	if e.compiler == nil {
+		return nil
+	}
+	// Host modules are only trampolines, so are compiled by both engines right away. This lets instances of either
+	// engine import their functions directly, see LinkImportedFunction.
+	if source.IsHostModule {
+		return e.compiler.CompileModule(ctx, source, listeners, ensureTermination, meterFuel, checkEpoch)
+	}
+
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	if _, ok := e.modules[source.ID]; !ok {
+		promotable := promotable(source)
+		e.modules[source.ID] = &module{
+			source:            source,
+			listeners:         listeners,
+			ensureTermination: ensureTermination,
+			meterFuel:         meterFuel,
+			checkEpoch:        checkEpoch,
+			workers:           wasm.CompilationWorkers(ctx),
+			promotable:        promotable,
+			swappable:         promotable && swappable(source),
+		}
+	}
+	return nil
+}
+
+// promotable returns false if the module imports or exports tables or reference globals. Their references may be
+// produced by instances of either engine, so all modules sharing them are interpreted.
+func promotable(m *wasm.Module) bool {
+	for i := range m.ImportSection {
+		imp := &m.ImportSection[i]
+		switch imp.Type {
+		case wasm.ExternTypeTable:
+			return false
+		case wasm.ExternTypeGlobal:
+			if isReference(imp.DescGlobal.ValType) {
+				return false
+			}
+		}
+	}
+	for i := range m.ExportSection {
+		exp := &m.ExportSection[i]
+		switch exp.Type {
+		case wasm.ExternTypeTable:
+			return false
+		case wasm.ExternTypeGlobal:
+			if exp.Index >= m.ImportGlobalCount && isReference(m.GlobalSection[exp.Index-m.ImportGlobalCount].Type.ValType) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// swappable returns true if the module has no tables, reference globals nor reference parameters or results. Hot
+// functions are promoted in its interpreted instances, as the compiled functions won't see references of the
+// interpreter.
+func swappable(m *wasm.Module) bool {
+	if m.ImportTableCount > 0 || len(m.TableSection) > 0 {
+		return false
+	}
+	for i := range m.GlobalSection {
+		if isReference(m.GlobalSection[i].Type.ValType) {
+			return false
+		}
+	}
+	for i := range m.TypeSection {
+		for _, vt := range m.TypeSection[i].Params {
+			if isReference(vt) {
+				return false
+			}
+		}
+		for _, vt := range m.TypeSection[i].Results {
+			if isReference(vt) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func isReference(vt wasm.ValueType) bool {
+	return vt == wasm.ValueTypeFuncref || vt == wasm.ValueTypeExternref || vt == wasm.ValueTypeExnref
+}
+
+// hot is called by the interpreter when the function at the given index of the module is hot.
+func (e *engine) hot(source *wasm.Module, index wasm.Index) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	m, ok := e.modules[source.ID]
+	if !ok || !m.promotable {
+		return
+	}
+	m.hot = append(m.hot, index)
+	switch m.state {
+	case stateInterpreted:
+		m.state = stateCompiling
+		e.compiling.Add(1)
+		go e.compile(m)
+	case stateCompiled:
+		e.promoteAll(m, index)
+	}
+}
+
+// compile compiles the module with the compiler, so that its new instances use it, and promotes its hot functions.
+func (e *engine) compile(m *module) {
+	defer e.compiling.Done()
+	ctx := wasm.WithCompilationWorkers(context.Background(), m.workers)
+	err := e.compiler.CompileModule(ctx, m.source, m.listeners, m.ensureTermination, m.meterFuel, m.checkEpoch)
+
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	if current, ok := e.modules[m.source.ID]; !ok { // deleted while compiling
+		if err == nil {
+			e.compiler.DeleteCompiledModule(m.source)
+		}
+		return
+	} else if current != m { // deleted and compiled again, which will reuse the compiled code when hot.
+		return
+	}
+	if err != nil {
+		m.state = stateFailed
+	} else {
+		m.state = stateCompiled
+		e.promoteAll(m, m.hot...)
+	}
+}
+
+// promoteAll promotes the functions at the given indexes in the interpreted instances of the module.
+func (e *engine) promoteAll(m *module, indexes ...wasm.Index) {
+	for _, i := range e.instances {
+		if i.module == m {
+			e.promote(i, indexes...)
+		}
+	}
+}
+
+// promote promotes the functions at the given indexes in the instance, once all its imports are linked. A function
+// which can't be promoted, for example because an imported module closed, keeps being interpreted.
+func (e *engine) promote(i *instance, indexes ...wasm.Index) {
+	if i.module.state != stateCompiled || i.linked < len(i.imports) {
+		return
+	}
+	if i.compiled == nil {
+		me, err := e.compiler.NewModuleEngine(i.module.source, i.instance)
+		if err != nil {
+			return
+		}
+		for index, imp := range i.imports {
+			importedEngine, importedIndex, err := e.link(false, imp.instance, imp.index)
+			if err != nil {
+				return
+			}
+			me.ResolveImportedFunction(wasm.Index(index), importedIndex, importedEngine)
+		}
+		i.compiled = me
+	}
+	for _, index := range indexes {
+		interpreter.Promote(i.interpreted, index, newCrossing(i.compiled, index))
+	}
+}
+
+// CompiledModuleCount implements the same method as documented on wasm.Engine.
+func (e *engine) CompiledModuleCount() uint32 {
+	return e.interpreter.CompiledModuleCount()
+}
+
+// DeleteCompiledModule implements the same method as documented on wasm.Engine.
+func (e *engine) DeleteCompiledModule(source *wasm.Module) {
+	e.mux.Lock()
+	delete(e.modules, source.ID)
+	e.mux.Unlock()
+
+	e.interpreter.DeleteCompiledModule(source)
+	if e.compiler != nil {
+		e.compiler.DeleteCompiledModule(source)
+	}
+}
+
+// NewModuleEngine implements the same method as documented on wasm.Engine.
+//
+// This uses the compiler if the module was compiled by it, or the interpreter otherwise.
+func (e *engine) NewModuleEngine(source *wasm.Module, mi *wasm.ModuleInstance) (wasm.ModuleEngine, error) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	m, ok := e.modules[source.ID]
+	if ok && m.state == stateCompiled {
+		return e.compiler.NewModuleEngine(source, mi)
+	}
+	me, err := e.interpreter.NewModuleEngine(source, mi)
+	if err == nil && ok && m.swappable {
+		e.instances[mi] = &instance{
+			module:      m,
+			instance:    mi,
+			interpreted: me,
+			imports:     make([]importedFunction, source.ImportFunctionCount),
+		}
+	}
+	return me, err
+}
@@ -0,0 +1,338 @@
+package tiered
+
+import (
+	"context"
+	"testing"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/internal/engine/interpreter"
+	"github.com/tetratelabs/wazero/internal/platform"
+	"github.com/tetratelabs/wazero/internal/testing/enginetest"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+	"github.com/tetratelabs/wazero/internal/wasmruntime"
+)
+
+// testCtx is an arbitrary, non-default context. Non-nil also prevents linter errors.
+var testCtx = context.WithValue(context.Background(), struct{}{}, "arbitrary")
+
+// engineTester implements enginetest.EngineTester.
+type engineTester struct{}
+
+// ListenerFactory implements enginetest.EngineTester ListenerFactory.
+func (engineTester) ListenerFactory() experimental.FunctionListenerFactory {
+	return nil
+}
+
+// NewEngine implements enginetest.EngineTester NewEngine.
+func (engineTester) NewEngine(enabledFeatures api.CoreFeatures) wasm.Engine {
+	return NewEngine(testCtx, enabledFeatures, nil)
+}
+
+func TestEngine_NewModuleEngine(t *testing.T) {
+	enginetest.RunTestEngineNewModuleEngine(t, engineTester{})
+}
+
+func TestEngine_MemoryGrowInRecursiveCall(t *testing.T) {
+	enginetest.RunTestEngineMemoryGrowInRecursiveCall(t, engineTester{})
+}
+
+func TestModuleEngine_Call(t *testing.T) {
+	enginetest.RunTestModuleEngineCall(t, engineTester{})
+}
+
+func TestModuleEngine_LookupFunction(t *testing.T) {
+	enginetest.RunTestModuleEngineLookupFunction(t, engineTester{})
+}
+
+func TestModuleEngine_MaxCallStackDepth(t *testing.T) {
+	enginetest.RunTestModuleEngineMaxCallStackDepth(t, engineTester{})
+}
+
+func TestEngine_Tiering(t *testing.T) {
+	features := api.CoreFeaturesV2
+	e := newEngine(testCtx, features, nil, 2)
+	defer e.Close()
+	s := wasm.NewStore(features, e)
+
+	instantiate(t, s, newHostModule(t, features), "env", nil)
+
+	// guest exports f, calling env.add1, and user exports g, calling f of the instance returned by the resolver.
+	guest := newModule("guest", "env", "add1", "f")
+	user := newModule("user", "guest", "f", "g")
+	resolver := func(instance *wasm.ModuleInstance) wasm.ImportResolver {
+		return func(string, string, wasm.ExternType) api.Module { return instance }
+	}
+
+	// Modules are interpreted until hot, then compiled in the background.
+	interpreted := instantiate(t, s, guest, "interpreted", nil)
+	require.True(t, interpreter.IsModuleEngine(interpreted.Engine))
+	require.Equal(t, uint64(2), call(t, interpreted, "f", 1))
+	require.Equal(t, uint64(3), call(t, interpreted, "f", 2))
+	e.compiling.Wait()
+
+	// The hot function is promoted in the existing instance.
+	if platform.CompilerSupported() {
+		require.NotNil(t, e.instances[interpreted].compiled)
+	}
+	require.Equal(t, uint64(3), call(t, interpreted, "f", 2))
+
+	// The new instance of the hot module imports the host function from the compiled host module.
+	compiled := instantiate(t, s, guest, "compiled", nil)
+	require.Equal(t, !platform.CompilerSupported(), interpreter.IsModuleEngine(compiled.Engine))
+	require.Equal(t, uint64(4), call(t, compiled, "f", 3))
+
+	// An interpreted instance can import functions of a compiled one, and the reverse.
+	importing := instantiate(t, s, user, "importing compiled", resolver(compiled))
+	require.True(t, interpreter.IsModuleEngine(importing.Engine))
+	require.Equal(t, uint64(5), call(t, importing, "g", 4))
+	require.Equal(t, uint64(6), call(t, importing, "g", 5))
+	e.compiling.Wait()
+
+	importing = instantiate(t, s, user, "importing interpreted", resolver(interpreted))
+	require.Equal(t, !platform.CompilerSupported(), interpreter.IsModuleEngine(importing.Engine))
+	require.Equal(t, uint64(7), call(t, importing, "g", 6))
+
+	if !platform.CompilerSupported() {
+		return
+	}
+	_, ok := e.counterparts[compiled]
+	require.True(t, ok)
+
+	// Counterparts of closed instances are deleted, and their functions aren't promoted anymore.
+	require.NoError(t, compiled.Close(testCtx))
+	_, ok = e.counterparts[compiled]
+	require.False(t, ok)
+	_, ok = e.counterparts[interpreted]
+	require.True(t, ok)
+	require.NoError(t, interpreted.Close(testCtx))
+	_, ok = e.counterparts[interpreted]
+	require.False(t, ok)
+	_, ok = e.instances[interpreted]
+	require.False(t, ok)
+}
+
+func TestEngine_CallDepth(t *testing.T) {
+	if !platform.CompilerSupported() {
+		t.Skip()
+	}
+	features := api.CoreFeaturesV2
+	e := newEngine(testCtx, features, nil, 1)
+	defer e.Close()
+	s := wasm.NewStore(features, e)
+	instantiate(t, s, newHostModule(t, features), "env", nil)
+
+	guest := newModule("guest", "env", "add1", "f")
+	require.Equal(t, uint64(2), call(t, instantiate(t, s, guest, "interpreted", nil), "f", 1))
+	e.compiling.Wait()
+	compiled := instantiate(t, s, guest, "compiled", nil)
+
+	// The interpreted g calls the compiled f through the bridge, which calls env.add1: four functions including the
+	// bridge.
+	user := newModule("user", "guest", "f", "g")
+	importing := instantiate(t, s, user, "importing", func(string, string, wasm.ExternType) api.Module {
+		return compiled
+	})
+	s.MaxCallStackDepth = 3
+	_, err := importing.ExportedFunction("g").Call(testCtx, 1)
+	require.ErrorIs(t, err, wasmruntime.ErrRuntimeStackOverflow)
+
+	s.MaxCallStackDepth = 4
+	require.Equal(t, uint64(2), call(t, importing, "g", 1))
+}
+
+func TestCrossing_CallWithDepth(t *testing.T) {
+	features := api.CoreFeaturesV2 | api.CoreFeatureExceptionHandling
+	e := interpreter.NewEngine(testCtx, features, nil)
+	defer e.Close()
+
+	// throw panics with exc, as host functions do to throw.
+	exc := &api.Exception{Values: []uint64{42}}
+	host, err := wasm.NewHostModule("env", []string{"throw"}, map[string]*wasm.HostFunc{
+		"throw": {
+			ExportName: "throw",
+			Code: wasm.Code{GoFunc: api.GoModuleFunc(func(context.Context, api.Module, []uint64) {
+				panic(exc)
+			})},
+		},
+	}, nil, features)
+	require.NoError(t, err)
+	require.NoError(t, e.CompileModule(testCtx, host, nil, false, false, false))
+	instance := &wasm.ModuleInstance{TypeIDs: []wasm.FunctionTypeID{0}, Source: host}
+	me, err := e.NewModuleEngine(host, instance)
+	require.NoError(t, err)
+	instance.Engine = me
+
+	// The exception is thrown again as is, so that the caller can catch it.
+	c := newCrossing(me, 0)
+	err = require.CapturePanic(func() { c.CallWithDepth(testCtx, nil, nil, 0) })
+	require.Equal(t, exc, err)
+}
+
+func newHostModule(t *testing.T, features api.CoreFeatures) *wasm.Module {
+	i32 := []wasm.ValueType{wasm.ValueTypeI32}
+	host, err := wasm.NewHostModule("env", []string{"add1"}, map[string]*wasm.HostFunc{
+		"add1": {
+			ExportName:  "add1",
+			ParamTypes:  i32,
+			ResultTypes: i32,
+			Code: wasm.Code{GoFunc: api.GoModuleFunc(func(_ context.Context, _ api.Module, stack []uint64) {
+				stack[0]++
+			})},
+		},
+	}, nil, features)
+	require.NoError(t, err)
+	return host
+}
+
+// newModule returns a module exporting a function named export, which calls the function named name imported from
+// moduleName, of type (i32) -> i32.
+func newModule(id, moduleName, name, export string) *wasm.Module {
+	imp := wasm.Import{Module: moduleName, Name: name, Type: wasm.ExternTypeFunc, DescFunc: 0}
+	exp := wasm.Export{Name: export, Type: wasm.ExternTypeFunc, Index: 1}
+	m := &wasm.Module{
+		TypeSection:         []wasm.FunctionType{{Params: []wasm.ValueType{wasm.ValueTypeI32}, Results: []wasm.ValueType{wasm.ValueTypeI32}}},
+		ImportSection:       []wasm.Import{imp},
+		ImportPerModule:     map[string][]*wasm.Import{moduleName: {&imp}},
+		ImportFunctionCount: 1,
+		FunctionSection:     []wasm.Index{0},
+		CodeSection: []wasm.Code{
+			{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeCall, 0, wasm.OpcodeEnd}},
+		},
+		ExportSection: []wasm.Export{exp},
+		Exports:       map[string]*wasm.Export{export: &exp},
+	}
+	m.AssignModuleID([]byte(id), false, false, false, false)
+	return m
+}
+
+func instantiate(t *testing.T, s *wasm.Store, m *wasm.Module, name string, resolver wasm.ImportResolver) *wasm.ModuleInstance {
+	require.NoError(t, m.Validate(s.EnabledFeatures))
+	require.NoError(t, s.Engine.CompileModule(testCtx, m, nil, false, false, false))
+	typeIDs, err := s.GetFunctionTypeIDs(m.TypeSection)
+	require.NoError(t, err)
+	instance, err := s.Instantiate(testCtx, m, name, nil, typeIDs, resolver)
+	require.NoError(t, err)
+	return instance
+}
+
+func call(t *testing.T, m *wasm.ModuleInstance, name string, param uint64) uint64 {
+	results, err := m.ExportedFunction(name).Call(testCtx, param)
+	require.NoError(t, err)
+	return results[0]
+}
+
+func TestPromotable(t *testing.T) {
+	tests := []struct {
+		name     string
+		module   *wasm.Module
+		expected bool
+	}{
+		{
+			name:     "empty",
+			module:   &wasm.Module{},
+			expected: true,
+		},
+		{
+			name: "imports numeric global",
+			module: &wasm.Module{ImportSection: []wasm.Import{
+				{Type: wasm.ExternTypeGlobal, DescGlobal: wasm.GlobalType{ValType: wasm.ValueTypeI32}},
+			}},
+			expected: true,
+		},
+		{
+			name: "imports table",
+			module: &wasm.Module{ImportSection: []wasm.Import{
+				{Type: wasm.ExternTypeTable, DescTable: wasm.Table{Type: wasm.RefTypeFuncref}},
+			}},
+			expected: false,
+		},
+		{
+			name: "imports funcref global",
+			module: &wasm.Module{ImportSection: []wasm.Import{
+				{Type: wasm.ExternTypeGlobal, DescGlobal: wasm.GlobalType{ValType: wasm.ValueTypeFuncref}},
+			}},
+			expected: false,
+		},
+		{
+			name: "exports table",
+			module: &wasm.Module{
+				TableSection:  []wasm.Table{{Type: wasm.RefTypeExternref}},
+				ExportSection: []wasm.Export{{Type: wasm.ExternTypeTable}},
+			},
+			expected: false,
+		},
+		{
+			name: "exports externref global",
+			module: &wasm.Module{
+				GlobalSection: []wasm.Global{{Type: wasm.GlobalType{ValType: wasm.ValueTypeExternref}}},
+				ExportSection: []wasm.Export{{Type: wasm.ExternTypeGlobal}},
+			},
+			expected: false,
+		},
+		{
+			name: "exports imported numeric global",
+			module: &wasm.Module{
+				ImportSection:     []wasm.Import{{Type: wasm.ExternTypeGlobal, DescGlobal: wasm.GlobalType{ValType: wasm.ValueTypeI64}}},
+				ImportGlobalCount: 1,
+				ExportSection:     []wasm.Export{{Type: wasm.ExternTypeGlobal}},
+			},
+			expected: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, promotable(tc.module))
+		})
+	}
+}
+
+func TestSwappable(t *testing.T) {
+	i32 := []wasm.ValueType{wasm.ValueTypeI32}
+	tests := []struct {
+		name     string
+		module   *wasm.Module
+		expected bool
+	}{
+		{
+			name:     "empty",
+			module:   &wasm.Module{},
+			expected: true,
+		},
+		{
+			name:     "numeric types",
+			module:   &wasm.Module{TypeSection: []wasm.FunctionType{{Params: i32, Results: i32}}},
+			expected: true,
+		},
+		{
+			name:     "table",
+			module:   &wasm.Module{TableSection: []wasm.Table{{Type: wasm.RefTypeFuncref}}},
+			expected: false,
+		},
+		{
+			name:     "funcref global",
+			module:   &wasm.Module{GlobalSection: []wasm.Global{{Type: wasm.GlobalType{ValType: wasm.ValueTypeFuncref}}}},
+			expected: false,
+		},
+		{
+			name:     "externref param",
+			module:   &wasm.Module{TypeSection: []wasm.FunctionType{{Params: []wasm.ValueType{wasm.ValueTypeExternref}}}},
+			expected: false,
+		},
+		{
+			name:     "funcref result",
+			module:   &wasm.Module{TypeSection: []wasm.FunctionType{{Results: []wasm.ValueType{wasm.ValueTypeFuncref}}}},
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, swappable(tc.module))
+		})
+	}
+}
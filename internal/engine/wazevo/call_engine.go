@@ -2,7 +2,9 @@ package wazevo
 
 import (
 	"context"
+	"math"
 	"reflect"
+	"sync/atomic"
 	"unsafe"
 
 	"github.com/tetratelabs/wazero/api"
@@ -33,6 +35,8 @@ type (
 		execCtx executionContext
 		// execCtxPtr holds the pointer to the executionContext which doesn't change after callEngine is created.
 		execCtxPtr uintptr
+		// maxStackBytes is the maximum length of stack, or zero for callStackCeiling.
+		maxStackBytes uintptr
 	}
 
 	// executionContext is the struct to be read/written by assembly functions.
@@ -55,11 +59,22 @@ type (
 		stackPointerBeforeGrow uintptr
 		// stackGrowRequiredSize holds the required size of stack grow.
 		stackGrowRequiredSize uintptr
-		// _ is needed to align .savedRegisters at 16 bytes boundary.
-		_ uint64
+		// fuel is the remaining fuel of the module, consumed by functions compiled with fuel metering. This also aligns
+		// .savedRegisters at 16 bytes boundary.
+		fuel int64
 		// savedRegisters is the opaque spaces for save/restore registers.
 		// We want to align 16 bytes for each register, so we use [64][2]uint64.
 		savedRegisters [64][2]uint64
+		// epoch points to the wasm.Store Epoch of the module, which is read by functions compiled with epoch checks.
+		epoch *atomic.Uint64
+		// epochDeadline is the epoch at which functions compiled with epoch checks exit with
+		// wazevoapi.ExitCodeEpochInterrupted.
+		epochDeadline uint64
+		// callDepth is the number of functions currently executing in this call, which is incremented on function
+		// entry and decremented on return.
+		callDepth uint64
+		// maxCallDepth is the maximum of callDepth, above which the call exits with wazevoapi.ExitCodeStackOverflow.
+		maxCallDepth uint64
 	}
 )
 
@@ -106,6 +121,28 @@ func (c *callEngine) CallWithStack(ctx context.Context, paramResultStack []uint6
 		paramResultPtr = &paramResultStack[0]
 	}
 
+	m := c.parent.module
+	maxCallStackDepth, maxStackBytes := m.StackLimits()
+	c.maxStackBytes = uintptr(maxStackBytes)
+	c.execCtx.callDepth, c.execCtx.maxCallDepth = 0, math.MaxUint64
+	if maxCallStackDepth > 0 {
+		c.execCtx.maxCallDepth = uint64(maxCallStackDepth)
+	}
+
+	// Functions compiled with fuel metering consume c.execCtx.fuel, which is subtracted from the module on return.
+	fuel := m.Fuel()
+	c.execCtx.fuel = fuel
+	defer func() {
+		m.ConsumeFuel(fuel - c.execCtx.fuel)
+	}()
+
+	// The epoch is set regardless of whether the module checks it, as the call can reach functions of other modules
+	// which do. The deadline is math.MaxUint64 if the context has none.
+	c.execCtx.epoch, c.execCtx.epochDeadline = m.Epoch(), m.EpochDeadline(ctx)
+	if c.execCtx.epoch == nil { // ModuleInstance created without a Store in tests.
+		c.execCtx.epoch = &noEpoch
+	}
+
 	entrypoint(c.executable, c.execCtxPtr, c.parent.opaquePtr, paramResultPtr, c.stackTop)
 	for {
 		switch c.execCtx.exitCode {
@@ -120,23 +157,40 @@ func (c *callEngine) CallWithStack(ctx context.Context, paramResultStack []uint6
 			afterStackGrowEntrypoint(c.execCtx.goCallReturnAddress, c.execCtxPtr, newsp)
 		case wazevoapi.ExitCodeUnreachable:
 			return wasmruntime.ErrRuntimeUnreachable
+		case wazevoapi.ExitCodeOutOfFuel:
+			return wasmruntime.ErrRuntimeOutOfFuel
+		case wazevoapi.ExitCodeEpochInterrupted:
+			return wasmruntime.ErrRuntimeEpochInterrupted
+		case wazevoapi.ExitCodeStackOverflow:
+			return wasmruntime.ErrRuntimeStackOverflow
 		default:
 			panic("BUG")
 		}
 	}
 }
 
-const callStackCeiling = uintptr(5000000) // in uint64 (8 bytes) == 40000000 bytes in total == 40mb.
+// noEpoch is the epoch read by functions of modules which weren't instantiated in a wasm.Store.
+var noEpoch atomic.Uint64
+
+// callStackCeiling is the default maximum length of callEngine.stack in bytes.
+const callStackCeiling = uintptr(40000000) // == 40mb.
 
 // growStack grows the stack, and returns the new stack pointer.
 func (c *callEngine) growStack() (newSP uintptr, err error) {
+	maxLen := c.maxStackBytes
+	if maxLen == 0 {
+		maxLen = callStackCeiling
+	}
 	currentLen := uintptr(len(c.stack))
-	if callStackCeiling < currentLen {
+	if maxLen < currentLen+c.execCtx.stackGrowRequiredSize {
 		err = wasmruntime.ErrRuntimeStackOverflow
 		return
 	}
 
 	newLen := 2*currentLen + c.execCtx.stackGrowRequiredSize
+	if newLen > maxLen {
+		newLen = maxLen
+	}
 	newStack := make([]byte, newLen)
 
 	relSp := c.stackTop - c.execCtx.stackPointerBeforeGrow
@@ -22,6 +22,16 @@ func TestCallEngine_growStack(t *testing.T) {
 		require.Error(t, err)
 	})
 
+	t.Run("stack overflow with maxStackBytes", func(t *testing.T) {
+		c := &callEngine{
+			stack:         make([]byte, 32),
+			maxStackBytes: 100,
+			execCtx:       executionContext{stackGrowRequiredSize: 160},
+		}
+		_, err := c.growStack()
+		require.Error(t, err)
+	})
+
 	t.Run("ok", func(t *testing.T) {
 		s := make([]byte, 32)
 		for i := range s {
@@ -0,0 +1,154 @@
+package wazevo
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/tetratelabs/wazero/internal/engine/wazevo/backend"
+	"github.com/tetratelabs/wazero/internal/engine/wazevo/frontend"
+	"github.com/tetratelabs/wazero/internal/engine/wazevo/ssa"
+	"github.com/tetratelabs/wazero/internal/engine/wazevo/wazevoapi"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+type (
+	// functionCode is the machine code of a function, before it is laid out in the executable.
+	functionCode struct {
+		body []byte
+		// rels are the relocations, whose offsets are relative to the start of body.
+		rels                []backend.RelocationInfo
+		goPreambleSize      int
+		needGoEntryPreamble bool
+	}
+
+	// compilationPanic is the value recovered from a panic while compiling a function on a worker goroutine, which
+	// compileFunctions panics with again on the calling goroutine.
+	compilationPanic struct {
+		recovered interface{}
+	}
+
+	// functionCompiler holds the compilers which are reused for each function, by one goroutine at a time.
+	functionCompiler struct {
+		ssaBuilder ssa.Builder
+		fe         *frontend.Compiler
+		be         backend.Compiler
+	}
+)
+
+func newFunctionCompiler(module *wasm.Module, offsets *wazevoapi.ModuleContextOffsetData, inlining, meterFuel, checkEpoch, countCallDepth bool) *functionCompiler {
+	ssaBuilder := ssa.NewBuilder()
+	return &functionCompiler{
+		ssaBuilder: ssaBuilder,
+		fe:         frontend.NewFrontendCompiler(module, ssaBuilder, offsets, inlining, meterFuel, checkEpoch, countCallDepth),
+		be:         backend.NewCompiler(newMachine(), ssaBuilder),
+	}
+}
+
+// compileFunctions compiles the local functions of the module, with up to wasm.CompilationWorkers goroutines.
+func compileFunctions(ctx context.Context, module *wasm.Module, offsets *wazevoapi.ModuleContextOffsetData, inlining, meterFuel, checkEpoch, countCallDepth bool, exportedFnIndex map[wasm.Index]struct{}) ([]functionCode, error) {
+	codes := make([]functionCode, len(module.CodeSection))
+	workers := wasm.CompilationWorkers(ctx)
+	if workers > len(codes) {
+		workers = len(codes)
+	}
+	if workers <= 1 {
+		fc := newFunctionCompiler(module, offsets, inlining, meterFuel, checkEpoch, countCallDepth)
+		for i := range codes {
+			if err := fc.compile(module, i, exportedFnIndex, &codes[i]); err != nil {
+				return nil, err
+			}
+		}
+		return codes, nil
+	}
+
+	errs := make([]error, len(codes))
+	var next int32 // the index of the next function to compile.
+	var failed atomic.Bool
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		fc := newFunctionCompiler(module, offsets, inlining, meterFuel, checkEpoch, countCallDepth)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Once a function failed, the functions of lower index still compile, so that the error is the same as
+			// when compiling one after another.
+			for !failed.Load() {
+				i := int(atomic.AddInt32(&next, 1)) - 1
+				if i >= len(codes) {
+					return
+				}
+				if errs[i] = fc.compileRecovering(module, i, exportedFnIndex, &codes[i]); errs[i] != nil {
+					failed.Store(true)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if p, ok := err.(*compilationPanic); ok {
+			panic(p.recovered)
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	return codes, nil
+}
+
+// Error implements error.
+func (p *compilationPanic) Error() string {
+	return fmt.Sprint(p.recovered)
+}
+
+// compileRecovering is like compile, except a panic is returned as a *compilationPanic, as it would otherwise crash
+// the process on a worker goroutine.
+func (fc *functionCompiler) compileRecovering(module *wasm.Module, i int, exportedFnIndex map[wasm.Index]struct{}, code *functionCode) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = &compilationPanic{recovered: recovered}
+		}
+	}()
+	return fc.compile(module, i, exportedFnIndex, code)
+}
+
+// compile compiles the function at the given index in the code section into code.
+func (fc *functionCompiler) compile(module *wasm.Module, i int, exportedFnIndex map[wasm.Index]struct{}, code *functionCode) error {
+	fidx := wasm.Index(i) + module.ImportFunctionCount
+	_, code.needGoEntryPreamble = exportedFnIndex[fidx]
+
+	typ := &module.TypeSection[module.FunctionSection[i]]
+	codeSeg := &module.CodeSection[i]
+	if codeSeg.GoFunc != nil {
+		panic("TODO: host module")
+	}
+
+	// Initializes both frontend and backend compilers.
+	fc.fe.Init(wasm.Index(i), typ, codeSeg.LocalTypes, codeSeg.Body)
+	fc.be.Init(code.needGoEntryPreamble)
+
+	// Lower Wasm to SSA.
+	if err := fc.fe.LowerToSSA(); err != nil {
+		return fmt.Errorf("wasm->ssa: %v", err)
+	}
+
+	// Run SSA-level optimization passes.
+	fc.ssaBuilder.RunPasses()
+
+	// Finalize the layout of SSA blocks which might use the optimization results.
+	fc.ssaBuilder.LayoutBlocks()
+
+	// Now our ssaBuilder contains the necessary information to further lower them to
+	// machine code.
+	body, rels, goPreambleSize, err := fc.be.Compile()
+	if err != nil {
+		return fmt.Errorf("ssa->machine code: %v", err)
+	}
+
+	// The compilers reuse their buffers for the next function, so copy them.
+	code.body = append([]byte(nil), body...)
+	code.rels = append([]backend.RelocationInfo(nil), rels...)
+	code.goPreambleSize = goPreambleSize
+	return nil
+}
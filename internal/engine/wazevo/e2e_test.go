@@ -5,10 +5,12 @@ import (
 	"fmt"
 	"math"
 	"testing"
+	"time"
 	"unsafe"
 
 	"github.com/tetratelabs/wazero"
 	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
 	"github.com/tetratelabs/wazero/internal/engine/wazevo"
 	"github.com/tetratelabs/wazero/internal/engine/wazevo/testcases"
 	"github.com/tetratelabs/wazero/internal/filecache"
@@ -125,6 +127,83 @@ func TestE2E(t *testing.T) {
 	}
 }
 
+func TestE2E_maxCallStackDepth(t *testing.T) {
+	config := wazero.NewRuntimeConfigCompiler().WithMaxCallStackDepth(10)
+	configureWazevo(config)
+
+	ctx := context.Background()
+	r := wazero.NewRuntimeWithConfig(ctx, config)
+	defer func() {
+		require.NoError(t, r.Close(ctx))
+	}()
+
+	// Calls itself forever, which overflows after 10 calls rather than growing the stack.
+	m := &wasm.Module{
+		TypeSection:     []wasm.FunctionType{{}},
+		FunctionSection: []wasm.Index{0},
+		CodeSection:     []wasm.Code{{Body: []byte{wasm.OpcodeCall, 0, wasm.OpcodeEnd}}},
+		ExportSection:   []wasm.Export{{Name: testcases.ExportName, Index: 0, Type: wasm.ExternTypeFunc}},
+	}
+	inst, err := r.Instantiate(ctx, binaryencoding.EncodeModule(m))
+	require.NoError(t, err)
+
+	_, err = inst.ExportedFunction(testcases.ExportName).Call(ctx)
+	require.EqualError(t, err, "stack overflow")
+}
+
+func TestE2E_epochInterruption(t *testing.T) {
+	config := wazero.NewRuntimeConfigCompiler()
+	configureWazevo(config)
+
+	ctx := context.Background()
+	r := wazero.NewRuntimeWithConfig(ctx, config)
+	defer func() {
+		require.NoError(t, r.Close(ctx))
+	}()
+
+	// (func (export "f") (loop br 0))
+	m := &wasm.Module{
+		TypeSection:     []wasm.FunctionType{{}},
+		FunctionSection: []wasm.Index{0},
+		CodeSection: []wasm.Code{{Body: []byte{
+			wasm.OpcodeLoop, 0x40, wasm.OpcodeBr, 0, wasm.OpcodeEnd, wasm.OpcodeEnd,
+		}}},
+		ExportSection: []wasm.Export{{Name: testcases.ExportName, Index: 0, Type: wasm.ExternTypeFunc}},
+	}
+	inst, err := r.Instantiate(experimental.WithEpochInterruption(ctx), binaryencoding.EncodeModule(m))
+	require.NoError(t, err)
+	f := inst.ExportedFunction(testcases.ExportName)
+
+	t.Run("entry", func(t *testing.T) {
+		// The deadline is the current epoch, so the call is interrupted on entry.
+		_, err := f.Call(experimental.WithEpochDeadline(ctx, 0))
+		require.ErrorIs(t, err, experimental.ErrEpochInterrupted)
+	})
+
+	t.Run("loop", func(t *testing.T) {
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			ticker := time.NewTicker(time.Millisecond)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-done:
+					return
+				case <-ticker.C:
+					r.(experimental.EpochCounter).IncrementEpoch()
+				}
+			}
+		}()
+
+		// The module can be called again after the interruption.
+		for i := 0; i < 2; i++ {
+			_, err := f.Call(experimental.WithEpochDeadline(ctx, 2))
+			require.ErrorIs(t, err, experimental.ErrEpochInterrupted)
+		}
+	})
+}
+
 // configureWazevo modifies wazero.RuntimeConfig and sets the wazevo implementation.
 // This is a hack to avoid modifying outside the wazevo package while testing it end-to-end.
 func configureWazevo(config wazero.RuntimeConfig) {
@@ -144,6 +223,9 @@ func configureWazevo(config wazero.RuntimeConfig) {
 		enabledFeatures       api.CoreFeatures
 		memoryLimitPages      uint32
 		memoryCapacityFromMax bool
+		memoryAllocator       experimental.MemoryAllocator
+		maxCallStackDepth     uint32
+		maxStackBytes         uint64
 		engineKind            int
 		dwarfDisabled         bool
 		newEngine
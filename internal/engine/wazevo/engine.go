@@ -51,7 +51,7 @@ func NewEngine(_ context.Context, _ api.CoreFeatures, _ filecache.Cache) wasm.En
 }
 
 // CompileModule implements wasm.Engine.
-func (e *engine) CompileModule(_ context.Context, module *wasm.Module, _ []experimental.FunctionListener, ensureTermination bool) error {
+func (e *engine) CompileModule(ctx context.Context, module *wasm.Module, listeners []experimental.FunctionListener, ensureTermination, meterFuel, checkEpoch bool) error {
 	e.rels = e.rels[:0]
 	cm := &compiledModule{offsets: wazevoapi.NewModuleContextOffsetData(module)}
 
@@ -69,75 +69,43 @@ func (e *engine) CompileModule(_ context.Context, module *wasm.Module, _ []exper
 		}
 	}
 
-	// Creates new compiler instances which are reused for each function.
-	ssaBuilder := ssa.NewBuilder()
-	fe := frontend.NewFrontendCompiler(module, ssaBuilder, &cm.offsets)
-	machine := newMachine()
-	be := backend.NewCompiler(machine, ssaBuilder)
+	// Compiles the functions, concurrently if wasm.CompilationWorkers is more than one, then lays them out in order
+	// of index, so that the executable is the same regardless of the count of workers.
+	// Inlined functions would consume the fuel of their instructions in a different block than the other engines,
+	// and wouldn't check the epoch on entry, so they aren't inlined with fuel metering or epoch checks.
+	inlining := listeners == nil && !meterFuel && !checkEpoch
+	// The call depth is always counted, as its maximum is set per wasm.Store, which isn't known until instantiation.
+	const countCallDepth = true
+	codes, err := compileFunctions(ctx, module, &cm.offsets, inlining, meterFuel, checkEpoch, countCallDepth, exportedFnIndex)
+	if err != nil {
+		return err
+	}
 
 	totalSize := 0 // Total binary size of the executable.
 	cm.functionOffsets = make([]compiledFunctionOffset, localFns)
-	bodies := make([][]byte, localFns)
-	for i := range module.CodeSection {
-		fidx := wasm.Index(i + importedFns)
-		fref := frontend.FunctionIndexToFuncRef(fidx)
-
-		_, needGoEntryPreamble := exportedFnIndex[fidx]
+	for i := range codes {
+		code := &codes[i]
+		fref := frontend.FunctionIndexToFuncRef(wasm.Index(i + importedFns))
 
 		// Align 16-bytes boundary.
 		totalSize = (totalSize + 15) &^ 15
 		compiledFuncOffset := &cm.functionOffsets[i]
 		compiledFuncOffset.offset = totalSize
 
-		typ := &module.TypeSection[module.FunctionSection[i]]
-
-		codeSeg := &module.CodeSection[i]
-		if codeSeg.GoFunc != nil {
-			panic("TODO: host module")
-		}
-
-		// Initializes both frontend and backend compilers.
-		fe.Init(wasm.Index(i), typ, codeSeg.LocalTypes, codeSeg.Body)
-		be.Init(needGoEntryPreamble)
-
-		// Lower Wasm to SSA.
-		err := fe.LowerToSSA()
-		if err != nil {
-			return fmt.Errorf("wasm->ssa: %v", err)
-		}
-
-		// Run SSA-level optimization passes.
-		ssaBuilder.RunPasses()
-
-		// Finalize the layout of SSA blocks which might use the optimization results.
-		ssaBuilder.LayoutBlocks()
-
-		// Now our ssaBuilder contains the necessary information to further lower them to
-		// machine code.
-		body, rels, goPreambleSize, err := be.Compile()
-		if err != nil {
-			return fmt.Errorf("ssa->machine code: %v", err)
-		}
-
 		e.refToBinaryOffset[fref] = totalSize +
 			// During the relocation, call target needs to be the beginning of function after Go entry preamble.
-			goPreambleSize
-		if needGoEntryPreamble {
-			compiledFuncOffset.goPreambleSize = goPreambleSize
+			code.goPreambleSize
+		if code.needGoEntryPreamble {
+			compiledFuncOffset.goPreambleSize = code.goPreambleSize
 		}
 
 		// At this point, relocation offsets are relative to the start of the function body,
 		// so we adjust it to the start of the executable.
-		for _, r := range rels {
+		for _, r := range code.rels {
 			r.Offset += int64(totalSize)
 			e.rels = append(e.rels, r)
 		}
-
-		// TODO: optimize as zero copy.
-		copied := make([]byte, len(body))
-		copy(copied, body)
-		bodies[i] = copied
-		totalSize += len(body)
+		totalSize += len(code.body)
 	}
 
 	// Allocate executable memory and then copy the generated machine code.
@@ -147,13 +115,13 @@ func (e *engine) CompileModule(_ context.Context, module *wasm.Module, _ []exper
 	}
 	cm.executable = executable
 
-	for i, b := range bodies {
+	for i := range codes {
 		offset := cm.functionOffsets[i]
-		copy(executable[offset.offset:], b)
+		copy(executable[offset.offset:], codes[i].body)
 	}
 
 	// Resolve relocations for local function calls.
-	machine.ResolveRelocations(e.refToBinaryOffset, executable, e.rels)
+	newMachine().ResolveRelocations(e.refToBinaryOffset, executable, e.rels)
 
 	fmt.Println(hex.EncodeToString(executable))
 
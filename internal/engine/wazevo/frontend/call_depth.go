@@ -0,0 +1,114 @@
+package frontend
+
+import (
+	"github.com/tetratelabs/wazero/internal/engine/wazevo/ssa"
+	"github.com/tetratelabs/wazero/internal/engine/wazevo/wazevoapi"
+)
+
+// incrementCallDepth inserts the increment of the call depth on the function entry, if it is counted.
+//
+// Like the other engines, the depth is the number of functions currently executing in the call, and if it exceeds
+// the maximum, the execution exits with wazevoapi.ExitCodeStackOverflow. The depth is decremented again in the block
+// returned by callDepthExitBlock, through which all the returns of the function pass.
+func (c *Compiler) incrementCallDepth() {
+	if !c.countCallDepth {
+		return
+	}
+	next := c.checkCallDepth()
+
+	store := c.ssaBuilder.AllocateInstruction()
+	store.AsStore(next, c.execCtxPtrValue, wazevoapi.ExecutionContextOffsets.CallDepth.U32())
+	c.ssaBuilder.InsertInstruction(store)
+
+	c.callDepthExitBlk = c.ssaBuilder.AllocateBasicBlock()
+	c.addBlockParamsFromWasmTypes(c.wasmFunctionTyp.Results, c.callDepthExitBlk)
+}
+
+// checkInlinedCallDepth inserts the check of the call depth for a call to an inlined function, if it is counted.
+//
+// Inlined functions never call others, so only the depth which the call would have reached needs to be checked.
+func (c *Compiler) checkInlinedCallDepth() {
+	if c.countCallDepth {
+		c.checkCallDepth()
+	}
+}
+
+// checkCallDepth inserts the exit with wazevoapi.ExitCodeStackOverflow if the call depth incremented by one exceeds
+// the maximum, and returns the incremented depth.
+func (c *Compiler) checkCallDepth() ssa.Value {
+	builder := c.ssaBuilder
+
+	depth := builder.AllocateInstruction()
+	depth.AsLoad(c.execCtxPtrValue, wazevoapi.ExecutionContextOffsets.CallDepth.U32(), ssa.TypeI64)
+	builder.InsertInstruction(depth)
+
+	one := builder.AllocateInstruction()
+	one.AsIconst64(1)
+	builder.InsertInstruction(one)
+
+	next := builder.AllocateInstruction()
+	next.AsIadd(depth.Return(), one.Return())
+	builder.InsertInstruction(next)
+
+	maxDepth := builder.AllocateInstruction()
+	maxDepth.AsLoad(c.execCtxPtrValue, wazevoapi.ExecutionContextOffsets.MaxCallDepth.U32(), ssa.TypeI64)
+	builder.InsertInstruction(maxDepth)
+
+	cmp := builder.AllocateInstruction()
+	cmp.AsIcmp(next.Return(), maxDepth.Return(), ssa.IntegerCmpCondUnsignedGreaterThan)
+	builder.InsertInstruction(cmp)
+
+	brnz := builder.AllocateInstruction()
+	brnz.AsBrnz(cmp.Return(), nil, c.getOrCreateTrapBlock(wazevoapi.ExitCodeStackOverflow))
+	builder.InsertInstruction(brnz)
+
+	// The rest of the block continues in a new one, which has only the current block as predecessor.
+	blk := builder.AllocateBasicBlock()
+	c.insertJumpToBlock(nil, blk)
+	builder.SetCurrentBlock(blk)
+	builder.Seal(blk)
+	return next.Return()
+}
+
+// callDepthExitBlock returns the block which the function returns through, which is the return block unless the
+// call depth is counted.
+func (c *Compiler) callDepthExitBlock() ssa.BasicBlock {
+	if c.callDepthExitBlk != nil {
+		return c.callDepthExitBlk
+	}
+	return c.ssaBuilder.ReturnBlock()
+}
+
+// emitCallDepthExitBlock emits the block returned by callDepthExitBlock, which decrements the call depth before
+// returning, if the call depth is counted.
+func (c *Compiler) emitCallDepthExitBlock() {
+	blk := c.callDepthExitBlk
+	if blk == nil {
+		return
+	}
+	builder := c.ssaBuilder
+	builder.SetCurrentBlock(blk)
+	builder.Seal(blk)
+
+	depth := builder.AllocateInstruction()
+	depth.AsLoad(c.execCtxPtrValue, wazevoapi.ExecutionContextOffsets.CallDepth.U32(), ssa.TypeI64)
+	builder.InsertInstruction(depth)
+
+	one := builder.AllocateInstruction()
+	one.AsIconst64(1)
+	builder.InsertInstruction(one)
+
+	prev := builder.AllocateInstruction()
+	prev.AsIsub(depth.Return(), one.Return())
+	builder.InsertInstruction(prev)
+
+	store := builder.AllocateInstruction()
+	store.AsStore(prev.Return(), c.execCtxPtrValue, wazevoapi.ExecutionContextOffsets.CallDepth.U32())
+	builder.InsertInstruction(store)
+
+	results := make([]ssa.Value, blk.Params())
+	for i := range results {
+		results[i] = blk.Param(i)
+	}
+	c.insertJumpToBlock(results, builder.ReturnBlock())
+}
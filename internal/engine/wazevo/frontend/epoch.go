@@ -0,0 +1,43 @@
+package frontend
+
+import (
+	"github.com/tetratelabs/wazero/internal/engine/wazevo/ssa"
+	"github.com/tetratelabs/wazero/internal/engine/wazevo/wazevoapi"
+)
+
+// checkEpoch inserts the check of the epoch against the deadline of the call, if epochs are checked.
+//
+// Like the other engines, this is inserted at the function entry and at each loop header, after the consumption of
+// the fuel. If the epoch reached the deadline, the execution exits with wazevoapi.ExitCodeEpochInterrupted.
+func (c *Compiler) checkEpoch() {
+	if !c.epochCheck {
+		return
+	}
+	builder := c.ssaBuilder
+
+	epochPtr := builder.AllocateInstruction()
+	epochPtr.AsLoad(c.execCtxPtrValue, wazevoapi.ExecutionContextOffsets.EpochPtr.U32(), ssa.TypeI64)
+	builder.InsertInstruction(epochPtr)
+
+	epoch := builder.AllocateInstruction()
+	epoch.AsLoad(epochPtr.Return(), 0, ssa.TypeI64)
+	builder.InsertInstruction(epoch)
+
+	deadline := builder.AllocateInstruction()
+	deadline.AsLoad(c.execCtxPtrValue, wazevoapi.ExecutionContextOffsets.EpochDeadline.U32(), ssa.TypeI64)
+	builder.InsertInstruction(deadline)
+
+	cmp := builder.AllocateInstruction()
+	cmp.AsIcmp(epoch.Return(), deadline.Return(), ssa.IntegerCmpCondUnsignedGreaterThanOrEqual)
+	builder.InsertInstruction(cmp)
+
+	brnz := builder.AllocateInstruction()
+	brnz.AsBrnz(cmp.Return(), nil, c.getOrCreateTrapBlock(wazevoapi.ExitCodeEpochInterrupted))
+	builder.InsertInstruction(brnz)
+
+	// The rest of the block continues in a new one, which has only the current block as predecessor.
+	next := builder.AllocateBasicBlock()
+	c.insertJumpToBlock(nil, next)
+	builder.SetCurrentBlock(next)
+	builder.Seal(next)
+}
@@ -37,16 +37,54 @@ type Compiler struct {
 	loweringState loweringState
 
 	execCtxPtrValue, moduleCtxPtrValue ssa.Value
+
+	// inlining is true if calls to small local functions are inlined. See inline.go.
+	inlining bool
+	// inlinedLocalToVariable is used as wasmLocalToVariable while lowering the body of an inlined function.
+	inlinedLocalToVariable map[wasm.Index]ssa.Variable
+	// inlinedArgs is reused to hold the arguments to an inlined function.
+	inlinedArgs []ssa.Value
+
+	// meterFuel is true if each basic block consumes the fuel of its instructions. See fuel.go.
+	meterFuel bool
+	// fuelCost is the constant instruction holding the cost of the current block, which is set when the block ends.
+	fuelCost *ssa.Instruction
+	// fuelInstructions is the count of the instructions consuming fuel in the current block.
+	fuelInstructions uint64
+
+	// epochCheck is true if the epoch is checked at the function entry and loop headers. See epoch.go.
+	epochCheck bool
+
+	// countCallDepth is true if the call depth is counted in the function entry and exit. See call_depth.go.
+	countCallDepth bool
+	// callDepthExitBlk is the block through which the current function returns if countCallDepth is true.
+	callDepthExitBlk ssa.BasicBlock
 }
 
 // NewFrontendCompiler returns a frontend Compiler.
-func NewFrontendCompiler(m *wasm.Module, ssaBuilder ssa.Builder, offset *wazevoapi.ModuleContextOffsetData) *Compiler {
+//
+// When `inlining` is true, calls to small local functions which never trap are inlined into the caller.
+// This must be false when function listeners are in use since the inlined functions won't be observable.
+//
+// When `meterFuel` is true, each basic block consumes the fuel of its instructions, like the other engines.
+//
+// When `checkEpoch` is true, the epoch is checked against the deadline of the call at the function entry and at
+// each loop header, like the other engines.
+//
+// When `countCallDepth` is true, the number of functions executing in the call is counted, and the call exits
+// when it exceeds the maximum of the execution context, like the other engines.
+func NewFrontendCompiler(m *wasm.Module, ssaBuilder ssa.Builder, offset *wazevoapi.ModuleContextOffsetData, inlining, meterFuel, checkEpoch, countCallDepth bool) *Compiler {
 	c := &Compiler{
-		m:                   m,
-		ssaBuilder:          ssaBuilder,
-		br:                  bytes.NewReader(nil),
-		wasmLocalToVariable: make(map[wasm.Index]ssa.Variable),
-		offset:              offset,
+		m:                      m,
+		ssaBuilder:             ssaBuilder,
+		br:                     bytes.NewReader(nil),
+		wasmLocalToVariable:    make(map[wasm.Index]ssa.Variable),
+		offset:                 offset,
+		inlining:               inlining,
+		inlinedLocalToVariable: make(map[wasm.Index]ssa.Variable),
+		meterFuel:              meterFuel,
+		epochCheck:             checkEpoch,
+		countCallDepth:         countCallDepth,
 	}
 
 	c.signatures = make(map[*wasm.FunctionType]*ssa.Signature, len(m.TypeSection))
@@ -82,6 +120,8 @@ func (c *Compiler) Init(idx wasm.Index, typ *wasm.FunctionType, localTypes []was
 	c.wasmFunctionTyp = typ
 	c.wasmFunctionLocalTypes = localTypes
 	c.wasmFunctionBody = body
+	c.fuelCost, c.fuelInstructions = nil, 0
+	c.callDepthExitBlk = nil
 }
 
 // Note: this assumes 64-bit platform (I believe we won't have 32-bit backend ;)).
@@ -129,8 +169,16 @@ func (c *Compiler) LowerToSSA() error {
 		c.wasmLocalToVariable[wasm.Index(i)] = variable
 	}
 	c.declareWasmLocals(entryBlock)
+	c.incrementCallDepth()
+	c.consumeFuel()
+	// Check the epoch on entry, as recursion can run for long without any loop.
+	c.checkEpoch()
 
-	c.lowerBody(entryBlock)
+	if err := c.lowerBody(entryBlock); err != nil {
+		return err
+	}
+	c.setFuelCost()
+	c.emitCallDepthExitBlock()
 	c.emitTrapBlocks()
 	return nil
 }
@@ -148,26 +196,30 @@ func (c *Compiler) declareWasmLocals(entry ssa.BasicBlock) {
 		variable := c.ssaBuilder.DeclareVariable(st)
 		c.wasmLocalToVariable[wasm.Index(i)+localCount] = variable
 
-		zeroInst := c.ssaBuilder.AllocateInstruction()
-		switch st {
-		case ssa.TypeI32:
-			zeroInst.AsIconst32(0)
-		case ssa.TypeI64:
-			zeroInst.AsIconst64(0)
-		case ssa.TypeF32:
-			zeroInst.AsF32const(0)
-		case ssa.TypeF64:
-			zeroInst.AsF64const(0)
-		default:
-			panic("TODO: " + wasm.ValueTypeName(typ))
-		}
-
-		c.ssaBuilder.InsertInstruction(zeroInst)
-		value := zeroInst.Return()
+		value := c.insertZeroValue(typ)
 		c.ssaBuilder.DefineVariable(variable, value, entry)
 	}
 }
 
+// insertZeroValue inserts the instruction producing the zero value of the given type into the current block.
+func (c *Compiler) insertZeroValue(typ wasm.ValueType) ssa.Value {
+	zeroInst := c.ssaBuilder.AllocateInstruction()
+	switch wasmToSSA(typ) {
+	case ssa.TypeI32:
+		zeroInst.AsIconst32(0)
+	case ssa.TypeI64:
+		zeroInst.AsIconst64(0)
+	case ssa.TypeF32:
+		zeroInst.AsF32const(0)
+	case ssa.TypeF64:
+		zeroInst.AsF64const(0)
+	default:
+		panic("TODO: " + wasm.ValueTypeName(typ))
+	}
+	c.ssaBuilder.InsertInstruction(zeroInst)
+	return zeroInst.Return()
+}
+
 // wasmToSSA converts wasm.ValueType to ssa.Type.
 func wasmToSSA(vt wasm.ValueType) ssa.Type {
 	switch vt {
@@ -1,6 +1,7 @@
 package frontend
 
 import (
+	"bytes"
 	"fmt"
 	"testing"
 
@@ -26,6 +27,14 @@ func TestCompiler_LowerToSSA(t *testing.T) {
 		exp string
 		// expAfterOpt is not empty when we want to check the result after optimization passes.
 		expAfterOpt string
+		// inlining is true if the inlining of small functions is enabled.
+		inlining bool
+		// meterFuel is true if the fuel consumption is inserted.
+		meterFuel bool
+		// checkEpoch is true if the epoch checks are inserted.
+		checkEpoch bool
+		// countCallDepth is true if the call depth is counted.
+		countCallDepth bool
 	}{
 		{
 			name: "empty", m: testcases.Empty.Module,
@@ -234,6 +243,267 @@ blk1: () <-- (blk0,blk1)
 
 blk3: () <-- (blk1)
 	Return
+`,
+		},
+		{
+			name: "loop - br_if with fuel", m: testcases.LoopBrIf.Module, meterFuel: true,
+			exp: `
+blk0: (exec_ctx:i64, module_ctx:i64)
+	v2:i64 = Iconst_64 0x0
+	v3:i64 = Load exec_ctx, 0x48
+	v4:i64 = Isub v3, v2
+	v5:i64 = Iconst_64 0x0
+	v6:i32 = Icmp lt_s, v4, v5
+	Brnz v6, blk1
+	Jump blk2
+
+blk1: () <-- (blk0,blk3,blk6,blk4)
+	v23:i32 = Iconst_32 0x3
+	Store v23, exec_ctx, 0x0
+	Trap exec_ctx
+
+blk2: () <-- (blk0)
+	Store v4, exec_ctx, 0x48
+	Jump blk3
+
+blk3: () <-- (blk2,blk5)
+	v7:i64 = Iconst_64 0x2
+	v8:i64 = Load exec_ctx, 0x48
+	v9:i64 = Isub v8, v7
+	v10:i64 = Iconst_64 0x0
+	v11:i32 = Icmp lt_s, v9, v10
+	Brnz v11, blk1
+	Jump blk5
+
+blk4: ()
+	v18:i64 = Iconst_64 0x0
+	v19:i64 = Load exec_ctx, 0x48
+	v20:i64 = Isub v19, v18
+	v21:i64 = Iconst_64 0x0
+	v22:i32 = Icmp lt_s, v20, v21
+	Brnz v22, blk1
+	Jump blk8
+
+blk5: () <-- (blk3)
+	Store v9, exec_ctx, 0x48
+	v12:i32 = Iconst_32 0x1
+	Brnz v12, blk3
+	Jump blk6
+
+blk6: () <-- (blk5)
+	v13:i64 = Iconst_64 0x1
+	v14:i64 = Load exec_ctx, 0x48
+	v15:i64 = Isub v14, v13
+	v16:i64 = Iconst_64 0x0
+	v17:i32 = Icmp lt_s, v15, v16
+	Brnz v17, blk1
+	Jump blk7
+
+blk7: () <-- (blk6)
+	Store v15, exec_ctx, 0x48
+	Return
+
+blk8: () <-- (blk4)
+	Store v20, exec_ctx, 0x48
+	Jump blk_ret
+`,
+		},
+		{
+			name: "loop - br_if with epoch", m: testcases.LoopBrIf.Module, checkEpoch: true,
+			exp: `
+blk0: (exec_ctx:i64, module_ctx:i64)
+	v2:i64 = Load exec_ctx, 0x450
+	v3:i64 = Load v2, 0x0
+	v4:i64 = Load exec_ctx, 0x458
+	v5:i32 = Icmp ge_u, v3, v4
+	Brnz v5, blk1
+	Jump blk2
+
+blk1: () <-- (blk0,blk3)
+	v11:i32 = Iconst_32 0x4
+	Store v11, exec_ctx, 0x0
+	Trap exec_ctx
+
+blk2: () <-- (blk0)
+	Jump blk3
+
+blk3: () <-- (blk2,blk5)
+	v6:i64 = Load exec_ctx, 0x450
+	v7:i64 = Load v6, 0x0
+	v8:i64 = Load exec_ctx, 0x458
+	v9:i32 = Icmp ge_u, v7, v8
+	Brnz v9, blk1
+	Jump blk5
+
+blk4: ()
+	Jump blk_ret
+
+blk5: () <-- (blk3)
+	v10:i32 = Iconst_32 0x1
+	Brnz v10, blk3
+	Jump blk6
+
+blk6: () <-- (blk5)
+	Return
+`,
+		},
+		{
+			name: "block - end - loop - end with fuel", m: testcases.BlockEndLoopEnd.Module, meterFuel: true,
+			exp: `
+blk0: (exec_ctx:i64, module_ctx:i64, v2:i32)
+	v3:i64 = Iconst_64 0x2
+	v4:i64 = Load exec_ctx, 0x48
+	v5:i64 = Isub v4, v3
+	v6:i64 = Iconst_64 0x0
+	v7:i32 = Icmp lt_s, v5, v6
+	Brnz v7, blk1
+	Jump blk2
+
+blk1: () <-- (blk0,blk4)
+	v14:i32 = Iconst_32 0x3
+	Store v14, exec_ctx, 0x0
+	Trap exec_ctx
+
+blk2: () <-- (blk0)
+	Store v5, exec_ctx, 0x48
+	Jump blk3
+
+blk3: () <-- (blk2)
+	Jump blk4, v2
+
+blk4: (v13:i32) <-- (blk3)
+	v8:i64 = Iconst_64 0x4
+	v9:i64 = Load exec_ctx, 0x48
+	v10:i64 = Isub v9, v8
+	v11:i64 = Iconst_64 0x0
+	v12:i32 = Icmp lt_s, v10, v11
+	Brnz v12, blk1
+	Jump blk6
+
+blk5: () <-- (blk6)
+	Jump blk_ret
+
+blk6: () <-- (blk4)
+	Store v10, exec_ctx, 0x48
+	Jump blk5
+`,
+		},
+		{
+			name: "block - br_if with fuel", m: testcases.BlockBrIf.Module, meterFuel: true,
+			exp: `
+blk0: (exec_ctx:i64, module_ctx:i64)
+	v2:i32 = Iconst_32 0x0
+	v3:i64 = Iconst_64 0x2
+	v4:i64 = Load exec_ctx, 0x48
+	v5:i64 = Isub v4, v3
+	v6:i64 = Iconst_64 0x0
+	v7:i32 = Icmp lt_s, v5, v6
+	Brnz v7, blk1
+	Jump blk2
+
+blk1: () <-- (blk0,blk4,blk3)
+	v19:i32 = Iconst_32 0x3
+	Store v19, exec_ctx, 0x0
+	Trap exec_ctx
+
+blk2: () <-- (blk0)
+	Store v5, exec_ctx, 0x48
+	Brnz v2, blk3
+	Jump blk4
+
+blk3: () <-- (blk2)
+	v13:i64 = Iconst_64 0x0
+	v14:i64 = Load exec_ctx, 0x48
+	v15:i64 = Isub v14, v13
+	v16:i64 = Iconst_64 0x0
+	v17:i32 = Icmp lt_s, v15, v16
+	Brnz v17, blk1
+	Jump blk7
+
+blk4: () <-- (blk2)
+	v8:i64 = Iconst_64 0x1
+	v9:i64 = Load exec_ctx, 0x48
+	v10:i64 = Isub v9, v8
+	v11:i64 = Iconst_64 0x0
+	v12:i32 = Icmp lt_s, v10, v11
+	Brnz v12, blk1
+	Jump blk5
+
+blk5: () <-- (blk4)
+	Store v10, exec_ctx, 0x48
+	Jump blk6
+
+blk6: () <-- (blk5)
+	v18:i32 = Iconst_32 0x2
+	Store v18, exec_ctx, 0x0
+	Trap exec_ctx
+
+blk7: () <-- (blk3)
+	Store v15, exec_ctx, 0x48
+	Jump blk_ret
+`,
+		},
+		{
+			name: "if-else with fuel", m: testcases.IfElse.Module, meterFuel: true,
+			exp: `
+blk0: (exec_ctx:i64, module_ctx:i64)
+	v2:i32 = Iconst_32 0x0
+	v3:i64 = Iconst_64 0x2
+	v4:i64 = Load exec_ctx, 0x48
+	v5:i64 = Isub v4, v3
+	v6:i64 = Iconst_64 0x0
+	v7:i32 = Icmp lt_s, v5, v6
+	Brnz v7, blk1
+	Jump blk2
+
+blk1: () <-- (blk0,blk3,blk4,blk5)
+	v23:i32 = Iconst_32 0x3
+	Store v23, exec_ctx, 0x0
+	Trap exec_ctx
+
+blk2: () <-- (blk0)
+	Store v5, exec_ctx, 0x48
+	Brz v2, blk4
+	Jump blk3
+
+blk3: () <-- (blk2)
+	v8:i64 = Iconst_64 0x0
+	v9:i64 = Load exec_ctx, 0x48
+	v10:i64 = Isub v9, v8
+	v11:i64 = Iconst_64 0x0
+	v12:i32 = Icmp lt_s, v10, v11
+	Brnz v12, blk1
+	Jump blk6
+
+blk4: () <-- (blk2)
+	v13:i64 = Iconst_64 0x1
+	v14:i64 = Load exec_ctx, 0x48
+	v15:i64 = Isub v14, v13
+	v16:i64 = Iconst_64 0x0
+	v17:i32 = Icmp lt_s, v15, v16
+	Brnz v17, blk1
+	Jump blk7
+
+blk5: () <-- (blk6)
+	v18:i64 = Iconst_64 0x0
+	v19:i64 = Load exec_ctx, 0x48
+	v20:i64 = Isub v19, v18
+	v21:i64 = Iconst_64 0x0
+	v22:i32 = Icmp lt_s, v20, v21
+	Brnz v22, blk1
+	Jump blk8
+
+blk6: () <-- (blk3)
+	Store v10, exec_ctx, 0x48
+	Jump blk5
+
+blk7: () <-- (blk4)
+	Store v15, exec_ctx, 0x48
+	Jump blk_ret
+
+blk8: () <-- (blk5)
+	Store v20, exec_ctx, 0x48
+	Jump blk_ret
 `,
 		},
 		{
@@ -475,6 +745,113 @@ blk0: (exec_ctx:i64, module_ctx:i64)
 	Store module_ctx, exec_ctx, 0x8
 	v5:i32, v6:i32 = Call f3:sig3, exec_ctx, module_ctx, v4
 	Jump blk_ret, v5, v6
+`,
+		},
+		{
+			name:     "call_inlined",
+			m:        testcases.Call.Module,
+			inlining: true,
+			exp: `
+blk0: (exec_ctx:i64, module_ctx:i64)
+	v2:i32 = Iconst_32 0x28
+	v3:i32 = Iconst_32 0x5
+	v4:i32 = Iadd v2, v3
+	Jump blk_ret, v4, v4
+`,
+			expAfterOpt: `
+blk0: (exec_ctx:i64, module_ctx:i64)
+	v4:i32 = Iconst_32 0x2d
+	Jump blk_ret, v4, v4
+`,
+		},
+		{
+			name: "only_return with call depth", m: testcases.OnlyReturn.Module, countCallDepth: true,
+			exp: `
+blk0: (exec_ctx:i64, module_ctx:i64)
+	v2:i64 = Load exec_ctx, 0x460
+	v3:i64 = Iconst_64 0x1
+	v4:i64 = Iadd v2, v3
+	v5:i64 = Load exec_ctx, 0x468
+	v6:i32 = Icmp gt_u, v4, v5
+	Brnz v6, blk1
+	Jump blk2
+
+blk1: () <-- (blk0)
+	v10:i32 = Iconst_32 0x5
+	Store v10, exec_ctx, 0x0
+	Trap exec_ctx
+
+blk2: () <-- (blk0)
+	Store v4, exec_ctx, 0x460
+	Jump blk3
+
+blk3: () <-- (blk2)
+	v7:i64 = Load exec_ctx, 0x460
+	v8:i64 = Iconst_64 0x1
+	v9:i64 = Isub v7, v8
+	Store v9, exec_ctx, 0x460
+	Jump blk_ret
+`,
+		},
+		{
+			name:     "call_inlined with call depth",
+			m:        testcases.Call.Module,
+			inlining: true, countCallDepth: true,
+			exp: `
+blk0: (exec_ctx:i64, module_ctx:i64)
+	v2:i64 = Load exec_ctx, 0x460
+	v3:i64 = Iconst_64 0x1
+	v4:i64 = Iadd v2, v3
+	v5:i64 = Load exec_ctx, 0x468
+	v6:i32 = Icmp gt_u, v4, v5
+	Brnz v6, blk1
+	Jump blk2
+
+blk1: () <-- (blk0,blk2,blk4,blk5)
+	v30:i32 = Iconst_32 0x5
+	Store v30, exec_ctx, 0x0
+	Trap exec_ctx
+
+blk2: () <-- (blk0)
+	Store v4, exec_ctx, 0x460
+	v9:i64 = Load exec_ctx, 0x460
+	v10:i64 = Iconst_64 0x1
+	v11:i64 = Iadd v9, v10
+	v12:i64 = Load exec_ctx, 0x468
+	v13:i32 = Icmp gt_u, v11, v12
+	Brnz v13, blk1
+	Jump blk4
+
+blk3: (v7:i32,v8:i32) <-- (blk6)
+	v27:i64 = Load exec_ctx, 0x460
+	v28:i64 = Iconst_64 0x1
+	v29:i64 = Isub v27, v28
+	Store v29, exec_ctx, 0x460
+	Jump blk_ret, v7, v8
+
+blk4: () <-- (blk2)
+	v14:i32 = Iconst_32 0x28
+	v15:i32 = Iconst_32 0x5
+	v16:i64 = Load exec_ctx, 0x460
+	v17:i64 = Iconst_64 0x1
+	v18:i64 = Iadd v16, v17
+	v19:i64 = Load exec_ctx, 0x468
+	v20:i32 = Icmp gt_u, v18, v19
+	Brnz v20, blk1
+	Jump blk5
+
+blk5: () <-- (blk4)
+	v21:i32 = Iadd v14, v15
+	v22:i64 = Load exec_ctx, 0x460
+	v23:i64 = Iconst_64 0x1
+	v24:i64 = Iadd v22, v23
+	v25:i64 = Load exec_ctx, 0x468
+	v26:i32 = Icmp gt_u, v24, v25
+	Brnz v26, blk1
+	Jump blk6
+
+blk6: () <-- (blk5)
+	Jump blk3, v21, v21
 `,
 		},
 		{
@@ -893,7 +1270,7 @@ blk0: (exec_ctx:i64, module_ctx:i64, v2:i32)
 			b := ssa.NewBuilder()
 
 			offset := wazevoapi.NewModuleContextOffsetData(tc.m)
-			fc := NewFrontendCompiler(tc.m, b, &offset)
+			fc := NewFrontendCompiler(tc.m, b, &offset, tc.inlining, tc.meterFuel, tc.checkEpoch, tc.countCallDepth)
 			typeIndex := tc.m.FunctionSection[tc.targetIndex]
 			code := &tc.m.CodeSection[tc.targetIndex]
 			fc.Init(tc.targetIndex, &tc.m.TypeSection[typeIndex], code.LocalTypes, code.Body)
@@ -917,3 +1294,113 @@ blk0: (exec_ctx:i64, module_ctx:i64, v2:i32)
 		})
 	}
 }
+
+func TestCompiler_LowerToSSA_unsupported(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		body   []byte
+		expErr string
+	}{
+		{
+			name:   "return_call",
+			body:   []byte{wasm.OpcodeReturnCall, 0, wasm.OpcodeEnd},
+			expErr: "tail calls are not supported yet",
+		},
+		{
+			name:   "return_call_indirect",
+			body:   []byte{wasm.OpcodeI32Const, 0, wasm.OpcodeReturnCallIndirect, 0, 0, wasm.OpcodeEnd},
+			expErr: "tail calls are not supported yet",
+		},
+		{
+			name:   "unreachable return_call",
+			body:   []byte{wasm.OpcodeUnreachable, wasm.OpcodeReturnCall, 0, wasm.OpcodeEnd},
+			expErr: "tail calls are not supported yet",
+		},
+		{
+			name:   "try_table",
+			body:   []byte{wasm.OpcodeTryTable, 0x40, 0, wasm.OpcodeEnd, wasm.OpcodeEnd},
+			expErr: "exception handling is not supported yet",
+		},
+		{
+			name:   "throw_ref",
+			body:   []byte{wasm.OpcodeUnreachable, wasm.OpcodeThrowRef, wasm.OpcodeEnd},
+			expErr: "exception handling is not supported yet",
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			m := &wasm.Module{
+				TypeSection:     []wasm.FunctionType{{}},
+				FunctionSection: []wasm.Index{0},
+				TableSection:    []wasm.Table{{Min: 1, Type: wasm.RefTypeFuncref}},
+				CodeSection:     []wasm.Code{{Body: tc.body}},
+			}
+			err := m.Validate(api.CoreFeaturesV2 | api.CoreFeatureTailCall | api.CoreFeatureExceptionHandling)
+			require.NoError(t, err, "invalid test case module!")
+
+			offset := wazevoapi.NewModuleContextOffsetData(m)
+			fc := NewFrontendCompiler(m, ssa.NewBuilder(), &offset, false, false, false, false)
+			fc.Init(0, &m.TypeSection[0], nil, tc.body)
+			require.EqualError(t, fc.LowerToSSA(), tc.expErr)
+		})
+	}
+}
+
+func TestCompiler_inlinable(t *testing.T) {
+	i32, v128, externref := wasm.ValueTypeI32, wasm.ValueTypeV128, wasm.ValueTypeExternref
+	for _, tc := range []struct {
+		name   string
+		typ    wasm.FunctionType
+		locals []wasm.ValueType
+		exp    bool
+	}{
+		{name: "numeric", typ: wasm.FunctionType{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32}}, locals: []wasm.ValueType{i32}, exp: true},
+		{name: "v128 param", typ: wasm.FunctionType{Params: []wasm.ValueType{v128}}},
+		{name: "ref result", typ: wasm.FunctionType{Results: []wasm.ValueType{externref}}},
+		{name: "v128 local", locals: []wasm.ValueType{v128}},
+		{name: "ref local", locals: []wasm.ValueType{externref}},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			c := &Compiler{m: &wasm.Module{
+				TypeSection:     []wasm.FunctionType{{}, tc.typ},
+				FunctionSection: []wasm.Index{0, 1},
+				CodeSection:     []wasm.Code{{Body: []byte{wasm.OpcodeEnd}}, {LocalTypes: tc.locals, Body: []byte{wasm.OpcodeEnd}}},
+			}}
+			require.Equal(t, tc.exp, c.inlinable(1))
+		})
+	}
+}
+
+func Test_inlinableBody(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		body []byte
+		exp  bool
+	}{
+		{name: "empty", body: []byte{wasm.OpcodeEnd}, exp: true},
+		{name: "add", body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 1, wasm.OpcodeI32Add, wasm.OpcodeEnd}, exp: true},
+		{
+			name: "const with end in immediate",
+			body: []byte{wasm.OpcodeI32Const, wasm.OpcodeEnd, wasm.OpcodeEnd},
+			exp:  true,
+		},
+		{name: "call", body: []byte{wasm.OpcodeCall, 0, wasm.OpcodeEnd}},
+		{name: "unreachable", body: []byte{wasm.OpcodeUnreachable, wasm.OpcodeEnd}},
+		{name: "control flow", body: []byte{wasm.OpcodeBlock, 0x40, wasm.OpcodeEnd, wasm.OpcodeEnd}},
+		{
+			name: "over budget",
+			body: append(bytes.Repeat([]byte{wasm.OpcodeNop}, inlineBudget+1), wasm.OpcodeEnd),
+		},
+		{
+			name: "within budget",
+			body: append(bytes.Repeat([]byte{wasm.OpcodeNop}, inlineBudget), wasm.OpcodeEnd),
+			exp:  true,
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.exp, inlinableBody(tc.body))
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package frontend
+
+import (
+	"github.com/tetratelabs/wazero/internal/engine/wazevo/ssa"
+	"github.com/tetratelabs/wazero/internal/engine/wazevo/wazevoapi"
+)
+
+// consumeFuel inserts the consumption of the fuel of the block which starts at the current one, if fuel is metered.
+//
+// Like the other engines, the cost of a block is the count of its Wasm instructions which consume fuel (see
+// wasm.ConsumesFuel), and is consumed before any of them is executed, as blocks are only entered from their
+// beginning. If the remaining fuel is less than the cost, the execution exits with wazevoapi.ExitCodeOutOfFuel
+// without consuming it.
+//
+// The cost isn't known until the block ends, so this inserts a constant which is set by setFuelCost.
+func (c *Compiler) consumeFuel() {
+	if !c.meterFuel {
+		return
+	}
+	c.setFuelCost()
+	builder := c.ssaBuilder
+
+	cost := builder.AllocateInstruction()
+	cost.AsIconst64(0)
+	builder.InsertInstruction(cost)
+	c.fuelCost = cost
+
+	load := builder.AllocateInstruction()
+	load.AsLoad(c.execCtxPtrValue, wazevoapi.ExecutionContextOffsets.Fuel.U32(), ssa.TypeI64)
+	builder.InsertInstruction(load)
+
+	sub := builder.AllocateInstruction()
+	sub.AsIsub(load.Return(), cost.Return())
+	builder.InsertInstruction(sub)
+	remaining := sub.Return()
+
+	zero := builder.AllocateInstruction()
+	zero.AsIconst64(0)
+	builder.InsertInstruction(zero)
+
+	cmp := builder.AllocateInstruction()
+	cmp.AsIcmp(remaining, zero.Return(), ssa.IntegerCmpCondSignedLessThan)
+	builder.InsertInstruction(cmp)
+
+	brnz := builder.AllocateInstruction()
+	brnz.AsBrnz(cmp.Return(), nil, c.getOrCreateTrapBlock(wazevoapi.ExitCodeOutOfFuel))
+	builder.InsertInstruction(brnz)
+
+	// The rest of the block continues in a new one, which has only the current block as predecessor.
+	next := builder.AllocateBasicBlock()
+	c.insertJumpToBlock(nil, next)
+	builder.SetCurrentBlock(next)
+	builder.Seal(next)
+
+	store := builder.AllocateInstruction()
+	store.AsStore(remaining, c.execCtxPtrValue, wazevoapi.ExecutionContextOffsets.Fuel.U32())
+	builder.InsertInstruction(store)
+}
+
+// setFuelCost sets the cost of the current block inserted by consumeFuel, if any.
+func (c *Compiler) setFuelCost() {
+	if c.fuelCost != nil {
+		c.fuelCost.AsIconst64(c.fuelInstructions)
+		c.fuelCost, c.fuelInstructions = nil, 0
+	}
+}
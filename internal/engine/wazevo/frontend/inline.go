@@ -0,0 +1,145 @@
+package frontend
+
+import (
+	"github.com/tetratelabs/wazero/internal/engine/wazevo/ssa"
+	"github.com/tetratelabs/wazero/internal/leb128"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// inlineBudget is the maximum number of Wasm instructions (excluding the final end) in a function body
+// to be inlined at call sites.
+const inlineBudget = 16
+
+// inlinable returns true if the call to the function at `fnIndex` can be inlined into the current function.
+//
+// Only local, non-recursive functions whose body is a small straight-line sequence of non-trapping instructions
+// are inlined. Since such a function never traps nor calls other functions, the inlined frame can never appear
+// in a stack trace, and therefore the traces stay the same as if it was not inlined.
+func (c *Compiler) inlinable(fnIndex wasm.Index) bool {
+	if fnIndex < c.m.ImportFunctionCount {
+		return false
+	}
+	localIndex := fnIndex - c.m.ImportFunctionCount
+	if localIndex == c.wasmLocalFunctionIndex {
+		return false
+	}
+	code := &c.m.CodeSection[localIndex]
+	if code.GoFunc != nil {
+		return false
+	}
+	typ := &c.m.TypeSection[c.m.FunctionSection[localIndex]]
+	if !numericTypes(typ.Params) || !numericTypes(typ.Results) || !numericTypes(code.LocalTypes) {
+		return false // insertZeroValue and wasmToSSA only support the numeric types.
+	}
+	return inlinableBody(code.Body)
+}
+
+// numericTypes returns true if all the given types are i32, i64, f32 or f64.
+func numericTypes(tps []wasm.ValueType) bool {
+	for _, tp := range tps {
+		switch tp {
+		case wasm.ValueTypeI32, wasm.ValueTypeI64, wasm.ValueTypeF32, wasm.ValueTypeF64:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// inlinableBody returns true if the given function body only consists of at most inlineBudget
+// instructions which are non-trapping and never transfer the control.
+func inlinableBody(body []byte) bool {
+	var count int
+	for pc := 0; pc < len(body); pc++ {
+		op := body[pc]
+		if op == wasm.OpcodeEnd {
+			// The only end must be the one of the function.
+			return pc == len(body)-1
+		}
+
+		count++
+		if count > inlineBudget {
+			return false
+		}
+
+		var err error
+		var n uint64
+		switch op {
+		case wasm.OpcodeLocalGet, wasm.OpcodeLocalSet:
+			_, n, err = leb128.LoadUint32(body[pc+1:])
+		case wasm.OpcodeI32Const:
+			_, n, err = leb128.LoadInt32(body[pc+1:])
+		case wasm.OpcodeI64Const:
+			_, n, err = leb128.LoadInt64(body[pc+1:])
+		case wasm.OpcodeF32Const:
+			n = 4
+		case wasm.OpcodeF64Const:
+			n = 8
+		case wasm.OpcodeNop, wasm.OpcodeDrop,
+			wasm.OpcodeI32Add, wasm.OpcodeI64Add, wasm.OpcodeI32Sub, wasm.OpcodeI64Sub, wasm.OpcodeI32Mul, wasm.OpcodeI64Mul,
+			wasm.OpcodeI32Shl, wasm.OpcodeI64Shl, wasm.OpcodeI32ShrU, wasm.OpcodeI64ShrU, wasm.OpcodeI32ShrS, wasm.OpcodeI64ShrS,
+			wasm.OpcodeI32Eq, wasm.OpcodeI64Eq, wasm.OpcodeI32Ne, wasm.OpcodeI64Ne,
+			wasm.OpcodeI32LtS, wasm.OpcodeI64LtS, wasm.OpcodeI32LtU, wasm.OpcodeI64LtU,
+			wasm.OpcodeI32GtS, wasm.OpcodeI64GtS, wasm.OpcodeI32GtU, wasm.OpcodeI64GtU,
+			wasm.OpcodeI32LeS, wasm.OpcodeI64LeS, wasm.OpcodeI32LeU, wasm.OpcodeI64LeU,
+			wasm.OpcodeI32GeS, wasm.OpcodeI64GeS, wasm.OpcodeI32GeU, wasm.OpcodeI64GeU,
+			wasm.OpcodeI64Extend8S, wasm.OpcodeI64Extend16S, wasm.OpcodeI64Extend32S,
+			wasm.OpcodeI64ExtendI32S, wasm.OpcodeI64ExtendI32U, wasm.OpcodeI32Extend8S, wasm.OpcodeI32Extend16S,
+			wasm.OpcodeF32Add, wasm.OpcodeF64Add, wasm.OpcodeF32Sub, wasm.OpcodeF64Sub,
+			wasm.OpcodeF32Mul, wasm.OpcodeF64Mul, wasm.OpcodeF32Div, wasm.OpcodeF64Div,
+			wasm.OpcodeF32Max, wasm.OpcodeF64Max, wasm.OpcodeF32Min, wasm.OpcodeF64Min,
+			wasm.OpcodeF32Eq, wasm.OpcodeF64Eq, wasm.OpcodeF32Ne, wasm.OpcodeF64Ne,
+			wasm.OpcodeF32Lt, wasm.OpcodeF64Lt, wasm.OpcodeF32Gt, wasm.OpcodeF64Gt,
+			wasm.OpcodeF32Le, wasm.OpcodeF64Le, wasm.OpcodeF32Ge, wasm.OpcodeF64Ge:
+		default:
+			return false
+		}
+		if err != nil {
+			return false
+		}
+		pc += int(n)
+	}
+	return false
+}
+
+// lowerInlinedCall lowers the body of the function at `fnIndex` directly into the current block
+// instead of emitting a call. The arguments are popped from, and the results are pushed onto the value stack.
+//
+// This must be called only when inlinable returns true.
+func (c *Compiler) lowerInlinedCall(fnIndex wasm.Index) {
+	builder, state := c.ssaBuilder, &c.loweringState
+	localIndex := fnIndex - c.m.ImportFunctionCount
+	typ := &c.m.TypeSection[c.m.FunctionSection[localIndex]]
+	code := &c.m.CodeSection[localIndex]
+
+	vars := c.inlinedLocalToVariable
+	for k := range vars {
+		delete(vars, k)
+	}
+
+	argN := len(typ.Params)
+	if cap(c.inlinedArgs) < argN {
+		c.inlinedArgs = make([]ssa.Value, argN)
+	}
+	args := c.inlinedArgs[:argN]
+	state.nPopInto(argN, args)
+	for i, p := range typ.Params {
+		variable := builder.DeclareVariable(wasmToSSA(p))
+		builder.DefineVariableInCurrentBB(variable, args[i])
+		vars[wasm.Index(i)] = variable
+	}
+	for i, l := range code.LocalTypes {
+		variable := builder.DeclareVariable(wasmToSSA(l))
+		builder.DefineVariableInCurrentBB(variable, c.insertZeroValue(l))
+		vars[wasm.Index(argN+i)] = variable
+	}
+
+	// Lower the body with the callee's locals, and restore the caller's state afterwards.
+	callerVars, callerBody, callerPC := c.wasmLocalToVariable, c.wasmFunctionBody, state.pc
+	c.wasmLocalToVariable, c.wasmFunctionBody = vars, code.Body
+	// Skips the final end since it would otherwise be treated as the end of the caller's control frame.
+	for state.pc = 0; state.pc < len(code.Body)-1; state.pc++ {
+		c.lowerOpcode(code.Body[state.pc])
+	}
+	c.wasmLocalToVariable, c.wasmFunctionBody, state.pc = callerVars, callerBody, callerPC
+}
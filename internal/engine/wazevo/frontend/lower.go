@@ -2,6 +2,7 @@ package frontend
 
 import (
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"math"
 	"strings"
@@ -35,6 +36,8 @@ type (
 		blockType *wasm.FunctionType
 		// clonedArgs hold the arguments to Else block.
 		clonedArgs []ssa.Value
+		// branchedTo is true if this is a block frame which is the target of a reachable branch.
+		branchedTo bool
 	}
 
 	controlFrameKind byte
@@ -120,19 +123,28 @@ func (l *loweringState) ctrlPeekAt(n int) (ret *controlFrame) {
 
 const debug = false
 
-// lowerBody lowers the body of the Wasm function to the SSA form.
-func (c *Compiler) lowerBody(entryBlk ssa.BasicBlock) {
+// lowerBody lowers the body of the Wasm function to the SSA form, or returns an error if it uses an instruction which
+// isn't supported yet, see unsupportedOpcode.
+func (c *Compiler) lowerBody(entryBlk ssa.BasicBlock) error {
 	c.ssaBuilder.Seal(entryBlk)
 
 	// Pushes the empty control frame which corresponds to the function return.
 	c.loweringState.ctrlPush(controlFrame{
 		kind:           controlFrameKindFunction,
 		blockType:      c.wasmFunctionTyp,
-		followingBlock: c.ssaBuilder.ReturnBlock(),
+		followingBlock: c.callDepthExitBlock(),
 	})
 
 	for c.loweringState.pc < len(c.wasmFunctionBody) {
 		op := c.wasmFunctionBody[c.loweringState.pc]
+		// Instructions which switch to another block do so after their other instructions, if any, so they are
+		// charged to the block they end.
+		if err := unsupportedOpcode(op); err != nil {
+			return err
+		}
+		if c.meterFuel && !c.loweringState.unreachable && wasm.ConsumesFuel(op) {
+			c.fuelInstructions++
+		}
 		c.lowerOpcode(op)
 		if debug {
 			fmt.Println("--------- Translated " + wasm.InstructionName(op) + " --------")
@@ -142,8 +154,29 @@ func (c *Compiler) lowerBody(entryBlk ssa.BasicBlock) {
 		}
 		c.loweringState.pc++
 	}
+	return nil
 }
 
+// unsupportedOpcode returns an error if the opcode is of an enabled feature which isn't supported yet, so that only
+// the modules using it fail to compile.
+func unsupportedOpcode(op wasm.Opcode) error {
+	switch op {
+	case wasm.OpcodeReturnCall, wasm.OpcodeReturnCallIndirect:
+		// Tail calls must reuse the frame of the caller, so that the stack doesn't grow with the tail recursion.
+		return errTailCallsUnsupported
+	case wasm.OpcodeTry, wasm.OpcodeCatch, wasm.OpcodeCatchAll, wasm.OpcodeDelegate, wasm.OpcodeThrow,
+		wasm.OpcodeRethrow, wasm.OpcodeThrowRef, wasm.OpcodeTryTable:
+		// Unwinding requires the handlers of each function in the native code.
+		return errExceptionHandlingUnsupported
+	}
+	return nil
+}
+
+var (
+	errTailCallsUnsupported         = errors.New("tail calls are not supported yet")
+	errExceptionHandlingUnsupported = errors.New("exception handling is not supported yet")
+)
+
 func (c *Compiler) lowerOpcode(op wasm.Opcode) {
 	builder := c.ssaBuilder
 	state := &c.loweringState
@@ -486,6 +519,8 @@ func (c *Compiler) lowerOpcode(op wasm.Opcode) {
 		builder.InsertInstruction(br)
 
 		c.switchTo(originalLen, loopHeader)
+		c.consumeFuel()
+		c.checkEpoch()
 
 	case wasm.OpcodeIf:
 		bt := c.readBlockType()
@@ -534,6 +569,7 @@ func (c *Compiler) lowerOpcode(op wasm.Opcode) {
 		// Then and Else (if exists) have only one predecessor.
 		builder.Seal(thenBlk)
 		builder.Seal(elseBlk)
+		c.consumeFuel()
 	case wasm.OpcodeElse:
 		ifctrl := state.ctrlPeekAt(0)
 		ifctrl.kind = controlFrameKindIfWithElse
@@ -561,12 +597,14 @@ func (c *Compiler) lowerOpcode(op wasm.Opcode) {
 		}
 
 		builder.SetCurrentBlock(elseBlk)
+		c.consumeFuel()
 
 	case wasm.OpcodeEnd:
 		ctrl := state.ctrlPop()
 		followingBlk := ctrl.followingBlock
+		unreachable := state.unreachable
 
-		if !state.unreachable {
+		if !unreachable {
 			// Top n-th args will be used as a result of the current control frame.
 			args := c.loweringState.nPeekDup(len(ctrl.blockType.Results))
 
@@ -600,6 +638,12 @@ func (c *Compiler) lowerOpcode(op wasm.Opcode) {
 		// Ready to start translating the following block.
 		c.switchTo(ctrl.originalStackLenWithoutParam, followingBlk)
 
+		// Like the other engines, the following block consumes fuel unless it can only be entered by falling
+		// through the end of a loop or a block which isn't the target of any branch.
+		if unreachable || (ctrl.kind != controlFrameKindLoop && (ctrl.kind != controlFrameKindBlock || ctrl.branchedTo)) {
+			c.consumeFuel()
+		}
+
 	case wasm.OpcodeBr:
 		labelIndex := c.readI32u()
 		if state.unreachable {
@@ -613,6 +657,7 @@ func (c *Compiler) lowerOpcode(op wasm.Opcode) {
 			targetBlk, argNum = targetFrame.blk, len(targetFrame.blockType.Params)
 		} else {
 			targetBlk, argNum = targetFrame.followingBlock, len(targetFrame.blockType.Results)
+			targetFrame.branchedTo = true
 		}
 		args := c.loweringState.nPeekDup(argNum)
 		c.insertJumpToBlock(args, targetBlk)
@@ -634,6 +679,7 @@ func (c *Compiler) lowerOpcode(op wasm.Opcode) {
 			targetBlk, argNum = targetFrame.blk, len(targetFrame.blockType.Params)
 		} else {
 			targetBlk, argNum = targetFrame.followingBlock, len(targetFrame.blockType.Results)
+			targetFrame.branchedTo = true
 		}
 		args := c.loweringState.nPeekDup(argNum)
 
@@ -648,14 +694,19 @@ func (c *Compiler) lowerOpcode(op wasm.Opcode) {
 
 		// Now start translating the instructions after br_if.
 		builder.SetCurrentBlock(elseBlk)
+		c.consumeFuel()
 
 	case wasm.OpcodeNop:
 	case wasm.OpcodeReturn:
 		results := c.loweringState.nPeekDup(c.results())
-		instr := builder.AllocateInstruction()
-
-		instr.AsReturn(results)
-		builder.InsertInstruction(instr)
+		if c.callDepthExitBlk != nil {
+			// The call depth is decremented before returning.
+			c.insertJumpToBlock(results, c.callDepthExitBlk)
+		} else {
+			instr := builder.AllocateInstruction()
+			instr.AsReturn(results)
+			builder.InsertInstruction(instr)
+		}
 		state.unreachable = true
 
 	case wasm.OpcodeUnreachable:
@@ -670,49 +721,7 @@ func (c *Compiler) lowerOpcode(op wasm.Opcode) {
 		if state.unreachable {
 			return
 		}
-
-		// Before transfer the control to the callee, we have to store the current module's moduleContextPtr
-		// into execContext.callerModuleContextPtr in case when the callee is a Go function.
-		//
-		// TODO: maybe this can be optimized out if this is in-module function calls. Investigate later.
-		c.storeCallerModuleContext()
-
-		typIndex := c.m.FunctionSection[fnIndex]
-		typ := &c.m.TypeSection[typIndex]
-
-		// TODO: reuse slice?
-		argN := len(typ.Params)
-		args := make([]ssa.Value, argN+2)
-		args[0] = c.execCtxPtrValue
-		state.nPopInto(argN, args[2:])
-
-		sig := c.signatures[typ]
-		call := builder.AllocateInstruction()
-		if fnIndex >= c.m.ImportFunctionCount {
-			args[1] = c.moduleCtxPtrValue // This case the callee module is itself.
-			call.AsCall(FunctionIndexToFuncRef(fnIndex), sig, args)
-			builder.InsertInstruction(call)
-		} else {
-			// This case we have to read the address of the imported function from the module context.
-			moduleCtx := c.moduleCtxPtrValue
-			loadFuncPtr, loadModuleCtxPtr := builder.AllocateInstruction(), builder.AllocateInstruction()
-			funcPtrOffset, moduleCtxPtrOffset := c.offset.ImportedFunctionOffset(fnIndex)
-			loadFuncPtr.AsLoad(moduleCtx, funcPtrOffset.U32(), ssa.TypeI64)
-			loadModuleCtxPtr.AsLoad(moduleCtx, moduleCtxPtrOffset.U32(), ssa.TypeI64)
-			builder.InsertInstruction(loadFuncPtr)
-			builder.InsertInstruction(loadModuleCtxPtr)
-
-			args[1] = loadModuleCtxPtr.Return() // This case the callee module is itself.
-
-			call.AsCallIndirect(loadFuncPtr.Return(), sig, args)
-			builder.InsertInstruction(call)
-		}
-
-		first, rest := call.Returns()
-		state.push(first)
-		for _, v := range rest {
-			state.push(v)
-		}
+		c.lowerCall(fnIndex)
 	case wasm.OpcodeDrop:
 		_ = state.pop()
 	default:
@@ -740,6 +749,60 @@ func (c *Compiler) insertFcmp(cond ssa.FloatCmpCond) {
 	state.push(value)
 }
 
+// lowerCall lowers the call to the function at `fnIndex`. The arguments are popped from, and the results are pushed
+// onto the value stack.
+func (c *Compiler) lowerCall(fnIndex wasm.Index) {
+	builder, state := c.ssaBuilder, &c.loweringState
+	if c.inlining && c.inlinable(fnIndex) {
+		c.checkInlinedCallDepth()
+		c.lowerInlinedCall(fnIndex)
+		return
+	}
+
+	// Before transfer the control to the callee, we have to store the current module's moduleContextPtr
+	// into execContext.callerModuleContextPtr in case when the callee is a Go function.
+	//
+	// TODO: maybe this can be optimized out if this is in-module function calls. Investigate later.
+	c.storeCallerModuleContext()
+
+	typIndex := c.m.FunctionSection[fnIndex]
+	typ := &c.m.TypeSection[typIndex]
+
+	// TODO: reuse slice?
+	argN := len(typ.Params)
+	args := make([]ssa.Value, argN+2)
+	args[0] = c.execCtxPtrValue
+	state.nPopInto(argN, args[2:])
+
+	sig := c.signatures[typ]
+	call := builder.AllocateInstruction()
+	if fnIndex >= c.m.ImportFunctionCount {
+		args[1] = c.moduleCtxPtrValue // This case the callee module is itself.
+		call.AsCall(FunctionIndexToFuncRef(fnIndex), sig, args)
+		builder.InsertInstruction(call)
+	} else {
+		// This case we have to read the address of the imported function from the module context.
+		moduleCtx := c.moduleCtxPtrValue
+		loadFuncPtr, loadModuleCtxPtr := builder.AllocateInstruction(), builder.AllocateInstruction()
+		funcPtrOffset, moduleCtxPtrOffset := c.offset.ImportedFunctionOffset(fnIndex)
+		loadFuncPtr.AsLoad(moduleCtx, funcPtrOffset.U32(), ssa.TypeI64)
+		loadModuleCtxPtr.AsLoad(moduleCtx, moduleCtxPtrOffset.U32(), ssa.TypeI64)
+		builder.InsertInstruction(loadFuncPtr)
+		builder.InsertInstruction(loadModuleCtxPtr)
+
+		args[1] = loadModuleCtxPtr.Return() // This case the callee module is itself.
+
+		call.AsCallIndirect(loadFuncPtr.Return(), sig, args)
+		builder.InsertInstruction(call)
+	}
+
+	first, rest := call.Returns()
+	state.push(first)
+	for _, v := range rest {
+		state.push(v)
+	}
+}
+
 // storeCallerModuleContext stores the current module's moduleContextPtr into execContext.callerModuleContextPtr.
 func (c *Compiler) storeCallerModuleContext() {
 	builder := c.ssaBuilder
@@ -2,6 +2,7 @@ package wazevo
 
 import (
 	"encoding/binary"
+	"errors"
 	"unsafe"
 
 	"github.com/tetratelabs/wazero/api"
@@ -105,10 +106,32 @@ func (m *moduleEngine) ResolveImportedFunction(index, indexInImportedModule wasm
 	binary.LittleEndian.PutUint64(m.opaque[moduleCtx:], uint64(uintptr(unsafe.Pointer(importedME.opaquePtr))))
 }
 
+// errFunctionReferencesUnsupported is returned when looking up function references, which tables of this engine
+// don't hold yet.
+var errFunctionReferencesUnsupported = errors.New("function references are not supported by this engine")
+
 // LookupFunction implements wasm.ModuleEngine.
-func (m *moduleEngine) LookupFunction(t *wasm.TableInstance, typeId wasm.FunctionTypeID, tableOffset wasm.Index) (api.Function, error) {
-	panic("TODO")
+func (m *moduleEngine) LookupFunction(*wasm.TableInstance, wasm.FunctionTypeID, wasm.Index) (api.Function, error) {
+	return nil, errFunctionReferencesUnsupported
+}
+
+// LookupFunctionReference implements wasm.ModuleEngine.
+func (m *moduleEngine) LookupFunctionReference(wasm.Reference, wasm.FunctionTypeID) (api.Function, error) {
+	return nil, errFunctionReferencesUnsupported
 }
 
 // FunctionInstanceReference implements wasm.ModuleEngine.
 func (m *moduleEngine) FunctionInstanceReference(funcIndex wasm.Index) wasm.Reference { panic("TODO") }
+
+// OwnsFunctionReference implements wasm.ModuleEngine.
+//
+// Function references aren't produced yet, see FunctionInstanceReference, so this is always false. As a result,
+// api.Table Set and Grow only accept null funcrefs with this engine.
+func (m *moduleEngine) OwnsFunctionReference(wasm.Reference) bool {
+	return false
+}
+
+// FunctionReferenceRange implements wasm.ModuleEngine.
+func (m *moduleEngine) FunctionReferenceRange() (begin, end wasm.Reference) {
+	return // empty as OwnsFunctionReference is always false.
+}
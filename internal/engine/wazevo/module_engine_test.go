@@ -108,3 +108,20 @@ func TestModuleEngine_ResolveImportedFunction(t *testing.T) {
 		require.Equal(t, expOpaquePtr, actualOpaquePtr)
 	}
 }
+
+func TestModuleEngine_LookupFunctionReference(t *testing.T) {
+	m := &moduleEngine{}
+	_, err := m.LookupFunctionReference(1, 0)
+	require.EqualError(t, err, "function references are not supported by this engine")
+	_, err = m.LookupFunction(&wasm.TableInstance{}, 0, 0)
+	require.EqualError(t, err, "function references are not supported by this engine")
+}
+
+func TestModuleEngine_OwnsFunctionReference(t *testing.T) {
+	// Function references aren't produced, so any non-null funcref set by the host is rejected.
+	m := &moduleEngine{}
+	require.False(t, m.OwnsFunctionReference(1))
+	begin, end := m.FunctionReferenceRange()
+	require.Zero(t, begin)
+	require.Zero(t, end)
+}
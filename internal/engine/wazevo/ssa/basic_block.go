@@ -285,3 +285,37 @@ func (bb *basicBlock) FormatHeader(b Builder) string {
 func (bb *basicBlock) String() string {
 	return strconv.Itoa(int(bb.id))
 }
+
+// removeInstruction removes the given instruction from this block.
+func (bb *basicBlock) removeInstruction(instr *Instruction) {
+	if prev := instr.prev; prev != nil {
+		prev.next = instr.next
+	} else {
+		bb.rootInstr = instr.next
+	}
+	if next := instr.next; next != nil {
+		next.prev = instr.prev
+	} else {
+		bb.currentInstr = instr.prev
+	}
+	instr.prev, instr.next = nil, nil
+}
+
+// insertInstructionBeforeBranches inserts the given instruction right before the branching instructions at the end of this block.
+func (bb *basicBlock) insertInstructionBeforeBranches(instr *Instruction) {
+	pos := bb.currentInstr
+	for pos != nil && pos.prev != nil && pos.prev.opcode.isBranching() {
+		pos = pos.prev
+	}
+	if pos == nil || !pos.opcode.isBranching() {
+		panic("BUG: block must end with a branching instruction")
+	}
+
+	instr.prev, instr.next = pos.prev, pos
+	if pos.prev != nil {
+		pos.prev.next = instr
+	} else {
+		bb.rootInstr = instr
+	}
+	pos.prev = instr
+}
@@ -120,6 +120,7 @@ func NewBuilder() Builder {
 		blkVisited:                     make(map[*basicBlock]int),
 		valueIDAliases:                 make(map[ValueID]Value),
 		redundantParameterIndexToValue: make(map[int]Value),
+		gvnTable:                       make(map[gvnKey][]*Instruction),
 		returnBlk:                      &basicBlock{id: basicBlockIDReturnBlock},
 	}
 }
@@ -158,6 +159,8 @@ type builder struct {
 	instStack                      []*Instruction
 	blkVisited                     map[*basicBlock]int
 	valueIDToInstruction           []*Instruction
+	valueIDToDefiningBlk           []*basicBlock
+	gvnTable                       map[gvnKey][]*Instruction
 	blkStack                       []*basicBlock
 	blkStack2                      []*basicBlock
 	ints                           []int
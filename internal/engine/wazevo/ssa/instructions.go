@@ -871,6 +871,15 @@ func (i *Instruction) HasSideEffects() bool {
 	}
 }
 
+// isBranching returns true if the opcode transfers the control to another block.
+func (o Opcode) isBranching() bool {
+	switch o {
+	case OpcodeJump, OpcodeBrz, OpcodeBrnz, OpcodeBrTable:
+		return true
+	}
+	return false
+}
+
 // instructionReturnTypes provides the function to determine the return types of an instruction.
 var instructionReturnTypes = [opcodeEnd]returnTypesFn{
 	OpcodeIshl:    returnTypesFnSingle,
@@ -16,11 +16,12 @@ func (b *builder) RunPasses() {
 	// 	WebAssembly program shouldn't result in irreducible CFG, but we should handle it properly in just in case.
 	// 	See FixIrreducible pass in LLVM: https://llvm.org/doxygen/FixIrreducible_8cpp_source.html
 
+	passConstFoldingOpt(b)
+	passGlobalValueNumberingOpt(b)
+	passLoopInvariantCodeMotionOpt(b)
+
 	// TODO: implement more optimization passes like:
 	// 	block coalescing.
-	// 	Copy-propagation.
-	// 	Constant folding.
-	// 	Common subexpression elimination.
 	// 	Arithmetic simplifications.
 	// 	and more!
 
@@ -264,3 +265,330 @@ func (b *builder) clearBlkVisited() {
 		delete(b.blkVisited, blk)
 	}
 }
+
+// passConstFoldingOpt folds the integer arithmetic whose operands are all constants into constants.
+// Since the blocks are visited in the reverse post order, the folded constants are propagated
+// to the subsequent instructions in the same pass.
+//
+// The folded instruction is rewritten in place into OpcodeIconst so that the produced Value stays the same.
+func passConstFoldingOpt(b *builder) {
+	b.calculateValueDefinitions()
+	for _, blk := range b.reversePostOrderedBasicBlocks {
+		for cur := blk.rootInstr; cur != nil; cur = cur.next {
+			b.resolveArgumentAlias(cur)
+			switch cur.opcode {
+			case OpcodeIadd, OpcodeIsub, OpcodeImul, OpcodeIshl, OpcodeUshr, OpcodeSshr:
+				x, xok := b.constantOf(cur.v)
+				y, yok := b.constantOf(cur.v2)
+				if xok && yok {
+					cur.asFoldedIconst(foldIntBinaryOp(cur.opcode, cur.typ, x, y))
+				}
+			case OpcodeIcmp:
+				x, xok := b.constantOf(cur.v)
+				y, yok := b.constantOf(cur.v2)
+				if xok && yok {
+					var res uint64
+					if foldIcmp(IntegerCmpCond(cur.u64), cur.v.Type(), x, y) {
+						res = 1
+					}
+					cur.asFoldedIconst(res)
+				}
+			case OpcodeSExtend, OpcodeUExtend:
+				if x, ok := b.constantOf(cur.v); ok {
+					from, to, signed := cur.ExtendData()
+					cur.asFoldedIconst(foldExtend(x, from, to, signed))
+				}
+			}
+		}
+	}
+}
+
+// asFoldedIconst rewrites this instruction into OpcodeIconst of the same type.
+func (i *Instruction) asFoldedIconst(v uint64) {
+	if i.typ == TypeI32 {
+		v = uint64(uint32(v))
+	}
+	i.opcode = OpcodeIconst
+	i.u64 = v
+	i.v = ValueInvalid
+	i.v2 = ValueInvalid
+}
+
+// constantOf returns the value of the integer constant if the given Value is produced by OpcodeIconst.
+func (b *builder) constantOf(v Value) (uint64, bool) {
+	if def := b.valueIDToInstruction[v.ID()]; def != nil && def.opcode == OpcodeIconst {
+		return def.u64, true
+	}
+	return 0, false
+}
+
+// foldIntBinaryOp computes the binary integer operation. Shift amounts are taken modulo the bit width.
+func foldIntBinaryOp(op Opcode, typ Type, x, y uint64) uint64 {
+	if typ == TypeI32 {
+		x, y := uint32(x), uint32(y)
+		switch op {
+		case OpcodeIadd:
+			return uint64(x + y)
+		case OpcodeIsub:
+			return uint64(x - y)
+		case OpcodeImul:
+			return uint64(x * y)
+		case OpcodeIshl:
+			return uint64(x << (y & 31))
+		case OpcodeUshr:
+			return uint64(x >> (y & 31))
+		case OpcodeSshr:
+			return uint64(uint32(int32(x) >> (y & 31)))
+		}
+	} else {
+		switch op {
+		case OpcodeIadd:
+			return x + y
+		case OpcodeIsub:
+			return x - y
+		case OpcodeImul:
+			return x * y
+		case OpcodeIshl:
+			return x << (y & 63)
+		case OpcodeUshr:
+			return x >> (y & 63)
+		case OpcodeSshr:
+			return uint64(int64(x) >> (y & 63))
+		}
+	}
+	panic("BUG: unsupported opcode for folding: " + op.String())
+}
+
+// foldIcmp evaluates the integer comparison of the given type.
+func foldIcmp(c IntegerCmpCond, typ Type, x, y uint64) bool {
+	var sx, sy int64
+	if typ == TypeI32 {
+		x, y = uint64(uint32(x)), uint64(uint32(y))
+		sx, sy = int64(int32(x)), int64(int32(y))
+	} else {
+		sx, sy = int64(x), int64(y)
+	}
+	switch c {
+	case IntegerCmpCondEqual:
+		return x == y
+	case IntegerCmpCondNotEqual:
+		return x != y
+	case IntegerCmpCondSignedLessThan:
+		return sx < sy
+	case IntegerCmpCondSignedGreaterThanOrEqual:
+		return sx >= sy
+	case IntegerCmpCondSignedGreaterThan:
+		return sx > sy
+	case IntegerCmpCondSignedLessThanOrEqual:
+		return sx <= sy
+	case IntegerCmpCondUnsignedLessThan:
+		return x < y
+	case IntegerCmpCondUnsignedGreaterThanOrEqual:
+		return x >= y
+	case IntegerCmpCondUnsignedGreaterThan:
+		return x > y
+	case IntegerCmpCondUnsignedLessThanOrEqual:
+		return x <= y
+	default:
+		panic("BUG: invalid integer comparison condition")
+	}
+}
+
+// foldExtend extends the lower `from` bits of x into `to` bits.
+func foldExtend(x uint64, from, to byte, signed bool) uint64 {
+	shift := 64 - from
+	if signed {
+		x = uint64(int64(x<<shift) >> shift)
+	} else {
+		x = x << shift >> shift
+	}
+	if to < 64 {
+		x &= 1<<to - 1
+	}
+	return x
+}
+
+// gvnKey is the key of builder.gvnTable which identifies the computation of an instruction.
+type gvnKey struct {
+	opcode Opcode
+	typ    Type
+	v, v2  Value
+	u64    uint64
+}
+
+// passGlobalValueNumberingOpt eliminates the redundant computations, i.e. pure instructions which compute
+// the same value as another instruction dominating them. The redundant ones are removed from the block, and
+// their results are aliased to the dominating instruction's result.
+//
+// The blocks are visited in the reverse post order so that dominating instructions are always numbered first.
+func passGlobalValueNumberingOpt(b *builder) {
+	b.calculateValueDefinitions()
+	for _, blk := range b.reversePostOrderedBasicBlocks {
+		var next *Instruction
+		for cur := blk.rootInstr; cur != nil; cur = next {
+			next = cur.next
+			b.resolveArgumentAlias(cur)
+			if !cur.movable() {
+				continue
+			}
+
+			key := gvnKey{opcode: cur.opcode, typ: cur.typ, v: cur.v, v2: cur.v2, u64: cur.u64}
+			if cur.opcode == OpcodeIadd || cur.opcode == OpcodeImul {
+				// Commutative, so normalize the order of the operands.
+				if key.v.ID() > key.v2.ID() {
+					key.v, key.v2 = key.v2, key.v
+				}
+			}
+
+			var dominating *Instruction
+			for _, candidate := range b.gvnTable[key] {
+				if b.isDominatedBy(blk, b.valueIDToDefiningBlk[candidate.rValue.ID()]) {
+					dominating = candidate
+					break
+				}
+			}
+			if dominating != nil {
+				b.alias(cur.rValue, dominating.rValue)
+				blk.removeInstruction(cur)
+			} else {
+				b.gvnTable[key] = append(b.gvnTable[key], cur)
+			}
+		}
+	}
+
+	// Clears the table for the next iteration.
+	for k := range b.gvnTable {
+		delete(b.gvnTable, k)
+	}
+}
+
+// passLoopInvariantCodeMotionOpt hoists the pure instructions whose operands are all defined outside a loop
+// into the loop's preheader, i.e. the only predecessor of the loop header outside the loop.
+//
+// This uses the dominators and the loop headers calculated by passCalculateImmediateDominators.
+// Loops without a preheader are left as-is.
+func passLoopInvariantCodeMotionOpt(b *builder) {
+	b.calculateValueDefinitions()
+	rpo := b.reversePostOrderedBasicBlocks
+	// Inner loops come after the outer ones in the reverse post order, so iterate backwards so that
+	// the instructions hoisted out of an inner loop can be further hoisted out of the outer one.
+	for i := len(rpo) - 1; i >= 0; i-- {
+		header := rpo[i]
+		if !header.loopHeader {
+			continue
+		}
+
+		var preheader *basicBlock
+		b.clearBlkVisited()
+		b.blkVisited[header] = 0
+		stack := b.blkStack[:0]
+		for j := range header.preds {
+			pred := header.preds[j].blk
+			if pred.invalid {
+				continue
+			}
+			if b.isDominatedBy(pred, header) {
+				// This is a back edge, so collect the loop body by walking the predecessors backwards.
+				stack = append(stack, pred)
+			} else if preheader == nil {
+				preheader = pred
+			} else {
+				preheader = header // Marks that there are multiple entries.
+			}
+		}
+		for len(stack) > 0 {
+			tail := len(stack) - 1
+			blk := stack[tail]
+			stack = stack[:tail]
+			if _, ok := b.blkVisited[blk]; ok {
+				continue
+			}
+			b.blkVisited[blk] = 0
+			for j := range blk.preds {
+				if pred := blk.preds[j].blk; !pred.invalid {
+					stack = append(stack, pred)
+				}
+			}
+		}
+		b.blkStack = stack
+
+		if preheader == nil || preheader == header {
+			continue
+		}
+
+		for _, blk := range rpo[i:] {
+			if _, ok := b.blkVisited[blk]; !ok {
+				continue
+			}
+			var next *Instruction
+			for cur := blk.rootInstr; cur != nil; cur = next {
+				next = cur.next
+				b.resolveArgumentAlias(cur)
+				if !cur.movable() || !b.definedOutsideOfLoop(cur.v) || !b.definedOutsideOfLoop(cur.v2) {
+					continue
+				}
+				blk.removeInstruction(cur)
+				preheader.insertInstructionBeforeBranches(cur)
+				b.valueIDToDefiningBlk[cur.rValue.ID()] = preheader
+			}
+		}
+	}
+}
+
+// definedOutsideOfLoop returns true if the Value is not defined in the loop body currently marked in builder.blkVisited.
+func (b *builder) definedOutsideOfLoop(v Value) bool {
+	if !v.Valid() {
+		return true
+	}
+	blk := b.valueIDToDefiningBlk[v.ID()]
+	if blk == nil {
+		return false
+	}
+	_, inLoop := b.blkVisited[blk]
+	return !inLoop
+}
+
+// movable returns true if this instruction can be freely eliminated or moved as long as its operands
+// are available, i.e. it doesn't have side effects, never traps, and doesn't depend on the memory state.
+//
+// Constants are excluded since backends materialize them at the use sites anyway.
+func (i *Instruction) movable() bool {
+	switch i.opcode {
+	case OpcodeLoad, OpcodeIconst, OpcodeF32const, OpcodeF64const:
+		return false
+	}
+	return instructionSideEffects[i.opcode] == sideEffectFalse && len(i.vs) == 0 && i.rValue.Valid() && len(i.rValues) == 0
+}
+
+// calculateValueDefinitions fills builder.valueIDToInstruction and builder.valueIDToDefiningBlk
+// for the current instructions and block parameters.
+func (b *builder) calculateValueDefinitions() {
+	nvid := int(b.nextValueID)
+	if nvid >= len(b.valueIDToInstruction) {
+		b.valueIDToInstruction = append(b.valueIDToInstruction, make([]*Instruction, nvid+1-len(b.valueIDToInstruction))...)
+	}
+	if nvid >= len(b.valueIDToDefiningBlk) {
+		b.valueIDToDefiningBlk = append(b.valueIDToDefiningBlk, make([]*basicBlock, nvid+1-len(b.valueIDToDefiningBlk))...)
+	}
+	for i := 0; i < nvid; i++ {
+		b.valueIDToInstruction[i] = nil
+		b.valueIDToDefiningBlk[i] = nil
+	}
+
+	for _, blk := range b.reversePostOrderedBasicBlocks {
+		for _, p := range blk.params {
+			b.valueIDToDefiningBlk[p.value.ID()] = blk
+		}
+		for cur := blk.rootInstr; cur != nil; cur = cur.next {
+			r1, rs := cur.Returns()
+			if r1.Valid() {
+				b.valueIDToInstruction[r1.ID()] = cur
+				b.valueIDToDefiningBlk[r1.ID()] = blk
+			}
+			for _, r := range rs {
+				b.valueIDToInstruction[r.ID()] = cur
+				b.valueIDToDefiningBlk[r.ID()] = blk
+			}
+		}
+	}
+}
@@ -276,6 +276,242 @@ blk0: ()
 blk1: () <-- (blk0)
 	v4:i32 = Iadd v2, v0
 	Return v4
+`,
+		},
+		{
+			name: "const folding",
+			pass: func(b *builder) {
+				passCalculateImmediateDominators(b)
+				passConstFoldingOpt(b)
+			},
+			setup: func(b *builder) func(*testing.T) {
+				entry := b.AllocateBasicBlock()
+				b.SetCurrentBlock(entry)
+
+				one := b.AllocateInstruction()
+				one.AsIconst32(1)
+				b.InsertInstruction(one)
+				two := b.AllocateInstruction()
+				two.AsIconst32(2)
+				b.InsertInstruction(two)
+
+				add := b.AllocateInstruction()
+				add.AsIadd(one.Return(), two.Return())
+				b.InsertInstruction(add)
+
+				shl := b.AllocateInstruction()
+				shl.AsIshl(add.Return(), two.Return())
+				b.InsertInstruction(shl)
+
+				cmp := b.AllocateInstruction()
+				cmp.AsIcmp(one.Return(), shl.Return(), IntegerCmpCondUnsignedLessThan)
+				b.InsertInstruction(cmp)
+
+				sub := b.AllocateInstruction()
+				sub.AsIsub(one.Return(), shl.Return())
+				b.InsertInstruction(sub)
+
+				ext := b.AllocateInstruction()
+				ext.AsSExtend(sub.Return(), 32, 64)
+				b.InsertInstruction(ext)
+
+				ret := b.AllocateInstruction()
+				ret.AsReturn([]Value{cmp.Return(), ext.Return()})
+				b.InsertInstruction(ret)
+				return nil
+			},
+			before: `
+blk0: ()
+	v0:i32 = Iconst_32 0x1
+	v1:i32 = Iconst_32 0x2
+	v2:i32 = Iadd v0, v1
+	v3:i32 = Ishl v2, v1
+	v4:i32 = Icmp lt_u, v0, v3
+	v5:i32 = Isub v0, v3
+	v6:i64 = SExtend v5, 32->64
+	Return v4, v6
+`,
+			after: `
+blk0: ()
+	v0:i32 = Iconst_32 0x1
+	v1:i32 = Iconst_32 0x2
+	v2:i32 = Iconst_32 0x3
+	v3:i32 = Iconst_32 0xc
+	v4:i32 = Iconst_32 0x1
+	v5:i32 = Iconst_32 0xfffffff5
+	v6:i64 = Iconst_64 0xfffffffffffffff5
+	Return v4, v6
+`,
+		},
+		{
+			name: "global value numbering",
+			pass: func(b *builder) {
+				passCalculateImmediateDominators(b)
+				passGlobalValueNumberingOpt(b)
+			},
+			setup: func(b *builder) func(*testing.T) {
+				entry, then, els := b.AllocateBasicBlock(), b.AllocateBasicBlock(), b.AllocateBasicBlock()
+				x, y := entry.AddParam(b, TypeI32), entry.AddParam(b, TypeI32)
+
+				b.SetCurrentBlock(entry)
+				{
+					add := b.AllocateInstruction()
+					add.AsIadd(x, y)
+					b.InsertInstruction(add)
+
+					brz := b.AllocateInstruction()
+					brz.AsBrz(add.Return(), nil, then)
+					b.InsertInstruction(brz)
+
+					jmp := b.AllocateInstruction()
+					jmp.AsJump(nil, els)
+					b.InsertInstruction(jmp)
+				}
+
+				b.SetCurrentBlock(then)
+				{
+					// Redundant with the one in the entry block, which dominates this block.
+					add := b.AllocateInstruction()
+					add.AsIadd(y, x)
+					b.InsertInstruction(add)
+
+					sub := b.AllocateInstruction()
+					sub.AsIsub(x, y)
+					b.InsertInstruction(sub)
+
+					ret := b.AllocateInstruction()
+					ret.AsReturn([]Value{add.Return(), sub.Return()})
+					b.InsertInstruction(ret)
+				}
+
+				b.SetCurrentBlock(els)
+				{
+					// Not redundant since the one in blk1 doesn't dominate this block.
+					sub := b.AllocateInstruction()
+					sub.AsIsub(x, y)
+					b.InsertInstruction(sub)
+
+					// Redundant with the one above in the same block.
+					sub2 := b.AllocateInstruction()
+					sub2.AsIsub(x, y)
+					b.InsertInstruction(sub2)
+
+					ret := b.AllocateInstruction()
+					ret.AsReturn([]Value{sub.Return(), sub2.Return()})
+					b.InsertInstruction(ret)
+				}
+				return nil
+			},
+			before: `
+blk0: (v0:i32, v1:i32)
+	v2:i32 = Iadd v0, v1
+	Brz v2, blk1
+	Jump blk2
+
+blk1: () <-- (blk0)
+	v3:i32 = Iadd v1, v0
+	v4:i32 = Isub v0, v1
+	Return v3, v4
+
+blk2: () <-- (blk0)
+	v5:i32 = Isub v0, v1
+	v6:i32 = Isub v0, v1
+	Return v5, v6
+`,
+			after: `
+blk0: (v0:i32, v1:i32)
+	v2:i32 = Iadd v0, v1
+	Brz v2, blk1
+	Jump blk2
+
+blk1: () <-- (blk0)
+	v4:i32 = Isub v0, v1
+	Return v2, v4
+
+blk2: () <-- (blk0)
+	v5:i32 = Isub v0, v1
+	Return v5, v5
+`,
+		},
+		{
+			name: "loop invariant code motion",
+			pass: func(b *builder) {
+				passCalculateImmediateDominators(b)
+				passLoopInvariantCodeMotionOpt(b)
+			},
+			setup: func(b *builder) func(*testing.T) {
+				entry, loopHeader, end := b.AllocateBasicBlock(), b.AllocateBasicBlock(), b.AllocateBasicBlock()
+				x, y := entry.AddParam(b, TypeI32), entry.AddParam(b, TypeI32)
+				phi := loopHeader.AddParam(b, TypeI32)
+
+				b.SetCurrentBlock(entry)
+				{
+					jmp := b.AllocateInstruction()
+					jmp.AsJump([]Value{x}, loopHeader)
+					b.InsertInstruction(jmp)
+				}
+
+				b.SetCurrentBlock(loopHeader)
+				{
+					// Invariant: only depends on the values defined outside the loop.
+					mul := b.AllocateInstruction()
+					mul.AsImul(x, y)
+					b.InsertInstruction(mul)
+
+					// Invariant: depends on the invariant above.
+					sub := b.AllocateInstruction()
+					sub.AsIsub(mul.Return(), x)
+					b.InsertInstruction(sub)
+
+					// Not invariant: depends on the loop parameter.
+					add := b.AllocateInstruction()
+					add.AsIadd(phi, sub.Return())
+					b.InsertInstruction(add)
+
+					brz := b.AllocateInstruction()
+					brz.AsBrz(add.Return(), []Value{add.Return()}, loopHeader)
+					b.InsertInstruction(brz)
+
+					jmp := b.AllocateInstruction()
+					jmp.AsJump(nil, end)
+					b.InsertInstruction(jmp)
+				}
+
+				b.SetCurrentBlock(end)
+				{
+					ret := b.AllocateInstruction()
+					ret.AsReturn(nil)
+					b.InsertInstruction(ret)
+				}
+				return nil
+			},
+			before: `
+blk0: (v0:i32, v1:i32)
+	Jump blk1, v0
+
+blk1: (v2:i32) <-- (blk0,blk1)
+	v3:i32 = Imul v0, v1
+	v4:i32 = Isub v3, v0
+	v5:i32 = Iadd v2, v4
+	Brz v5, blk1, v5
+	Jump blk2
+
+blk2: () <-- (blk1)
+	Return
+`,
+			after: `
+blk0: (v0:i32, v1:i32)
+	v3:i32 = Imul v0, v1
+	v4:i32 = Isub v3, v0
+	Jump blk1, v0
+
+blk1: (v2:i32) <-- (blk0,blk1)
+	v5:i32 = Iadd v2, v4
+	Brz v5, blk1, v5
+	Jump blk2
+
+blk2: () <-- (blk1)
+	Return
 `,
 		},
 	} {
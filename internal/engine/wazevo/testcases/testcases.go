@@ -172,6 +172,22 @@ var (
 			wasm.OpcodeEnd,
 		}, []wasm.ValueType{}),
 	}
+	BlockEndLoopEnd = TestCase{
+		Name: "block_end_loop_end",
+		Module: SingleFunctionModule(i32_v, []byte{
+			wasm.OpcodeBlock, 0x40,
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeDrop,
+			wasm.OpcodeEnd,
+			wasm.OpcodeLoop, 0x40,
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeDrop,
+			wasm.OpcodeEnd,
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeDrop,
+			wasm.OpcodeEnd,
+		}, []wasm.ValueType{}),
+	}
 	BlockBlockBr = TestCase{
 		Name: "block_block_br",
 		Module: SingleFunctionModule(vv, []byte{
@@ -8,7 +8,9 @@ import (
 	"unsafe"
 
 	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
 	"github.com/tetratelabs/wazero/internal/engine/wazevo/wazevoapi"
+	"github.com/tetratelabs/wazero/internal/testing/enginetest"
 	"github.com/tetratelabs/wazero/internal/testing/require"
 	"github.com/tetratelabs/wazero/internal/wasm"
 )
@@ -22,11 +24,83 @@ func TestMain(m *testing.M) {
 	os.Exit(m.Run())
 }
 
+// engineTester implements enginetest.EngineTester.
+type engineTester struct{}
+
+// ListenerFactory implements enginetest.EngineTester ListenerFactory.
+func (engineTester) ListenerFactory() experimental.FunctionListenerFactory {
+	return nil
+}
+
+// NewEngine implements enginetest.EngineTester NewEngine.
+func (engineTester) NewEngine(enabledFeatures api.CoreFeatures) wasm.Engine {
+	return NewEngine(ctx, enabledFeatures, nil)
+}
+
+func TestModuleEngine_MaxCallStackDepth(t *testing.T) {
+	enginetest.RunTestModuleEngineMaxCallStackDepth(t, engineTester{})
+}
+
 func TestNewEngine(t *testing.T) {
 	e := NewEngine(ctx, api.CoreFeaturesV1, nil)
 	require.NotNil(t, e)
 }
 
+func TestEngine_CompileModule_tailCall(t *testing.T) {
+	e := NewEngine(ctx, api.CoreFeaturesV2|api.CoreFeatureTailCall, nil)
+
+	// Enabling tail calls doesn't affect modules which don't use them.
+	m := &wasm.Module{
+		TypeSection:     []wasm.FunctionType{{}},
+		FunctionSection: []wasm.Index{0, 0},
+		CodeSection:     []wasm.Code{{Body: []byte{wasm.OpcodeEnd}}, {Body: []byte{wasm.OpcodeCall, 0, wasm.OpcodeEnd}}},
+		ID:              wasm.ModuleID{1},
+	}
+	require.NoError(t, e.CompileModule(ctx, m, nil, false, false, false))
+
+	m = &wasm.Module{
+		TypeSection:     []wasm.FunctionType{{}},
+		FunctionSection: []wasm.Index{0, 0},
+		CodeSection:     []wasm.Code{{Body: []byte{wasm.OpcodeEnd}}, {Body: []byte{wasm.OpcodeReturnCall, 0, wasm.OpcodeEnd}}},
+		ID:              wasm.ModuleID{2},
+	}
+	err := e.CompileModule(ctx, m, nil, false, false, false)
+	require.EqualError(t, err, "wasm->ssa: tail calls are not supported yet")
+}
+
+func TestCompileFunctions_panic(t *testing.T) {
+	// The compiler panics on the host function, which is between two functions which compile.
+	m := &wasm.Module{
+		TypeSection:     []wasm.FunctionType{{}},
+		FunctionSection: []wasm.Index{0, 0, 0},
+		CodeSection: []wasm.Code{
+			{Body: []byte{wasm.OpcodeEnd}},
+			{GoFunc: api.GoFunc(func(context.Context, []uint64) {})},
+			{Body: []byte{wasm.OpcodeEnd}},
+		},
+	}
+	offsets := wazevoapi.NewModuleContextOffsetData(m)
+
+	tests := []struct {
+		name    string
+		workers int
+	}{
+		{name: "one worker", workers: 1},
+		{name: "workers", workers: 3},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			// The panic is on the calling goroutine regardless of the count of workers, so it doesn't crash the process.
+			err := require.CapturePanic(func() {
+				_, _ = compileFunctions(wasm.WithCompilationWorkers(ctx, tc.workers), m, &offsets, false, false, false, false, nil)
+			})
+			require.EqualError(t, err, "TODO: host module")
+		})
+	}
+}
+
 func TestEngine_CompiledModuleCount(t *testing.T) {
 	e, ok := NewEngine(ctx, api.CoreFeaturesV1, nil).(*engine)
 	require.True(t, ok)
@@ -57,6 +131,11 @@ func Test_ExecutionContextOffsets(t *testing.T) {
 	require.Equal(t, wazevoapi.Offset(unsafe.Offsetof(execCtx.goCallReturnAddress)), offsets.GoCallReturnAddress)
 	require.Equal(t, wazevoapi.Offset(unsafe.Offsetof(execCtx.stackPointerBeforeGrow)), offsets.StackPointerBeforeGrow)
 	require.Equal(t, wazevoapi.Offset(unsafe.Offsetof(execCtx.stackGrowRequiredSize)), offsets.StackGrowRequiredSize)
+	require.Equal(t, wazevoapi.Offset(unsafe.Offsetof(execCtx.fuel)), offsets.Fuel)
 	require.Equal(t, wazevoapi.Offset(unsafe.Offsetof(execCtx.savedRegisters)), offsets.SavedRegistersBegin)
+	require.Equal(t, wazevoapi.Offset(unsafe.Offsetof(execCtx.epoch)), offsets.EpochPtr)
+	require.Equal(t, wazevoapi.Offset(unsafe.Offsetof(execCtx.epochDeadline)), offsets.EpochDeadline)
+	require.Equal(t, wazevoapi.Offset(unsafe.Offsetof(execCtx.callDepth)), offsets.CallDepth)
+	require.Equal(t, wazevoapi.Offset(unsafe.Offsetof(execCtx.maxCallDepth)), offsets.MaxCallDepth)
 	require.Equal(t, wazevoapi.Offset(unsafe.Offsetof(execCtx.savedRegisters))%16, wazevoapi.Offset(0))
 }
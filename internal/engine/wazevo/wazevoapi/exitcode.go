@@ -6,5 +6,8 @@ const (
 	ExitCodeOK ExitCode = iota
 	ExitCodeGrowStack
 	ExitCodeUnreachable
+	ExitCodeOutOfFuel
+	ExitCodeEpochInterrupted
+	ExitCodeStackOverflow
 	ExitCodeCount
 )
@@ -12,7 +12,12 @@ var ExecutionContextOffsets = ExecutionContextOffsetData{
 	GoCallReturnAddress:    48,
 	StackPointerBeforeGrow: 56,
 	StackGrowRequiredSize:  64,
+	Fuel:                   72,
 	SavedRegistersBegin:    80,
+	EpochPtr:               1104,
+	EpochDeadline:          1112,
+	CallDepth:              1120,
+	MaxCallDepth:           1128,
 }
 
 // ExecutionContextOffsetData allows the compilers to get the information about offsets to the fields of wazevo.executionContext,
@@ -36,8 +41,18 @@ type ExecutionContextOffsetData struct {
 	StackPointerBeforeGrow Offset
 	// StackGrowRequiredSize is an offset of `stackGrowRequiredSize` field in wazevo.executionContext
 	StackGrowRequiredSize Offset
+	// Fuel is an offset of `fuel` field in wazevo.executionContext
+	Fuel Offset
 	// GoCallReturnAddress is an offset of the first element of `savedRegisters` field in wazevo.executionContext
 	SavedRegistersBegin Offset
+	// EpochPtr is an offset of `epoch` field in wazevo.executionContext
+	EpochPtr Offset
+	// EpochDeadline is an offset of `epochDeadline` field in wazevo.executionContext
+	EpochDeadline Offset
+	// CallDepth is an offset of `callDepth` field in wazevo.executionContext
+	CallDepth Offset
+	// MaxCallDepth is an offset of `maxCallDepth` field in wazevo.executionContext
+	MaxCallDepth Offset
 }
 
 // ModuleContextOffsetData allows the compilers to get the information about offsets to the fields of wazevo.moduleContextOpaque,
@@ -70,7 +85,7 @@ func (o Offset) I64() int64 {
 func NewModuleContextOffsetData(m *wasm.Module) ModuleContextOffsetData {
 	ret := ModuleContextOffsetData{}
 	var offset Offset
-	if m.MemorySection != nil {
+	if len(m.MemorySection) > 0 {
 		ret.LocalMemoryBegin = offset
 		// buffer base + memory size.
 		const localMemorySizeInOpaqueVMContext = 16
@@ -25,7 +25,7 @@ func TestNewModuleContextOffsetData(t *testing.T) {
 		},
 		{
 			name: "local mem",
-			m:    &wasm.Module{MemorySection: &wasm.Memory{}},
+			m:    &wasm.Module{MemorySection: []wasm.Memory{{}}},
 			exp: ModuleContextOffsetData{
 				LocalMemoryBegin:       0,
 				ImportedMemoryBegin:    -1,
@@ -65,7 +65,7 @@ func TestNewModuleContextOffsetData(t *testing.T) {
 		},
 		{
 			name: "local mem / imported func",
-			m:    &wasm.Module{MemorySection: &wasm.Memory{}, ImportFunctionCount: 10},
+			m:    &wasm.Module{MemorySection: []wasm.Memory{{}}, ImportFunctionCount: 10},
 			exp: ModuleContextOffsetData{
 				LocalMemoryBegin:       0,
 				ImportedMemoryBegin:    -1,
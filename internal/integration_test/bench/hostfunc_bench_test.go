@@ -176,7 +176,7 @@ func setupHostCallBench(requireNoError func(error)) *wasm.ModuleInstance {
 	host := &wasm.ModuleInstance{ModuleName: "host", TypeIDs: []wasm.FunctionTypeID{0}}
 	host.Exports = hostModule.Exports
 
-	err := eng.CompileModule(testCtx, hostModule, nil, false)
+	err := eng.CompileModule(testCtx, hostModule, nil, false, false, false)
 	requireNoError(err)
 
 	hostMe, err := eng.NewModuleEngine(hostModule, host)
@@ -206,11 +206,11 @@ func setupHostCallBench(requireNoError func(error)) *wasm.ModuleInstance {
 			{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeCall, 1, wasm.OpcodeEnd}}, // Calling the index 1 = host.go-reflect.
 		},
 		// Indicates that this module has a memory so that compilers are able to assemble memory-related initialization.
-		MemorySection: &wasm.Memory{Min: 1},
+		MemorySection: []wasm.Memory{{Min: 1}},
 		ID:            wasm.ModuleID{1},
 	}
 
-	err = eng.CompileModule(testCtx, importingModule, nil, false)
+	err = eng.CompileModule(testCtx, importingModule, nil, false, false, false)
 	requireNoError(err)
 
 	importing := &wasm.ModuleInstance{TypeIDs: []wasm.FunctionTypeID{0}}
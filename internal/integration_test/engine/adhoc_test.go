@@ -60,6 +60,10 @@ func TestEngineInterpreter(t *testing.T) {
 	runAllTests(t, tests, wazero.NewRuntimeConfigInterpreter().WithCloseOnContextDone(true))
 }
 
+func TestEngineTiered(t *testing.T) {
+	runAllTests(t, tests, wazero.NewRuntimeConfigTiered().WithCloseOnContextDone(true))
+}
+
 func runAllTests(t *testing.T, tests map[string]func(t *testing.T, r wazero.Runtime), config wazero.RuntimeConfig) {
 	for name, testf := range tests {
 		name := name   // pin
@@ -759,7 +763,7 @@ func testMultipleInstantiation(t *testing.T, r wazero.Runtime) {
 	bin := binaryencoding.EncodeModule(&wasm.Module{
 		TypeSection:     []wasm.FunctionType{{}},
 		FunctionSection: []wasm.Index{0},
-		MemorySection:   &wasm.Memory{Min: 1, Cap: 1, Max: 1, IsMaxEncoded: true},
+		MemorySection:   []wasm.Memory{{Min: 1, Cap: 1, Max: 1, IsMaxEncoded: true}},
 		CodeSection: []wasm.Code{{
 			Body: []byte{
 				wasm.OpcodeI32Const, 1, // i32.const 1    ;; memory offset
@@ -798,3 +802,97 @@ func testMultipleInstantiation(t *testing.T, r wazero.Runtime) {
 		require.Equal(t, uint64(1000), after)
 	}
 }
+
+// recursionWasm exports "recurse", which calls itself n times before calling the imported "env.leaf", and returns n.
+var recursionWasm = binaryencoding.EncodeModule(&wasm.Module{
+	TypeSection: []wasm.FunctionType{
+		{},
+		{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32}, ParamNumInUint64: 1, ResultNumInUint64: 1},
+	},
+	ImportSection: []wasm.Import{
+		{Module: "env", Name: "leaf", Type: wasm.ExternTypeFunc, DescFunc: 0},
+	},
+	FunctionSection: []wasm.Index{1},
+	CodeSection: []wasm.Code{
+		{Body: []byte{
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeI32Eqz,
+			wasm.OpcodeIf, 0x40,
+			wasm.OpcodeCall, 0,
+			wasm.OpcodeI32Const, 0,
+			wasm.OpcodeReturn,
+			wasm.OpcodeEnd,
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeI32Const, 1,
+			wasm.OpcodeI32Sub,
+			wasm.OpcodeCall, 1,
+			wasm.OpcodeI32Const, 1,
+			wasm.OpcodeI32Add,
+			wasm.OpcodeEnd,
+		}},
+	},
+	ExportSection: []wasm.Export{{Name: "recurse", Type: wasm.ExternTypeFunc, Index: 1}},
+})
+
+// TestStackLimits ensures that engines overflow at the same call depth, which includes host functions.
+func TestStackLimits(t *testing.T) {
+	configs := map[string]wazero.RuntimeConfig{"interpreter": wazero.NewRuntimeConfigInterpreter()}
+	if platform.CompilerSupported() {
+		configs["compiler"] = wazero.NewRuntimeConfigCompiler()
+	}
+
+	for name, config := range configs {
+		config := config
+		t.Run(name, func(t *testing.T) {
+			newRecurse := func(t *testing.T, config wazero.RuntimeConfig) api.Function {
+				r := wazero.NewRuntimeWithConfig(testCtx, config)
+				t.Cleanup(func() { require.NoError(t, r.Close(testCtx)) })
+
+				_, err := r.NewHostModuleBuilder("env").NewFunctionBuilder().
+					WithFunc(func() {}).Export("leaf").Instantiate(testCtx)
+				require.NoError(t, err)
+				mod, err := r.Instantiate(testCtx, recursionWasm)
+				require.NoError(t, err)
+				return mod.ExportedFunction("recurse")
+			}
+
+			t.Run("max call stack depth", func(t *testing.T) {
+				recurse := newRecurse(t, config.WithMaxCallStackDepth(10))
+
+				// 9 calls of recurse and 1 of leaf.
+				res, err := recurse.Call(testCtx, 8)
+				require.NoError(t, err)
+				require.Equal(t, uint64(8), res[0])
+
+				_, err = recurse.Call(testCtx, 9)
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "stack overflow")
+
+				// The depth is reset for each call.
+				res, err = recurse.Call(testCtx, 8)
+				require.NoError(t, err)
+				require.Equal(t, uint64(8), res[0])
+			})
+
+			t.Run("max stack bytes", func(t *testing.T) {
+				recurse := newRecurse(t, config.WithMaxStackBytes(4096))
+
+				res, err := recurse.Call(testCtx, 10)
+				require.NoError(t, err)
+				require.Equal(t, uint64(10), res[0])
+
+				_, err = recurse.Call(testCtx, 1000)
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "stack overflow")
+			})
+
+			t.Run("default", func(t *testing.T) {
+				recurse := newRecurse(t, config)
+
+				res, err := recurse.Call(testCtx, 1000)
+				require.NoError(t, err)
+				require.Equal(t, uint64(1000), res[0])
+			})
+		})
+	}
+}
@@ -0,0 +1,338 @@
+package adhoc
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/platform"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+	"github.com/tetratelabs/wazero/internal/wasmruntime"
+)
+
+func TestExceptionHandlingCompiler(t *testing.T) {
+	if !platform.CompilerSupported() {
+		t.Skip()
+	}
+	testExceptionHandling(t, wazero.NewRuntimeConfigCompiler())
+}
+
+func TestExceptionHandlingInterpreter(t *testing.T) {
+	testExceptionHandling(t, wazero.NewRuntimeConfigInterpreter())
+}
+
+// exceptionWasm exports the tag "e" with an i32 value and functions which throw and catch exceptions of it, across
+// frames and the imported host function "env.throw" which throws with the tag of the calling module.
+var exceptionWasm = func() []byte {
+	i32, i64, f64 := wasm.ValueTypeI32, wasm.ValueTypeI64, wasm.ValueTypeF64
+	return binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection: []wasm.FunctionType{
+			{Params: []wasm.ValueType{i32}, ParamNumInUint64: 1},
+			{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32}, ParamNumInUint64: 1, ResultNumInUint64: 1},
+			{Params: []wasm.ValueType{i64, f64}, ParamNumInUint64: 2},
+			{Results: []wasm.ValueType{i64, f64}, ResultNumInUint64: 2},
+		},
+		ImportSection: []wasm.Import{
+			{Type: wasm.ExternTypeFunc, Module: "env", Name: "throw", DescFunc: 0},
+		},
+		FunctionSection: []wasm.Index{0, 1, 1, 1, 1, 1, 1, 3},
+		TagSection:      []wasm.Tag{{Type: 0}, {Type: 2}},
+		CodeSection: []wasm.Code{
+			// throw(x): throw $e(x)
+			{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeThrow, 0, wasm.OpcodeEnd}},
+			// catch(x): try throw(x) catch $e(v) v+1
+			{Body: []byte{
+				wasm.OpcodeTry, i32, wasm.OpcodeLocalGet, 0, wasm.OpcodeCall, 1, wasm.OpcodeI32Const, 0,
+				wasm.OpcodeCatch, 0, wasm.OpcodeI32Const, 1, wasm.OpcodeI32Add,
+				wasm.OpcodeEnd,
+				wasm.OpcodeEnd,
+			}},
+			// catch_all(x): try env.throw(x) catch_all 42
+			{Body: []byte{
+				wasm.OpcodeTry, i32, wasm.OpcodeLocalGet, 0, wasm.OpcodeCall, 0, wasm.OpcodeI32Const, 0,
+				wasm.OpcodeCatchAll, wasm.OpcodeI32Const, 42,
+				wasm.OpcodeEnd,
+				wasm.OpcodeEnd,
+			}},
+			// rethrow(x): try (try throw $e(x) catch_all rethrow) catch $e(v) v*2
+			{Body: []byte{
+				wasm.OpcodeTry, i32,
+				wasm.OpcodeTry, i32, wasm.OpcodeLocalGet, 0, wasm.OpcodeThrow, 0,
+				wasm.OpcodeCatchAll, wasm.OpcodeRethrow, 0,
+				wasm.OpcodeEnd,
+				wasm.OpcodeCatch, 0, wasm.OpcodeI32Const, 2, wasm.OpcodeI32Mul,
+				wasm.OpcodeEnd,
+				wasm.OpcodeEnd,
+			}},
+			// delegate(x): try (try throw $e(x) delegate 0) 0 catch $e(v) v
+			{Body: []byte{
+				wasm.OpcodeTry, i32,
+				wasm.OpcodeTry, 0x40, wasm.OpcodeLocalGet, 0, wasm.OpcodeThrow, 0,
+				wasm.OpcodeDelegate, 0,
+				wasm.OpcodeI32Const, 0,
+				wasm.OpcodeCatch, 0,
+				wasm.OpcodeEnd,
+				wasm.OpcodeEnd,
+			}},
+			// locals(x): 50 + (try x += 10; throw(x) catch $e x)
+			{Body: []byte{
+				wasm.OpcodeI32Const, 50,
+				wasm.OpcodeTry, i32,
+				wasm.OpcodeLocalGet, 0, wasm.OpcodeI32Const, 10, wasm.OpcodeI32Add, wasm.OpcodeLocalSet, 0,
+				wasm.OpcodeLocalGet, 0, wasm.OpcodeCall, 1, wasm.OpcodeI32Const, 0,
+				wasm.OpcodeCatch, 0, wasm.OpcodeDrop, wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeEnd,
+				wasm.OpcodeI32Add,
+				wasm.OpcodeEnd,
+			}},
+			// loop(n): counts the exceptions caught in n iterations.
+			{LocalTypes: []wasm.ValueType{i32}, Body: []byte{
+				wasm.OpcodeLoop, 0x40,
+				wasm.OpcodeTry, 0x40, wasm.OpcodeLocalGet, 0, wasm.OpcodeCall, 1,
+				wasm.OpcodeCatch, 0, wasm.OpcodeDrop,
+				wasm.OpcodeLocalGet, 1, wasm.OpcodeI32Const, 1, wasm.OpcodeI32Add, wasm.OpcodeLocalSet, 1,
+				wasm.OpcodeEnd,
+				wasm.OpcodeLocalGet, 0, wasm.OpcodeI32Const, 1, wasm.OpcodeI32Sub, wasm.OpcodeLocalTee, 0,
+				wasm.OpcodeBrIf, 0,
+				wasm.OpcodeEnd,
+				wasm.OpcodeLocalGet, 1,
+				wasm.OpcodeEnd,
+			}},
+			// pair(): try throw $pair(7, 1.5) catch $pair
+			{Body: []byte{
+				wasm.OpcodeTry, 3, // type index 3: (i64, f64)
+				wasm.OpcodeI64Const, 7, wasm.OpcodeF64Const, 0, 0, 0, 0, 0, 0, 0xf8, 0x3f, wasm.OpcodeThrow, 1,
+				wasm.OpcodeCatch, 1,
+				wasm.OpcodeEnd,
+				wasm.OpcodeEnd,
+			}},
+		},
+		ExportSection: []wasm.Export{
+			{Name: "e", Type: wasm.ExternTypeTag, Index: 0},
+			{Name: "throw", Type: wasm.ExternTypeFunc, Index: 1},
+			{Name: "catch", Type: wasm.ExternTypeFunc, Index: 2},
+			{Name: "catch_all", Type: wasm.ExternTypeFunc, Index: 3},
+			{Name: "rethrow", Type: wasm.ExternTypeFunc, Index: 4},
+			{Name: "delegate", Type: wasm.ExternTypeFunc, Index: 5},
+			{Name: "locals", Type: wasm.ExternTypeFunc, Index: 6},
+			{Name: "loop", Type: wasm.ExternTypeFunc, Index: 7},
+			{Name: "pair", Type: wasm.ExternTypeFunc, Index: 8},
+		},
+	})
+}()
+
+func testExceptionHandling(t *testing.T, config wazero.RuntimeConfig) {
+	t.Run("disabled", func(t *testing.T) {
+		r := wazero.NewRuntimeWithConfig(testCtx, config)
+		defer r.Close(testCtx)
+
+		_, err := r.CompileModule(testCtx, exceptionWasm)
+		require.Error(t, err)
+		_, err = r.CompileModule(testCtx, tryTableWasm)
+		require.Error(t, err)
+	})
+
+	r := wazero.NewRuntimeWithConfig(testCtx, config.WithCoreFeatures(api.CoreFeaturesV2|api.CoreFeatureExceptionHandling))
+	defer r.Close(testCtx)
+
+	_, err := r.NewHostModuleBuilder("env").NewFunctionBuilder().
+		WithGoModuleFunction(api.GoModuleFunc(func(_ context.Context, m api.Module, stack []uint64) {
+			panic(&api.Exception{Tag: m.ExportedTag("e"), Values: []uint64{stack[0]}})
+		}), []api.ValueType{api.ValueTypeI32}, nil).Export("throw").
+		Instantiate(testCtx)
+	require.NoError(t, err)
+
+	mod, err := r.Instantiate(testCtx, exceptionWasm)
+	require.NoError(t, err)
+
+	call := func(t *testing.T, name string, params ...uint64) []uint64 {
+		results, err := mod.ExportedFunction(name).Call(testCtx, params...)
+		require.NoError(t, err)
+		return results
+	}
+
+	t.Run("uncaught", func(t *testing.T) {
+		_, err := mod.ExportedFunction("throw").Call(testCtx, 5)
+		var exc *api.Exception
+		require.True(t, errors.As(err, &exc))
+		require.Equal(t, mod.ExportedTag("e"), exc.Tag)
+		require.Equal(t, []uint64{5}, exc.Values)
+		require.Equal(t, []api.ValueType{api.ValueTypeI32}, exc.Tag.ParamTypes())
+
+		// The call engine can be reused after the exception.
+		require.Equal(t, []uint64{6}, call(t, "catch", 5))
+	})
+
+	t.Run("catch", func(t *testing.T) {
+		require.Equal(t, []uint64{6}, call(t, "catch", 5))
+	})
+
+	t.Run("catch_all from host", func(t *testing.T) {
+		require.Equal(t, []uint64{42}, call(t, "catch_all", 5))
+	})
+
+	t.Run("rethrow", func(t *testing.T) {
+		require.Equal(t, []uint64{10}, call(t, "rethrow", 5))
+	})
+
+	t.Run("delegate", func(t *testing.T) {
+		require.Equal(t, []uint64{5}, call(t, "delegate", 5))
+	})
+
+	t.Run("locals", func(t *testing.T) {
+		require.Equal(t, []uint64{65}, call(t, "locals", 5))
+	})
+
+	t.Run("loop", func(t *testing.T) {
+		// The frames unwound by the exceptions don't count in the call depth.
+		require.Equal(t, []uint64{100_000}, call(t, "loop", 100_000))
+	})
+
+	t.Run("multiple values", func(t *testing.T) {
+		require.Equal(t, []uint64{7, api.EncodeF64(1.5)}, call(t, "pair"))
+	})
+
+	t.Run("try_table", func(t *testing.T) {
+		tryTable, err := r.InstantiateWithConfig(testCtx, tryTableWasm, wazero.NewModuleConfig().WithName("try_table"))
+		require.NoError(t, err)
+
+		call := func(t *testing.T, name string, params ...uint64) []uint64 {
+			results, err := tryTable.ExportedFunction(name).Call(testCtx, params...)
+			require.NoError(t, err)
+			return results
+		}
+
+		t.Run("catch", func(t *testing.T) {
+			require.Equal(t, []uint64{6}, call(t, "catch", 5))
+		})
+
+		t.Run("catch_ref and throw_ref", func(t *testing.T) {
+			require.Equal(t, []uint64{5}, call(t, "catch_ref", 5))
+		})
+
+		t.Run("catch_all from host", func(t *testing.T) {
+			require.Equal(t, []uint64{42}, call(t, "catch_all", 5))
+		})
+
+		t.Run("loop", func(t *testing.T) {
+			require.Equal(t, []uint64{100_000}, call(t, "loop", 100_000))
+		})
+
+		t.Run("throw_ref null", func(t *testing.T) {
+			_, err := tryTable.ExportedFunction("throw_ref_null").Call(testCtx)
+			require.ErrorIs(t, err, wasmruntime.ErrRuntimeNullReference)
+		})
+
+		t.Run("throw_ref to host", func(t *testing.T) {
+			_, err := tryTable.ExportedFunction("uncaught_ref").Call(testCtx, 5)
+			var exc *api.Exception
+			require.True(t, errors.As(err, &exc))
+			require.Equal(t, tryTable.ExportedTag("e"), exc.Tag)
+			require.Equal(t, []uint64{5}, exc.Values)
+
+			// The call engine can be reused after the exception.
+			require.Equal(t, []uint64{5}, call(t, "catch_ref", 5))
+		})
+	})
+}
+
+// tryTableWasm exports the tag "e" with an i32 value and functions which catch exceptions of it with try_table, and
+// throw them again with throw_ref. This imports "env.throw" the same as exceptionWasm.
+var tryTableWasm = func() []byte {
+	i32, exnref := wasm.ValueTypeI32, wasm.ValueTypeExnref
+	return binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection: []wasm.FunctionType{
+			{Params: []wasm.ValueType{i32}, ParamNumInUint64: 1},
+			{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32}, ParamNumInUint64: 1, ResultNumInUint64: 1},
+			{Results: []wasm.ValueType{i32, exnref}, ResultNumInUint64: 2},
+			{Results: []wasm.ValueType{i32}, ResultNumInUint64: 1},
+		},
+		ImportSection: []wasm.Import{
+			{Type: wasm.ExternTypeFunc, Module: "env", Name: "throw", DescFunc: 0},
+		},
+		FunctionSection: []wasm.Index{0, 1, 1, 1, 3, 1, 0},
+		TagSection:      []wasm.Tag{{Type: 0}},
+		CodeSection: []wasm.Code{
+			// throw(x): throw $e(x)
+			{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeThrow, 0, wasm.OpcodeEnd}},
+			// catch(x): 1 + (block (try_table (catch $e 0) throw(x); 0))
+			{Body: []byte{
+				wasm.OpcodeBlock, i32,
+				wasm.OpcodeTryTable, i32, 1, wasm.TryTableCatchKindCatch, 0, 0,
+				wasm.OpcodeLocalGet, 0, wasm.OpcodeCall, 1, wasm.OpcodeI32Const, 0,
+				wasm.OpcodeEnd,
+				wasm.OpcodeEnd,
+				wasm.OpcodeI32Const, 1, wasm.OpcodeI32Add,
+				wasm.OpcodeEnd,
+			}},
+			// catch_ref(x): catches $e(x) with its reference, and throws it again to the outer try_table with
+			// throw_ref from a local.
+			{LocalTypes: []wasm.ValueType{exnref}, Body: []byte{
+				wasm.OpcodeBlock, i32,
+				wasm.OpcodeTryTable, i32, 1, wasm.TryTableCatchKindCatch, 0, 0,
+				wasm.OpcodeBlock, 2, // type index 2: (i32, exnref)
+				wasm.OpcodeTryTable, 0x40, 1, wasm.TryTableCatchKindCatchRef, 0, 0,
+				wasm.OpcodeLocalGet, 0, wasm.OpcodeCall, 1,
+				wasm.OpcodeEnd,
+				wasm.OpcodeUnreachable,
+				wasm.OpcodeEnd,
+				wasm.OpcodeLocalSet, 1, wasm.OpcodeDrop, wasm.OpcodeLocalGet, 1, wasm.OpcodeThrowRef,
+				wasm.OpcodeEnd,
+				wasm.OpcodeEnd,
+				wasm.OpcodeEnd,
+			}},
+			// catch_all(x): (block (try_table (catch_all 0) env.throw(x)) (return 0)) 42
+			{Body: []byte{
+				wasm.OpcodeBlock, 0x40,
+				wasm.OpcodeTryTable, 0x40, 1, wasm.TryTableCatchKindCatchAll, 0,
+				wasm.OpcodeLocalGet, 0, wasm.OpcodeCall, 0,
+				wasm.OpcodeEnd,
+				wasm.OpcodeI32Const, 0, wasm.OpcodeReturn,
+				wasm.OpcodeEnd,
+				wasm.OpcodeI32Const, 42,
+				wasm.OpcodeEnd,
+			}},
+			// throw_ref_null(): throw_ref (ref.null exn)
+			{Body: []byte{wasm.OpcodeRefNull, exnref, wasm.OpcodeThrowRef, wasm.OpcodeEnd}},
+			// loop(n): counts the iterations of a loop, which is continued by catching $e(n-1) until n is zero.
+			{LocalTypes: []wasm.ValueType{i32}, Body: []byte{
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeLoop, 0, // type index 0: (i32) -> ()
+				wasm.OpcodeLocalTee, 0,
+				wasm.OpcodeIf, 0x40,
+				wasm.OpcodeLocalGet, 1, wasm.OpcodeI32Const, 1, wasm.OpcodeI32Add, wasm.OpcodeLocalSet, 1,
+				wasm.OpcodeTryTable, 0x40, 1, wasm.TryTableCatchKindCatch, 0, 1,
+				wasm.OpcodeLocalGet, 0, wasm.OpcodeI32Const, 1, wasm.OpcodeI32Sub, wasm.OpcodeCall, 1,
+				wasm.OpcodeEnd,
+				wasm.OpcodeEnd,
+				wasm.OpcodeEnd,
+				wasm.OpcodeLocalGet, 1,
+				wasm.OpcodeEnd,
+			}},
+			// uncaught_ref(x): catches env.throw(x) with catch_all_ref, and throws it to the caller with throw_ref.
+			{Body: []byte{
+				wasm.OpcodeBlock, exnref,
+				wasm.OpcodeTryTable, 0x40, 1, wasm.TryTableCatchKindCatchAllRef, 0,
+				wasm.OpcodeLocalGet, 0, wasm.OpcodeCall, 0,
+				wasm.OpcodeEnd,
+				wasm.OpcodeReturn,
+				wasm.OpcodeEnd,
+				wasm.OpcodeThrowRef,
+				wasm.OpcodeEnd,
+			}},
+		},
+		ExportSection: []wasm.Export{
+			{Name: "e", Type: wasm.ExternTypeTag, Index: 0},
+			{Name: "catch", Type: wasm.ExternTypeFunc, Index: 2},
+			{Name: "catch_ref", Type: wasm.ExternTypeFunc, Index: 3},
+			{Name: "catch_all", Type: wasm.ExternTypeFunc, Index: 4},
+			{Name: "throw_ref_null", Type: wasm.ExternTypeFunc, Index: 5},
+			{Name: "loop", Type: wasm.ExternTypeFunc, Index: 6},
+			{Name: "uncaught_ref", Type: wasm.ExternTypeFunc, Index: 7},
+		},
+	})
+}()
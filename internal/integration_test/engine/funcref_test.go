@@ -0,0 +1,227 @@
+package adhoc
+
+import (
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/platform"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+	"github.com/tetratelabs/wazero/internal/wasmruntime"
+)
+
+func TestExtendedConstAndFunctionReferencesCompiler(t *testing.T) {
+	if !platform.CompilerSupported() {
+		t.Skip()
+	}
+	testExtendedConstAndFunctionReferences(t, wazero.NewRuntimeConfigCompiler())
+}
+
+func TestExtendedConstAndFunctionReferencesInterpreter(t *testing.T) {
+	testExtendedConstAndFunctionReferences(t, wazero.NewRuntimeConfigInterpreter())
+}
+
+// baseWasm exports the immutable global "base" = 100, which is imported by funcrefWasm as LLVM PIC modules import
+// __memory_base and __table_base.
+var baseWasm = binaryencoding.EncodeModule(&wasm.Module{
+	GlobalSection: []wasm.Global{
+		{
+			Type: wasm.GlobalType{ValType: wasm.ValueTypeI32},
+			Init: wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: []byte{0xe4, 0x00}}, // 100
+		},
+	},
+	ExportSection: []wasm.Export{{Name: "base", Type: wasm.ExternTypeGlobal, Index: 0}},
+})
+
+// funcrefWasm exports globals, a data segment and an element segment initialized with extended constant expressions,
+// and functions which call typed function references.
+var funcrefWasm = func() []byte {
+	i32, i64 := wasm.ValueTypeI32, wasm.ValueTypeI64
+	return binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection: []wasm.FunctionType{
+			{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32}, ParamNumInUint64: 1, ResultNumInUint64: 1},
+			{Results: []wasm.ValueType{i32}, ResultNumInUint64: 1},
+		},
+		ImportSection: []wasm.Import{
+			{Type: wasm.ExternTypeGlobal, Module: "env", Name: "base", DescGlobal: wasm.GlobalType{ValType: i32}},
+		},
+		FunctionSection: []wasm.Index{0, 0, 0, 1, 0, 0, 0, 1},
+		TableSection:    []wasm.Table{{Min: 1, Type: wasm.RefTypeFuncref}},
+		MemorySection:   []wasm.Memory{{Min: 1, Max: 1, IsMaxEncoded: true}},
+		GlobalSection: []wasm.Global{
+			{
+				// base + 16
+				Type: wasm.GlobalType{ValType: i32},
+				Init: wasm.ConstantExpression{
+					Opcode: wasm.OpcodeI32Add,
+					Data:   []byte{wasm.OpcodeGlobalGet, 0, wasm.OpcodeI32Const, 16},
+				},
+			},
+			{
+				// 3 * 5 - 1
+				Type: wasm.GlobalType{ValType: i64},
+				Init: wasm.ConstantExpression{
+					Opcode: wasm.OpcodeI64Sub,
+					Data:   []byte{wasm.OpcodeI64Const, 3, wasm.OpcodeI64Const, 5, wasm.OpcodeI64Mul, wasm.OpcodeI64Const, 1},
+				},
+			},
+		},
+		ElementSection: []wasm.ElementSegment{
+			{
+				// base - 100
+				OffsetExpr: wasm.ConstantExpression{
+					Opcode: wasm.OpcodeI32Sub,
+					Data:   []byte{wasm.OpcodeGlobalGet, 0, wasm.OpcodeI32Const, 0xe4, 0x00},
+				},
+				Init: []wasm.Index{0},
+				Type: wasm.RefTypeFuncref,
+			},
+		},
+		DataSection: []wasm.DataSegment{
+			{
+				// base * 2
+				OffsetExpression: wasm.ConstantExpression{
+					Opcode: wasm.OpcodeI32Mul,
+					Data:   []byte{wasm.OpcodeGlobalGet, 0, wasm.OpcodeI32Const, 2},
+				},
+				Init: []byte("hi"),
+			},
+		},
+		CodeSection: []wasm.Code{
+			// double(x): x * 2
+			{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeI32Const, 2, wasm.OpcodeI32Mul, wasm.OpcodeEnd}},
+			// call_ref(x): call_ref $double(x)
+			{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeRefFunc, 0, wasm.OpcodeCallRef, 0, wasm.OpcodeEnd}},
+			// call_null(x): call_ref null(x)
+			{Body: []byte{
+				wasm.OpcodeLocalGet, 0, wasm.OpcodeRefNull, wasm.RefTypeFuncref, wasm.OpcodeCallRef, 0,
+				wasm.OpcodeEnd,
+			}},
+			// as_non_null(): ref.as_non_null null; 0
+			{Body: []byte{
+				wasm.OpcodeRefNull, wasm.RefTypeFuncref, wasm.OpcodeRefAsNonNull, wasm.OpcodeDrop, wasm.OpcodeI32Const, 0,
+				wasm.OpcodeEnd,
+			}},
+			// br_on_null(x): f = x ? $double : null; 7 (br_on_null f) call_ref f(7)
+			{LocalTypes: []wasm.ValueType{wasm.ValueTypeFuncref}, Body: []byte{
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeIf, wasm.RefTypePrefixNullable, 0, // (result (ref null 0))
+				wasm.OpcodeRefFunc, 0,
+				wasm.OpcodeElse,
+				wasm.OpcodeRefNull, wasm.RefTypeFuncref,
+				wasm.OpcodeEnd,
+				wasm.OpcodeLocalSet, 1,
+				wasm.OpcodeBlock, i32,
+				wasm.OpcodeI32Const, 7, wasm.OpcodeLocalGet, 1,
+				wasm.OpcodeBrOnNull, 0,
+				wasm.OpcodeCallRef, 0,
+				wasm.OpcodeEnd,
+				wasm.OpcodeEnd,
+			}},
+			// br_on_non_null(x): f = x ? $double : null; (br_on_non_null f) return 100; call_ref f(x)
+			{Body: []byte{
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeBlock, wasm.RefTypePrefixNonNullable, 0, // (result (ref 0))
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeIf, wasm.RefTypePrefixNullable, 0, // (result (ref null 0))
+				wasm.OpcodeRefFunc, 0,
+				wasm.OpcodeElse,
+				wasm.OpcodeRefNull, wasm.RefTypeFuncref,
+				wasm.OpcodeEnd,
+				wasm.OpcodeBrOnNonNull, 0,
+				wasm.OpcodeI32Const, 0xe4, 0x00, wasm.OpcodeReturn,
+				wasm.OpcodeEnd,
+				wasm.OpcodeCallRef, 0,
+				wasm.OpcodeEnd,
+			}},
+			// type_mismatch(x): call_ref $as_non_null(x)
+			{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeRefFunc, 3, wasm.OpcodeCallRef, 0, wasm.OpcodeEnd}},
+			// call_indirect(): table[0](21)
+			{Body: []byte{
+				wasm.OpcodeI32Const, 21, wasm.OpcodeI32Const, 0, wasm.OpcodeCallIndirect, 0, 0,
+				wasm.OpcodeEnd,
+			}},
+		},
+		ExportSection: []wasm.Export{
+			{Name: "memory", Type: wasm.ExternTypeMemory, Index: 0},
+			{Name: "g32", Type: wasm.ExternTypeGlobal, Index: 1},
+			{Name: "g64", Type: wasm.ExternTypeGlobal, Index: 2},
+			{Name: "double", Type: wasm.ExternTypeFunc, Index: 0},
+			{Name: "call_ref", Type: wasm.ExternTypeFunc, Index: 1},
+			{Name: "call_null", Type: wasm.ExternTypeFunc, Index: 2},
+			{Name: "as_non_null", Type: wasm.ExternTypeFunc, Index: 3},
+			{Name: "br_on_null", Type: wasm.ExternTypeFunc, Index: 4},
+			{Name: "br_on_non_null", Type: wasm.ExternTypeFunc, Index: 5},
+			{Name: "type_mismatch", Type: wasm.ExternTypeFunc, Index: 6},
+			{Name: "call_indirect", Type: wasm.ExternTypeFunc, Index: 7},
+		},
+	})
+}()
+
+func testExtendedConstAndFunctionReferences(t *testing.T, config wazero.RuntimeConfig) {
+	t.Run("disabled", func(t *testing.T) {
+		r := wazero.NewRuntimeWithConfig(testCtx, config)
+		defer r.Close(testCtx)
+
+		_, err := r.CompileModule(testCtx, funcrefWasm)
+		require.Error(t, err)
+	})
+
+	r := wazero.NewRuntimeWithConfig(testCtx, config.WithCoreFeatures(
+		api.CoreFeaturesV2|api.CoreFeatureExtendedConst|api.CoreFeatureFunctionReferences))
+	defer r.Close(testCtx)
+
+	_, err := r.InstantiateWithConfig(testCtx, baseWasm, wazero.NewModuleConfig().WithName("env"))
+	require.NoError(t, err)
+
+	mod, err := r.Instantiate(testCtx, funcrefWasm)
+	require.NoError(t, err)
+
+	call := func(t *testing.T, name string, params ...uint64) []uint64 {
+		results, err := mod.ExportedFunction(name).Call(testCtx, params...)
+		require.NoError(t, err)
+		return results
+	}
+
+	t.Run("extended const", func(t *testing.T) {
+		require.Equal(t, uint64(116), mod.ExportedGlobal("g32").Get())
+		require.Equal(t, uint64(14), mod.ExportedGlobal("g64").Get())
+
+		data, ok := mod.ExportedMemory("memory").Read(200, 2)
+		require.True(t, ok)
+		require.Equal(t, "hi", string(data))
+
+		require.Equal(t, []uint64{42}, call(t, "call_indirect"))
+	})
+
+	t.Run("call_ref", func(t *testing.T) {
+		require.Equal(t, []uint64{42}, call(t, "call_ref", 21))
+	})
+
+	t.Run("call_ref null", func(t *testing.T) {
+		_, err := mod.ExportedFunction("call_null").Call(testCtx, 21)
+		require.ErrorIs(t, err, wasmruntime.ErrRuntimeNullFunctionReference)
+	})
+
+	t.Run("call_ref type mismatch", func(t *testing.T) {
+		_, err := mod.ExportedFunction("type_mismatch").Call(testCtx, 21)
+		require.ErrorIs(t, err, wasmruntime.ErrRuntimeIndirectCallTypeMismatch)
+	})
+
+	t.Run("ref.as_non_null", func(t *testing.T) {
+		_, err := mod.ExportedFunction("as_non_null").Call(testCtx)
+		require.ErrorIs(t, err, wasmruntime.ErrRuntimeNullReference)
+	})
+
+	t.Run("br_on_null", func(t *testing.T) {
+		require.Equal(t, []uint64{14}, call(t, "br_on_null", 1))
+		require.Equal(t, []uint64{7}, call(t, "br_on_null", 0))
+	})
+
+	t.Run("br_on_non_null", func(t *testing.T) {
+		require.Equal(t, []uint64{10}, call(t, "br_on_non_null", 5))
+		require.Equal(t, []uint64{100}, call(t, "br_on_non_null", 0))
+	})
+}
@@ -0,0 +1,215 @@
+package adhoc
+
+import (
+	"math"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/leb128"
+	"github.com/tetratelabs/wazero/internal/platform"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+	"github.com/tetratelabs/wazero/internal/wasmruntime"
+)
+
+func TestMemory64Compiler(t *testing.T) {
+	if !platform.CompilerSupported() {
+		t.Skip()
+	}
+	testMemory64(t, wazero.NewRuntimeConfigCompiler())
+}
+
+func TestMemory64Interpreter(t *testing.T) {
+	testMemory64(t, wazero.NewRuntimeConfigInterpreter())
+}
+
+// memory64Wasm defines a 64-bit memory initialized by a data segment, and exports functions which access it with
+// i64 addresses.
+var memory64Wasm = func() []byte {
+	i32, i64 := wasm.ValueTypeI32, wasm.ValueTypeI64
+	// offset4GiB is the memarg of a byte access with the static offset 4GiB, which is only valid for 64-bit memories.
+	offset4GiB := append([]byte{0}, leb128.EncodeUint64(1<<32)...)
+	return binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection: []wasm.FunctionType{
+			{Params: []wasm.ValueType{i64}, Results: []wasm.ValueType{i32}, ParamNumInUint64: 1, ResultNumInUint64: 1},
+			{Params: []wasm.ValueType{i64, i32}, ParamNumInUint64: 2},
+			{Params: []wasm.ValueType{i64}, Results: []wasm.ValueType{i64}, ParamNumInUint64: 1, ResultNumInUint64: 1},
+			{Results: []wasm.ValueType{i64}, ResultNumInUint64: 1},
+			{Params: []wasm.ValueType{i64, i32, i64}, ParamNumInUint64: 3},
+			{Params: []wasm.ValueType{i64, i64, i64}, ParamNumInUint64: 3},
+			{Params: []wasm.ValueType{i64, i32}, Results: []wasm.ValueType{i32}, ParamNumInUint64: 2, ResultNumInUint64: 1},
+		},
+		FunctionSection: []wasm.Index{0, 1, 0, 2, 3, 4, 5, 6},
+		MemorySection:   []wasm.Memory{{Min: 1, Max: 2, IsMaxEncoded: true, Is64: true}},
+		DataSection: []wasm.DataSegment{
+			{
+				OffsetExpression: wasm.ConstantExpression{Opcode: wasm.OpcodeI64Const, Data: []byte{0}},
+				Init:             []byte("hello"),
+			},
+		},
+		CodeSection: []wasm.Code{
+			// load(addr): i32.load8_u.
+			{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeI32Load8U, 0, 0, wasm.OpcodeEnd}},
+			// store(addr, v): i32.store8.
+			{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 1, wasm.OpcodeI32Store8, 0, 0, wasm.OpcodeEnd}},
+			// load4GiB(addr): i32.load8_u with the static offset 4GiB.
+			{Body: append(append([]byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeI32Load8U}, offset4GiB...), wasm.OpcodeEnd)},
+			// grow(delta): memory.grow.
+			{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeMemoryGrow, 0, wasm.OpcodeEnd}},
+			// size(): memory.size.
+			{Body: []byte{wasm.OpcodeMemorySize, 0, wasm.OpcodeEnd}},
+			// fill(addr, v, n): memory.fill.
+			{Body: []byte{
+				wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 1, wasm.OpcodeLocalGet, 2,
+				wasm.OpcodeMiscPrefix, wasm.OpcodeMiscMemoryFill, 0,
+				wasm.OpcodeEnd,
+			}},
+			// copy(dst, src, n): memory.copy.
+			{Body: []byte{
+				wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 1, wasm.OpcodeLocalGet, 2,
+				wasm.OpcodeMiscPrefix, wasm.OpcodeMiscMemoryCopy, 0, 0,
+				wasm.OpcodeEnd,
+			}},
+			// atomic_add(addr, v): i32.atomic.rmw.add.
+			{Body: []byte{
+				wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 1,
+				wasm.OpcodeAtomicPrefix, wasm.OpcodeAtomicI32RmwAdd, 2, 0,
+				wasm.OpcodeEnd,
+			}},
+		},
+		ExportSection: []wasm.Export{
+			{Name: "memory", Type: wasm.ExternTypeMemory, Index: 0},
+			{Name: "load", Type: wasm.ExternTypeFunc, Index: 0},
+			{Name: "store", Type: wasm.ExternTypeFunc, Index: 1},
+			{Name: "load4GiB", Type: wasm.ExternTypeFunc, Index: 2},
+			{Name: "grow", Type: wasm.ExternTypeFunc, Index: 3},
+			{Name: "size", Type: wasm.ExternTypeFunc, Index: 4},
+			{Name: "fill", Type: wasm.ExternTypeFunc, Index: 5},
+			{Name: "copy", Type: wasm.ExternTypeFunc, Index: 6},
+			{Name: "atomic_add", Type: wasm.ExternTypeFunc, Index: 7},
+		},
+	})
+}()
+
+func testMemory64(t *testing.T, config wazero.RuntimeConfig) {
+	const pageSize = uint64(wasm.MemoryPageSize)
+
+	t.Run("disabled", func(t *testing.T) {
+		r := wazero.NewRuntimeWithConfig(testCtx, config.WithCoreFeatures(api.CoreFeaturesV2|api.CoreFeatureThreads))
+		defer r.Close(testCtx)
+
+		_, err := r.CompileModule(testCtx, memory64Wasm)
+		require.Error(t, err)
+	})
+
+	instantiate := func(t *testing.T) (wazero.Runtime, api.Module) {
+		r := wazero.NewRuntimeWithConfig(testCtx,
+			config.WithCoreFeatures(api.CoreFeaturesV2|api.CoreFeatureThreads|api.CoreFeatureMemory64))
+
+		mod, err := r.Instantiate(testCtx, memory64Wasm)
+		require.NoError(t, err)
+		return r, mod
+	}
+
+	call := func(t *testing.T, mod api.Module, name string, params ...uint64) []uint64 {
+		results, err := mod.ExportedFunction(name).Call(testCtx, params...)
+		require.NoError(t, err)
+		return results
+	}
+
+	t.Run("load and store", func(t *testing.T) {
+		r, mod := instantiate(t)
+		defer r.Close(testCtx)
+
+		require.Equal(t, []uint64{'h'}, call(t, mod, "load", 0))
+		call(t, mod, "store", 1, 'a')
+		require.Equal(t, []uint64{'a'}, call(t, mod, "load", 1))
+		call(t, mod, "store", pageSize-1, 'z')
+		require.Equal(t, []uint64{'z'}, call(t, mod, "load", pageSize-1))
+	})
+
+	t.Run("size and grow", func(t *testing.T) {
+		r, mod := instantiate(t)
+		defer r.Close(testCtx)
+
+		require.Equal(t, []uint64{1}, call(t, mod, "size"))
+		require.Equal(t, []uint64{1}, call(t, mod, "grow", 1))
+		require.Equal(t, []uint64{2}, call(t, mod, "size"))
+		// The maximum is two pages, and failures are -1 in signed 64-bit integer.
+		require.Equal(t, []uint64{math.MaxUint64}, call(t, mod, "grow", 1))
+		// The delta isn't truncated to 32 bits.
+		require.Equal(t, []uint64{math.MaxUint64}, call(t, mod, "grow", 1<<32))
+		require.Equal(t, []uint64{2}, call(t, mod, "size"))
+
+		call(t, mod, "store", pageSize, 'c')
+		require.Equal(t, []uint64{'c'}, call(t, mod, "load", pageSize))
+	})
+
+	t.Run("fill and copy", func(t *testing.T) {
+		r, mod := instantiate(t)
+		defer r.Close(testCtx)
+
+		call(t, mod, "fill", 5, 'x', 3)
+		call(t, mod, "copy", 10, 0, 8)
+
+		buf, ok := mod.Memory().Read64(10, 8)
+		require.True(t, ok)
+		require.Equal(t, "helloxxx", string(buf))
+	})
+
+	t.Run("atomic", func(t *testing.T) {
+		r, mod := instantiate(t)
+		defer r.Close(testCtx)
+
+		require.True(t, mod.Memory().WriteUint32Le64(8, 1))
+		require.Equal(t, []uint64{1}, call(t, mod, "atomic_add", 8, 2))
+		v, ok := mod.Memory().ReadUint32Le64(8)
+		require.True(t, ok)
+		require.Equal(t, uint32(3), v)
+	})
+
+	t.Run("api", func(t *testing.T) {
+		r, mod := instantiate(t)
+		defer r.Close(testCtx)
+
+		mem := mod.Memory()
+		require.Equal(t, pageSize, mem.Size64())
+		require.True(t, mem.WriteString64(pageSize-2, "ab"))
+		require.Equal(t, []uint64{'b'}, call(t, mod, "load", pageSize-1))
+		_, ok := mem.ReadByte64(pageSize)
+		require.False(t, ok)
+		_, ok = mem.Read64(1<<32, 1)
+		require.False(t, ok)
+		require.False(t, mem.Write64(math.MaxUint64, []byte{1}))
+	})
+
+	t.Run("out of bounds", func(t *testing.T) {
+		r, mod := instantiate(t)
+		defer r.Close(testCtx)
+
+		for _, tc := range []struct {
+			name   string
+			params []uint64
+		}{
+			{name: "load", params: []uint64{pageSize}},
+			{name: "load", params: []uint64{1 << 32}},
+			// The effective address wraps around to zero if it's not checked.
+			{name: "load", params: []uint64{math.MaxUint64}},
+			{name: "load4GiB", params: []uint64{0}},
+			{name: "load4GiB", params: []uint64{math.MaxUint64 - 1<<32 + 1}},
+			{name: "store", params: []uint64{math.MaxUint64, 'a'}},
+			{name: "fill", params: []uint64{pageSize - 1, 'x', 2}},
+			{name: "fill", params: []uint64{math.MaxUint64, 'x', 1}},
+			{name: "fill", params: []uint64{1, 'x', math.MaxUint64}},
+			{name: "copy", params: []uint64{0, 1, math.MaxUint64}},
+			{name: "copy", params: []uint64{math.MaxUint64, 0, 1}},
+			{name: "copy", params: []uint64{0, 1 << 32, 1}},
+			{name: "atomic_add", params: []uint64{math.MaxUint64 - 3, 1}},
+		} {
+			_, err := mod.ExportedFunction(tc.name).Call(testCtx, tc.params...)
+			require.ErrorIs(t, err, wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess, "%s%v", tc.name, tc.params)
+		}
+	})
+}
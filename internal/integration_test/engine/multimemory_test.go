@@ -0,0 +1,203 @@
+package adhoc
+
+import (
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/platform"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+	"github.com/tetratelabs/wazero/internal/wasmruntime"
+)
+
+func TestMultiMemoryCompiler(t *testing.T) {
+	if !platform.CompilerSupported() {
+		t.Skip()
+	}
+	testMultiMemory(t, wazero.NewRuntimeConfigCompiler())
+}
+
+func TestMultiMemoryInterpreter(t *testing.T) {
+	testMultiMemory(t, wazero.NewRuntimeConfigInterpreter())
+}
+
+// multiMemoryImportedWasm exports a memory which is imported by multiMemoryWasm as its memory zero.
+var multiMemoryImportedWasm = binaryencoding.EncodeModule(&wasm.Module{
+	MemorySection: []wasm.Memory{{Min: 1, Max: 1, IsMaxEncoded: true}},
+	ExportSection: []wasm.Export{{Name: "memory", Type: wasm.ExternTypeMemory, Index: 0}},
+})
+
+// multiMemoryWasm imports memory zero, defines memory one initialized by a data segment, and exports functions which
+// access memory one by its index.
+var multiMemoryWasm = func() []byte {
+	i32 := wasm.ValueTypeI32
+	return binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection: []wasm.FunctionType{
+			{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32}, ParamNumInUint64: 1, ResultNumInUint64: 1},
+			{Params: []wasm.ValueType{i32, i32}, ParamNumInUint64: 2},
+			{Results: []wasm.ValueType{i32}, ResultNumInUint64: 1},
+			{Params: []wasm.ValueType{i32, i32, i32}, ParamNumInUint64: 3},
+			{Params: []wasm.ValueType{i32, i32}, Results: []wasm.ValueType{i32}, ParamNumInUint64: 2, ResultNumInUint64: 1},
+		},
+		ImportSection: []wasm.Import{
+			{Type: wasm.ExternTypeMemory, Module: "env", Name: "memory", DescMem: &wasm.Memory{Min: 1}},
+		},
+		FunctionSection: []wasm.Index{0, 1, 0, 2, 3, 3, 4},
+		MemorySection:   []wasm.Memory{{Min: 1, Max: 2, IsMaxEncoded: true}},
+		DataSection: []wasm.DataSegment{
+			{
+				MemoryIndex:      1,
+				OffsetExpression: wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: []byte{0}},
+				Init:             []byte("hello"),
+			},
+		},
+		CodeSection: []wasm.Code{
+			// load1(addr): i32.load8_u of memory 1.
+			{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeI32Load8U, 0x40, 1, 0, wasm.OpcodeEnd}},
+			// store1(addr, v): i32.store8 of memory 1.
+			{Body: []byte{
+				wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 1, wasm.OpcodeI32Store8, 0x40, 1, 0,
+				wasm.OpcodeEnd,
+			}},
+			// grow1(delta): memory.grow of memory 1.
+			{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeMemoryGrow, 1, wasm.OpcodeEnd}},
+			// size1(): memory.size of memory 1.
+			{Body: []byte{wasm.OpcodeMemorySize, 1, wasm.OpcodeEnd}},
+			// fill1(addr, v, n): memory.fill of memory 1.
+			{Body: []byte{
+				wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 1, wasm.OpcodeLocalGet, 2,
+				wasm.OpcodeMiscPrefix, wasm.OpcodeMiscMemoryFill, 1,
+				wasm.OpcodeEnd,
+			}},
+			// copy1to0(dst, src, n): memory.copy from memory 1 to memory 0.
+			{Body: []byte{
+				wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 1, wasm.OpcodeLocalGet, 2,
+				wasm.OpcodeMiscPrefix, wasm.OpcodeMiscMemoryCopy, 0, 1,
+				wasm.OpcodeEnd,
+			}},
+			// store1_load0(addr, v): stores v into memory 1 and then loads from memory 0 at the same address.
+			{Body: []byte{
+				wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 1, wasm.OpcodeI32Store8, 0x40, 1, 0,
+				wasm.OpcodeLocalGet, 0, wasm.OpcodeI32Load8U, 0, 0,
+				wasm.OpcodeEnd,
+			}},
+		},
+		ExportSection: []wasm.Export{
+			{Name: "memory0", Type: wasm.ExternTypeMemory, Index: 0},
+			{Name: "memory1", Type: wasm.ExternTypeMemory, Index: 1},
+			{Name: "load1", Type: wasm.ExternTypeFunc, Index: 0},
+			{Name: "store1", Type: wasm.ExternTypeFunc, Index: 1},
+			{Name: "grow1", Type: wasm.ExternTypeFunc, Index: 2},
+			{Name: "size1", Type: wasm.ExternTypeFunc, Index: 3},
+			{Name: "fill1", Type: wasm.ExternTypeFunc, Index: 4},
+			{Name: "copy1to0", Type: wasm.ExternTypeFunc, Index: 5},
+			{Name: "store1_load0", Type: wasm.ExternTypeFunc, Index: 6},
+		},
+	})
+}()
+
+func testMultiMemory(t *testing.T, config wazero.RuntimeConfig) {
+	const pageSize = uint64(wasm.MemoryPageSize)
+
+	t.Run("disabled", func(t *testing.T) {
+		r := wazero.NewRuntimeWithConfig(testCtx, config.WithCoreFeatures(api.CoreFeaturesV2))
+		defer r.Close(testCtx)
+
+		_, err := r.CompileModule(testCtx, multiMemoryWasm)
+		require.Error(t, err)
+	})
+
+	instantiate := func(t *testing.T) (wazero.Runtime, api.Module) {
+		r := wazero.NewRuntimeWithConfig(testCtx, config.WithCoreFeatures(api.CoreFeaturesV2|api.CoreFeatureMultiMemory))
+
+		_, err := r.InstantiateWithConfig(testCtx, multiMemoryImportedWasm, wazero.NewModuleConfig().WithName("env"))
+		require.NoError(t, err)
+
+		mod, err := r.Instantiate(testCtx, multiMemoryWasm)
+		require.NoError(t, err)
+		return r, mod
+	}
+
+	call := func(t *testing.T, mod api.Module, name string, params ...uint64) []uint64 {
+		results, err := mod.ExportedFunction(name).Call(testCtx, params...)
+		require.NoError(t, err)
+		return results
+	}
+
+	t.Run("exports", func(t *testing.T) {
+		r, mod := instantiate(t)
+		defer r.Close(testCtx)
+
+		mem0, mem1 := mod.ExportedMemory("memory0"), mod.ExportedMemory("memory1")
+		require.Equal(t, r.Module("env").ExportedMemory("memory"), mem0)
+		require.Equal(t, mem0, mod.Memory())
+
+		// The data segment is applied to memory one only.
+		buf, ok := mem1.Read(0, 5)
+		require.True(t, ok)
+		require.Equal(t, "hello", string(buf))
+		buf, ok = mem0.Read(0, 5)
+		require.True(t, ok)
+		require.Equal(t, make([]byte, 5), buf)
+
+		defs := mod.ExportedMemoryDefinitions()
+		require.Equal(t, 2, len(defs))
+		require.Equal(t, uint32(0), defs["memory0"].Index())
+		require.Equal(t, uint32(1), defs["memory1"].Index())
+	})
+
+	t.Run("load and store", func(t *testing.T) {
+		r, mod := instantiate(t)
+		defer r.Close(testCtx)
+
+		require.Equal(t, []uint64{'h'}, call(t, mod, "load1", 0))
+		call(t, mod, "store1", 1, 'a')
+		require.Equal(t, []uint64{'a'}, call(t, mod, "load1", 1))
+
+		// Memory zero is used again after the access to memory one.
+		ok := mod.Memory().WriteByte(2, 'z')
+		require.True(t, ok)
+		require.Equal(t, []uint64{'z'}, call(t, mod, "store1_load0", 2, 'b'))
+		require.Equal(t, []uint64{'b'}, call(t, mod, "load1", 2))
+	})
+
+	t.Run("size and grow", func(t *testing.T) {
+		r, mod := instantiate(t)
+		defer r.Close(testCtx)
+
+		require.Equal(t, []uint64{1}, call(t, mod, "size1"))
+		require.Equal(t, []uint64{1}, call(t, mod, "grow1", 1))
+		require.Equal(t, []uint64{2}, call(t, mod, "size1"))
+		// The maximum of memory one is two pages.
+		require.Equal(t, []uint64{0xffffffff}, call(t, mod, "grow1", 1))
+
+		// The access beyond the initial page of memory one succeeds after growing it, but memory zero is intact.
+		call(t, mod, "store1", pageSize, 'c')
+		require.Equal(t, []uint64{'c'}, call(t, mod, "load1", pageSize))
+		require.Equal(t, uint32(wasm.MemoryPageSize), mod.Memory().Size())
+	})
+
+	t.Run("fill and copy", func(t *testing.T) {
+		r, mod := instantiate(t)
+		defer r.Close(testCtx)
+
+		call(t, mod, "fill1", 5, 'x', 3)
+		call(t, mod, "copy1to0", 10, 0, 8)
+
+		buf, ok := mod.Memory().Read(10, 8)
+		require.True(t, ok)
+		require.Equal(t, "helloxxx", string(buf))
+	})
+
+	t.Run("out of bounds", func(t *testing.T) {
+		r, mod := instantiate(t)
+		defer r.Close(testCtx)
+
+		_, err := mod.ExportedFunction("load1").Call(testCtx, pageSize)
+		require.ErrorIs(t, err, wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+		_, err = mod.ExportedFunction("copy1to0").Call(testCtx, pageSize-1, 0, 2)
+		require.ErrorIs(t, err, wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+	})
+}
@@ -0,0 +1,243 @@
+package adhoc
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/platform"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+func TestRelaxedSIMDCompiler(t *testing.T) {
+	if !platform.CompilerSupported() {
+		t.Skip()
+	}
+	testRelaxedSIMD(t, wazero.NewRuntimeConfigCompiler())
+}
+
+func TestRelaxedSIMDInterpreter(t *testing.T) {
+	testRelaxedSIMD(t, wazero.NewRuntimeConfigInterpreter())
+}
+
+// relaxedSIMDWasm exports a function for each relaxed vector instruction, named after the instruction, which applies
+// it to the v128 parameters.
+var relaxedSIMDWasm = func() []byte {
+	v128 := wasm.ValueTypeV128
+	m := &wasm.Module{
+		TypeSection: []wasm.FunctionType{
+			{Params: []wasm.ValueType{v128}, Results: []wasm.ValueType{v128}},
+			{Params: []wasm.ValueType{v128, v128}, Results: []wasm.ValueType{v128}},
+			{Params: []wasm.ValueType{v128, v128, v128}, Results: []wasm.ValueType{v128}},
+		},
+	}
+	for op := wasm.OpcodeVecI8x16RelaxedSwizzle; op <= wasm.OpcodeVecI32x4RelaxedDotI8x16I7x16AddS; op++ {
+		var paramNum byte
+		switch op {
+		case wasm.OpcodeVecI32x4RelaxedTruncF32x4S, wasm.OpcodeVecI32x4RelaxedTruncF32x4U,
+			wasm.OpcodeVecI32x4RelaxedTruncF64x2SZero, wasm.OpcodeVecI32x4RelaxedTruncF64x2UZero:
+			paramNum = 1
+		case wasm.OpcodeVecI8x16RelaxedSwizzle, wasm.OpcodeVecF32x4RelaxedMin, wasm.OpcodeVecF32x4RelaxedMax,
+			wasm.OpcodeVecF64x2RelaxedMin, wasm.OpcodeVecF64x2RelaxedMax, wasm.OpcodeVecI16x8RelaxedQ15mulrS,
+			wasm.OpcodeVecI16x8RelaxedDotI8x16I7x16S:
+			paramNum = 2
+		default:
+			paramNum = 3
+		}
+
+		var body []byte
+		for i := byte(0); i < paramNum; i++ {
+			body = append(body, wasm.OpcodeLocalGet, i)
+		}
+		body = append(body, wasm.OpcodeVecPrefix, op, wasm.OpcodeVecRelaxedSuffix, wasm.OpcodeEnd)
+
+		m.ExportSection = append(m.ExportSection, wasm.Export{
+			Name: wasm.RelaxedVectorInstructionName(op), Type: wasm.ExternTypeFunc, Index: uint32(len(m.FunctionSection)),
+		})
+		m.FunctionSection = append(m.FunctionSection, wasm.Index(paramNum-1))
+		m.CodeSection = append(m.CodeSection, wasm.Code{Body: body})
+	}
+	return binaryencoding.EncodeModule(m)
+}()
+
+func testRelaxedSIMD(t *testing.T, config wazero.RuntimeConfig) {
+	t.Run("disabled", func(t *testing.T) {
+		r := wazero.NewRuntimeWithConfig(testCtx, config)
+		defer r.Close(testCtx)
+
+		_, err := r.CompileModule(testCtx, relaxedSIMDWasm)
+		require.Error(t, err)
+	})
+
+	r := wazero.NewRuntimeWithConfig(testCtx, config.WithCoreFeatures(api.CoreFeaturesV2|api.CoreFeatureRelaxedSIMD))
+	defer r.Close(testCtx)
+
+	mod, err := r.Instantiate(testCtx, relaxedSIMDWasm)
+	require.NoError(t, err)
+
+	i8x16 := func(vs ...int8) []uint64 {
+		var b [16]byte
+		for i, v := range vs {
+			b[i] = byte(v)
+		}
+		return []uint64{binary.LittleEndian.Uint64(b[:8]), binary.LittleEndian.Uint64(b[8:])}
+	}
+	i16x8 := func(vs ...int16) []uint64 {
+		var b [16]byte
+		for i, v := range vs {
+			binary.LittleEndian.PutUint16(b[i*2:], uint16(v))
+		}
+		return []uint64{binary.LittleEndian.Uint64(b[:8]), binary.LittleEndian.Uint64(b[8:])}
+	}
+	i32x4 := func(v1, v2, v3, v4 uint32) []uint64 {
+		return []uint64{uint64(v1) | uint64(v2)<<32, uint64(v3) | uint64(v4)<<32}
+	}
+	f32x4 := func(v1, v2, v3, v4 float32) []uint64 {
+		return i32x4(math.Float32bits(v1), math.Float32bits(v2), math.Float32bits(v3), math.Float32bits(v4))
+	}
+	f64x2 := func(v1, v2 float64) []uint64 {
+		return []uint64{math.Float64bits(v1), math.Float64bits(v2)}
+	}
+	params := func(vs ...[]uint64) (ret []uint64) {
+		for _, v := range vs {
+			ret = append(ret, v...)
+		}
+		return
+	}
+	nan32, nan64 := float32(math.NaN()), math.NaN()
+	ones := i8x16(1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1)
+	seq := i8x16(1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16)
+	minI8 := i8x16(-128, -128, -128, -128, -128, -128, -128, -128, -128, -128, -128, -128, -128, -128, -128, -128)
+
+	tests := []struct {
+		name     string
+		fn       string // defaults to name
+		params   []uint64
+		expected []uint64
+	}{
+		{
+			name:     wasm.OpcodeVecI8x16RelaxedSwizzleName,
+			params:   params(seq, i8x16(15, 0, 16, -1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 127)),
+			expected: i8x16(16, 1, 0, 0, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 0),
+		},
+		{
+			name:     wasm.OpcodeVecI32x4RelaxedTruncF32x4SName,
+			params:   f32x4(1.5, -1.5, nan32, 3e9),
+			expected: i32x4(1, 0xffffffff, 0, math.MaxInt32),
+		},
+		{
+			name:     wasm.OpcodeVecI32x4RelaxedTruncF32x4UName,
+			params:   f32x4(1.5, -1.5, nan32, 5e9),
+			expected: i32x4(1, 0, 0, math.MaxUint32),
+		},
+		{
+			name:     wasm.OpcodeVecI32x4RelaxedTruncF64x2SZeroName,
+			params:   f64x2(-2.7, 1e10),
+			expected: i32x4(0xfffffffe, math.MaxInt32, 0, 0),
+		},
+		{
+			name:     wasm.OpcodeVecI32x4RelaxedTruncF64x2UZeroName,
+			params:   f64x2(nan64, 1e10),
+			expected: i32x4(0, math.MaxUint32, 0, 0),
+		},
+		{
+			// The second lane would be 2^-24 if the multiplication and addition were fused.
+			name:     wasm.OpcodeVecF32x4RelaxedMaddName,
+			params:   params(f32x4(2, 1+0x1p-12, 0.5, -1), f32x4(3, 1+0x1p-12, 4, 0), f32x4(1, -(1+0x1p-11), -2, 3)),
+			expected: f32x4(7, 0, 0, 3),
+		},
+		{
+			name:     wasm.OpcodeVecF32x4RelaxedNmaddName,
+			params:   params(f32x4(2, 1+0x1p-12, 0.5, -1), f32x4(3, 1+0x1p-12, 4, 0), f32x4(1, 1+0x1p-11, -2, 3)),
+			expected: f32x4(-5, 0, -4, 3),
+		},
+		{
+			name:     wasm.OpcodeVecF64x2RelaxedMaddName,
+			params:   params(f64x2(2, 1+0x1p-27), f64x2(3, 1+0x1p-27), f64x2(1, -(1+0x1p-26))),
+			expected: f64x2(7, 0),
+		},
+		{
+			name:     wasm.OpcodeVecF64x2RelaxedNmaddName,
+			params:   params(f64x2(2, 1+0x1p-27), f64x2(3, 1+0x1p-27), f64x2(1, 1+0x1p-26)),
+			expected: f64x2(-5, 0),
+		},
+		{
+			name:     wasm.OpcodeVecI8x16RelaxedLaneselectName,
+			params:   params(i32x4(0x11111111, 0x22222222, 0x33333333, 0x44444444), i32x4(0, 0, 0, 0), i32x4(0xff00ff00, 0, 0xffffffff, 0x0f0f0f0f)),
+			expected: i32x4(0x11001100, 0, 0x33333333, 0x04040404),
+		},
+		{
+			name:     wasm.OpcodeVecI16x8RelaxedLaneselectName,
+			params:   params(i32x4(1, 2, 3, 4), i32x4(5, 6, 7, 8), i32x4(0xffffffff, 0, 0xffffffff, 0)),
+			expected: i32x4(1, 6, 3, 8),
+		},
+		{
+			name:     wasm.OpcodeVecI32x4RelaxedLaneselectName,
+			params:   params(i32x4(1, 2, 3, 4), i32x4(5, 6, 7, 8), i32x4(0, 0xffffffff, 0, 0xffffffff)),
+			expected: i32x4(5, 2, 7, 4),
+		},
+		{
+			name:     wasm.OpcodeVecI64x2RelaxedLaneselectName,
+			params:   params(i32x4(1, 2, 3, 4), i32x4(5, 6, 7, 8), i32x4(0xffffffff, 0xffffffff, 0, 0)),
+			expected: i32x4(1, 2, 7, 8),
+		},
+		{
+			name:     wasm.OpcodeVecF32x4RelaxedMinName,
+			params:   params(f32x4(1, -2, 3, -4), f32x4(2, -1, -3, 4)),
+			expected: f32x4(1, -2, -3, -4),
+		},
+		{
+			name:     wasm.OpcodeVecF32x4RelaxedMaxName,
+			params:   params(f32x4(1, -2, 3, -4), f32x4(2, -1, -3, 4)),
+			expected: f32x4(2, -1, 3, 4),
+		},
+		{
+			name:     wasm.OpcodeVecF64x2RelaxedMinName,
+			params:   params(f64x2(1, -2), f64x2(2, -3)),
+			expected: f64x2(1, -3),
+		},
+		{
+			name:     wasm.OpcodeVecF64x2RelaxedMaxName,
+			params:   params(f64x2(1, -2), f64x2(2, -3)),
+			expected: f64x2(2, -2),
+		},
+		{
+			name:     wasm.OpcodeVecI16x8RelaxedQ15mulrSName,
+			params:   params(i16x8(0x4000, -0x8000, 100, 0, 0, 0, 0, 0), i16x8(0x4000, -0x8000, -0x4000, 0, 0, 0, 0, 0)),
+			expected: i16x8(0x2000, 0x7fff, -50, 0, 0, 0, 0, 0),
+		},
+		{
+			name:     wasm.OpcodeVecI16x8RelaxedDotI8x16I7x16SName,
+			params:   params(ones, seq),
+			expected: i16x8(3, 7, 11, 15, 19, 23, 27, 31),
+		},
+		{
+			name:     wasm.OpcodeVecI16x8RelaxedDotI8x16I7x16SName + " saturated",
+			fn:       wasm.OpcodeVecI16x8RelaxedDotI8x16I7x16SName,
+			params:   params(minI8, minI8),
+			expected: i16x8(0x7fff, 0x7fff, 0x7fff, 0x7fff, 0x7fff, 0x7fff, 0x7fff, 0x7fff),
+		},
+		{
+			name:     wasm.OpcodeVecI32x4RelaxedDotI8x16I7x16AddSName,
+			params:   params(ones, seq, i32x4(100, 100, 0xffffff9c, 0)),
+			expected: i32x4(110, 126, 0xffffffc6, 58),
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			fn := tc.fn
+			if fn == "" {
+				fn = tc.name
+			}
+			results, err := mod.ExportedFunction(fn).Call(testCtx, tc.params...)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, results)
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package adhoc
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/experimental/logging"
+	"github.com/tetratelabs/wazero/internal/platform"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+	"github.com/tetratelabs/wazero/internal/wasmruntime"
+)
+
+func TestTailCallCompiler(t *testing.T) {
+	if !platform.CompilerSupported() {
+		t.Skip()
+	}
+	testTailCall(t, wazero.NewRuntimeConfigCompiler())
+}
+
+func TestTailCallInterpreter(t *testing.T) {
+	testTailCall(t, wazero.NewRuntimeConfigInterpreter())
+}
+
+// tailCallWasm exports functions which recurse via return_call and return_call_indirect deeper than the call stack
+// would allow without tail calls.
+var tailCallWasm = func() []byte {
+	i32, i64 := wasm.ValueTypeI32, wasm.ValueTypeI64
+	return binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection: []wasm.FunctionType{
+			{Params: []wasm.ValueType{i64}, Results: []wasm.ValueType{i32}, ParamNumInUint64: 1, ResultNumInUint64: 1},
+			{Params: []wasm.ValueType{i64}, Results: []wasm.ValueType{i64, i64, i64}, ParamNumInUint64: 1, ResultNumInUint64: 3},
+			{
+				Params:           []wasm.ValueType{i64, i64, i64, i64},
+				Results:          []wasm.ValueType{i64, i64, i64},
+				ParamNumInUint64: 4, ResultNumInUint64: 3,
+			},
+			{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32}, ParamNumInUint64: 1, ResultNumInUint64: 1},
+			{Results: []wasm.ValueType{i32}, ResultNumInUint64: 1},
+		},
+		ImportSection: []wasm.Import{
+			{Type: wasm.ExternTypeFunc, Module: "env", Name: "double", DescFunc: 3},
+		},
+		FunctionSection: []wasm.Index{0, 0, 1, 2, 0, 3, 4},
+		TableSection:    []wasm.Table{{Min: 2, Type: wasm.RefTypeFuncref}},
+		ElementSection: []wasm.ElementSegment{
+			{
+				OffsetExpr: wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: []byte{0}},
+				Init:       []wasm.Index{5, 3},
+				Type:       wasm.RefTypeFuncref,
+			},
+		},
+		CodeSection: []wasm.Code{
+			// is_even(n): n == 0 ? 1 : is_odd(n - 1)
+			{Body: []byte{
+				wasm.OpcodeLocalGet, 0, wasm.OpcodeI64Eqz, wasm.OpcodeIf, 0x40, wasm.OpcodeI32Const, 1, wasm.OpcodeReturn, wasm.OpcodeEnd,
+				wasm.OpcodeLocalGet, 0, wasm.OpcodeI64Const, 1, wasm.OpcodeI64Sub, wasm.OpcodeReturnCall, 2,
+				wasm.OpcodeEnd,
+			}},
+			// is_odd(n): n == 0 ? 0 : is_even(n - 1)
+			{Body: []byte{
+				wasm.OpcodeLocalGet, 0, wasm.OpcodeI64Eqz, wasm.OpcodeIf, 0x40, wasm.OpcodeI32Const, 0, wasm.OpcodeReturn, wasm.OpcodeEnd,
+				wasm.OpcodeLocalGet, 0, wasm.OpcodeI64Const, 1, wasm.OpcodeI64Sub, wasm.OpcodeReturnCall, 1,
+				wasm.OpcodeEnd,
+			}},
+			// countdown(n): n == 0 ? (1, 2, 3) : countdown_sum(n - 1, 1, 2, 3)
+			{Body: []byte{
+				wasm.OpcodeLocalGet, 0, wasm.OpcodeI64Eqz, wasm.OpcodeIf, 0x40,
+				wasm.OpcodeI64Const, 1, wasm.OpcodeI64Const, 2, wasm.OpcodeI64Const, 3, wasm.OpcodeReturn,
+				wasm.OpcodeEnd,
+				wasm.OpcodeLocalGet, 0, wasm.OpcodeI64Const, 1, wasm.OpcodeI64Sub,
+				wasm.OpcodeI64Const, 1, wasm.OpcodeI64Const, 2, wasm.OpcodeI64Const, 3,
+				wasm.OpcodeReturnCall, 4,
+				wasm.OpcodeEnd,
+			}},
+			// countdown_sum(a, b, c, d): countdown(a + b + c + d - 6)
+			{Body: []byte{
+				wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 1, wasm.OpcodeI64Add,
+				wasm.OpcodeLocalGet, 2, wasm.OpcodeI64Add, wasm.OpcodeLocalGet, 3, wasm.OpcodeI64Add,
+				wasm.OpcodeI64Const, 6, wasm.OpcodeI64Sub,
+				wasm.OpcodeReturnCall, 3,
+				wasm.OpcodeEnd,
+			}},
+			// dispatch(n): n == 0 ? 42 : table[0](n - 1), where table[0] is dispatch.
+			{Body: []byte{
+				wasm.OpcodeLocalGet, 0, wasm.OpcodeI64Eqz, wasm.OpcodeIf, 0x40, wasm.OpcodeI32Const, 42, wasm.OpcodeReturn, wasm.OpcodeEnd,
+				wasm.OpcodeLocalGet, 0, wasm.OpcodeI64Const, 1, wasm.OpcodeI64Sub,
+				wasm.OpcodeI32Const, 0, wasm.OpcodeReturnCallIndirect, 0, 0,
+				wasm.OpcodeEnd,
+			}},
+			// call_double(x): double(x)
+			{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeReturnCall, 0, wasm.OpcodeEnd}},
+			// type_mismatch(): table[1](0), where table[1] is countdown.
+			{Body: []byte{
+				wasm.OpcodeI64Const, 0, wasm.OpcodeI32Const, 1, wasm.OpcodeReturnCallIndirect, 0, 0,
+				wasm.OpcodeEnd,
+			}},
+		},
+		ExportSection: []wasm.Export{
+			{Name: "is_even", Type: wasm.ExternTypeFunc, Index: 1},
+			{Name: "countdown", Type: wasm.ExternTypeFunc, Index: 3},
+			{Name: "dispatch", Type: wasm.ExternTypeFunc, Index: 5},
+			{Name: "call_double", Type: wasm.ExternTypeFunc, Index: 6},
+			{Name: "type_mismatch", Type: wasm.ExternTypeFunc, Index: 7},
+		},
+	})
+}()
+
+func testTailCall(t *testing.T, config wazero.RuntimeConfig) {
+	instantiate := func(t *testing.T, ctx context.Context) (wazero.Runtime, api.Module) {
+		r := wazero.NewRuntimeWithConfig(ctx, config.WithCoreFeatures(api.CoreFeaturesV2|api.CoreFeatureTailCall))
+
+		_, err := r.NewHostModuleBuilder("env").NewFunctionBuilder().
+			WithFunc(func(x uint32) uint32 { return x * 2 }).Export("double").
+			Instantiate(ctx)
+		require.NoError(t, err)
+
+		mod, err := r.Instantiate(ctx, tailCallWasm)
+		require.NoError(t, err)
+		return r, mod
+	}
+
+	r, mod := instantiate(t, testCtx)
+	defer r.Close(testCtx)
+
+	call := func(t *testing.T, mod api.Module, name string, params ...uint64) []uint64 {
+		results, err := mod.ExportedFunction(name).Call(testCtx, params...)
+		require.NoError(t, err)
+		return results
+	}
+
+	t.Run("return_call", func(t *testing.T) {
+		// The depth exceeds the maximum call depth, which would overflow without tail calls.
+		require.Equal(t, []uint64{1}, call(t, mod, "is_even", 2_000_000))
+		require.Equal(t, []uint64{0}, call(t, mod, "is_even", 2_000_001))
+	})
+
+	t.Run("return_call more params and results", func(t *testing.T) {
+		require.Equal(t, []uint64{1, 2, 3}, call(t, mod, "countdown", 1_000_000))
+	})
+
+	t.Run("return_call_indirect", func(t *testing.T) {
+		require.Equal(t, []uint64{42}, call(t, mod, "dispatch", 2_000_000))
+	})
+
+	t.Run("return_call host function", func(t *testing.T) {
+		require.Equal(t, []uint64{42}, call(t, mod, "call_double", 21))
+	})
+
+	t.Run("return_call_indirect type mismatch", func(t *testing.T) {
+		_, err := mod.ExportedFunction("type_mismatch").Call(testCtx)
+		require.ErrorIs(t, err, wasmruntime.ErrRuntimeIndirectCallTypeMismatch)
+	})
+
+	t.Run("listener", func(t *testing.T) {
+		var log bytes.Buffer
+		ctx := context.WithValue(testCtx, experimental.FunctionListenerFactoryKey{}, logging.NewLoggingListenerFactory(&log))
+		r, mod := instantiate(t, ctx)
+		defer r.Close(ctx)
+
+		require.Equal(t, []uint64{0}, call(t, mod, "is_even", 5))
+		require.Equal(t, []uint64{1, 2, 3}, call(t, mod, "countdown", 2))
+		require.Equal(t, []uint64{42}, call(t, mod, "dispatch", 3))
+
+		// Each function listened to before is also listened to after.
+		require.Equal(t, strings.Count(log.String(), "-->"), strings.Count(log.String(), "<--"))
+	})
+}
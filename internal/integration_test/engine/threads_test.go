@@ -0,0 +1,196 @@
+package adhoc
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/platform"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+func TestThreadsCompiler(t *testing.T) {
+	if !platform.CompilerSupported() {
+		t.Skip()
+	}
+	testThreads(t, wazero.NewRuntimeConfigCompiler())
+}
+
+func TestThreadsInterpreter(t *testing.T) {
+	testThreads(t, wazero.NewRuntimeConfigInterpreter())
+}
+
+// threadsWasm exports a function per atomic instruction under test, which operates on a shared memory of one page.
+var threadsWasm = func() []byte {
+	i32, i64 := wasm.ValueTypeI32, wasm.ValueTypeI64
+	atomic := func(op wasm.OpcodeAtomic, localCount byte, memarg ...byte) []byte {
+		var body []byte
+		for i := byte(0); i < localCount; i++ {
+			body = append(body, wasm.OpcodeLocalGet, i)
+		}
+		body = append(body, wasm.OpcodeAtomicPrefix, op)
+		return append(append(body, memarg...), wasm.OpcodeEnd)
+	}
+	module := &wasm.Module{
+		TypeSection: []wasm.FunctionType{
+			{Params: []wasm.ValueType{i32, i32}, Results: []wasm.ValueType{i32}, ParamNumInUint64: 2, ResultNumInUint64: 1},
+			{Params: []wasm.ValueType{i32, i32, i32}, Results: []wasm.ValueType{i32}, ParamNumInUint64: 3, ResultNumInUint64: 1},
+			{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32}, ParamNumInUint64: 1, ResultNumInUint64: 1},
+			{Params: []wasm.ValueType{i32, i32}, ParamNumInUint64: 2},
+			{Params: []wasm.ValueType{i32, i32, i64}, Results: []wasm.ValueType{i32}, ParamNumInUint64: 3, ResultNumInUint64: 1},
+			{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i64}, ParamNumInUint64: 1, ResultNumInUint64: 1},
+			{},
+		},
+		FunctionSection: []wasm.Index{0, 0, 1, 2, 3, 4, 0, 5, 6},
+		MemorySection:   []wasm.Memory{{Min: 1, Cap: 1, Max: 1, IsMaxEncoded: true, IsShared: true}},
+		CodeSection: []wasm.Code{
+			{Body: atomic(wasm.OpcodeAtomicI32RmwAdd, 2, 0x2, 0x0)},
+			{Body: atomic(wasm.OpcodeAtomicI32Rmw8AddU, 2, 0x0, 0x0)},
+			{Body: atomic(wasm.OpcodeAtomicI32RmwCmpxchg, 3, 0x2, 0x0)},
+			{Body: atomic(wasm.OpcodeAtomicI32Load, 1, 0x2, 0x0)},
+			{Body: atomic(wasm.OpcodeAtomicI32Store, 2, 0x2, 0x0)},
+			{Body: atomic(wasm.OpcodeAtomicMemoryWait32, 3, 0x2, 0x0)},
+			{Body: atomic(wasm.OpcodeAtomicMemoryNotify, 2, 0x2, 0x0)},
+			{Body: atomic(wasm.OpcodeAtomicI64Load, 1, 0x3, 0x0)},
+			{Body: atomic(wasm.OpcodeAtomicFence, 0, 0x0)},
+		},
+		ExportSection: []wasm.Export{
+			{Name: "add", Type: wasm.ExternTypeFunc, Index: 0},
+			{Name: "add8", Type: wasm.ExternTypeFunc, Index: 1},
+			{Name: "cmpxchg", Type: wasm.ExternTypeFunc, Index: 2},
+			{Name: "load", Type: wasm.ExternTypeFunc, Index: 3},
+			{Name: "store", Type: wasm.ExternTypeFunc, Index: 4},
+			{Name: "wait", Type: wasm.ExternTypeFunc, Index: 5},
+			{Name: "notify", Type: wasm.ExternTypeFunc, Index: 6},
+			{Name: "load64", Type: wasm.ExternTypeFunc, Index: 7},
+			{Name: "fence", Type: wasm.ExternTypeFunc, Index: 8},
+		},
+	}
+	return binaryencoding.EncodeModule(module)
+}()
+
+func testThreads(t *testing.T, config wazero.RuntimeConfig) {
+	r := wazero.NewRuntimeWithConfig(testCtx, config.WithCoreFeatures(api.CoreFeaturesV2|api.CoreFeatureThreads))
+	defer r.Close(testCtx)
+
+	mod, err := r.Instantiate(testCtx, threadsWasm)
+	require.NoError(t, err)
+
+	call := func(name string, params ...uint64) (uint64, error) {
+		res, err := mod.ExportedFunction(name).Call(testCtx, params...)
+		if err != nil || len(res) == 0 {
+			return 0, err
+		}
+		return res[0], nil
+	}
+	requireCall := func(t *testing.T, expected uint64, name string, params ...uint64) {
+		actual, err := call(name, params...)
+		require.NoError(t, err)
+		require.Equal(t, expected, actual)
+	}
+
+	t.Run("rmw", func(t *testing.T) {
+		requireCall(t, 0, "store", 0, 0x1ff)
+		requireCall(t, 0x1ff, "add", 0, 1)
+		// The narrow addition wraps around without carrying into the next byte.
+		requireCall(t, 0x00, "add8", 0, 0xff)
+		requireCall(t, 0x2ff, "load", 0)
+		requireCall(t, 0, "fence")
+	})
+
+	t.Run("cmpxchg", func(t *testing.T) {
+		requireCall(t, 0, "cmpxchg", 4, 0, 5)
+		requireCall(t, 5, "cmpxchg", 4, 0, 7)
+		requireCall(t, 5, "load", 4)
+	})
+
+	t.Run("i64", func(t *testing.T) {
+		requireCall(t, 0, "store", 8, 1)
+		requireCall(t, 0, "store", 12, 2)
+		requireCall(t, 2<<32|1, "load64", 8)
+	})
+
+	t.Run("unaligned", func(t *testing.T) {
+		_, err := call("add", 1, 1)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "unaligned atomic")
+	})
+
+	t.Run("out of bounds", func(t *testing.T) {
+		_, err := call("load", uint64(wasm.MemoryPageSize))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "out of bounds memory access")
+	})
+
+	t.Run("wait", func(t *testing.T) {
+		requireCall(t, wasm.AtomicWaitNotEqual, "wait", 16, 1, 0)
+		requireCall(t, wasm.AtomicWaitTimedOut, "wait", 16, 0, 1000)
+	})
+
+	t.Run("concurrent add", func(t *testing.T) {
+		const goroutines, iterations = 8, 1000
+		var wg sync.WaitGroup
+		wg.Add(goroutines)
+		for i := 0; i < goroutines; i++ {
+			go func() {
+				defer wg.Done()
+				add := mod.ExportedFunction("add")
+				for j := 0; j < iterations; j++ {
+					_, err := add.Call(testCtx, 20, 1)
+					require.NoError(t, err)
+				}
+			}()
+		}
+		wg.Wait()
+		requireCall(t, goroutines*iterations, "load", 20)
+	})
+
+	t.Run("wait and notify", func(t *testing.T) {
+		done := make(chan uint64)
+		go func() {
+			res, err := call("wait", 24, 0, uint64(0xffffffffffffffff)) // No timeout.
+			require.NoError(t, err)
+			done <- res
+		}()
+		// Retry until the waiter is woken, as it may not have started waiting yet.
+		for {
+			woken, err := call("notify", 24, 1)
+			require.NoError(t, err)
+			if woken == 1 {
+				break
+			}
+		}
+		require.Equal(t, wasm.AtomicWaitOk, <-done)
+	})
+
+	t.Run("wait woken by context done", func(t *testing.T) {
+		r := wazero.NewRuntimeWithConfig(testCtx, config.WithCloseOnContextDone(true).
+			WithCoreFeatures(api.CoreFeaturesV2|api.CoreFeatureThreads))
+		defer r.Close(testCtx)
+		mod, err := r.Instantiate(testCtx, threadsWasm)
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(testCtx, 10*time.Millisecond)
+		defer cancel()
+		_, err = mod.ExportedFunction("wait").Call(ctx, 24, 0, uint64(0xffffffffffffffff)) // No timeout.
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	// This closes the module, so must be the last test.
+	t.Run("wait woken by close", func(t *testing.T) {
+		done := make(chan error)
+		go func() {
+			_, err := call("wait", 24, 0, uint64(0xffffffffffffffff)) // No timeout.
+			done <- err
+		}()
+		time.Sleep(10 * time.Millisecond) // Either waiting or not called yet, which both fail the same.
+		require.NoError(t, mod.CloseWithExitCode(testCtx, 3))
+		require.ErrorIs(t, <-done, sys.NewExitError(3))
+	})
+}
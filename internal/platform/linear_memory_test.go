@@ -0,0 +1,45 @@
+package platform
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestLinearMemory(t *testing.T) {
+	switch runtime.GOOS {
+	case "linux", "darwin", "freebsd":
+	default:
+		_, err := ReserveLinearMemory(1 << 20)
+		require.Error(t, err)
+		return
+	}
+
+	const reserve, page = 1 << 20, 1 << 16
+	mem, err := ReserveLinearMemory(reserve)
+	require.NoError(t, err)
+	defer mem.Free()
+
+	b := mem.Reallocate(page)
+	require.Equal(t, page, len(b))
+	require.Equal(t, page, cap(b))
+	b[page-1] = 1
+
+	// Growing doesn't move the memory.
+	grown := mem.Reallocate(2 * page)
+	require.Equal(t, 2*page, len(grown))
+	require.Equal(t, &b[0], &grown[0])
+	require.Equal(t, byte(1), grown[page-1])
+	require.Equal(t, byte(0), grown[2*page-1])
+
+	// Shrinking returns a view of the accessible memory.
+	require.Equal(t, page, len(mem.Reallocate(page)))
+
+	// The reservation can't be exceeded.
+	require.Nil(t, mem.Reallocate(reserve+page))
+	require.Equal(t, reserve, len(mem.Reallocate(reserve)))
+
+	_, err = ReserveLinearMemory(0)
+	require.EqualError(t, err, "invalid reservation size: 0")
+}
@@ -0,0 +1,58 @@
+//go:build darwin || linux || freebsd
+
+package platform
+
+import (
+	"fmt"
+	"math"
+	"syscall"
+)
+
+// LinearMemory is a reservation of virtual memory, which is made readable and
+// writable as it grows. The rest of the reservation is inaccessible, so it
+// acts as guard pages.
+type LinearMemory struct {
+	reserved []byte
+	// size is the length of the accessible prefix of reserved.
+	size uint64
+}
+
+// ReserveLinearMemory reserves the given number of bytes of virtual memory,
+// without making any of it accessible.
+func ReserveLinearMemory(reserve uint64) (*LinearMemory, error) {
+	if reserve == 0 || reserve > math.MaxInt {
+		return nil, fmt.Errorf("invalid reservation size: %d", reserve)
+	}
+	b, err := syscall.Mmap(-1, 0, int(reserve), syscall.PROT_NONE, syscall.MAP_ANON|syscall.MAP_PRIVATE)
+	if err != nil {
+		return nil, err
+	}
+	return &LinearMemory{reserved: b}, nil
+}
+
+// Reallocate implements the same method as documented on
+// experimental.LinearMemory. size must be a multiple of the OS page size.
+//
+// The memory never moves, so the returned slice always shares the same
+// backing.
+func (m *LinearMemory) Reallocate(size uint64) []byte {
+	if size > uint64(len(m.reserved)) {
+		return nil
+	} else if size > m.size {
+		if err := mprotect(m.reserved[m.size:size], syscall.PROT_READ|syscall.PROT_WRITE); err != nil {
+			return nil
+		}
+		m.size = size
+	}
+	return m.reserved[:size:size]
+}
+
+// Free implements the same method as documented on experimental.LinearMemory.
+func (m *LinearMemory) Free() {
+	if m.reserved != nil {
+		if err := syscall.Munmap(m.reserved); err != nil {
+			panic(err) // see mustMunmapCodeSegment
+		}
+		m.reserved, m.size = nil, 0
+	}
+}
@@ -0,0 +1,27 @@
+//go:build !(darwin || linux || freebsd)
+
+package platform
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// LinearMemory is a reservation of virtual memory, which isn't supported on
+// this platform.
+type LinearMemory struct{}
+
+// ReserveLinearMemory returns an error as reserving virtual memory isn't
+// supported on this platform.
+func ReserveLinearMemory(uint64) (*LinearMemory, error) {
+	return nil, fmt.Errorf("linear memory reservation unsupported on GOOS=%s", runtime.GOOS)
+}
+
+// Reallocate implements the same method as documented on
+// experimental.LinearMemory.
+func (m *LinearMemory) Reallocate(uint64) []byte {
+	return nil
+}
+
+// Free implements the same method as documented on experimental.LinearMemory.
+func (m *LinearMemory) Free() {}
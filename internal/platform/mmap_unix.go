@@ -34,11 +34,15 @@ func mmapCodeSegmentARM64(size int) ([]byte, error) {
 
 // MprotectRX is like syscall.Mprotect with RX permission, defined locally so that freebsd compiles.
 func MprotectRX(b []byte) (err error) {
+	return mprotect(b, syscall.PROT_READ|syscall.PROT_EXEC)
+}
+
+// mprotect is like syscall.Mprotect, defined locally so that freebsd compiles.
+func mprotect(b []byte, prot int) (err error) {
 	var _p0 unsafe.Pointer
 	if len(b) > 0 {
 		_p0 = unsafe.Pointer(&b[0])
 	}
-	const prot = syscall.PROT_READ | syscall.PROT_EXEC
 	_, _, e1 := syscall.Syscall(syscall.SYS_MPROTECT, uintptr(_p0), uintptr(len(b)), uintptr(prot))
 	if e1 != 0 {
 		err = syscall.Errno(e1)
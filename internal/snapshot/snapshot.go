@@ -0,0 +1,201 @@
+// Package snapshot allows experimental.Snapshot without introducing a package
+// cycle.
+package snapshot
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Key is a context.Context Value key. Its associated value should be a
+// *Snapshot.
+type Key struct{}
+
+// FromContext returns the snapshot registered with Key, or nil if there is
+// none.
+func FromContext(ctx context.Context) *Snapshot {
+	if ctx == nil { // Instantiate doesn't crash on nil.
+		return nil
+	}
+	snap, _ := ctx.Value(Key{}).(*Snapshot)
+	return snap
+}
+
+// Snapshotter is implemented by module instances which can be snapshotted.
+type Snapshotter interface {
+	Snapshot() (*Snapshot, error)
+}
+
+// Snapshot is the state of a module instance which is not reproduced by
+// instantiating its module again.
+//
+// Only state defined by the module is included. Imported memory, globals and
+// tables belong to the module that defined them.
+type Snapshot struct {
+	// ModuleID is the ID of the compiled module the snapshot was taken from.
+	ModuleID [32]byte
+
+	// Memory is a copy of the defined memory, or nil when there is none.
+	Memory []byte
+
+	// Globals are the defined mutable globals.
+	Globals []Global
+
+	// Tables are the defined tables.
+	Tables []Table
+}
+
+// Global is the value of a mutable global.
+type Global struct {
+	// Index is the index of the global in the module's global index space.
+	Index uint32
+
+	// Val and ValHi are the same as on wasm.GlobalInstance, except funcref
+	// values are encoded as function indices, like Table.Elements.
+	Val, ValHi uint64
+}
+
+// Table holds the elements of a table.
+type Table struct {
+	// Index is the index of the table in the module's table index space.
+	Index uint32
+
+	// Elements are one plus the function index of each element, or zero for
+	// null.
+	Elements []uint32
+}
+
+// magic is the first bytes of an encoded Snapshot. The last byte is the
+// version of the encoding.
+var magic = []byte{'W', 'S', 'N', 'P', 2}
+
+// Encode returns the binary encoding of the snapshot.
+func (s *Snapshot) Encode() []byte {
+	size := len(magic) + len(s.ModuleID) + 1 + 8 + len(s.Memory) + 4 + len(s.Globals)*20 + 4
+	for i := range s.Tables {
+		size += 8 + len(s.Tables[i].Elements)*4
+	}
+
+	b := make([]byte, 0, size)
+	b = append(b, magic...)
+	b = append(b, s.ModuleID[:]...)
+	if s.Memory == nil {
+		b = append(b, 0)
+	} else {
+		b = append(b, 1)
+		// 64-bit as a memory can be 4GiB, or more with memory64.
+		b = binary.LittleEndian.AppendUint64(b, uint64(len(s.Memory)))
+		b = append(b, s.Memory...)
+	}
+
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(s.Globals)))
+	for _, g := range s.Globals {
+		b = binary.LittleEndian.AppendUint32(b, g.Index)
+		b = binary.LittleEndian.AppendUint64(b, g.Val)
+		b = binary.LittleEndian.AppendUint64(b, g.ValHi)
+	}
+
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(s.Tables)))
+	for _, t := range s.Tables {
+		b = binary.LittleEndian.AppendUint32(b, t.Index)
+		b = binary.LittleEndian.AppendUint32(b, uint32(len(t.Elements)))
+		for _, e := range t.Elements {
+			b = binary.LittleEndian.AppendUint32(b, e)
+		}
+	}
+	return b
+}
+
+// Decode decodes a snapshot encoded by Snapshot.Encode.
+func Decode(b []byte) (*Snapshot, error) {
+	if !bytes.HasPrefix(b, magic) {
+		return nil, errors.New("invalid snapshot header")
+	}
+	r := bytes.NewReader(b[len(magic):])
+
+	s := &Snapshot{}
+	if _, err := io.ReadFull(r, s.ModuleID[:]); err != nil {
+		return nil, fmt.Errorf("read module ID: %w", err)
+	}
+
+	hasMemory, err := r.ReadByte()
+	if err != nil {
+		return nil, fmt.Errorf("read memory: %w", err)
+	}
+	switch hasMemory {
+	case 0:
+	case 1:
+		size, err := readUint64(r)
+		if err != nil {
+			return nil, fmt.Errorf("read memory size: %w", err)
+		} else if size > uint64(r.Len()) {
+			return nil, fmt.Errorf("read memory: %w", io.ErrUnexpectedEOF)
+		}
+		s.Memory = make([]byte, size)
+		_, _ = io.ReadFull(r, s.Memory)
+	default:
+		return nil, fmt.Errorf("invalid memory flag: %#x", hasMemory)
+	}
+
+	count, err := readUint32(r)
+	if err != nil {
+		return nil, fmt.Errorf("read global count: %w", err)
+	} else if int64(count)*20 > int64(r.Len()) {
+		return nil, fmt.Errorf("read globals: %w", io.ErrUnexpectedEOF)
+	}
+	s.Globals = make([]Global, count)
+	for i := range s.Globals {
+		g := &s.Globals[i]
+		g.Index, _ = readUint32(r)
+		g.Val, _ = readUint64(r)
+		g.ValHi, _ = readUint64(r)
+	}
+
+	if count, err = readUint32(r); err != nil {
+		return nil, fmt.Errorf("read table count: %w", err)
+	} else if int64(count)*8 > int64(r.Len()) {
+		return nil, fmt.Errorf("read tables: %w", io.ErrUnexpectedEOF)
+	}
+	s.Tables = make([]Table, count)
+	for i := range s.Tables {
+		t := &s.Tables[i]
+		if t.Index, err = readUint32(r); err != nil {
+			return nil, fmt.Errorf("read table[%d] index: %w", i, err)
+		}
+		size, err := readUint32(r)
+		if err != nil {
+			return nil, fmt.Errorf("read table[%d] size: %w", i, err)
+		} else if int64(size)*4 > int64(r.Len()) {
+			return nil, fmt.Errorf("read table[%d] elements: %w", i, io.ErrUnexpectedEOF)
+		}
+		t.Elements = make([]uint32, size)
+		for j := range t.Elements {
+			t.Elements[j], _ = readUint32(r)
+		}
+	}
+
+	if r.Len() != 0 {
+		return nil, fmt.Errorf("%d trailing bytes", r.Len())
+	}
+	return s, nil
+}
+
+func readUint32(r *bytes.Reader) (uint32, error) {
+	var b [4]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint32(b[:]), nil
+}
+
+func readUint64(r *bytes.Reader) (uint64, error) {
+	var b [8]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint64(b[:]), nil
+}
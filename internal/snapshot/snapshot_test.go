@@ -0,0 +1,120 @@
+package snapshot
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestSnapshot_EncodeDecode(t *testing.T) {
+	tests := []struct {
+		name  string
+		input *Snapshot
+	}{
+		{
+			name:  "empty",
+			input: &Snapshot{Globals: []Global{}, Tables: []Table{}},
+		},
+		{
+			name:  "empty memory",
+			input: &Snapshot{Memory: []byte{}, Globals: []Global{}, Tables: []Table{}},
+		},
+		{
+			name: "all",
+			input: &Snapshot{
+				ModuleID: [32]byte{1, 2, 3},
+				Memory:   []byte{1, 2, 3, 4},
+				Globals:  []Global{{Index: 1, Val: 2, ValHi: 3}, {Index: 4, Val: 0xffffffffffffffff}},
+				Tables:   []Table{{Index: 0, Elements: []uint32{0, 1, 2}}, {Index: 2, Elements: []uint32{}}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := Decode(tc.input.Encode())
+			require.NoError(t, err)
+			require.Equal(t, tc.input, actual)
+		})
+	}
+}
+
+func TestDecode_Errors(t *testing.T) {
+	valid := (&Snapshot{
+		Memory:  []byte{1, 2, 3, 4},
+		Globals: []Global{{Index: 1, Val: 2}},
+		Tables:  []Table{{Index: 0, Elements: []uint32{1}}},
+	}).Encode()
+
+	tests := []struct {
+		name        string
+		input       []byte
+		expectedErr string
+	}{
+		{
+			name:        "empty",
+			input:       nil,
+			expectedErr: "invalid snapshot header",
+		},
+		{
+			name:        "wrong version",
+			input:       []byte{'W', 'S', 'N', 'P', 1},
+			expectedErr: "invalid snapshot header",
+		},
+		{
+			name:        "missing module ID",
+			input:       valid[:10],
+			expectedErr: "read module ID: unexpected EOF",
+		},
+		{
+			name:        "truncated memory",
+			input:       valid[:len(magic)+32+1+8+2],
+			expectedErr: "read memory: unexpected EOF",
+		},
+		{
+			name:        "truncated memory size",
+			input:       valid[:len(magic)+32+1+4],
+			expectedErr: "read memory size: unexpected EOF",
+		},
+		{
+			name: "memory size beyond 32 bits",
+			// The low 32 bits are the length of the memory, which must not wrap.
+			input:       withMemorySize(valid, 1<<32|4),
+			expectedErr: "read memory: unexpected EOF",
+		},
+		{
+			name:        "truncated table",
+			input:       valid[:len(valid)-1],
+			expectedErr: "read table[0] elements: unexpected EOF",
+		},
+		{
+			name:        "trailing bytes",
+			input:       append(append([]byte{}, valid...), 0),
+			expectedErr: "1 trailing bytes",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Decode(tc.input)
+			require.EqualError(t, err, tc.expectedErr)
+		})
+	}
+}
+
+func TestSnapshot_Encode_memorySize(t *testing.T) {
+	b := (&Snapshot{Memory: []byte{1, 2, 3}}).Encode()
+	offset := len(magic) + 32 + 1
+	require.Equal(t, uint64(3), binary.LittleEndian.Uint64(b[offset:]))
+	require.Equal(t, []byte{1, 2, 3}, b[offset+8:offset+11])
+}
+
+// withMemorySize returns a copy of the encoded snapshot with the given memory size.
+func withMemorySize(encoded []byte, size uint64) []byte {
+	ret := append([]byte{}, encoded...)
+	binary.LittleEndian.PutUint64(ret[len(magic)+32+1:], size)
+	return ret
+}
@@ -277,6 +277,12 @@ func (c *FSContext) RootFS() sys.FS {
 	}
 }
 
+// OpenFileCount returns the number of files in the table, including the
+// standard I/O and pre-opened directories.
+func (c *FSContext) OpenFileCount() int {
+	return c.openedFiles.Len()
+}
+
 // LookupFile returns a file if it is in the table.
 func (c *FSContext) LookupFile(fd int32) (*FileEntry, bool) {
 	return c.openedFiles.Lookup(fd)
@@ -5,8 +5,14 @@ import (
 )
 
 func encodeConstantExpression(expr wasm.ConstantExpression) (ret []byte) {
-	ret = append(ret, expr.Opcode)
-	ret = append(ret, expr.Data...)
+	if expr.IsExtended() {
+		// The preceding instructions are held in Data.
+		ret = append(ret, expr.Data...)
+		ret = append(ret, expr.Opcode)
+	} else {
+		ret = append(ret, expr.Opcode)
+		ret = append(ret, expr.Data...)
+	}
 	ret = append(ret, wasm.OpcodeEnd)
 	return
 }
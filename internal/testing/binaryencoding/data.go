@@ -6,8 +6,13 @@ import (
 )
 
 func encodeDataSegment(d *wasm.DataSegment) (ret []byte) {
-	// Currently multiple memories are not supported.
-	ret = append(ret, leb128.EncodeInt32(0)...)
+	if d.MemoryIndex == 0 {
+		ret = append(ret, leb128.EncodeInt32(0)...)
+	} else {
+		// Active data segment with an explicit memory index.
+		ret = append(ret, leb128.EncodeInt32(2)...)
+		ret = append(ret, leb128.EncodeUint32(d.MemoryIndex)...)
+	}
 	ret = append(ret, encodeConstantExpression(d.OffsetExpression)...)
 	ret = append(ret, leb128.EncodeUint32(uint32(len(d.Init)))...)
 	ret = append(ret, d.Init...)
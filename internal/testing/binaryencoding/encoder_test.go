@@ -108,7 +108,7 @@ func TestModule_Encode(t *testing.T) {
 			name: "table and memory section",
 			input: &wasm.Module{
 				TableSection:  []wasm.Table{{Min: 3, Type: wasm.RefTypeFuncref}},
-				MemorySection: &wasm.Memory{Min: 1, Max: 1, IsMaxEncoded: true},
+				MemorySection: []wasm.Memory{{Min: 1, Max: 1, IsMaxEncoded: true}},
 			},
 			expected: append(append(Magic, version...),
 				wasm.SectionIDTable, 0x04, // 4 bytes in this section
@@ -119,6 +119,22 @@ func TestModule_Encode(t *testing.T) {
 				0x01, 0x01, 0x01, // min and max = 1
 			),
 		},
+		{
+			name: "memory and tag section",
+			input: &wasm.Module{
+				MemorySection: []wasm.Memory{{Min: 1, Max: 1, IsMaxEncoded: true}},
+				TagSection:    []wasm.Tag{{Type: 0}, {Type: 1}},
+			},
+			expected: append(append(Magic, version...),
+				wasm.SectionIDMemory, 0x04, // 4 bytes in this section
+				0x01,             // 1 memory
+				0x01, 0x01, 0x01, // min and max = 1
+				wasm.SectionIDTag, 0x05, // 5 bytes in this section
+				0x02,       // 2 tags
+				0x00, 0x00, // exception attribute and type index 0
+				0x00, 0x01, // exception attribute and type index 1
+			),
+		},
 		{
 			name: "exported func with instructions",
 			input: &wasm.Module{
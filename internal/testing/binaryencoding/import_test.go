@@ -148,6 +148,21 @@ func TestEncodeImport(t *testing.T) {
 				0x0, 0x1, // Limit without max.
 			},
 		},
+		{
+			name: "tag",
+			input: &wasm.Import{
+				Type:    wasm.ExternTypeTag,
+				Module:  "my",
+				Name:    "tag",
+				DescTag: 2,
+			},
+			expected: []byte{
+				0x02, 'm', 'y',
+				0x03, 't', 'a', 'g',
+				wasm.ExternTypeTag,
+				0x00, 0x02, // exception attribute and type index
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -12,5 +12,13 @@ func EncodeMemory(i *wasm.Memory) []byte {
 	if !i.IsMaxEncoded {
 		maxPtr = nil
 	}
-	return EncodeLimitsType(i.Min, maxPtr)
+	b := EncodeLimitsType(i.Min, maxPtr)
+	if i.IsShared {
+		b[0] |= 0x02
+	}
+	// Limits of a 64-bit memory are u64, but the LEB128 encoding of a uint32 is the same as that of a uint64.
+	if i.Is64 {
+		b[0] |= 0x04
+	}
+	return b
 }
@@ -79,16 +79,36 @@ func encodeTableSection(tables []wasm.Table) []byte {
 	return encodeSection(wasm.SectionIDTable, contents)
 }
 
-// encodeMemorySection encodes a wasm.SectionIDMemory for the module-defined function in WebAssembly 1.0
+// encodeMemorySection encodes a wasm.SectionIDMemory for the module-defined memories in WebAssembly 1.0
 // (20191205) Binary Format.
 //
 // See EncodeMemory
 // See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#memory-section%E2%91%A0
-func encodeMemorySection(memory *wasm.Memory) []byte {
-	contents := append([]byte{1}, EncodeMemory(memory)...)
+func encodeMemorySection(memories []wasm.Memory) []byte {
+	contents := leb128.EncodeUint32(uint32(len(memories)))
+	for i := range memories {
+		contents = append(contents, EncodeMemory(&memories[i])...)
+	}
 	return encodeSection(wasm.SectionIDMemory, contents)
 }
 
+// encodeTagSection encodes a wasm.SectionIDTag for the module-defined tags, introduced in
+// api.CoreFeatureExceptionHandling.
+//
+// See https://github.com/WebAssembly/exception-handling/blob/main/proposals/exception-handling/legacy/Exceptions.md#tag-section
+func encodeTagSection(tags []wasm.Tag) []byte {
+	contents := leb128.EncodeUint32(uint32(len(tags)))
+	for i := range tags {
+		contents = append(contents, encodeTagType(tags[i].Type)...)
+	}
+	return encodeSection(wasm.SectionIDTag, contents)
+}
+
+// encodeTagType returns the tag type of the type index, whose attribute is always exception.
+func encodeTagType(typeIndex wasm.Index) []byte {
+	return append([]byte{0x00}, leb128.EncodeUint32(typeIndex)...)
+}
+
 // encodeGlobalSection encodes a wasm.SectionIDGlobal for the given globals in WebAssembly 1.0 (20191205) Binary
 // Format.
 //
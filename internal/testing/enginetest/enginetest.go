@@ -71,17 +71,18 @@ func RunTestEngineMemoryGrowInRecursiveCall(t *testing.T, et EngineTester) {
 				}},
 			},
 		},
+		nil,
 		enabledFeatures,
 	)
 	require.NoError(t, err)
 
-	err = s.Engine.CompileModule(testCtx, hm, nil, false)
+	err = s.Engine.CompileModule(testCtx, hm, nil, false, false, false)
 	require.NoError(t, err)
 
 	typeIDs, err := s.GetFunctionTypeIDs(hm.TypeSection)
 	require.NoError(t, err)
 
-	_, err = s.Instantiate(testCtx, hm, hostModuleName, nil, typeIDs)
+	_, err = s.Instantiate(testCtx, hm, hostModuleName, nil, typeIDs, nil)
 	require.NoError(t, err)
 
 	m := &wasm.Module{
@@ -102,22 +103,22 @@ func RunTestEngineMemoryGrowInRecursiveCall(t *testing.T, et EngineTester) {
 			},
 			{
 				// Grows memory by 1 page.
-				Body: []byte{wasm.OpcodeI32Const, 1, wasm.OpcodeMemoryGrow, wasm.OpcodeDrop, wasm.OpcodeEnd},
+				Body: []byte{wasm.OpcodeI32Const, 1, wasm.OpcodeMemoryGrow, 0, wasm.OpcodeDrop, wasm.OpcodeEnd},
 			},
 		},
-		MemorySection:   &wasm.Memory{Max: 1000},
+		MemorySection:   []wasm.Memory{{Max: 1000}},
 		ImportSection:   []wasm.Import{{Module: hostModuleName, Name: hostFnName, DescFunc: 0}},
 		ImportPerModule: map[string][]*wasm.Import{hostModuleName: {{Module: hostModuleName, Name: hostFnName, DescFunc: 0}}},
 	}
 	m.BuildMemoryDefinitions()
 
-	err = s.Engine.CompileModule(testCtx, m, nil, false)
+	err = s.Engine.CompileModule(testCtx, m, nil, false, false, false)
 	require.NoError(t, err)
 
 	typeIDs, err = s.GetFunctionTypeIDs(m.TypeSection)
 	require.NoError(t, err)
 
-	inst, err := s.Instantiate(testCtx, m, t.Name(), nil, typeIDs)
+	inst, err := s.Instantiate(testCtx, m, t.Name(), nil, typeIDs, nil)
 	require.NoError(t, err)
 
 	growFn = inst.Engine.NewFunction(2)
@@ -155,7 +156,7 @@ func RunTestModuleEngineCall(t *testing.T, et EngineTester) {
 	}
 
 	listeners := buildFunctionListeners(et.ListenerFactory(), m)
-	err := e.CompileModule(testCtx, m, listeners, false)
+	err := e.CompileModule(testCtx, m, listeners, false, false, false)
 	require.NoError(t, err)
 
 	// To use the function, we first need to add it to a module.
@@ -208,7 +209,7 @@ func RunTestModuleEngineCallWithStack(t *testing.T, et EngineTester) {
 	}
 
 	listeners := buildFunctionListeners(et.ListenerFactory(), m)
-	err := e.CompileModule(testCtx, m, listeners, false)
+	err := e.CompileModule(testCtx, m, listeners, false, false, false)
 	require.NoError(t, err)
 
 	// To use the function, we first need to add it to a module.
@@ -248,7 +249,7 @@ func RunTestModuleEngineLookupFunction(t *testing.T, et EngineTester) {
 		},
 	}
 
-	err := e.CompileModule(testCtx, mod, nil, false)
+	err := e.CompileModule(testCtx, mod, nil, false, false, false)
 	require.NoError(t, err)
 	m := &wasm.ModuleInstance{
 		TypeIDs: []wasm.FunctionTypeID{0, 1},
@@ -309,6 +310,75 @@ func RunTestModuleEngineLookupFunction(t *testing.T, et EngineTester) {
 		require.NoError(t, err)
 		require.Equal(t, wasm.Index(2), f2.Definition().Index())
 	})
+
+	t.Run("reference", func(t *testing.T) {
+		f, err := me.LookupFunctionReference(me.FunctionInstanceReference(1), m.TypeIDs[0])
+		require.NoError(t, err)
+		require.Equal(t, wasm.Index(1), f.Definition().Index())
+
+		_, err = me.LookupFunctionReference(0, m.TypeIDs[0])
+		require.Equal(t, wasmruntime.ErrRuntimeInvalidTableAccess, err)
+
+		_, err = me.LookupFunctionReference(me.FunctionInstanceReference(1), m.TypeIDs[1])
+		require.Equal(t, wasmruntime.ErrRuntimeIndirectCallTypeMismatch, err)
+	})
+}
+
+// RunTestModuleEngineMaxCallStackDepth ensures that every engine fails a call with a stack overflow at the same
+// depth, given wasm.Store MaxCallStackDepth.
+func RunTestModuleEngineMaxCallStackDepth(t *testing.T, et EngineTester) {
+	e := et.NewEngine(api.CoreFeaturesV1)
+	defer e.Close()
+	s := wasm.NewStore(api.CoreFeaturesV1, e)
+	s.MaxCallStackDepth = 10
+
+	m := &wasm.Module{
+		TypeSection:     []wasm.FunctionType{{Params: []wasm.ValueType{i32}, ParamNumInUint64: 1}, {}},
+		FunctionSection: []wasm.Index{0, 1},
+		CodeSection: []wasm.Code{
+			{Body: []byte{
+				// Calls itself with the param minus one until it is zero, then calls the empty function. Therefore,
+				// the call with n executes n+2 functions.
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeIf, 0x40,
+				wasm.OpcodeLocalGet, 0, wasm.OpcodeI32Const, 1, wasm.OpcodeI32Sub, wasm.OpcodeCall, 0,
+				wasm.OpcodeElse,
+				wasm.OpcodeCall, 1,
+				wasm.OpcodeEnd,
+				wasm.OpcodeEnd,
+			}},
+			{Body: []byte{wasm.OpcodeEnd}},
+		},
+		ExportSection: []wasm.Export{{Name: "recurse", Index: 0, Type: wasm.ExternTypeFunc}},
+	}
+	m.Exports = exportMap(m)
+
+	err := e.CompileModule(testCtx, m, nil, false, false, false)
+	require.NoError(t, err)
+
+	typeIDs, err := s.GetFunctionTypeIDs(m.TypeSection)
+	require.NoError(t, err)
+
+	inst, err := s.Instantiate(testCtx, m, t.Name(), nil, typeIDs, nil)
+	require.NoError(t, err)
+	recurse := inst.ExportedFunction("recurse")
+
+	for _, tc := range []struct {
+		n      uint64
+		expErr error
+	}{
+		{n: 8},
+		{n: 9, expErr: wasmruntime.ErrRuntimeStackOverflow},
+		// The depth is reset after a stack overflow.
+		{n: 8},
+	} {
+		_, err := recurse.Call(testCtx, tc.n)
+		if tc.expErr != nil {
+			require.ErrorIs(t, err, tc.expErr)
+		} else {
+			require.NoError(t, err)
+		}
+	}
 }
 
 func runTestModuleEngineCallHostFnMem(t *testing.T, et EngineTester, readMem *wasm.Code) {
@@ -645,7 +715,7 @@ func RunTestModuleEngineBeforeListenerStackIterator(t *testing.T, et EngineTeste
 	}
 
 	listeners := buildFunctionListeners(fnListener, m)
-	err := e.CompileModule(testCtx, m, listeners, false)
+	err := e.CompileModule(testCtx, m, listeners, false, false, false)
 	require.NoError(t, err)
 
 	module := &wasm.ModuleInstance{
@@ -763,7 +833,7 @@ func RunTestModuleEngineBeforeListenerGlobals(t *testing.T, et EngineTester) {
 	}
 
 	listeners := buildFunctionListeners(fnListener, m)
-	err := e.CompileModule(testCtx, m, listeners, false)
+	err := e.CompileModule(testCtx, m, listeners, false, false, false)
 	require.NoError(t, err)
 
 	module := &wasm.ModuleInstance{
@@ -940,7 +1010,7 @@ func RunTestModuleEngineStackIteratorOffset(t *testing.T, et EngineTester) {
 	m.CodeSection[1].BodyOffsetInCodeSection = f2offset
 
 	listeners := buildFunctionListeners(fnListener, m)
-	err = e.CompileModule(testCtx, m, listeners, false)
+	err = e.CompileModule(testCtx, m, listeners, false, false, false)
 	require.NoError(t, err)
 
 	module := &wasm.ModuleInstance{
@@ -1020,7 +1090,7 @@ func RunTestModuleEngineMemory(t *testing.T, et EngineTester) {
 	m := &wasm.Module{
 		TypeSection:     []wasm.FunctionType{{Params: []api.ValueType{api.ValueTypeI32}, ParamNumInUint64: 1}, {}},
 		FunctionSection: []wasm.Index{0, 1},
-		MemorySection:   &wasm.Memory{Min: 1, Cap: 1, Max: 2},
+		MemorySection:   []wasm.Memory{{Min: 1, Cap: 1, Max: 2}},
 		DataSection: []wasm.DataSegment{
 			{
 				Passive: true,
@@ -1050,13 +1120,16 @@ func RunTestModuleEngineMemory(t *testing.T, et EngineTester) {
 	}
 	listeners := buildFunctionListeners(et.ListenerFactory(), m)
 
-	err := e.CompileModule(testCtx, m, listeners, false)
+	err := e.CompileModule(testCtx, m, listeners, false, false, false)
+	require.NoError(t, err)
+
+	mem, err := wasm.NewMemoryInstance(&m.MemorySection[0], nil)
 	require.NoError(t, err)
 
 	// Assign memory to the module instance
 	module := &wasm.ModuleInstance{
 		ModuleName:     t.Name(),
-		MemoryInstance: wasm.NewMemoryInstance(m.MemorySection),
+		MemoryInstance: mem,
 		DataInstances:  []wasm.DataInstance{m.DataSection[0].Init},
 		TypeIDs:        []wasm.FunctionTypeID{0, 1},
 	}
@@ -1179,7 +1252,7 @@ func setupCallTests(t *testing.T, e wasm.Engine, divBy *wasm.Code, fnlf experime
 		ID: wasm.ModuleID{0},
 	}
 	lns := buildFunctionListeners(fnlf, hostModule)
-	err := e.CompileModule(testCtx, hostModule, lns, false)
+	err := e.CompileModule(testCtx, hostModule, lns, false, false, false)
 	require.NoError(t, err)
 	host := &wasm.ModuleInstance{ModuleName: hostModule.NameSection.ModuleName, TypeIDs: []wasm.FunctionTypeID{0}}
 	host.Exports = exportMap(hostModule)
@@ -1212,7 +1285,7 @@ func setupCallTests(t *testing.T, e wasm.Engine, divBy *wasm.Code, fnlf experime
 		ID: wasm.ModuleID{1},
 	}
 	lns = buildFunctionListeners(fnlf, importedModule)
-	err = e.CompileModule(testCtx, importedModule, lns, false)
+	err = e.CompileModule(testCtx, importedModule, lns, false, false, false)
 	require.NoError(t, err)
 
 	imported := &wasm.ModuleInstance{
@@ -1245,7 +1318,7 @@ func setupCallTests(t *testing.T, e wasm.Engine, divBy *wasm.Code, fnlf experime
 		ID: wasm.ModuleID{2},
 	}
 	lns = buildFunctionListeners(fnlf, importingModule)
-	err = e.CompileModule(testCtx, importingModule, lns, false)
+	err = e.CompileModule(testCtx, importingModule, lns, false, false, false)
 	require.NoError(t, err)
 
 	// Add the exported function.
@@ -1276,7 +1349,7 @@ func setupCallMemTests(t *testing.T, e wasm.Engine, readMem *wasm.Code) *wasm.Mo
 		},
 		ID: wasm.ModuleID{0},
 	}
-	err := e.CompileModule(testCtx, hostModule, nil, false)
+	err := e.CompileModule(testCtx, hostModule, nil, false, false, false)
 	require.NoError(t, err)
 	host := &wasm.ModuleInstance{ModuleName: hostModule.NameSection.ModuleName, TypeIDs: []wasm.FunctionTypeID{0}}
 	host.Exports = exportMap(hostModule)
@@ -1306,10 +1379,10 @@ func setupCallMemTests(t *testing.T, e wasm.Engine, readMem *wasm.Code) *wasm.Mo
 			},
 		},
 		// Indicates that this module has a memory so that compilers are able to assembe memory-related initialization.
-		MemorySection: &wasm.Memory{Min: 1},
+		MemorySection: []wasm.Memory{{Min: 1}},
 		ID:            wasm.ModuleID{1},
 	}
-	err = e.CompileModule(testCtx, importingModule, nil, false)
+	err = e.CompileModule(testCtx, importingModule, nil, false, false, false)
 	require.NoError(t, err)
 
 	// Add the exported function.
@@ -6,11 +6,12 @@ import (
 	"io"
 	"math"
 
+	"github.com/tetratelabs/wazero/api"
 	"github.com/tetratelabs/wazero/internal/leb128"
 	"github.com/tetratelabs/wazero/internal/wasm"
 )
 
-func decodeCode(r *bytes.Reader, codeSectionStart uint64, ret *wasm.Code) (err error) {
+func decodeCode(r *bytes.Reader, codeSectionStart uint64, enabledFeatures api.CoreFeatures, ret *wasm.Code) (err error) {
 	ss, _, err := leb128.DecodeUint32(r)
 	if err != nil {
 		return fmt.Errorf("get the size of code: %w", err)
@@ -27,10 +28,10 @@ func decodeCode(r *bytes.Reader, codeSectionStart uint64, ret *wasm.Code) (err e
 	}
 
 	// Validate the locals.
-	bytesRead = 0
+	localsStart := r.Len()
 	var sum uint64
 	for i := uint32(0); i < ls; i++ {
-		num, n, err := leb128.DecodeUint32(r)
+		num, _, err := leb128.DecodeUint32(r)
 		if err != nil {
 			return fmt.Errorf("read n of locals: %v", err)
 		} else if remaining < 0 {
@@ -39,17 +40,8 @@ func decodeCode(r *bytes.Reader, codeSectionStart uint64, ret *wasm.Code) (err e
 
 		sum += uint64(num)
 
-		b, err := r.ReadByte()
-		if err != nil {
-			return fmt.Errorf("read type of local: %v", err)
-		}
-
-		bytesRead += n + 1
-		switch vt := b; vt {
-		case wasm.ValueTypeI32, wasm.ValueTypeF32, wasm.ValueTypeI64, wasm.ValueTypeF64,
-			wasm.ValueTypeFuncref, wasm.ValueTypeExternref, wasm.ValueTypeV128:
-		default:
-			return fmt.Errorf("invalid local type: 0x%x", vt)
+		if _, err = decodeLocalType(r, enabledFeatures); err != nil {
+			return err
 		}
 	}
 
@@ -58,24 +50,27 @@ func decodeCode(r *bytes.Reader, codeSectionStart uint64, ret *wasm.Code) (err e
 	}
 
 	// Rewind the buffer.
-	_, err = r.Seek(-int64(bytesRead), io.SeekCurrent)
+	_, err = r.Seek(-int64(localsStart-r.Len()), io.SeekCurrent)
 	if err != nil {
 		return err
 	}
 
 	localTypes := make([]wasm.ValueType, 0, sum)
 	for i := uint32(0); i < ls; i++ {
-		num, bytesRead, err := leb128.DecodeUint32(r)
-		remaining -= int64(bytesRead) + 1 // +1 for the subsequent ReadByte
+		before := r.Len()
+		num, _, err := leb128.DecodeUint32(r)
 		if err != nil {
 			return fmt.Errorf("read n of locals: %v", err)
-		} else if remaining < 0 {
-			return io.EOF
 		}
 
-		b, err := r.ReadByte()
+		// Typed references can take more than one byte.
+		b, err := decodeLocalType(r, enabledFeatures)
 		if err != nil {
-			return fmt.Errorf("read type of local: %v", err)
+			return err
+		}
+		remaining -= int64(before - r.Len())
+		if remaining < 0 {
+			return io.EOF
 		}
 
 		for j := uint32(0); j < num; j++ {
@@ -98,3 +93,30 @@ func decodeCode(r *bytes.Reader, codeSectionStart uint64, ret *wasm.Code) (err e
 	ret.Body = body
 	return nil
 }
+
+// decodeLocalType decodes the value type of locals, including typed references in api.CoreFeatureFunctionReferences.
+func decodeLocalType(r *bytes.Reader, enabledFeatures api.CoreFeatures) (wasm.ValueType, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return 0, fmt.Errorf("read type of local: %v", err)
+	}
+
+	switch vt := b; vt {
+	case wasm.ValueTypeI32, wasm.ValueTypeF32, wasm.ValueTypeI64, wasm.ValueTypeF64,
+		wasm.ValueTypeFuncref, wasm.ValueTypeExternref, wasm.ValueTypeV128:
+		return vt, nil
+	case wasm.ValueTypeExnref:
+		if err = enabledFeatures.RequireEnabled(api.CoreFeatureExceptionHandling); err != nil {
+			return 0, fmt.Errorf("invalid local type: 0x%x as %v", vt, err)
+		}
+		return vt, nil
+	case wasm.RefTypePrefixNullable, wasm.RefTypePrefixNonNullable:
+		if err = enabledFeatures.RequireEnabled(api.CoreFeatureFunctionReferences); err != nil {
+			return 0, fmt.Errorf("invalid local type: 0x%x as %v", vt, err)
+		}
+		vt, _, err = wasm.DecodeHeapType(r)
+		return vt, err
+	default:
+		return 0, fmt.Errorf("invalid local type: 0x%x", vt)
+	}
+}
@@ -11,14 +11,61 @@ import (
 	"github.com/tetratelabs/wazero/internal/wasm"
 )
 
+// decodeConstantExpression decodes a constant expression into ret. When the expression consists of multiple
+// instructions, which is introduced in api.CoreFeatureExtendedConst, see wasm.ConstantExpression IsExtended for how
+// they are stored.
 func decodeConstantExpression(r *bytes.Reader, enabledFeatures api.CoreFeatures, ret *wasm.ConstantExpression) error {
+	begin := r.Size() - int64(r.Len())
+	opcode, offsetAtData, err := decodeConstantInstruction(r, enabledFeatures)
+	if err != nil {
+		return err
+	}
+
+	lastBegin := begin
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return fmt.Errorf("look for end opcode: %v", err)
+		}
+		if b == wasm.OpcodeEnd {
+			break
+		}
+		if !enabledFeatures.IsEnabled(api.CoreFeatureExtendedConst) {
+			return fmt.Errorf("constant expression has been not terminated")
+		}
+		_ = r.UnreadByte()
+		lastBegin = r.Size() - int64(r.Len())
+		if opcode, offsetAtData, err = decodeConstantInstruction(r, enabledFeatures); err != nil {
+			return err
+		}
+	}
+
+	end := r.Size() - int64(r.Len()) - 1 // Excludes the end opcode.
+	ret.Opcode = opcode
+	if lastBegin != begin {
+		// The preceding instructions are the operands of the last one.
+		if !ret.IsExtended() {
+			return fmt.Errorf("constant expression has been not terminated")
+		}
+		offsetAtData, end = begin, lastBegin
+	}
+
+	ret.Data = make([]byte, end-offsetAtData)
+	if _, err = r.ReadAt(ret.Data, offsetAtData); err != nil {
+		return fmt.Errorf("error re-buffering ConstantExpression.Data")
+	}
+	return nil
+}
+
+// decodeConstantInstruction decodes an instruction of a constant expression, and returns its opcode and the offset
+// of its immediate.
+func decodeConstantInstruction(r *bytes.Reader, enabledFeatures api.CoreFeatures) (wasm.Opcode, int64, error) {
 	b, err := r.ReadByte()
 	if err != nil {
-		return fmt.Errorf("read opcode: %v", err)
+		return 0, 0, fmt.Errorf("read opcode: %v", err)
 	}
 
-	remainingBeforeData := int64(r.Len())
-	offsetAtData := r.Size() - remainingBeforeData
+	offsetAtData := r.Size() - int64(r.Len())
 
 	opcode := b
 	switch opcode {
@@ -31,75 +78,64 @@ func decodeConstantExpression(r *bytes.Reader, enabledFeatures api.CoreFeatures,
 	case wasm.OpcodeF32Const:
 		buf := make([]byte, 4)
 		if _, err := io.ReadFull(r, buf); err != nil {
-			return fmt.Errorf("read f32 constant: %v", err)
+			return 0, 0, fmt.Errorf("read f32 constant: %v", err)
 		}
 		_, err = ieee754.DecodeFloat32(buf)
 	case wasm.OpcodeF64Const:
 		buf := make([]byte, 8)
 		if _, err := io.ReadFull(r, buf); err != nil {
-			return fmt.Errorf("read f64 constant: %v", err)
+			return 0, 0, fmt.Errorf("read f64 constant: %v", err)
 		}
 		_, err = ieee754.DecodeFloat64(buf)
 	case wasm.OpcodeGlobalGet:
 		_, _, err = leb128.DecodeUint32(r)
 	case wasm.OpcodeRefNull:
 		if err := enabledFeatures.RequireEnabled(api.CoreFeatureBulkMemoryOperations); err != nil {
-			return fmt.Errorf("ref.null is not supported as %w", err)
+			return 0, 0, fmt.Errorf("ref.null is not supported as %w", err)
 		}
 		reftype, err := r.ReadByte()
 		if err != nil {
-			return fmt.Errorf("read reference type for ref.null: %w", err)
+			return 0, 0, fmt.Errorf("read reference type for ref.null: %w", err)
 		} else if reftype != wasm.RefTypeFuncref && reftype != wasm.RefTypeExternref {
-			return fmt.Errorf("invalid type for ref.null: 0x%x", reftype)
+			return 0, 0, fmt.Errorf("invalid type for ref.null: 0x%x", reftype)
 		}
 	case wasm.OpcodeRefFunc:
 		if err := enabledFeatures.RequireEnabled(api.CoreFeatureBulkMemoryOperations); err != nil {
-			return fmt.Errorf("ref.func is not supported as %w", err)
+			return 0, 0, fmt.Errorf("ref.func is not supported as %w", err)
 		}
 		// Parsing index.
 		_, _, err = leb128.DecodeUint32(r)
 	case wasm.OpcodeVecPrefix:
 		if err := enabledFeatures.RequireEnabled(api.CoreFeatureSIMD); err != nil {
-			return fmt.Errorf("vector instructions are not supported as %w", err)
+			return 0, 0, fmt.Errorf("vector instructions are not supported as %w", err)
 		}
 		opcode, err = r.ReadByte()
 		if err != nil {
-			return fmt.Errorf("read vector instruction opcode suffix: %w", err)
+			return 0, 0, fmt.Errorf("read vector instruction opcode suffix: %w", err)
 		}
 
 		if opcode != wasm.OpcodeVecV128Const {
-			return fmt.Errorf("invalid vector opcode for const expression: %#x", opcode)
+			return 0, 0, fmt.Errorf("invalid vector opcode for const expression: %#x", opcode)
 		}
 
-		remainingBeforeData = int64(r.Len())
-		offsetAtData = r.Size() - remainingBeforeData
+		offsetAtData = r.Size() - int64(r.Len())
 
 		n, err := r.Read(make([]byte, 16))
 		if err != nil {
-			return fmt.Errorf("read vector const instruction immediates: %w", err)
+			return 0, 0, fmt.Errorf("read vector const instruction immediates: %w", err)
 		} else if n != 16 {
-			return fmt.Errorf("read vector const instruction immediates: needs 16 bytes but was %d bytes", n)
+			return 0, 0, fmt.Errorf("read vector const instruction immediates: needs 16 bytes but was %d bytes", n)
+		}
+	case wasm.OpcodeI32Add, wasm.OpcodeI32Sub, wasm.OpcodeI32Mul, wasm.OpcodeI64Add, wasm.OpcodeI64Sub, wasm.OpcodeI64Mul:
+		if err := enabledFeatures.RequireEnabled(api.CoreFeatureExtendedConst); err != nil {
+			return 0, 0, fmt.Errorf("%s is not supported as %w", wasm.InstructionName(opcode), err)
 		}
 	default:
-		return fmt.Errorf("%v for const expression opt code: %#x", ErrInvalidByte, b)
+		return 0, 0, fmt.Errorf("%v for const expression opt code: %#x", ErrInvalidByte, b)
 	}
 
 	if err != nil {
-		return fmt.Errorf("read value: %v", err)
-	}
-
-	if b, err = r.ReadByte(); err != nil {
-		return fmt.Errorf("look for end opcode: %v", err)
+		return 0, 0, fmt.Errorf("read value: %v", err)
 	}
-
-	if b != wasm.OpcodeEnd {
-		return fmt.Errorf("constant expression has been not terminated")
-	}
-
-	ret.Data = make([]byte, remainingBeforeData-int64(r.Len())-1)
-	if _, err = r.ReadAt(ret.Data, offsetAtData); err != nil {
-		return fmt.Errorf("error re-buffering ConstantExpression.Data")
-	}
-	ret.Opcode = opcode
-	return nil
+	return opcode, offsetAtData, nil
 }
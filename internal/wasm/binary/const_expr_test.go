@@ -79,6 +79,32 @@ func TestDecodeConstantExpression(t *testing.T) {
 				},
 			},
 		},
+		{
+			in: []byte{
+				wasm.OpcodeGlobalGet, 0,
+				wasm.OpcodeI32Const, 16,
+				wasm.OpcodeI32Add,
+				wasm.OpcodeEnd,
+			},
+			exp: wasm.ConstantExpression{
+				Opcode: wasm.OpcodeI32Add,
+				Data:   []byte{wasm.OpcodeGlobalGet, 0, wasm.OpcodeI32Const, 16},
+			},
+		},
+		{
+			in: []byte{
+				wasm.OpcodeI64Const, 3,
+				wasm.OpcodeI64Const, 5,
+				wasm.OpcodeI64Mul,
+				wasm.OpcodeI64Const, 1,
+				wasm.OpcodeI64Sub,
+				wasm.OpcodeEnd,
+			},
+			exp: wasm.ConstantExpression{
+				Opcode: wasm.OpcodeI64Sub,
+				Data:   []byte{wasm.OpcodeI64Const, 3, wasm.OpcodeI64Const, 5, wasm.OpcodeI64Mul, wasm.OpcodeI64Const, 1},
+			},
+		},
 	}
 
 	for i, tt := range tests {
@@ -86,7 +112,7 @@ func TestDecodeConstantExpression(t *testing.T) {
 		t.Run(strconv.Itoa(i), func(t *testing.T) {
 			var actual wasm.ConstantExpression
 			err := decodeConstantExpression(bytes.NewReader(tc.in),
-				api.CoreFeatureBulkMemoryOperations|api.CoreFeatureSIMD, &actual)
+				api.CoreFeatureBulkMemoryOperations|api.CoreFeatureSIMD|api.CoreFeatureExtendedConst, &actual)
 			require.NoError(t, err)
 			require.Equal(t, tc.exp, actual)
 		})
@@ -178,6 +204,43 @@ func TestDecodeConstantExpression_errors(t *testing.T) {
 			expectedErr: "read vector const instruction immediates: needs 16 bytes but was 8 bytes",
 			features:    api.CoreFeatureSIMD,
 		},
+		{
+			in: []byte{
+				wasm.OpcodeI32Const, 1,
+				wasm.OpcodeI32Const, 2,
+				wasm.OpcodeI32Add,
+				wasm.OpcodeEnd,
+			},
+			expectedErr: "constant expression has been not terminated",
+			features:    api.CoreFeaturesV2,
+		},
+		{
+			in: []byte{
+				wasm.OpcodeI32Add,
+				wasm.OpcodeEnd,
+			},
+			expectedErr: "i32.add is not supported as feature \"extended-const\" is disabled",
+			features:    api.CoreFeaturesV2,
+		},
+		{
+			in: []byte{
+				wasm.OpcodeI32Const, 1,
+				wasm.OpcodeI32Const, 2,
+				wasm.OpcodeEnd,
+			},
+			expectedErr: "constant expression has been not terminated",
+			features:    api.CoreFeatureExtendedConst,
+		},
+		{
+			in: []byte{
+				wasm.OpcodeI32Const, 1,
+				wasm.OpcodeI32Const, 2,
+				wasm.OpcodeI32DivS,
+				wasm.OpcodeEnd,
+			},
+			expectedErr: "invalid byte for const expression opt code: 0x6d",
+			features:    api.CoreFeatureExtendedConst,
+		},
 	}
 
 	for _, tt := range tests {
@@ -97,6 +97,25 @@ func Test_decodeDataSegment(t *testing.T) {
 			expErr:   "memory index must be zero but was 1",
 			features: api.CoreFeatureBulkMemoryOperations,
 		},
+		{
+			in: []byte{
+				0x2,
+				0x1, // Memory index.
+				// Const expression.
+				wasm.OpcodeI32Const, 0x1, wasm.OpcodeEnd,
+				// Two initial data.
+				0x2, 0xf, 0xf,
+			},
+			exp: wasm.DataSegment{
+				OffsetExpression: wasm.ConstantExpression{
+					Opcode: wasm.OpcodeI32Const,
+					Data:   []byte{0x1},
+				},
+				Init:        []byte{0xf, 0xf},
+				MemoryIndex: 1,
+			},
+			features: api.CoreFeatureBulkMemoryOperations | api.CoreFeatureMultiMemory,
+		},
 		{
 			in: []byte{
 				0x2,
@@ -2,6 +2,7 @@ package binary
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 
 	"github.com/tetratelabs/wazero/api"
@@ -12,7 +13,7 @@ import (
 //
 // See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#binary-global
 func decodeGlobal(r *bytes.Reader, enabledFeatures api.CoreFeatures, ret *wasm.Global) (err error) {
-	ret.Type, err = decodeGlobalType(r)
+	ret.Type, err = decodeGlobalType(r, enabledFeatures)
 	if err != nil {
 		return err
 	}
@@ -24,14 +25,17 @@ func decodeGlobal(r *bytes.Reader, enabledFeatures api.CoreFeatures, ret *wasm.G
 // decodeGlobalType returns the wasm.GlobalType decoded with the WebAssembly 1.0 (20191205) Binary Format.
 //
 // See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#binary-globaltype
-func decodeGlobalType(r *bytes.Reader) (wasm.GlobalType, error) {
-	vt, err := decodeValueTypes(r, 1)
+func decodeGlobalType(r *bytes.Reader, enabledFeatures api.CoreFeatures) (wasm.GlobalType, error) {
+	vt, err := decodeValueType(r, enabledFeatures)
 	if err != nil {
 		return wasm.GlobalType{}, fmt.Errorf("read value type: %w", err)
+	} else if vt == wasm.ValueTypeExnref {
+		// exnref values are only valid during the call which caught the exception. See wasm.ValueTypeExnref.
+		return wasm.GlobalType{}, errors.New("global of exnref type is not supported")
 	}
 
 	ret := wasm.GlobalType{
-		ValType: vt[0],
+		ValType: vt,
 	}
 
 	b, err := r.ReadByte()
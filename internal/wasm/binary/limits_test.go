@@ -56,10 +56,37 @@ func TestLimitsType(t *testing.T) {
 		})
 
 		t.Run(fmt.Sprintf("decode - %s", tc.name), func(t *testing.T) {
-			min, max, err := decodeLimitsType(bytes.NewReader(b))
+			min, max, shared, is64, err := decodeLimitsType(bytes.NewReader(b))
 			require.NoError(t, err)
+			require.False(t, shared)
+			require.False(t, is64)
 			require.Equal(t, min, tc.min)
 			require.Equal(t, max, tc.max)
 		})
 	}
 }
+
+func TestLimitsType_64(t *testing.T) {
+	t.Run("min", func(t *testing.T) {
+		min, max, shared, is64, err := decodeLimitsType(bytes.NewReader([]byte{0x4, 0xff, 0xff, 0xff, 0xff, 0xf}))
+		require.NoError(t, err)
+		require.False(t, shared)
+		require.True(t, is64)
+		require.Equal(t, uint32(math.MaxUint32), min)
+		require.Nil(t, max)
+	})
+
+	t.Run("shared min max", func(t *testing.T) {
+		min, max, shared, is64, err := decodeLimitsType(bytes.NewReader([]byte{0x7, 1, 2}))
+		require.NoError(t, err)
+		require.True(t, shared)
+		require.True(t, is64)
+		require.Equal(t, uint32(1), min)
+		require.Equal(t, uint32(2), *max)
+	})
+
+	t.Run("max over uint32", func(t *testing.T) {
+		_, _, _, _, err := decodeLimitsType(bytes.NewReader([]byte{0x5, 0, 0x80, 0x80, 0x80, 0x80, 0x10}))
+		require.EqualError(t, err, "read max of limit: 4294967296 pages over limit of 4294967295 pages")
+	})
+}
@@ -2,7 +2,10 @@ package binary
 
 import (
 	"bytes"
+	"errors"
+	"fmt"
 
+	"github.com/tetratelabs/wazero/api"
 	"github.com/tetratelabs/wazero/internal/wasm"
 )
 
@@ -11,16 +14,32 @@ import (
 // See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#binary-memory
 func decodeMemory(
 	r *bytes.Reader,
-	memorySizer func(minPages uint32, maxPages *uint32) (min, capacity, max uint32),
+	enabledFeatures api.CoreFeatures,
+	memorySizer memorySizer,
 	memoryLimitPages uint32,
 ) (*wasm.Memory, error) {
-	min, maxP, err := decodeLimitsType(r)
+	min, maxP, shared, is64, err := decodeLimitsType(r)
 	if err != nil {
 		return nil, err
 	}
 
-	min, capacity, max := memorySizer(min, maxP)
-	mem := &wasm.Memory{Min: min, Cap: capacity, Max: max, IsMaxEncoded: maxP != nil}
+	if is64 {
+		if err = enabledFeatures.RequireEnabled(api.CoreFeatureMemory64); err != nil {
+			return nil, fmt.Errorf("64-bit memory invalid as %w", err)
+		}
+	}
+
+	if shared {
+		if err = enabledFeatures.RequireEnabled(api.CoreFeatureThreads); err != nil {
+			return nil, fmt.Errorf("shared memory invalid as %w", err)
+		}
+		if maxP == nil {
+			return nil, errors.New("shared memory requires a maximum size")
+		}
+	}
+
+	min, capacity, max := memorySizer(min, maxP, is64)
+	mem := &wasm.Memory{Min: min, Cap: capacity, Max: max, IsMaxEncoded: maxP != nil, IsShared: shared, Is64: is64}
 
 	return mem, mem.Validate(memoryLimitPages)
 }
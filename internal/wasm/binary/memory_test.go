@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/tetratelabs/wazero/api"
 	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
 	"github.com/tetratelabs/wazero/internal/testing/require"
 	"github.com/tetratelabs/wazero/internal/wasm"
@@ -21,6 +22,7 @@ func Test_newMemorySizer(t *testing.T) {
 		limit                                      uint32
 		min                                        uint32
 		max                                        *uint32
+		is64                                       bool
 		expectedMin, expectedCapacity, expectedMax uint32
 	}{
 		{
@@ -103,13 +105,30 @@ func Test_newMemorySizer(t *testing.T) {
 			expectedCapacity: 0,
 			expectedMax:      5,
 		},
+		{
+			name:             "memoryLimitPages over the limit of 32-bit memories",
+			limit:            defaultLimit * 2,
+			min:              zero,
+			expectedMin:      zero,
+			expectedCapacity: zero,
+			expectedMax:      defaultLimit,
+		},
+		{
+			name:             "memoryLimitPages over the limit of 32-bit memories, 64-bit",
+			limit:            defaultLimit * 2,
+			min:              zero,
+			is64:             true,
+			expectedMin:      zero,
+			expectedCapacity: zero,
+			expectedMax:      defaultLimit * 2,
+		},
 	}
 
 	for _, tt := range tests {
 		tc := tt
 		t.Run(tc.name, func(t *testing.T) {
 			sizer := newMemorySizer(tc.limit, tc.memoryCapacityFromMax)
-			min, capacity, max := sizer(tc.min, tc.max)
+			min, capacity, max := sizer(tc.min, tc.max, tc.is64)
 			require.Equal(t, tc.expectedMin, min)
 			require.Equal(t, tc.expectedCapacity, capacity)
 			require.Equal(t, tc.expectedMax, max)
@@ -157,6 +176,16 @@ func TestMemoryType(t *testing.T) {
 			input:    &wasm.Memory{Min: max, Cap: max, Max: max, IsMaxEncoded: true},
 			expected: []byte{0x1, 0x80, 0x80, 0x4, 0x80, 0x80, 0x4},
 		},
+		{
+			name:     "shared",
+			input:    &wasm.Memory{Min: 1, Cap: 1, Max: 2, IsMaxEncoded: true, IsShared: true},
+			expected: []byte{0x3, 1, 2},
+		},
+		{
+			name:     "64-bit",
+			input:    &wasm.Memory{Min: 1, Cap: 1, Max: 2, IsMaxEncoded: true, Is64: true},
+			expected: []byte{0x5, 1, 2},
+		},
 		{
 			name:             "min 0, max largest, wazero limit",
 			input:            &wasm.Memory{Max: max, IsMaxEncoded: true},
@@ -182,7 +211,7 @@ func TestMemoryType(t *testing.T) {
 				expectedDecoded.Max = tmax
 			}
 
-			binary, err := decodeMemory(bytes.NewReader(b), newMemorySizer(tmax, false), tmax)
+			binary, err := decodeMemory(bytes.NewReader(b), api.CoreFeaturesV2|api.CoreFeatureThreads|api.CoreFeatureMemory64, newMemorySizer(tmax, false), tmax)
 			require.NoError(t, err)
 			require.Equal(t, binary, expectedDecoded)
 		})
@@ -212,14 +241,34 @@ func TestDecodeMemoryType_Errors(t *testing.T) {
 			input:       []byte{0x1, 0, 0xff, 0xff, 0xff, 0xff, 0xf},
 			expectedErr: "max 4294967295 pages (3 Ti) over limit of 65536 pages (4 Gi)",
 		},
+		{
+			name:        "shared disabled",
+			input:       []byte{0x3, 0, 1},
+			expectedErr: `shared memory invalid as feature "threads" is disabled`,
+		},
+		{
+			name:        "64-bit disabled",
+			input:       []byte{0x4, 0},
+			expectedErr: `64-bit memory invalid as feature "memory64" is disabled`,
+		},
+		{
+			name:        "invalid flag",
+			input:       []byte{0x8, 0},
+			expectedErr: "invalid byte for limits: 0x8 not in (0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07)",
+		},
 	}
 
 	for _, tt := range tests {
 		tc := tt
 
 		t.Run(tc.name, func(t *testing.T) {
-			_, err := decodeMemory(bytes.NewReader(tc.input), newMemorySizer(max, false), max)
+			_, err := decodeMemory(bytes.NewReader(tc.input), api.CoreFeaturesV2, newMemorySizer(max, false), max)
 			require.EqualError(t, err, tc.expectedErr)
 		})
 	}
+
+	t.Run("shared without max", func(t *testing.T) {
+		_, err := decodeMemory(bytes.NewReader([]byte{0x2, 0}), api.CoreFeaturesV2|api.CoreFeatureThreads, newMemorySizer(max, false), max)
+		require.EqualError(t, err, "shared memory requires a maximum size")
+	})
 }
@@ -88,7 +88,8 @@ func TestMemorySection(t *testing.T) {
 	tests := []struct {
 		name     string
 		input    []byte
-		expected *wasm.Memory
+		features api.CoreFeatures
+		expected []wasm.Memory
 	}{
 		{
 			name: "min and min with max",
@@ -96,7 +97,21 @@ func TestMemorySection(t *testing.T) {
 				0x01,             // 1 memory
 				0x01, 0x02, 0x03, // (memory 2 3)
 			},
-			expected: &wasm.Memory{Min: 2, Cap: 2, Max: three, IsMaxEncoded: true},
+			features: api.CoreFeaturesV2,
+			expected: []wasm.Memory{{Min: 2, Cap: 2, Max: three, IsMaxEncoded: true}},
+		},
+		{
+			name: "multiple memories",
+			input: []byte{
+				0x02,       // 2 memories
+				0x00, 0x01, // (memory 1)
+				0x01, 0x02, 0x03, // (memory 2 3)
+			},
+			features: api.CoreFeaturesV2 | api.CoreFeatureMultiMemory,
+			expected: []wasm.Memory{
+				{Min: 1, Cap: 1, Max: max},
+				{Min: 2, Cap: 2, Max: three, IsMaxEncoded: true},
+			},
 		},
 	}
 
@@ -104,7 +119,7 @@ func TestMemorySection(t *testing.T) {
 		tc := tt
 
 		t.Run(tc.name, func(t *testing.T) {
-			memories, err := decodeMemorySection(bytes.NewReader(tc.input), newMemorySizer(max, false), max)
+			memories, err := decodeMemorySection(bytes.NewReader(tc.input), tc.features, newMemorySizer(max, false), max)
 			require.NoError(t, err)
 			require.Equal(t, tc.expected, memories)
 		})
@@ -134,7 +149,54 @@ func TestMemorySection_Errors(t *testing.T) {
 		tc := tt
 
 		t.Run(tc.name, func(t *testing.T) {
-			_, err := decodeMemorySection(bytes.NewReader(tc.input), newMemorySizer(max, false), max)
+			_, err := decodeMemorySection(bytes.NewReader(tc.input), api.CoreFeaturesV2, newMemorySizer(max, false), max)
+			require.EqualError(t, err, tc.expectedErr)
+		})
+	}
+}
+
+func TestDecodeTagSection(t *testing.T) {
+	tags, err := decodeTagSection(bytes.NewReader([]byte{
+		0x02,       // 2 tags
+		0x00, 0x00, // (tag (type 0))
+		0x00, 0x03, // (tag (type 3))
+	}), api.CoreFeatureExceptionHandling)
+	require.NoError(t, err)
+	require.Equal(t, []wasm.Tag{{Type: 0}, {Type: 3}}, tags)
+}
+
+func TestDecodeTagSection_Errors(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       []byte
+		features    api.CoreFeatures
+		expectedErr string
+	}{
+		{
+			name:        "disabled",
+			input:       []byte{0x01, 0x00, 0x00},
+			features:    api.CoreFeaturesV2,
+			expectedErr: "tag section not supported as feature \"exception-handling\" is disabled",
+		},
+		{
+			name:        "invalid attribute",
+			input:       []byte{0x01, 0x01, 0x00},
+			features:    api.CoreFeatureExceptionHandling,
+			expectedErr: "tag[0]: invalid byte: invalid tag attribute: 0x1",
+		},
+		{
+			name:        "missing type index",
+			input:       []byte{0x01, 0x00},
+			features:    api.CoreFeatureExceptionHandling,
+			expectedErr: "tag[0]: read type index of tag: EOF",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := decodeTagSection(bytes.NewReader(tc.input), tc.features)
 			require.EqualError(t, err, tc.expectedErr)
 		})
 	}
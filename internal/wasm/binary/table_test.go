@@ -81,6 +81,18 @@ func TestDecodeTableType_Errors(t *testing.T) {
 			input:       []byte{0x50, 0x1, 0x80, 0x80, 0x4, 0},
 			expectedErr: "table type funcref is invalid: feature \"reference-types\" is disabled",
 		},
+		{
+			name:        "shared",
+			input:       []byte{wasm.RefTypeFuncref, 0x3, 0, 1},
+			expectedErr: "tables cannot be marked as shared",
+			features:    api.CoreFeatureReferenceTypes | api.CoreFeatureThreads,
+		},
+		{
+			name:        "64-bit",
+			input:       []byte{wasm.RefTypeFuncref, 0x4, 0},
+			expectedErr: "tables cannot be 64-bit",
+			features:    api.CoreFeatureReferenceTypes | api.CoreFeatureMemory64,
+		},
 		{
 			name:        "max < min",
 			input:       []byte{wasm.RefTypeFuncref, 0x1, 0x80, 0x80, 0x4, 0},
@@ -0,0 +1,30 @@
+package binary
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/tetratelabs/wazero/internal/leb128"
+)
+
+// tagAttributeException is the only attribute of tags, which means the tag is for exceptions.
+const tagAttributeException = 0x00
+
+// decodeTagType returns the index in wasm.Module TypeSection of the tag's type, introduced in
+// api.CoreFeatureExceptionHandling.
+//
+// See https://github.com/WebAssembly/exception-handling/blob/main/proposals/exception-handling/legacy/Exceptions.md#tag-index-space
+func decodeTagType(r *bytes.Reader) (uint32, error) {
+	attribute, err := r.ReadByte()
+	if err != nil {
+		return 0, fmt.Errorf("read tag attribute: %w", err)
+	}
+	if attribute != tagAttributeException {
+		return 0, fmt.Errorf("%w: invalid tag attribute: %#x", ErrInvalidByte, attribute)
+	}
+	typeIndex, _, err := leb128.DecodeUint32(r)
+	if err != nil {
+		return 0, fmt.Errorf("read type index of tag: %w", err)
+	}
+	return typeIndex, nil
+}
@@ -7,30 +7,51 @@ import (
 	"unicode/utf8"
 	"unsafe"
 
+	"github.com/tetratelabs/wazero/api"
 	"github.com/tetratelabs/wazero/internal/leb128"
 	"github.com/tetratelabs/wazero/internal/wasm"
 )
 
-func decodeValueTypes(r *bytes.Reader, num uint32) ([]wasm.ValueType, error) {
+func decodeValueTypes(r *bytes.Reader, num uint32, enabledFeatures api.CoreFeatures) ([]wasm.ValueType, error) {
 	if num == 0 {
 		return nil, nil
 	}
 
 	ret := make([]wasm.ValueType, num)
-	_, err := io.ReadFull(r, ret)
+	for i := range ret {
+		v, err := decodeValueType(r, enabledFeatures)
+		if err != nil {
+			return nil, err
+		}
+		ret[i] = v
+	}
+	return ret, nil
+}
+
+// decodeValueType decodes a value type. Typed references introduced in api.CoreFeatureFunctionReferences are decoded
+// as wasm.ValueTypeFuncref or wasm.ValueTypeExternref.
+func decodeValueType(r *bytes.Reader, enabledFeatures api.CoreFeatures) (wasm.ValueType, error) {
+	v, err := r.ReadByte()
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
 
-	for _, v := range ret {
-		switch v {
-		case wasm.ValueTypeI32, wasm.ValueTypeF32, wasm.ValueTypeI64, wasm.ValueTypeF64,
-			wasm.ValueTypeExternref, wasm.ValueTypeFuncref, wasm.ValueTypeV128:
-		default:
-			return nil, fmt.Errorf("invalid value type: %d", v)
+	switch v {
+	case wasm.ValueTypeI32, wasm.ValueTypeF32, wasm.ValueTypeI64, wasm.ValueTypeF64,
+		wasm.ValueTypeExternref, wasm.ValueTypeFuncref, wasm.ValueTypeV128:
+	case wasm.ValueTypeExnref:
+		if err = enabledFeatures.RequireEnabled(api.CoreFeatureExceptionHandling); err != nil {
+			return 0, fmt.Errorf("exnref invalid as %v", err)
+		}
+	case wasm.RefTypePrefixNullable, wasm.RefTypePrefixNonNullable:
+		if err = enabledFeatures.RequireEnabled(api.CoreFeatureFunctionReferences); err != nil {
+			return 0, fmt.Errorf("typed reference invalid as %v", err)
 		}
+		v, _, err = wasm.DecodeHeapType(r)
+	default:
+		err = fmt.Errorf("invalid value type: %d", v)
 	}
-	return ret, nil
+	return v, err
 }
 
 // decodeUTF8 decodes a size prefixed string from the reader, returning it and the count of bytes read.
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"testing"
 
+	"github.com/tetratelabs/wazero/api"
 	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
 	"github.com/tetratelabs/wazero/internal/testing/require"
 	"github.com/tetratelabs/wazero/internal/wasm"
@@ -113,6 +114,81 @@ func TestEncodeValTypes(t *testing.T) {
 	}
 }
 
+func Test_decodeValueType(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    []byte
+		expected wasm.ValueType
+	}{
+		{name: "i32", input: []byte{wasm.ValueTypeI32}, expected: wasm.ValueTypeI32},
+		{name: "funcref", input: []byte{wasm.ValueTypeFuncref}, expected: wasm.ValueTypeFuncref},
+		{
+			name:     "(ref null func)",
+			input:    []byte{wasm.RefTypePrefixNullable, 0x70},
+			expected: wasm.ValueTypeFuncref,
+		},
+		{
+			name:     "(ref extern)",
+			input:    []byte{wasm.RefTypePrefixNonNullable, 0x6f},
+			expected: wasm.ValueTypeExternref,
+		},
+		{
+			name:     "(ref null $t)",
+			input:    []byte{wasm.RefTypePrefixNullable, 0x80, 0x01},
+			expected: wasm.ValueTypeFuncref,
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+
+		t.Run(tc.name, func(t *testing.T) {
+			r := bytes.NewReader(tc.input)
+			actual, err := decodeValueType(r, api.CoreFeaturesV2|api.CoreFeatureFunctionReferences)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, actual)
+			require.Zero(t, r.Len())
+		})
+	}
+}
+
+func Test_decodeValueType_errors(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       []byte
+		features    api.CoreFeatures
+		expectedErr string
+	}{
+		{
+			name:        "typed reference disabled",
+			input:       []byte{wasm.RefTypePrefixNullable, 0x70},
+			features:    api.CoreFeaturesV2,
+			expectedErr: "typed reference invalid as feature \"function-references\" is disabled",
+		},
+		{
+			name:        "invalid heap type",
+			input:       []byte{wasm.RefTypePrefixNullable, 0x7f},
+			features:    api.CoreFeatureFunctionReferences,
+			expectedErr: "invalid heap type: -1",
+		},
+		{
+			name:        "invalid value type",
+			input:       []byte{0x6e},
+			features:    api.CoreFeatureFunctionReferences,
+			expectedErr: "invalid value type: 110",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := decodeValueType(bytes.NewReader(tc.input), tc.features)
+			require.EqualError(t, err, tc.expectedErr)
+		})
+	}
+}
+
 func Test_decodeUTF8(t *testing.T) {
 	t.Run("empty", func(t *testing.T) {
 		actual, n, err := decodeUTF8(bytes.NewReader([]byte{0, '?', '?'}), "")
@@ -0,0 +1,141 @@
+package wasm
+
+import (
+	"fmt"
+
+	"github.com/tetratelabs/wazero/internal/leb128"
+)
+
+// IsExtended returns true if this consists of multiple instructions, which is introduced in
+// api.CoreFeatureExtendedConst. In that case, Opcode is the last instruction, which is one of the arithmetic
+// instructions allowed in constant expressions, and Data holds the instructions preceding it.
+//
+// For example, `(global.get 0) (i32.const 16) (i32.add)` is ConstantExpression{Opcode: OpcodeI32Add, Data:
+// []byte{OpcodeGlobalGet, 0, OpcodeI32Const, 16}}.
+func (e *ConstantExpression) IsExtended() bool {
+	switch e.Opcode {
+	case OpcodeI32Add, OpcodeI32Sub, OpcodeI32Mul, OpcodeI64Add, OpcodeI64Sub, OpcodeI64Mul:
+		return true
+	}
+	return false
+}
+
+// validateExtendedConstExpression validates the ConstantExpression where IsExtended is true, and returns the type of
+// its value.
+func validateExtendedConstExpression(globals []GlobalType, expr *ConstantExpression) (ValueType, error) {
+	var stack []ValueType
+	apply := func(opcode Opcode) error {
+		t := ValueTypeI32
+		if opcode == OpcodeI64Add || opcode == OpcodeI64Sub || opcode == OpcodeI64Mul {
+			t = ValueTypeI64
+		}
+		if len(stack) < 2 {
+			return fmt.Errorf("%s needs two values on the stack in const expression", InstructionName(opcode))
+		}
+		x, y := stack[len(stack)-2], stack[len(stack)-1]
+		if x != t || y != t {
+			return fmt.Errorf("%s needs %s values but got %s and %s in const expression",
+				InstructionName(opcode), ValueTypeName(t), ValueTypeName(x), ValueTypeName(y))
+		}
+		stack = stack[:len(stack)-1]
+		return nil
+	}
+
+	data := expr.Data
+	for len(data) > 0 {
+		opcode := data[0]
+		data = data[1:]
+		var n uint64
+		var err error
+		switch opcode {
+		case OpcodeI32Const:
+			_, n, err = leb128.LoadInt32(data)
+			if err != nil {
+				return 0, fmt.Errorf("read i32: %w", err)
+			}
+			stack = append(stack, ValueTypeI32)
+		case OpcodeI64Const:
+			_, n, err = leb128.LoadInt64(data)
+			if err != nil {
+				return 0, fmt.Errorf("read i64: %w", err)
+			}
+			stack = append(stack, ValueTypeI64)
+		case OpcodeGlobalGet:
+			var id uint32
+			id, n, err = leb128.LoadUint32(data)
+			if err != nil {
+				return 0, fmt.Errorf("read index of global: %w", err)
+			}
+			if uint32(len(globals)) <= id {
+				return 0, fmt.Errorf("global index out of range")
+			}
+			stack = append(stack, globals[id].ValType)
+		case OpcodeI32Add, OpcodeI32Sub, OpcodeI32Mul, OpcodeI64Add, OpcodeI64Sub, OpcodeI64Mul:
+			if err = apply(opcode); err != nil {
+				return 0, err
+			}
+		default:
+			return 0, fmt.Errorf("invalid opcode for extended const expression: 0x%x", opcode)
+		}
+		data = data[n:]
+	}
+
+	if err := apply(expr.Opcode); err != nil {
+		return 0, err
+	} else if len(stack) != 1 {
+		return 0, fmt.Errorf("const expression must have exactly one value, but had %d", len(stack))
+	}
+	return stack[0], nil
+}
+
+// executeExtendedConstExpression executes the ConstantExpression where IsExtended is true. The validity of the
+// expression is ensured by validateExtendedConstExpression.
+func executeExtendedConstExpression(globals []*GlobalInstance, expr *ConstantExpression) uint64 {
+	var stack []uint64
+	apply := func(opcode Opcode) {
+		x, y := stack[len(stack)-2], stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		var v uint64
+		switch opcode {
+		case OpcodeI32Add:
+			v = uint64(uint32(x) + uint32(y))
+		case OpcodeI32Sub:
+			v = uint64(uint32(x) - uint32(y))
+		case OpcodeI32Mul:
+			v = uint64(uint32(x) * uint32(y))
+		case OpcodeI64Add:
+			v = x + y
+		case OpcodeI64Sub:
+			v = x - y
+		case OpcodeI64Mul:
+			v = x * y
+		}
+		stack[len(stack)-1] = v
+	}
+
+	data := expr.Data
+	for len(data) > 0 {
+		opcode := data[0]
+		data = data[1:]
+		var n uint64
+		switch opcode {
+		case OpcodeI32Const:
+			var v int32
+			v, n, _ = leb128.LoadInt32(data)
+			stack = append(stack, uint64(uint32(v)))
+		case OpcodeI64Const:
+			var v int64
+			v, n, _ = leb128.LoadInt64(data)
+			stack = append(stack, uint64(v))
+		case OpcodeGlobalGet:
+			var id uint32
+			id, n, _ = leb128.LoadUint32(data)
+			stack = append(stack, globals[id].Val)
+		default:
+			apply(opcode)
+		}
+		data = data[n:]
+	}
+	apply(expr.Opcode)
+	return stack[0]
+}
@@ -0,0 +1,126 @@
+package wasm
+
+import (
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestConstantExpression_IsExtended(t *testing.T) {
+	require.False(t, (&ConstantExpression{Opcode: OpcodeI32Const, Data: []byte{1}}).IsExtended())
+	require.False(t, (&ConstantExpression{Opcode: OpcodeGlobalGet, Data: []byte{0}}).IsExtended())
+	require.True(t, (&ConstantExpression{Opcode: OpcodeI32Add}).IsExtended())
+	require.True(t, (&ConstantExpression{Opcode: OpcodeI64Mul}).IsExtended())
+}
+
+func Test_validateExtendedConstExpression(t *testing.T) {
+	globals := []GlobalType{{ValType: ValueTypeI32}, {ValType: ValueTypeI64}}
+
+	tests := []struct {
+		name        string
+		expr        ConstantExpression
+		expected    ValueType
+		expectedErr string
+	}{
+		{
+			name:     "global.get i32.const i32.add",
+			expr:     ConstantExpression{Opcode: OpcodeI32Add, Data: []byte{OpcodeGlobalGet, 0, OpcodeI32Const, 16}},
+			expected: ValueTypeI32,
+		},
+		{
+			name: "i64.const i64.const i64.mul i64.const i64.sub",
+			expr: ConstantExpression{
+				Opcode: OpcodeI64Sub,
+				Data:   []byte{OpcodeI64Const, 3, OpcodeI64Const, 5, OpcodeI64Mul, OpcodeI64Const, 1},
+			},
+			expected: ValueTypeI64,
+		},
+		{
+			name:        "not enough values",
+			expr:        ConstantExpression{Opcode: OpcodeI32Add, Data: []byte{OpcodeI32Const, 1}},
+			expectedErr: "i32.add needs two values on the stack in const expression",
+		},
+		{
+			name:        "type mismatch",
+			expr:        ConstantExpression{Opcode: OpcodeI32Add, Data: []byte{OpcodeI32Const, 1, OpcodeGlobalGet, 1}},
+			expectedErr: "i32.add needs i32 values but got i32 and i64 in const expression",
+		},
+		{
+			name: "too many values",
+			expr: ConstantExpression{
+				Opcode: OpcodeI32Add,
+				Data:   []byte{OpcodeI32Const, 1, OpcodeI32Const, 2, OpcodeI32Const, 3},
+			},
+			expectedErr: "const expression must have exactly one value, but had 2",
+		},
+		{
+			name:        "global index out of range",
+			expr:        ConstantExpression{Opcode: OpcodeI32Add, Data: []byte{OpcodeGlobalGet, 2, OpcodeI32Const, 1}},
+			expectedErr: "global index out of range",
+		},
+		{
+			name:        "invalid opcode",
+			expr:        ConstantExpression{Opcode: OpcodeI32Add, Data: []byte{OpcodeI32Const, 1, OpcodeNop}},
+			expectedErr: "invalid opcode for extended const expression: 0x1",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := validateExtendedConstExpression(globals, &tc.expr)
+			if tc.expectedErr != "" {
+				require.EqualError(t, err, tc.expectedErr)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tc.expected, actual)
+			}
+		})
+	}
+}
+
+func Test_executeExtendedConstExpression(t *testing.T) {
+	globals := []*GlobalInstance{{Val: 100}, {Val: 0xffff_ffff}}
+
+	tests := []struct {
+		name     string
+		expr     ConstantExpression
+		expected uint64
+	}{
+		{
+			name:     "i32.add",
+			expr:     ConstantExpression{Opcode: OpcodeI32Add, Data: []byte{OpcodeGlobalGet, 0, OpcodeI32Const, 16}},
+			expected: 116,
+		},
+		{
+			name:     "i32.sub",
+			expr:     ConstantExpression{Opcode: OpcodeI32Sub, Data: []byte{OpcodeGlobalGet, 0, OpcodeI32Const, 0xe5, 0x00}},
+			expected: 0xffff_ffff,
+		},
+		{
+			name:     "i32.mul wraps",
+			expr:     ConstantExpression{Opcode: OpcodeI32Mul, Data: []byte{OpcodeGlobalGet, 1, OpcodeI32Const, 2}},
+			expected: 0xffff_fffe,
+		},
+		{
+			name: "i64",
+			expr: ConstantExpression{
+				Opcode: OpcodeI64Sub,
+				Data:   []byte{OpcodeI64Const, 3, OpcodeI64Const, 5, OpcodeI64Mul, OpcodeI64Const, 1},
+			},
+			expected: 14,
+		},
+		{
+			name:     "i64.add negative",
+			expr:     ConstantExpression{Opcode: OpcodeI64Add, Data: []byte{OpcodeI64Const, 0x7f, OpcodeI64Const, 0x7f}},
+			expected: 0xffff_ffff_ffff_fffe,
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, executeExtendedConstExpression(globals, &tc.expr))
+		})
+	}
+}
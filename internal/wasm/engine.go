@@ -14,7 +14,10 @@ type Engine interface {
 	Close() (err error)
 
 	// CompileModule implements the same method as documented on wasm.Engine.
-	CompileModule(ctx context.Context, module *Module, listeners []experimental.FunctionListener, ensureTermination bool) error
+	//
+	// When meterFuel is true, functions consume the fuel of the calling module per block of instructions. When
+	// checkEpoch is true, functions trap once the Store.Epoch reaches ModuleInstance.EpochDeadline.
+	CompileModule(ctx context.Context, module *Module, listeners []experimental.FunctionListener, ensureTermination, meterFuel, checkEpoch bool) error
 
 	// CompiledModuleCount is exported for testing, to track the size of the compilation cache.
 	CompiledModuleCount() uint32
@@ -34,6 +37,56 @@ type Engine interface {
 	NewModuleEngine(module *Module, instance *ModuleInstance) (ModuleEngine, error)
 }
 
+// compilationWorkersKey is a context.Context key for the count of goroutines compiling the functions of a module.
+type compilationWorkersKey struct{}
+
+// WithCompilationWorkers returns a context making Engine.CompileModule compile functions with up to the given count
+// of goroutines. Engines which support it produce the same compiled code regardless of this count.
+func WithCompilationWorkers(ctx context.Context, workers int) context.Context {
+	return context.WithValue(ctx, compilationWorkersKey{}, workers)
+}
+
+// CompilationWorkers returns the count of goroutines set with WithCompilationWorkers, or one if unset or lower.
+func CompilationWorkers(ctx context.Context) int {
+	if workers, ok := ctx.Value(compilationWorkersKey{}).(int); ok && workers > 1 {
+		return workers
+	}
+	return 1
+}
+
+// ImportLinker is implemented by an Engine which instantiates modules with different engines, so that a module can
+// import functions from a module instance whose ModuleEngine is of another engine.
+type ImportLinker interface {
+	// LinkImportedFunction returns the ModuleEngine and function Index to pass to ModuleEngine.ResolveImportedFunction
+	// of the importing module instance, for its imported function at the given Index, which is the function at
+	// indexInImportedModule in the imported module instance.
+	LinkImportedFunction(importing *ModuleInstance, index Index, imported *ModuleInstance, indexInImportedModule Index) (ModuleEngine, Index, error)
+
+	// ReleaseModuleInstance is called once the module instance is closed, or failed to instantiate, so that the
+	// ModuleEngine of the other engine linked to it can be released.
+	ReleaseModuleInstance(m *ModuleInstance)
+}
+
+// CallDepthGoFunction is a host function which is passed the call depth: the count of functions executing in the
+// call from Go, including itself. This lets it call a function of another ModuleEngine with CallDepthFunction, so
+// that the functions executing in both count towards Store.MaxCallStackDepth.
+type CallDepthGoFunction interface {
+	api.GoModuleFunction
+
+	// CallWithDepth is like Call, except it is passed the call depth.
+	CallWithDepth(ctx context.Context, mod api.Module, stack []uint64, depth uint64)
+}
+
+// CallDepthFunction is implemented by the api.Function returned by ModuleEngine.NewFunction of engines which support
+// CallDepthGoFunction.
+type CallDepthFunction interface {
+	api.Function
+
+	// CallWithStackAndDepth is like CallWithStack, except the count of functions executing starts at the given depth
+	// instead of zero.
+	CallWithStackAndDepth(ctx context.Context, stack []uint64, depth uint64) error
+}
+
 // ModuleEngine implements function calls for a given module.
 type ModuleEngine interface {
 	// NewFunction returns an api.Function for the given function pointed by the given Index.
@@ -48,7 +101,21 @@ type ModuleEngine interface {
 	// LookupFunction returns the api.Function created from the function in the function table at the given offset.
 	LookupFunction(t *TableInstance, typeId FunctionTypeID, tableOffset Index) (api.Function, error)
 
+	// LookupFunctionReference returns the api.Function created from the given funcref, which must be non-null
+	// and have the given type. The Reference is one produced by FunctionInstanceReference, e.g. read from a table.
+	LookupFunctionReference(ref Reference, typeId FunctionTypeID) (api.Function, error)
+
 	// FunctionInstanceReference returns Reference for the given Index for a FunctionInstance. The returned values are used by
 	// the initialization via ElementSegment.
 	FunctionInstanceReference(funcIndex Index) Reference
+
+	// OwnsFunctionReference returns true if the non-null Reference was returned by FunctionInstanceReference of this
+	// ModuleEngine. This is used to validate references set by the host, which would otherwise crash the engine.
+	OwnsFunctionReference(ref Reference) bool
+
+	// FunctionReferenceRange returns the range [begin, end) containing all References returned by
+	// FunctionInstanceReference, or zeros if there are none, including when the ModuleEngine doesn't support them.
+	// The ranges of ModuleEngines in use don't overlap, which the Store relies on to find the ModuleEngine which owns
+	// a Reference.
+	FunctionReferenceRange() (begin, end Reference)
 }
@@ -0,0 +1,44 @@
+package wasm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestCompilationWorkers(t *testing.T) {
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		expected int
+	}{
+		{
+			name:     "unset",
+			ctx:      context.Background(),
+			expected: 1,
+		},
+		{
+			name:     "zero",
+			ctx:      WithCompilationWorkers(context.Background(), 0),
+			expected: 1,
+		},
+		{
+			name:     "negative",
+			ctx:      WithCompilationWorkers(context.Background(), -1),
+			expected: 1,
+		},
+		{
+			name:     "several",
+			ctx:      WithCompilationWorkers(context.Background(), 4),
+			expected: 4,
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, CompilationWorkers(tc.ctx))
+		})
+	}
+}
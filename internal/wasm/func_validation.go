@@ -20,19 +20,26 @@ const maximumValuesOnStack = 1 << 27
 // * idx is the index in the FunctionSection
 // * functions are the function index, which is prefixed by imports. The value is the TypeSection index.
 // * globals are the global index, which is prefixed by imports.
-// * memory is the potentially imported memory and can be nil.
+// * memories are the memory index, which is prefixed by imports.
 // * table is the potentially imported table and can be nil.
 // * declaredFunctionIndexes is the set of function indexes declared by declarative element segments which can be acceed by OpcodeRefFunc instruction.
 //
 // Returns an error if the instruction sequence is not valid,
 // or potentially it can exceed the maximum number of values on the stack.
 func (m *Module) validateFunction(sts *stacks, enabledFeatures api.CoreFeatures, idx Index, functions []Index,
-	globals []GlobalType, memory *Memory, tables []Table, declaredFunctionIndexes map[Index]struct{}, br *bytes.Reader,
+	globals []GlobalType, memories []*Memory, tables []Table, declaredFunctionIndexes map[Index]struct{}, br *bytes.Reader,
 ) error {
-	return m.validateFunctionWithMaxStackValues(sts, enabledFeatures, idx, functions, globals, memory, tables, maximumValuesOnStack, declaredFunctionIndexes, br)
+	return m.validateFunctionWithMaxStackValues(sts, enabledFeatures, idx, functions, globals, memories, tables, maximumValuesOnStack, declaredFunctionIndexes, br)
 }
 
-func readMemArg(pc uint64, body []byte) (align, offset uint32, read uint64, err error) {
+// memArgMemoryIndexFlag is the bit of the alignment in a memory argument which signals that a memory index follows it,
+// as added by api.CoreFeatureMultiMemory.
+const memArgMemoryIndexFlag = 1 << 6
+
+// readMemArg reads the memory argument of an instruction at pc, and returns the memory it accesses. The memory index is
+// zero unless api.CoreFeatureMultiMemory is enabled and the alignment has the memArgMemoryIndexFlag set, which is cleared
+// from the returned align. The offset is a u64 when the memory is 64-bit, introduced in api.CoreFeatureMemory64.
+func readMemArg(enabledFeatures api.CoreFeatures, memories []*Memory, pc uint64, body []byte, instName string) (align uint32, memory *Memory, offset uint64, read uint64, err error) {
 	align, num, err := leb128.LoadUint32(body[pc:])
 	if err != nil {
 		err = fmt.Errorf("read memory align: %v", err)
@@ -40,14 +47,65 @@ func readMemArg(pc uint64, body []byte) (align, offset uint32, read uint64, err
 	}
 	read += num
 
-	offset, num, err = leb128.LoadUint32(body[pc+num:])
+	var memoryIndex Index
+	if align&memArgMemoryIndexFlag != 0 && enabledFeatures.IsEnabled(api.CoreFeatureMultiMemory) {
+		align &^= memArgMemoryIndexFlag
+		memoryIndex, num, err = leb128.LoadUint32(body[pc+read:])
+		if err != nil {
+			err = fmt.Errorf("read memory index: %v", err)
+			return
+		}
+		read += num
+	}
+	if err = validateMemoryIndex(enabledFeatures, memories, memoryIndex, instName); err != nil {
+		return
+	}
+	memory = memories[memoryIndex]
+
+	if memory.Is64 {
+		offset, num, err = leb128.LoadUint64(body[pc+read:])
+	} else {
+		var offset32 uint32
+		offset32, num, err = leb128.LoadUint32(body[pc+read:])
+		offset = uint64(offset32)
+	}
 	if err != nil {
 		err = fmt.Errorf("read memory offset: %v", err)
 		return
 	}
 
 	read += num
-	return align, offset, read, nil
+	return align, memory, offset, read, nil
+}
+
+// validateMemoryIndex returns an error if the memory at the index doesn't exist, or if the index is non-zero without
+// api.CoreFeatureMultiMemory.
+func validateMemoryIndex(enabledFeatures api.CoreFeatures, memories []*Memory, index Index, instName string) error {
+	if index != 0 {
+		if err := enabledFeatures.RequireEnabled(api.CoreFeatureMultiMemory); err != nil {
+			return fmt.Errorf("memory index %d for %s invalid as %v", index, instName, err)
+		}
+	}
+	if index >= uint32(len(memories)) {
+		return fmt.Errorf("unknown memory %d for %s", index, instName)
+	}
+	return nil
+}
+
+// readMemoryIndex reads the memory index immediate of a memory instruction at pc, which is a reserved zero byte
+// unless api.CoreFeatureMultiMemory is enabled.
+func readMemoryIndex(enabledFeatures api.CoreFeatures, memories []*Memory, pc uint64, body []byte, instName string) (index Index, read uint64, err error) {
+	index, read, err = leb128.LoadUint32(body[pc:])
+	if err != nil {
+		err = fmt.Errorf("failed to read memory index for %s: %v", instName, err)
+		return
+	}
+	if enabledFeatures.IsEnabled(api.CoreFeatureMultiMemory) {
+		err = validateMemoryIndex(enabledFeatures, memories, index, instName)
+	} else if index != 0 || read != 1 {
+		err = fmt.Errorf("%s reserved byte must be zero encoded with 1 byte", instName)
+	}
+	return
 }
 
 // validateFunctionWithMaxStackValues is like validateFunction, but allows overriding maxStackValues for testing.
@@ -60,7 +118,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 	idx Index,
 	functions []Index,
 	globals []GlobalType,
-	memory *Memory,
+	memories []*Memory,
 	tables []Table,
 	maxStackValues int,
 	declaredFunctionIndexes map[Index]struct{},
@@ -86,6 +144,8 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				instName = MiscInstructionName(body[pc+1])
 			} else if op == OpcodeVecPrefix {
 				instName = VectorInstructionName(body[pc+1])
+			} else if op == OpcodeAtomicPrefix {
+				instName = AtomicInstructionName(body[pc+1])
 			} else {
 				instName = InstructionName(op)
 			}
@@ -93,21 +153,22 @@ func (m *Module) validateFunctionWithMaxStackValues(
 		}
 
 		if OpcodeI32Load <= op && op <= OpcodeI64Store32 {
-			if memory == nil {
+			if len(memories) == 0 {
 				return fmt.Errorf("memory must exist for %s", InstructionName(op))
 			}
 			pc++
-			align, _, read, err := readMemArg(pc, body)
+			align, memory, _, read, err := readMemArg(enabledFeatures, memories, pc, body, InstructionName(op))
 			if err != nil {
 				return err
 			}
+			addrType := memory.AddressType()
 			pc += read - 1
 			switch op {
 			case OpcodeI32Load:
 				if 1<<align > 32/8 {
 					return fmt.Errorf("invalid memory alignment")
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addrType); err != nil {
 					return err
 				}
 				valueTypeStack.push(ValueTypeI32)
@@ -115,7 +176,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if 1<<align > 32/8 {
 					return fmt.Errorf("invalid memory alignment")
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addrType); err != nil {
 					return err
 				}
 				valueTypeStack.push(ValueTypeF32)
@@ -126,7 +187,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
 					return err
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addrType); err != nil {
 					return err
 				}
 			case OpcodeF32Store:
@@ -136,14 +197,14 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if err := valueTypeStack.popAndVerifyType(ValueTypeF32); err != nil {
 					return err
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addrType); err != nil {
 					return err
 				}
 			case OpcodeI64Load:
 				if 1<<align > 64/8 {
 					return fmt.Errorf("invalid memory alignment")
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addrType); err != nil {
 					return err
 				}
 				valueTypeStack.push(ValueTypeI64)
@@ -151,7 +212,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if 1<<align > 64/8 {
 					return fmt.Errorf("invalid memory alignment")
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addrType); err != nil {
 					return err
 				}
 				valueTypeStack.push(ValueTypeF64)
@@ -162,7 +223,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if err := valueTypeStack.popAndVerifyType(ValueTypeI64); err != nil {
 					return err
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addrType); err != nil {
 					return err
 				}
 			case OpcodeF64Store:
@@ -172,14 +233,14 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if err := valueTypeStack.popAndVerifyType(ValueTypeF64); err != nil {
 					return err
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addrType); err != nil {
 					return err
 				}
 			case OpcodeI32Load8S:
 				if 1<<align > 1 {
 					return fmt.Errorf("invalid memory alignment")
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addrType); err != nil {
 					return err
 				}
 				valueTypeStack.push(ValueTypeI32)
@@ -187,7 +248,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if 1<<align > 1 {
 					return fmt.Errorf("invalid memory alignment")
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addrType); err != nil {
 					return err
 				}
 				valueTypeStack.push(ValueTypeI32)
@@ -195,7 +256,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if 1<<align > 1 {
 					return fmt.Errorf("invalid memory alignment")
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addrType); err != nil {
 					return err
 				}
 				valueTypeStack.push(ValueTypeI64)
@@ -206,7 +267,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
 					return err
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addrType); err != nil {
 					return err
 				}
 			case OpcodeI64Store8:
@@ -216,14 +277,14 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if err := valueTypeStack.popAndVerifyType(ValueTypeI64); err != nil {
 					return err
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addrType); err != nil {
 					return err
 				}
 			case OpcodeI32Load16S, OpcodeI32Load16U:
 				if 1<<align > 16/8 {
 					return fmt.Errorf("invalid memory alignment")
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addrType); err != nil {
 					return err
 				}
 				valueTypeStack.push(ValueTypeI32)
@@ -231,7 +292,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if 1<<align > 16/8 {
 					return fmt.Errorf("invalid memory alignment")
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addrType); err != nil {
 					return err
 				}
 				valueTypeStack.push(ValueTypeI64)
@@ -242,7 +303,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
 					return err
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addrType); err != nil {
 					return err
 				}
 			case OpcodeI64Store16:
@@ -252,14 +313,14 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if err := valueTypeStack.popAndVerifyType(ValueTypeI64); err != nil {
 					return err
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addrType); err != nil {
 					return err
 				}
 			case OpcodeI64Load32S, OpcodeI64Load32U:
 				if 1<<align > 32/8 {
 					return fmt.Errorf("invalid memory alignment")
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addrType); err != nil {
 					return err
 				}
 				valueTypeStack.push(ValueTypeI64)
@@ -270,12 +331,12 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if err := valueTypeStack.popAndVerifyType(ValueTypeI64); err != nil {
 					return err
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addrType); err != nil {
 					return err
 				}
 			}
 		} else if OpcodeMemorySize <= op && op <= OpcodeMemoryGrow {
-			if memory == nil {
+			if len(memories) == 0 {
 				return fmt.Errorf("memory must exist for %s", InstructionName(op))
 			}
 			pc++
@@ -283,17 +344,23 @@ func (m *Module) validateFunctionWithMaxStackValues(
 			if err != nil {
 				return fmt.Errorf("read immediate: %v", err)
 			}
-			if val != 0 || num != 1 {
+			if enabledFeatures.IsEnabled(api.CoreFeatureMultiMemory) {
+				if err := validateMemoryIndex(enabledFeatures, memories, val, InstructionName(op)); err != nil {
+					return err
+				}
+			} else if val != 0 || num != 1 {
 				return fmt.Errorf("memory instruction reserved bytes not zero with 1 byte")
 			}
+			// The page counts are i64 for a 64-bit memory.
+			pagesType := memories[val].AddressType()
 			switch Opcode(op) {
 			case OpcodeMemoryGrow:
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(pagesType); err != nil {
 					return err
 				}
-				valueTypeStack.push(ValueTypeI32)
+				valueTypeStack.push(pagesType)
 			case OpcodeMemorySize:
-				valueTypeStack.push(ValueTypeI32)
+				valueTypeStack.push(pagesType)
 			}
 			pc += num - 1
 		} else if OpcodeI32Const <= op && op <= OpcodeF64Const {
@@ -527,7 +594,12 @@ func (m *Module) validateFunctionWithMaxStackValues(
 
 			// br_table instruction is stack-polymorphic.
 			valueTypeStack.unreachable()
-		} else if op == OpcodeCall {
+		} else if op == OpcodeCall || op == OpcodeReturnCall {
+			if op == OpcodeReturnCall {
+				if err := enabledFeatures.RequireEnabled(api.CoreFeatureTailCall); err != nil {
+					return fmt.Errorf("%s invalid as %v", OpcodeReturnCallName, err)
+				}
+			}
 			pc++
 			index, num, err := leb128.LoadUint32(body[pc:])
 			if err != nil {
@@ -540,13 +612,26 @@ func (m *Module) validateFunctionWithMaxStackValues(
 			funcType := &m.TypeSection[functions[index]]
 			for i := 0; i < len(funcType.Params); i++ {
 				if err := valueTypeStack.popAndVerifyType(funcType.Params[len(funcType.Params)-1-i]); err != nil {
-					return fmt.Errorf("type mismatch on %s operation param type: %v", OpcodeCallName, err)
+					return fmt.Errorf("type mismatch on %s operation param type: %v", InstructionName(op), err)
 				}
 			}
-			for _, exp := range funcType.Results {
-				valueTypeStack.push(exp)
+			if op == OpcodeReturnCall {
+				if err := validateReturnCallResults(op, funcType, functionType); err != nil {
+					return err
+				}
+				// return_call instruction is stack-polymorphic.
+				valueTypeStack.unreachable()
+			} else {
+				for _, exp := range funcType.Results {
+					valueTypeStack.push(exp)
+				}
+			}
+		} else if op == OpcodeCallIndirect || op == OpcodeReturnCallIndirect {
+			if op == OpcodeReturnCallIndirect {
+				if err := enabledFeatures.RequireEnabled(api.CoreFeatureTailCall); err != nil {
+					return fmt.Errorf("%s invalid as %v", OpcodeReturnCallIndirectName, err)
+				}
 			}
-		} else if op == OpcodeCallIndirect {
 			pc++
 			typeIndex, num, err := leb128.LoadUint32(body[pc:])
 			if err != nil {
@@ -555,7 +640,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 			pc += num
 
 			if int(typeIndex) >= len(m.TypeSection) {
-				return fmt.Errorf("invalid type index at %s: %d", OpcodeCallIndirectName, typeIndex)
+				return fmt.Errorf("invalid type index at %s: %d", InstructionName(op), typeIndex)
 			}
 
 			tableIndex, num, err := leb128.LoadUint32(body[pc:])
@@ -575,20 +660,28 @@ func (m *Module) validateFunctionWithMaxStackValues(
 
 			table := tables[tableIndex]
 			if table.Type != RefTypeFuncref {
-				return fmt.Errorf("table is not funcref type but was %s for %s", RefTypeName(table.Type), OpcodeCallIndirectName)
+				return fmt.Errorf("table is not funcref type but was %s for %s", RefTypeName(table.Type), InstructionName(op))
 			}
 
 			if err = valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
-				return fmt.Errorf("cannot pop the offset in table for %s", OpcodeCallIndirectName)
+				return fmt.Errorf("cannot pop the offset in table for %s", InstructionName(op))
 			}
 			funcType := &m.TypeSection[typeIndex]
 			for i := 0; i < len(funcType.Params); i++ {
 				if err = valueTypeStack.popAndVerifyType(funcType.Params[len(funcType.Params)-1-i]); err != nil {
-					return fmt.Errorf("type mismatch on %s operation input type", OpcodeCallIndirectName)
+					return fmt.Errorf("type mismatch on %s operation input type", InstructionName(op))
 				}
 			}
-			for _, exp := range funcType.Results {
-				valueTypeStack.push(exp)
+			if op == OpcodeReturnCallIndirect {
+				if err := validateReturnCallResults(op, funcType, functionType); err != nil {
+					return err
+				}
+				// return_call_indirect instruction is stack-polymorphic.
+				valueTypeStack.unreachable()
+			} else {
+				for _, exp := range funcType.Results {
+					valueTypeStack.push(exp)
+				}
 			}
 		} else if OpcodeI32Eqz <= op && op <= OpcodeI64Extend32S {
 			switch op {
@@ -815,6 +908,11 @@ func (m *Module) validateFunctionWithMaxStackValues(
 					valueTypeStack.push(ValueTypeExternref)
 				case ValueTypeFuncref:
 					valueTypeStack.push(ValueTypeFuncref)
+				case ValueTypeExnref:
+					if err := enabledFeatures.RequireEnabled(api.CoreFeatureExceptionHandling); err != nil {
+						return fmt.Errorf("ref.null exn invalid as %v", err)
+					}
+					valueTypeStack.push(ValueTypeExnref)
 				default:
 					return fmt.Errorf("unknown type for ref.null: 0x%x", reftype)
 				}
@@ -838,6 +936,91 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				pc += num - 1
 				valueTypeStack.push(ValueTypeFuncref)
 			}
+		} else if op == OpcodeRefAsNonNull {
+			if err := enabledFeatures.RequireEnabled(api.CoreFeatureFunctionReferences); err != nil {
+				return fmt.Errorf("%s invalid as %v", OpcodeRefAsNonNullName, err)
+			}
+			tp, err := valueTypeStack.pop()
+			if err != nil {
+				return fmt.Errorf("cannot pop the operand for %s: %v", OpcodeRefAsNonNullName, err)
+			} else if !isReferenceValueType(tp) && tp != valueTypeUnknown {
+				return fmt.Errorf("type mismatch: expected reference type but was %s", ValueTypeName(tp))
+			}
+			valueTypeStack.push(tp)
+		} else if op == OpcodeBrOnNull || op == OpcodeBrOnNonNull {
+			if err := enabledFeatures.RequireEnabled(api.CoreFeatureFunctionReferences); err != nil {
+				return fmt.Errorf("%s invalid as %v", InstructionName(op), err)
+			}
+			tp, err := valueTypeStack.pop()
+			if err != nil {
+				return fmt.Errorf("cannot pop the operand for %s: %v", InstructionName(op), err)
+			} else if !isReferenceValueType(tp) && tp != valueTypeUnknown {
+				return fmt.Errorf("type mismatch: expected reference type but was %s", ValueTypeName(tp))
+			}
+
+			pc++
+			index, num, err := leb128.LoadUint32(body[pc:])
+			if err != nil {
+				return fmt.Errorf("read immediate: %v", err)
+			} else if int(index) >= len(controlBlockStack.stack) {
+				return fmt.Errorf(
+					"invalid ln param given for %s: index=%d with %d for the current label stack length",
+					InstructionName(op), index, len(controlBlockStack.stack))
+			}
+			pc += num - 1
+			target := &controlBlockStack.stack[len(controlBlockStack.stack)-int(index)-1]
+			var targetResultType []ValueType
+			if target.op == OpcodeLoop {
+				targetResultType = target.blockType.Params
+			} else {
+				targetResultType = target.blockType.Results
+			}
+			if op == OpcodeBrOnNonNull {
+				// The non-null reference is passed to the label as its last value.
+				last := len(targetResultType) - 1
+				if last < 0 || !isReferenceValueType(targetResultType[last]) ||
+					(tp != valueTypeUnknown && tp != targetResultType[last]) {
+					return fmt.Errorf("type mismatch on %s: label doesn't take %s as its last value",
+						OpcodeBrOnNonNullName, ValueTypeName(tp))
+				}
+				targetResultType = targetResultType[:last]
+			}
+			if err := valueTypeStack.popResults(op, targetResultType, false); err != nil {
+				return err
+			}
+			// Push back the result
+			for _, t := range targetResultType {
+				valueTypeStack.push(t)
+			}
+			if op == OpcodeBrOnNull {
+				// The reference is left on the stack unless it is null.
+				valueTypeStack.push(tp)
+			}
+		} else if op == OpcodeCallRef {
+			if err := enabledFeatures.RequireEnabled(api.CoreFeatureFunctionReferences); err != nil {
+				return fmt.Errorf("%s invalid as %v", OpcodeCallRefName, err)
+			}
+			pc++
+			typeIndex, num, err := leb128.LoadUint32(body[pc:])
+			if err != nil {
+				return fmt.Errorf("read immediate: %v", err)
+			}
+			pc += num - 1
+			if int(typeIndex) >= len(m.TypeSection) {
+				return fmt.Errorf("invalid type index at %s: %d", OpcodeCallRefName, typeIndex)
+			}
+			if err = valueTypeStack.popAndVerifyType(ValueTypeFuncref); err != nil {
+				return fmt.Errorf("cannot pop the function reference for %s: %v", OpcodeCallRefName, err)
+			}
+			funcType := &m.TypeSection[typeIndex]
+			for i := 0; i < len(funcType.Params); i++ {
+				if err = valueTypeStack.popAndVerifyType(funcType.Params[len(funcType.Params)-1-i]); err != nil {
+					return fmt.Errorf("type mismatch on %s operation input type", OpcodeCallRefName)
+				}
+			}
+			for _, exp := range funcType.Results {
+				valueTypeStack.push(exp)
+			}
 		} else if op == OpcodeTableGet || op == OpcodeTableSet {
 			if err := enabledFeatures.RequireEnabled(api.CoreFeatureReferenceTypes); err != nil {
 				return fmt.Errorf("%s is invalid as %v", InstructionName(op), err)
@@ -923,10 +1106,9 @@ func (m *Module) validateFunctionWithMaxStackValues(
 					}
 					pc += num - 1
 				case OpcodeMiscMemoryInit, OpcodeMiscMemoryCopy, OpcodeMiscMemoryFill:
-					if memory == nil {
+					if len(memories) == 0 {
 						return fmt.Errorf("memory must exist for %s", MiscInstructionName(miscOpcode))
 					}
-					params = []ValueType{ValueTypeI32, ValueTypeI32, ValueTypeI32}
 
 					if miscOpcode == OpcodeMiscMemoryInit {
 						if m.DataCountSection == nil {
@@ -946,24 +1128,34 @@ func (m *Module) validateFunctionWithMaxStackValues(
 					}
 
 					pc++
-					val, num, err := leb128.LoadUint32(body[pc:])
+					index, num, err := readMemoryIndex(enabledFeatures, memories, pc, body, MiscInstructionName(miscOpcode))
 					if err != nil {
-						return fmt.Errorf("failed to read memory index for %s: %v", MiscInstructionName(miscOpcode), err)
-					}
-					if val != 0 || num != 1 {
-						return fmt.Errorf("%s reserved byte must be zero encoded with 1 byte", MiscInstructionName(miscOpcode))
+						return err
 					}
-					if miscOpcode == OpcodeMiscMemoryCopy {
-						pc++
-						// memory.copy needs two memory index which are reserved as zero.
-						val, num, err := leb128.LoadUint32(body[pc:])
+					// Addresses and sizes in a 64-bit memory are i64, except the size of memory.init, which is in
+					// the data segment, and that of memory.copy unless both memories are 64-bit. Note that params
+					// are in the order of popping, so the destination address is the last one.
+					addrType := memories[index].AddressType()
+					switch miscOpcode {
+					case OpcodeMiscMemoryInit:
+						params = []ValueType{ValueTypeI32, ValueTypeI32, addrType}
+					case OpcodeMiscMemoryFill:
+						params = []ValueType{addrType, ValueTypeI32, addrType}
+					case OpcodeMiscMemoryCopy:
+						pc += num
+						// memory.copy needs two memory indexes: the destination followed by the source.
+						index, num, err = readMemoryIndex(enabledFeatures, memories, pc, body, MiscInstructionName(miscOpcode))
 						if err != nil {
-							return fmt.Errorf("failed to read memory index for %s: %v", MiscInstructionName(miscOpcode), err)
+							return err
 						}
-						if val != 0 || num != 1 {
-							return fmt.Errorf("%s reserved byte must be zero encoded with 1 byte", MiscInstructionName(miscOpcode))
+						srcAddrType := memories[index].AddressType()
+						sizeType := ValueTypeI32
+						if addrType == ValueTypeI64 && srcAddrType == ValueTypeI64 {
+							sizeType = ValueTypeI64
 						}
+						params = []ValueType{sizeType, srcAddrType, addrType}
 					}
+					pc += num - 1
 
 				case OpcodeMiscTableInit:
 					params = []ValueType{ValueTypeI32, ValueTypeI32, ValueTypeI32}
@@ -1084,6 +1276,36 @@ func (m *Module) validateFunctionWithMaxStackValues(
 					valueTypeStack.push(r)
 				}
 			}
+		} else if op == OpcodeVecPrefix && IsVecRelaxed(body, pc+1) {
+			pc++
+			// Relaxed vector instructions come with three bytes where the first byte is always OpcodeVecPrefix,
+			// and the last byte is always OpcodeVecRelaxedSuffix.
+			relaxedOpcode := body[pc]
+			pc++
+			for _, feature := range []api.CoreFeatures{api.CoreFeatureSIMD, api.CoreFeatureRelaxedSIMD} {
+				if err := enabledFeatures.RequireEnabled(feature); err != nil {
+					return fmt.Errorf("%s invalid as %v", relaxedVectorInstructionName[relaxedOpcode], err)
+				}
+			}
+
+			var paramNum int
+			switch relaxedOpcode {
+			case OpcodeVecI32x4RelaxedTruncF32x4S, OpcodeVecI32x4RelaxedTruncF32x4U,
+				OpcodeVecI32x4RelaxedTruncF64x2SZero, OpcodeVecI32x4RelaxedTruncF64x2UZero:
+				paramNum = 1
+			case OpcodeVecI8x16RelaxedSwizzle, OpcodeVecF32x4RelaxedMin, OpcodeVecF32x4RelaxedMax,
+				OpcodeVecF64x2RelaxedMin, OpcodeVecF64x2RelaxedMax, OpcodeVecI16x8RelaxedQ15mulrS,
+				OpcodeVecI16x8RelaxedDotI8x16I7x16S:
+				paramNum = 2
+			default: // madd, nmadd, laneselect and the dot product with accumulation.
+				paramNum = 3
+			}
+			for i := 0; i < paramNum; i++ {
+				if err := valueTypeStack.popAndVerifyType(ValueTypeV128); err != nil {
+					return fmt.Errorf("cannot pop the operand for %s: %v", relaxedVectorInstructionName[relaxedOpcode], err)
+				}
+			}
+			valueTypeStack.push(ValueTypeV128)
 		} else if op == OpcodeVecPrefix {
 			pc++
 			// Vector instructions come with two bytes where the first byte is always OpcodeVecPrefix,
@@ -1111,14 +1333,15 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				OpcodeVecV128Load32x2s, OpcodeVecV128Load32x2u, OpcodeVecV128Load8Splat, OpcodeVecV128Load16Splat,
 				OpcodeVecV128Load32Splat, OpcodeVecV128Load64Splat,
 				OpcodeVecV128Load32zero, OpcodeVecV128Load64zero:
-				if memory == nil {
+				if len(memories) == 0 {
 					return fmt.Errorf("memory must exist for %s", VectorInstructionName(vecOpcode))
 				}
 				pc++
-				align, _, read, err := readMemArg(pc, body)
+				align, memory, _, read, err := readMemArg(enabledFeatures, memories, pc, body, VectorInstructionName(vecOpcode))
 				if err != nil {
 					return err
 				}
+				addrType := memory.AddressType()
 				pc += read - 1
 				var maxAlign uint32
 				switch vecOpcode {
@@ -1144,19 +1367,20 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if 1<<align > maxAlign {
 					return fmt.Errorf("invalid memory alignment %d for %s", align, VectorInstructionName(vecOpcode))
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addrType); err != nil {
 					return fmt.Errorf("cannot pop the operand for %s: %v", VectorInstructionName(vecOpcode), err)
 				}
 				valueTypeStack.push(ValueTypeV128)
 			case OpcodeVecV128Store:
-				if memory == nil {
+				if len(memories) == 0 {
 					return fmt.Errorf("memory must exist for %s", VectorInstructionName(vecOpcode))
 				}
 				pc++
-				align, _, read, err := readMemArg(pc, body)
+				align, memory, _, read, err := readMemArg(enabledFeatures, memories, pc, body, VectorInstructionName(vecOpcode))
 				if err != nil {
 					return err
 				}
+				addrType := memory.AddressType()
 				pc += read - 1
 				if 1<<align > 128/8 {
 					return fmt.Errorf("invalid memory alignment %d for %s", align, OpcodeVecV128StoreName)
@@ -1164,19 +1388,20 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if err := valueTypeStack.popAndVerifyType(ValueTypeV128); err != nil {
 					return fmt.Errorf("cannot pop the operand for %s: %v", OpcodeVecV128StoreName, err)
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addrType); err != nil {
 					return fmt.Errorf("cannot pop the operand for %s: %v", OpcodeVecV128StoreName, err)
 				}
 			case OpcodeVecV128Load8Lane, OpcodeVecV128Load16Lane, OpcodeVecV128Load32Lane, OpcodeVecV128Load64Lane:
-				if memory == nil {
+				if len(memories) == 0 {
 					return fmt.Errorf("memory must exist for %s", VectorInstructionName(vecOpcode))
 				}
 				attr := vecLoadLanes[vecOpcode]
 				pc++
-				align, _, read, err := readMemArg(pc, body)
+				align, memory, _, read, err := readMemArg(enabledFeatures, memories, pc, body, VectorInstructionName(vecOpcode))
 				if err != nil {
 					return err
 				}
+				addrType := memory.AddressType()
 				if 1<<align > attr.alignMax {
 					return fmt.Errorf("invalid memory alignment %d for %s", align, vectorInstructionName[vecOpcode])
 				}
@@ -1191,20 +1416,21 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if err := valueTypeStack.popAndVerifyType(ValueTypeV128); err != nil {
 					return fmt.Errorf("cannot pop the operand for %s: %v", vectorInstructionName[vecOpcode], err)
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addrType); err != nil {
 					return fmt.Errorf("cannot pop the operand for %s: %v", vectorInstructionName[vecOpcode], err)
 				}
 				valueTypeStack.push(ValueTypeV128)
 			case OpcodeVecV128Store8Lane, OpcodeVecV128Store16Lane, OpcodeVecV128Store32Lane, OpcodeVecV128Store64Lane:
-				if memory == nil {
+				if len(memories) == 0 {
 					return fmt.Errorf("memory must exist for %s", VectorInstructionName(vecOpcode))
 				}
 				attr := vecStoreLanes[vecOpcode]
 				pc++
-				align, _, read, err := readMemArg(pc, body)
+				align, memory, _, read, err := readMemArg(enabledFeatures, memories, pc, body, VectorInstructionName(vecOpcode))
 				if err != nil {
 					return err
 				}
+				addrType := memory.AddressType()
 				if 1<<align > attr.alignMax {
 					return fmt.Errorf("invalid memory alignment %d for %s", align, vectorInstructionName[vecOpcode])
 				}
@@ -1219,7 +1445,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				if err := valueTypeStack.popAndVerifyType(ValueTypeV128); err != nil {
 					return fmt.Errorf("cannot pop the operand for %s: %v", vectorInstructionName[vecOpcode], err)
 				}
-				if err := valueTypeStack.popAndVerifyType(ValueTypeI32); err != nil {
+				if err := valueTypeStack.popAndVerifyType(addrType); err != nil {
 					return fmt.Errorf("cannot pop the operand for %s: %v", vectorInstructionName[vecOpcode], err)
 				}
 			case OpcodeVecI8x16ExtractLaneS,
@@ -1388,6 +1614,54 @@ func (m *Module) validateFunctionWithMaxStackValues(
 			default:
 				return fmt.Errorf("TODO: SIMD instruction %s will be implemented in #506", vectorInstructionName[vecOpcode])
 			}
+		} else if op == OpcodeAtomicPrefix {
+			pc++
+			// Atomic instructions come with two bytes where the first byte is always OpcodeAtomicPrefix,
+			// and the second byte determines the actual instruction.
+			atomicOpcode := body[pc]
+			if err := enabledFeatures.RequireEnabled(api.CoreFeatureThreads); err != nil {
+				return fmt.Errorf("%s invalid as %v", AtomicInstructionName(atomicOpcode), err)
+			}
+			pc++
+
+			if atomicOpcode == OpcodeAtomicFence {
+				// The fence has a reserved byte instead of the memory argument, which must be zero.
+				if body[pc] != 0 {
+					return fmt.Errorf("%s reserved byte must be zero", AtomicInstructionName(atomicOpcode))
+				}
+			} else {
+				if len(memories) == 0 {
+					return fmt.Errorf("memory must exist for %s", AtomicInstructionName(atomicOpcode))
+				}
+				align, memory, _, read, err := readMemArg(enabledFeatures, memories, pc, body, AtomicInstructionName(atomicOpcode))
+				if err != nil {
+					return err
+				}
+				addrType := memory.AddressType()
+				pc += read - 1
+
+				typ, width, params, hasResult, err := atomicInstructionSignature(atomicOpcode)
+				if err != nil {
+					return err
+				}
+				// Unlike non-atomic instructions, the alignment must be exactly the natural one.
+				if 1<<align != width {
+					return fmt.Errorf("invalid memory alignment")
+				}
+				for i := len(params) - 1; i >= 0; i-- {
+					// The first param is the address, which is i64 for a 64-bit memory.
+					param := params[i]
+					if i == 0 {
+						param = addrType
+					}
+					if err := valueTypeStack.popAndVerifyType(param); err != nil {
+						return fmt.Errorf("cannot pop the operand for %s: %v", AtomicInstructionName(atomicOpcode), err)
+					}
+				}
+				if hasResult {
+					valueTypeStack.push(typ)
+				}
+			}
 		} else if op == OpcodeBlock {
 			br.Reset(body[pc+1:])
 			bt, num, err := DecodeBlockType(m.TypeSection, br, enabledFeatures)
@@ -1455,6 +1729,166 @@ func (m *Module) validateFunctionWithMaxStackValues(
 			for _, p := range bl.blockType.Params {
 				valueTypeStack.push(p)
 			}
+		} else if op == OpcodeTry {
+			if err := enabledFeatures.RequireEnabled(api.CoreFeatureExceptionHandling); err != nil {
+				return fmt.Errorf("%s invalid as %v", OpcodeTryName, err)
+			}
+			br.Reset(body[pc+1:])
+			bt, num, err := DecodeBlockType(m.TypeSection, br, enabledFeatures)
+			if err != nil {
+				return fmt.Errorf("read block: %w", err)
+			}
+			controlBlockStack.push(pc, 0, 0, bt, num, op)
+			if err = valueTypeStack.popParams(op, bt.Params, false); err != nil {
+				return err
+			}
+			// Plus we have to push any block params again.
+			for _, p := range bt.Params {
+				valueTypeStack.push(p)
+			}
+			valueTypeStack.pushStackLimit(len(bt.Params))
+			pc += num
+		} else if op == OpcodeCatch || op == OpcodeCatchAll {
+			instName := InstructionName(op)
+			if err := enabledFeatures.RequireEnabled(api.CoreFeatureExceptionHandling); err != nil {
+				return fmt.Errorf("%s invalid as %v", instName, err)
+			}
+			var tagType *FunctionType
+			if op == OpcodeCatch {
+				pc++
+				index, num, err := leb128.LoadUint32(body[pc:])
+				if err != nil {
+					return fmt.Errorf("read immediate: %v", err)
+				}
+				pc += num - 1
+				if tagType = m.typeOfTag(index); tagType == nil {
+					return fmt.Errorf("invalid tag index for %s", instName)
+				}
+			}
+			bl := &controlBlockStack.stack[len(controlBlockStack.stack)-1]
+			if bl.op != OpcodeTry && bl.op != OpcodeCatch {
+				return fmt.Errorf("%s must be in a try block", instName)
+			}
+			// Check the type soundness of the instructions *before* entering this handler.
+			if err := valueTypeStack.popResults(bl.op, bl.blockType.Results, true); err != nil {
+				return err
+			}
+			// Before entering instructions inside the handler, we pop all the values pushed by the previous ones.
+			valueTypeStack.resetAtStackLimit()
+			// The handler starts with the values of the caught exception instead of the block params.
+			if tagType != nil {
+				for _, p := range tagType.Params {
+					valueTypeStack.push(p)
+				}
+			}
+			bl.op = op
+		} else if op == OpcodeDelegate {
+			if err := enabledFeatures.RequireEnabled(api.CoreFeatureExceptionHandling); err != nil {
+				return fmt.Errorf("%s invalid as %v", OpcodeDelegateName, err)
+			}
+			pc++
+			index, num, err := leb128.LoadUint32(body[pc:])
+			if err != nil {
+				return fmt.Errorf("read immediate: %v", err)
+			}
+			pc += num - 1
+			if len(controlBlockStack.stack) == 0 {
+				return fmt.Errorf("redundant %s instruction at %#x", OpcodeDelegateName, pc)
+			}
+			bl := controlBlockStack.pop()
+			if bl.op != OpcodeTry {
+				return fmt.Errorf("%s must end a try block without handlers", OpcodeDelegateName)
+			} else if int(index) >= len(controlBlockStack.stack) {
+				return fmt.Errorf("invalid %s operation: index out of range", OpcodeDelegateName)
+			}
+			bl.endAt = pc
+
+			// Delegate ends the try block the same way as OpcodeEnd.
+			if err := valueTypeStack.requireStackValues(false, OpcodeTryName, bl.blockType.Results, true); err != nil {
+				return err
+			}
+			valueTypeStack.resetAtStackLimit()
+			for _, exp := range bl.blockType.Results {
+				valueTypeStack.push(exp)
+			}
+			valueTypeStack.popStackLimit()
+		} else if op == OpcodeThrow {
+			if err := enabledFeatures.RequireEnabled(api.CoreFeatureExceptionHandling); err != nil {
+				return fmt.Errorf("%s invalid as %v", OpcodeThrowName, err)
+			}
+			pc++
+			index, num, err := leb128.LoadUint32(body[pc:])
+			if err != nil {
+				return fmt.Errorf("read immediate: %v", err)
+			}
+			pc += num - 1
+			tagType := m.typeOfTag(index)
+			if tagType == nil {
+				return fmt.Errorf("invalid tag index for %s", OpcodeThrowName)
+			}
+			for i := len(tagType.Params) - 1; i >= 0; i-- {
+				if err := valueTypeStack.popAndVerifyType(tagType.Params[i]); err != nil {
+					return fmt.Errorf("type mismatch on %s operation param type: %v", OpcodeThrowName, err)
+				}
+			}
+			// throw instruction is stack-polymorphic.
+			valueTypeStack.unreachable()
+		} else if op == OpcodeRethrow {
+			if err := enabledFeatures.RequireEnabled(api.CoreFeatureExceptionHandling); err != nil {
+				return fmt.Errorf("%s invalid as %v", OpcodeRethrowName, err)
+			}
+			pc++
+			index, num, err := leb128.LoadUint32(body[pc:])
+			if err != nil {
+				return fmt.Errorf("read immediate: %v", err)
+			} else if int(index) >= len(controlBlockStack.stack) {
+				return fmt.Errorf("invalid %s operation: index out of range", OpcodeRethrowName)
+			}
+			pc += num - 1
+			if target := &controlBlockStack.stack[len(controlBlockStack.stack)-int(index)-1]; target.op != OpcodeCatch && target.op != OpcodeCatchAll {
+				return fmt.Errorf("invalid %s operation: label %d is not a catch block", OpcodeRethrowName, index)
+			}
+			// rethrow instruction is stack-polymorphic.
+			valueTypeStack.unreachable()
+		} else if op == OpcodeTryTable {
+			if err := enabledFeatures.RequireEnabled(api.CoreFeatureExceptionHandling); err != nil {
+				return fmt.Errorf("%s invalid as %v", OpcodeTryTableName, err)
+			}
+			br.Reset(body[pc+1:])
+			bt, num, err := DecodeBlockType(m.TypeSection, br, enabledFeatures)
+			if err != nil {
+				return fmt.Errorf("read block: %w", err)
+			}
+			catches, n, err := DecodeTryTableCatches(br)
+			if err != nil {
+				return fmt.Errorf("read %s: %w", OpcodeTryTableName, err)
+			}
+			num += n
+			// The labels of the catches are relative to the block enclosing try_table.
+			for i := range catches {
+				if err = validateTryTableCatch(m, &catches[i], controlBlockStack); err != nil {
+					return fmt.Errorf("invalid catch[%d] of %s: %w", i, OpcodeTryTableName, err)
+				}
+			}
+			controlBlockStack.push(pc, 0, 0, bt, num, op)
+			if err = valueTypeStack.popParams(op, bt.Params, false); err != nil {
+				return err
+			}
+			// Plus we have to push any block params again.
+			for _, p := range bt.Params {
+				valueTypeStack.push(p)
+			}
+			valueTypeStack.pushStackLimit(len(bt.Params))
+			pc += num
+		} else if op == OpcodeThrowRef {
+			if err := enabledFeatures.RequireEnabled(api.CoreFeatureExceptionHandling); err != nil {
+				return fmt.Errorf("%s invalid as %v", OpcodeThrowRefName, err)
+			}
+			if err := valueTypeStack.popAndVerifyType(ValueTypeExnref); err != nil {
+				return fmt.Errorf("cannot pop the operand for %s: %v", OpcodeThrowRefName, err)
+			}
+			// throw_ref instruction is stack-polymorphic.
+			valueTypeStack.unreachable()
 		} else if op == OpcodeEnd {
 			if len(controlBlockStack.stack) == 0 {
 				return fmt.Errorf("redundant End instruction at %#x", pc)
@@ -1533,7 +1967,8 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				pc++
 				tp := body[pc]
 				if tp != ValueTypeI32 && tp != ValueTypeI64 && tp != ValueTypeF32 && tp != ValueTypeF64 &&
-					tp != api.ValueTypeExternref && tp != ValueTypeFuncref && tp != ValueTypeV128 {
+					tp != api.ValueTypeExternref && tp != ValueTypeFuncref && tp != ValueTypeV128 &&
+					(tp != ValueTypeExnref || !enabledFeatures.IsEnabled(api.CoreFeatureExceptionHandling)) {
 					return fmt.Errorf("invalid type %s for %s", ValueTypeName(tp), OpcodeTypedSelectName)
 				}
 			} else if isReferenceValueType(v1) || isReferenceValueType(v2) {
@@ -1882,9 +2317,106 @@ type controlBlock struct {
 	op Opcode
 }
 
+// validateReturnCallResults ensures the results of the callee of a tail call are the same as the results of the
+// calling function, as the callee returns them to the caller of the calling function.
+func validateReturnCallResults(op Opcode, callee, caller *FunctionType) error {
+	if !bytes.Equal(callee.Results, caller.Results) {
+		var have, want strings.Builder
+		writeValueTypes(callee.Results, &have)
+		writeValueTypes(caller.Results, &want)
+		return fmt.Errorf("type mismatch on %s operation results: have (%s) but function returns (%s)",
+			InstructionName(op), have.String(), want.String())
+	}
+	return nil
+}
+
+// atomicInstructionSignature returns the signature of the atomic instruction other than OpcodeAtomicFence.
+// typ is the type of the value stored or returned, and width is the number of bytes accessed in the memory.
+// When hasResult is true, the instruction pushes a value of typ, except wait and notify pushing an i32.
+func atomicInstructionSignature(op OpcodeAtomic) (typ ValueType, width uint32, params []ValueType, hasResult bool, err error) {
+	switch op {
+	case OpcodeAtomicMemoryNotify:
+		return ValueTypeI32, 4, []ValueType{ValueTypeI32, ValueTypeI32}, true, nil
+	case OpcodeAtomicMemoryWait32:
+		return ValueTypeI32, 4, []ValueType{ValueTypeI32, ValueTypeI32, ValueTypeI64}, true, nil
+	case OpcodeAtomicMemoryWait64:
+		return ValueTypeI32, 8, []ValueType{ValueTypeI32, ValueTypeI64, ValueTypeI64}, true, nil
+	}
+
+	if op < OpcodeAtomicI32Load || op > OpcodeAtomicI64Rmw32CmpxchgU {
+		return 0, 0, nil, false, fmt.Errorf("invalid atomic opcode: %#x", op)
+	}
+
+	// Loads, stores and each kind of read-modify-write instructions are grouped into seven instructions
+	// which differ only by the value type and width in this order.
+	switch (op - OpcodeAtomicI32Load) % 7 {
+	case 0:
+		typ, width = ValueTypeI32, 4
+	case 1:
+		typ, width = ValueTypeI64, 8
+	case 2:
+		typ, width = ValueTypeI32, 1
+	case 3:
+		typ, width = ValueTypeI32, 2
+	case 4:
+		typ, width = ValueTypeI64, 1
+	case 5:
+		typ, width = ValueTypeI64, 2
+	case 6:
+		typ, width = ValueTypeI64, 4
+	}
+
+	switch {
+	case op <= OpcodeAtomicI64Load32U:
+		params, hasResult = []ValueType{ValueTypeI32}, true
+	case op <= OpcodeAtomicI64Store32:
+		params = []ValueType{ValueTypeI32, typ}
+	case op < OpcodeAtomicI32RmwCmpxchg:
+		params, hasResult = []ValueType{ValueTypeI32, typ}, true
+	default:
+		params, hasResult = []ValueType{ValueTypeI32, typ, typ}, true
+	}
+	return
+}
+
+// validateTryTableCatch ensures the values passed by the catch clause of OpcodeTryTable match the types of its label.
+func validateTryTableCatch(m *Module, c *TryTableCatch, controlBlockStack *controlBlockStack) error {
+	var types []ValueType
+	if !c.CatchesAll() {
+		tagType := m.typeOfTag(c.Tag)
+		if tagType == nil {
+			return fmt.Errorf("invalid tag index %d", c.Tag)
+		}
+		types = tagType.Params
+	}
+	if c.PassesRef() {
+		types = append(types[:len(types):len(types)], ValueTypeExnref)
+	}
+
+	if int(c.Label) >= len(controlBlockStack.stack) {
+		return fmt.Errorf("label index out of range")
+	}
+	target := &controlBlockStack.stack[len(controlBlockStack.stack)-int(c.Label)-1]
+	targetTypes := target.blockType.Results
+	if target.op == OpcodeLoop {
+		targetTypes = target.blockType.Params
+	}
+	if len(types) != len(targetTypes) {
+		return fmt.Errorf("type mismatch: label expects %d values but %d are passed", len(targetTypes), len(types))
+	}
+	for i := range types {
+		if types[i] != targetTypes[i] {
+			return fmt.Errorf("type mismatch: label expects %s but %s is passed",
+				ValueTypeName(targetTypes[i]), ValueTypeName(types[i]))
+		}
+	}
+	return nil
+}
+
 // DecodeBlockType decodes the type index from a positive 33-bit signed integer. Negative numbers indicate up to one
 // WebAssembly 1.0 (20191205) compatible result type. Positive numbers are decoded when `enabledFeatures` include
-// CoreFeatureMultiValue and include an index in the Module.TypeSection.
+// CoreFeatureMultiValue and include an index in the Module.TypeSection. Typed references are decoded when
+// `enabledFeatures` include CoreFeatureFunctionReferences.
 //
 // See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#binary-blocktype
 // See https://github.com/WebAssembly/spec/blob/wg-2.0.draft1/proposals/multi-value/Overview.md
@@ -1912,6 +2444,25 @@ func DecodeBlockType(types []FunctionType, r *bytes.Reader, enabledFeatures api.
 		ret = blockType_v_funcref
 	case -17: // 0x6f in original byte = externref
 		ret = blockType_v_externref
+	case -23: // 0x69 in original byte = exnref
+		if err = enabledFeatures.RequireEnabled(api.CoreFeatureExceptionHandling); err != nil {
+			return nil, num, fmt.Errorf("block with exnref result invalid as %v", err)
+		}
+		ret = blockType_v_exnref
+	case -29, -28: // 0x63 or 0x64 in original byte = typed reference
+		if err = enabledFeatures.RequireEnabled(api.CoreFeatureFunctionReferences); err != nil {
+			return nil, num, fmt.Errorf("block with typed reference result invalid as %v", err)
+		}
+		refType, n, err := DecodeHeapType(r)
+		if err != nil {
+			return nil, 0, err
+		}
+		num += n
+		if refType == RefTypeFuncref {
+			ret = blockType_v_funcref
+		} else {
+			ret = blockType_v_externref
+		}
 	default:
 		if err = enabledFeatures.RequireEnabled(api.CoreFeatureMultiValue); err != nil {
 			return nil, num, fmt.Errorf("block with function type return invalid as %v", err)
@@ -1934,6 +2485,7 @@ var (
 	blockType_v_v128      = &FunctionType{Results: []ValueType{ValueTypeV128}, ResultNumInUint64: 2}
 	blockType_v_funcref   = &FunctionType{Results: []ValueType{ValueTypeFuncref}, ResultNumInUint64: 1}
 	blockType_v_externref = &FunctionType{Results: []ValueType{ValueTypeExternref}, ResultNumInUint64: 1}
+	blockType_v_exnref    = &FunctionType{Results: []ValueType{ValueTypeExnref}, ResultNumInUint64: 1}
 )
 
 // SplitCallStack returns the input stack resliced to the count of params and
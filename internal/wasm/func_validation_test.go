@@ -301,7 +301,7 @@ func TestModule_ValidateFunction_BulkMemoryOperations(t *testing.T) {
 					DataCountSection: &c,
 				}
 				err := m.validateFunction(&stacks{}, api.CoreFeatureBulkMemoryOperations,
-					0, []Index{0}, nil, &Memory{}, []Table{{}, {}}, nil, bytes.NewReader(nil))
+					0, []Index{0}, nil, []*Memory{{}}, []Table{{}, {}}, nil, bytes.NewReader(nil))
 				require.NoError(t, err)
 			})
 		}
@@ -312,7 +312,7 @@ func TestModule_ValidateFunction_BulkMemoryOperations(t *testing.T) {
 			dataSection         []DataSegment
 			elementSection      []ElementSegment
 			dataCountSectionNil bool
-			memory              *Memory
+			memory              []*Memory
 			tables              []Table
 			flag                api.CoreFeatures
 			expectedErr         string
@@ -338,48 +338,48 @@ func TestModule_ValidateFunction_BulkMemoryOperations(t *testing.T) {
 			{
 				body:        []byte{OpcodeMiscPrefix, OpcodeMiscMemoryInit},
 				flag:        api.CoreFeatureBulkMemoryOperations,
-				memory:      &Memory{},
+				memory:      []*Memory{{}},
 				expectedErr: "failed to read data segment index for memory.init: EOF",
 			},
 			{
 				body:        []byte{OpcodeMiscPrefix, OpcodeMiscMemoryInit, 100 /* data section out of range */},
 				flag:        api.CoreFeatureBulkMemoryOperations,
-				memory:      &Memory{},
+				memory:      []*Memory{{}},
 				dataSection: []DataSegment{{}},
 				expectedErr: "index 100 out of range of data section(len=1)",
 			},
 			{
 				body:        []byte{OpcodeMiscPrefix, OpcodeMiscMemoryInit, 0},
 				flag:        api.CoreFeatureBulkMemoryOperations,
-				memory:      &Memory{},
+				memory:      []*Memory{{}},
 				dataSection: []DataSegment{{}},
 				expectedErr: "failed to read memory index for memory.init: EOF",
 			},
 			{
 				body:        []byte{OpcodeMiscPrefix, OpcodeMiscMemoryInit, 0, 1},
 				flag:        api.CoreFeatureBulkMemoryOperations,
-				memory:      &Memory{},
+				memory:      []*Memory{{}},
 				dataSection: []DataSegment{{}},
 				expectedErr: "memory.init reserved byte must be zero encoded with 1 byte",
 			},
 			{
 				body:        []byte{OpcodeMiscPrefix, OpcodeMiscMemoryInit, 0, 0},
 				flag:        api.CoreFeatureBulkMemoryOperations,
-				memory:      &Memory{},
+				memory:      []*Memory{{}},
 				dataSection: []DataSegment{{}},
 				expectedErr: "cannot pop the operand for memory.init: i32 missing",
 			},
 			{
 				body:        []byte{OpcodeI32Const, 0, OpcodeMiscPrefix, OpcodeMiscMemoryInit, 0, 0},
 				flag:        api.CoreFeatureBulkMemoryOperations,
-				memory:      &Memory{},
+				memory:      []*Memory{{}},
 				dataSection: []DataSegment{{}},
 				expectedErr: "cannot pop the operand for memory.init: i32 missing",
 			},
 			{
 				body:        []byte{OpcodeI32Const, 0, OpcodeI32Const, 0, OpcodeMiscPrefix, OpcodeMiscMemoryInit, 0, 0},
 				flag:        api.CoreFeatureBulkMemoryOperations,
-				memory:      &Memory{},
+				memory:      []*Memory{{}},
 				dataSection: []DataSegment{{}},
 				expectedErr: "cannot pop the operand for memory.init: i32 missing",
 			},
@@ -392,20 +392,20 @@ func TestModule_ValidateFunction_BulkMemoryOperations(t *testing.T) {
 			{
 				body:                []byte{OpcodeMiscPrefix, OpcodeMiscDataDrop},
 				dataCountSectionNil: true,
-				memory:              &Memory{},
+				memory:              []*Memory{{}},
 				flag:                api.CoreFeatureBulkMemoryOperations,
 				expectedErr:         `data.drop requires data count section`,
 			},
 			{
 				body:        []byte{OpcodeMiscPrefix, OpcodeMiscDataDrop},
 				flag:        api.CoreFeatureBulkMemoryOperations,
-				memory:      &Memory{},
+				memory:      []*Memory{{}},
 				expectedErr: "failed to read data segment index for data.drop: EOF",
 			},
 			{
 				body:        []byte{OpcodeMiscPrefix, OpcodeMiscDataDrop, 100 /* data section out of range */},
 				flag:        api.CoreFeatureBulkMemoryOperations,
-				memory:      &Memory{},
+				memory:      []*Memory{{}},
 				dataSection: []DataSegment{{}},
 				expectedErr: "index 100 out of range of data section(len=1)",
 			},
@@ -424,37 +424,37 @@ func TestModule_ValidateFunction_BulkMemoryOperations(t *testing.T) {
 			{
 				body:        []byte{OpcodeMiscPrefix, OpcodeMiscMemoryCopy},
 				flag:        api.CoreFeatureBulkMemoryOperations,
-				memory:      &Memory{},
+				memory:      []*Memory{{}},
 				expectedErr: `failed to read memory index for memory.copy: EOF`,
 			},
 			{
 				body:        []byte{OpcodeMiscPrefix, OpcodeMiscMemoryCopy, 0},
 				flag:        api.CoreFeatureBulkMemoryOperations,
-				memory:      &Memory{},
+				memory:      []*Memory{{}},
 				expectedErr: "failed to read memory index for memory.copy: EOF",
 			},
 			{
 				body:        []byte{OpcodeMiscPrefix, OpcodeMiscMemoryCopy, 0, 1},
 				flag:        api.CoreFeatureBulkMemoryOperations,
-				memory:      &Memory{},
+				memory:      []*Memory{{}},
 				expectedErr: "memory.copy reserved byte must be zero encoded with 1 byte",
 			},
 			{
 				body:        []byte{OpcodeMiscPrefix, OpcodeMiscMemoryCopy, 0, 0},
 				flag:        api.CoreFeatureBulkMemoryOperations,
-				memory:      &Memory{},
+				memory:      []*Memory{{}},
 				expectedErr: "cannot pop the operand for memory.copy: i32 missing",
 			},
 			{
 				body:        []byte{OpcodeI32Const, 0, OpcodeMiscPrefix, OpcodeMiscMemoryCopy, 0, 0},
 				flag:        api.CoreFeatureBulkMemoryOperations,
-				memory:      &Memory{},
+				memory:      []*Memory{{}},
 				expectedErr: "cannot pop the operand for memory.copy: i32 missing",
 			},
 			{
 				body:        []byte{OpcodeI32Const, 0, OpcodeI32Const, 0, OpcodeMiscPrefix, OpcodeMiscMemoryCopy, 0, 0},
 				flag:        api.CoreFeatureBulkMemoryOperations,
-				memory:      &Memory{},
+				memory:      []*Memory{{}},
 				expectedErr: "cannot pop the operand for memory.copy: i32 missing",
 			},
 			// memory.fill
@@ -472,31 +472,31 @@ func TestModule_ValidateFunction_BulkMemoryOperations(t *testing.T) {
 			{
 				body:        []byte{OpcodeMiscPrefix, OpcodeMiscMemoryFill},
 				flag:        api.CoreFeatureBulkMemoryOperations,
-				memory:      &Memory{},
+				memory:      []*Memory{{}},
 				expectedErr: `failed to read memory index for memory.fill: EOF`,
 			},
 			{
 				body:        []byte{OpcodeMiscPrefix, OpcodeMiscMemoryFill, 1},
 				flag:        api.CoreFeatureBulkMemoryOperations,
-				memory:      &Memory{},
+				memory:      []*Memory{{}},
 				expectedErr: `memory.fill reserved byte must be zero encoded with 1 byte`,
 			},
 			{
 				body:        []byte{OpcodeMiscPrefix, OpcodeMiscMemoryFill, 0},
 				flag:        api.CoreFeatureBulkMemoryOperations,
-				memory:      &Memory{},
+				memory:      []*Memory{{}},
 				expectedErr: "cannot pop the operand for memory.fill: i32 missing",
 			},
 			{
 				body:        []byte{OpcodeI32Const, 0, OpcodeMiscPrefix, OpcodeMiscMemoryFill, 0},
 				flag:        api.CoreFeatureBulkMemoryOperations,
-				memory:      &Memory{},
+				memory:      []*Memory{{}},
 				expectedErr: "cannot pop the operand for memory.fill: i32 missing",
 			},
 			{
 				body:        []byte{OpcodeI32Const, 0, OpcodeI32Const, 0, OpcodeMiscPrefix, OpcodeMiscMemoryFill, 0},
 				flag:        api.CoreFeatureBulkMemoryOperations,
-				memory:      &Memory{},
+				memory:      []*Memory{{}},
 				expectedErr: "cannot pop the operand for memory.fill: i32 missing",
 			},
 			// table.init
@@ -2220,7 +2220,7 @@ func TestModule_funcValidation_CallIndirect(t *testing.T) {
 			}}},
 		}
 		err := m.validateFunction(&stacks{}, api.CoreFeatureReferenceTypes,
-			0, []Index{0}, nil, &Memory{}, []Table{{Type: RefTypeFuncref}}, nil, bytes.NewReader(nil))
+			0, []Index{0}, nil, []*Memory{{}}, []Table{{Type: RefTypeFuncref}}, nil, bytes.NewReader(nil))
 		require.NoError(t, err)
 	})
 	t.Run("non zero table index", func(t *testing.T) {
@@ -2235,12 +2235,12 @@ func TestModule_funcValidation_CallIndirect(t *testing.T) {
 		}
 		t.Run("disabled", func(t *testing.T) {
 			err := m.validateFunction(&stacks{}, api.CoreFeaturesV1,
-				0, []Index{0}, nil, &Memory{}, []Table{{}, {}}, nil, bytes.NewReader(nil))
+				0, []Index{0}, nil, []*Memory{{}}, []Table{{}, {}}, nil, bytes.NewReader(nil))
 			require.EqualError(t, err, "table index must be zero but was 100: feature \"reference-types\" is disabled")
 		})
 		t.Run("enabled but out of range", func(t *testing.T) {
 			err := m.validateFunction(&stacks{}, api.CoreFeatureReferenceTypes,
-				0, []Index{0}, nil, &Memory{}, []Table{{}, {}}, nil, bytes.NewReader(nil))
+				0, []Index{0}, nil, []*Memory{{}}, []Table{{}, {}}, nil, bytes.NewReader(nil))
 			require.EqualError(t, err, "unknown table index: 100")
 		})
 	})
@@ -2255,11 +2255,89 @@ func TestModule_funcValidation_CallIndirect(t *testing.T) {
 			}}},
 		}
 		err := m.validateFunction(&stacks{}, api.CoreFeatureReferenceTypes,
-			0, []Index{0}, nil, &Memory{}, []Table{{Type: RefTypeExternref}}, nil, bytes.NewReader(nil))
+			0, []Index{0}, nil, []*Memory{{}}, []Table{{Type: RefTypeExternref}}, nil, bytes.NewReader(nil))
 		require.EqualError(t, err, "table is not funcref type but was externref for call_indirect")
 	})
 }
 
+func TestModule_funcValidation_TailCall(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        []byte
+		features    api.CoreFeatures
+		expectedErr string
+	}{
+		{
+			name:     "return_call",
+			body:     []byte{OpcodeLocalGet, 0, OpcodeReturnCall, 0, OpcodeEnd},
+			features: api.CoreFeatureTailCall,
+		},
+		{
+			name:     "return_call_indirect",
+			body:     []byte{OpcodeLocalGet, 0, OpcodeI32Const, 0, OpcodeReturnCallIndirect, 0, 0, OpcodeEnd},
+			features: api.CoreFeatureTailCall,
+		},
+		{
+			name: "return_call stack-polymorphic",
+			body: []byte{
+				OpcodeLocalGet, 0, OpcodeReturnCall, 0,
+				OpcodeI64Const, 0, OpcodeI64Const, 0, OpcodeI64Add, OpcodeDrop,
+				OpcodeEnd,
+			},
+			features: api.CoreFeatureTailCall,
+		},
+		{
+			name:        "return_call disabled",
+			body:        []byte{OpcodeLocalGet, 0, OpcodeReturnCall, 0, OpcodeEnd},
+			features:    api.CoreFeaturesV2,
+			expectedErr: "return_call invalid as feature \"tail-call\" is disabled",
+		},
+		{
+			name:        "return_call_indirect disabled",
+			body:        []byte{OpcodeLocalGet, 0, OpcodeI32Const, 0, OpcodeReturnCallIndirect, 0, 0, OpcodeEnd},
+			features:    api.CoreFeaturesV2,
+			expectedErr: "return_call_indirect invalid as feature \"tail-call\" is disabled",
+		},
+		{
+			name:        "return_call param type mismatch",
+			body:        []byte{OpcodeReturnCall, 0, OpcodeEnd},
+			features:    api.CoreFeatureTailCall,
+			expectedErr: "type mismatch on return_call operation param type: i32 missing",
+		},
+		{
+			name:        "return_call results mismatch",
+			body:        []byte{OpcodeLocalGet, 0, OpcodeReturnCall, 1, OpcodeEnd},
+			features:    api.CoreFeatureTailCall,
+			expectedErr: "type mismatch on return_call operation results: have () but function returns (i32)",
+		},
+		{
+			name:        "return_call_indirect results mismatch",
+			body:        []byte{OpcodeLocalGet, 0, OpcodeI32Const, 0, OpcodeReturnCallIndirect, 1, 0, OpcodeEnd},
+			features:    api.CoreFeatureTailCall,
+			expectedErr: "type mismatch on return_call_indirect operation results: have () but function returns (i32)",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+
+		t.Run(tc.name, func(t *testing.T) {
+			m := &Module{
+				TypeSection:     []FunctionType{i32_i32, i32_v},
+				FunctionSection: []Index{0, 1},
+				CodeSection:     []Code{{Body: tc.body}, {Body: []byte{OpcodeEnd}}},
+			}
+			err := m.validateFunction(&stacks{}, tc.features,
+				0, []Index{0, 1}, nil, nil, []Table{{Type: RefTypeFuncref}}, nil, bytes.NewReader(nil))
+			if tc.expectedErr != "" {
+				require.EqualError(t, err, tc.expectedErr)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
 func TestModule_funcValidation_RefTypes(t *testing.T) {
 	tests := []struct {
 		name                    string
@@ -3177,7 +3255,7 @@ func TestModule_funcValidation_SIMD(t *testing.T) {
 				CodeSection:     []Code{{Body: tc.body}},
 			}
 			err := m.validateFunction(&stacks{}, api.CoreFeatureSIMD,
-				0, []Index{0}, nil, &Memory{}, nil, nil, bytes.NewReader(nil))
+				0, []Index{0}, nil, []*Memory{{}}, nil, nil, bytes.NewReader(nil))
 			require.NoError(t, err)
 		})
 	}
@@ -3323,12 +3401,639 @@ func TestModule_funcValidation_SIMD_error(t *testing.T) {
 				CodeSection:     []Code{{Body: tc.body}},
 			}
 			err := m.validateFunction(&stacks{}, tc.flag,
-				0, []Index{0}, nil, &Memory{}, nil, nil, bytes.NewReader(nil))
+				0, []Index{0}, nil, []*Memory{{}}, nil, nil, bytes.NewReader(nil))
 			require.EqualError(t, err, tc.expectedErr)
 		})
 	}
 }
 
+func TestModule_funcValidation_Atomic(t *testing.T) {
+	// i32.atomic.rmw.add with the memory argument (align=2, offset=0).
+	rmwAdd := []byte{OpcodeI32Const, 0, OpcodeI32Const, 1, OpcodeAtomicPrefix, OpcodeAtomicI32RmwAdd, 0x2, 0x0, OpcodeDrop, OpcodeEnd}
+
+	tests := []struct {
+		name        string
+		body        []byte
+		noMemory    bool
+		features    api.CoreFeatures
+		expectedErr string
+	}{
+		{
+			name:     "i32.atomic.rmw.add",
+			body:     rmwAdd,
+			features: api.CoreFeaturesV2 | api.CoreFeatureThreads,
+		},
+		{
+			name: "i64.atomic.rmw32.cmpxchg_u",
+			body: []byte{
+				OpcodeI32Const, 0, OpcodeI64Const, 1, OpcodeI64Const, 2,
+				OpcodeAtomicPrefix, OpcodeAtomicI64Rmw32CmpxchgU, 0x2, 0x0, OpcodeDrop, OpcodeEnd,
+			},
+			features: api.CoreFeaturesV2 | api.CoreFeatureThreads,
+		},
+		{
+			name: "memory.atomic.wait64",
+			body: []byte{
+				OpcodeI32Const, 0, OpcodeI64Const, 0, OpcodeI64Const, 0,
+				OpcodeAtomicPrefix, OpcodeAtomicMemoryWait64, 0x3, 0x0, OpcodeDrop, OpcodeEnd,
+			},
+			features: api.CoreFeaturesV2 | api.CoreFeatureThreads,
+		},
+		{
+			name:     "atomic.fence",
+			body:     []byte{OpcodeAtomicPrefix, OpcodeAtomicFence, 0x0, OpcodeEnd},
+			features: api.CoreFeaturesV2 | api.CoreFeatureThreads,
+		},
+		{
+			name:        "disabled",
+			body:        rmwAdd,
+			features:    api.CoreFeaturesV2,
+			expectedErr: `i32.atomic.rmw.add invalid as feature "threads" is disabled`,
+		},
+		{
+			name:        "no memory",
+			body:        rmwAdd,
+			noMemory:    true,
+			features:    api.CoreFeaturesV2 | api.CoreFeatureThreads,
+			expectedErr: "memory must exist for i32.atomic.rmw.add",
+		},
+		{
+			name:        "alignment lower than natural",
+			body:        []byte{OpcodeI32Const, 0, OpcodeI32Const, 1, OpcodeAtomicPrefix, OpcodeAtomicI32RmwAdd, 0x1, 0x0, OpcodeDrop, OpcodeEnd},
+			features:    api.CoreFeaturesV2 | api.CoreFeatureThreads,
+			expectedErr: "invalid memory alignment",
+		},
+		{
+			name:        "type mismatch",
+			body:        []byte{OpcodeI32Const, 0, OpcodeI32Const, 1, OpcodeAtomicPrefix, OpcodeAtomicI64RmwAdd, 0x3, 0x0, OpcodeDrop, OpcodeEnd},
+			features:    api.CoreFeaturesV2 | api.CoreFeatureThreads,
+			expectedErr: "cannot pop the operand for i64.atomic.rmw.add: type mismatch: expected i64, but was i32",
+		},
+		{
+			name:        "fence with non-zero reserved byte",
+			body:        []byte{OpcodeAtomicPrefix, OpcodeAtomicFence, 0x1, OpcodeEnd},
+			features:    api.CoreFeaturesV2 | api.CoreFeatureThreads,
+			expectedErr: "atomic.fence reserved byte must be zero",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			m := &Module{
+				TypeSection:     []FunctionType{v_v},
+				FunctionSection: []Index{0},
+				CodeSection:     []Code{{Body: tc.body}},
+			}
+			var memories []*Memory
+			if !tc.noMemory {
+				memories = []*Memory{{Min: 1, Max: 1, IsShared: true}}
+			}
+			err := m.validateFunction(&stacks{}, tc.features,
+				0, []Index{0}, nil, memories, nil, nil, bytes.NewReader(nil))
+			if tc.expectedErr != "" {
+				require.EqualError(t, err, tc.expectedErr)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestModule_funcValidation_ExceptionHandling(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        []byte
+		features    api.CoreFeatures
+		expectedErr string
+	}{
+		{
+			name: "try catch",
+			body: []byte{
+				OpcodeTry, ValueTypeI32, OpcodeLocalGet, 0, OpcodeThrow, 0,
+				OpcodeCatch, 0,
+				OpcodeEnd,
+				OpcodeEnd,
+			},
+			features: api.CoreFeatureExceptionHandling,
+		},
+		{
+			name: "try catch_all rethrow",
+			body: []byte{
+				OpcodeTry, ValueTypeI32, OpcodeI32Const, 1,
+				OpcodeCatchAll, OpcodeRethrow, 0,
+				OpcodeEnd,
+				OpcodeEnd,
+			},
+			features: api.CoreFeatureExceptionHandling,
+		},
+		{
+			name: "try delegate",
+			body: []byte{
+				OpcodeTry, 0x40, OpcodeLocalGet, 0, OpcodeThrow, 0,
+				OpcodeDelegate, 0,
+				OpcodeLocalGet, 0,
+				OpcodeEnd,
+			},
+			features: api.CoreFeatureExceptionHandling,
+		},
+		{
+			name:        "try disabled",
+			body:        []byte{OpcodeTry, 0x40, OpcodeEnd, OpcodeLocalGet, 0, OpcodeEnd},
+			features:    api.CoreFeaturesV2,
+			expectedErr: "try invalid as feature \"exception-handling\" is disabled",
+		},
+		{
+			name:        "throw disabled",
+			body:        []byte{OpcodeLocalGet, 0, OpcodeThrow, 0, OpcodeEnd},
+			features:    api.CoreFeaturesV2,
+			expectedErr: "throw invalid as feature \"exception-handling\" is disabled",
+		},
+		{
+			name:        "throw param type mismatch",
+			body:        []byte{OpcodeThrow, 0, OpcodeEnd},
+			features:    api.CoreFeatureExceptionHandling,
+			expectedErr: "type mismatch on throw operation param type: i32 missing",
+		},
+		{
+			name:        "throw invalid tag",
+			body:        []byte{OpcodeLocalGet, 0, OpcodeThrow, 1, OpcodeEnd},
+			features:    api.CoreFeatureExceptionHandling,
+			expectedErr: "invalid tag index for throw",
+		},
+		{
+			name:        "catch outside try",
+			body:        []byte{OpcodeBlock, 0x40, OpcodeCatch, 0, OpcodeDrop, OpcodeEnd, OpcodeLocalGet, 0, OpcodeEnd},
+			features:    api.CoreFeatureExceptionHandling,
+			expectedErr: "catch must be in a try block",
+		},
+		{
+			name:        "catch after catch_all",
+			body:        []byte{OpcodeTry, 0x40, OpcodeCatchAll, OpcodeCatch, 0, OpcodeDrop, OpcodeEnd, OpcodeLocalGet, 0, OpcodeEnd},
+			features:    api.CoreFeatureExceptionHandling,
+			expectedErr: "catch must be in a try block",
+		},
+		{
+			name:        "catch results mismatch",
+			body:        []byte{OpcodeTry, ValueTypeI32, OpcodeCatch, 0, OpcodeEnd, OpcodeEnd},
+			features:    api.CoreFeatureExceptionHandling,
+			expectedErr: "not enough results in try block\n\thave ()\n\twant (i32)",
+		},
+		{
+			name:        "rethrow outside catch",
+			body:        []byte{OpcodeTry, 0x40, OpcodeRethrow, 0, OpcodeEnd, OpcodeLocalGet, 0, OpcodeEnd},
+			features:    api.CoreFeatureExceptionHandling,
+			expectedErr: "invalid rethrow operation: label 0 is not a catch block",
+		},
+		{
+			name:        "delegate after catch_all",
+			body:        []byte{OpcodeTry, 0x40, OpcodeCatchAll, OpcodeDelegate, 0, OpcodeLocalGet, 0, OpcodeEnd},
+			features:    api.CoreFeatureExceptionHandling,
+			expectedErr: "delegate must end a try block without handlers",
+		},
+		{
+			name:        "delegate index out of range",
+			body:        []byte{OpcodeTry, 0x40, OpcodeDelegate, 1, OpcodeLocalGet, 0, OpcodeEnd},
+			features:    api.CoreFeatureExceptionHandling,
+			expectedErr: "invalid delegate operation: index out of range",
+		},
+		{
+			name: "try_table catch",
+			body: []byte{
+				OpcodeBlock, ValueTypeI32,
+				OpcodeTryTable, ValueTypeI32, 1, TryTableCatchKindCatch, 0, 0, OpcodeLocalGet, 0,
+				OpcodeEnd,
+				OpcodeEnd,
+				OpcodeEnd,
+			},
+			features: api.CoreFeatureExceptionHandling,
+		},
+		{
+			name: "try_table catch_all_ref throw_ref",
+			body: []byte{
+				OpcodeBlock, ValueTypeExnref,
+				OpcodeTryTable, 0x40, 1, TryTableCatchKindCatchAllRef, 0,
+				OpcodeEnd,
+				OpcodeLocalGet, 0, OpcodeReturn,
+				OpcodeEnd,
+				OpcodeThrowRef,
+				OpcodeEnd,
+			},
+			features: api.CoreFeatureExceptionHandling,
+		},
+		{
+			name: "try_table catch to loop",
+			body: []byte{
+				OpcodeLocalGet, 0,
+				OpcodeLoop, 1, // (i32) -> ()
+				OpcodeTryTable, 0x40, 1, TryTableCatchKindCatch, 0, 0, OpcodeEnd,
+				OpcodeDrop,
+				OpcodeEnd,
+				OpcodeLocalGet, 0,
+				OpcodeEnd,
+			},
+			features: api.CoreFeatureExceptionHandling | api.CoreFeatureMultiValue,
+		},
+		{
+			name:        "try_table disabled",
+			body:        []byte{OpcodeTryTable, 0x40, 0, OpcodeEnd, OpcodeLocalGet, 0, OpcodeEnd},
+			features:    api.CoreFeaturesV2,
+			expectedErr: "try_table invalid as feature \"exception-handling\" is disabled",
+		},
+		{
+			name:        "throw_ref disabled",
+			body:        []byte{OpcodeThrowRef, OpcodeEnd},
+			features:    api.CoreFeaturesV2,
+			expectedErr: "throw_ref invalid as feature \"exception-handling\" is disabled",
+		},
+		{
+			name:        "try_table invalid catch kind",
+			body:        []byte{OpcodeTryTable, 0x40, 1, 4, 0, OpcodeEnd, OpcodeLocalGet, 0, OpcodeEnd},
+			features:    api.CoreFeatureExceptionHandling,
+			expectedErr: "read try_table: invalid kind of catch[0]: 0x4",
+		},
+		{
+			name:        "try_table invalid tag",
+			body:        []byte{OpcodeTryTable, 0x40, 1, TryTableCatchKindCatch, 1, 0, OpcodeEnd, OpcodeLocalGet, 0, OpcodeEnd},
+			features:    api.CoreFeatureExceptionHandling,
+			expectedErr: "invalid catch[0] of try_table: invalid tag index 1",
+		},
+		{
+			name:        "try_table label out of range",
+			body:        []byte{OpcodeTryTable, 0x40, 1, TryTableCatchKindCatchAll, 1, OpcodeEnd, OpcodeLocalGet, 0, OpcodeEnd},
+			features:    api.CoreFeatureExceptionHandling,
+			expectedErr: "invalid catch[0] of try_table: label index out of range",
+		},
+		{
+			name: "try_table catch_ref type mismatch",
+			body: []byte{
+				OpcodeBlock, ValueTypeI32,
+				OpcodeTryTable, 0x40, 1, TryTableCatchKindCatchRef, 0, 0, OpcodeEnd,
+				OpcodeLocalGet, 0,
+				OpcodeEnd,
+				OpcodeEnd,
+			},
+			features:    api.CoreFeatureExceptionHandling,
+			expectedErr: "invalid catch[0] of try_table: type mismatch: label expects 1 values but 2 are passed",
+		},
+		{
+			name: "try_table catch_all_ref type mismatch",
+			body: []byte{
+				OpcodeBlock, ValueTypeI32,
+				OpcodeTryTable, 0x40, 1, TryTableCatchKindCatchAllRef, 0, OpcodeEnd,
+				OpcodeLocalGet, 0,
+				OpcodeEnd,
+				OpcodeEnd,
+			},
+			features:    api.CoreFeatureExceptionHandling,
+			expectedErr: "invalid catch[0] of try_table: type mismatch: label expects i32 but exnref is passed",
+		},
+		{
+			name:        "throw_ref type mismatch",
+			body:        []byte{OpcodeLocalGet, 0, OpcodeThrowRef, OpcodeEnd},
+			features:    api.CoreFeatureExceptionHandling,
+			expectedErr: "cannot pop the operand for throw_ref: type mismatch: expected exnref, but was i32",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+
+		t.Run(tc.name, func(t *testing.T) {
+			m := &Module{
+				TypeSection:     []FunctionType{i32_i32, i32_v},
+				FunctionSection: []Index{0},
+				TagSection:      []Tag{{Type: 1}},
+				CodeSection:     []Code{{Body: tc.body}},
+			}
+			err := m.validateFunction(&stacks{}, tc.features,
+				0, []Index{0}, nil, nil, nil, nil, bytes.NewReader(nil))
+			if tc.expectedErr != "" {
+				require.EqualError(t, err, tc.expectedErr)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestModule_funcValidation_FunctionReferences(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        []byte
+		features    api.CoreFeatures
+		expectedErr string
+	}{
+		{
+			name:     "call_ref",
+			body:     []byte{OpcodeLocalGet, 0, OpcodeRefFunc, 0, OpcodeCallRef, 0, OpcodeEnd},
+			features: api.CoreFeaturesV2 | api.CoreFeatureFunctionReferences,
+		},
+		{
+			name: "ref.as_non_null",
+			body: []byte{
+				OpcodeRefNull, RefTypeFuncref, OpcodeRefAsNonNull, OpcodeDrop, OpcodeLocalGet, 0,
+				OpcodeEnd,
+			},
+			features: api.CoreFeaturesV2 | api.CoreFeatureFunctionReferences,
+		},
+		{
+			name: "br_on_null",
+			body: []byte{
+				OpcodeBlock, ValueTypeI32,
+				OpcodeLocalGet, 0, OpcodeRefFunc, 0, OpcodeBrOnNull, 0,
+				OpcodeCallRef, 0,
+				OpcodeEnd,
+				OpcodeEnd,
+			},
+			features: api.CoreFeaturesV2 | api.CoreFeatureFunctionReferences,
+		},
+		{
+			name: "br_on_non_null",
+			body: []byte{
+				OpcodeLocalGet, 0,
+				OpcodeBlock, RefTypePrefixNonNullable, 0,
+				OpcodeRefFunc, 0, OpcodeBrOnNonNull, 0,
+				OpcodeLocalGet, 0, OpcodeReturn,
+				OpcodeEnd,
+				OpcodeCallRef, 0,
+				OpcodeEnd,
+			},
+			features: api.CoreFeaturesV2 | api.CoreFeatureFunctionReferences,
+		},
+		{
+			name:        "call_ref disabled",
+			body:        []byte{OpcodeLocalGet, 0, OpcodeRefFunc, 0, OpcodeCallRef, 0, OpcodeEnd},
+			features:    api.CoreFeaturesV2,
+			expectedErr: "call_ref invalid as feature \"function-references\" is disabled",
+		},
+		{
+			name:        "ref.as_non_null disabled",
+			body:        []byte{OpcodeRefNull, RefTypeFuncref, OpcodeRefAsNonNull, OpcodeDrop, OpcodeLocalGet, 0, OpcodeEnd},
+			features:    api.CoreFeaturesV2,
+			expectedErr: "ref.as_non_null invalid as feature \"function-references\" is disabled",
+		},
+		{
+			name:        "typed block disabled",
+			body:        []byte{OpcodeBlock, RefTypePrefixNullable, 0, OpcodeRefFunc, 0, OpcodeEnd, OpcodeDrop, OpcodeLocalGet, 0, OpcodeEnd},
+			features:    api.CoreFeaturesV2,
+			expectedErr: "read block: block with typed reference result invalid as feature \"function-references\" is disabled",
+		},
+		{
+			name:        "call_ref on non reference",
+			body:        []byte{OpcodeLocalGet, 0, OpcodeLocalGet, 0, OpcodeCallRef, 0, OpcodeEnd},
+			features:    api.CoreFeaturesV2 | api.CoreFeatureFunctionReferences,
+			expectedErr: "cannot pop the function reference for call_ref: type mismatch: expected funcref, but was i32",
+		},
+		{
+			name:        "ref.as_non_null on non reference",
+			body:        []byte{OpcodeLocalGet, 0, OpcodeRefAsNonNull, OpcodeEnd},
+			features:    api.CoreFeaturesV2 | api.CoreFeatureFunctionReferences,
+			expectedErr: "type mismatch: expected reference type but was i32",
+		},
+		{
+			name:        "br_on_non_null target without reference",
+			body:        []byte{OpcodeBlock, ValueTypeI32, OpcodeRefFunc, 0, OpcodeBrOnNonNull, 0, OpcodeLocalGet, 0, OpcodeEnd, OpcodeEnd},
+			features:    api.CoreFeaturesV2 | api.CoreFeatureFunctionReferences,
+			expectedErr: "type mismatch on br_on_non_null: label doesn't take funcref as its last value",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+
+		t.Run(tc.name, func(t *testing.T) {
+			m := &Module{
+				TypeSection:     []FunctionType{i32_i32},
+				FunctionSection: []Index{0},
+				CodeSection:     []Code{{Body: tc.body}},
+			}
+			err := m.validateFunction(&stacks{}, tc.features,
+				0, []Index{0}, nil, nil, nil, map[Index]struct{}{0: {}}, bytes.NewReader(nil))
+			if tc.expectedErr != "" {
+				require.EqualError(t, err, tc.expectedErr)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestModule_funcValidation_RelaxedSIMD(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        []byte
+		features    api.CoreFeatures
+		expectedErr string
+	}{
+		{
+			name: "f32x4.relaxed_madd",
+			body: []byte{
+				OpcodeLocalGet, 0, OpcodeLocalGet, 1, OpcodeLocalGet, 2,
+				OpcodeVecPrefix, OpcodeVecF32x4RelaxedMadd, OpcodeVecRelaxedSuffix,
+				OpcodeEnd,
+			},
+			features: api.CoreFeaturesV2 | api.CoreFeatureRelaxedSIMD,
+		},
+		{
+			name: "i16x8.relaxed_dot_i8x16_i7x16_s",
+			body: []byte{
+				OpcodeLocalGet, 0, OpcodeLocalGet, 1,
+				OpcodeVecPrefix, OpcodeVecI16x8RelaxedDotI8x16I7x16S, OpcodeVecRelaxedSuffix,
+				OpcodeEnd,
+			},
+			features: api.CoreFeaturesV2 | api.CoreFeatureRelaxedSIMD,
+		},
+		{
+			name: "i32x4.relaxed_trunc_f32x4_s",
+			body: []byte{
+				OpcodeLocalGet, 0,
+				OpcodeVecPrefix, OpcodeVecI32x4RelaxedTruncF32x4S, OpcodeVecRelaxedSuffix,
+				OpcodeEnd,
+			},
+			features: api.CoreFeaturesV2 | api.CoreFeatureRelaxedSIMD,
+		},
+		{
+			// i16x8.abs is 0x80 0x01 in LEB128, whereas i8x16.relaxed_swizzle is 0x80 0x02.
+			name: "i16x8.abs",
+			body: []byte{
+				OpcodeLocalGet, 0,
+				OpcodeVecPrefix, OpcodeVecI16x8Abs, 0x01,
+				OpcodeEnd,
+			},
+			features: api.CoreFeaturesV2,
+		},
+		{
+			name: "relaxed-simd disabled",
+			body: []byte{
+				OpcodeLocalGet, 0, OpcodeLocalGet, 1, OpcodeLocalGet, 2,
+				OpcodeVecPrefix, OpcodeVecF32x4RelaxedMadd, OpcodeVecRelaxedSuffix,
+				OpcodeEnd,
+			},
+			features:    api.CoreFeaturesV2,
+			expectedErr: "f32x4.relaxed_madd invalid as feature \"relaxed-simd\" is disabled",
+		},
+		{
+			name: "simd disabled",
+			body: []byte{
+				OpcodeLocalGet, 0, OpcodeLocalGet, 1,
+				OpcodeVecPrefix, OpcodeVecI8x16RelaxedSwizzle, OpcodeVecRelaxedSuffix,
+				OpcodeEnd,
+			},
+			features:    api.CoreFeaturesV1 | api.CoreFeatureRelaxedSIMD,
+			expectedErr: "i8x16.relaxed_swizzle invalid as feature \"simd\" is disabled",
+		},
+		{
+			name: "type mismatch",
+			body: []byte{
+				OpcodeLocalGet, 0, OpcodeLocalGet, 1, OpcodeI32Const, 0,
+				OpcodeVecPrefix, OpcodeVecI32x4RelaxedLaneselect, OpcodeVecRelaxedSuffix,
+				OpcodeEnd,
+			},
+			features:    api.CoreFeaturesV2 | api.CoreFeatureRelaxedSIMD,
+			expectedErr: "cannot pop the operand for i32x4.relaxed_laneselect: type mismatch: expected v128, but was i32",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+
+		t.Run(tc.name, func(t *testing.T) {
+			m := &Module{
+				TypeSection: []FunctionType{{
+					Params:  []ValueType{ValueTypeV128, ValueTypeV128, ValueTypeV128},
+					Results: []ValueType{ValueTypeV128},
+				}},
+				FunctionSection: []Index{0},
+				CodeSection:     []Code{{Body: tc.body}},
+			}
+			err := m.validateFunction(&stacks{}, tc.features,
+				0, []Index{0}, nil, nil, nil, nil, bytes.NewReader(nil))
+			if tc.expectedErr != "" {
+				require.EqualError(t, err, tc.expectedErr)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestModule_funcValidation_Memory64(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        []byte
+		is64        bool
+		expectedErr string
+	}{
+		{
+			name: "i32.load with i64 address",
+			body: []byte{OpcodeI64Const, 0, OpcodeI32Load, 0x2, 0x0, OpcodeDrop, OpcodeEnd},
+			is64: true,
+		},
+		{
+			name: "i64.store with i64 address and 64-bit offset",
+			body: []byte{
+				OpcodeI64Const, 0, OpcodeI64Const, 1,
+				OpcodeI64Store, 0x3, 0x80, 0x80, 0x80, 0x80, 0x10, // offset=1<<32
+				OpcodeEnd,
+			},
+			is64: true,
+		},
+		{
+			name: "memory.size and memory.grow",
+			body: []byte{OpcodeMemorySize, 0, OpcodeMemoryGrow, 0, OpcodeDrop, OpcodeEnd},
+			is64: true,
+		},
+		{
+			name: "memory.fill",
+			body: []byte{
+				OpcodeI64Const, 0, OpcodeI32Const, 0, OpcodeI64Const, 0,
+				OpcodeMiscPrefix, OpcodeMiscMemoryFill, 0, OpcodeEnd,
+			},
+			is64: true,
+		},
+		{
+			name: "memory.copy",
+			body: []byte{
+				OpcodeI64Const, 0, OpcodeI64Const, 0, OpcodeI64Const, 0,
+				OpcodeMiscPrefix, OpcodeMiscMemoryCopy, 0, 0, OpcodeEnd,
+			},
+			is64: true,
+		},
+		{
+			name: "memory.init",
+			body: []byte{
+				OpcodeI64Const, 0, OpcodeI32Const, 0, OpcodeI32Const, 0,
+				OpcodeMiscPrefix, OpcodeMiscMemoryInit, 0, 0, OpcodeEnd,
+			},
+			is64: true,
+		},
+		{
+			name: "i32.atomic.rmw.add with i64 address",
+			body: []byte{
+				OpcodeI64Const, 0, OpcodeI32Const, 1,
+				OpcodeAtomicPrefix, OpcodeAtomicI32RmwAdd, 0x2, 0x0, OpcodeDrop, OpcodeEnd,
+			},
+			is64: true,
+		},
+		{
+			name:        "i32 address in 64-bit memory",
+			body:        []byte{OpcodeI32Const, 0, OpcodeI32Load, 0x2, 0x0, OpcodeDrop, OpcodeEnd},
+			is64:        true,
+			expectedErr: "type mismatch: expected i64, but was i32",
+		},
+		{
+			name:        "i64 address in 32-bit memory",
+			body:        []byte{OpcodeI64Const, 0, OpcodeI32Load, 0x2, 0x0, OpcodeDrop, OpcodeEnd},
+			expectedErr: "type mismatch: expected i32, but was i64",
+		},
+		{
+			name: "64-bit offset in 32-bit memory",
+			body: []byte{
+				OpcodeI32Const, 0,
+				OpcodeI32Load, 0x2, 0x80, 0x80, 0x80, 0x80, 0x10, // offset=1<<32
+				OpcodeDrop, OpcodeEnd,
+			},
+			expectedErr: "read memory offset: overflows a 32-bit integer",
+		},
+		{
+			name: "memory.copy from 32-bit memory",
+			body: []byte{
+				OpcodeI64Const, 0, OpcodeI32Const, 0, OpcodeI32Const, 0,
+				OpcodeMiscPrefix, OpcodeMiscMemoryCopy, 0, 1, OpcodeEnd,
+			},
+			is64: true,
+		},
+		{
+			name:        "memory.grow with i32 delta in 64-bit memory",
+			body:        []byte{OpcodeI32Const, 1, OpcodeMemoryGrow, 0, OpcodeDrop, OpcodeEnd},
+			is64:        true,
+			expectedErr: "type mismatch: expected i64, but was i32",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			m := &Module{
+				TypeSection:      []FunctionType{v_v},
+				FunctionSection:  []Index{0},
+				CodeSection:      []Code{{Body: tc.body}},
+				DataSection:      []DataSegment{{}},
+				DataCountSection: &[]uint32{1}[0],
+			}
+			memories := []*Memory{{Min: 1, Max: 1, IsShared: true, Is64: tc.is64}, {Min: 1}}
+			err := m.validateFunction(&stacks{}, api.CoreFeaturesV2|api.CoreFeatureThreads|api.CoreFeatureMultiMemory|api.CoreFeatureMemory64,
+				0, []Index{0}, nil, memories, nil, nil, bytes.NewReader(nil))
+			if tc.expectedErr != "" {
+				require.EqualError(t, err, tc.expectedErr)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
 func TestDecodeBlockType(t *testing.T) {
 	t.Run("primitive", func(t *testing.T) {
 		for _, tc := range []struct {
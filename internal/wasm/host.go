@@ -1,10 +1,12 @@
 package wasm
 
 import (
+	"encoding/binary"
 	"errors"
 	"fmt"
 
 	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/leb128"
 	"github.com/tetratelabs/wazero/internal/wasmdebug"
 )
 
@@ -44,11 +46,56 @@ func (f *HostFunc) WithGoModuleFunc(fn api.GoModuleFunc) *HostFunc {
 	return &ret
 }
 
+// HostExterns are the memory, globals and tables defined by a host module, used for NewHostModule.
+type HostExterns struct {
+	// Memory is the possibly nil memory of the host module.
+	Memory *HostMemory
+
+	// Globals are exported in order, so the first has index zero.
+	Globals []*HostGlobal
+
+	// Tables are exported in order, so the first has index zero.
+	Tables []*HostTable
+}
+
+// HostMemory is a memory defined in Go, used for NewHostModule.
+type HostMemory struct {
+	// ExportName is the name the memory is exported as.
+	ExportName string
+
+	// Memory is the limits of the memory, which must already be validated.
+	Memory *Memory
+}
+
+// HostGlobal is a global defined in Go, used for NewHostModule.
+type HostGlobal struct {
+	// ExportName is the name the global is exported as.
+	ExportName string
+
+	// Type is the value type and mutability of the global.
+	Type GlobalType
+
+	// Init is the initial value, encoded the same as api.Global Get.
+	Init uint64
+}
+
+// HostTable is a table defined in Go, used for NewHostModule.
+type HostTable struct {
+	// ExportName is the name the table is exported as.
+	ExportName string
+
+	// Table is the limits and element type of the table.
+	Table Table
+}
+
 // NewHostModule is defined internally for use in WASI tests and to keep the code size in the root directory small.
+//
+// externs is nil unless the host module defines a memory, globals or tables.
 func NewHostModule(
 	moduleName string,
 	exportNames []string,
 	nameToHostFunc map[string]*HostFunc,
+	externs *HostExterns,
 	enabledFeatures api.CoreFeatures,
 ) (m *Module, err error) {
 	if moduleName != "" {
@@ -57,11 +104,26 @@ func NewHostModule(
 		return nil, errors.New("a module name must not be empty")
 	}
 
-	if exportCount := uint32(len(nameToHostFunc)); exportCount > 0 {
+	exportCount := uint32(len(nameToHostFunc))
+	if externs != nil {
+		if externs.Memory != nil {
+			exportCount++
+		}
+		exportCount += uint32(len(externs.Globals) + len(externs.Tables))
+	}
+
+	if exportCount > 0 {
 		m.ExportSection = make([]Export, 0, exportCount)
 		m.Exports = make(map[string]*Export, exportCount)
-		if err = addFuncs(m, exportNames, nameToHostFunc, enabledFeatures); err != nil {
-			return
+		if len(nameToHostFunc) > 0 {
+			if err = addFuncs(m, exportNames, nameToHostFunc, enabledFeatures); err != nil {
+				return
+			}
+		}
+		if externs != nil {
+			if err = addExterns(m, externs, enabledFeatures); err != nil {
+				return
+			}
 		}
 	}
 
@@ -72,7 +134,7 @@ func NewHostModule(
 	// compilation of host modules is not costly as it's merely small trampolines vs the real-world native Wasm binary.
 	// TODO: refactor engines so that we can properly cache compiled machine codes for host modules.
 	m.AssignModuleID([]byte(fmt.Sprintf("@@@@@@@@%p", m)), // @@@@@@@@ = any 8 bytes different from Wasm header.
-		false, false)
+		false, false, false, false)
 	return
 }
 
@@ -159,6 +221,78 @@ func addFuncs(
 	return nil
 }
 
+func addExterns(m *Module, externs *HostExterns, enabledFeatures api.CoreFeatures) error {
+	moduleName := m.NameSection.ModuleName
+
+	if hm := externs.Memory; hm != nil {
+		m.MemorySection = []Memory{*hm.Memory}
+		if err := m.addHostExport(ExternTypeMemory, hm.ExportName, 0); err != nil {
+			return fmt.Errorf("memory[%s.%s] %w", moduleName, hm.ExportName, err)
+		}
+	}
+
+	for i, hg := range externs.Globals {
+		init, err := hostGlobalInit(hg.Type.ValType, hg.Init)
+		if err != nil {
+			return fmt.Errorf("global[%s.%s] %w", moduleName, hg.ExportName, err)
+		}
+		m.GlobalSection = append(m.GlobalSection, Global{Type: hg.Type, Init: init})
+		if err = m.addHostExport(ExternTypeGlobal, hg.ExportName, Index(i)); err != nil {
+			return fmt.Errorf("global[%s.%s] %w", moduleName, hg.ExportName, err)
+		}
+	}
+
+	for i, ht := range externs.Tables {
+		if t := ht.Table.Type; t != RefTypeFuncref && t != RefTypeExternref {
+			return fmt.Errorf("table[%s.%s] unsupported ref type %s", moduleName, ht.ExportName, RefTypeName(t))
+		} else if max := ht.Table.Max; max != nil && ht.Table.Min > *max {
+			return fmt.Errorf("table[%s.%s] min %d > max %d", moduleName, ht.ExportName, ht.Table.Min, *max)
+		}
+		if i > 0 || ht.Table.Type != RefTypeFuncref {
+			// Guard >1.0 feature reference-types, which allows multiple and externref tables.
+			if err := enabledFeatures.RequireEnabled(api.CoreFeatureReferenceTypes); err != nil {
+				return fmt.Errorf("table[%s.%s] invalid as %v", moduleName, ht.ExportName, err)
+			}
+		}
+		m.TableSection = append(m.TableSection, ht.Table)
+		if err := m.addHostExport(ExternTypeTable, ht.ExportName, Index(i)); err != nil {
+			return fmt.Errorf("table[%s.%s] %w", moduleName, ht.ExportName, err)
+		}
+	}
+	return nil
+}
+
+// addHostExport adds an export to a host module, failing if the name is already exported.
+func (m *Module) addHostExport(et ExternType, name string, idx Index) error {
+	if _, ok := m.Exports[name]; ok {
+		return fmt.Errorf("duplicates export name %q", name)
+	}
+	m.ExportSection = append(m.ExportSection, Export{Type: et, Name: name, Index: idx})
+	m.Exports[name] = &m.ExportSection[len(m.ExportSection)-1]
+	return nil
+}
+
+// hostGlobalInit returns the constant expression which initializes a global of the given type to v.
+func hostGlobalInit(valType ValueType, v uint64) (ConstantExpression, error) {
+	switch valType {
+	case ValueTypeI32:
+		return ConstantExpression{Opcode: OpcodeI32Const, Data: leb128.EncodeInt32(int32(v))}, nil
+	case ValueTypeI64:
+		return ConstantExpression{Opcode: OpcodeI64Const, Data: leb128.EncodeInt64(int64(v))}, nil
+	case ValueTypeF32:
+		return ConstantExpression{Opcode: OpcodeF32Const, Data: binary.LittleEndian.AppendUint32(nil, uint32(v))}, nil
+	case ValueTypeF64:
+		return ConstantExpression{Opcode: OpcodeF64Const, Data: binary.LittleEndian.AppendUint64(nil, v)}, nil
+	case ValueTypeExternref:
+		if v != 0 {
+			return ConstantExpression{}, errors.New("externref must be initialized to null")
+		}
+		return ConstantExpression{Opcode: OpcodeRefNull, Data: []byte{ValueTypeExternref}}, nil
+	default:
+		return ConstantExpression{}, fmt.Errorf("unsupported value type %s", ValueTypeName(valType))
+	}
+}
+
 func (m *Module) maybeAddType(params, results []ValueType, enabledFeatures api.CoreFeatures) (Index, error) {
 	if len(results) > 1 {
 		// Guard >1.0 feature multi-value
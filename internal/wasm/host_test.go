@@ -23,7 +23,7 @@ func swap(ctx context.Context, x, y uint32) (uint32, uint32) {
 
 func TestNewHostModule(t *testing.T) {
 	t.Run("empty name not allowed", func(t *testing.T) {
-		_, err := NewHostModule("", nil, nil, api.CoreFeaturesV2)
+		_, err := NewHostModule("", nil, nil, nil, api.CoreFeaturesV2)
 		require.Error(t, err)
 	})
 
@@ -117,7 +117,7 @@ func TestNewHostModule(t *testing.T) {
 		tc := tt
 
 		t.Run(tc.name, func(t *testing.T) {
-			m, e := NewHostModule(tc.moduleName, tc.exportNames, tc.nameToHostFunc, api.CoreFeaturesV2)
+			m, e := NewHostModule(tc.moduleName, tc.exportNames, tc.nameToHostFunc, nil, api.CoreFeaturesV2)
 			require.NoError(t, e)
 			requireHostModuleEquals(t, tc.expected, m)
 			require.True(t, m.IsHostModule)
@@ -125,6 +125,38 @@ func TestNewHostModule(t *testing.T) {
 	}
 }
 
+func TestNewHostModule_Externs(t *testing.T) {
+	ten := uint32(10)
+	m, err := NewHostModule("env", nil, nil, &HostExterns{
+		Memory: &HostMemory{ExportName: "memory", Memory: &Memory{Min: 1, Cap: 1, Max: 2, IsMaxEncoded: true}},
+		Globals: []*HostGlobal{
+			{ExportName: "i32", Type: GlobalType{ValType: ValueTypeI32}, Init: api.EncodeI32(-1)},
+			{ExportName: "i64", Type: GlobalType{ValType: ValueTypeI64, Mutable: true}, Init: 2},
+			{ExportName: "f32", Type: GlobalType{ValType: ValueTypeF32}, Init: api.EncodeF32(1.5)},
+			{ExportName: "f64", Type: GlobalType{ValType: ValueTypeF64}, Init: api.EncodeF64(-1.5)},
+			{ExportName: "externref", Type: GlobalType{ValType: ValueTypeExternref}},
+		},
+		Tables: []*HostTable{
+			{ExportName: "funcs", Table: Table{Min: 1, Max: &ten, Type: RefTypeFuncref}},
+			{ExportName: "externs", Table: Table{Type: RefTypeExternref}},
+		},
+	}, api.CoreFeaturesV2)
+	require.NoError(t, err)
+	require.True(t, m.IsHostModule)
+
+	require.Equal(t, []Memory{{Min: 1, Cap: 1, Max: 2, IsMaxEncoded: true}}, m.MemorySection)
+	require.Equal(t, []Table{{Min: 1, Max: &ten, Type: RefTypeFuncref}, {Type: RefTypeExternref}}, m.TableSection)
+	require.Equal(t, 8, len(m.ExportSection))
+	require.Equal(t, &Export{Name: "externs", Type: ExternTypeTable, Index: 1}, m.Exports["externs"])
+
+	// Ensure the initial values round-trip through the constant expressions.
+	for i, expected := range []uint64{api.EncodeU32(0xffffffff), 2, api.EncodeF32(1.5), api.EncodeF64(-1.5), 0} {
+		g := &GlobalInstance{Type: m.GlobalSection[i].Type}
+		g.initialize(nil, &m.GlobalSection[i].Init, nil)
+		require.Equal(t, expected, g.Val)
+	}
+}
+
 func requireHostModuleEquals(t *testing.T, expected, actual *Module) {
 	// `require.Equal(t, expected, actual)` fails reflect pointers don't match, so brute compare:
 	require.Equal(t, expected.TypeSection, actual.TypeSection)
@@ -179,7 +211,51 @@ func TestNewHostModule_Errors(t *testing.T) {
 		tc := tt
 
 		t.Run(tc.name, func(t *testing.T) {
-			_, e := NewHostModule(tc.moduleName, tc.exportNames, tc.nameToHostFunc, api.CoreFeaturesV1)
+			_, e := NewHostModule(tc.moduleName, tc.exportNames, tc.nameToHostFunc, nil, api.CoreFeaturesV1)
+			require.EqualError(t, e, tc.expectedErr)
+		})
+	}
+}
+
+func TestNewHostModule_Externs_Errors(t *testing.T) {
+	tests := []struct {
+		name        string
+		externs     *HostExterns
+		expectedErr string
+	}{
+		{
+			name:        "v128 global",
+			externs:     &HostExterns{Globals: []*HostGlobal{{ExportName: "g", Type: GlobalType{ValType: ValueTypeV128}}}},
+			expectedErr: "global[env.g] unsupported value type v128",
+		},
+		{
+			name:        "externref table",
+			externs:     &HostExterns{Tables: []*HostTable{{ExportName: "t", Table: Table{Type: RefTypeExternref}}}},
+			expectedErr: `table[env.t] invalid as feature "reference-types" is disabled`,
+		},
+		{
+			name: "multiple tables",
+			externs: &HostExterns{Tables: []*HostTable{
+				{ExportName: "t1", Table: Table{Type: RefTypeFuncref}},
+				{ExportName: "t2", Table: Table{Type: RefTypeFuncref}},
+			}},
+			expectedErr: `table[env.t2] invalid as feature "reference-types" is disabled`,
+		},
+		{
+			name: "duplicate name",
+			externs: &HostExterns{
+				Memory:  &HostMemory{ExportName: "x", Memory: &Memory{}},
+				Globals: []*HostGlobal{{ExportName: "x", Type: GlobalType{ValType: ValueTypeI32}}},
+			},
+			expectedErr: `global[env.x] duplicates export name "x"`,
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+
+		t.Run(tc.name, func(t *testing.T) {
+			_, e := NewHostModule("env", nil, nil, tc.externs, api.CoreFeaturesV1)
 			require.EqualError(t, e, tc.expectedErr)
 		})
 	}
@@ -20,7 +20,30 @@ const (
 	// OpcodeElse brackets a sequence of instructions enclosed by an OpcodeIf. A branch instruction on a then label
 	// breaks out to after the OpcodeEnd on the enclosing OpcodeIf.
 	OpcodeElse Opcode = 0x05
-	// OpcodeEnd terminates a control instruction OpcodeBlock, OpcodeLoop or OpcodeIf.
+
+	// Below are toggled with CoreFeatureExceptionHandling
+
+	// OpcodeTry brackets a sequence of instructions like OpcodeBlock, whose exceptions are handled by the following
+	// OpcodeCatch and OpcodeCatchAll, or delegated to an outer label by OpcodeDelegate.
+	OpcodeTry Opcode = 0x06
+	// OpcodeCatch handles exceptions thrown with a tag inside the enclosing OpcodeTry, pushing the values of the
+	// exception onto the stack.
+	OpcodeCatch Opcode = 0x07
+	// OpcodeThrow throws an exception with a tag, whose values are popped from the stack.
+	OpcodeThrow Opcode = 0x08
+	// OpcodeRethrow throws the exception caught by an enclosing OpcodeCatch or OpcodeCatchAll again.
+	OpcodeRethrow Opcode = 0x09
+	// OpcodeDelegate terminates an OpcodeTry without handlers, delegating its exceptions to the handlers of a label.
+	OpcodeDelegate Opcode = 0x18
+	// OpcodeCatchAll handles any exception thrown inside the enclosing OpcodeTry.
+	OpcodeCatchAll Opcode = 0x19
+	// OpcodeThrowRef throws the exception referenced by the ValueTypeExnref popped from the stack, trapping if null.
+	OpcodeThrowRef Opcode = 0x0a
+	// OpcodeTryTable brackets a sequence of instructions like OpcodeBlock, whose exceptions are handled by branching
+	// to the labels of its catch clauses. See TryTableCatchKind.
+	OpcodeTryTable Opcode = 0x1f
+
+	// OpcodeEnd terminates a control instruction OpcodeBlock, OpcodeLoop, OpcodeIf, OpcodeTry or OpcodeTryTable.
 	OpcodeEnd Opcode = 0x0b
 
 	// OpcodeBr is a stack-polymorphic opcode that performs an unconditional branch. How the stack is modified depends
@@ -43,6 +66,19 @@ const (
 	OpcodeCall         Opcode = 0x10
 	OpcodeCallIndirect Opcode = 0x11
 
+	// Below are toggled with CoreFeatureTailCall
+
+	// OpcodeReturnCall is like OpcodeCall followed by OpcodeReturn, except the callee reuses the frame of the caller.
+	OpcodeReturnCall Opcode = 0x12
+	// OpcodeReturnCallIndirect is like OpcodeCallIndirect followed by OpcodeReturn, except the callee reuses the
+	// frame of the caller.
+	OpcodeReturnCallIndirect Opcode = 0x13
+
+	// Below are toggled with CoreFeatureFunctionReferences
+
+	// OpcodeCallRef calls the function reference popped from the stack, whose type equals the immediate type index.
+	OpcodeCallRef Opcode = 0x14
+
 	// parametric instructions
 
 	OpcodeDrop        Opcode = 0x1a
@@ -251,6 +287,17 @@ const (
 	// Currently, this is only supported in the constant expression in element segments.
 	OpcodeRefFunc = 0xd2
 
+	// Below are toggled with CoreFeatureFunctionReferences
+
+	// OpcodeRefAsNonNull traps if the reference on the stack is null, and leaves it on the stack otherwise.
+	OpcodeRefAsNonNull Opcode = 0xd4
+	// OpcodeBrOnNull branches to the label if the reference on the stack is null, popping it. Otherwise, the
+	// reference is left on the stack.
+	OpcodeBrOnNull Opcode = 0xd5
+	// OpcodeBrOnNonNull branches to the label with the reference on the stack if it is not null. Otherwise, the
+	// reference is popped.
+	OpcodeBrOnNonNull Opcode = 0xd6
+
 	// Below are toggled with CoreFeatureSignExtensionOps
 
 	// OpcodeI32Extend8S extends a signed 8-bit integer to a 32-bit integer.
@@ -281,6 +328,10 @@ const (
 	// OpcodeVecPrefix is the prefix of all vector isntructions introduced in
 	// CoreFeatureSIMD.
 	OpcodeVecPrefix Opcode = 0xfd
+
+	// OpcodeAtomicPrefix is the prefix of all atomic instructions introduced in
+	// CoreFeatureThreads.
+	OpcodeAtomicPrefix Opcode = 0xfe
 )
 
 // OpcodeMisc represents opcodes of the miscellaneous operations.
@@ -320,6 +371,111 @@ const (
 	OpcodeMiscTableFill OpcodeMisc = 0x11
 )
 
+// OpcodeAtomic represents an opcode of atomic instructions which has
+// multi-byte encoding and is prefixed by OpcodeAtomicPrefix.
+//
+// These opcodes are toggled with CoreFeatureThreads.
+type OpcodeAtomic = byte
+
+const (
+	// Below are wait and notify instructions, and the fence.
+
+	OpcodeAtomicMemoryNotify OpcodeAtomic = 0x00
+	OpcodeAtomicMemoryWait32 OpcodeAtomic = 0x01
+	OpcodeAtomicMemoryWait64 OpcodeAtomic = 0x02
+	OpcodeAtomicFence        OpcodeAtomic = 0x03
+
+	// Below are atomic loads.
+
+	OpcodeAtomicI32Load    OpcodeAtomic = 0x10
+	OpcodeAtomicI64Load    OpcodeAtomic = 0x11
+	OpcodeAtomicI32Load8U  OpcodeAtomic = 0x12
+	OpcodeAtomicI32Load16U OpcodeAtomic = 0x13
+	OpcodeAtomicI64Load8U  OpcodeAtomic = 0x14
+	OpcodeAtomicI64Load16U OpcodeAtomic = 0x15
+	OpcodeAtomicI64Load32U OpcodeAtomic = 0x16
+
+	// Below are atomic stores.
+
+	OpcodeAtomicI32Store   OpcodeAtomic = 0x17
+	OpcodeAtomicI64Store   OpcodeAtomic = 0x18
+	OpcodeAtomicI32Store8  OpcodeAtomic = 0x19
+	OpcodeAtomicI32Store16 OpcodeAtomic = 0x1a
+	OpcodeAtomicI64Store8  OpcodeAtomic = 0x1b
+	OpcodeAtomicI64Store16 OpcodeAtomic = 0x1c
+	OpcodeAtomicI64Store32 OpcodeAtomic = 0x1d
+
+	// Below are atomic read-modify-write instructions of add.
+
+	OpcodeAtomicI32RmwAdd    OpcodeAtomic = 0x1e
+	OpcodeAtomicI64RmwAdd    OpcodeAtomic = 0x1f
+	OpcodeAtomicI32Rmw8AddU  OpcodeAtomic = 0x20
+	OpcodeAtomicI32Rmw16AddU OpcodeAtomic = 0x21
+	OpcodeAtomicI64Rmw8AddU  OpcodeAtomic = 0x22
+	OpcodeAtomicI64Rmw16AddU OpcodeAtomic = 0x23
+	OpcodeAtomicI64Rmw32AddU OpcodeAtomic = 0x24
+
+	// Below are atomic read-modify-write instructions of sub.
+
+	OpcodeAtomicI32RmwSub    OpcodeAtomic = 0x25
+	OpcodeAtomicI64RmwSub    OpcodeAtomic = 0x26
+	OpcodeAtomicI32Rmw8SubU  OpcodeAtomic = 0x27
+	OpcodeAtomicI32Rmw16SubU OpcodeAtomic = 0x28
+	OpcodeAtomicI64Rmw8SubU  OpcodeAtomic = 0x29
+	OpcodeAtomicI64Rmw16SubU OpcodeAtomic = 0x2a
+	OpcodeAtomicI64Rmw32SubU OpcodeAtomic = 0x2b
+
+	// Below are atomic read-modify-write instructions of and.
+
+	OpcodeAtomicI32RmwAnd    OpcodeAtomic = 0x2c
+	OpcodeAtomicI64RmwAnd    OpcodeAtomic = 0x2d
+	OpcodeAtomicI32Rmw8AndU  OpcodeAtomic = 0x2e
+	OpcodeAtomicI32Rmw16AndU OpcodeAtomic = 0x2f
+	OpcodeAtomicI64Rmw8AndU  OpcodeAtomic = 0x30
+	OpcodeAtomicI64Rmw16AndU OpcodeAtomic = 0x31
+	OpcodeAtomicI64Rmw32AndU OpcodeAtomic = 0x32
+
+	// Below are atomic read-modify-write instructions of or.
+
+	OpcodeAtomicI32RmwOr    OpcodeAtomic = 0x33
+	OpcodeAtomicI64RmwOr    OpcodeAtomic = 0x34
+	OpcodeAtomicI32Rmw8OrU  OpcodeAtomic = 0x35
+	OpcodeAtomicI32Rmw16OrU OpcodeAtomic = 0x36
+	OpcodeAtomicI64Rmw8OrU  OpcodeAtomic = 0x37
+	OpcodeAtomicI64Rmw16OrU OpcodeAtomic = 0x38
+	OpcodeAtomicI64Rmw32OrU OpcodeAtomic = 0x39
+
+	// Below are atomic read-modify-write instructions of xor.
+
+	OpcodeAtomicI32RmwXor    OpcodeAtomic = 0x3a
+	OpcodeAtomicI64RmwXor    OpcodeAtomic = 0x3b
+	OpcodeAtomicI32Rmw8XorU  OpcodeAtomic = 0x3c
+	OpcodeAtomicI32Rmw16XorU OpcodeAtomic = 0x3d
+	OpcodeAtomicI64Rmw8XorU  OpcodeAtomic = 0x3e
+	OpcodeAtomicI64Rmw16XorU OpcodeAtomic = 0x3f
+	OpcodeAtomicI64Rmw32XorU OpcodeAtomic = 0x40
+
+	// Below are atomic read-modify-write instructions of xchg.
+
+	OpcodeAtomicI32RmwXchg    OpcodeAtomic = 0x41
+	OpcodeAtomicI64RmwXchg    OpcodeAtomic = 0x42
+	OpcodeAtomicI32Rmw8XchgU  OpcodeAtomic = 0x43
+	OpcodeAtomicI32Rmw16XchgU OpcodeAtomic = 0x44
+	OpcodeAtomicI64Rmw8XchgU  OpcodeAtomic = 0x45
+	OpcodeAtomicI64Rmw16XchgU OpcodeAtomic = 0x46
+	OpcodeAtomicI64Rmw32XchgU OpcodeAtomic = 0x47
+
+	// Below are atomic read-modify-write instructions of cmpxchg.
+
+	OpcodeAtomicI32RmwCmpxchg    OpcodeAtomic = 0x48
+	OpcodeAtomicI64RmwCmpxchg    OpcodeAtomic = 0x49
+	OpcodeAtomicI32Rmw8CmpxchgU  OpcodeAtomic = 0x4a
+	OpcodeAtomicI32Rmw16CmpxchgU OpcodeAtomic = 0x4b
+	OpcodeAtomicI64Rmw8CmpxchgU  OpcodeAtomic = 0x4c
+	OpcodeAtomicI64Rmw16CmpxchgU OpcodeAtomic = 0x4d
+	OpcodeAtomicI64Rmw32CmpxchgU OpcodeAtomic = 0x4e
+)
+
 // OpcodeVec represents an opcode of a vector instructions which has
 // multi-byte encoding and is prefixed by OpcodeMiscPrefix.
 //
@@ -623,6 +779,50 @@ const (
 	OpcodeVecF64x2PromoteLowF32x4Zero OpcodeVec = 0x5f
 )
 
+// OpcodeVecRelaxed represents an opcode of a relaxed vector instruction from the relaxed-simd proposal, which
+// has OpcodeVecPrefix.
+//
+// Relaxed opcodes are 0x100 to 0x113, so their LEB128 encoding is two bytes: the value here, followed by
+// OpcodeVecRelaxedSuffix.
+//
+// See https://github.com/WebAssembly/relaxed-simd/blob/main/proposals/relaxed-simd/Overview.md#binary-format
+type OpcodeVecRelaxed = byte
+
+// OpcodeVecRelaxedSuffix is the second byte of the LEB128 encoding of all OpcodeVecRelaxed. Non-relaxed vector
+// opcodes which are larger than 0x7f are followed by 0x01 instead.
+const OpcodeVecRelaxedSuffix byte = 0x02
+
+const (
+	OpcodeVecI8x16RelaxedSwizzle           OpcodeVecRelaxed = 0x80
+	OpcodeVecI32x4RelaxedTruncF32x4S       OpcodeVecRelaxed = 0x81
+	OpcodeVecI32x4RelaxedTruncF32x4U       OpcodeVecRelaxed = 0x82
+	OpcodeVecI32x4RelaxedTruncF64x2SZero   OpcodeVecRelaxed = 0x83
+	OpcodeVecI32x4RelaxedTruncF64x2UZero   OpcodeVecRelaxed = 0x84
+	OpcodeVecF32x4RelaxedMadd              OpcodeVecRelaxed = 0x85
+	OpcodeVecF32x4RelaxedNmadd             OpcodeVecRelaxed = 0x86
+	OpcodeVecF64x2RelaxedMadd              OpcodeVecRelaxed = 0x87
+	OpcodeVecF64x2RelaxedNmadd             OpcodeVecRelaxed = 0x88
+	OpcodeVecI8x16RelaxedLaneselect        OpcodeVecRelaxed = 0x89
+	OpcodeVecI16x8RelaxedLaneselect        OpcodeVecRelaxed = 0x8a
+	OpcodeVecI32x4RelaxedLaneselect        OpcodeVecRelaxed = 0x8b
+	OpcodeVecI64x2RelaxedLaneselect        OpcodeVecRelaxed = 0x8c
+	OpcodeVecF32x4RelaxedMin               OpcodeVecRelaxed = 0x8d
+	OpcodeVecF32x4RelaxedMax               OpcodeVecRelaxed = 0x8e
+	OpcodeVecF64x2RelaxedMin               OpcodeVecRelaxed = 0x8f
+	OpcodeVecF64x2RelaxedMax               OpcodeVecRelaxed = 0x90
+	OpcodeVecI16x8RelaxedQ15mulrS          OpcodeVecRelaxed = 0x91
+	OpcodeVecI16x8RelaxedDotI8x16I7x16S    OpcodeVecRelaxed = 0x92
+	OpcodeVecI32x4RelaxedDotI8x16I7x16AddS OpcodeVecRelaxed = 0x93
+)
+
+// IsVecRelaxed returns true if body[pc:] starts with a relaxed vector opcode, where pc points to the byte right after
+// OpcodeVecPrefix.
+func IsVecRelaxed(body []byte, pc uint64) bool {
+	return pc+1 < uint64(len(body)) &&
+		OpcodeVecI8x16RelaxedSwizzle <= body[pc] && body[pc] <= OpcodeVecI32x4RelaxedDotI8x16I7x16AddS &&
+		body[pc+1] == OpcodeVecRelaxedSuffix
+}
+
 const (
 	OpcodeUnreachableName       = "unreachable"
 	OpcodeNopName               = "nop"
@@ -813,8 +1013,26 @@ const (
 	OpcodeI64Extend16SName = "i64.extend16_s"
 	OpcodeI64Extend32SName = "i64.extend32_s"
 
-	OpcodeMiscPrefixName = "misc_prefix"
-	OpcodeVecPrefixName  = "vector_prefix"
+	OpcodeMiscPrefixName   = "misc_prefix"
+	OpcodeVecPrefixName    = "vector_prefix"
+	OpcodeAtomicPrefixName = "atomic_prefix"
+
+	OpcodeReturnCallName         = "return_call"
+	OpcodeReturnCallIndirectName = "return_call_indirect"
+
+	OpcodeTryName      = "try"
+	OpcodeCatchName    = "catch"
+	OpcodeThrowName    = "throw"
+	OpcodeRethrowName  = "rethrow"
+	OpcodeDelegateName = "delegate"
+	OpcodeCatchAllName = "catch_all"
+	OpcodeThrowRefName = "throw_ref"
+	OpcodeTryTableName = "try_table"
+
+	OpcodeCallRefName      = "call_ref"
+	OpcodeRefAsNonNullName = "ref.as_non_null"
+	OpcodeBrOnNullName     = "br_on_null"
+	OpcodeBrOnNonNullName  = "br_on_non_null"
 )
 
 var instructionNames = [256]string{
@@ -1007,8 +1225,32 @@ var instructionNames = [256]string{
 	OpcodeI64Extend16S: OpcodeI64Extend16SName,
 	OpcodeI64Extend32S: OpcodeI64Extend32SName,
 
-	OpcodeMiscPrefix: OpcodeMiscPrefixName,
-	OpcodeVecPrefix:  OpcodeVecPrefixName,
+	OpcodeMiscPrefix:   OpcodeMiscPrefixName,
+	OpcodeVecPrefix:    OpcodeVecPrefixName,
+	OpcodeAtomicPrefix: OpcodeAtomicPrefixName,
+
+	// Below are toggled with CoreFeatureTailCall
+
+	OpcodeReturnCall:         OpcodeReturnCallName,
+	OpcodeReturnCallIndirect: OpcodeReturnCallIndirectName,
+
+	// Below are toggled with CoreFeatureExceptionHandling
+
+	OpcodeTry:      OpcodeTryName,
+	OpcodeCatch:    OpcodeCatchName,
+	OpcodeThrow:    OpcodeThrowName,
+	OpcodeRethrow:  OpcodeRethrowName,
+	OpcodeDelegate: OpcodeDelegateName,
+	OpcodeCatchAll: OpcodeCatchAllName,
+	OpcodeThrowRef: OpcodeThrowRefName,
+	OpcodeTryTable: OpcodeTryTableName,
+
+	// Below are toggled with CoreFeatureFunctionReferences
+
+	OpcodeCallRef:      OpcodeCallRefName,
+	OpcodeRefAsNonNull: OpcodeRefAsNonNullName,
+	OpcodeBrOnNull:     OpcodeBrOnNullName,
+	OpcodeBrOnNonNull:  OpcodeBrOnNonNullName,
 }
 
 // InstructionName returns the instruction corresponding to this binary Opcode.
@@ -1017,6 +1259,18 @@ func InstructionName(oc Opcode) string {
 	return instructionNames[oc]
 }
 
+// ConsumesFuel returns false for the instructions which only delimit blocks, and true for all the others, including
+// prefixed ones. Engines metering fuel consume one unit per executed instruction which consumes fuel, so that the
+// fuel consumed by a call is the same for all engines.
+func ConsumesFuel(oc Opcode) bool {
+	switch oc {
+	case OpcodeNop, OpcodeBlock, OpcodeLoop, OpcodeElse, OpcodeEnd,
+		OpcodeTry, OpcodeCatch, OpcodeCatchAll, OpcodeDelegate, OpcodeTryTable:
+		return false
+	}
+	return true
+}
+
 const (
 	OpcodeI32TruncSatF32SName = "i32.trunc_sat_f32_s"
 	OpcodeI32TruncSatF32UName = "i32.trunc_sat_f32_u"
@@ -1548,3 +1802,217 @@ var vectorInstructionName = map[OpcodeVec]string{
 func VectorInstructionName(oc OpcodeVec) (ret string) {
 	return vectorInstructionName[oc]
 }
+
+const (
+	OpcodeVecI8x16RelaxedSwizzleName           = "i8x16.relaxed_swizzle"
+	OpcodeVecI32x4RelaxedTruncF32x4SName       = "i32x4.relaxed_trunc_f32x4_s"
+	OpcodeVecI32x4RelaxedTruncF32x4UName       = "i32x4.relaxed_trunc_f32x4_u"
+	OpcodeVecI32x4RelaxedTruncF64x2SZeroName   = "i32x4.relaxed_trunc_f64x2_s_zero"
+	OpcodeVecI32x4RelaxedTruncF64x2UZeroName   = "i32x4.relaxed_trunc_f64x2_u_zero"
+	OpcodeVecF32x4RelaxedMaddName              = "f32x4.relaxed_madd"
+	OpcodeVecF32x4RelaxedNmaddName             = "f32x4.relaxed_nmadd"
+	OpcodeVecF64x2RelaxedMaddName              = "f64x2.relaxed_madd"
+	OpcodeVecF64x2RelaxedNmaddName             = "f64x2.relaxed_nmadd"
+	OpcodeVecI8x16RelaxedLaneselectName        = "i8x16.relaxed_laneselect"
+	OpcodeVecI16x8RelaxedLaneselectName        = "i16x8.relaxed_laneselect"
+	OpcodeVecI32x4RelaxedLaneselectName        = "i32x4.relaxed_laneselect"
+	OpcodeVecI64x2RelaxedLaneselectName        = "i64x2.relaxed_laneselect"
+	OpcodeVecF32x4RelaxedMinName               = "f32x4.relaxed_min"
+	OpcodeVecF32x4RelaxedMaxName               = "f32x4.relaxed_max"
+	OpcodeVecF64x2RelaxedMinName               = "f64x2.relaxed_min"
+	OpcodeVecF64x2RelaxedMaxName               = "f64x2.relaxed_max"
+	OpcodeVecI16x8RelaxedQ15mulrSName          = "i16x8.relaxed_q15mulr_s"
+	OpcodeVecI16x8RelaxedDotI8x16I7x16SName    = "i16x8.relaxed_dot_i8x16_i7x16_s"
+	OpcodeVecI32x4RelaxedDotI8x16I7x16AddSName = "i32x4.relaxed_dot_i8x16_i7x16_add_s"
+)
+
+var relaxedVectorInstructionName = map[OpcodeVecRelaxed]string{
+	OpcodeVecI8x16RelaxedSwizzle:           OpcodeVecI8x16RelaxedSwizzleName,
+	OpcodeVecI32x4RelaxedTruncF32x4S:       OpcodeVecI32x4RelaxedTruncF32x4SName,
+	OpcodeVecI32x4RelaxedTruncF32x4U:       OpcodeVecI32x4RelaxedTruncF32x4UName,
+	OpcodeVecI32x4RelaxedTruncF64x2SZero:   OpcodeVecI32x4RelaxedTruncF64x2SZeroName,
+	OpcodeVecI32x4RelaxedTruncF64x2UZero:   OpcodeVecI32x4RelaxedTruncF64x2UZeroName,
+	OpcodeVecF32x4RelaxedMadd:              OpcodeVecF32x4RelaxedMaddName,
+	OpcodeVecF32x4RelaxedNmadd:             OpcodeVecF32x4RelaxedNmaddName,
+	OpcodeVecF64x2RelaxedMadd:              OpcodeVecF64x2RelaxedMaddName,
+	OpcodeVecF64x2RelaxedNmadd:             OpcodeVecF64x2RelaxedNmaddName,
+	OpcodeVecI8x16RelaxedLaneselect:        OpcodeVecI8x16RelaxedLaneselectName,
+	OpcodeVecI16x8RelaxedLaneselect:        OpcodeVecI16x8RelaxedLaneselectName,
+	OpcodeVecI32x4RelaxedLaneselect:        OpcodeVecI32x4RelaxedLaneselectName,
+	OpcodeVecI64x2RelaxedLaneselect:        OpcodeVecI64x2RelaxedLaneselectName,
+	OpcodeVecF32x4RelaxedMin:               OpcodeVecF32x4RelaxedMinName,
+	OpcodeVecF32x4RelaxedMax:               OpcodeVecF32x4RelaxedMaxName,
+	OpcodeVecF64x2RelaxedMin:               OpcodeVecF64x2RelaxedMinName,
+	OpcodeVecF64x2RelaxedMax:               OpcodeVecF64x2RelaxedMaxName,
+	OpcodeVecI16x8RelaxedQ15mulrS:          OpcodeVecI16x8RelaxedQ15mulrSName,
+	OpcodeVecI16x8RelaxedDotI8x16I7x16S:    OpcodeVecI16x8RelaxedDotI8x16I7x16SName,
+	OpcodeVecI32x4RelaxedDotI8x16I7x16AddS: OpcodeVecI32x4RelaxedDotI8x16I7x16AddSName,
+}
+
+// RelaxedVectorInstructionName returns the instruction name corresponding to the relaxed vector Opcode.
+func RelaxedVectorInstructionName(oc OpcodeVecRelaxed) (ret string) {
+	return relaxedVectorInstructionName[oc]
+}
+
+const (
+	OpcodeAtomicMemoryNotifyName = "memory.atomic.notify"
+	OpcodeAtomicMemoryWait32Name = "memory.atomic.wait32"
+	OpcodeAtomicMemoryWait64Name = "memory.atomic.wait64"
+	OpcodeAtomicFenceName        = "atomic.fence"
+
+	OpcodeAtomicI32LoadName    = "i32.atomic.load"
+	OpcodeAtomicI64LoadName    = "i64.atomic.load"
+	OpcodeAtomicI32Load8UName  = "i32.atomic.load8_u"
+	OpcodeAtomicI32Load16UName = "i32.atomic.load16_u"
+	OpcodeAtomicI64Load8UName  = "i64.atomic.load8_u"
+	OpcodeAtomicI64Load16UName = "i64.atomic.load16_u"
+	OpcodeAtomicI64Load32UName = "i64.atomic.load32_u"
+
+	OpcodeAtomicI32StoreName   = "i32.atomic.store"
+	OpcodeAtomicI64StoreName   = "i64.atomic.store"
+	OpcodeAtomicI32Store8Name  = "i32.atomic.store8"
+	OpcodeAtomicI32Store16Name = "i32.atomic.store16"
+	OpcodeAtomicI64Store8Name  = "i64.atomic.store8"
+	OpcodeAtomicI64Store16Name = "i64.atomic.store16"
+	OpcodeAtomicI64Store32Name = "i64.atomic.store32"
+
+	OpcodeAtomicI32RmwAddName    = "i32.atomic.rmw.add"
+	OpcodeAtomicI64RmwAddName    = "i64.atomic.rmw.add"
+	OpcodeAtomicI32Rmw8AddUName  = "i32.atomic.rmw8.add_u"
+	OpcodeAtomicI32Rmw16AddUName = "i32.atomic.rmw16.add_u"
+	OpcodeAtomicI64Rmw8AddUName  = "i64.atomic.rmw8.add_u"
+	OpcodeAtomicI64Rmw16AddUName = "i64.atomic.rmw16.add_u"
+	OpcodeAtomicI64Rmw32AddUName = "i64.atomic.rmw32.add_u"
+
+	OpcodeAtomicI32RmwSubName    = "i32.atomic.rmw.sub"
+	OpcodeAtomicI64RmwSubName    = "i64.atomic.rmw.sub"
+	OpcodeAtomicI32Rmw8SubUName  = "i32.atomic.rmw8.sub_u"
+	OpcodeAtomicI32Rmw16SubUName = "i32.atomic.rmw16.sub_u"
+	OpcodeAtomicI64Rmw8SubUName  = "i64.atomic.rmw8.sub_u"
+	OpcodeAtomicI64Rmw16SubUName = "i64.atomic.rmw16.sub_u"
+	OpcodeAtomicI64Rmw32SubUName = "i64.atomic.rmw32.sub_u"
+
+	OpcodeAtomicI32RmwAndName    = "i32.atomic.rmw.and"
+	OpcodeAtomicI64RmwAndName    = "i64.atomic.rmw.and"
+	OpcodeAtomicI32Rmw8AndUName  = "i32.atomic.rmw8.and_u"
+	OpcodeAtomicI32Rmw16AndUName = "i32.atomic.rmw16.and_u"
+	OpcodeAtomicI64Rmw8AndUName  = "i64.atomic.rmw8.and_u"
+	OpcodeAtomicI64Rmw16AndUName = "i64.atomic.rmw16.and_u"
+	OpcodeAtomicI64Rmw32AndUName = "i64.atomic.rmw32.and_u"
+
+	OpcodeAtomicI32RmwOrName    = "i32.atomic.rmw.or"
+	OpcodeAtomicI64RmwOrName    = "i64.atomic.rmw.or"
+	OpcodeAtomicI32Rmw8OrUName  = "i32.atomic.rmw8.or_u"
+	OpcodeAtomicI32Rmw16OrUName = "i32.atomic.rmw16.or_u"
+	OpcodeAtomicI64Rmw8OrUName  = "i64.atomic.rmw8.or_u"
+	OpcodeAtomicI64Rmw16OrUName = "i64.atomic.rmw16.or_u"
+	OpcodeAtomicI64Rmw32OrUName = "i64.atomic.rmw32.or_u"
+
+	OpcodeAtomicI32RmwXorName    = "i32.atomic.rmw.xor"
+	OpcodeAtomicI64RmwXorName    = "i64.atomic.rmw.xor"
+	OpcodeAtomicI32Rmw8XorUName  = "i32.atomic.rmw8.xor_u"
+	OpcodeAtomicI32Rmw16XorUName = "i32.atomic.rmw16.xor_u"
+	OpcodeAtomicI64Rmw8XorUName  = "i64.atomic.rmw8.xor_u"
+	OpcodeAtomicI64Rmw16XorUName = "i64.atomic.rmw16.xor_u"
+	OpcodeAtomicI64Rmw32XorUName = "i64.atomic.rmw32.xor_u"
+
+	OpcodeAtomicI32RmwXchgName    = "i32.atomic.rmw.xchg"
+	OpcodeAtomicI64RmwXchgName    = "i64.atomic.rmw.xchg"
+	OpcodeAtomicI32Rmw8XchgUName  = "i32.atomic.rmw8.xchg_u"
+	OpcodeAtomicI32Rmw16XchgUName = "i32.atomic.rmw16.xchg_u"
+	OpcodeAtomicI64Rmw8XchgUName  = "i64.atomic.rmw8.xchg_u"
+	OpcodeAtomicI64Rmw16XchgUName = "i64.atomic.rmw16.xchg_u"
+	OpcodeAtomicI64Rmw32XchgUName = "i64.atomic.rmw32.xchg_u"
+
+	OpcodeAtomicI32RmwCmpxchgName    = "i32.atomic.rmw.cmpxchg"
+	OpcodeAtomicI64RmwCmpxchgName    = "i64.atomic.rmw.cmpxchg"
+	OpcodeAtomicI32Rmw8CmpxchgUName  = "i32.atomic.rmw8.cmpxchg_u"
+	OpcodeAtomicI32Rmw16CmpxchgUName = "i32.atomic.rmw16.cmpxchg_u"
+	OpcodeAtomicI64Rmw8CmpxchgUName  = "i64.atomic.rmw8.cmpxchg_u"
+	OpcodeAtomicI64Rmw16CmpxchgUName = "i64.atomic.rmw16.cmpxchg_u"
+	OpcodeAtomicI64Rmw32CmpxchgUName = "i64.atomic.rmw32.cmpxchg_u"
+)
+
+var atomicInstructionNames = [256]string{
+	OpcodeAtomicMemoryNotify: OpcodeAtomicMemoryNotifyName,
+	OpcodeAtomicMemoryWait32: OpcodeAtomicMemoryWait32Name,
+	OpcodeAtomicMemoryWait64: OpcodeAtomicMemoryWait64Name,
+	OpcodeAtomicFence:        OpcodeAtomicFenceName,
+
+	OpcodeAtomicI32Load:    OpcodeAtomicI32LoadName,
+	OpcodeAtomicI64Load:    OpcodeAtomicI64LoadName,
+	OpcodeAtomicI32Load8U:  OpcodeAtomicI32Load8UName,
+	OpcodeAtomicI32Load16U: OpcodeAtomicI32Load16UName,
+	OpcodeAtomicI64Load8U:  OpcodeAtomicI64Load8UName,
+	OpcodeAtomicI64Load16U: OpcodeAtomicI64Load16UName,
+	OpcodeAtomicI64Load32U: OpcodeAtomicI64Load32UName,
+
+	OpcodeAtomicI32Store:   OpcodeAtomicI32StoreName,
+	OpcodeAtomicI64Store:   OpcodeAtomicI64StoreName,
+	OpcodeAtomicI32Store8:  OpcodeAtomicI32Store8Name,
+	OpcodeAtomicI32Store16: OpcodeAtomicI32Store16Name,
+	OpcodeAtomicI64Store8:  OpcodeAtomicI64Store8Name,
+	OpcodeAtomicI64Store16: OpcodeAtomicI64Store16Name,
+	OpcodeAtomicI64Store32: OpcodeAtomicI64Store32Name,
+
+	OpcodeAtomicI32RmwAdd:    OpcodeAtomicI32RmwAddName,
+	OpcodeAtomicI64RmwAdd:    OpcodeAtomicI64RmwAddName,
+	OpcodeAtomicI32Rmw8AddU:  OpcodeAtomicI32Rmw8AddUName,
+	OpcodeAtomicI32Rmw16AddU: OpcodeAtomicI32Rmw16AddUName,
+	OpcodeAtomicI64Rmw8AddU:  OpcodeAtomicI64Rmw8AddUName,
+	OpcodeAtomicI64Rmw16AddU: OpcodeAtomicI64Rmw16AddUName,
+	OpcodeAtomicI64Rmw32AddU: OpcodeAtomicI64Rmw32AddUName,
+
+	OpcodeAtomicI32RmwSub:    OpcodeAtomicI32RmwSubName,
+	OpcodeAtomicI64RmwSub:    OpcodeAtomicI64RmwSubName,
+	OpcodeAtomicI32Rmw8SubU:  OpcodeAtomicI32Rmw8SubUName,
+	OpcodeAtomicI32Rmw16SubU: OpcodeAtomicI32Rmw16SubUName,
+	OpcodeAtomicI64Rmw8SubU:  OpcodeAtomicI64Rmw8SubUName,
+	OpcodeAtomicI64Rmw16SubU: OpcodeAtomicI64Rmw16SubUName,
+	OpcodeAtomicI64Rmw32SubU: OpcodeAtomicI64Rmw32SubUName,
+
+	OpcodeAtomicI32RmwAnd:    OpcodeAtomicI32RmwAndName,
+	OpcodeAtomicI64RmwAnd:    OpcodeAtomicI64RmwAndName,
+	OpcodeAtomicI32Rmw8AndU:  OpcodeAtomicI32Rmw8AndUName,
+	OpcodeAtomicI32Rmw16AndU: OpcodeAtomicI32Rmw16AndUName,
+	OpcodeAtomicI64Rmw8AndU:  OpcodeAtomicI64Rmw8AndUName,
+	OpcodeAtomicI64Rmw16AndU: OpcodeAtomicI64Rmw16AndUName,
+	OpcodeAtomicI64Rmw32AndU: OpcodeAtomicI64Rmw32AndUName,
+
+	OpcodeAtomicI32RmwOr:    OpcodeAtomicI32RmwOrName,
+	OpcodeAtomicI64RmwOr:    OpcodeAtomicI64RmwOrName,
+	OpcodeAtomicI32Rmw8OrU:  OpcodeAtomicI32Rmw8OrUName,
+	OpcodeAtomicI32Rmw16OrU: OpcodeAtomicI32Rmw16OrUName,
+	OpcodeAtomicI64Rmw8OrU:  OpcodeAtomicI64Rmw8OrUName,
+	OpcodeAtomicI64Rmw16OrU: OpcodeAtomicI64Rmw16OrUName,
+	OpcodeAtomicI64Rmw32OrU: OpcodeAtomicI64Rmw32OrUName,
+
+	OpcodeAtomicI32RmwXor:    OpcodeAtomicI32RmwXorName,
+	OpcodeAtomicI64RmwXor:    OpcodeAtomicI64RmwXorName,
+	OpcodeAtomicI32Rmw8XorU:  OpcodeAtomicI32Rmw8XorUName,
+	OpcodeAtomicI32Rmw16XorU: OpcodeAtomicI32Rmw16XorUName,
+	OpcodeAtomicI64Rmw8XorU:  OpcodeAtomicI64Rmw8XorUName,
+	OpcodeAtomicI64Rmw16XorU: OpcodeAtomicI64Rmw16XorUName,
+	OpcodeAtomicI64Rmw32XorU: OpcodeAtomicI64Rmw32XorUName,
+
+	OpcodeAtomicI32RmwXchg:    OpcodeAtomicI32RmwXchgName,
+	OpcodeAtomicI64RmwXchg:    OpcodeAtomicI64RmwXchgName,
+	OpcodeAtomicI32Rmw8XchgU:  OpcodeAtomicI32Rmw8XchgUName,
+	OpcodeAtomicI32Rmw16XchgU: OpcodeAtomicI32Rmw16XchgUName,
+	OpcodeAtomicI64Rmw8XchgU:  OpcodeAtomicI64Rmw8XchgUName,
+	OpcodeAtomicI64Rmw16XchgU: OpcodeAtomicI64Rmw16XchgUName,
+	OpcodeAtomicI64Rmw32XchgU: OpcodeAtomicI64Rmw32XchgUName,
+
+	OpcodeAtomicI32RmwCmpxchg:    OpcodeAtomicI32RmwCmpxchgName,
+	OpcodeAtomicI64RmwCmpxchg:    OpcodeAtomicI64RmwCmpxchgName,
+	OpcodeAtomicI32Rmw8CmpxchgU:  OpcodeAtomicI32Rmw8CmpxchgUName,
+	OpcodeAtomicI32Rmw16CmpxchgU: OpcodeAtomicI32Rmw16CmpxchgUName,
+	OpcodeAtomicI64Rmw8CmpxchgU:  OpcodeAtomicI64Rmw8CmpxchgUName,
+	OpcodeAtomicI64Rmw16CmpxchgU: OpcodeAtomicI64Rmw16CmpxchgUName,
+	OpcodeAtomicI64Rmw32CmpxchgU: OpcodeAtomicI64Rmw32CmpxchgUName,
+}
+
+// AtomicInstructionName returns the instruction corresponding to this atomic Opcode.
+func AtomicInstructionName(oc OpcodeAtomic) (ret string) {
+	return atomicInstructionNames[oc]
+}
@@ -5,11 +5,14 @@ import (
 	"fmt"
 	"math"
 	"reflect"
+	"runtime"
 	"sync"
 	"unsafe"
 
 	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
 	"github.com/tetratelabs/wazero/internal/internalapi"
+	"github.com/tetratelabs/wazero/internal/platform"
 )
 
 const (
@@ -20,6 +23,10 @@ const (
 	// MemoryLimitPages is maximum number of pages defined (2^16).
 	// See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#grow-mem
 	MemoryLimitPages = uint32(65536)
+	// MemoryLimitPages64 is the maximum number of pages of a 64-bit memory, introduced in api.CoreFeatureMemory64.
+	// The proposal allows up to 2^48 pages, but we bound this to the maximum page count representable in uint32.
+	// See https://github.com/WebAssembly/memory64/blob/main/proposals/memory64/Overview.md
+	MemoryLimitPages64 = uint32(math.MaxUint32)
 	// MemoryPageSizeInBits satisfies the relation: "1 << MemoryPageSizeInBits == MemoryPageSize".
 	MemoryPageSizeInBits = 16
 )
@@ -37,22 +44,96 @@ type MemoryInstance struct {
 
 	Buffer        []byte
 	Min, Cap, Max uint32
+	// Shared is true when the memory is shared between threads, introduced in api.CoreFeatureThreads.
+	// The address space of a shared memory is reserved up to Max at instantiation, so that Buffer never moves on Grow.
+	Shared bool
+	// Is64 is true when the memory is addressed by i64, introduced in api.CoreFeatureMemory64.
+	Is64 bool
 	// mux is used to prevent overlapping calls to Grow.
 	mux sync.RWMutex
+	// waiters are the goroutines blocked in memory.atomic.wait32 or memory.atomic.wait64, keyed by the address.
+	waiters waiters
 	// definition is known at compile time.
 	definition api.MemoryDefinition
+
+	// expBuffer is non-nil when Buffer was allocated by an experimental.MemoryAllocator.
+	expBuffer *linearMemory
+}
+
+// linearMemory frees the experimental.LinearMemory it wraps once unreachable.
+//
+// The memory isn't freed when the module defining it is closed, as importing modules, host functions holding an
+// api.Memory and in-flight calls can still access Buffer. This is a separate object from MemoryInstance, so that the
+// finalizer isn't set on an object which can be part of a reference cycle.
+type linearMemory struct {
+	experimental.LinearMemory
 }
 
-// NewMemoryInstance creates a new instance based on the parameters in the SectionIDMemory.
-func NewMemoryInstance(memSec *Memory) *MemoryInstance {
+func newLinearMemory(mem experimental.LinearMemory) *linearMemory {
+	ret := &linearMemory{mem}
+	runtime.SetFinalizer(ret, func(m *linearMemory) { m.Free() })
+	return ret
+}
+
+// NewMemoryInstance creates a new instance based on the parameters in the SectionIDMemory. When allocator is nil,
+// Buffer is a Go slice.
+func NewMemoryInstance(memSec *Memory, allocator experimental.MemoryAllocator) (*MemoryInstance, error) {
 	min := MemoryPagesToBytesNum(memSec.Min)
 	capacity := MemoryPagesToBytesNum(memSec.Cap)
-	return &MemoryInstance{
-		Buffer: make([]byte, min, capacity),
-		Min:    memSec.Min,
-		Cap:    memSec.Cap,
-		Max:    memSec.Max,
+	if memSec.IsShared {
+		// The buffer of a shared memory is accessed from multiple goroutines without locks, so it must never be
+		// reallocated. Therefore, we don't use the allocator, which is allowed to move the memory on growth.
+		// Instead, the virtual memory up to the max is reserved where supported, so that only the pages in use are
+		// allocated, and otherwise the buffer is allocated up to the max.
+		if mem, err := platform.ReserveLinearMemory(MemoryPagesToBytesNum(memSec.Max)); err == nil {
+			if buffer := mem.Reallocate(min); uint64(len(buffer)) == min {
+				return &MemoryInstance{
+					Buffer:    buffer,
+					Min:       memSec.Min,
+					Cap:       memSec.Min,
+					Max:       memSec.Max,
+					Shared:    true,
+					Is64:      memSec.Is64,
+					expBuffer: newLinearMemory(mem),
+				}, nil
+			}
+			mem.Free()
+		}
+		return &MemoryInstance{
+			Buffer: make([]byte, min, MemoryPagesToBytesNum(memSec.Max)),
+			Min:    memSec.Min,
+			Cap:    memSec.Max,
+			Max:    memSec.Max,
+			Shared: true,
+			Is64:   memSec.Is64,
+		}, nil
+	} else if allocator == nil {
+		return &MemoryInstance{
+			Buffer: make([]byte, min, capacity),
+			Min:    memSec.Min,
+			Cap:    memSec.Cap,
+			Max:    memSec.Max,
+			Is64:   memSec.Is64,
+		}, nil
 	}
+
+	expBuffer, err := allocator.Allocate(capacity, MemoryPagesToBytesNum(memSec.Max))
+	if err != nil {
+		return nil, fmt.Errorf("allocate memory: %w", err)
+	}
+	buffer := expBuffer.Reallocate(min)
+	if uint64(len(buffer)) != min {
+		expBuffer.Free()
+		return nil, fmt.Errorf("allocate memory: failed to reallocate to %d bytes", min)
+	}
+	return &MemoryInstance{
+		Buffer:    buffer,
+		Min:       memSec.Min,
+		Cap:       memSec.Min,
+		Max:       memSec.Max,
+		Is64:      memSec.Is64,
+		expBuffer: newLinearMemory(expBuffer),
+	}, nil
 }
 
 // Definition implements the same method as documented on api.Memory.
@@ -67,27 +148,107 @@ func (m *MemoryInstance) Size() uint32 {
 
 // ReadByte implements the same method as documented on api.Memory.
 func (m *MemoryInstance) ReadByte(offset uint32) (byte, bool) {
-	if offset >= m.size() {
+	return m.ReadByte64(uint64(offset))
+}
+
+// ReadUint16Le implements the same method as documented on api.Memory.
+func (m *MemoryInstance) ReadUint16Le(offset uint32) (uint16, bool) {
+	return m.ReadUint16Le64(uint64(offset))
+}
+
+// ReadUint32Le implements the same method as documented on api.Memory.
+func (m *MemoryInstance) ReadUint32Le(offset uint32) (uint32, bool) {
+	return m.readUint32Le(uint64(offset))
+}
+
+// ReadFloat32Le implements the same method as documented on api.Memory.
+func (m *MemoryInstance) ReadFloat32Le(offset uint32) (float32, bool) {
+	return m.ReadFloat32Le64(uint64(offset))
+}
+
+// ReadUint64Le implements the same method as documented on api.Memory.
+func (m *MemoryInstance) ReadUint64Le(offset uint32) (uint64, bool) {
+	return m.readUint64Le(uint64(offset))
+}
+
+// ReadFloat64Le implements the same method as documented on api.Memory.
+func (m *MemoryInstance) ReadFloat64Le(offset uint32) (float64, bool) {
+	return m.ReadFloat64Le64(uint64(offset))
+}
+
+// Read implements the same method as documented on api.Memory.
+func (m *MemoryInstance) Read(offset, byteCount uint32) ([]byte, bool) {
+	return m.Read64(uint64(offset), uint64(byteCount))
+}
+
+// WriteByte implements the same method as documented on api.Memory.
+func (m *MemoryInstance) WriteByte(offset uint32, v byte) bool {
+	return m.WriteByte64(uint64(offset), v)
+}
+
+// WriteUint16Le implements the same method as documented on api.Memory.
+func (m *MemoryInstance) WriteUint16Le(offset uint32, v uint16) bool {
+	return m.WriteUint16Le64(uint64(offset), v)
+}
+
+// WriteUint32Le implements the same method as documented on api.Memory.
+func (m *MemoryInstance) WriteUint32Le(offset, v uint32) bool {
+	return m.writeUint32Le(uint64(offset), v)
+}
+
+// WriteFloat32Le implements the same method as documented on api.Memory.
+func (m *MemoryInstance) WriteFloat32Le(offset uint32, v float32) bool {
+	return m.writeUint32Le(uint64(offset), math.Float32bits(v))
+}
+
+// WriteUint64Le implements the same method as documented on api.Memory.
+func (m *MemoryInstance) WriteUint64Le(offset uint32, v uint64) bool {
+	return m.writeUint64Le(uint64(offset), v)
+}
+
+// WriteFloat64Le implements the same method as documented on api.Memory.
+func (m *MemoryInstance) WriteFloat64Le(offset uint32, v float64) bool {
+	return m.writeUint64Le(uint64(offset), math.Float64bits(v))
+}
+
+// Write implements the same method as documented on api.Memory.
+func (m *MemoryInstance) Write(offset uint32, val []byte) bool {
+	return m.Write64(uint64(offset), val)
+}
+
+// WriteString implements the same method as documented on api.Memory.
+func (m *MemoryInstance) WriteString(offset uint32, val string) bool {
+	return m.WriteString64(uint64(offset), val)
+}
+
+// Size64 implements the same method as documented on api.Memory.
+func (m *MemoryInstance) Size64() uint64 {
+	return uint64(len(m.Buffer))
+}
+
+// ReadByte64 implements the same method as documented on api.Memory.
+func (m *MemoryInstance) ReadByte64(offset uint64) (byte, bool) {
+	if offset >= uint64(len(m.Buffer)) {
 		return 0, false
 	}
 	return m.Buffer[offset], true
 }
 
-// ReadUint16Le implements the same method as documented on api.Memory.
-func (m *MemoryInstance) ReadUint16Le(offset uint32) (uint16, bool) {
+// ReadUint16Le64 implements the same method as documented on api.Memory.
+func (m *MemoryInstance) ReadUint16Le64(offset uint64) (uint16, bool) {
 	if !m.hasSize(offset, 2) {
 		return 0, false
 	}
 	return binary.LittleEndian.Uint16(m.Buffer[offset : offset+2]), true
 }
 
-// ReadUint32Le implements the same method as documented on api.Memory.
-func (m *MemoryInstance) ReadUint32Le(offset uint32) (uint32, bool) {
+// ReadUint32Le64 implements the same method as documented on api.Memory.
+func (m *MemoryInstance) ReadUint32Le64(offset uint64) (uint32, bool) {
 	return m.readUint32Le(offset)
 }
 
-// ReadFloat32Le implements the same method as documented on api.Memory.
-func (m *MemoryInstance) ReadFloat32Le(offset uint32) (float32, bool) {
+// ReadFloat32Le64 implements the same method as documented on api.Memory.
+func (m *MemoryInstance) ReadFloat32Le64(offset uint64) (float32, bool) {
 	v, ok := m.readUint32Le(offset)
 	if !ok {
 		return 0, false
@@ -95,13 +256,13 @@ func (m *MemoryInstance) ReadFloat32Le(offset uint32) (float32, bool) {
 	return math.Float32frombits(v), true
 }
 
-// ReadUint64Le implements the same method as documented on api.Memory.
-func (m *MemoryInstance) ReadUint64Le(offset uint32) (uint64, bool) {
+// ReadUint64Le64 implements the same method as documented on api.Memory.
+func (m *MemoryInstance) ReadUint64Le64(offset uint64) (uint64, bool) {
 	return m.readUint64Le(offset)
 }
 
-// ReadFloat64Le implements the same method as documented on api.Memory.
-func (m *MemoryInstance) ReadFloat64Le(offset uint32) (float64, bool) {
+// ReadFloat64Le64 implements the same method as documented on api.Memory.
+func (m *MemoryInstance) ReadFloat64Le64(offset uint64) (float64, bool) {
 	v, ok := m.readUint64Le(offset)
 	if !ok {
 		return 0, false
@@ -109,25 +270,25 @@ func (m *MemoryInstance) ReadFloat64Le(offset uint32) (float64, bool) {
 	return math.Float64frombits(v), true
 }
 
-// Read implements the same method as documented on api.Memory.
-func (m *MemoryInstance) Read(offset, byteCount uint32) ([]byte, bool) {
-	if !m.hasSize(offset, uint64(byteCount)) {
+// Read64 implements the same method as documented on api.Memory.
+func (m *MemoryInstance) Read64(offset, byteCount uint64) ([]byte, bool) {
+	if !m.hasSize(offset, byteCount) {
 		return nil, false
 	}
 	return m.Buffer[offset : offset+byteCount : offset+byteCount], true
 }
 
-// WriteByte implements the same method as documented on api.Memory.
-func (m *MemoryInstance) WriteByte(offset uint32, v byte) bool {
-	if offset >= m.size() {
+// WriteByte64 implements the same method as documented on api.Memory.
+func (m *MemoryInstance) WriteByte64(offset uint64, v byte) bool {
+	if offset >= uint64(len(m.Buffer)) {
 		return false
 	}
 	m.Buffer[offset] = v
 	return true
 }
 
-// WriteUint16Le implements the same method as documented on api.Memory.
-func (m *MemoryInstance) WriteUint16Le(offset uint32, v uint16) bool {
+// WriteUint16Le64 implements the same method as documented on api.Memory.
+func (m *MemoryInstance) WriteUint16Le64(offset uint64, v uint16) bool {
 	if !m.hasSize(offset, 2) {
 		return false
 	}
@@ -135,28 +296,28 @@ func (m *MemoryInstance) WriteUint16Le(offset uint32, v uint16) bool {
 	return true
 }
 
-// WriteUint32Le implements the same method as documented on api.Memory.
-func (m *MemoryInstance) WriteUint32Le(offset, v uint32) bool {
+// WriteUint32Le64 implements the same method as documented on api.Memory.
+func (m *MemoryInstance) WriteUint32Le64(offset uint64, v uint32) bool {
 	return m.writeUint32Le(offset, v)
 }
 
-// WriteFloat32Le implements the same method as documented on api.Memory.
-func (m *MemoryInstance) WriteFloat32Le(offset uint32, v float32) bool {
+// WriteFloat32Le64 implements the same method as documented on api.Memory.
+func (m *MemoryInstance) WriteFloat32Le64(offset uint64, v float32) bool {
 	return m.writeUint32Le(offset, math.Float32bits(v))
 }
 
-// WriteUint64Le implements the same method as documented on api.Memory.
-func (m *MemoryInstance) WriteUint64Le(offset uint32, v uint64) bool {
+// WriteUint64Le64 implements the same method as documented on api.Memory.
+func (m *MemoryInstance) WriteUint64Le64(offset uint64, v uint64) bool {
 	return m.writeUint64Le(offset, v)
 }
 
-// WriteFloat64Le implements the same method as documented on api.Memory.
-func (m *MemoryInstance) WriteFloat64Le(offset uint32, v float64) bool {
+// WriteFloat64Le64 implements the same method as documented on api.Memory.
+func (m *MemoryInstance) WriteFloat64Le64(offset uint64, v float64) bool {
 	return m.writeUint64Le(offset, math.Float64bits(v))
 }
 
-// Write implements the same method as documented on api.Memory.
-func (m *MemoryInstance) Write(offset uint32, val []byte) bool {
+// Write64 implements the same method as documented on api.Memory.
+func (m *MemoryInstance) Write64(offset uint64, val []byte) bool {
 	if !m.hasSize(offset, uint64(len(val))) {
 		return false
 	}
@@ -164,8 +325,8 @@ func (m *MemoryInstance) Write(offset uint32, val []byte) bool {
 	return true
 }
 
-// WriteString implements the same method as documented on api.Memory.
-func (m *MemoryInstance) WriteString(offset uint32, val string) bool {
+// WriteString64 implements the same method as documented on api.Memory.
+func (m *MemoryInstance) WriteString64(offset uint64, val string) bool {
 	if !m.hasSize(offset, uint64(len(val))) {
 		return false
 	}
@@ -189,10 +350,19 @@ func (m *MemoryInstance) Grow(delta uint32) (result uint32, ok bool) {
 		return currentPages, true
 	}
 
-	// If exceeds the max of memory size, we push -1 according to the spec.
+	// If exceeds the max of memory size, we push -1 according to the spec. The sum is compared in uint64, as the
+	// delta can be close to math.MaxUint32 for a 64-bit memory.
 	newPages := currentPages + delta
-	if newPages > m.Max {
+	if uint64(currentPages)+uint64(delta) > uint64(m.Max) {
 		return 0, false
+	} else if m.expBuffer != nil { // let the allocator grow the memory.
+		buffer := m.expBuffer.Reallocate(MemoryPagesToBytesNum(newPages))
+		if uint64(len(buffer)) != MemoryPagesToBytesNum(newPages) {
+			return 0, false
+		}
+		m.Buffer = buffer
+		m.Cap = newPages
+		return currentPages, true
 	} else if newPages > m.Cap { // grow the memory.
 		m.Buffer = append(m.Buffer, make([]byte, MemoryPagesToBytesNum(delta))...)
 		m.Cap = newPages
@@ -243,13 +413,14 @@ func (m *MemoryInstance) size() uint32 {
 // hasSize returns true if Len is sufficient for byteCount at the given offset.
 //
 // Note: This is always fine, because memory can grow, but never shrink.
-func (m *MemoryInstance) hasSize(offset uint32, byteCount uint64) bool {
-	return uint64(offset)+byteCount <= uint64(len(m.Buffer)) // uint64 prevents overflow on add
+func (m *MemoryInstance) hasSize(offset uint64, byteCount uint64) bool {
+	size := uint64(len(m.Buffer))
+	return byteCount <= size && offset <= size-byteCount // avoids overflow on add
 }
 
 // readUint32Le implements ReadUint32Le without using a context. This is extracted as both ints and floats are stored in
 // memory as uint32le.
-func (m *MemoryInstance) readUint32Le(offset uint32) (uint32, bool) {
+func (m *MemoryInstance) readUint32Le(offset uint64) (uint32, bool) {
 	if !m.hasSize(offset, 4) {
 		return 0, false
 	}
@@ -258,7 +429,7 @@ func (m *MemoryInstance) readUint32Le(offset uint32) (uint32, bool) {
 
 // readUint64Le implements ReadUint64Le without using a context. This is extracted as both ints and floats are stored in
 // memory as uint64le.
-func (m *MemoryInstance) readUint64Le(offset uint32) (uint64, bool) {
+func (m *MemoryInstance) readUint64Le(offset uint64) (uint64, bool) {
 	if !m.hasSize(offset, 8) {
 		return 0, false
 	}
@@ -267,7 +438,7 @@ func (m *MemoryInstance) readUint64Le(offset uint32) (uint64, bool) {
 
 // writeUint32Le implements WriteUint32Le without using a context. This is extracted as both ints and floats are stored
 // in memory as uint32le.
-func (m *MemoryInstance) writeUint32Le(offset uint32, v uint32) bool {
+func (m *MemoryInstance) writeUint32Le(offset uint64, v uint32) bool {
 	if !m.hasSize(offset, 4) {
 		return false
 	}
@@ -277,7 +448,7 @@ func (m *MemoryInstance) writeUint32Le(offset uint32, v uint32) bool {
 
 // writeUint64Le implements WriteUint64Le without using a context. This is extracted as both ints and floats are stored
 // in memory as uint64le.
-func (m *MemoryInstance) writeUint64Le(offset uint32, v uint64) bool {
+func (m *MemoryInstance) writeUint64Le(offset uint64, v uint64) bool {
 	if !m.hasSize(offset, 8) {
 		return false
 	}
@@ -0,0 +1,290 @@
+package wasm
+
+import (
+	"container/list"
+	"math/bits"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unsafe"
+
+	"github.com/tetratelabs/wazero/internal/wasmruntime"
+)
+
+// Below implements the atomic instructions introduced in api.CoreFeatureThreads in Go, so that they can be used by
+// all the engines.
+//
+// Narrower accesses than 32-bit are implemented with compare-and-swap on the aligned 32-bit word containing the value,
+// so that they are atomic against any other atomic access to the same word. Since the values in the memory are
+// little-endian, the words are byte-swapped on big-endian hosts.
+
+// hostLittleEndian is true if the host is little-endian, in which case the byte order of the memory and the
+// sync/atomic operations are the same.
+var hostLittleEndian = func() bool {
+	v := uint16(1)
+	return *(*byte)(unsafe.Pointer(&v)) == 1
+}()
+
+// Results of memory.atomic.wait32 and memory.atomic.wait64.
+const (
+	// AtomicWaitOk is returned when the waiter was woken by memory.atomic.notify.
+	AtomicWaitOk uint64 = 0
+	// AtomicWaitNotEqual is returned when the loaded value didn't match the expected one.
+	AtomicWaitNotEqual uint64 = 1
+	// AtomicWaitTimedOut is returned when the waiter wasn't woken before the timeout.
+	AtomicWaitTimedOut uint64 = 2
+)
+
+// waiters tracks the goroutines blocked in memory.atomic.wait32 or memory.atomic.wait64.
+type waiters struct {
+	mux sync.Mutex
+	// m is the FIFO queue of waiters per address, where each element is the chan closed by memory.atomic.notify.
+	m map[uint64]*list.List
+}
+
+// AtomicLoad returns the value of `size` bytes at the effective address `offset`, zero-extended to uint64.
+//
+// The error is either wasmruntime.ErrRuntimeUnalignedAtomic or wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess.
+func (m *MemoryInstance) AtomicLoad(offset uint64, size uint32) (uint64, error) {
+	p, err := m.atomicAddress(offset, size)
+	if err != nil {
+		return 0, err
+	}
+	if size == 8 {
+		return load64(p), nil
+	}
+	wp, shift, mask := m.atomicWord(offset, size)
+	return uint64(load32(wp)>>shift) & uint64(mask), nil
+}
+
+// AtomicStore stores the lower `size` bytes of `v` at the effective address `offset`.
+//
+// The error is either wasmruntime.ErrRuntimeUnalignedAtomic or wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess.
+func (m *MemoryInstance) AtomicStore(offset uint64, size uint32, v uint64) error {
+	_, err := m.AtomicRMW(offset, size, v, atomicXchg)
+	return err
+}
+
+// AtomicRMW replaces the value of `size` bytes at the effective address `offset` with `op(old, v)` truncated to
+// `size` bytes, and returns the old value zero-extended to uint64.
+//
+// The error is either wasmruntime.ErrRuntimeUnalignedAtomic or wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess.
+func (m *MemoryInstance) AtomicRMW(offset uint64, size uint32, v uint64, op func(old, v uint64) uint64) (uint64, error) {
+	p, err := m.atomicAddress(offset, size)
+	if err != nil {
+		return 0, err
+	}
+	if size == 8 {
+		for {
+			old := load64(p)
+			if cas64(p, old, op(old, v)) {
+				return old, nil
+			}
+		}
+	}
+
+	wp, shift, mask := m.atomicWord(offset, size)
+	for {
+		word := load32(wp)
+		old := (word >> shift) & mask
+		replacement := uint32(op(uint64(old), v)) & mask
+		if cas32(wp, word, word&^(mask<<shift)|replacement<<shift) {
+			return uint64(old), nil
+		}
+	}
+}
+
+// AtomicCompareExchange replaces the value of `size` bytes at the effective address `offset` with `replacement` if
+// it equals `expected`, both truncated to `size` bytes. This returns the old value zero-extended to uint64 regardless
+// of whether the value was replaced.
+//
+// The error is either wasmruntime.ErrRuntimeUnalignedAtomic or wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess.
+func (m *MemoryInstance) AtomicCompareExchange(offset uint64, size uint32, expected, replacement uint64) (uint64, error) {
+	return m.AtomicRMW(offset, size, replacement, func(old, v uint64) uint64 {
+		if size < 8 {
+			expected &= 1<<(size*8) - 1
+		}
+		if old == expected {
+			return v
+		}
+		return old
+	})
+}
+
+// AtomicWait32 implements memory.atomic.wait32. This blocks until the value of 4 bytes at the effective address
+// `offset` is notified by AtomicNotify if it equals `expected`, or at most `timeout` nanoseconds if non-negative.
+// `waiter` is the module of the blocked call, if any, whose closure also wakes the call up.
+//
+// The result is AtomicWaitOk, AtomicWaitNotEqual or AtomicWaitTimedOut, and the error is either
+// wasmruntime.ErrRuntimeUnalignedAtomic, wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess,
+// wasmruntime.ErrRuntimeExpectedSharedMemory or the sys.ExitError of the closed waiter.
+func (m *MemoryInstance) AtomicWait32(offset uint64, expected uint32, timeout int64, waiter *ModuleInstance) (uint64, error) {
+	p, err := m.atomicAddress(offset, 4)
+	if err != nil {
+		return 0, err
+	} else if !m.Shared {
+		return 0, wasmruntime.ErrRuntimeExpectedSharedMemory
+	}
+	return m.wait(offset, timeout, waiter, func() bool { return load32(p) == expected })
+}
+
+// AtomicWait64 is like AtomicWait32, but implements memory.atomic.wait64 which compares the value of 8 bytes.
+func (m *MemoryInstance) AtomicWait64(offset uint64, expected uint64, timeout int64, waiter *ModuleInstance) (uint64, error) {
+	p, err := m.atomicAddress(offset, 8)
+	if err != nil {
+		return 0, err
+	} else if !m.Shared {
+		return 0, wasmruntime.ErrRuntimeExpectedSharedMemory
+	}
+	return m.wait(offset, timeout, waiter, func() bool { return load64(p) == expected })
+}
+
+// AtomicNotify implements memory.atomic.notify. This wakes up at most `count` waiters on the effective address
+// `offset` in the order they started waiting, and returns the number of woken waiters.
+//
+// The error is either wasmruntime.ErrRuntimeUnalignedAtomic or wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess.
+func (m *MemoryInstance) AtomicNotify(offset uint64, count uint32) (uint32, error) {
+	if _, err := m.atomicAddress(offset, 4); err != nil {
+		return 0, err
+	} else if !m.Shared {
+		return 0, nil // Nothing can wait on a non-shared memory.
+	}
+
+	w := &m.waiters
+	w.mux.Lock()
+	defer w.mux.Unlock()
+
+	queue := w.m[offset]
+	if queue == nil {
+		return 0, nil
+	}
+	var woken uint32
+	for ; woken < count && queue.Len() > 0; woken++ {
+		close(queue.Remove(queue.Front()).(chan struct{}))
+	}
+	if queue.Len() == 0 {
+		delete(w.m, offset)
+	}
+	return woken, nil
+}
+
+// wait blocks the current goroutine on the address `offset` if `equal` returns true, until notified, timed out or the
+// `waiter` is closed.
+//
+// Note: `equal` is called while holding the lock of the waiters, so that AtomicNotify after a store to the address
+// always wakes up the waiter which loaded the value before the store.
+func (m *MemoryInstance) wait(offset uint64, timeout int64, waiter *ModuleInstance, equal func() bool) (uint64, error) {
+	var done <-chan struct{} // nil blocks forever.
+	if waiter != nil {
+		done = waiter.Done()
+	}
+
+	w := &m.waiters
+	w.mux.Lock()
+	if !equal() {
+		w.mux.Unlock()
+		return AtomicWaitNotEqual, nil
+	}
+	if w.m == nil {
+		w.m = map[uint64]*list.List{}
+	}
+	queue := w.m[offset]
+	if queue == nil {
+		queue = list.New()
+		w.m[offset] = queue
+	}
+	ch := make(chan struct{})
+	elem := queue.PushBack(ch)
+	w.mux.Unlock()
+
+	var timedOut <-chan time.Time // nil blocks forever.
+	if timeout >= 0 {
+		timer := time.NewTimer(time.Duration(timeout))
+		defer timer.Stop()
+		timedOut = timer.C
+	}
+
+	var closed bool
+	select {
+	case <-ch:
+		return AtomicWaitOk, nil
+	case <-timedOut:
+	case <-done:
+		closed = true
+	}
+
+	w.mux.Lock()
+	select {
+	case <-ch: // Notified concurrently with the timeout or the closure.
+		w.mux.Unlock()
+		return AtomicWaitOk, nil
+	default:
+	}
+	queue.Remove(elem)
+	if queue.Len() == 0 {
+		delete(w.m, offset)
+	}
+	w.mux.Unlock()
+
+	if closed {
+		// This is called without the lock, as it can close the resources of the module.
+		return 0, waiter.FailIfClosed()
+	}
+	return AtomicWaitTimedOut, nil
+}
+
+// atomicAddress returns the pointer to the effective address `offset` accessed by an atomic instruction of `size`
+// bytes, or an error if the address is unaligned or out of bounds.
+func (m *MemoryInstance) atomicAddress(offset uint64, size uint32) (unsafe.Pointer, error) {
+	if offset%uint64(size) != 0 {
+		return nil, wasmruntime.ErrRuntimeUnalignedAtomic
+	}
+	if !m.hasSize(offset, uint64(size)) {
+		return nil, wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess
+	}
+	return unsafe.Pointer(&m.Buffer[offset]), nil
+}
+
+// atomicWord returns the pointer to the aligned 32-bit word containing the `size` bytes at `offset`, as well as the
+// shift and mask to extract the value from the word in the little-endian order. This must be called after
+// atomicAddress succeeds, which guarantees that the word is in bounds as the memory size is a multiple of 4.
+func (m *MemoryInstance) atomicWord(offset uint64, size uint32) (wp unsafe.Pointer, shift, mask uint32) {
+	wp = unsafe.Pointer(&m.Buffer[offset&^3])
+	shift = uint32(offset&3) * 8
+	mask = uint32(1<<(size*8) - 1)
+	return
+}
+
+func atomicXchg(_, v uint64) uint64 { return v }
+
+func load32(p unsafe.Pointer) uint32 {
+	return fromLE32(atomic.LoadUint32((*uint32)(p)))
+}
+
+func load64(p unsafe.Pointer) uint64 {
+	return fromLE64(atomic.LoadUint64((*uint64)(p)))
+}
+
+func cas32(p unsafe.Pointer, old, replacement uint32) bool {
+	return atomic.CompareAndSwapUint32((*uint32)(p), fromLE32(old), fromLE32(replacement))
+}
+
+func cas64(p unsafe.Pointer, old, replacement uint64) bool {
+	return atomic.CompareAndSwapUint64((*uint64)(p), fromLE64(old), fromLE64(replacement))
+}
+
+// fromLE32 converts between the little-endian order of the memory and the host order. This is its own inverse.
+func fromLE32(v uint32) uint32 {
+	if hostLittleEndian {
+		return v
+	}
+	return bits.ReverseBytes32(v)
+}
+
+// fromLE64 is like fromLE32, but for 64-bit values.
+func fromLE64(v uint64) uint64 {
+	if hostLittleEndian {
+		return v
+	}
+	return bits.ReverseBytes64(v)
+}
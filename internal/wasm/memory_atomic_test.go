@@ -0,0 +1,234 @@
+package wasm
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasmruntime"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+func TestNewMemoryInstance_Shared(t *testing.T) {
+	m, err := NewMemoryInstance(&Memory{Min: 1, Cap: 1, Max: 3, IsShared: true}, nil)
+	require.NoError(t, err)
+	require.True(t, m.Shared)
+	require.Equal(t, uint32(1), m.PageSize())
+	if mem, err := platform.ReserveLinearMemory(1 << 16); err == nil {
+		mem.Free()
+		// Only the pages in use are allocated.
+		require.NotNil(t, m.expBuffer)
+		require.Equal(t, uint32(1), m.Cap)
+	} else {
+		require.Equal(t, int(MemoryPagesToBytesNum(3)), cap(m.Buffer))
+	}
+
+	// Growing a shared memory must never move the buffer.
+	before := &m.Buffer[0]
+	_, ok := m.Grow(2)
+	require.True(t, ok)
+	require.Equal(t, uint32(3), m.PageSize())
+	require.Equal(t, before, &m.Buffer[0])
+
+	// The max can't be exceeded.
+	_, ok = m.Grow(1)
+	require.False(t, ok)
+}
+
+func TestMemoryInstance_AtomicRMW(t *testing.T) {
+	m := &MemoryInstance{Buffer: make([]byte, 16), Shared: true}
+	add := func(old, v uint64) uint64 { return old + v }
+
+	for _, tc := range []struct {
+		name           string
+		offset         uint64
+		size           uint32
+		v              uint64
+		expectedOld    uint64
+		expectedLoad   uint64
+		expectedBuffer []byte
+	}{
+		{
+			name:           "8-bit wraps without carry",
+			offset:         3,
+			size:           1,
+			v:              0x1ff,
+			expectedOld:    0,
+			expectedLoad:   0xff,
+			expectedBuffer: []byte{0, 0, 0, 0xff, 0, 0, 0, 0},
+		},
+		{
+			name:           "16-bit",
+			offset:         2,
+			size:           2,
+			v:              0x0102,
+			expectedOld:    0xff00,
+			expectedLoad:   0x0002,
+			expectedBuffer: []byte{0, 0, 0x02, 0x00, 0, 0, 0, 0},
+		},
+		{
+			name:           "32-bit",
+			offset:         4,
+			size:           4,
+			v:              0x01020304,
+			expectedOld:    0,
+			expectedLoad:   0x01020304,
+			expectedBuffer: []byte{0, 0, 0x02, 0x00, 0x04, 0x03, 0x02, 0x01},
+		},
+		{
+			name:           "64-bit",
+			offset:         0,
+			size:           8,
+			v:              1,
+			expectedOld:    0x01020304_00020000,
+			expectedLoad:   0x01020304_00020001,
+			expectedBuffer: []byte{0x01, 0, 0x02, 0x00, 0x04, 0x03, 0x02, 0x01},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			old, err := m.AtomicRMW(tc.offset, tc.size, tc.v, add)
+			require.NoError(t, err)
+			require.Equal(t, tc.expectedOld, old)
+
+			v, err := m.AtomicLoad(tc.offset, tc.size)
+			require.NoError(t, err)
+			require.Equal(t, tc.expectedLoad, v)
+			require.Equal(t, tc.expectedBuffer, m.Buffer[:8])
+		})
+	}
+}
+
+func TestMemoryInstance_AtomicCompareExchange(t *testing.T) {
+	m := &MemoryInstance{Buffer: make([]byte, 8), Shared: true}
+
+	// The expected value is truncated to the size of the access.
+	old, err := m.AtomicCompareExchange(1, 1, 0x100, 0xab)
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), old)
+	require.Equal(t, []byte{0, 0xab, 0, 0}, m.Buffer[:4])
+
+	old, err = m.AtomicCompareExchange(1, 1, 0, 0xcd)
+	require.NoError(t, err)
+	require.Equal(t, uint64(0xab), old)
+	require.Equal(t, []byte{0, 0xab, 0, 0}, m.Buffer[:4])
+}
+
+func TestMemoryInstance_Atomic_errors(t *testing.T) {
+	m := &MemoryInstance{Buffer: make([]byte, 16)}
+
+	_, err := m.AtomicLoad(2, 4)
+	require.Equal(t, wasmruntime.ErrRuntimeUnalignedAtomic, err)
+
+	_, err = m.AtomicLoad(16, 4)
+	require.Equal(t, wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess, err)
+
+	// The alignment is checked before the bounds.
+	err = m.AtomicStore(17, 2, 0)
+	require.Equal(t, wasmruntime.ErrRuntimeUnalignedAtomic, err)
+
+	_, err = m.AtomicWait32(0, 0, 0, nil)
+	require.Equal(t, wasmruntime.ErrRuntimeExpectedSharedMemory, err)
+
+	_, err = m.AtomicWait64(0, 0, 0, nil)
+	require.Equal(t, wasmruntime.ErrRuntimeExpectedSharedMemory, err)
+
+	// Notify on a non-shared memory is valid, but nothing can be woken.
+	woken, err := m.AtomicNotify(0, 1)
+	require.NoError(t, err)
+	require.Equal(t, uint32(0), woken)
+}
+
+func TestMemoryInstance_AtomicWait_AtomicNotify(t *testing.T) {
+	m := &MemoryInstance{Buffer: make([]byte, 16), Shared: true}
+
+	t.Run("not equal", func(t *testing.T) {
+		res, err := m.AtomicWait32(0, 1, -1, nil)
+		require.NoError(t, err)
+		require.Equal(t, AtomicWaitNotEqual, res)
+
+		res, err = m.AtomicWait64(8, 1, -1, nil)
+		require.NoError(t, err)
+		require.Equal(t, AtomicWaitNotEqual, res)
+	})
+
+	t.Run("timed out", func(t *testing.T) {
+		res, err := m.AtomicWait32(0, 0, int64(time.Millisecond), nil)
+		require.NoError(t, err)
+		require.Equal(t, AtomicWaitTimedOut, res)
+		require.Equal(t, 0, len(m.waiters.m))
+	})
+
+	t.Run("notify count", func(t *testing.T) {
+		const waiters = 3
+		var wg sync.WaitGroup
+		wg.Add(waiters)
+		results := make(chan uint64, waiters)
+		for i := 0; i < waiters; i++ {
+			go func() {
+				defer wg.Done()
+				res, err := m.AtomicWait32(4, 0, -1, nil)
+				require.NoError(t, err)
+				results <- res
+			}()
+		}
+
+		// Wait until all the goroutines are blocked.
+		for {
+			m.waiters.mux.Lock()
+			n := 0
+			if q := m.waiters.m[4]; q != nil {
+				n = q.Len()
+			}
+			m.waiters.mux.Unlock()
+			if n == waiters {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+
+		woken, err := m.AtomicNotify(4, 2)
+		require.NoError(t, err)
+		require.Equal(t, uint32(2), woken)
+
+		woken, err = m.AtomicNotify(4, 2)
+		require.NoError(t, err)
+		require.Equal(t, uint32(1), woken)
+
+		wg.Wait()
+		close(results)
+		for res := range results {
+			require.Equal(t, AtomicWaitOk, res)
+		}
+		require.Equal(t, 0, len(m.waiters.m))
+	})
+
+	t.Run("closed", func(t *testing.T) {
+		waiter := &ModuleInstance{}
+		errs := make(chan error)
+		go func() {
+			_, err := m.AtomicWait64(8, 0, -1, waiter)
+			errs <- err
+		}()
+
+		// Wait until the goroutine is blocked.
+		for {
+			m.waiters.mux.Lock()
+			n := len(m.waiters.m)
+			m.waiters.mux.Unlock()
+			if n == 1 {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+
+		require.NoError(t, waiter.closeWithExitCode(testCtx, 3))
+		require.Equal(t, sys.NewExitError(3), <-errs)
+		require.Equal(t, 0, len(m.waiters.m))
+
+		// Waiting after the closure returns immediately.
+		_, err := m.AtomicWait32(0, 0, -1, waiter)
+		require.Equal(t, sys.NewExitError(3), err)
+	})
+}
@@ -1,13 +1,18 @@
 package wasm
 
 import (
+	"errors"
 	"math"
 	"reflect"
+	"runtime"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 	"unsafe"
 
 	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
 	"github.com/tetratelabs/wazero/internal/testing/require"
 )
 
@@ -90,6 +95,79 @@ func TestMemoryInstance_Grow_Size(t *testing.T) {
 	}
 }
 
+// sliceAllocator is an experimental.MemoryAllocator which copies on growth like append.
+type sliceAllocator struct {
+	capacity, max uint64
+	buf           []byte
+	// freed is set by the finalizer goroutine.
+	freed atomic.Bool
+}
+
+func (a *sliceAllocator) Allocate(capacity, max uint64) (experimental.LinearMemory, error) {
+	if max == 0 {
+		return nil, errors.New("no memory")
+	}
+	a.capacity, a.max = capacity, max
+	return a, nil
+}
+
+func (a *sliceAllocator) Reallocate(size uint64) []byte {
+	if size > a.max {
+		return nil
+	}
+	a.buf = append(a.buf, make([]byte, size-uint64(len(a.buf)))...)
+	return a.buf
+}
+
+func (a *sliceAllocator) Free() {
+	a.freed.Store(true)
+}
+
+func TestNewMemoryInstance_allocator(t *testing.T) {
+	allocator := &sliceAllocator{}
+	m, err := NewMemoryInstance(&Memory{Min: 1, Cap: 2, Max: 3}, allocator)
+	require.NoError(t, err)
+	require.Equal(t, MemoryPagesToBytesNum(2), allocator.capacity)
+	require.Equal(t, MemoryPagesToBytesNum(3), allocator.max)
+	require.Equal(t, uint32(1), m.PageSize())
+
+	// Growth is delegated to the allocator, and contents are preserved.
+	m.Buffer[0] = 1
+	res, ok := m.Grow(2)
+	require.True(t, ok)
+	require.Equal(t, uint32(1), res)
+	require.Equal(t, uint32(3), m.PageSize())
+	require.Equal(t, byte(1), m.Buffer[0])
+
+	// The allocator failing to grow fails memory.grow.
+	m.Max = 4
+	_, ok = m.Grow(1)
+	require.False(t, ok)
+	require.Equal(t, uint32(3), m.PageSize())
+
+	_, err = NewMemoryInstance(&Memory{}, allocator)
+	require.EqualError(t, err, "allocate memory: no memory")
+}
+
+func TestNewMemoryInstance_allocatorFree(t *testing.T) {
+	allocator := &sliceAllocator{}
+	m, err := NewMemoryInstance(&Memory{Min: 1, Max: 1}, allocator)
+	require.NoError(t, err)
+
+	// The memory is only freed once unreachable, as it can be used after the
+	// module defining it is closed.
+	runtime.GC()
+	require.False(t, allocator.freed.Load())
+	runtime.KeepAlive(m)
+
+	// Finalizers run on another goroutine, so wait for it.
+	for i := 0; i < 100 && !allocator.freed.Load(); i++ {
+		runtime.GC()
+		time.Sleep(time.Millisecond)
+	}
+	require.True(t, allocator.freed.Load())
+}
+
 func TestMemoryInstance_ReadByte(t *testing.T) {
 	mem := &MemoryInstance{Buffer: []byte{0, 0, 0, 0, 0, 0, 0, 16}, Min: 1}
 	v, ok := mem.ReadByte(7)
@@ -150,7 +228,7 @@ func TestMemoryInstance_HasSize(t *testing.T) {
 
 	tests := []struct {
 		name        string
-		offset      uint32
+		offset      uint64
 		sizeInBytes uint64
 		expected    bool
 	}{
@@ -162,7 +240,7 @@ func TestMemoryInstance_HasSize(t *testing.T) {
 		},
 		{
 			name:        "maximum valid sizeInBytes",
-			offset:      memory.Size() - 8,
+			offset:      memory.Size64() - 8,
 			sizeInBytes: 8,
 			expected:    true,
 		},
@@ -174,7 +252,7 @@ func TestMemoryInstance_HasSize(t *testing.T) {
 		},
 		{
 			name:        "offset exceeds the memory size",
-			offset:      memory.Size(),
+			offset:      memory.Size64(),
 			sizeInBytes: 1, // arbitrary size
 			expected:    false,
 		},
@@ -184,6 +262,12 @@ func TestMemoryInstance_HasSize(t *testing.T) {
 			sizeInBytes: 4,                  // if there's overflow, offset + sizeInBytes is 3, and it may pass the check
 			expected:    false,
 		},
+		{
+			name:        "offset + sizeInBytes overflows in uint64",
+			offset:      math.MaxUint64 - 1, // invalid too large offset
+			sizeInBytes: 4,                  // if there's overflow, offset + sizeInBytes is 2, and it may pass the check
+			expected:    false,
+		},
 		{
 			name:        "address.wast:200",
 			offset:      4294967295,
@@ -465,6 +549,29 @@ func TestMemoryInstance_Read(t *testing.T) {
 	require.False(t, ok)
 }
 
+func TestMemoryInstance_Read64(t *testing.T) {
+	mem := &MemoryInstance{Buffer: []byte{0, 0, 0, 0, 16, 0, 0, 0}, Min: 1, Is64: true}
+
+	buf, ok := mem.Read64(4, 4)
+	require.True(t, ok)
+	require.Equal(t, []byte{16, 0, 0, 0}, buf)
+
+	b, ok := mem.ReadByte64(4)
+	require.True(t, ok)
+	require.Equal(t, byte(16), b)
+
+	_, ok = mem.Read64(5, 4)
+	require.False(t, ok)
+
+	// The offset isn't truncated to uint32.
+	_, ok = mem.Read64(math.MaxUint32+1+4, 4)
+	require.False(t, ok)
+	_, ok = mem.ReadByte64(math.MaxUint32 + 1)
+	require.False(t, ok)
+	_, ok = mem.Read64(4, math.MaxUint64)
+	require.False(t, ok)
+}
+
 func TestMemoryInstance_WriteUint16Le(t *testing.T) {
 	memory := &MemoryInstance{Buffer: make([]byte, 100)}
 
@@ -737,6 +844,21 @@ func TestMemoryInstance_Write(t *testing.T) {
 	require.False(t, ok)
 }
 
+func TestMemoryInstance_Write64(t *testing.T) {
+	mem := &MemoryInstance{Buffer: []byte{0, 0, 0, 0, 16, 0, 0, 0}, Min: 1, Is64: true}
+
+	require.True(t, mem.Write64(4, []byte{16, 0, 0, 4}))
+	require.True(t, mem.WriteByte64(0, 1))
+	require.Equal(t, []byte{1, 0, 0, 0, 16, 0, 0, 4}, mem.Buffer)
+	require.Equal(t, uint64(8), mem.Size64())
+
+	require.False(t, mem.Write64(5, []byte{16, 0, 0, 4}))
+	// The offset isn't truncated to uint32.
+	require.False(t, mem.Write64(math.MaxUint32+1, []byte{16}))
+	require.False(t, mem.WriteByte64(math.MaxUint32+1, 1))
+	require.Equal(t, []byte{1, 0, 0, 0, 16, 0, 0, 4}, mem.Buffer)
+}
+
 func TestMemoryInstance_Write_overflow(t *testing.T) {
 	mem := &MemoryInstance{Buffer: []byte{0, 0, 0, 0, 16, 0, 0, 0}, Min: 1}
 
@@ -4,8 +4,11 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math"
+	"sync/atomic"
 
 	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
 	"github.com/tetratelabs/wazero/sys"
 )
 
@@ -139,7 +142,33 @@ const (
 
 func (m *ModuleInstance) setExitCode(exitCode uint32, flag exitCodeFlag) bool {
 	closed := flag | uint64(exitCode)<<32 // Store exitCode as high-order bits.
-	return m.Closed.CompareAndSwap(0, closed)
+	if !m.Closed.CompareAndSwap(0, closed) {
+		return false
+	}
+	if m.s != nil {
+		if linker, ok := m.s.Engine.(ImportLinker); ok {
+			linker.ReleaseModuleInstance(m)
+		}
+	}
+	// Only the first close reaches here, so done is closed once.
+	m.doneMux.Lock()
+	if m.done == nil {
+		m.done = make(chan struct{})
+	}
+	close(m.done)
+	m.doneMux.Unlock()
+	return true
+}
+
+// Done returns a channel which is closed when the module is closed, for example by CloseWithExitCode or when the
+// context is done with RuntimeConfig.WithCloseOnContextDone. This is used to wake up calls blocked in Go.
+func (m *ModuleInstance) Done() <-chan struct{} {
+	m.doneMux.Lock()
+	defer m.doneMux.Unlock()
+	if m.done == nil {
+		m.done = make(chan struct{}) // closed by setExitCode, even if closed concurrently.
+	}
+	return m.done
 }
 
 // ensureResourcesClosed ensures that resources assigned to ModuleInstance is released.
@@ -150,9 +179,16 @@ func (m *ModuleInstance) ensureResourcesClosed(ctx context.Context) (err error)
 		m.CloseNotifier = nil
 	}
 
+	g := m.threads.Load()
+	if g != nil {
+		g.exit(ctx, m, uint32(m.Closed.Load()>>32))
+	}
+
 	if sysCtx := m.Sys; sysCtx != nil { // nil if from HostModuleBuilder
-		if err = sysCtx.FS().Close(); err != nil {
-			return err
+		if g == nil || g.main == m { // threads share the Sys of the main module.
+			if err = sysCtx.FS().Close(); err != nil {
+				return err
+			}
 		}
 		m.Sys = nil
 	}
@@ -167,6 +203,28 @@ func (m *ModuleInstance) ensureResourcesClosed(ctx context.Context) (err error)
 	return
 }
 
+// Stats implements experimental.Stats
+func (m *ModuleInstance) Stats() *experimental.ModuleStats {
+	ret := &experimental.ModuleStats{
+		MemorySizes: make([]uint32, 0, len(m.Memories)),
+		TableSizes:  make([]uint32, 0, len(m.Tables)),
+		GlobalCount: len(m.Globals),
+		Closed:      m.IsClosed(),
+	}
+	for _, mem := range m.Memories {
+		ret.MemorySizes = append(ret.MemorySizes, mem.PageSize())
+	}
+	for _, t := range m.Tables {
+		t.mux.RLock() // table.grow can run concurrently.
+		ret.TableSizes = append(ret.TableSizes, uint32(len(t.References)))
+		t.mux.RUnlock()
+	}
+	if sysCtx := m.Sys; sysCtx != nil && !ret.Closed {
+		ret.OpenFiles = sysCtx.FS().OpenFileCount()
+	}
+	return ret
+}
+
 // Memory implements the same method as documented on api.Module.
 func (m *ModuleInstance) Memory() api.Memory {
 	return m.MemoryInstance
@@ -174,27 +232,44 @@ func (m *ModuleInstance) Memory() api.Memory {
 
 // ExportedMemory implements the same method as documented on api.Module.
 func (m *ModuleInstance) ExportedMemory(name string) api.Memory {
-	_, err := m.getExport(name, ExternTypeMemory)
+	exp, err := m.getExport(name, ExternTypeMemory)
 	if err != nil {
 		return nil
 	}
-	// We Assume that we have at most one memory.
-	return m.MemoryInstance
+	return m.Memories[exp.Index]
 }
 
 // ExportedMemoryDefinitions implements the same method as documented on
 // api.Module.
 func (m *ModuleInstance) ExportedMemoryDefinitions() map[string]api.MemoryDefinition {
-	// Special case as we currently only support one memory.
-	if mem := m.MemoryInstance; mem != nil {
-		// Now, find out if it is exported
-		for name, exp := range m.Exports {
-			if exp.Type == ExternTypeMemory {
-				return map[string]api.MemoryDefinition{name: mem.definition}
-			}
+	ret := map[string]api.MemoryDefinition{}
+	for name, exp := range m.Exports {
+		if exp.Type == ExternTypeMemory {
+			ret[name] = m.Memories[exp.Index].definition
 		}
 	}
-	return map[string]api.MemoryDefinition{}
+	return ret
+}
+
+// ExportedTable implements the same method as documented on api.Module.
+func (m *ModuleInstance) ExportedTable(name string) api.Table {
+	exp, err := m.getExport(name, ExternTypeTable)
+	if err != nil {
+		return nil
+	}
+	return table{t: m.Tables[exp.Index], m: m}
+}
+
+// ExportedTableDefinitions implements the same method as documented on
+// api.Module.
+func (m *ModuleInstance) ExportedTableDefinitions() map[string]api.TableDefinition {
+	result := map[string]api.TableDefinition{}
+	for name, exp := range m.Exports {
+		if exp.Type == ExternTypeTable {
+			result[name] = m.Tables[exp.Index].definition
+		}
+	}
+	return result
 }
 
 // ExportedFunction implements the same method as documented on api.Module.
@@ -245,3 +320,103 @@ func (m *ModuleInstance) NumGlobal() int {
 func (m *ModuleInstance) Global(idx int) api.Global {
 	return constantGlobal{g: m.Globals[idx]}
 }
+
+// NumTable implements experimental.InternalModule.
+func (m *ModuleInstance) NumTable() int {
+	return len(m.Tables)
+}
+
+// Table implements experimental.InternalModule.
+func (m *ModuleInstance) Table(idx int) api.Table {
+	return table{t: m.Tables[idx], m: m}
+}
+
+// LookupFunction implements experimental.InternalModule.
+func (m *ModuleInstance) LookupFunction(ref uint64, params, results []api.ValueType) (api.Function, error) {
+	// The engines dereference funcrefs without checking them, so reject ones which they didn't produce.
+	if ref != 0 && !m.ownsFunctionReference(Reference(ref)) {
+		return nil, fmt.Errorf("funcref %#x was not produced by this runtime", ref)
+	}
+	typeID, err := m.s.GetFunctionTypeID(&FunctionType{Params: params, Results: results})
+	if err != nil {
+		return nil, err
+	}
+	return m.Engine.LookupFunctionReference(Reference(ref), typeID)
+}
+
+// ownsFunctionReference returns true if the non-null Reference was produced by the ModuleEngine of this module, which
+// is checked first as it's the most likely, or of another module in the store.
+func (m *ModuleInstance) ownsFunctionReference(ref Reference) bool {
+	if m.Engine != nil && m.Engine.OwnsFunctionReference(ref) {
+		return true
+	}
+	return m.s.ownsFunctionReference(ref)
+}
+
+// AddFuel implements the same method as documented on experimental.AddFuel.
+func (m *ModuleInstance) AddFuel(fuel uint64) {
+	for {
+		old := m.fuel.Load()
+		updated := old + int64(fuel)
+		if fuel > math.MaxInt64 || updated < old { // saturate on overflow
+			updated = math.MaxInt64
+		}
+		if m.fuel.CompareAndSwap(old, updated) {
+			return
+		}
+	}
+}
+
+// RemainingFuel implements the same method as documented on experimental.RemainingFuel.
+func (m *ModuleInstance) RemainingFuel() uint64 {
+	return uint64(m.Fuel())
+}
+
+// Fuel returns the remaining fuel for the engine to consume, which is never negative.
+func (m *ModuleInstance) Fuel() int64 {
+	// Concurrent calls can consume more than the remaining fuel in total.
+	if fuel := m.fuel.Load(); fuel > 0 {
+		return fuel
+	}
+	return 0
+}
+
+// ConsumeFuel subtracts the fuel consumed by the engine since it last called Fuel.
+func (m *ModuleInstance) ConsumeFuel(consumed int64) {
+	if consumed != 0 {
+		m.fuel.Add(-consumed)
+	}
+}
+
+// StackLimits returns Store.MaxCallStackDepth and Store.MaxStackBytes of the store this module was instantiated in,
+// where zero means the default of the engine.
+func (m *ModuleInstance) StackLimits() (maxCallStackDepth uint32, maxStackBytes uint64) {
+	if m.s == nil { // ModuleInstance created without a Store in tests.
+		return 0, 0
+	}
+	return m.s.MaxCallStackDepth, m.s.MaxStackBytes
+}
+
+// Epoch returns the epoch of the store this module was instantiated in.
+func (m *ModuleInstance) Epoch() *atomic.Uint64 {
+	if m.s == nil { // ModuleInstance created without a Store in tests.
+		return nil
+	}
+	return &m.s.Epoch
+}
+
+// EpochDeadline returns the epoch at which a call with the given context is
+// interrupted, or math.MaxUint64 if there is no deadline.
+//
+// See experimental.WithEpochDeadline
+func (m *ModuleInstance) EpochDeadline(ctx context.Context) uint64 {
+	ticks, ok := ctx.Value(experimental.EpochDeadlineKey{}).(uint64)
+	if !ok || m.s == nil {
+		return math.MaxUint64
+	}
+	epoch := m.s.Epoch.Load()
+	if deadline := epoch + ticks; deadline >= epoch {
+		return deadline
+	}
+	return math.MaxUint64 // saturate on overflow
+}
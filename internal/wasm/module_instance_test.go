@@ -39,7 +39,7 @@ func TestModuleInstance_String(t *testing.T) {
 
 		t.Run(tc.name, func(t *testing.T) {
 			// Ensure paths that can create the host module can see the name.
-			m, err := s.Instantiate(testCtx, &Module{}, tc.moduleName, nil, nil)
+			m, err := s.Instantiate(testCtx, &Module{}, tc.moduleName, nil, nil, nil)
 			defer m.Close(testCtx) //nolint
 
 			require.NoError(t, err)
@@ -86,7 +86,7 @@ func TestModuleInstance_Close(t *testing.T) {
 		t.Run(fmt.Sprintf("%s calls ns.CloseWithExitCode(module.name))", tc.name), func(t *testing.T) {
 			for _, ctx := range []context.Context{nil, testCtx} { // Ensure it doesn't crash on nil!
 				moduleName := t.Name()
-				m, err := s.Instantiate(ctx, &Module{}, moduleName, nil, nil)
+				m, err := s.Instantiate(ctx, &Module{}, moduleName, nil, nil, nil)
 				require.NoError(t, err)
 
 				// We use side effects to see if Close called ns.CloseWithExitCode (without repeating store_test.go).
@@ -118,7 +118,7 @@ func TestModuleInstance_Close(t *testing.T) {
 		_, errno := fsCtx.OpenFile(testFS, "/foo", sys.O_RDONLY, 0)
 		require.EqualErrno(t, 0, errno)
 
-		m, err := s.Instantiate(testCtx, &Module{}, t.Name(), sysCtx, nil)
+		m, err := s.Instantiate(testCtx, &Module{}, t.Name(), sysCtx, nil, nil)
 		require.NoError(t, err)
 
 		// We use side effects to determine if Close in fact called Context.Close (without repeating sys_test.go).
@@ -153,7 +153,7 @@ func TestModuleInstance_Close(t *testing.T) {
 		_, errno := fsCtx.OpenFile(testFS, "/foo", sys.O_RDONLY, 0)
 		require.EqualErrno(t, 0, errno)
 
-		m, err := s.Instantiate(testCtx, &Module{}, t.Name(), sysCtx, nil)
+		m, err := s.Instantiate(testCtx, &Module{}, t.Name(), sysCtx, nil, nil)
 		require.NoError(t, err)
 
 		// In sys.FS, non syscall errors map to sys.EIO.
@@ -194,7 +194,7 @@ func TestModuleInstance_CallDynamic(t *testing.T) {
 		t.Run(fmt.Sprintf("%s calls ns.CloseWithExitCode(module.name))", tc.name), func(t *testing.T) {
 			for _, ctx := range []context.Context{nil, testCtx} { // Ensure it doesn't crash on nil!
 				moduleName := t.Name()
-				m, err := s.Instantiate(ctx, &Module{}, moduleName, nil, nil)
+				m, err := s.Instantiate(ctx, &Module{}, moduleName, nil, nil, nil)
 				require.NoError(t, err)
 
 				// We use side effects to see if Close called ns.CloseWithExitCode (without repeating store_test.go).
@@ -223,7 +223,7 @@ func TestModuleInstance_CallDynamic(t *testing.T) {
 		_, errno := fsCtx.OpenFile(testFS, "/foo", sys.O_RDONLY, 0)
 		require.EqualErrno(t, 0, errno)
 
-		m, err := s.Instantiate(testCtx, &Module{}, t.Name(), sysCtx, nil)
+		m, err := s.Instantiate(testCtx, &Module{}, t.Name(), sysCtx, nil, nil)
 		require.NoError(t, err)
 
 		// We use side effects to determine if Close in fact called Context.Close (without repeating sys_test.go).
@@ -252,7 +252,7 @@ func TestModuleInstance_CallDynamic(t *testing.T) {
 		_, errno := fsCtx.OpenFile(testFS, path, sys.O_RDONLY, 0)
 		require.EqualErrno(t, 0, errno)
 
-		m, err := s.Instantiate(testCtx, &Module{}, t.Name(), sysCtx, nil)
+		m, err := s.Instantiate(testCtx, &Module{}, t.Name(), sysCtx, nil, nil)
 		require.NoError(t, err)
 
 		// In sys.FS, non syscall errors map to sys.EIO.
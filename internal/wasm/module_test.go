@@ -124,7 +124,7 @@ func TestModule_allDeclarations(t *testing.T) {
 		module            *Module
 		expectedFunctions []Index
 		expectedGlobals   []GlobalType
-		expectedMemory    *Memory
+		expectedMemories  []*Memory
 		expectedTables    []Table
 	}{
 		// Functions.
@@ -172,13 +172,20 @@ func TestModule_allDeclarations(t *testing.T) {
 			module: &Module{
 				ImportSection: []Import{{Type: ExternTypeMemory, DescMem: &Memory{Min: 1, Max: 10}}},
 			},
-			expectedMemory: &Memory{Min: 1, Max: 10},
+			expectedMemories: []*Memory{{Min: 1, Max: 10}},
 		},
 		{
 			module: &Module{
-				MemorySection: &Memory{Min: 100},
+				MemorySection: []Memory{{Min: 100}},
 			},
-			expectedMemory: &Memory{Min: 100},
+			expectedMemories: []*Memory{{Min: 100}},
+		},
+		{
+			module: &Module{
+				ImportSection: []Import{{Type: ExternTypeMemory, DescMem: &Memory{Min: 1, Max: 10}}},
+				MemorySection: []Memory{{Min: 100}, {Min: 200}},
+			},
+			expectedMemories: []*Memory{{Min: 1, Max: 10}, {Min: 100}, {Min: 200}},
 		},
 		// Tables.
 		{
@@ -198,12 +205,12 @@ func TestModule_allDeclarations(t *testing.T) {
 	for i, tt := range tests {
 		tc := tt
 		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
-			functions, globals, memory, tables, err := tc.module.AllDeclarations()
+			functions, globals, memories, tables, err := tc.module.AllDeclarations()
 			require.NoError(t, err)
 			require.Equal(t, tc.expectedFunctions, functions)
 			require.Equal(t, tc.expectedGlobals, globals)
 			require.Equal(t, tc.expectedTables, tables)
-			require.Equal(t, tc.expectedMemory, memory)
+			require.Equal(t, tc.expectedMemories, memories)
 		})
 	}
 }
@@ -551,7 +558,7 @@ func TestModule_validateMemory(t *testing.T) {
 				Opcode: OpcodeUnreachable, // Invalid!
 			},
 		}}}
-		err := m.validateMemory(&Memory{}, nil, api.CoreFeaturesV1)
+		err := m.validateMemory([]*Memory{{}}, nil, api.CoreFeaturesV1)
 		require.EqualError(t, err, "calculate offset: invalid opcode for const expression: 0x0")
 	})
 	t.Run("ok", func(t *testing.T) {
@@ -562,9 +569,44 @@ func TestModule_validateMemory(t *testing.T) {
 				Data:   leb128.EncodeInt32(1),
 			},
 		}}}
-		err := m.validateMemory(&Memory{}, nil, api.CoreFeaturesV1)
+		err := m.validateMemory([]*Memory{{}}, nil, api.CoreFeaturesV1)
+		require.NoError(t, err)
+	})
+	t.Run("multiple memories disabled", func(t *testing.T) {
+		m := Module{}
+		err := m.validateMemory([]*Memory{{}, {}}, nil, api.CoreFeaturesV2)
+		require.EqualError(t, err, `multiple memories invalid as feature "multi-memory" is disabled`)
+	})
+	t.Run("active data segment memory index out of range", func(t *testing.T) {
+		m := Module{DataSection: []DataSegment{{
+			OffsetExpression: ConstantExpression{Opcode: OpcodeI32Const, Data: leb128.EncodeInt32(0)},
+			MemoryIndex:      2,
+		}}}
+		err := m.validateMemory([]*Memory{{}, {}}, nil, api.CoreFeaturesV2|api.CoreFeatureMultiMemory)
+		require.EqualError(t, err, "unknown memory 2 as active data target")
+	})
+	t.Run("ok multiple memories", func(t *testing.T) {
+		m := Module{DataSection: []DataSegment{{
+			OffsetExpression: ConstantExpression{Opcode: OpcodeI32Const, Data: leb128.EncodeInt32(0)},
+			MemoryIndex:      1,
+		}}}
+		err := m.validateMemory([]*Memory{{}, {}}, nil, api.CoreFeaturesV2|api.CoreFeatureMultiMemory)
+		require.NoError(t, err)
+	})
+	t.Run("ok 64-bit memory", func(t *testing.T) {
+		m := Module{DataSection: []DataSegment{{
+			OffsetExpression: ConstantExpression{Opcode: OpcodeI64Const, Data: leb128.EncodeInt64(0)},
+		}}}
+		err := m.validateMemory([]*Memory{{Is64: true}}, nil, api.CoreFeaturesV2|api.CoreFeatureMemory64)
 		require.NoError(t, err)
 	})
+	t.Run("i32 offset for 64-bit memory", func(t *testing.T) {
+		m := Module{DataSection: []DataSegment{{
+			OffsetExpression: ConstantExpression{Opcode: OpcodeI32Const, Data: leb128.EncodeInt32(0)},
+		}}}
+		err := m.validateMemory([]*Memory{{Is64: true}}, nil, api.CoreFeaturesV2|api.CoreFeatureMemory64)
+		require.EqualError(t, err, "calculate offset: const expression type mismatch expected i64 but got i32")
+	})
 }
 
 func TestModule_validateImports(t *testing.T) {
@@ -649,8 +691,9 @@ func TestModule_validateExports(t *testing.T) {
 		exportSection   []Export
 		functions       []Index
 		globals         []GlobalType
-		memory          *Memory
+		memories        []*Memory
 		tables          []Table
+		tags            []Index
 		expectedErr     string
 	}{
 		{name: "empty export section", exportSection: []Export{}},
@@ -716,7 +759,13 @@ func TestModule_validateExports(t *testing.T) {
 			name:            "memory",
 			enabledFeatures: api.CoreFeaturesV1,
 			exportSection:   []Export{{Type: ExternTypeMemory, Index: 0}},
-			memory:          &Memory{},
+			memories:        []*Memory{{}},
+		},
+		{
+			name:            "multiple memories",
+			enabledFeatures: api.CoreFeaturesV2 | api.CoreFeatureMultiMemory,
+			exportSection:   []Export{{Type: ExternTypeMemory, Index: 0}, {Type: ExternTypeMemory, Index: 1}},
+			memories:        []*Memory{{}, {}},
 		},
 		{
 			name:            "memory out of range",
@@ -725,13 +774,33 @@ func TestModule_validateExports(t *testing.T) {
 			tables:          []Table{},
 			expectedErr:     `memory for export["e"] out of range`,
 		},
+		{
+			name:            "tag",
+			enabledFeatures: api.CoreFeaturesV2 | api.CoreFeatureExceptionHandling,
+			exportSection:   []Export{{Type: ExternTypeTag, Index: 0}},
+			tags:            []Index{0},
+		},
+		{
+			name:            "tag out of range",
+			enabledFeatures: api.CoreFeaturesV2 | api.CoreFeatureExceptionHandling,
+			exportSection:   []Export{{Type: ExternTypeTag, Index: 1, Name: "e"}},
+			tags:            []Index{0},
+			expectedErr:     `tag for export["e"] out of range`,
+		},
+		{
+			name:            "tag disabled",
+			enabledFeatures: api.CoreFeaturesV2,
+			exportSection:   []Export{{Type: ExternTypeTag, Index: 0, Name: "e"}},
+			tags:            []Index{0},
+			expectedErr:     `invalid export["e"] tag[0]: feature "exception-handling" is disabled`,
+		},
 	}
 
 	for _, tt := range tests {
 		tc := tt
 		t.Run(tc.name, func(t *testing.T) {
 			m := Module{ExportSection: tc.exportSection}
-			err := m.validateExports(tc.enabledFeatures, tc.functions, tc.globals, tc.memory, tc.tables)
+			err := m.validateExports(tc.enabledFeatures, tc.functions, tc.globals, tc.memories, tc.tables, tc.tags)
 			if tc.expectedErr != "" {
 				require.EqualError(t, err, tc.expectedErr)
 			} else {
@@ -836,22 +905,36 @@ func TestModule_buildGlobals(t *testing.T) {
 func TestModule_buildMemoryInstance(t *testing.T) {
 	t.Run("nil", func(t *testing.T) {
 		m := ModuleInstance{}
-		m.buildMemory(&Module{})
+		require.NoError(t, m.buildMemory(&Module{}, nil))
 		require.Nil(t, m.MemoryInstance)
 	})
 	t.Run("non-nil", func(t *testing.T) {
 		min := uint32(1)
 		max := uint32(10)
 		mDef := MemoryDefinition{moduleName: "foo"}
-		m := ModuleInstance{}
-		m.buildMemory(&Module{
-			MemorySection:           &Memory{Min: min, Cap: min, Max: max},
+		m := ModuleInstance{Memories: make([]*MemoryInstance, 1)}
+		require.NoError(t, m.buildMemory(&Module{
+			MemorySection:           []Memory{{Min: min, Cap: min, Max: max}},
 			MemoryDefinitionSection: []MemoryDefinition{mDef},
-		})
+		}, nil))
 		mem := m.MemoryInstance
 		require.Equal(t, min, mem.Min)
 		require.Equal(t, max, mem.Max)
 		require.Equal(t, &mDef, mem.definition)
+		require.Equal(t, []*MemoryInstance{mem}, m.Memories)
+	})
+	t.Run("multiple after imported", func(t *testing.T) {
+		imported := &MemoryInstance{Min: 1}
+		m := ModuleInstance{Memories: []*MemoryInstance{imported, nil, nil}}
+		require.NoError(t, m.buildMemory(&Module{
+			ImportMemoryCount:       1,
+			MemorySection:           []Memory{{Min: 2, Cap: 2, Max: 2}, {Min: 3, Cap: 3, Max: 3}},
+			MemoryDefinitionSection: []MemoryDefinition{{index: 0}, {index: 1}, {index: 2}},
+		}, nil))
+		require.Equal(t, imported, m.MemoryInstance)
+		require.Equal(t, uint32(2), m.Memories[1].Min)
+		require.Equal(t, uint32(3), m.Memories[2].Min)
+		require.Equal(t, Index(2), m.Memories[2].definition.Index())
 	})
 }
 
@@ -1010,30 +1093,26 @@ func TestModule_declaredFunctionIndexes(t *testing.T) {
 }
 
 func TestModule_AssignModuleID(t *testing.T) {
-	getID := func(bin []byte, withListener, withEnsureTermination bool) ModuleID {
+	getID := func(bin []byte, withListener, withEnsureTermination, withFuel, withEpoch bool) ModuleID {
 		m := Module{}
-		m.AssignModuleID(bin, withListener, withEnsureTermination)
+		m.AssignModuleID(bin, withListener, withEnsureTermination, withFuel, withEpoch)
 		return m.ID
 	}
 
 	// Ensures that different args always produce the different IDs.
 	exists := map[ModuleID]struct{}{}
-	for _, tc := range []struct {
-		bin                                 []byte
-		withListener, withEnsureTermination bool
-	}{
-		{bin: []byte{1, 2, 3}, withListener: false, withEnsureTermination: false},
-		{bin: []byte{1, 2, 3}, withListener: false, withEnsureTermination: true},
-		{bin: []byte{1, 2, 3}, withListener: true, withEnsureTermination: false},
-		{bin: []byte{1, 2, 3}, withListener: true, withEnsureTermination: true},
-		{bin: []byte{1, 2, 3, 4}, withListener: false, withEnsureTermination: false},
-		{bin: []byte{1, 2, 3, 4}, withListener: false, withEnsureTermination: true},
-		{bin: []byte{1, 2, 3, 4}, withListener: true, withEnsureTermination: false},
-		{bin: []byte{1, 2, 3, 4}, withListener: true, withEnsureTermination: true},
-	} {
-		id := getID(tc.bin, tc.withListener, tc.withEnsureTermination)
-		_, exist := exists[id]
-		require.False(t, exist)
-		exists[id] = struct{}{}
+	for _, bin := range [][]byte{{1, 2, 3}, {1, 2, 3, 4}} {
+		for _, withListener := range []bool{false, true} {
+			for _, withEnsureTermination := range []bool{false, true} {
+				for _, withFuel := range []bool{false, true} {
+					for _, withEpoch := range []bool{false, true} {
+						id := getID(bin, withListener, withEnsureTermination, withFuel, withEpoch)
+						_, exist := exists[id]
+						require.False(t, exist)
+						exists[id] = struct{}{}
+					}
+				}
+			}
+		}
 	}
 }
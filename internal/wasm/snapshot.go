@@ -0,0 +1,197 @@
+package wasm
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/tetratelabs/wazero/internal/snapshot"
+)
+
+// Snapshot implements snapshot.Snapshotter.
+func (m *ModuleInstance) Snapshot() (*snapshot.Snapshot, error) {
+	if err := m.FailIfClosed(); err != nil {
+		return nil, err
+	}
+
+	module := m.Source
+	ret := &snapshot.Snapshot{ModuleID: module.ID, Globals: []snapshot.Global{}, Tables: []snapshot.Table{}}
+
+	switch len(module.MemorySection) {
+	case 0:
+	case 1:
+		mem := m.Memories[module.ImportMemoryCount]
+		mem.mux.RLock()
+		ret.Memory = make([]byte, len(mem.Buffer))
+		copy(ret.Memory, mem.Buffer)
+		mem.mux.RUnlock()
+	default:
+		return nil, errors.New("snapshots of more than one defined memory are not supported")
+	}
+
+	var funcIndices map[Reference]uint32 // lazily built, as most modules have no funcref state.
+	funcIndex := func(ref Reference) (uint32, error) {
+		if ref == 0 {
+			return 0, nil
+		}
+		if funcIndices == nil {
+			if m.functionReferencesUnsupported() {
+				return 0, errFunctionReferencesUnsupported
+			}
+			funcIndices = m.functionIndices()
+		}
+		idx, ok := funcIndices[ref]
+		if !ok {
+			return 0, errors.New("references a function of another module")
+		}
+		return idx + 1, nil
+	}
+
+	for i := module.ImportGlobalCount; i < Index(len(m.Globals)); i++ {
+		g := m.Globals[i]
+		if !g.Type.Mutable {
+			continue
+		}
+		val := g.Val
+		switch g.Type.ValType {
+		case ValueTypeFuncref:
+			idx, err := funcIndex(Reference(val))
+			if err != nil {
+				return nil, fmt.Errorf("global[%d] %w", i, err)
+			}
+			val = uint64(idx)
+		case ValueTypeExternref:
+			if val != 0 {
+				return nil, fmt.Errorf("global[%d] holds a non-null externref", i)
+			}
+		}
+		ret.Globals = append(ret.Globals, snapshot.Global{Index: i, Val: val, ValHi: g.ValHi})
+	}
+
+	for i := module.ImportTableCount; i < Index(len(m.Tables)); i++ {
+		t := m.Tables[i]
+		t.mux.RLock()
+		elements := make([]uint32, len(t.References))
+		var err error
+		for j, ref := range t.References {
+			if t.Type == RefTypeExternref {
+				if ref != 0 {
+					err = fmt.Errorf("table[%d] holds a non-null externref at offset %d", i, j)
+					break
+				}
+				continue
+			}
+			if elements[j], err = funcIndex(ref); err != nil {
+				err = fmt.Errorf("table[%d] offset %d %w", i, j, err)
+				break
+			}
+		}
+		t.mux.RUnlock()
+		if err != nil {
+			return nil, err
+		}
+		ret.Tables = append(ret.Tables, snapshot.Table{Index: i, Elements: elements})
+	}
+	return ret, nil
+}
+
+// errFunctionReferencesUnsupported is returned when a snapshot has function references, but the engine doesn't
+// produce them, so can neither take nor restore the snapshot.
+var errFunctionReferencesUnsupported = errors.New("function references are not supported by this engine")
+
+// functionReferencesUnsupported returns true if the module has functions, but the engine produces no references to
+// them, as ModuleEngine.FunctionInstanceReference would fail.
+func (m *ModuleInstance) functionReferencesUnsupported() bool {
+	if m.Source.ImportFunctionCount == 0 && len(m.Source.FunctionSection) == 0 {
+		return false
+	}
+	begin, end := m.Engine.FunctionReferenceRange()
+	return begin == end
+}
+
+// functionIndices returns the function index of each function reference in
+// this module's function index space.
+func (m *ModuleInstance) functionIndices() map[Reference]uint32 {
+	count := m.Source.ImportFunctionCount + Index(len(m.Source.FunctionSection))
+	ret := make(map[Reference]uint32, count)
+	for i := Index(0); i < count; i++ {
+		ret[m.Engine.FunctionInstanceReference(i)] = i
+	}
+	return ret
+}
+
+// restore overwrites the state defined by this module with the snapshot.
+func (m *ModuleInstance) restore(snap *snapshot.Snapshot) error {
+	module := m.Source
+	if snap.ModuleID != module.ID {
+		return errors.New("snapshot was taken from a different module")
+	}
+
+	if (snap.Memory != nil) != (len(module.MemorySection) == 1) {
+		return errors.New("snapshot memory doesn't match module")
+	} else if snap.Memory != nil {
+		mem := m.Memories[module.ImportMemoryCount]
+		pages := memoryBytesNumToPages(uint64(len(snap.Memory)))
+		if uint64(len(snap.Memory)) != MemoryPagesToBytesNum(pages) {
+			return fmt.Errorf("snapshot memory size %d isn't a multiple of the page size", len(snap.Memory))
+		} else if current := mem.PageSize(); pages < current {
+			return fmt.Errorf("snapshot memory pages %d < %d", pages, current)
+		} else if _, ok := mem.Grow(pages - current); !ok {
+			return fmt.Errorf("snapshot memory pages %d > max %d", pages, mem.Max)
+		}
+		copy(mem.Buffer, snap.Memory)
+	}
+
+	funcCount := module.ImportFunctionCount + Index(len(module.FunctionSection))
+	funcRef := func(idx uint32) (Reference, error) {
+		if idx == 0 {
+			return 0, nil
+		} else if idx > funcCount {
+			return 0, fmt.Errorf("function index %d out of range", idx-1)
+		} else if m.functionReferencesUnsupported() {
+			return 0, errFunctionReferencesUnsupported
+		}
+		return m.Engine.FunctionInstanceReference(idx - 1), nil
+	}
+
+	for _, sg := range snap.Globals {
+		if sg.Index < module.ImportGlobalCount || sg.Index >= Index(len(m.Globals)) || !m.Globals[sg.Index].Type.Mutable {
+			return fmt.Errorf("snapshot global[%d] isn't a mutable global defined by the module", sg.Index)
+		}
+		g := m.Globals[sg.Index]
+		val := sg.Val
+		if g.Type.ValType == ValueTypeFuncref {
+			ref, err := funcRef(uint32(val))
+			if err != nil {
+				return fmt.Errorf("snapshot global[%d] %w", sg.Index, err)
+			}
+			val = uint64(ref)
+		}
+		g.Val, g.ValHi = val, sg.ValHi
+	}
+
+	for _, st := range snap.Tables {
+		if st.Index < module.ImportTableCount || st.Index >= Index(len(m.Tables)) {
+			return fmt.Errorf("snapshot table[%d] isn't a table defined by the module", st.Index)
+		}
+		t := m.Tables[st.Index]
+		if size := uint32(len(st.Elements)); size < t.Min || (t.Max != nil && size > *t.Max) {
+			return fmt.Errorf("snapshot table[%d] size %d out of range", st.Index, size)
+		}
+		references := make([]Reference, len(st.Elements))
+		for j, e := range st.Elements {
+			if t.Type == RefTypeExternref {
+				if e != 0 {
+					return fmt.Errorf("snapshot table[%d] holds a non-null externref", st.Index)
+				}
+				continue
+			}
+			ref, err := funcRef(e)
+			if err != nil {
+				return fmt.Errorf("snapshot table[%d] %w", st.Index, err)
+			}
+			references[j] = ref
+		}
+		t.References = references
+	}
+	return nil
+}
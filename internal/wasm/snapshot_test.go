@@ -0,0 +1,66 @@
+package wasm
+
+import (
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/snapshot"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestModuleInstance_Snapshot_functionReferencesUnsupported(t *testing.T) {
+	// The engine of this module has a function, but produces no references to it.
+	module := &Module{
+		TypeSection:     []FunctionType{{}},
+		FunctionSection: []Index{0},
+		TableSection:    []Table{{Min: 1, Type: RefTypeFuncref}},
+		GlobalSection:   []Global{{Type: GlobalType{ValType: ValueTypeFuncref, Mutable: true}}},
+	}
+	newModuleInstance := func(tableRef, globalRef Reference) *ModuleInstance {
+		return &ModuleInstance{
+			Source:  module,
+			Engine:  &mockModuleEngine{},
+			Tables:  []*TableInstance{{References: []Reference{tableRef}, Min: 1, Type: RefTypeFuncref}},
+			Globals: []*GlobalInstance{{Type: GlobalType{ValType: ValueTypeFuncref, Mutable: true}, Val: uint64(globalRef)}},
+		}
+	}
+
+	t.Run("snapshot null references", func(t *testing.T) {
+		snap, err := newModuleInstance(0, 0).Snapshot()
+		require.NoError(t, err)
+		require.Equal(t, []snapshot.Table{{Index: 0, Elements: []uint32{0}}}, snap.Tables)
+	})
+
+	t.Run("snapshot table", func(t *testing.T) {
+		_, err := newModuleInstance(0xa, 0).Snapshot()
+		require.EqualError(t, err, "table[0] offset 0 function references are not supported by this engine")
+	})
+
+	t.Run("snapshot global", func(t *testing.T) {
+		_, err := newModuleInstance(0, 0xa).Snapshot()
+		require.EqualError(t, err, "global[0] function references are not supported by this engine")
+	})
+
+	t.Run("restore null references", func(t *testing.T) {
+		m := newModuleInstance(0xa, 0xa)
+		require.NoError(t, m.restore(&snapshot.Snapshot{
+			Globals: []snapshot.Global{{Index: 0}},
+			Tables:  []snapshot.Table{{Index: 0, Elements: []uint32{0}}},
+		}))
+		require.Equal(t, []Reference{0}, m.Tables[0].References)
+		require.Zero(t, m.Globals[0].Val)
+	})
+
+	t.Run("restore table", func(t *testing.T) {
+		err := newModuleInstance(0, 0).restore(&snapshot.Snapshot{
+			Tables: []snapshot.Table{{Index: 0, Elements: []uint32{1}}},
+		})
+		require.EqualError(t, err, "snapshot table[0] function references are not supported by this engine")
+	})
+
+	t.Run("restore global", func(t *testing.T) {
+		err := newModuleInstance(0, 0).restore(&snapshot.Snapshot{
+			Globals: []snapshot.Global{{Index: 0, Val: 1}},
+		})
+		require.EqualError(t, err, "snapshot global[0] function references are not supported by this engine")
+	})
+}
@@ -3,14 +3,17 @@ package wasm
 import (
 	"context"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"sync"
 	"sync/atomic"
 
 	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
 	"github.com/tetratelabs/wazero/internal/close"
 	"github.com/tetratelabs/wazero/internal/internalapi"
 	"github.com/tetratelabs/wazero/internal/leb128"
+	"github.com/tetratelabs/wazero/internal/snapshot"
 	internalsys "github.com/tetratelabs/wazero/internal/sys"
 	"github.com/tetratelabs/wazero/sys"
 )
@@ -44,6 +47,10 @@ type (
 		// track when to shrink it.
 		nameToModuleCap int // guarded by mux
 
+		// functionReferences are the ranges of function references of the modules in moduleList, sorted by begin.
+		// This avoids asking every module whether it owns a reference set by the host.
+		functionReferences []functionReferenceRange // guarded by mux
+
 		// EnabledFeatures are read-only to allow optimizations.
 		EnabledFeatures api.CoreFeatures
 
@@ -58,10 +65,32 @@ type (
 		// Note: this is fixed to 2^27 but have this a field for testability.
 		functionMaxTypes uint32
 
+		// MemoryAllocator allocates the memories defined by modules when non-nil.
+		MemoryAllocator experimental.MemoryAllocator
+
+		// MaxCallStackDepth is the maximum number of nested function calls in a call from Go, or zero for the
+		// default of the engine.
+		MaxCallStackDepth uint32
+
+		// MaxStackBytes is the maximum size of the stack in a call from Go, or zero for the default of the engine.
+		// The stack layout depends on the engine, so the same limit can be reached at different depths.
+		MaxStackBytes uint64
+
+		// Epoch is incremented by experimental.EpochCounter, and read by functions compiled with epoch checks.
+		//
+		// Note: The compiler engine reads this from native code, so this must not be wrapped in another type.
+		Epoch atomic.Uint64
+
 		// mux is used to guard the fields from concurrent access.
 		mux sync.RWMutex
 	}
 
+	// functionReferenceRange is the range returned by ModuleEngine.FunctionReferenceRange of a module.
+	functionReferenceRange struct {
+		begin, end Reference
+		m          *ModuleInstance
+	}
+
 	// ModuleInstance represents instantiated wasm module.
 	// The difference from the spec is that in wazero, a ModuleInstance holds pointers
 	// to the instances, rather than "addresses" (i.e. index to Store.Functions, Globals, etc) for convenience.
@@ -78,6 +107,10 @@ type (
 		MemoryInstance *MemoryInstance
 		Tables         []*TableInstance
 
+		// Memories holds all the memories of the module, beginning with imported ones, and MemoryInstance is the first
+		// of them. This has more than one element only when api.CoreFeatureMultiMemory is enabled.
+		Memories []*MemoryInstance
+
 		// Engine implements function calls for this module.
 		Engine ModuleEngine
 
@@ -114,6 +147,11 @@ type (
 		// See /RATIONALE.md
 		Closed atomic.Uint64
 
+		// doneMux guards done.
+		doneMux sync.Mutex
+		// done is lazily created by Done, and closed once Closed is set.
+		done chan struct{}
+
 		// CodeCloser is non-nil when the code should be closed after this module.
 		CodeCloser api.Closer
 
@@ -126,6 +164,22 @@ type (
 
 		// CloseNotifier is an experimental hook called once on close.
 		CloseNotifier close.Notifier
+
+		// MaxThreads is the maximum number of threads spawned via SpawnThread which can run at the same time.
+		MaxThreads uint32
+		// threads is the group of threads sharing memory with this module, or nil if no thread was spawned.
+		threads atomic.Pointer[threadGroup]
+		// importResolver is the resolver this module was instantiated with, reused to instantiate its threads.
+		importResolver ImportResolver
+
+		// fuel is the remaining fuel for calls into this module, when compiled with fuel metering.
+		//
+		// Note: Exclusively reading and updating this with atomics guarantees cross-goroutine observations.
+		fuel atomic.Int64
+
+		// Tags holds all the tags of the module, beginning with imported ones, introduced in
+		// api.CoreFeatureExceptionHandling.
+		Tags []*TagInstance
 	}
 
 	// DataInstance holds bytes corresponding to the data segment in a module.
@@ -185,17 +239,7 @@ func (m *ModuleInstance) applyElements(elems []ElementSegment) {
 			len(elem.Init) == 0 {
 			continue
 		}
-		var offset uint32
-		if elem.OffsetExpr.Opcode == OpcodeGlobalGet {
-			// Ignore error as it's already validated.
-			globalIdx, _, _ := leb128.LoadUint32(elem.OffsetExpr.Data)
-			global := m.Globals[globalIdx]
-			offset = uint32(global.Val)
-		} else {
-			// Ignore error as it's already validated.
-			o, _, _ := leb128.LoadInt32(elem.OffsetExpr.Data)
-			offset = uint32(o)
-		}
+		offset := uint32(executeConstExpressionI32(m.Globals, &elem.OffsetExpr))
 
 		table := m.Tables[elem.TableIndex]
 		references := table.References
@@ -239,9 +283,7 @@ func (m *ModuleInstance) validateData(data []DataSegment) (err error) {
 	for i := range data {
 		d := &data[i]
 		if !d.IsPassive() {
-			offset := int(executeConstExpressionI32(m.Globals, &d.OffsetExpression))
-			ceil := offset + len(d.Init)
-			if offset < 0 || ceil > len(m.MemoryInstance.Buffer) {
+			if _, ok := m.dataOffset(d); !ok {
 				return fmt.Errorf("%s[%d]: out of bounds memory access", SectionIDName(SectionIDData), i)
 			}
 		}
@@ -258,16 +300,28 @@ func (m *ModuleInstance) applyData(data []DataSegment) error {
 		d := &data[i]
 		m.DataInstances[i] = d.Init
 		if !d.IsPassive() {
-			offset := executeConstExpressionI32(m.Globals, &d.OffsetExpression)
-			if offset < 0 || int(offset)+len(d.Init) > len(m.MemoryInstance.Buffer) {
+			offset, ok := m.dataOffset(d)
+			if !ok {
 				return fmt.Errorf("%s[%d]: out of bounds memory access", SectionIDName(SectionIDData), i)
 			}
-			copy(m.MemoryInstance.Buffer[offset:], d.Init)
+			copy(m.Memories[d.MemoryIndex].Buffer[offset:], d.Init)
 		}
 	}
 	return nil
 }
 
+// dataOffset returns the offset of the active data segment in its memory, or false if the segment doesn't fit in it.
+// The offset expression is i64 when the memory is 64-bit, introduced in api.CoreFeatureMemory64.
+func (m *ModuleInstance) dataOffset(d *DataSegment) (uint64, bool) {
+	mem := m.Memories[d.MemoryIndex]
+	if mem.Is64 {
+		offset := uint64(executeConstExpressionI64(m.Globals, &d.OffsetExpression))
+		return offset, mem.hasSize(offset, uint64(len(d.Init)))
+	}
+	offset := executeConstExpressionI32(m.Globals, &d.OffsetExpression)
+	return uint64(offset), offset >= 0 && mem.hasSize(uint64(offset), uint64(len(d.Init)))
+}
+
 // GetExport returns an export of the given name and type or errs if not exported or the wrong type.
 func (m *ModuleInstance) getExport(name string, et ExternType) (*Export, error) {
 	exp, ok := m.Exports[name]
@@ -280,6 +334,12 @@ func (m *ModuleInstance) getExport(name string, et ExternType) (*Export, error)
 	return exp, nil
 }
 
+// ImportResolver returns the module which exports the import of the given module name, name and type, or nil to
+// resolve it by module name in the Store.
+//
+// See wazero.ModuleConfig WithImportResolver
+type ImportResolver func(moduleName, name string, et ExternType) api.Module
+
 func NewStore(enabledFeatures api.CoreFeatures, engine Engine) *Store {
 	return &Store{
 		nameToModule:     map[string]*ModuleInstance{},
@@ -297,6 +357,7 @@ func NewStore(enabledFeatures api.CoreFeatures, engine Engine) *Store {
 // * ctx: the default context used for function calls.
 // * name: the name of the module.
 // * sys: the system context, which will be closed (SysContext.Close) on ModuleInstance.Close.
+// * resolver: resolves imports before looking up modules by name in this store, or nil.
 //
 // Note: Module.Validate must be called prior to instantiation.
 func (s *Store) Instantiate(
@@ -305,9 +366,10 @@ func (s *Store) Instantiate(
 	name string,
 	sys *internalsys.Context,
 	typeIDs []FunctionTypeID,
+	resolver ImportResolver,
 ) (*ModuleInstance, error) {
 	// Instantiate the module and add it to the store so that other modules can import it.
-	m, err := s.instantiate(ctx, module, name, sys, typeIDs)
+	m, err := s.instantiate(ctx, module, name, sys, typeIDs, resolver)
 	if err != nil {
 		return nil, err
 	}
@@ -326,17 +388,28 @@ func (s *Store) instantiate(
 	name string,
 	sysCtx *internalsys.Context,
 	typeIDs []FunctionTypeID,
+	resolver ImportResolver,
 ) (m *ModuleInstance, err error) {
-	m = &ModuleInstance{ModuleName: name, TypeIDs: typeIDs, Sys: sysCtx, s: s, Source: module}
+	m = &ModuleInstance{ModuleName: name, TypeIDs: typeIDs, Sys: sysCtx, s: s, Source: module, importResolver: resolver}
+	if linker, ok := s.Engine.(ImportLinker); ok {
+		instance := m // m is nil on error.
+		defer func() {
+			if err != nil {
+				linker.ReleaseModuleInstance(instance)
+			}
+		}()
+	}
 
 	m.Tables = make([]*TableInstance, int(module.ImportTableCount)+len(module.TableSection))
 	m.Globals = make([]*GlobalInstance, int(module.ImportGlobalCount)+len(module.GlobalSection))
+	m.Memories = make([]*MemoryInstance, int(module.ImportMemoryCount)+len(module.MemorySection))
+	m.Tags = make([]*TagInstance, int(module.ImportTagCount)+len(module.TagSection))
 	m.Engine, err = s.Engine.NewModuleEngine(module, m)
 	if err != nil {
 		return nil, err
 	}
 
-	if err = m.resolveImports(module); err != nil {
+	if err = m.resolveImports(module, resolver); err != nil {
 		return nil, err
 	}
 
@@ -348,7 +421,10 @@ func (s *Store) instantiate(
 	}
 
 	m.buildGlobals(module, m.Engine.FunctionInstanceReference)
-	m.buildMemory(module)
+	m.buildTags(module)
+	if err = m.buildMemory(module, s.MemoryAllocator); err != nil {
+		return nil, err
+	}
 	m.Exports = module.Exports
 
 	// As of reference types proposal, data segment validation must happen after instantiation,
@@ -370,6 +446,14 @@ func (s *Store) instantiate(
 
 	m.applyElements(module.ElementSection)
 
+	// When restoring from a snapshot, the start function already ran before it was taken.
+	if snap := snapshot.FromContext(ctx); snap != nil {
+		if err = m.restore(snap); err != nil {
+			return nil, fmt.Errorf("restore snapshot: %w", err)
+		}
+		return
+	}
+
 	// Execute the start function.
 	if module.StartSection != nil {
 		funcIdx := *module.StartSection
@@ -384,15 +468,26 @@ func (s *Store) instantiate(
 	return
 }
 
-func (m *ModuleInstance) resolveImports(module *Module) (err error) {
+func (m *ModuleInstance) resolveImports(module *Module, resolver ImportResolver) (err error) {
 	for moduleName, imports := range module.ImportPerModule {
-		var importedModule *ModuleInstance
-		importedModule, err = m.s.module(moduleName)
-		if err != nil {
-			return err
-		}
+		var storeModule *ModuleInstance // lazily looked up, when the resolver doesn't resolve an import.
 
 		for _, i := range imports {
+			var importedModule *ModuleInstance
+			if resolver != nil {
+				if importedModule, err = m.resolveImport(resolver, i); err != nil {
+					return
+				}
+			}
+			if importedModule == nil {
+				if storeModule == nil {
+					if storeModule, err = m.s.module(moduleName); err != nil {
+						return
+					}
+				}
+				importedModule = storeModule
+			}
+
 			var imported *Export
 			imported, err = importedModule.getExport(i.Name, i.Type)
 			if err != nil {
@@ -409,7 +504,14 @@ func (m *ModuleInstance) resolveImports(module *Module) (err error) {
 					return
 				}
 
-				m.Engine.ResolveImportedFunction(i.IndexPerType, imported.Index, importedModule.Engine)
+				importedEngine, importedIndex := importedModule.Engine, imported.Index
+				if linker, ok := m.s.Engine.(ImportLinker); ok {
+					if importedEngine, importedIndex, err = linker.LinkImportedFunction(m, i.IndexPerType, importedModule, imported.Index); err != nil {
+						err = errorInvalidImport(i, err)
+						return
+					}
+				}
+				m.Engine.ResolveImportedFunction(i.IndexPerType, importedIndex, importedEngine)
 			case ExternTypeTable:
 				expected := i.DescTable
 				importedTable := importedModule.Tables[imported.Index]
@@ -437,7 +539,7 @@ func (m *ModuleInstance) resolveImports(module *Module) (err error) {
 				m.Tables[i.IndexPerType] = importedTable
 			case ExternTypeMemory:
 				expected := i.DescMem
-				importedMemory := importedModule.MemoryInstance
+				importedMemory := importedModule.Memories[imported.Index]
 
 				if expected.Min > memoryBytesNumToPages(uint64(len(importedMemory.Buffer))) {
 					err = errorMinSizeMismatch(i, expected.Min, importedMemory.Min)
@@ -448,7 +550,19 @@ func (m *ModuleInstance) resolveImports(module *Module) (err error) {
 					err = errorMaxSizeMismatch(i, expected.Max, importedMemory.Max)
 					return
 				}
-				m.MemoryInstance = importedMemory
+
+				if expected.IsShared != importedMemory.Shared {
+					err = errorInvalidImport(i, fmt.Errorf("shared mismatch: %t != %t",
+						expected.IsShared, importedMemory.Shared))
+					return
+				}
+
+				if expected.Is64 != importedMemory.Is64 {
+					err = errorInvalidImport(i, fmt.Errorf("64-bit mismatch: %t != %t",
+						expected.Is64, importedMemory.Is64))
+					return
+				}
+				m.Memories[i.IndexPerType] = importedMemory
 			case ExternTypeGlobal:
 				expected := i.DescGlobal
 				importedGlobal := importedModule.Globals[imported.Index]
@@ -465,12 +579,35 @@ func (m *ModuleInstance) resolveImports(module *Module) (err error) {
 					return
 				}
 				m.Globals[i.IndexPerType] = importedGlobal
+			case ExternTypeTag:
+				expected := &module.TypeSection[i.DescTag]
+				importedTag := importedModule.Tags[imported.Index]
+				if !importedTag.Type.EqualsSignature(expected.Params, expected.Results) {
+					err = errorInvalidImport(i, fmt.Errorf("signature mismatch: %s != %s", expected, importedTag.Type))
+					return
+				}
+				m.Tags[i.IndexPerType] = importedTag
 			}
 		}
 	}
 	return
 }
 
+// resolveImport returns the module which resolver resolved the import to, or nil if it didn't resolve it.
+func (m *ModuleInstance) resolveImport(resolver ImportResolver, i *Import) (*ModuleInstance, error) {
+	resolved := resolver(i.Module, i.Name, i.Type)
+	if resolved == nil {
+		return nil, nil
+	}
+	importedModule, ok := resolved.(*ModuleInstance)
+	if !ok || importedModule.s != m.s {
+		return nil, errorInvalidImport(i, errors.New("resolved module is not instantiated in the same runtime"))
+	} else if err := importedModule.FailIfClosed(); err != nil {
+		return nil, errorInvalidImport(i, err)
+	}
+	return importedModule, nil
+}
+
 func errorMinSizeMismatch(i *Import, expected, actual uint32) error {
 	return errorInvalidImport(i, fmt.Errorf("minimum size mismatch: %d > %d", expected, actual))
 }
@@ -498,6 +635,24 @@ func executeConstExpressionI32(importedGlobals []*GlobalInstance, expr *Constant
 		id, _, _ := leb128.LoadUint32(expr.Data)
 		g := importedGlobals[id]
 		ret = int32(g.Val)
+	default:
+		ret = int32(executeExtendedConstExpression(importedGlobals, expr))
+	}
+	return
+}
+
+// executeConstExpressionI64 is like executeConstExpressionI32, but for the ValueTypeI64 offset of a data segment in a
+// 64-bit memory.
+func executeConstExpressionI64(importedGlobals []*GlobalInstance, expr *ConstantExpression) (ret int64) {
+	switch expr.Opcode {
+	case OpcodeI64Const:
+		ret, _, _ = leb128.LoadInt64(expr.Data)
+	case OpcodeGlobalGet:
+		id, _, _ := leb128.LoadUint32(expr.Data)
+		g := importedGlobals[id]
+		ret = int64(g.Val)
+	default:
+		ret = int64(executeExtendedConstExpression(importedGlobals, expr))
 	}
 	return
 }
@@ -548,6 +703,8 @@ func (g *GlobalInstance) initialize(importedGlobals []*GlobalInstance, expr *Con
 		g.Val = uint64(funcRefResolver(v))
 	case OpcodeVecV128Const:
 		g.Val, g.ValHi = binary.LittleEndian.Uint64(expr.Data[0:8]), binary.LittleEndian.Uint64(expr.Data[8:16])
+	default:
+		g.Val = executeExtendedConstExpression(importedGlobals, expr)
 	}
 }
 
@@ -3,6 +3,7 @@ package wasm
 import (
 	"errors"
 	"fmt"
+	"sort"
 
 	"github.com/tetratelabs/wazero/api"
 )
@@ -26,6 +27,7 @@ func (s *Store) deleteModule(m *ModuleInstance) error {
 	// on subsequent calls to deleteModule.
 	m.prev = nil
 	m.next = nil
+	s.unindexFunctionReferences(m)
 
 	if m.ModuleName != "" {
 		delete(s.nameToModule, m.ModuleName)
@@ -58,6 +60,44 @@ func (s *Store) module(moduleName string) (*ModuleInstance, error) {
 	return m, nil
 }
 
+// ownsFunctionReference returns true if the Reference was produced by the ModuleEngine of a module in this store.
+func (s *Store) ownsFunctionReference(ref Reference) bool {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+	// Find the last range beginning at or before ref, as the ranges don't overlap.
+	i := sort.Search(len(s.functionReferences), func(i int) bool { return s.functionReferences[i].begin > ref }) - 1
+	if i < 0 || ref >= s.functionReferences[i].end {
+		return false
+	}
+	return s.functionReferences[i].m.Engine.OwnsFunctionReference(ref)
+}
+
+// indexFunctionReferences adds the range of function references of the module to Store.functionReferences, so that
+// ownsFunctionReference can find it without iterating all modules. This must be called while holding the lock.
+func (s *Store) indexFunctionReferences(m *ModuleInstance) {
+	if m.Engine == nil {
+		return
+	}
+	begin, end := m.Engine.FunctionReferenceRange()
+	if begin == end {
+		return
+	}
+	i := sort.Search(len(s.functionReferences), func(i int) bool { return s.functionReferences[i].begin > begin })
+	s.functionReferences = append(s.functionReferences, functionReferenceRange{})
+	copy(s.functionReferences[i+1:], s.functionReferences[i:])
+	s.functionReferences[i] = functionReferenceRange{begin: begin, end: end, m: m}
+}
+
+// unindexFunctionReferences reverts indexFunctionReferences. This must be called while holding the lock.
+func (s *Store) unindexFunctionReferences(m *ModuleInstance) {
+	for i := range s.functionReferences {
+		if s.functionReferences[i].m == m {
+			s.functionReferences = append(s.functionReferences[:i], s.functionReferences[i+1:]...)
+			return
+		}
+	}
+}
+
 // registerModule registers a ModuleInstance into the store.
 // This makes the ModuleInstance visible for import if it's not anonymous, and ensures it is closed when the store is.
 func (s *Store) registerModule(m *ModuleInstance) error {
@@ -84,9 +124,26 @@ func (s *Store) registerModule(m *ModuleInstance) error {
 		m.next.prev = m
 	}
 	s.moduleList = m
+	s.indexFunctionReferences(m)
 	return nil
 }
 
+// Modules implements wazero.Runtime Modules
+func (s *Store) Modules() []api.Module {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	var ret []api.Module
+	for m := s.moduleList; m != nil; m = m.next {
+		ret = append(ret, m)
+	}
+	// moduleList is ordered from the newest, so reverse it.
+	for i, j := 0, len(ret)-1; i < j; i, j = i+1, j-1 {
+		ret[i], ret[j] = ret[j], ret[i]
+	}
+	return ret
+}
+
 // Module implements wazero.Runtime Module
 func (s *Store) Module(moduleName string) api.Module {
 	m, err := s.module(moduleName)
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/tetratelabs/wazero/api"
 	"github.com/tetratelabs/wazero/internal/testing/require"
 )
 
@@ -168,6 +169,54 @@ func TestStore_Module(t *testing.T) {
 	})
 }
 
+func TestStore_Modules(t *testing.T) {
+	s, m1, m2 := newTestStore()
+	require.Equal(t, []api.Module{m1, m2}, s.Modules())
+
+	require.NoError(t, s.deleteModule(m1))
+	require.Equal(t, []api.Module{m2}, s.Modules())
+
+	require.NoError(t, s.deleteModule(m2))
+	require.Nil(t, s.Modules())
+}
+
+func TestStore_ownsFunctionReference(t *testing.T) {
+	s := newStore()
+	one := &ModuleInstance{ModuleName: "1", Engine: &mockModuleEngine{functionRefs: map[Index]Reference{0: 0x20, 1: 0x28}}}
+	two := &ModuleInstance{ModuleName: "2", Engine: &mockModuleEngine{functionRefs: map[Index]Reference{0: 0x10}}}
+	three := &ModuleInstance{ModuleName: "3", Engine: &mockModuleEngine{functionRefs: map[Index]Reference{0: 0x30}}}
+	empty := &ModuleInstance{ModuleName: "empty", Engine: &mockModuleEngine{}}
+	for _, m := range []*ModuleInstance{one, two, three, empty} {
+		require.NoError(t, s.registerModule(m))
+	}
+
+	// Ranges are sorted by begin, regardless of the order modules were registered.
+	require.Equal(t, []functionReferenceRange{
+		{begin: 0x10, end: 0x11, m: two},
+		{begin: 0x20, end: 0x29, m: one},
+		{begin: 0x30, end: 0x31, m: three},
+	}, s.functionReferences)
+
+	for _, ref := range []Reference{0x10, 0x20, 0x28, 0x30} {
+		require.True(t, s.ownsFunctionReference(ref))
+	}
+	// Within the range of a module, but not one of its references.
+	require.False(t, s.ownsFunctionReference(0x24))
+	// Outside the ranges.
+	for _, ref := range []Reference{0x8, 0x11, 0x29, 0x31} {
+		require.False(t, s.ownsFunctionReference(ref))
+	}
+
+	// Deleting a module removes its references.
+	require.NoError(t, s.deleteModule(one))
+	require.False(t, s.ownsFunctionReference(0x20))
+	require.True(t, s.ownsFunctionReference(0x30))
+	require.Equal(t, []functionReferenceRange{
+		{begin: 0x10, end: 0x11, m: two},
+		{begin: 0x30, end: 0x31, m: three},
+	}, s.functionReferences)
+}
+
 // newTestStore sets up a new Store without adding test coverage its functions.
 func newTestStore() (*Store, *ModuleInstance, *ModuleInstance) {
 	s := newStore()
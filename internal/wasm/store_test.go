@@ -32,14 +32,14 @@ func TestModuleInstance_Memory(t *testing.T) {
 		{
 			name: "memory not exported, one page",
 			input: &Module{
-				MemorySection:           &Memory{Min: 1, Cap: 1},
+				MemorySection:           []Memory{{Min: 1, Cap: 1}},
 				MemoryDefinitionSection: []MemoryDefinition{{}},
 			},
 		},
 		{
 			name: "memory exported, different name",
 			input: &Module{
-				MemorySection:           &Memory{Min: 1, Cap: 1},
+				MemorySection:           []Memory{{Min: 1, Cap: 1}},
 				MemoryDefinitionSection: []MemoryDefinition{{}},
 				ExportSection:           []Export{{Type: ExternTypeMemory, Name: "momory", Index: 0}},
 			},
@@ -47,7 +47,7 @@ func TestModuleInstance_Memory(t *testing.T) {
 		{
 			name: "memory exported, but zero length",
 			input: &Module{
-				MemorySection:           &Memory{},
+				MemorySection:           []Memory{{}},
 				MemoryDefinitionSection: []MemoryDefinition{{}},
 				Exports:                 map[string]*Export{"memory": {Type: ExternTypeMemory, Name: "memory"}},
 			},
@@ -56,7 +56,7 @@ func TestModuleInstance_Memory(t *testing.T) {
 		{
 			name: "memory exported, one page",
 			input: &Module{
-				MemorySection:           &Memory{Min: 1, Cap: 1},
+				MemorySection:           []Memory{{Min: 1, Cap: 1}},
 				MemoryDefinitionSection: []MemoryDefinition{{}},
 				Exports:                 map[string]*Export{"memory": {Type: ExternTypeMemory, Name: "memory"}},
 			},
@@ -66,7 +66,7 @@ func TestModuleInstance_Memory(t *testing.T) {
 		{
 			name: "memory exported, two pages",
 			input: &Module{
-				MemorySection:           &Memory{Min: 2, Cap: 2},
+				MemorySection:           []Memory{{Min: 2, Cap: 2}},
 				MemoryDefinitionSection: []MemoryDefinition{{}},
 				Exports:                 map[string]*Export{"memory": {Type: ExternTypeMemory, Name: "memory"}},
 			},
@@ -81,7 +81,7 @@ func TestModuleInstance_Memory(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			s := newStore()
 
-			instance, err := s.Instantiate(testCtx, tc.input, "test", nil, nil)
+			instance, err := s.Instantiate(testCtx, tc.input, "test", nil, nil, nil)
 			require.NoError(t, err)
 
 			mem := instance.ExportedMemory("memory")
@@ -100,12 +100,13 @@ func TestStore_Instantiate(t *testing.T) {
 		"foo",
 		[]string{"fn"},
 		map[string]*HostFunc{"fn": {ExportName: "fn", Code: Code{GoFunc: func() {}}}},
+		nil,
 		api.CoreFeaturesV1,
 	)
 	require.NoError(t, err)
 
 	sysCtx := sys.DefaultContext(nil)
-	mod, err := s.Instantiate(testCtx, m, "bar", sysCtx, []FunctionTypeID{0})
+	mod, err := s.Instantiate(testCtx, m, "bar", sysCtx, []FunctionTypeID{0}, nil)
 	require.NoError(t, err)
 	defer mod.Close(testCtx)
 
@@ -146,18 +147,18 @@ func TestStore_CloseWithExitCode(t *testing.T) {
 				CodeSection:               []Code{{Body: []byte{OpcodeEnd}}},
 				Exports:                   map[string]*Export{"fn": {Type: ExternTypeFunc, Name: "fn"}},
 				FunctionDefinitionSection: []FunctionDefinition{{Functype: &v_v}},
-			}, importedModuleName, nil, []FunctionTypeID{0})
+			}, importedModuleName, nil, []FunctionTypeID{0}, nil)
 			require.NoError(t, err)
 
 			m2, err := s.Instantiate(testCtx, &Module{
 				ImportFunctionCount:     1,
 				TypeSection:             []FunctionType{v_v},
 				ImportSection:           []Import{{Type: ExternTypeFunc, Module: importedModuleName, Name: "fn", DescFunc: 0}},
-				MemorySection:           &Memory{Min: 1, Cap: 1},
+				MemorySection:           []Memory{{Min: 1, Cap: 1}},
 				MemoryDefinitionSection: []MemoryDefinition{{}},
 				GlobalSection:           []Global{{Type: GlobalType{}, Init: ConstantExpression{Opcode: OpcodeI32Const, Data: const1}}},
 				TableSection:            []Table{{Min: 10}},
-			}, importingModuleName, nil, []FunctionTypeID{0})
+			}, importingModuleName, nil, []FunctionTypeID{0}, nil)
 			require.NoError(t, err)
 
 			if tc.testClosed {
@@ -184,12 +185,13 @@ func TestStore_hammer(t *testing.T) {
 		importedModuleName,
 		[]string{"fn"},
 		map[string]*HostFunc{"fn": {ExportName: "fn", Code: Code{GoFunc: func() {}}}},
+		nil,
 		api.CoreFeaturesV1,
 	)
 	require.NoError(t, err)
 
 	s := newStore()
-	imported, err := s.Instantiate(testCtx, m, importedModuleName, nil, []FunctionTypeID{0})
+	imported, err := s.Instantiate(testCtx, m, importedModuleName, nil, []FunctionTypeID{0}, nil)
 	require.NoError(t, err)
 
 	_, ok := s.nameToModule[imported.Name()]
@@ -200,7 +202,7 @@ func TestStore_hammer(t *testing.T) {
 		TypeSection:             []FunctionType{v_v},
 		FunctionSection:         []uint32{0},
 		CodeSection:             []Code{{Body: []byte{OpcodeEnd}}},
-		MemorySection:           &Memory{Min: 1, Cap: 1},
+		MemorySection:           []Memory{{Min: 1, Cap: 1}},
 		MemoryDefinitionSection: []MemoryDefinition{{}},
 		GlobalSection: []Global{{
 			Type: GlobalType{ValType: ValueTypeI32},
@@ -221,7 +223,7 @@ func TestStore_hammer(t *testing.T) {
 		N = 100
 	}
 	hammer.NewHammer(t, P, N).Run(func(name string) {
-		mod, instantiateErr := s.Instantiate(testCtx, importingModule, name, sys.DefaultContext(nil), []FunctionTypeID{0})
+		mod, instantiateErr := s.Instantiate(testCtx, importingModule, name, sys.DefaultContext(nil), []FunctionTypeID{0}, nil)
 		require.NoError(t, instantiateErr)
 		require.NoError(t, mod.Close(testCtx))
 	}, nil)
@@ -243,12 +245,13 @@ func TestStore_hammer_close(t *testing.T) {
 		importedModuleName,
 		[]string{"fn"},
 		map[string]*HostFunc{"fn": {ExportName: "fn", Code: Code{GoFunc: func() {}}}},
+		nil,
 		api.CoreFeaturesV1,
 	)
 	require.NoError(t, err)
 
 	s := newStore()
-	imported, err := s.Instantiate(testCtx, m, importedModuleName, nil, []FunctionTypeID{0})
+	imported, err := s.Instantiate(testCtx, m, importedModuleName, nil, []FunctionTypeID{0}, nil)
 	require.NoError(t, err)
 
 	_, ok := s.nameToModule[imported.Name()]
@@ -259,7 +262,7 @@ func TestStore_hammer_close(t *testing.T) {
 		TypeSection:             []FunctionType{v_v},
 		FunctionSection:         []uint32{0},
 		CodeSection:             []Code{{Body: []byte{OpcodeEnd}}},
-		MemorySection:           &Memory{Min: 1, Cap: 1},
+		MemorySection:           []Memory{{Min: 1, Cap: 1}},
 		MemoryDefinitionSection: []MemoryDefinition{{}},
 		GlobalSection: []Global{{
 			Type: GlobalType{ValType: ValueTypeI32},
@@ -274,7 +277,7 @@ func TestStore_hammer_close(t *testing.T) {
 	const instCount = 10000
 	instances := make([]api.Module, instCount)
 	for i := 0; i < instCount; i++ {
-		mod, instantiateErr := s.Instantiate(testCtx, importingModule, strconv.Itoa(i), sys.DefaultContext(nil), []FunctionTypeID{0})
+		mod, instantiateErr := s.Instantiate(testCtx, importingModule, strconv.Itoa(i), sys.DefaultContext(nil), []FunctionTypeID{0}, nil)
 		require.NoError(t, instantiateErr)
 		instances[i] = mod
 	}
@@ -307,23 +310,24 @@ func TestStore_Instantiate_Errors(t *testing.T) {
 		importedModuleName,
 		[]string{"fn"},
 		map[string]*HostFunc{"fn": {ExportName: "fn", Code: Code{GoFunc: func() {}}}},
+		nil,
 		api.CoreFeaturesV1,
 	)
 	require.NoError(t, err)
 
 	t.Run("Fails if module name already in use", func(t *testing.T) {
 		s := newStore()
-		_, err = s.Instantiate(testCtx, m, importedModuleName, nil, []FunctionTypeID{0})
+		_, err = s.Instantiate(testCtx, m, importedModuleName, nil, []FunctionTypeID{0}, nil)
 		require.NoError(t, err)
 
 		// Trying to register it again should fail
-		_, err = s.Instantiate(testCtx, m, importedModuleName, nil, []FunctionTypeID{0})
+		_, err = s.Instantiate(testCtx, m, importedModuleName, nil, []FunctionTypeID{0}, nil)
 		require.EqualError(t, err, "module[imported] has already been instantiated")
 	})
 
 	t.Run("fail resolve import", func(t *testing.T) {
 		s := newStore()
-		_, err = s.Instantiate(testCtx, m, importedModuleName, nil, []FunctionTypeID{0})
+		_, err = s.Instantiate(testCtx, m, importedModuleName, nil, []FunctionTypeID{0}, nil)
 		require.NoError(t, err)
 
 		hm := s.nameToModule[importedModuleName]
@@ -341,14 +345,14 @@ func TestStore_Instantiate_Errors(t *testing.T) {
 				importedModuleName: {{Type: ExternTypeFunc, Module: importedModuleName, Name: "fn", DescFunc: 0}},
 				"non-exist":        {{Name: "fn", DescFunc: 0}},
 			},
-		}, importingModuleName, nil, nil)
+		}, importingModuleName, nil, nil, nil)
 		require.EqualError(t, err, "module[non-exist] not instantiated")
 	})
 
 	t.Run("creating engine failed", func(t *testing.T) {
 		s := newStore()
 
-		_, err = s.Instantiate(testCtx, m, importedModuleName, nil, []FunctionTypeID{0})
+		_, err = s.Instantiate(testCtx, m, importedModuleName, nil, []FunctionTypeID{0}, nil)
 		require.NoError(t, err)
 
 		hm := s.nameToModule[importedModuleName]
@@ -370,7 +374,7 @@ func TestStore_Instantiate_Errors(t *testing.T) {
 			},
 		}
 
-		_, err = s.Instantiate(testCtx, importingModule, importingModuleName, nil, []FunctionTypeID{0})
+		_, err = s.Instantiate(testCtx, importingModule, importingModuleName, nil, []FunctionTypeID{0}, nil)
 		require.EqualError(t, err, "some engine creation error")
 	})
 
@@ -379,7 +383,7 @@ func TestStore_Instantiate_Errors(t *testing.T) {
 		engine := s.Engine.(*mockEngine)
 		engine.callFailIndex = 1
 
-		_, err = s.Instantiate(testCtx, m, importedModuleName, nil, []FunctionTypeID{0})
+		_, err = s.Instantiate(testCtx, m, importedModuleName, nil, []FunctionTypeID{0}, nil)
 		require.NoError(t, err)
 
 		hm := s.nameToModule[importedModuleName]
@@ -397,7 +401,7 @@ func TestStore_Instantiate_Errors(t *testing.T) {
 			},
 		}
 
-		_, err = s.Instantiate(testCtx, importingModule, importingModuleName, nil, []FunctionTypeID{0})
+		_, err = s.Instantiate(testCtx, importingModule, importingModuleName, nil, []FunctionTypeID{0}, nil)
 		require.EqualError(t, err, "start function[1] failed: call failed")
 	})
 }
@@ -430,7 +434,7 @@ func (e *mockEngine) Close() error {
 }
 
 // CompileModule implements the same method as documented on wasm.Engine.
-func (e *mockEngine) CompileModule(context.Context, *Module, []experimental.FunctionListener, bool) error {
+func (e *mockEngine) CompileModule(context.Context, *Module, []experimental.FunctionListener, bool, bool, bool) error {
 	return nil
 }
 
@@ -439,6 +443,11 @@ func (e *mockModuleEngine) LookupFunction(*TableInstance, FunctionTypeID, Index)
 	return nil, nil
 }
 
+// LookupFunctionReference implements the same method as documented on wasm.ModuleEngine.
+func (e *mockModuleEngine) LookupFunctionReference(Reference, FunctionTypeID) (api.Function, error) {
+	return nil, nil
+}
+
 // CompiledModuleCount implements the same method as documented on wasm.Engine.
 func (e *mockEngine) CompiledModuleCount() uint32 { return 0 }
 
@@ -458,6 +467,29 @@ func (e *mockModuleEngine) FunctionInstanceReference(i Index) Reference {
 	return e.functionRefs[i]
 }
 
+// OwnsFunctionReference implements the same method as documented on wasm.ModuleEngine.
+func (e *mockModuleEngine) OwnsFunctionReference(ref Reference) bool {
+	for _, r := range e.functionRefs {
+		if r == ref {
+			return true
+		}
+	}
+	return false
+}
+
+// FunctionReferenceRange implements the same method as documented on wasm.ModuleEngine.
+func (e *mockModuleEngine) FunctionReferenceRange() (begin, end Reference) {
+	for _, r := range e.functionRefs {
+		if begin == 0 || r < begin {
+			begin = r
+		}
+		if r >= end {
+			end = r + 1
+		}
+	}
+	return
+}
+
 // ResolveImportedFunction implements the same method as documented on wasm.ModuleEngine.
 func (e *mockModuleEngine) ResolveImportedFunction(index, importedIndex Index, _ ModuleEngine) {
 	e.resolveImportsCalled[index] = importedIndex
@@ -670,13 +702,51 @@ func Test_resolveImports(t *testing.T) {
 
 	t.Run("module not instantiated", func(t *testing.T) {
 		m := &ModuleInstance{s: newStore()}
-		err := m.resolveImports(&Module{ImportPerModule: map[string][]*Import{"unknown": {{}}}})
+		err := m.resolveImports(&Module{ImportPerModule: map[string][]*Import{"unknown": {{}}}}, nil)
+		require.EqualError(t, err, "module[unknown] not instantiated")
+	})
+	t.Run("resolver", func(t *testing.T) {
+		s := newStore()
+		m := &ModuleInstance{s: s}
+		resolved := &ModuleInstance{s: s, Exports: map[string]*Export{}, ModuleName: "resolved"}
+		resolver := func(moduleName, name string, et ExternType) api.Module {
+			require.Equal(t, moduleName, "unknown")
+			require.Equal(t, name, "target")
+			require.Equal(t, ExternTypeGlobal, et)
+			return resolved
+		}
+
+		// The module is resolved instead of looked up in the store.
+		err := m.resolveImports(&Module{ImportPerModule: map[string][]*Import{
+			"unknown": {{Module: "unknown", Name: "target", Type: ExternTypeGlobal}},
+		}}, resolver)
+		require.EqualError(t, err, "\"target\" is not exported in module \"resolved\"")
+
+		// The resolved module must be from the same store.
+		resolved.s = newStore()
+		err = m.resolveImports(&Module{ImportPerModule: map[string][]*Import{
+			"unknown": {{Module: "unknown", Name: "target", Type: ExternTypeGlobal}},
+		}}, resolver)
+		require.EqualError(t, err, "import global[unknown.target]: resolved module is not instantiated in the same runtime")
+
+		// The resolved module must not be closed.
+		resolved.s = s
+		resolved.Closed.Store(exitCodeFlagResourceClosed | 2<<32)
+		err = m.resolveImports(&Module{ImportPerModule: map[string][]*Import{
+			"unknown": {{Module: "unknown", Name: "target", Type: ExternTypeGlobal}},
+		}}, resolver)
+		require.EqualError(t, err, "import global[unknown.target]: module closed with exit_code(2)")
+	})
+	t.Run("resolver returns nil", func(t *testing.T) {
+		m := &ModuleInstance{s: newStore()}
+		err := m.resolveImports(&Module{ImportPerModule: map[string][]*Import{"unknown": {{}}}},
+			func(string, string, ExternType) api.Module { return nil })
 		require.EqualError(t, err, "module[unknown] not instantiated")
 	})
 	t.Run("export instance not found", func(t *testing.T) {
 		m := &ModuleInstance{s: newStore()}
 		m.s.nameToModule[moduleName] = &ModuleInstance{Exports: map[string]*Export{}, ModuleName: moduleName}
-		err := m.resolveImports(&Module{ImportPerModule: map[string][]*Import{moduleName: {{Name: "unknown"}}}})
+		err := m.resolveImports(&Module{ImportPerModule: map[string][]*Import{moduleName: {{Name: "unknown"}}}}, nil)
 		require.EqualError(t, err, "\"unknown\" is not exported in module \"test\"")
 	})
 	t.Run("func", func(t *testing.T) {
@@ -712,7 +782,7 @@ func Test_resolveImports(t *testing.T) {
 			}
 
 			m := &ModuleInstance{Engine: &mockModuleEngine{resolveImportsCalled: map[Index]Index{}}, s: s, Source: module}
-			err := m.resolveImports(module)
+			err := m.resolveImports(module, nil)
 			require.NoError(t, err)
 
 			me := m.Engine.(*mockModuleEngine)
@@ -742,7 +812,7 @@ func Test_resolveImports(t *testing.T) {
 			}
 
 			m := &ModuleInstance{Engine: &mockModuleEngine{resolveImportsCalled: map[Index]Index{}}, s: s, Source: module}
-			err := m.resolveImports(module)
+			err := m.resolveImports(module, nil)
 			require.EqualError(t, err, "import func[test.target]: signature mismatch: v_f32 != v_v")
 		})
 	})
@@ -759,6 +829,7 @@ func Test_resolveImports(t *testing.T) {
 				&Module{
 					ImportPerModule: map[string][]*Import{moduleName: {{Name: name, Type: ExternTypeGlobal, DescGlobal: g.Type}}},
 				},
+				nil,
 			)
 			require.NoError(t, err)
 			require.True(t, globalsContain(m.Globals, g), "expected to find %v in %v", g, m.Globals)
@@ -778,7 +849,7 @@ func Test_resolveImports(t *testing.T) {
 				ImportPerModule: map[string][]*Import{moduleName: {
 					{Module: moduleName, Name: name, Type: ExternTypeGlobal, DescGlobal: GlobalType{Mutable: true}},
 				}},
-			})
+			}, nil)
 			require.EqualError(t, err, "import global[test.target]: mutability mismatch: true != false")
 		})
 		t.Run("type mismatch", func(t *testing.T) {
@@ -796,7 +867,7 @@ func Test_resolveImports(t *testing.T) {
 				ImportPerModule: map[string][]*Import{moduleName: {
 					{Module: moduleName, Name: name, Type: ExternTypeGlobal, DescGlobal: GlobalType{ValType: ValueTypeF64}},
 				}},
-			})
+			}, nil)
 			require.EqualError(t, err, "import global[test.target]: value type mismatch: f64 != i32")
 		})
 	})
@@ -807,42 +878,43 @@ func Test_resolveImports(t *testing.T) {
 			s := newStore()
 			s.nameToModule[moduleName] = &ModuleInstance{
 				MemoryInstance: memoryInst,
+				Memories:       []*MemoryInstance{memoryInst},
 				Exports: map[string]*Export{name: {
 					Type: ExternTypeMemory,
 				}},
 				ModuleName: moduleName,
 			}
-			m := &ModuleInstance{s: s}
+			m := &ModuleInstance{Memories: make([]*MemoryInstance, 1), s: s}
 			err := m.resolveImports(&Module{
 				ImportPerModule: map[string][]*Import{
 					moduleName: {{Module: moduleName, Name: name, Type: ExternTypeMemory, DescMem: &Memory{Max: max}}},
 				},
-			})
+			}, nil)
 			require.NoError(t, err)
-			require.Equal(t, m.MemoryInstance, memoryInst)
+			require.Equal(t, m.Memories[0], memoryInst)
 		})
 		t.Run("minimum size mismatch", func(t *testing.T) {
 			importMemoryType := &Memory{Min: 2, Cap: 2}
 			s := newStore()
 			s.nameToModule[moduleName] = &ModuleInstance{
-				MemoryInstance: &MemoryInstance{Min: importMemoryType.Min - 1, Cap: 2},
+				Memories: []*MemoryInstance{&MemoryInstance{Min: importMemoryType.Min - 1, Cap: 2}},
 				Exports: map[string]*Export{name: {
 					Type: ExternTypeMemory,
 				}},
 				ModuleName: moduleName,
 			}
-			m := &ModuleInstance{s: s}
+			m := &ModuleInstance{Memories: make([]*MemoryInstance, 1), s: s}
 			err := m.resolveImports(&Module{
 				ImportPerModule: map[string][]*Import{
 					moduleName: {{Module: moduleName, Name: name, Type: ExternTypeMemory, DescMem: importMemoryType}},
 				},
-			})
+			}, nil)
 			require.EqualError(t, err, "import memory[test.target]: minimum size mismatch: 2 > 1")
 		})
 		t.Run("maximum size mismatch", func(t *testing.T) {
 			s := newStore()
 			s.nameToModule[moduleName] = &ModuleInstance{
-				MemoryInstance: &MemoryInstance{Max: MemoryLimitPages},
+				Memories: []*MemoryInstance{&MemoryInstance{Max: MemoryLimitPages}},
 				Exports: map[string]*Export{name: {
 					Type: ExternTypeMemory,
 				}},
@@ -851,17 +923,17 @@ func Test_resolveImports(t *testing.T) {
 
 			max := uint32(10)
 			importMemoryType := &Memory{Max: max}
-			m := &ModuleInstance{s: s}
+			m := &ModuleInstance{Memories: make([]*MemoryInstance, 1), s: s}
 			err := m.resolveImports(&Module{
 				ImportPerModule: map[string][]*Import{moduleName: {{Module: moduleName, Name: name, Type: ExternTypeMemory, DescMem: importMemoryType}}},
-			})
+			}, nil)
 			require.EqualError(t, err, "import memory[test.target]: maximum size mismatch: 10 < 65536")
 		})
 	})
 }
 
 func TestModuleInstance_validateData(t *testing.T) {
-	m := &ModuleInstance{MemoryInstance: &MemoryInstance{Buffer: make([]byte, 5)}}
+	m := &ModuleInstance{Memories: []*MemoryInstance{{Buffer: make([]byte, 5)}}}
 	tests := []struct {
 		name   string
 		data   []DataSegment
@@ -906,17 +978,17 @@ func TestModuleInstance_validateData(t *testing.T) {
 
 func TestModuleInstance_applyData(t *testing.T) {
 	t.Run("ok", func(t *testing.T) {
-		m := &ModuleInstance{MemoryInstance: &MemoryInstance{Buffer: make([]byte, 10)}}
+		m := &ModuleInstance{Memories: []*MemoryInstance{{Buffer: make([]byte, 10)}}}
 		err := m.applyData([]DataSegment{
 			{OffsetExpression: ConstantExpression{Opcode: OpcodeI32Const, Data: const0}, Init: []byte{0xa, 0xf}},
 			{OffsetExpression: ConstantExpression{Opcode: OpcodeI32Const, Data: leb128.EncodeUint32(8)}, Init: []byte{0x1, 0x5}},
 		})
 		require.NoError(t, err)
-		require.Equal(t, []byte{0xa, 0xf, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x1, 0x5}, m.MemoryInstance.Buffer)
+		require.Equal(t, []byte{0xa, 0xf, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x1, 0x5}, m.Memories[0].Buffer)
 		require.Equal(t, [][]byte{{0xa, 0xf}, {0x1, 0x5}}, m.DataInstances)
 	})
 	t.Run("error", func(t *testing.T) {
-		m := &ModuleInstance{MemoryInstance: &MemoryInstance{Buffer: make([]byte, 5)}}
+		m := &ModuleInstance{Memories: []*MemoryInstance{{Buffer: make([]byte, 5)}}}
 		err := m.applyData([]DataSegment{
 			{OffsetExpression: ConstantExpression{Opcode: OpcodeI32Const, Data: leb128.EncodeUint32(8)}, Init: []byte{}},
 		})
@@ -1,11 +1,13 @@
 package wasm
 
 import (
+	"bytes"
 	"fmt"
 	"math"
 	"sync"
 
 	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/internalapi"
 	"github.com/tetratelabs/wazero/internal/leb128"
 )
 
@@ -26,6 +28,37 @@ const (
 	RefTypeExternref = ValueTypeExternref
 )
 
+const (
+	// RefTypePrefixNullable prefixes the heap type of a nullable typed reference, e.g. `(ref null $t)`.
+	// This is introduced in CoreFeatureFunctionReferences.
+	RefTypePrefixNullable byte = 0x63
+	// RefTypePrefixNonNullable prefixes the heap type of a non-nullable typed reference, e.g. `(ref $t)`.
+	// This is introduced in CoreFeatureFunctionReferences.
+	RefTypePrefixNonNullable byte = 0x64
+)
+
+// DecodeHeapType decodes the heap type following RefTypePrefixNullable or RefTypePrefixNonNullable, and returns the
+// type of the reference, which is either RefTypeFuncref or RefTypeExternref, as well as the count of bytes read.
+//
+// Note: References typed with a function type are treated as RefTypeFuncref, as wazero doesn't track the function
+// types and nullability of references during validation.
+func DecodeHeapType(r *bytes.Reader) (RefType, uint64, error) {
+	raw, num, err := leb128.DecodeInt33AsInt64(r)
+	if err != nil {
+		return 0, 0, fmt.Errorf("read heap type: %w", err)
+	}
+	switch {
+	case raw == -16: // 0x70 in original byte = func
+		return RefTypeFuncref, num, nil
+	case raw == -17: // 0x6f in original byte = extern
+		return RefTypeExternref, num, nil
+	case raw >= 0: // type index
+		return RefTypeFuncref, num, nil
+	default:
+		return 0, 0, fmt.Errorf("invalid heap type: %d", raw)
+	}
+}
+
 func RefTypeName(t RefType) (ret string) {
 	switch t {
 	case RefTypeFuncref:
@@ -127,6 +160,9 @@ type TableInstance struct {
 	// Type is either RefTypeFuncref or RefTypeExternRef.
 	Type RefType
 
+	// definition is known at compile time.
+	definition api.TableDefinition
+
 	// mux is used to prevent overlapping calls to Grow.
 	mux sync.RWMutex
 }
@@ -225,6 +261,10 @@ func (m *Module) validateTable(enabledFeatures api.CoreFeatures, tables []Table,
 						return err
 					}
 				}
+			} else if elem.OffsetExpr.IsExtended() && enabledFeatures.IsEnabled(api.CoreFeatureExtendedConst) {
+				if err := validateConstExpression(m.importedGlobalTypes(), 0, &elem.OffsetExpr, ValueTypeI32); err != nil {
+					return fmt.Errorf("%s[%d] has an invalid const expression: %w", SectionIDName(SectionIDElement), idx, err)
+				}
 			} else {
 				return fmt.Errorf("%s[%d] has an invalid const expression: %s", SectionIDName(SectionIDElement), idx, InstructionName(oc))
 			}
@@ -247,7 +287,7 @@ func (m *ModuleInstance) buildTables(module *Module, skipBoundCheck bool) (err e
 		// The module defining the table is the one that sets its Min/Max etc.
 		m.Tables[idx] = &TableInstance{
 			References: make([]Reference, tsec.Min), Min: tsec.Min, Max: tsec.Max,
-			Type: tsec.Type,
+			Type: tsec.Type, definition: module.TableDefinition(idx),
 		}
 		idx++
 	}
@@ -256,17 +296,7 @@ func (m *ModuleInstance) buildTables(module *Module, skipBoundCheck bool) (err e
 		for elemI := range module.ElementSection { // Do not loop over the value since elementSegments is a slice of value.
 			elem := &module.ElementSection[elemI]
 			table := m.Tables[elem.TableIndex]
-			var offset uint32
-			if elem.OffsetExpr.Opcode == OpcodeGlobalGet {
-				// Ignore error as it's already validated.
-				globalIdx, _, _ := leb128.LoadUint32(elem.OffsetExpr.Data)
-				global := m.Globals[globalIdx]
-				offset = uint32(global.Val)
-			} else { // i32.const
-				// Ignore error as it's already validated.
-				o, _, _ := leb128.LoadInt32(elem.OffsetExpr.Data)
-				offset = uint32(o)
-			}
+			offset := uint32(executeConstExpressionI32(m.Globals, &elem.OffsetExpr))
 
 			// Check to see if we are out-of-bounds
 			initCount := uint64(len(elem.Init))
@@ -309,6 +339,16 @@ func (m *Module) verifyImportGlobalI32(sectionID SectionID, sectionIdx Index, id
 	return fmt.Errorf("%s[%d] (global.get %d): out of range of imported globals", SectionIDName(sectionID), sectionIdx, idx)
 }
 
+// importedGlobalTypes returns the types of the imported globals, which constant expressions can reference.
+func (m *Module) importedGlobalTypes() (ret []GlobalType) {
+	for i := range m.ImportSection {
+		if imp := &m.ImportSection[i]; imp.Type == ExternTypeGlobal {
+			ret = append(ret, imp.DescGlobal)
+		}
+	}
+	return
+}
+
 // Grow appends the `initialRef` by `delta` times into the References slice.
 // Returns -1 if the operation is not valid, otherwise the old length of the table.
 //
@@ -338,3 +378,82 @@ func (t *TableInstance) Grow(delta uint32, initialRef Reference) (currentLen uin
 	}
 	return
 }
+
+// table wraps TableInstance to implement api.Table.
+type table struct {
+	internalapi.WazeroOnlyType
+	t *TableInstance
+	// m is the module the table was accessed from, used to look up functions.
+	m *ModuleInstance
+}
+
+// Definition implements the same method as documented on api.Table.
+func (t table) Definition() api.TableDefinition {
+	return t.t.definition
+}
+
+// Size implements the same method as documented on api.Table.
+func (t table) Size() uint32 {
+	t.t.mux.RLock()
+	defer t.t.mux.RUnlock()
+	return uint32(len(t.t.References))
+}
+
+// Grow implements the same method as documented on api.Table.
+func (t table) Grow(delta uint32, initialRef uint64) (previousSize uint32, ok bool) {
+	if !t.validReference(initialRef) {
+		return 0, false
+	}
+	if previousSize = t.t.Grow(delta, Reference(initialRef)); previousSize == 0xffffffff {
+		return 0, false
+	}
+	return previousSize, true
+}
+
+// Get implements the same method as documented on api.Table.
+func (t table) Get(offset uint32) (uint64, bool) {
+	t.t.mux.RLock()
+	defer t.t.mux.RUnlock()
+	if offset >= uint32(len(t.t.References)) {
+		return 0, false
+	}
+	return uint64(t.t.References[offset]), true
+}
+
+// Set implements the same method as documented on api.Table.
+func (t table) Set(offset uint32, ref uint64) bool {
+	if !t.validReference(ref) {
+		return false
+	}
+	t.t.mux.Lock()
+	defer t.t.mux.Unlock()
+	if offset >= uint32(len(t.t.References)) {
+		return false
+	}
+	t.t.References[offset] = Reference(ref)
+	return true
+}
+
+// validReference returns false if ref is a non-null funcref which wasn't produced by a module of the store, as the
+// engines dereference funcrefs without checking them.
+func (t table) validReference(ref uint64) bool {
+	if ref == 0 || t.t.Type != RefTypeFuncref {
+		return true
+	}
+	return t.m.ownsFunctionReference(Reference(ref))
+}
+
+// Function implements the same method as documented on api.Table.
+func (t table) Function(offset uint32, params, results []api.ValueType) (api.Function, error) {
+	typeID, err := t.m.s.GetFunctionTypeID(&FunctionType{Params: params, Results: results})
+	if err != nil {
+		return nil, err
+	}
+	// LookupFunction reads the references, which Set and Grow write under the lock.
+	t.t.mux.RLock()
+	defer t.t.mux.RUnlock()
+	return t.m.Engine.LookupFunction(t.t, typeID, offset)
+}
+
+// compile-time check to ensure table is a api.Table.
+var _ api.Table = table{}
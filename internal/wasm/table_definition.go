@@ -0,0 +1,126 @@
+package wasm
+
+import (
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/internalapi"
+)
+
+// ImportedTables returns the definitions of each imported table.
+func (m *Module) ImportedTables() (ret []api.TableDefinition) {
+	for i := uint32(0); i < m.ImportTableCount; i++ {
+		ret = append(ret, m.TableDefinition(i))
+	}
+	return
+}
+
+// ExportedTables returns the definitions of each exported table.
+func (m *Module) ExportedTables() map[string]api.TableDefinition {
+	ret := map[string]api.TableDefinition{}
+	for i := range m.ExportSection {
+		exp := &m.ExportSection[i]
+		if exp.Type == ExternTypeTable {
+			ret[exp.Name] = m.TableDefinition(exp.Index)
+		}
+	}
+	return ret
+}
+
+// TableDefinition returns the TableDefinition for the given `index`.
+func (m *Module) TableDefinition(index Index) *TableDefinition {
+	m.tableDefinitionSectionInitOnce.Do(m.buildTableDefinitions)
+	return &m.TableDefinitionSection[index]
+}
+
+// buildTableDefinitions generates table metadata that can be parsed from
+// the module. This must be called after all validation.
+func (m *Module) buildTableDefinitions() {
+	var moduleName string
+	if m.NameSection != nil {
+		moduleName = m.NameSection.ModuleName
+	}
+
+	m.TableDefinitionSection = make([]TableDefinition, 0, m.ImportTableCount+uint32(len(m.TableSection)))
+	importTableIdx := Index(0)
+	for i := range m.ImportSection {
+		imp := &m.ImportSection[i]
+		if imp.Type != ExternTypeTable {
+			continue
+		}
+
+		m.TableDefinitionSection = append(m.TableDefinitionSection, TableDefinition{
+			importDesc: &[2]string{imp.Module, imp.Name},
+			index:      importTableIdx,
+			table:      &imp.DescTable,
+		})
+		importTableIdx++
+	}
+
+	for i := range m.TableSection {
+		m.TableDefinitionSection = append(m.TableDefinitionSection, TableDefinition{
+			index: importTableIdx + Index(i),
+			table: &m.TableSection[i],
+		})
+	}
+
+	for i := range m.TableDefinitionSection {
+		d := &m.TableDefinitionSection[i]
+		d.moduleName = moduleName
+		for i := range m.ExportSection {
+			e := &m.ExportSection[i]
+			if e.Type == ExternTypeTable && e.Index == d.index {
+				d.exportNames = append(d.exportNames, e.Name)
+			}
+		}
+	}
+}
+
+// TableDefinition implements api.TableDefinition
+type TableDefinition struct {
+	internalapi.WazeroOnlyType
+	moduleName  string
+	index       Index
+	importDesc  *[2]string
+	exportNames []string
+	table       *Table
+}
+
+// ModuleName implements the same method as documented on api.TableDefinition.
+func (f *TableDefinition) ModuleName() string {
+	return f.moduleName
+}
+
+// Index implements the same method as documented on api.TableDefinition.
+func (f *TableDefinition) Index() uint32 {
+	return f.index
+}
+
+// Import implements the same method as documented on api.TableDefinition.
+func (f *TableDefinition) Import() (moduleName, name string, isImport bool) {
+	if importDesc := f.importDesc; importDesc != nil {
+		moduleName, name, isImport = importDesc[0], importDesc[1], true
+	}
+	return
+}
+
+// ExportNames implements the same method as documented on api.TableDefinition.
+func (f *TableDefinition) ExportNames() []string {
+	return f.exportNames
+}
+
+// Type implements the same method as documented on api.TableDefinition.
+func (f *TableDefinition) Type() api.RefType {
+	return f.table.Type
+}
+
+// Min implements the same method as documented on api.TableDefinition.
+func (f *TableDefinition) Min() uint32 {
+	return f.table.Min
+}
+
+// Max implements the same method as documented on api.TableDefinition.
+func (f *TableDefinition) Max() (max uint32, encoded bool) {
+	if f.table.Max != nil {
+		max, encoded = *f.table.Max, true
+	}
+	return
+}
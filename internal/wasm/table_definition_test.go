@@ -0,0 +1,109 @@
+package wasm
+
+import (
+	"testing"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestModule_TableDefinition(t *testing.T) {
+	three := uint32(3)
+	tests := []struct {
+		name            string
+		m               *Module
+		expectedImports []api.TableDefinition
+		expectedExports map[string]api.TableDefinition
+	}{
+		{
+			name:            "no exports",
+			m:               &Module{},
+			expectedExports: map[string]api.TableDefinition{},
+		},
+		{
+			name: "no tables",
+			m: &Module{
+				ExportSection: []Export{{Type: ExternTypeGlobal, Index: 0}},
+				GlobalSection: []Global{{}},
+			},
+			expectedExports: map[string]api.TableDefinition{},
+		},
+		{
+			name: "exports defined table{2,3}",
+			m: &Module{
+				ExportSection: []Export{
+					{Name: "table_index=0", Type: ExternTypeTable, Index: 0},
+					{Name: "", Type: ExternTypeGlobal, Index: 0},
+				},
+				GlobalSection: []Global{{}},
+				TableSection:  []Table{{Min: 2, Max: &three, Type: RefTypeFuncref}},
+			},
+			expectedExports: map[string]api.TableDefinition{
+				"table_index=0": &TableDefinition{
+					index:       0,
+					exportNames: []string{"table_index=0"},
+					table:       &Table{Min: 2, Max: &three, Type: RefTypeFuncref},
+				},
+			},
+		},
+		{
+			name: "exports imported table{0,} and defined table{2,3}",
+			m: &Module{
+				ImportTableCount: 1,
+				ImportSection: []Import{{
+					Type:      ExternTypeTable,
+					DescTable: Table{Type: RefTypeExternref},
+				}},
+				ExportSection: []Export{
+					{Name: "imported_table", Type: ExternTypeTable, Index: 0},
+					{Name: "table_index=1", Type: ExternTypeTable, Index: 1},
+				},
+				TableSection: []Table{{Min: 2, Max: &three, Type: RefTypeFuncref}},
+			},
+			expectedImports: []api.TableDefinition{
+				&TableDefinition{
+					index:       0,
+					importDesc:  &[2]string{"", ""},
+					exportNames: []string{"imported_table"},
+					table:       &Table{Type: RefTypeExternref},
+				},
+			},
+			expectedExports: map[string]api.TableDefinition{
+				"imported_table": &TableDefinition{
+					index:       0,
+					importDesc:  &[2]string{"", ""},
+					exportNames: []string{"imported_table"},
+					table:       &Table{Type: RefTypeExternref},
+				},
+				"table_index=1": &TableDefinition{
+					index:       1,
+					exportNames: []string{"table_index=1"},
+					table:       &Table{Min: 2, Max: &three, Type: RefTypeFuncref},
+				},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expectedImports, tc.m.ImportedTables())
+			require.Equal(t, tc.expectedExports, tc.m.ExportedTables())
+		})
+	}
+}
+
+func TestTableDefinition(t *testing.T) {
+	three := uint32(3)
+	d := &TableDefinition{table: &Table{Min: 1, Max: &three, Type: RefTypeExternref}}
+	require.Equal(t, api.RefTypeExternref, d.Type())
+	require.Equal(t, uint32(1), d.Min())
+	max, ok := d.Max()
+	require.True(t, ok)
+	require.Equal(t, three, max)
+
+	d = &TableDefinition{table: &Table{Type: RefTypeFuncref}}
+	require.Equal(t, api.RefTypeFuncref, d.Type())
+	_, ok = d.Max()
+	require.False(t, ok)
+}
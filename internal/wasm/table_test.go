@@ -33,7 +33,7 @@ func Test_resolveImports_table(t *testing.T) {
 			ImportPerModule: map[string][]*Import{
 				moduleName: {{Module: moduleName, Name: name, Type: ExternTypeTable, DescTable: Table{Max: &max}}},
 			},
-		})
+		}, nil)
 		require.NoError(t, err)
 		require.Equal(t, m.Tables[0], tableInst)
 	})
@@ -50,7 +50,7 @@ func Test_resolveImports_table(t *testing.T) {
 			ImportPerModule: map[string][]*Import{
 				moduleName: {{Module: moduleName, Name: name, Type: ExternTypeTable, DescTable: importTableType}},
 			},
-		})
+		}, nil)
 		require.EqualError(t, err, "import table[test.target]: minimum size mismatch: 2 > 1")
 	})
 	t.Run("maximum size mismatch", func(t *testing.T) {
@@ -67,7 +67,7 @@ func Test_resolveImports_table(t *testing.T) {
 			ImportPerModule: map[string][]*Import{
 				moduleName: {{Module: moduleName, Name: name, Type: ExternTypeTable, DescTable: importTableType}},
 			},
-		})
+		}, nil)
 		require.EqualError(t, err, "import table[test.target]: maximum size mismatch: 10, but actual has no max")
 	})
 	t.Run("type mismatch", func(t *testing.T) {
@@ -82,7 +82,7 @@ func Test_resolveImports_table(t *testing.T) {
 			ImportPerModule: map[string][]*Import{
 				moduleName: {{Module: moduleName, Name: name, Type: ExternTypeTable, DescTable: Table{Type: RefTypeExternref}}},
 			},
-		})
+		}, nil)
 		require.EqualError(t, err, "import table[test.target]: table type mismatch: externref != funcref")
 	})
 }
@@ -924,6 +924,11 @@ func TestModule_buildTables(t *testing.T) {
 			err := m.buildTables(tc.module, false)
 			require.NoError(t, err)
 
+			// Defined tables carry their definition, which isn't in the expectations.
+			for _, table := range m.Tables[len(tc.importedTables):] {
+				require.NotNil(t, table.definition)
+				table.definition = nil
+			}
 			require.Equal(t, tc.expectedTables, m.Tables)
 		})
 	}
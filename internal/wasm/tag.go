@@ -0,0 +1,181 @@
+package wasm
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/internalapi"
+	"github.com/tetratelabs/wazero/internal/leb128"
+)
+
+// Tag is a tag defined in a module, introduced in api.CoreFeatureExceptionHandling.
+//
+// See https://github.com/WebAssembly/exception-handling/blob/main/proposals/exception-handling/legacy/Exceptions.md#tag-section
+type Tag struct {
+	// Type is the index in Module.TypeSection of the function type, whose parameters are the values of exceptions
+	// thrown with this tag. The type has no results.
+	Type Index
+}
+
+// TryTableCatchKind is the kind of a catch clause of OpcodeTryTable.
+type TryTableCatchKind = byte
+
+const (
+	// TryTableCatchKindCatch catches the exceptions thrown with a tag, and branches with their values.
+	TryTableCatchKindCatch TryTableCatchKind = 0x00
+	// TryTableCatchKindCatchRef is the same as TryTableCatchKindCatch, except that a ValueTypeExnref referencing the
+	// exception is passed to the label after the values.
+	TryTableCatchKindCatchRef TryTableCatchKind = 0x01
+	// TryTableCatchKindCatchAll catches any exception, and branches without values.
+	TryTableCatchKindCatchAll TryTableCatchKind = 0x02
+	// TryTableCatchKindCatchAllRef is the same as TryTableCatchKindCatchAll, except that a ValueTypeExnref
+	// referencing the exception is passed to the label.
+	TryTableCatchKindCatchAllRef TryTableCatchKind = 0x03
+)
+
+// TryTableCatch is a catch clause of OpcodeTryTable.
+//
+// See https://github.com/WebAssembly/exception-handling/blob/main/proposals/exception-handling/Exceptions.md#control-flow-instructions
+type TryTableCatch struct {
+	Kind TryTableCatchKind
+	// Tag is the index of the tag caught, which is zero for TryTableCatchKindCatchAll and TryTableCatchKindCatchAllRef.
+	Tag Index
+	// Label is the index of the label to which this branches, relative to the block enclosing the try_table.
+	Label Index
+}
+
+// CatchesAll returns true if this catches any exception regardless of its tag.
+func (c *TryTableCatch) CatchesAll() bool {
+	return c.Kind == TryTableCatchKindCatchAll || c.Kind == TryTableCatchKindCatchAllRef
+}
+
+// PassesRef returns true if this passes a ValueTypeExnref referencing the exception to the label.
+func (c *TryTableCatch) PassesRef() bool {
+	return c.Kind == TryTableCatchKindCatchRef || c.Kind == TryTableCatchKindCatchAllRef
+}
+
+// DecodeTryTableCatches decodes the catch clauses of OpcodeTryTable, which follow its block type, and returns them
+// and the number of bytes read.
+func DecodeTryTableCatches(r *bytes.Reader) (catches []TryTableCatch, num uint64, err error) {
+	count, n, err := leb128.DecodeUint32(r)
+	if err != nil {
+		return nil, 0, fmt.Errorf("read the number of catches: %w", err)
+	}
+	num += n
+	if count == 0 {
+		return
+	}
+	catches = make([]TryTableCatch, count)
+	for i := range catches {
+		c := &catches[i]
+		if c.Kind, err = r.ReadByte(); err != nil {
+			return nil, 0, fmt.Errorf("read the kind of catch[%d]: %w", i, err)
+		}
+		num++
+		switch c.Kind {
+		case TryTableCatchKindCatch, TryTableCatchKindCatchRef:
+			if c.Tag, n, err = leb128.DecodeUint32(r); err != nil {
+				return nil, 0, fmt.Errorf("read the tag of catch[%d]: %w", i, err)
+			}
+			num += n
+		case TryTableCatchKindCatchAll, TryTableCatchKindCatchAllRef:
+		default:
+			return nil, 0, fmt.Errorf("invalid kind of catch[%d]: 0x%x", i, c.Kind)
+		}
+		if c.Label, n, err = leb128.DecodeUint32(r); err != nil {
+			return nil, 0, fmt.Errorf("read the label of catch[%d]: %w", i, err)
+		}
+		num += n
+	}
+	return
+}
+
+// TagInstance is the runtime representation of a Tag, which is shared by the modules importing it.
+//
+// This implements api.Tag.
+type TagInstance struct {
+	internalapi.WazeroOnlyType
+
+	// Type is the function type of the tag.
+	Type *FunctionType
+}
+
+// ParamTypes implements the same method as documented on api.Tag.
+func (t *TagInstance) ParamTypes() []api.ValueType {
+	return t.Type.Params
+}
+
+// AllTags returns the type indexes of all tags in a module including imported ones.
+func (m *Module) AllTags() (tags []Index) {
+	for i := range m.ImportSection {
+		if imp := &m.ImportSection[i]; imp.Type == ExternTypeTag {
+			tags = append(tags, imp.DescTag)
+		}
+	}
+	for i := range m.TagSection {
+		tags = append(tags, m.TagSection[i].Type)
+	}
+	return
+}
+
+// typeOfTag returns the function type of the tag at the index, which is prefixed by imports, or nil if out of range.
+func (m *Module) typeOfTag(tagIdx Index) *FunctionType {
+	typeSectionLength := uint32(len(m.TypeSection))
+	if tagIdx < m.ImportTagCount {
+		// Imports are not exclusively tags. This is the current tag index in the loop.
+		cur := Index(0)
+		for i := range m.ImportSection {
+			imp := &m.ImportSection[i]
+			if imp.Type != ExternTypeTag {
+				continue
+			}
+			if tagIdx == cur {
+				if imp.DescTag >= typeSectionLength {
+					return nil
+				}
+				return &m.TypeSection[imp.DescTag]
+			}
+			cur++
+		}
+	}
+
+	tagSectionIdx := tagIdx - m.ImportTagCount
+	if tagSectionIdx >= uint32(len(m.TagSection)) {
+		return nil
+	}
+	typeIdx := m.TagSection[tagSectionIdx].Type
+	if typeIdx >= typeSectionLength {
+		return nil
+	}
+	return &m.TypeSection[typeIdx]
+}
+
+// validateTags ensures the types of tags, including imported ones, exist and have no results.
+func (m *Module) validateTags(tags []Index) error {
+	for i, typeIndex := range tags {
+		if typeIndex >= uint32(len(m.TypeSection)) {
+			return fmt.Errorf("type index of tag[%d] out of range", i)
+		}
+		if len(m.TypeSection[typeIndex].Results) > 0 {
+			return fmt.Errorf("type of tag[%d] must have no results: %s", i, &m.TypeSection[typeIndex])
+		}
+	}
+	return nil
+}
+
+func (m *ModuleInstance) buildTags(module *Module) {
+	importedCount := int(module.ImportTagCount)
+	for i := range module.TagSection {
+		m.Tags[importedCount+i] = &TagInstance{Type: &module.TypeSection[module.TagSection[i].Type]}
+	}
+}
+
+// ExportedTag implements the same method as documented on api.Module.
+func (m *ModuleInstance) ExportedTag(name string) api.Tag {
+	exp, err := m.getExport(name, ExternTypeTag)
+	if err != nil {
+		return nil
+	}
+	return m.Tags[exp.Index]
+}
@@ -0,0 +1,123 @@
+package wasm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/tetratelabs/wazero/sys"
+)
+
+// threadGroup is the set of instances of the same module which share its memory as threads, so that exiting any of
+// them exits all of them, as is the case for threads of a process.
+type threadGroup struct {
+	mux sync.Mutex
+	// main is the module which spawned the first thread. This owns the resources shared with the threads, such as Sys.
+	main *ModuleInstance
+	// threads are the spawned modules which haven't exited yet.
+	threads map[*ModuleInstance]struct{}
+	// count is the number of threads including the ones being instantiated, which is limited by main.MaxThreads.
+	count uint32
+	// lastID is the ID of the most recently spawned thread.
+	lastID uint32
+	// exited is true once any module in the group exited other than by ExitThread.
+	exited bool
+	// exitCode is the exit code of the module which exited the group.
+	exitCode uint32
+}
+
+// SpawnThread instantiates the source module of `m` as a new anonymous module sharing its imports, notably the shared
+// memory, and Sys. The returned ID is unique in the group of threads spawned from `m`, starting at one.
+//
+// Closing any module in the group closes all of them with the same exit code, except ExitThread which only closes
+// the thread it is called on.
+//
+// # Notes
+//
+//   - The number of threads running at the same time is limited by MaxThreads of the module which spawned the first.
+//   - Active data segments and the start function are applied again on instantiation, so toolchains which support
+//     threads initialize memory with passive data segments guarded by an atomic flag.
+func (m *ModuleInstance) SpawnThread(ctx context.Context) (thread *ModuleInstance, id uint32, err error) {
+	if mem := m.MemoryInstance; mem == nil || !mem.Shared || m.Source.ImportMemoryCount == 0 {
+		return nil, 0, errors.New("threads require an imported shared memory")
+	}
+
+	g := m.threadGroup()
+	g.mux.Lock()
+	if g.exited {
+		g.mux.Unlock()
+		return nil, 0, sys.NewExitError(g.exitCode)
+	} else if g.count >= g.main.MaxThreads {
+		g.mux.Unlock()
+		return nil, 0, fmt.Errorf("thread limit %d reached", g.main.MaxThreads)
+	}
+	g.count++
+	g.lastID++
+	id = g.lastID
+	g.mux.Unlock()
+
+	thread, err = m.s.Instantiate(ctx, m.Source, "", m.Sys, m.TypeIDs, m.importResolver)
+
+	g.mux.Lock()
+	if err != nil {
+		g.count--
+		g.mux.Unlock()
+		return nil, 0, err
+	}
+	thread.threads.Store(g)
+	if g.exited { // The group exited while the thread was instantiating.
+		g.count--
+		g.mux.Unlock()
+		_ = thread.CloseWithExitCode(ctx, g.exitCode)
+		return nil, 0, sys.NewExitError(g.exitCode)
+	}
+	g.threads[thread] = struct{}{}
+	g.mux.Unlock()
+	return thread, id, nil
+}
+
+// ExitThread closes this module, spawned by SpawnThread, without closing the others in its group.
+func (m *ModuleInstance) ExitThread(ctx context.Context) error {
+	if g := m.threads.Load(); g != nil {
+		g.mux.Lock()
+		if _, ok := g.threads[m]; ok {
+			delete(g.threads, m)
+			g.count--
+		}
+		g.mux.Unlock()
+	}
+	return m.CloseWithExitCode(ctx, 0)
+}
+
+// threadGroup returns the group of threads of this module, creating it if this module hasn't spawned any yet.
+func (m *ModuleInstance) threadGroup() *threadGroup {
+	if g := m.threads.Load(); g != nil {
+		return g
+	}
+	m.threads.CompareAndSwap(nil, &threadGroup{main: m, threads: map[*ModuleInstance]struct{}{}})
+	return m.threads.Load()
+}
+
+// exit closes all the modules in the group with the exit code of `m`, unless `m` is a thread which left the group
+// via ExitThread.
+func (g *threadGroup) exit(ctx context.Context, m *ModuleInstance, exitCode uint32) {
+	g.mux.Lock()
+	if _, ok := g.threads[m]; g.exited || (!ok && m != g.main) {
+		g.mux.Unlock()
+		return
+	}
+	g.exited, g.exitCode = true, exitCode
+	modules := make([]*ModuleInstance, 0, len(g.threads)+1)
+	modules = append(modules, g.main)
+	for thread := range g.threads {
+		modules = append(modules, thread)
+	}
+	g.threads = nil
+	g.mux.Unlock()
+
+	// Close outside the lock, as closing each module calls exit again.
+	for _, module := range modules {
+		_ = module.CloseWithExitCode(ctx, exitCode)
+	}
+}
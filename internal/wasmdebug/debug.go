@@ -132,6 +132,11 @@ func (s *stackTrace) FromRecovered(recovered interface{}) error {
 		return fmt.Errorf("wasm error: %w\nwasm stack trace:\n\t%s", wasmErr, stack)
 	}
 
+	// An uncaught exception isn't an error of the host, so don't mention it was recovered either.
+	if exc, ok := recovered.(*api.Exception); ok {
+		return fmt.Errorf("%w\nwasm stack trace:\n\t%s", exc, stack)
+	}
+
 	// If we have a runtime.Error, something severe happened which should include the stack trace. This could be
 	// a nil pointer from wazero or a user-defined function from HostModuleBuilder.
 	if runtimeErr, ok := recovered.(runtime.Error); ok {
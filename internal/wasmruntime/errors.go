@@ -27,6 +27,24 @@ var (
 	ErrRuntimeInvalidTableAccess = New("invalid table access")
 	// ErrRuntimeIndirectCallTypeMismatch indicates that the type check failed during call_indirect.
 	ErrRuntimeIndirectCallTypeMismatch = New("indirect call type mismatch")
+	// ErrRuntimeOutOfFuel indicates that the fuel of the module was insufficient
+	// to execute the next block of instructions.
+	ErrRuntimeOutOfFuel = New("out of fuel")
+	// ErrRuntimeEpochInterrupted indicates that the epoch deadline of the call
+	// was reached.
+	ErrRuntimeEpochInterrupted = New("epoch deadline reached")
+	// ErrRuntimeUnalignedAtomic indicates that an atomic instruction accessed the
+	// memory at an address which isn't aligned to the size of the access.
+	ErrRuntimeUnalignedAtomic = New("unaligned atomic")
+	// ErrRuntimeExpectedSharedMemory indicates that memory.atomic.wait32 or
+	// memory.atomic.wait64 was executed against a memory which is not shared.
+	ErrRuntimeExpectedSharedMemory = New("expected shared memory")
+	// ErrRuntimeNullReference indicates that ref.as_non_null or throw_ref was executed
+	// against a null reference.
+	ErrRuntimeNullReference = New("null reference")
+	// ErrRuntimeNullFunctionReference indicates that call_ref was executed
+	// against a null function reference.
+	ErrRuntimeNullFunctionReference = New("null function reference")
 )
 
 // Error is returned by a wasm.Engine during the execution of Wasm functions, and they indicate that the Wasm runtime
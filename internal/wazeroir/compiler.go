@@ -21,6 +21,12 @@ const (
 	controlFrameKindLoop
 	controlFrameKindIfWithElse
 	controlFrameKindIfWithoutElse
+	// controlFrameKindTry is the body of a try block.
+	controlFrameKindTry
+	// controlFrameKindCatch is a try block after its first catch.
+	controlFrameKindCatch
+	// controlFrameKindTryTable is the body of a try_table block.
+	controlFrameKindTryTable
 )
 
 type (
@@ -31,6 +37,11 @@ type (
 		originalStackLenWithoutParam int
 		blockType                    *wasm.FunctionType
 		kind                         controlFrameKind
+		// handler is the index in CompilationResult.ExceptionHandlers for controlFrameKindTry, controlFrameKindCatch
+		// and controlFrameKindTryTable.
+		handler int
+		// catches are the catch clauses of controlFrameKindTryTable, whose landing pads are emitted at its end.
+		catches []wasm.TryTableCatch
 	}
 	controlFrames struct{ frames []controlFrame }
 )
@@ -54,7 +65,10 @@ func (c *controlFrame) asLabel() Label {
 	case controlFrameKindFunction:
 		return NewLabel(LabelKindReturn, 0)
 	case controlFrameKindIfWithElse,
-		controlFrameKindIfWithoutElse:
+		controlFrameKindIfWithoutElse,
+		controlFrameKindTry,
+		controlFrameKindCatch,
+		controlFrameKindTryTable:
 		return NewLabel(LabelKindContinuation, c.frameID)
 	}
 	panic(fmt.Sprintf("unreachable: a bug in wazeroir implementation: %v", c.kind))
@@ -102,6 +116,17 @@ func (c *controlFrames) push(frame controlFrame) {
 	c.frames = append(c.frames, frame)
 }
 
+// exceptionHandler returns the index of the handler of exceptions thrown at the n-th frame, which is the innermost
+// try or try_table block around it except the ones already in their catches, or -1 if there's none.
+func (c *controlFrames) exceptionHandler(n int) int {
+	for i := len(c.frames) - n - 1; i >= 0; i-- {
+		if frame := &c.frames[i]; frame.kind == controlFrameKindTry || frame.kind == controlFrameKindTryTable {
+			return frame.handler
+		}
+	}
+	return -1
+}
+
 func (c *Compiler) initializeStack() {
 	// Reuse the existing slice.
 	c.localIndexToStackHeightInUint64 = c.localIndexToStackHeightInUint64[:0]
@@ -180,8 +205,14 @@ type Compiler struct {
 	types []wasm.FunctionType
 	// funcs holds the type indexes for all declared functions in the module where the target function exists.
 	funcs []uint32
+	// tags holds the type indexes for all declared tags in the module where the target function exists.
+	tags []uint32
 	// globals holds the global types for all declared globals in the module where the target function exists.
 	globals []wasm.GlobalType
+	// memories holds all declared memories in the module where the target function exists.
+	memories []*wasm.Memory
+	// hasMemory64 is true if any of memories is 64-bit, introduced in api.CoreFeatureMemory64.
+	hasMemory64 bool
 
 	// needSourceOffset is true if this module requires DWARF based stack trace.
 	needSourceOffset bool
@@ -189,6 +220,16 @@ type Compiler struct {
 	bodyOffsetInCodeSection uint64
 
 	ensureTermination bool
+	// meterFuel is true if the fuel consumption is emitted at the beginning of each block.
+	meterFuel bool
+	// fuelCosts are the count of Wasm instructions consuming fuel in each block of operations, which begins at the
+	// function entry or at a label or catch. See insertFuelConsumption.
+	fuelCosts []uint64
+	// checkEpoch is true if the epoch deadline is checked at the beginning of each function and loop.
+	checkEpoch bool
+	// memorySelected is true if the current instruction selected a memory other than zero, which is selected again
+	// after the instruction.
+	memorySelected bool
 	// Pre-allocated bytes.Reader to be used in various places.
 	br             *bytes.Reader
 	funcTypeToSigs funcTypeToIRSignatures
@@ -235,6 +276,9 @@ type CompilationResult struct {
 	//
 	// This example the label corresponding to `(block i32.const 1111)` is never be reached at runtime because `br 0` exits the function before we reach there
 	LabelCallers map[Label]uint32
+	// ExceptionHandlers holds the handlers of the try blocks in this function in the order of their beginning.
+	// Non nil only when the function contains try blocks, introduced in api.CoreFeatureExceptionHandling.
+	ExceptionHandlers []ExceptionHandler
 	// UsesMemory is true if this function might use memory.
 	UsesMemory bool
 
@@ -246,6 +290,8 @@ type CompilationResult struct {
 	Functions []wasm.Index
 	// Types holds all the types in the module from which this function is compiled.
 	Types []wasm.FunctionType
+	// Tags holds the type indexes of all the tags in the module from which this function is compiled.
+	Tags []wasm.Index
 	// HasMemory is true if the module from which this function is compiled has memory declaration.
 	HasMemory bool
 	// HasTable is true if the module from which this function is compiled has table declaration.
@@ -258,16 +304,22 @@ type CompilationResult struct {
 
 // NewCompiler returns the new *Compiler for the given parameters.
 // Use Compiler.Next function to get compilation result per function.
-func NewCompiler(enabledFeatures api.CoreFeatures, callFrameStackSizeInUint64 int, module *wasm.Module, ensureTermination bool) (*Compiler, error) {
+func NewCompiler(enabledFeatures api.CoreFeatures, callFrameStackSizeInUint64 int, module *wasm.Module, ensureTermination, meterFuel, checkEpoch bool) (*Compiler, error) {
 	functions, globals, mem, tables, err := module.AllDeclarations()
 	if err != nil {
 		return nil, err
 	}
 
-	hasMemory, hasTable, hasDataInstances, hasElementInstances := mem != nil, len(tables) > 0,
+	hasMemory, hasTable, hasDataInstances, hasElementInstances := len(mem) > 0, len(tables) > 0,
 		len(module.DataSection) > 0, len(module.ElementSection) > 0
 
 	types := module.TypeSection
+	tags := module.AllTags()
+
+	var hasMemory64 bool
+	for _, m := range mem {
+		hasMemory64 = hasMemory64 || m.Is64
+	}
 
 	c := &Compiler{
 		module:                     module,
@@ -278,6 +330,7 @@ func NewCompiler(enabledFeatures api.CoreFeatures, callFrameStackSizeInUint64 in
 			Globals:             globals,
 			Functions:           functions,
 			Types:               types,
+			Tags:                tags,
 			HasMemory:           hasMemory,
 			HasTable:            hasTable,
 			HasDataInstances:    hasDataInstances,
@@ -285,13 +338,19 @@ func NewCompiler(enabledFeatures api.CoreFeatures, callFrameStackSizeInUint64 in
 			LabelCallers:        map[Label]uint32{},
 		},
 		globals:           globals,
+		memories:          mem,
+		hasMemory64:       hasMemory64,
 		funcs:             functions,
 		types:             types,
+		tags:              tags,
 		ensureTermination: ensureTermination,
+		meterFuel:         meterFuel,
+		checkEpoch:        checkEpoch,
 		br:                bytes.NewReader(nil),
 		funcTypeToSigs: funcTypeToIRSignatures{
 			indirectCalls: make([]*signature, len(types)),
 			directCalls:   make([]*signature, len(types)),
+			refCalls:      make([]*signature, len(types)),
 			wasmTypes:     types,
 		},
 		needSourceOffset: module.DWARFLines != nil,
@@ -301,7 +360,17 @@ func NewCompiler(enabledFeatures api.CoreFeatures, callFrameStackSizeInUint64 in
 
 // Next returns the next CompilationResult for this Compiler.
 func (c *Compiler) Next() (*CompilationResult, error) {
-	funcIndex := c.next
+	result, err := c.Compile(c.next)
+	if err != nil {
+		return nil, err
+	}
+	c.next++
+	return result, nil
+}
+
+// Compile returns the CompilationResult of the function at the given index in the code section, which is reused by
+// the next compilation. Different Compilers of the same module can be used concurrently.
+func (c *Compiler) Compile(funcIndex int) (*CompilationResult, error) {
 	code := &c.module.CodeSection[funcIndex]
 	sig := &c.types[c.module.FunctionSection[funcIndex]]
 
@@ -309,6 +378,7 @@ func (c *Compiler) Next() (*CompilationResult, error) {
 	c.result.Operations = c.result.Operations[:0]
 	c.result.IROperationSourceOffsetsInWasmBinary = c.result.IROperationSourceOffsetsInWasmBinary[:0]
 	c.result.UsesMemory = false
+	c.result.ExceptionHandlers = nil
 	// Clears the existing entries in LabelCallers.
 	for frameID := uint32(0); frameID <= c.currentFrameID; frameID++ {
 		for k := LabelKind(0); k < LabelKindNum; k++ {
@@ -320,11 +390,11 @@ func (c *Compiler) Next() (*CompilationResult, error) {
 	c.currentOpPC = 0
 	c.currentFrameID = 0
 	c.unreachableState.on, c.unreachableState.depth = false, 0
+	c.fuelCosts = append(c.fuelCosts[:0], 0)
 
 	if err := c.compile(sig, code.Body, code.LocalTypes, code.BodyOffsetInCodeSection); err != nil {
 		return nil, err
 	}
-	c.next++
 	return &c.result, nil
 }
 
@@ -352,6 +422,11 @@ func (c *Compiler) compile(sig *wasm.FunctionType, body []byte, localTypes []was
 		c.emitDefaultValue(t)
 	}
 
+	// Check the epoch on entry, as recursion can run for long without any loop.
+	if c.checkEpoch {
+		c.emit(NewOperationCheckEpoch())
+	}
+
 	// Insert the function control frame.
 	c.controlFrames.push(controlFrame{
 		frameID:   c.nextFrameID(),
@@ -361,13 +436,95 @@ func (c *Compiler) compile(sig *wasm.FunctionType, body []byte, localTypes []was
 
 	// Now, enter the function body.
 	for !c.controlFrames.empty() && c.pc < uint64(len(c.body)) {
+		// Instructions which emit a label or catch do so after their other operations, if any, so they are charged
+		// to the block they end.
+		if c.meterFuel && !c.unreachableState.on && wasm.ConsumesFuel(c.body[c.pc]) {
+			c.fuelCosts[len(c.fuelCosts)-1]++
+		}
 		if err := c.handleInstruction(); err != nil {
 			return fmt.Errorf("handling instruction: %w", err)
 		}
+		if c.memorySelected {
+			c.emit(NewOperationSelectMemory(0))
+			c.memorySelected = false
+		}
+	}
+
+	if c.meterFuel {
+		c.insertFuelConsumption()
 	}
 	return nil
 }
 
+// insertFuelConsumption inserts OperationKindConsumeFuel at the beginning of the function and after each label,
+// charging the number of Wasm instructions consuming fuel until the next label. See wasm.ConsumesFuel.
+//
+// Each block of operations between labels is entered only from its beginning, as branches always target labels.
+// Therefore, the fuel is consumed once for the entire block, before any of its operations is executed. Catches are
+// treated as labels, as they are entered by the engines catching exceptions.
+func (c *Compiler) insertFuelConsumption() {
+	ops := c.result.Operations
+	offsets := c.result.IROperationSourceOffsetsInWasmBinary
+
+	// newIndexes maps the index of each operation to the one after the insertion, which is necessary to update the
+	// ranges and catches of the exception handlers.
+	var newIndexes []uint64
+	if len(c.result.ExceptionHandlers) > 0 {
+		newIndexes = make([]uint64, len(ops)+1)
+	}
+
+	newOps := make([]UnionOperation, 0, len(ops)+len(c.fuelCosts))
+	var newOffsets []uint64
+	if offsets != nil {
+		newOffsets = make([]uint64, 0, cap(newOps))
+	}
+
+	// consume appends the consumption of the block at the given index, whose source offset is the one of the
+	// operation at i, the first of the block.
+	consume := func(block, i int) {
+		if cost := c.fuelCosts[block]; cost > 0 {
+			newOps = append(newOps, NewOperationConsumeFuel(cost))
+			if offsets != nil {
+				if i >= len(offsets) {
+					i = len(offsets) - 1
+				}
+				newOffsets = append(newOffsets, offsets[i])
+			}
+		}
+	}
+
+	block := 0
+	consume(block, 0)
+	for i := range ops {
+		if newIndexes != nil {
+			newIndexes[i] = uint64(len(newOps))
+		}
+		newOps = append(newOps, ops[i])
+		if offsets != nil {
+			newOffsets = append(newOffsets, offsets[i])
+		}
+		if kind := ops[i].Kind; kind == OperationKindLabel || kind == OperationKindCatch {
+			block++
+			consume(block, i+1)
+		}
+	}
+	if newIndexes != nil {
+		newIndexes[len(ops)] = uint64(len(newOps))
+	}
+
+	c.result.Operations = newOps
+	c.result.IROperationSourceOffsetsInWasmBinary = newOffsets
+
+	for i := range c.result.ExceptionHandlers {
+		handler := &c.result.ExceptionHandlers[i]
+		handler.Start, handler.End = newIndexes[handler.Start], newIndexes[handler.End]
+		for j := range handler.Catches {
+			catch := &handler.Catches[j]
+			catch.Target = newIndexes[catch.Target]
+		}
+	}
+}
+
 // Translate the current Wasm instruction to wazeroir's operations,
 // and emit the results into c.results.
 func (c *Compiler) handleInstruction() error {
@@ -379,6 +536,8 @@ func (c *Compiler) handleInstruction() error {
 			instName = wasm.VectorInstructionName(c.body[c.pc+1])
 		} else if op == wasm.OpcodeMiscPrefix {
 			instName = wasm.MiscInstructionName(c.body[c.pc+1])
+		} else if op == wasm.OpcodeAtomicPrefix {
+			instName = wasm.AtomicInstructionName(c.body[c.pc+1])
 		} else {
 			instName = wasm.InstructionName(op)
 		}
@@ -474,6 +633,9 @@ operatorSwitch:
 		if c.ensureTermination {
 			c.emit(NewOperationBuiltinFunctionCheckExitCode())
 		}
+		if c.checkEpoch {
+			c.emit(NewOperationCheckEpoch())
+		}
 	case wasm.OpcodeIf:
 		c.br.Reset(c.body[c.pc+1:])
 		bt, num, err := wasm.DecodeBlockType(c.types, c.br, c.enabledFeatures)
@@ -565,7 +727,159 @@ operatorSwitch:
 		c.emit(NewOperationBr(continuationLabel))
 		// Initiate the else block.
 		c.emit(NewOperationLabel(elseLabel))
-	case wasm.OpcodeEnd:
+	case wasm.OpcodeTry:
+		c.br.Reset(c.body[c.pc+1:])
+		bt, num, err := wasm.DecodeBlockType(c.types, c.br, c.enabledFeatures)
+		if err != nil {
+			return fmt.Errorf("reading block type for try instruction: %w", err)
+		}
+		c.pc += num
+
+		if c.unreachableState.on {
+			// If it is currently in unreachable,
+			// just remove the entire block.
+			c.unreachableState.depth++
+			break operatorSwitch
+		}
+
+		// Create a new frame -- entering try, and its handler whose range ends at the first catch.
+		frame := controlFrame{
+			frameID:                      c.nextFrameID(),
+			originalStackLenWithoutParam: len(c.stack) - len(bt.Params),
+			kind:                         controlFrameKindTry,
+			blockType:                    bt,
+			handler:                      len(c.result.ExceptionHandlers),
+		}
+		c.result.ExceptionHandlers = append(c.result.ExceptionHandlers, ExceptionHandler{
+			Start:       uint64(len(c.result.Operations)),
+			Next:        c.controlFrames.exceptionHandler(0),
+			StackHeight: c.stackLenInUint64(frame.originalStackLenWithoutParam),
+		})
+		c.controlFrames.push(frame)
+		c.emit(NewOperationTry(frame.handler))
+	case wasm.OpcodeCatch, wasm.OpcodeCatchAll:
+		var tagIndex uint32
+		if op == wasm.OpcodeCatch {
+			v, n, err := leb128.LoadUint32(c.body[c.pc+1:])
+			if err != nil {
+				return fmt.Errorf("read the tag for catch: %w", err)
+			}
+			c.pc += n
+			tagIndex = v
+		}
+
+		if c.unreachableState.on && c.unreachableState.depth > 0 {
+			// If it is currently in unreachable, and the nested try,
+			// just remove the entire catch block.
+			break operatorSwitch
+		}
+
+		frame := c.controlFrames.top()
+		c.endTryBody(frame, op, 0)
+		if !c.unreachableState.on {
+			// Exit the body of try or the previous catch to the continuation of the block.
+			continuationLabel := NewLabel(LabelKindContinuation, frame.frameID)
+			c.result.LabelCallers[continuationLabel]++
+			c.emit(NewOperationDrop(c.getFrameDropRange(frame, true)))
+			c.emit(NewOperationBr(continuationLabel))
+		}
+		// The catch is entered only from the engine catching an exception, so it is reachable
+		// even if the previous instructions are not.
+		c.resetUnreachable()
+		frame.kind = controlFrameKindCatch
+
+		// Reset the stack manipulated by the previous instructions, and push the handle of the exception
+		// for rethrow, followed by the values of the exception.
+		c.stack = c.stack[:frame.originalStackLenWithoutParam]
+		c.stackPush(UnsignedTypeI64)
+		if op == wasm.OpcodeCatch {
+			for _, t := range c.types[c.tags[tagIndex]].Params {
+				c.stackPush(wasmValueTypeToUnsignedType(t))
+			}
+		}
+
+		catchAll := op == wasm.OpcodeCatchAll
+		handler := &c.result.ExceptionHandlers[frame.handler]
+		handler.Catches = append(handler.Catches, ExceptionCatch{
+			Tag:      tagIndex,
+			CatchAll: catchAll,
+			Ref:      ExceptionRefHandle,
+			Target:   uint64(len(c.result.Operations)),
+		})
+		c.emit(NewOperationCatch(frame.handler, tagIndex, catchAll, ExceptionRefHandle))
+	case wasm.OpcodeTryTable:
+		c.br.Reset(c.body[c.pc+1:])
+		bt, num, err := wasm.DecodeBlockType(c.types, c.br, c.enabledFeatures)
+		if err != nil {
+			return fmt.Errorf("reading block type for try_table instruction: %w", err)
+		}
+		catches, n, err := wasm.DecodeTryTableCatches(c.br)
+		if err != nil {
+			return fmt.Errorf("reading catches for try_table instruction: %w", err)
+		}
+		c.pc += num + n
+
+		if c.unreachableState.on {
+			// If it is currently in unreachable,
+			// just remove the entire block.
+			c.unreachableState.depth++
+			break operatorSwitch
+		}
+
+		// Create a new frame -- entering try_table, and its handler whose catches are completed at its end.
+		frame := controlFrame{
+			frameID:                      c.nextFrameID(),
+			originalStackLenWithoutParam: len(c.stack) - len(bt.Params),
+			kind:                         controlFrameKindTryTable,
+			blockType:                    bt,
+			handler:                      len(c.result.ExceptionHandlers),
+			catches:                      catches,
+		}
+		c.result.ExceptionHandlers = append(c.result.ExceptionHandlers, ExceptionHandler{
+			Start:       uint64(len(c.result.Operations)),
+			Next:        c.controlFrames.exceptionHandler(0),
+			StackHeight: c.stackLenInUint64(frame.originalStackLenWithoutParam),
+		})
+		c.controlFrames.push(frame)
+		c.emit(NewOperationTry(frame.handler))
+	case wasm.OpcodeThrowRef:
+		c.emit(NewOperationThrowRef())
+		// ThrowRef operation is stack-polymorphic, and mark the state as unreachable.
+		c.markUnreachable()
+	case wasm.OpcodeThrow:
+		c.emit(NewOperationThrow(index))
+		// Throw operation is stack-polymorphic, and mark the state as unreachable.
+		c.markUnreachable()
+	case wasm.OpcodeRethrow:
+		targetIndex, n, err := leb128.LoadUint32(c.body[c.pc+1:])
+		if err != nil {
+			return fmt.Errorf("read the target for rethrow: %w", err)
+		}
+		c.pc += n
+
+		if c.unreachableState.on {
+			// If it is currently in unreachable, rethrow is no-op.
+			break operatorSwitch
+		}
+
+		// The handle of the exception is at the bottom of the catch block.
+		targetFrame := c.controlFrames.get(int(targetIndex))
+		depth := c.stackLenInUint64(len(c.stack)) - 1 - c.stackLenInUint64(targetFrame.originalStackLenWithoutParam)
+		c.emit(NewOperationRethrow(depth))
+		// Rethrow operation is stack-polymorphic, and mark the state as unreachable.
+		c.markUnreachable()
+	case wasm.OpcodeEnd, wasm.OpcodeDelegate:
+		// Delegate ends the try block as well, delegating its exceptions to the label.
+		var delegateIndex uint32
+		if op == wasm.OpcodeDelegate {
+			v, n, err := leb128.LoadUint32(c.body[c.pc+1:])
+			if err != nil {
+				return fmt.Errorf("read the label for delegate: %w", err)
+			}
+			c.pc += n
+			delegateIndex = v
+		}
+
 		if c.unreachableState.on && c.unreachableState.depth > 0 {
 			c.unreachableState.depth--
 			break operatorSwitch
@@ -576,6 +890,7 @@ operatorSwitch:
 			if c.controlFrames.empty() {
 				return nil
 			}
+			c.endTryBody(frame, op, delegateIndex)
 
 			c.stack = c.stack[:frame.originalStackLenWithoutParam]
 			for _, t := range frame.blockType.Results {
@@ -583,6 +898,7 @@ operatorSwitch:
 			}
 
 			continuationLabel := NewLabel(LabelKindContinuation, frame.frameID)
+			c.emitTryTableCatches(frame)
 			if frame.kind == controlFrameKindIfWithoutElse {
 				// Emit the else label.
 				elseLabel := NewLabel(LabelKindElse, frame.frameID)
@@ -600,6 +916,7 @@ operatorSwitch:
 		}
 
 		frame := c.controlFrames.pop()
+		c.endTryBody(frame, op, delegateIndex)
 
 		// We need to reset the stack so that
 		// the values pushed inside the block.
@@ -634,11 +951,15 @@ operatorSwitch:
 			// Initiate the continuation.
 			c.emit(NewOperationLabel(continuationLabel))
 		case controlFrameKindBlockWithContinuationLabel,
-			controlFrameKindIfWithElse:
+			controlFrameKindIfWithElse,
+			controlFrameKindTry,
+			controlFrameKindCatch,
+			controlFrameKindTryTable:
 			continuationLabel := NewLabel(LabelKindContinuation, frame.frameID)
 			c.result.LabelCallers[continuationLabel]++
 			c.emit(dropOp)
 			c.emit(NewOperationBr(continuationLabel))
+			c.emitTryTableCatches(frame)
 			c.emit(NewOperationLabel(continuationLabel))
 		case controlFrameKindLoop, controlFrameKindBlockWithoutContinuationLabel:
 			c.emit(
@@ -781,6 +1102,28 @@ operatorSwitch:
 		c.emit(
 			NewOperationCallIndirect(typeIndex, tableIndex),
 		)
+	case wasm.OpcodeCallRef:
+		c.emit(
+			NewOperationCallRef(index),
+		)
+	case wasm.OpcodeReturnCall:
+		c.emit(
+			NewOperationTailCall(index),
+		)
+		// Like return, tail calls are stack-polymorphic.
+		c.markUnreachable()
+	case wasm.OpcodeReturnCallIndirect:
+		typeIndex := index
+		tableIndex, n, err := leb128.LoadUint32(c.body[c.pc+1:])
+		if err != nil {
+			return fmt.Errorf("read target for br_table: %w", err)
+		}
+		c.pc += n
+		c.emit(
+			NewOperationTailCallIndirect(typeIndex, tableIndex),
+		)
+		// Like return, tail calls are stack-polymorphic.
+		c.markUnreachable()
 	case wasm.OpcodeDrop:
 		r := InclusiveRange{Start: 0, End: 0}
 		if peekValueType == UnsignedTypeV128 {
@@ -1019,15 +1362,21 @@ operatorSwitch:
 		)
 	case wasm.OpcodeMemorySize:
 		c.result.UsesMemory = true
-		c.pc++ // Skip the reserved one byte.
+		index, err := c.readMemoryIndex(wasm.OpcodeMemorySizeName)
+		if err != nil {
+			return err
+		}
 		c.emit(
-			NewOperationMemorySize(),
+			NewOperationMemorySize(c.memory64(index)),
 		)
 	case wasm.OpcodeMemoryGrow:
 		c.result.UsesMemory = true
-		c.pc++ // Skip the reserved one byte.
+		index, err := c.readMemoryIndex(wasm.OpcodeMemoryGrowName)
+		if err != nil {
+			return err
+		}
 		c.emit(
-			NewOperationMemoryGrow(),
+			NewOperationMemoryGrow(c.memory64(index)),
 		)
 	case wasm.OpcodeI32Const:
 		val, num, err := leb128.LoadInt32(c.body[c.pc+1:])
@@ -1586,6 +1935,79 @@ operatorSwitch:
 		c.emit(
 			NewOperationConstI64(0),
 		)
+	case wasm.OpcodeRefAsNonNull:
+		c.emit(
+			NewOperationRefAsNonNull(),
+		)
+	case wasm.OpcodeBrOnNull:
+		targetIndex, n, err := leb128.LoadUint32(c.body[c.pc+1:])
+		if err != nil {
+			return fmt.Errorf("read the target for br_on_null: %w", err)
+		}
+		c.pc += n
+
+		if c.unreachableState.on {
+			// If it is currently in unreachable, br_on_null is no-op.
+			break operatorSwitch
+		}
+
+		// Check if the opaque pointer (i64) on the top of the stack is zero without consuming it.
+		c.emit(NewOperationPick(0, false))
+		c.emit(NewOperationEqz(UnsignedInt64))
+
+		nullLabel := NewLabel(LabelKindHeader, c.nextFrameID())
+		c.result.LabelCallers[nullLabel]++
+		continuationLabel := NewLabel(LabelKindHeader, c.nextFrameID())
+		c.result.LabelCallers[continuationLabel]++
+		c.emit(NewOperationBrIf(nullLabel, continuationLabel, NopInclusiveRange))
+
+		// On null, the reference is dropped before branching to the target.
+		c.emit(NewOperationLabel(nullLabel))
+		c.stackPop()
+		c.emit(NewOperationDrop(InclusiveRange{Start: 0, End: 0}))
+		targetFrame := c.controlFrames.get(int(targetIndex))
+		targetFrame.ensureContinuation()
+		dropOp := NewOperationDrop(c.getFrameDropRange(targetFrame, false))
+		targetID := targetFrame.asLabel()
+		c.result.LabelCallers[targetID]++
+		c.emit(dropOp)
+		c.emit(NewOperationBr(targetID))
+
+		// Otherwise, the reference is left on the stack.
+		c.stackPush(UnsignedTypeI64)
+		c.emit(NewOperationLabel(continuationLabel))
+	case wasm.OpcodeBrOnNonNull:
+		targetIndex, n, err := leb128.LoadUint32(c.body[c.pc+1:])
+		if err != nil {
+			return fmt.Errorf("read the target for br_on_non_null: %w", err)
+		}
+		c.pc += n
+
+		if c.unreachableState.on {
+			// If it is currently in unreachable, br_on_non_null is no-op.
+			break operatorSwitch
+		}
+
+		// The reference is carried to the target on branching, so it is on the stack while branching.
+		c.stackPush(UnsignedTypeI64)
+		c.emit(NewOperationPick(0, false))
+		c.emit(NewOperationEqz(UnsignedInt64))
+		c.emit(NewOperationEqz(UnsignedInt32))
+
+		targetFrame := c.controlFrames.get(int(targetIndex))
+		targetFrame.ensureContinuation()
+		drop := c.getFrameDropRange(targetFrame, false)
+		target := targetFrame.asLabel()
+		c.result.LabelCallers[target]++
+
+		continuationLabel := NewLabel(LabelKindHeader, c.nextFrameID())
+		c.result.LabelCallers[continuationLabel]++
+		c.emit(NewOperationBrIf(target, continuationLabel, drop))
+
+		// Otherwise, the null reference is dropped.
+		c.emit(NewOperationLabel(continuationLabel))
+		c.stackPop()
+		c.emit(NewOperationDrop(InclusiveRange{Start: 0, End: 0}))
 	case wasm.OpcodeRefIsNull:
 		// Simply compare the opaque pointer (i64) with zero.
 		c.emit(
@@ -1658,9 +2080,13 @@ operatorSwitch:
 			if err != nil {
 				return fmt.Errorf("reading i32.const value: %v", err)
 			}
-			c.pc += num + 1 // +1 to skip the memory index which is fixed to zero.
+			c.pc += num
+			index, err := c.readMemoryIndex(wasm.OpcodeMemoryInitName)
+			if err != nil {
+				return err
+			}
 			c.emit(
-				NewOperationMemoryInit(dataIndex),
+				NewOperationMemoryInit(dataIndex, c.memory64(index)),
 			)
 		case wasm.OpcodeMiscDataDrop:
 			dataIndex, num, err := leb128.LoadUint32(c.body[c.pc+1:])
@@ -1673,15 +2099,28 @@ operatorSwitch:
 			)
 		case wasm.OpcodeMiscMemoryCopy:
 			c.result.UsesMemory = true
-			c.pc += 2 // +2 to skip two memory indexes which are fixed to zero.
+			// Unlike other instructions, the memories are not selected as memory.copy can copy between two memories.
+			dst, num, err := leb128.LoadUint32(c.body[c.pc+1:])
+			if err != nil {
+				return fmt.Errorf("reading destination memory index for %s: %w", wasm.OpcodeMemoryCopyName, err)
+			}
+			c.pc += num
+			src, num, err := leb128.LoadUint32(c.body[c.pc+1:])
+			if err != nil {
+				return fmt.Errorf("reading source memory index for %s: %w", wasm.OpcodeMemoryCopyName, err)
+			}
+			c.pc += num
 			c.emit(
-				NewOperationMemoryCopy(),
+				NewOperationMemoryCopy(dst, src, c.memory64(dst) || c.memory64(src)),
 			)
 		case wasm.OpcodeMiscMemoryFill:
 			c.result.UsesMemory = true
-			c.pc += 1 // +1 to skip the memory index which is fixed to zero.
+			index, err := c.readMemoryIndex(wasm.OpcodeMemoryFillName)
+			if err != nil {
+				return err
+			}
 			c.emit(
-				NewOperationMemoryFill(),
+				NewOperationMemoryFill(c.memory64(index)),
 			)
 		case wasm.OpcodeMiscTableInit:
 			elemIndex, num, err := leb128.LoadUint32(c.body[c.pc+1:])
@@ -1757,6 +2196,88 @@ operatorSwitch:
 			return fmt.Errorf("unsupported misc instruction in wazeroir: 0x%x", op)
 		}
 	case wasm.OpcodeVecPrefix:
+		if wasm.IsVecRelaxed(c.body, c.pc+1) {
+			c.pc++
+			relaxedOp := c.body[c.pc]
+			c.pc++ // Skips wasm.OpcodeVecRelaxedSuffix.
+			// The relaxed instructions are lowered to their deterministic counterparts where they exist, so
+			// that the results don't depend on the engine or the platform.
+			switch relaxedOp {
+			case wasm.OpcodeVecI8x16RelaxedSwizzle:
+				c.emit(
+					NewOperationV128Swizzle(),
+				)
+			case wasm.OpcodeVecI32x4RelaxedTruncF32x4S:
+				c.emit(
+					NewOperationV128ITruncSatFromF(ShapeF32x4, true),
+				)
+			case wasm.OpcodeVecI32x4RelaxedTruncF32x4U:
+				c.emit(
+					NewOperationV128ITruncSatFromF(ShapeF32x4, false),
+				)
+			case wasm.OpcodeVecI32x4RelaxedTruncF64x2SZero:
+				c.emit(
+					NewOperationV128ITruncSatFromF(ShapeF64x2, true),
+				)
+			case wasm.OpcodeVecI32x4RelaxedTruncF64x2UZero:
+				c.emit(
+					NewOperationV128ITruncSatFromF(ShapeF64x2, false),
+				)
+			case wasm.OpcodeVecF32x4RelaxedMadd:
+				c.emit(
+					NewOperationV128RelaxedMadd(ShapeF32x4, false),
+				)
+			case wasm.OpcodeVecF32x4RelaxedNmadd:
+				c.emit(
+					NewOperationV128RelaxedMadd(ShapeF32x4, true),
+				)
+			case wasm.OpcodeVecF64x2RelaxedMadd:
+				c.emit(
+					NewOperationV128RelaxedMadd(ShapeF64x2, false),
+				)
+			case wasm.OpcodeVecF64x2RelaxedNmadd:
+				c.emit(
+					NewOperationV128RelaxedMadd(ShapeF64x2, true),
+				)
+			case wasm.OpcodeVecI8x16RelaxedLaneselect, wasm.OpcodeVecI16x8RelaxedLaneselect,
+				wasm.OpcodeVecI32x4RelaxedLaneselect, wasm.OpcodeVecI64x2RelaxedLaneselect:
+				c.emit(
+					NewOperationV128Bitselect(),
+				)
+			case wasm.OpcodeVecF32x4RelaxedMin:
+				c.emit(
+					NewOperationV128Min(ShapeF32x4, false),
+				)
+			case wasm.OpcodeVecF32x4RelaxedMax:
+				c.emit(
+					NewOperationV128Max(ShapeF32x4, false),
+				)
+			case wasm.OpcodeVecF64x2RelaxedMin:
+				c.emit(
+					NewOperationV128Min(ShapeF64x2, false),
+				)
+			case wasm.OpcodeVecF64x2RelaxedMax:
+				c.emit(
+					NewOperationV128Max(ShapeF64x2, false),
+				)
+			case wasm.OpcodeVecI16x8RelaxedQ15mulrS:
+				c.emit(
+					NewOperationV128Q15mulrSatS(),
+				)
+			case wasm.OpcodeVecI16x8RelaxedDotI8x16I7x16S:
+				c.emit(
+					NewOperationV128RelaxedDot(false),
+				)
+			case wasm.OpcodeVecI32x4RelaxedDotI8x16I7x16AddS:
+				c.emit(
+					NewOperationV128RelaxedDot(true),
+				)
+			default:
+				return fmt.Errorf("unsupported relaxed vector instruction in wazeroir: %s",
+					wasm.RelaxedVectorInstructionName(relaxedOp))
+			}
+			break
+		}
 		c.pc++
 		switch vecOp := c.body[c.pc]; vecOp {
 		case wasm.OpcodeVecV128Const:
@@ -2848,6 +3369,64 @@ operatorSwitch:
 		default:
 			return fmt.Errorf("unsupported vector instruction in wazeroir: %s", wasm.VectorInstructionName(vecOp))
 		}
+	case wasm.OpcodeAtomicPrefix:
+		c.pc++
+		switch atomicOp := c.body[c.pc]; {
+		case atomicOp == wasm.OpcodeAtomicFence:
+			c.pc++ // Skips the reserved byte.
+			c.emit(
+				NewOperationAtomicFence(),
+			)
+		case atomicOp == wasm.OpcodeAtomicMemoryNotify:
+			arg, err := c.readMemoryArg(wasm.OpcodeAtomicMemoryNotifyName)
+			if err != nil {
+				return err
+			}
+			c.emit(
+				NewOperationAtomicMemoryNotify(arg),
+			)
+		case atomicOp == wasm.OpcodeAtomicMemoryWait32, atomicOp == wasm.OpcodeAtomicMemoryWait64:
+			arg, err := c.readMemoryArg(wasm.AtomicInstructionName(atomicOp))
+			if err != nil {
+				return err
+			}
+			typ := UnsignedTypeI32
+			if atomicOp == wasm.OpcodeAtomicMemoryWait64 {
+				typ = UnsignedTypeI64
+			}
+			c.emit(
+				NewOperationAtomicMemoryWait(typ, arg),
+			)
+		case atomicOp >= wasm.OpcodeAtomicI32Load && atomicOp <= wasm.OpcodeAtomicI64Rmw32CmpxchgU:
+			arg, err := c.readMemoryArg(wasm.AtomicInstructionName(atomicOp))
+			if err != nil {
+				return err
+			}
+			typ := atomicValueType(atomicOp)
+			switch {
+			case atomicOp <= wasm.OpcodeAtomicI64Load32U:
+				c.emit(
+					NewOperationAtomicLoad(typ, arg),
+				)
+			case atomicOp <= wasm.OpcodeAtomicI64Store32:
+				c.emit(
+					NewOperationAtomicStore(typ, arg),
+				)
+			case atomicOp < wasm.OpcodeAtomicI32RmwCmpxchg:
+				// Each kind of read-modify-write instructions consists of seven instructions,
+				// and the kinds are ordered in the same way as AtomicArithmeticOp.
+				op := AtomicArithmeticOp((atomicOp - wasm.OpcodeAtomicI32RmwAdd) / 7)
+				c.emit(
+					NewOperationAtomicRMW(typ, arg, op),
+				)
+			default:
+				c.emit(
+					NewOperationAtomicRMWCmpxchg(typ, arg),
+				)
+			}
+		default:
+			return fmt.Errorf("unsupported atomic instruction in wazeroir: %s", wasm.AtomicInstructionName(atomicOp))
+		}
 	default:
 		return fmt.Errorf("unsupported instruction in wazeroir: 0x%x", op)
 	}
@@ -2857,6 +3436,64 @@ operatorSwitch:
 	return nil
 }
 
+// endTryBody ends the range of the handler of the frame popped by op if it is still in the body of try or try_table. When op is
+// wasm.OpcodeDelegate, the exceptions thrown in the range are handled by the handler of the label at delegateIndex.
+func (c *Compiler) endTryBody(frame *controlFrame, op wasm.Opcode, delegateIndex uint32) {
+	if frame.kind != controlFrameKindTry && frame.kind != controlFrameKindTryTable {
+		return
+	}
+	handler := &c.result.ExceptionHandlers[frame.handler]
+	handler.End = uint64(len(c.result.Operations))
+	if op == wasm.OpcodeDelegate {
+		handler.Next = c.controlFrames.exceptionHandler(int(delegateIndex))
+	}
+}
+
+// emitTryTableCatches emits the landing pads of the catches of the try_table frame popped by wasm.OpcodeEnd, which
+// branch to their labels with the values pushed by the engine catching an exception. The stack is left as is.
+func (c *Compiler) emitTryTableCatches(frame *controlFrame) {
+	if frame.kind != controlFrameKindTryTable {
+		return
+	}
+	results := append([]UnsignedType(nil), c.stack[frame.originalStackLenWithoutParam:]...)
+	handler := &c.result.ExceptionHandlers[frame.handler]
+	for i := range frame.catches {
+		catch := &frame.catches[i]
+		ref := ExceptionRefNone
+		if catch.PassesRef() {
+			ref = ExceptionRefExnref
+		}
+
+		// The landing pad is entered with the values of the exception on top of the stack below the block.
+		c.stack = c.stack[:frame.originalStackLenWithoutParam]
+		if !catch.CatchesAll() {
+			for _, t := range c.types[c.tags[catch.Tag]].Params {
+				c.stackPush(wasmValueTypeToUnsignedType(t))
+			}
+		}
+		if ref == ExceptionRefExnref {
+			c.stackPush(UnsignedTypeI64)
+		}
+		handler.Catches = append(handler.Catches, ExceptionCatch{
+			Tag:      catch.Tag,
+			CatchAll: catch.CatchesAll(),
+			Ref:      ref,
+			Target:   uint64(len(c.result.Operations)),
+		})
+		c.emit(NewOperationCatch(frame.handler, catch.Tag, catch.CatchesAll(), ref))
+
+		// The label is relative to the block enclosing try_table, which is already popped.
+		targetFrame := c.controlFrames.get(int(catch.Label))
+		targetFrame.ensureContinuation()
+		dropOp := NewOperationDrop(c.getFrameDropRange(targetFrame, false))
+		targetID := targetFrame.asLabel()
+		c.result.LabelCallers[targetID]++
+		c.emit(dropOp)
+		c.emit(NewOperationBr(targetID))
+	}
+	c.stack = append(c.stack[:frame.originalStackLenWithoutParam], results...)
+}
+
 func (c *Compiler) nextFrameID() (id uint32) {
 	id = c.currentFrameID + 1
 	c.currentFrameID++
@@ -2870,11 +3507,15 @@ func (c *Compiler) applyToStack(opcode wasm.Opcode) (index uint32, err error) {
 		// and it DOES affect the signature of opcode.
 		wasm.OpcodeCall,
 		wasm.OpcodeCallIndirect,
+		wasm.OpcodeReturnCall,
+		wasm.OpcodeReturnCallIndirect,
+		wasm.OpcodeCallRef,
 		wasm.OpcodeLocalGet,
 		wasm.OpcodeLocalSet,
 		wasm.OpcodeLocalTee,
 		wasm.OpcodeGlobalGet,
-		wasm.OpcodeGlobalSet:
+		wasm.OpcodeGlobalSet,
+		wasm.OpcodeThrow:
 		// Assumes that we are at the opcode now so skip it before read immediates.
 		v, num, err := leb128.LoadUint32(c.body[c.pc+1:])
 		if err != nil {
@@ -2898,6 +3539,9 @@ func (c *Compiler) applyToStack(opcode wasm.Opcode) (index uint32, err error) {
 	if err != nil {
 		return 0, err
 	}
+	if c.hasMemory64 {
+		s = c.memory64Signature(opcode, s)
+	}
 
 	// Manipulate the stack according to the signature.
 	// Note that the following algorithm assumes that
@@ -2957,6 +3601,10 @@ func (c *Compiler) stackPush(ts UnsignedType) {
 func (c *Compiler) emit(op UnionOperation) {
 	if !c.unreachableState.on {
 		switch op.Kind {
+		case OperationKindLabel, OperationKindCatch:
+			if c.meterFuel {
+				c.fuelCosts = append(c.fuelCosts, 0)
+			}
 		case OperationKindDrop:
 			// If the drop range is nil,
 			// we could remove such operations.
@@ -2980,7 +3628,7 @@ func (c *Compiler) emitDefaultValue(t wasm.ValueType) {
 	case wasm.ValueTypeI32:
 		c.stackPush(UnsignedTypeI32)
 		c.emit(NewOperationConstI32(0))
-	case wasm.ValueTypeI64, wasm.ValueTypeExternref, wasm.ValueTypeFuncref:
+	case wasm.ValueTypeI64, wasm.ValueTypeExternref, wasm.ValueTypeFuncref, wasm.ValueTypeExnref:
 		c.stackPush(UnsignedTypeI64)
 		c.emit(NewOperationConstI64(0))
 	case wasm.ValueTypeF32:
@@ -3059,10 +3707,66 @@ func (c *Compiler) readMemoryArg(tag string) (MemoryArg, error) {
 		return MemoryArg{}, fmt.Errorf("reading alignment for %s: %w", tag, err)
 	}
 	c.pc += num
-	offset, num, err := leb128.LoadUint32(c.body[c.pc+1:])
+	// The memory index follows the alignment if its bit 6 is set, as the validation ensures that the multi-memory
+	// feature is enabled in that case.
+	var index wasm.Index
+	if alignment&memoryArgMemoryIndexFlag != 0 {
+		alignment &^= memoryArgMemoryIndexFlag
+		if index, err = c.readMemoryIndex(tag); err != nil {
+			return MemoryArg{}, err
+		}
+	}
+	// The offset is a u64 for a 64-bit memory.
+	memory64 := c.memory64(index)
+	var offset uint64
+	if memory64 {
+		offset, num, err = leb128.LoadUint64(c.body[c.pc+1:])
+	} else {
+		var offset32 uint32
+		offset32, num, err = leb128.LoadUint32(c.body[c.pc+1:])
+		offset = uint64(offset32)
+	}
 	if err != nil {
 		return MemoryArg{}, fmt.Errorf("reading offset for %s: %w", tag, err)
 	}
 	c.pc += num
-	return MemoryArg{Offset: offset, Alignment: alignment}, nil
+	return MemoryArg{Offset: offset, Alignment: alignment, Memory64: memory64}, nil
+}
+
+// memoryArgMemoryIndexFlag is the bit of the alignment in a memory argument which signals that a memory index follows.
+const memoryArgMemoryIndexFlag = 1 << 6
+
+// readMemoryIndex reads the memory index immediate of the current instruction, and emits OperationKindSelectMemory
+// before the instruction if it is not zero.
+func (c *Compiler) readMemoryIndex(tag string) (wasm.Index, error) {
+	index, num, err := leb128.LoadUint32(c.body[c.pc+1:])
+	if err != nil {
+		return 0, fmt.Errorf("reading memory index for %s: %w", tag, err)
+	}
+	c.pc += num
+	if index != 0 {
+		c.emit(NewOperationSelectMemory(index))
+		c.memorySelected = true
+	}
+	return index, nil
+}
+
+// memory64 returns true if the memory at the index is 64-bit, introduced in api.CoreFeatureMemory64.
+func (c *Compiler) memory64(index wasm.Index) bool {
+	return c.hasMemory64 && c.memories[index].Is64
+}
+
+// peekMemoryIndex returns the memory index immediate at pos without advancing pc.
+func (c *Compiler) peekMemoryIndex(pos uint64) wasm.Index {
+	index, _, _ := leb128.LoadUint32(c.body[pos:])
+	return index
+}
+
+// peekMemoryArgMemoryIndex returns the memory index in the memory argument at pos without advancing pc.
+func (c *Compiler) peekMemoryArgMemoryIndex(pos uint64) wasm.Index {
+	alignment, num, _ := leb128.LoadUint32(c.body[pos:])
+	if alignment&memoryArgMemoryIndexFlag == 0 {
+		return 0
+	}
+	return c.peekMemoryIndex(pos + num)
 }
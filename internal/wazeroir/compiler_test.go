@@ -9,6 +9,7 @@ import (
 	"github.com/tetratelabs/wazero/internal/leb128"
 	"github.com/tetratelabs/wazero/internal/testing/require"
 	"github.com/tetratelabs/wazero/internal/wasm"
+	"github.com/tetratelabs/wazero/internal/wasmdebug"
 )
 
 var (
@@ -157,7 +158,7 @@ func TestCompile(t *testing.T) {
 			expected: &CompilationResult{
 				Operations: []UnionOperation{ // begin with params: [$delta]
 					NewOperationPick(0, false),                         // [$delta, $delta]
-					NewOperationMemoryGrow(),                           // [$delta, $old_size]
+					NewOperationMemoryGrow(false),                      // [$delta, $old_size]
 					NewOperationDrop(InclusiveRange{Start: 1, End: 1}), // [$old_size]
 					NewOperationBr(NewLabel(LabelKindReturn, 0)),       // return!
 				},
@@ -184,7 +185,7 @@ func TestCompile(t *testing.T) {
 			for _, tp := range tc.module.TypeSection {
 				tp.CacheNumInUint64()
 			}
-			c, err := NewCompiler(enabledFeatures, 0, tc.module, false)
+			c, err := NewCompiler(enabledFeatures, 0, tc.module, false, false, false)
 			require.NoError(t, err)
 
 			fn, err := c.Next()
@@ -267,7 +268,7 @@ func TestCompile_BulkMemoryOperations(t *testing.T) {
 	module := &wasm.Module{
 		TypeSection:     []wasm.FunctionType{v_v},
 		FunctionSection: []wasm.Index{0},
-		MemorySection:   &wasm.Memory{Min: 1},
+		MemorySection:   []wasm.Memory{{Min: 1}},
 		DataSection: []wasm.DataSegment{
 			{
 				OffsetExpression: wasm.ConstantExpression{
@@ -297,7 +298,7 @@ func TestCompile_BulkMemoryOperations(t *testing.T) {
 			NewOperationConstI32(16),                     // [16]
 			NewOperationConstI32(0),                      // [16, 0]
 			NewOperationConstI32(7),                      // [16, 0, 7]
-			NewOperationMemoryInit(1),                    // []
+			NewOperationMemoryInit(1, false),             // []
 			NewOperationDataDrop(1),                      // []
 			NewOperationBr(NewLabel(LabelKindReturn, 0)), // return!
 		},
@@ -309,7 +310,7 @@ func TestCompile_BulkMemoryOperations(t *testing.T) {
 		Types:            []wasm.FunctionType{v_v},
 	}
 
-	c, err := NewCompiler(api.CoreFeatureBulkMemoryOperations, 0, module, false)
+	c, err := NewCompiler(api.CoreFeatureBulkMemoryOperations, 0, module, false, false, false)
 	require.NoError(t, err)
 
 	actual, err := c.Next()
@@ -593,7 +594,7 @@ func TestCompile_MultiValue(t *testing.T) {
 			for _, tp := range tc.module.TypeSection {
 				tp.CacheNumInUint64()
 			}
-			c, err := NewCompiler(enabledFeatures, 0, tc.module, false)
+			c, err := NewCompiler(enabledFeatures, 0, tc.module, false, false, false)
 			require.NoError(t, err)
 
 			actual, err := c.Next()
@@ -629,7 +630,7 @@ func TestCompile_NonTrappingFloatToIntConversion(t *testing.T) {
 		Functions:    []wasm.Index{0},
 		Types:        []wasm.FunctionType{f32_i32},
 	}
-	c, err := NewCompiler(api.CoreFeatureNonTrappingFloatToIntConversion, 0, module, false)
+	c, err := NewCompiler(api.CoreFeatureNonTrappingFloatToIntConversion, 0, module, false, false, false)
 	require.NoError(t, err)
 
 	actual, err := c.Next()
@@ -658,7 +659,7 @@ func TestCompile_SignExtensionOps(t *testing.T) {
 		Functions:    []wasm.Index{0},
 		Types:        []wasm.FunctionType{i32_i32},
 	}
-	c, err := NewCompiler(api.CoreFeatureSignExtensionOps, 0, module, false)
+	c, err := NewCompiler(api.CoreFeatureSignExtensionOps, 0, module, false, false, false)
 	require.NoError(t, err)
 
 	actual, err := c.Next()
@@ -670,7 +671,7 @@ func requireCompilationResult(t *testing.T, enabledFeatures api.CoreFeatures, ex
 	if enabledFeatures == 0 {
 		enabledFeatures = api.CoreFeaturesV2
 	}
-	c, err := NewCompiler(enabledFeatures, 0, module, false)
+	c, err := NewCompiler(enabledFeatures, 0, module, false, false, false)
 	require.NoError(t, err)
 
 	actual, err := c.Next()
@@ -713,7 +714,7 @@ func TestCompile_CallIndirectNonZeroTableIndex(t *testing.T) {
 		Types:        []wasm.FunctionType{v_v, v_v, v_v},
 	}
 
-	c, err := NewCompiler(api.CoreFeatureBulkMemoryOperations, 0, module, false)
+	c, err := NewCompiler(api.CoreFeatureBulkMemoryOperations, 0, module, false, false, false)
 	require.NoError(t, err)
 
 	actual, err := c.Next()
@@ -721,6 +722,267 @@ func TestCompile_CallIndirectNonZeroTableIndex(t *testing.T) {
 	require.Equal(t, expected, actual)
 }
 
+func TestCompile_TailCall(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     []byte
+		expected []UnionOperation
+	}{
+		{
+			name: "return_call",
+			body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeReturnCall, 0, wasm.OpcodeEnd},
+			expected: []UnionOperation{ // begin with params: [$x]
+				NewOperationPick(0, false), // [$x, $x]
+				NewOperationTailCall(0),    // unreachable
+			},
+		},
+		{
+			name: "return_call_indirect",
+			body: []byte{
+				wasm.OpcodeLocalGet, 0, wasm.OpcodeI32Const, 0, wasm.OpcodeReturnCallIndirect, 0, 0,
+				wasm.OpcodeI32Const, 1, // unreachable
+				wasm.OpcodeEnd,
+			},
+			expected: []UnionOperation{ // begin with params: [$x]
+				NewOperationPick(0, false),         // [$x, $x]
+				NewOperationConstI32(0),            // [$x, $x, 0]
+				NewOperationTailCallIndirect(0, 0), // unreachable
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			module := &wasm.Module{
+				TypeSection:     []wasm.FunctionType{i32_i32},
+				FunctionSection: []wasm.Index{0},
+				CodeSection:     []wasm.Code{{Body: tc.body}},
+				TableSection:    []wasm.Table{{Type: wasm.RefTypeFuncref}},
+			}
+			c, err := NewCompiler(api.CoreFeaturesV2|api.CoreFeatureTailCall, 0, module, false, false, false)
+			require.NoError(t, err)
+
+			actual, err := c.Next()
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, actual.Operations)
+		})
+	}
+}
+
+func TestCompile_ExceptionHandling(t *testing.T) {
+	tests := []struct {
+		name             string
+		body             []byte
+		meterFuel        bool
+		expected         []UnionOperation
+		expectedHandlers []ExceptionHandler
+	}{
+		{
+			name: "try catch",
+			body: []byte{
+				wasm.OpcodeTry, wasm.ValueTypeI32, wasm.OpcodeLocalGet, 0, wasm.OpcodeThrow, 0,
+				wasm.OpcodeCatch, 0,
+				wasm.OpcodeEnd,
+				wasm.OpcodeEnd,
+			},
+			expected: []UnionOperation{ // begin with params: [$x]
+				NewOperationTry(0),                                 // [$x]
+				NewOperationPick(0, false),                         // [$x, $x]
+				NewOperationThrow(0),                               // unreachable
+				NewOperationCatch(0, 0, false, ExceptionRefHandle), // [$x, $exception, $v]
+				NewOperationDrop(InclusiveRange{Start: 1, End: 1}), // [$x, $v]
+				NewOperationBr(NewLabel(LabelKindContinuation, 2)),
+				NewOperationLabel(NewLabel(LabelKindContinuation, 2)),
+				NewOperationDrop(InclusiveRange{Start: 1, End: 1}), // [$v]
+				NewOperationBr(NewLabel(LabelKindReturn, 0)),       // return!
+			},
+			expectedHandlers: []ExceptionHandler{
+				{Start: 0, End: 3, Next: -1, StackHeight: 1, Catches: []ExceptionCatch{{Tag: 0, Ref: ExceptionRefHandle, Target: 3}}},
+			},
+		},
+		{
+			name: "delegate and rethrow",
+			body: []byte{
+				wasm.OpcodeTry, 0x40,
+				wasm.OpcodeTry, 0x40, wasm.OpcodeLocalGet, 0, wasm.OpcodeThrow, 0,
+				wasm.OpcodeDelegate, 0,
+				wasm.OpcodeCatchAll, wasm.OpcodeRethrow, 0,
+				wasm.OpcodeEnd,
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeEnd,
+			},
+			expected: []UnionOperation{ // begin with params: [$x]
+				NewOperationTry(0),         // [$x]
+				NewOperationTry(1),         // [$x]
+				NewOperationPick(0, false), // [$x, $x]
+				NewOperationThrow(0),       // unreachable
+				NewOperationLabel(NewLabel(LabelKindContinuation, 3)),
+				NewOperationBr(NewLabel(LabelKindContinuation, 2)),
+				NewOperationCatch(0, 0, true, ExceptionRefHandle), // [$x, $exception]
+				NewOperationRethrow(0),                            // unreachable
+				NewOperationLabel(NewLabel(LabelKindContinuation, 2)),
+				NewOperationPick(0, false),                         // [$x, $x]
+				NewOperationDrop(InclusiveRange{Start: 1, End: 1}), // [$x]
+				NewOperationBr(NewLabel(LabelKindReturn, 0)),       // return!
+			},
+			expectedHandlers: []ExceptionHandler{
+				{Start: 0, End: 5, Next: -1, StackHeight: 1, Catches: []ExceptionCatch{{CatchAll: true, Ref: ExceptionRefHandle, Target: 6}}},
+				{Start: 1, End: 4, Next: 0, StackHeight: 1},
+			},
+		},
+		{
+			name: "try_table",
+			body: []byte{
+				wasm.OpcodeBlock, wasm.ValueTypeI32,
+				wasm.OpcodeTryTable, wasm.ValueTypeI32, 1, wasm.TryTableCatchKindCatch, 0, 0,
+				wasm.OpcodeLocalGet, 0, wasm.OpcodeThrow, 0,
+				wasm.OpcodeEnd,
+				wasm.OpcodeEnd,
+				wasm.OpcodeEnd,
+			},
+			expected: []UnionOperation{ // begin with params: [$x]
+				NewOperationTry(0),                               // [$x]
+				NewOperationPick(0, false),                       // [$x, $x]
+				NewOperationThrow(0),                             // unreachable
+				NewOperationCatch(0, 0, false, ExceptionRefNone), // [$x, $v]
+				NewOperationBr(NewLabel(LabelKindContinuation, 2)),
+				NewOperationLabel(NewLabel(LabelKindContinuation, 3)),
+				NewOperationBr(NewLabel(LabelKindContinuation, 2)),
+				NewOperationLabel(NewLabel(LabelKindContinuation, 2)),
+				NewOperationDrop(InclusiveRange{Start: 1, End: 1}), // [$v]
+				NewOperationBr(NewLabel(LabelKindReturn, 0)),       // return!
+			},
+			expectedHandlers: []ExceptionHandler{
+				{Start: 0, End: 3, Next: -1, StackHeight: 1, Catches: []ExceptionCatch{{Tag: 0, Ref: ExceptionRefNone, Target: 3}}},
+			},
+		},
+		{
+			name: "meter fuel",
+			body: []byte{
+				wasm.OpcodeTry, 0x40, wasm.OpcodeLocalGet, 0, wasm.OpcodeThrow, 0,
+				wasm.OpcodeCatch, 0, wasm.OpcodeReturn,
+				wasm.OpcodeEnd,
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeEnd,
+			},
+			meterFuel: true,
+			expected: []UnionOperation{ // begin with params: [$x]
+				NewOperationConsumeFuel(2),                         // local.get, throw
+				NewOperationTry(0),                                 // [$x]
+				NewOperationPick(0, false),                         // [$x, $x]
+				NewOperationThrow(0),                               // unreachable
+				NewOperationCatch(0, 0, false, ExceptionRefHandle), // [$x, $exception, $v]
+				NewOperationConsumeFuel(1),                         // return
+				NewOperationDrop(InclusiveRange{Start: 1, End: 2}), // [$v]
+				NewOperationBr(NewLabel(LabelKindReturn, 0)),       // return!
+				NewOperationLabel(NewLabel(LabelKindContinuation, 2)),
+				NewOperationConsumeFuel(1),                         // local.get
+				NewOperationPick(0, false),                         // [$x, $x]
+				NewOperationDrop(InclusiveRange{Start: 1, End: 1}), // [$x]
+				NewOperationBr(NewLabel(LabelKindReturn, 0)),       // return!
+			},
+			expectedHandlers: []ExceptionHandler{
+				{Start: 1, End: 4, Next: -1, StackHeight: 1, Catches: []ExceptionCatch{{Tag: 0, Ref: ExceptionRefHandle, Target: 4}}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			module := &wasm.Module{
+				TypeSection:     []wasm.FunctionType{i32_i32, {Params: []wasm.ValueType{i32}, ParamNumInUint64: 1}},
+				FunctionSection: []wasm.Index{0},
+				TagSection:      []wasm.Tag{{Type: 1}},
+				CodeSection:     []wasm.Code{{Body: tc.body}},
+			}
+			c, err := NewCompiler(api.CoreFeaturesV2|api.CoreFeatureExceptionHandling, 0, module, false, tc.meterFuel, false)
+			require.NoError(t, err)
+
+			actual, err := c.Next()
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, actual.Operations)
+			require.Equal(t, tc.expectedHandlers, actual.ExceptionHandlers)
+		})
+	}
+}
+
+func TestCompile_MultiMemory(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     []byte
+		expected []UnionOperation
+	}{
+		{
+			name: "load memory 0",
+			body: []byte{wasm.OpcodeI32Const, 0, wasm.OpcodeI32Load, 0x2, 0x4, wasm.OpcodeDrop, wasm.OpcodeEnd},
+			expected: []UnionOperation{ // begin with params: []
+				NewOperationConstI32(0), // [0]
+				NewOperationLoad(UnsignedTypeI32, MemoryArg{Alignment: 2, Offset: 4}), // [x]
+				NewOperationDrop(InclusiveRange{}),                                    // []
+				NewOperationBr(NewLabel(LabelKindReturn, 0)),                          // return!
+			},
+		},
+		{
+			name: "load memory 1",
+			body: []byte{wasm.OpcodeI32Const, 0, wasm.OpcodeI32Load, 0x2 | 0x40, 1, 0x4, wasm.OpcodeDrop, wasm.OpcodeEnd},
+			expected: []UnionOperation{ // begin with params: []
+				NewOperationConstI32(0),     // [0]
+				NewOperationSelectMemory(1), // [0]
+				NewOperationLoad(UnsignedTypeI32, MemoryArg{Alignment: 2, Offset: 4}), // [x]
+				NewOperationSelectMemory(0),                  // [x]
+				NewOperationDrop(InclusiveRange{}),           // []
+				NewOperationBr(NewLabel(LabelKindReturn, 0)), // return!
+			},
+		},
+		{
+			name: "memory.size 1",
+			body: []byte{wasm.OpcodeMemorySize, 1, wasm.OpcodeDrop, wasm.OpcodeEnd},
+			expected: []UnionOperation{ // begin with params: []
+				NewOperationSelectMemory(1),                  // []
+				NewOperationMemorySize(false),                // [x]
+				NewOperationSelectMemory(0),                  // [x]
+				NewOperationDrop(InclusiveRange{}),           // []
+				NewOperationBr(NewLabel(LabelKindReturn, 0)), // return!
+			},
+		},
+		{
+			name: "memory.copy 1 0",
+			body: []byte{
+				wasm.OpcodeI32Const, 0, wasm.OpcodeI32Const, 0, wasm.OpcodeI32Const, 0,
+				wasm.OpcodeMiscPrefix, wasm.OpcodeMiscMemoryCopy, 1, 0,
+				wasm.OpcodeEnd,
+			},
+			expected: []UnionOperation{ // begin with params: []
+				NewOperationConstI32(0),                      // [0]
+				NewOperationConstI32(0),                      // [0, 0]
+				NewOperationConstI32(0),                      // [0, 0, 0]
+				NewOperationMemoryCopy(1, 0, false),          // []
+				NewOperationBr(NewLabel(LabelKindReturn, 0)), // return!
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			module := &wasm.Module{
+				TypeSection:     []wasm.FunctionType{v_v},
+				FunctionSection: []wasm.Index{0},
+				CodeSection:     []wasm.Code{{Body: tc.body}},
+				MemorySection:   []wasm.Memory{{Min: 1}, {Min: 1}},
+			}
+			c, err := NewCompiler(api.CoreFeaturesV2|api.CoreFeatureMultiMemory, 0, module, false, false, false)
+			require.NoError(t, err)
+
+			actual, err := c.Next()
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, actual.Operations)
+		})
+	}
+}
+
 func TestCompile_Refs(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -806,7 +1068,114 @@ func TestCompile_Refs(t *testing.T) {
 				FunctionSection: []wasm.Index{0},
 				CodeSection:     []wasm.Code{{Body: tc.body}},
 			}
-			c, err := NewCompiler(api.CoreFeaturesV2, 0, module, false)
+			c, err := NewCompiler(api.CoreFeaturesV2, 0, module, false, false, false)
+			require.NoError(t, err)
+
+			actual, err := c.Next()
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, actual.Operations)
+		})
+	}
+}
+
+func TestCompile_FunctionReferences(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     []byte
+		expected []UnionOperation
+	}{
+		{
+			name: "call_ref",
+			body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeRefFunc, 0, wasm.OpcodeCallRef, 0, wasm.OpcodeEnd},
+			expected: []UnionOperation{ // begin with params: [$x]
+				NewOperationPick(0, false),                         // [$x, $x]
+				NewOperationRefFunc(0),                             // [$x, $x, $f]
+				NewOperationCallRef(0),                             // [$x, $y]
+				NewOperationDrop(InclusiveRange{Start: 1, End: 1}), // [$y]
+				NewOperationBr(NewLabel(LabelKindReturn, 0)),       // return!
+			},
+		},
+		{
+			name: "ref.as_non_null",
+			body: []byte{
+				wasm.OpcodeRefNull, wasm.RefTypeFuncref, wasm.OpcodeRefAsNonNull, wasm.OpcodeDrop,
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeEnd,
+			},
+			expected: []UnionOperation{ // begin with params: [$x]
+				NewOperationConstI64(0),                            // [$x, null]
+				NewOperationRefAsNonNull(),                         // [$x, null]
+				NewOperationDrop(InclusiveRange{Start: 0, End: 0}), // [$x]
+				NewOperationPick(0, false),                         // [$x, $x]
+				NewOperationDrop(InclusiveRange{Start: 1, End: 1}), // [$x]
+				NewOperationBr(NewLabel(LabelKindReturn, 0)),       // return!
+			},
+		},
+		{
+			name: "br_on_null",
+			body: []byte{
+				wasm.OpcodeBlock, wasm.ValueTypeI32,
+				wasm.OpcodeLocalGet, 0, wasm.OpcodeRefFunc, 0, wasm.OpcodeBrOnNull, 0,
+				wasm.OpcodeCallRef, 0,
+				wasm.OpcodeEnd,
+				wasm.OpcodeEnd,
+			},
+			expected: []UnionOperation{ // begin with params: [$x]
+				NewOperationPick(0, false),     // [$x, $x]
+				NewOperationRefFunc(0),         // [$x, $x, $f]
+				NewOperationPick(0, false),     // [$x, $x, $f, $f]
+				NewOperationEqz(UnsignedInt64), // [$x, $x, $f, $f == null]
+				NewOperationBrIf(NewLabel(LabelKindHeader, 3), NewLabel(LabelKindHeader, 4), NopInclusiveRange),
+				NewOperationLabel(NewLabel(LabelKindHeader, 3)),    // [$x, $x, $f]
+				NewOperationDrop(InclusiveRange{Start: 0, End: 0}), // [$x, $x]
+				NewOperationBr(NewLabel(LabelKindContinuation, 2)),
+				NewOperationLabel(NewLabel(LabelKindHeader, 4)), // [$x, $x, $f]
+				NewOperationCallRef(0),                          // [$x, $y]
+				NewOperationBr(NewLabel(LabelKindContinuation, 2)),
+				NewOperationLabel(NewLabel(LabelKindContinuation, 2)),
+				NewOperationDrop(InclusiveRange{Start: 1, End: 1}), // [$y]
+				NewOperationBr(NewLabel(LabelKindReturn, 0)),       // return!
+			},
+		},
+		{
+			name: "br_on_non_null",
+			body: []byte{
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeBlock, wasm.RefTypePrefixNonNullable, 0,
+				wasm.OpcodeRefFunc, 0, wasm.OpcodeBrOnNonNull, 0,
+				wasm.OpcodeRefFunc, 0,
+				wasm.OpcodeEnd,
+				wasm.OpcodeCallRef, 0,
+				wasm.OpcodeEnd,
+			},
+			expected: []UnionOperation{ // begin with params: [$x]
+				NewOperationPick(0, false),     // [$x, $x]
+				NewOperationRefFunc(0),         // [$x, $x, $f]
+				NewOperationPick(0, false),     // [$x, $x, $f, $f]
+				NewOperationEqz(UnsignedInt64), // [$x, $x, $f, $f == null]
+				NewOperationEqz(UnsignedInt32), // [$x, $x, $f, $f != null]
+				NewOperationBrIf(NewLabel(LabelKindContinuation, 2), NewLabel(LabelKindHeader, 3), NopInclusiveRange),
+				NewOperationLabel(NewLabel(LabelKindHeader, 3)),    // [$x, $x, null]
+				NewOperationDrop(InclusiveRange{Start: 0, End: 0}), // [$x, $x]
+				NewOperationRefFunc(0), // [$x, $x, $f]
+				NewOperationBr(NewLabel(LabelKindContinuation, 2)),
+				NewOperationLabel(NewLabel(LabelKindContinuation, 2)),
+				NewOperationCallRef(0),                             // [$x, $y]
+				NewOperationDrop(InclusiveRange{Start: 1, End: 1}), // [$y]
+				NewOperationBr(NewLabel(LabelKindReturn, 0)),       // return!
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			module := &wasm.Module{
+				TypeSection:     []wasm.FunctionType{i32_i32},
+				FunctionSection: []wasm.Index{0},
+				CodeSection:     []wasm.Code{{Body: tc.body}},
+			}
+			c, err := NewCompiler(api.CoreFeaturesV2|api.CoreFeatureFunctionReferences, 0, module, false, false, false)
 			require.NoError(t, err)
 
 			actual, err := c.Next()
@@ -878,7 +1247,7 @@ func TestCompile_TableGetOrSet(t *testing.T) {
 				CodeSection:     []wasm.Code{{Body: tc.body}},
 				TableSection:    []wasm.Table{{}},
 			}
-			c, err := NewCompiler(api.CoreFeaturesV2, 0, module, false)
+			c, err := NewCompiler(api.CoreFeaturesV2, 0, module, false, false, false)
 			require.NoError(t, err)
 
 			actual, err := c.Next()
@@ -950,7 +1319,7 @@ func TestCompile_TableGrowFillSize(t *testing.T) {
 				CodeSection:     []wasm.Code{{Body: tc.body}},
 				TableSection:    []wasm.Table{{}},
 			}
-			c, err := NewCompiler(api.CoreFeaturesV2, 0, module, false)
+			c, err := NewCompiler(api.CoreFeaturesV2, 0, module, false, false, false)
 			require.NoError(t, err)
 
 			actual, err := c.Next()
@@ -1161,7 +1530,7 @@ func TestCompile_Locals(t *testing.T) {
 	for _, tt := range tests {
 		tc := tt
 		t.Run(tc.name, func(t *testing.T) {
-			c, err := NewCompiler(api.CoreFeaturesV2, 0, tc.mod, false)
+			c, err := NewCompiler(api.CoreFeaturesV2, 0, tc.mod, false, false, false)
 			require.NoError(t, err)
 
 			actual, err := c.Next()
@@ -1270,6 +1639,13 @@ func TestCompile_Vec(t *testing.T) {
 		return
 	}
 
+	relaxed := func(paramNum int, vec wasm.OpcodeVecRelaxed) (ret []byte) {
+		for i := 0; i < paramNum; i++ {
+			ret = addV128Const(ret)
+		}
+		return append(ret, wasm.OpcodeVecPrefix, vec, wasm.OpcodeVecRelaxedSuffix, wasm.OpcodeDrop, wasm.OpcodeEnd)
+	}
+
 	tests := []struct {
 		name                 string
 		body                 []byte
@@ -2633,6 +3009,86 @@ func TestCompile_Vec(t *testing.T) {
 			needDropBeforeReturn: true,
 			expected:             NewOperationV128ITruncSatFromF(ShapeF64x2, false),
 		},
+		{
+			name: wasm.OpcodeVecI8x16RelaxedSwizzleName, body: relaxed(2, wasm.OpcodeVecI8x16RelaxedSwizzle),
+			needDropBeforeReturn: true,
+			expected:             NewOperationV128Swizzle(),
+		},
+		{
+			name: wasm.OpcodeVecI32x4RelaxedTruncF32x4SName, body: relaxed(1, wasm.OpcodeVecI32x4RelaxedTruncF32x4S),
+			needDropBeforeReturn: true,
+			expected:             NewOperationV128ITruncSatFromF(ShapeF32x4, true),
+		},
+		{
+			name: wasm.OpcodeVecI32x4RelaxedTruncF32x4UName, body: relaxed(1, wasm.OpcodeVecI32x4RelaxedTruncF32x4U),
+			needDropBeforeReturn: true,
+			expected:             NewOperationV128ITruncSatFromF(ShapeF32x4, false),
+		},
+		{
+			name: wasm.OpcodeVecI32x4RelaxedTruncF64x2SZeroName, body: relaxed(1, wasm.OpcodeVecI32x4RelaxedTruncF64x2SZero),
+			needDropBeforeReturn: true,
+			expected:             NewOperationV128ITruncSatFromF(ShapeF64x2, true),
+		},
+		{
+			name: wasm.OpcodeVecI32x4RelaxedTruncF64x2UZeroName, body: relaxed(1, wasm.OpcodeVecI32x4RelaxedTruncF64x2UZero),
+			needDropBeforeReturn: true,
+			expected:             NewOperationV128ITruncSatFromF(ShapeF64x2, false),
+		},
+		{
+			name: wasm.OpcodeVecF32x4RelaxedMaddName, body: relaxed(3, wasm.OpcodeVecF32x4RelaxedMadd),
+			needDropBeforeReturn: true,
+			expected:             NewOperationV128RelaxedMadd(ShapeF32x4, false),
+		},
+		{
+			name: wasm.OpcodeVecF32x4RelaxedNmaddName, body: relaxed(3, wasm.OpcodeVecF32x4RelaxedNmadd),
+			needDropBeforeReturn: true,
+			expected:             NewOperationV128RelaxedMadd(ShapeF32x4, true),
+		},
+		{
+			name: wasm.OpcodeVecF64x2RelaxedMaddName, body: relaxed(3, wasm.OpcodeVecF64x2RelaxedMadd),
+			needDropBeforeReturn: true,
+			expected:             NewOperationV128RelaxedMadd(ShapeF64x2, false),
+		},
+		{
+			name: wasm.OpcodeVecF64x2RelaxedNmaddName, body: relaxed(3, wasm.OpcodeVecF64x2RelaxedNmadd),
+			needDropBeforeReturn: true,
+			expected:             NewOperationV128RelaxedMadd(ShapeF64x2, true),
+		},
+		{
+			name: wasm.OpcodeVecI8x16RelaxedLaneselectName, body: relaxed(3, wasm.OpcodeVecI8x16RelaxedLaneselect),
+			needDropBeforeReturn: true,
+			expected:             NewOperationV128Bitselect(),
+		},
+		{
+			name: wasm.OpcodeVecI64x2RelaxedLaneselectName, body: relaxed(3, wasm.OpcodeVecI64x2RelaxedLaneselect),
+			needDropBeforeReturn: true,
+			expected:             NewOperationV128Bitselect(),
+		},
+		{
+			name: wasm.OpcodeVecF32x4RelaxedMinName, body: relaxed(2, wasm.OpcodeVecF32x4RelaxedMin),
+			needDropBeforeReturn: true,
+			expected:             NewOperationV128Min(ShapeF32x4, false),
+		},
+		{
+			name: wasm.OpcodeVecF64x2RelaxedMaxName, body: relaxed(2, wasm.OpcodeVecF64x2RelaxedMax),
+			needDropBeforeReturn: true,
+			expected:             NewOperationV128Max(ShapeF64x2, false),
+		},
+		{
+			name: wasm.OpcodeVecI16x8RelaxedQ15mulrSName, body: relaxed(2, wasm.OpcodeVecI16x8RelaxedQ15mulrS),
+			needDropBeforeReturn: true,
+			expected:             NewOperationV128Q15mulrSatS(),
+		},
+		{
+			name: wasm.OpcodeVecI16x8RelaxedDotI8x16I7x16SName, body: relaxed(2, wasm.OpcodeVecI16x8RelaxedDotI8x16I7x16S),
+			needDropBeforeReturn: true,
+			expected:             NewOperationV128RelaxedDot(false),
+		},
+		{
+			name: wasm.OpcodeVecI32x4RelaxedDotI8x16I7x16AddSName, body: relaxed(3, wasm.OpcodeVecI32x4RelaxedDotI8x16I7x16AddS),
+			needDropBeforeReturn: true,
+			expected:             NewOperationV128RelaxedDot(true),
+		},
 	}
 
 	for _, tt := range tests {
@@ -2641,10 +3097,10 @@ func TestCompile_Vec(t *testing.T) {
 			module := &wasm.Module{
 				TypeSection:     []wasm.FunctionType{v_v},
 				FunctionSection: []wasm.Index{0},
-				MemorySection:   &wasm.Memory{},
+				MemorySection:   []wasm.Memory{{}},
 				CodeSection:     []wasm.Code{{Body: tc.body}},
 			}
-			c, err := NewCompiler(api.CoreFeaturesV2, 0, module, false)
+			c, err := NewCompiler(api.CoreFeaturesV2, 0, module, false, false, false)
 			require.NoError(t, err)
 
 			res, err := c.Next()
@@ -2724,7 +3180,7 @@ func TestCompile_unreachable_Br_BrIf_BrTable(t *testing.T) {
 	for _, tt := range tests {
 		tc := tt
 		t.Run(tc.name, func(t *testing.T) {
-			c, err := NewCompiler(api.CoreFeaturesV2, 0, tc.mod, false)
+			c, err := NewCompiler(api.CoreFeaturesV2, 0, tc.mod, false, false, false)
 			require.NoError(t, err)
 
 			actual, err := c.Next()
@@ -2767,7 +3223,7 @@ func TestCompile_drop_vectors(t *testing.T) {
 	for _, tt := range tests {
 		tc := tt
 		t.Run(tc.name, func(t *testing.T) {
-			c, err := NewCompiler(api.CoreFeaturesV2, 0, tc.mod, false)
+			c, err := NewCompiler(api.CoreFeaturesV2, 0, tc.mod, false, false, false)
 			require.NoError(t, err)
 
 			actual, err := c.Next()
@@ -2840,7 +3296,7 @@ func TestCompile_select_vectors(t *testing.T) {
 	for _, tt := range tests {
 		tc := tt
 		t.Run(tc.name, func(t *testing.T) {
-			c, err := NewCompiler(api.CoreFeaturesV2, 0, tc.mod, false)
+			c, err := NewCompiler(api.CoreFeaturesV2, 0, tc.mod, false, false, false)
 			require.NoError(t, err)
 
 			actual, err := c.Next()
@@ -3062,7 +3518,7 @@ func Test_ensureTermination(t *testing.T) {
 					},
 				}},
 			}
-			c, err := NewCompiler(api.CoreFeaturesV2, 0, mod, tc.ensureTermination)
+			c, err := NewCompiler(api.CoreFeaturesV2, 0, mod, tc.ensureTermination, false, false)
 			require.NoError(t, err)
 
 			actual, err := c.Next()
@@ -3071,3 +3527,86 @@ func Test_ensureTermination(t *testing.T) {
 		})
 	}
 }
+
+func Test_meterFuel(t *testing.T) {
+	mod := &wasm.Module{
+		TypeSection:     []wasm.FunctionType{v_v},
+		FunctionSection: []wasm.Index{0},
+		CodeSection: []wasm.Code{{
+			Body: []byte{
+				wasm.OpcodeI32Const, 0,
+				wasm.OpcodeLoop, 0, wasm.OpcodeI32Const, 1, wasm.OpcodeBrIf, 0, wasm.OpcodeEnd,
+				wasm.OpcodeI32Const, 1,
+				wasm.OpcodeLoop, 0, wasm.OpcodeEnd,
+				wasm.OpcodeI32Add,
+				wasm.OpcodeDrop,
+				wasm.OpcodeEnd,
+			},
+			BodyOffsetInCodeSection: 100,
+		}},
+		DWARFLines: &wasmdebug.DWARFLines{}, // needs source offsets
+	}
+	c, err := NewCompiler(api.CoreFeaturesV2, 0, mod, false, true, false)
+	require.NoError(t, err)
+
+	actual, err := c.Next()
+	require.NoError(t, err)
+	require.Equal(t, `.entrypoint
+	ConsumeFuel 1
+	ConstI32 0x0
+	Br .L2
+.L2
+	ConsumeFuel 2
+	ConstI32 0x1
+	BrIf .L2, .L3
+.L3
+	ConsumeFuel 1
+	ConstI32 0x1
+	Br .L4
+.L4
+	ConsumeFuel 2
+	i32.Add
+	Drop 0..0
+	Br .return
+`, Format(actual.Operations))
+
+	// Source offsets are index-correlated with operations, where fuel is attributed to the first operation of the block.
+	require.Equal(t, len(actual.Operations), len(actual.IROperationSourceOffsetsInWasmBinary))
+	for i, op := range actual.Operations {
+		if op.Kind == OperationKindConsumeFuel {
+			require.Equal(t, actual.IROperationSourceOffsetsInWasmBinary[i+1], actual.IROperationSourceOffsetsInWasmBinary[i])
+		}
+	}
+}
+
+func Test_checkEpoch(t *testing.T) {
+	mod := &wasm.Module{
+		TypeSection:     []wasm.FunctionType{v_v},
+		FunctionSection: []wasm.Index{0},
+		CodeSection: []wasm.Code{{
+			Body: []byte{
+				wasm.OpcodeI32Const, 0,
+				wasm.OpcodeLoop, 0, wasm.OpcodeI32Const, 1, wasm.OpcodeBrIf, 0, wasm.OpcodeEnd,
+				wasm.OpcodeDrop,
+				wasm.OpcodeEnd,
+			},
+		}},
+	}
+	c, err := NewCompiler(api.CoreFeaturesV2, 0, mod, false, false, true)
+	require.NoError(t, err)
+
+	actual, err := c.Next()
+	require.NoError(t, err)
+	require.Equal(t, `.entrypoint
+	CheckEpoch
+	ConstI32 0x0
+	Br .L2
+.L2
+	CheckEpoch
+	ConstI32 0x1
+	BrIf .L2, .L3
+.L3
+	Drop 0..0
+	Br .return
+`, Format(actual.Operations))
+}
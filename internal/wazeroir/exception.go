@@ -0,0 +1,77 @@
+package wazeroir
+
+import "github.com/tetratelabs/wazero/internal/wasm"
+
+// ExceptionHandler is the handler of exceptions thrown by the operations in the body of a try block, introduced in
+// api.CoreFeatureExceptionHandling.
+//
+// Try blocks are nested, so the handler of an operation is the last one in CompilationResult.ExceptionHandlers whose
+// body contains it. If none of its catches matches the exception, it is handled by Next instead.
+type ExceptionHandler struct {
+	// Start and End are the range [Start, End) of the operations in the body of the try block.
+	//
+	// These are the indexes in CompilationResult.Operations, but engines can translate them into other units such as
+	// offsets in the native code, as FindExceptionCatch only compares them with the given position.
+	Start, End uint64
+	// Next is the index of the handler of exceptions which this doesn't catch, or -1 if they are thrown to the caller.
+	//
+	// For a try block ending with wasm.OpcodeDelegate, this is the handler of its label and Catches is empty.
+	Next int
+	// StackHeight is the height of the stack in uint64 below the block, including the parameters and locals of the
+	// function, to which the stack is truncated before entering a catch.
+	StackHeight int
+	// Catches are the catches of the try block in order.
+	Catches []ExceptionCatch
+}
+
+// ExceptionCatch is either wasm.OpcodeCatch or wasm.OpcodeCatchAll of an ExceptionHandler, or a catch clause of
+// wasm.OpcodeTryTable.
+type ExceptionCatch struct {
+	// Tag is the index of the tag caught, unless CatchAll is true.
+	Tag wasm.Index
+	// CatchAll is true if this catches any exception.
+	CatchAll bool
+	// Ref is how the caught exception is referenced on the stack.
+	Ref ExceptionRef
+	// Target is the index of OperationKindCatch in CompilationResult.Operations, or the engine specific address of it.
+	Target uint64
+}
+
+// ExceptionRef is how the exception caught by ExceptionCatch is referenced on the stack, in addition to its values
+// which are pushed unless ExceptionCatch.CatchAll is true.
+type ExceptionRef byte
+
+const (
+	// ExceptionRefNone pushes no reference, which is the case of wasm.TryTableCatchKindCatch and
+	// wasm.TryTableCatchKindCatchAll.
+	ExceptionRefNone ExceptionRef = iota
+	// ExceptionRefHandle pushes the handle of the exception for OperationKindRethrow below its values, which is the
+	// case of wasm.OpcodeCatch and wasm.OpcodeCatchAll.
+	ExceptionRefHandle
+	// ExceptionRefExnref pushes a wasm.ValueTypeExnref referencing the exception above its values, which is the case
+	// of wasm.TryTableCatchKindCatchRef and wasm.TryTableCatchKindCatchAllRef.
+	ExceptionRefExnref
+)
+
+// FindExceptionCatch returns the catch of the exception thrown at the position pc, as well as its handler.
+// matchesTag returns true if the exception is thrown with the tag of the index. This returns false if the exception
+// is not caught in the function.
+func FindExceptionCatch(handlers []ExceptionHandler, pc uint64, matchesTag func(wasm.Index) bool) (*ExceptionHandler, *ExceptionCatch, bool) {
+	h := -1
+	for i := len(handlers) - 1; i >= 0; i-- {
+		if handler := &handlers[i]; handler.Start <= pc && pc < handler.End {
+			h = i
+			break
+		}
+	}
+	for h >= 0 {
+		handler := &handlers[h]
+		for i := range handler.Catches {
+			if c := &handler.Catches[i]; c.CatchAll || matchesTag(c.Tag) {
+				return handler, c, true
+			}
+		}
+		h = handler.Next
+	}
+	return nil, nil, false
+}
@@ -0,0 +1,45 @@
+package wazeroir
+
+import (
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+func TestFindExceptionCatch(t *testing.T) {
+	handlers := []ExceptionHandler{
+		{Start: 0, End: 10, Next: -1, Catches: []ExceptionCatch{{Tag: 0, Target: 10}, {CatchAll: true, Target: 20}}},
+		{Start: 2, End: 8, Next: 0, Catches: []ExceptionCatch{{Tag: 1, Target: 30}}},
+		// Delegates to the function, skipping the handlers above.
+		{Start: 4, End: 6, Next: -1},
+		{Start: 11, End: 15, Next: -1, Catches: []ExceptionCatch{{Tag: 1, Target: 40}}},
+	}
+
+	tests := []struct {
+		name           string
+		pc             uint64
+		tag            wasm.Index
+		expectedTarget uint64
+		expectedOk     bool
+	}{
+		{name: "outermost", pc: 1, tag: 0, expectedTarget: 10, expectedOk: true},
+		{name: "outermost catch_all", pc: 1, tag: 2, expectedTarget: 20, expectedOk: true},
+		{name: "nested", pc: 2, tag: 1, expectedTarget: 30, expectedOk: true},
+		{name: "nested to outer", pc: 7, tag: 0, expectedTarget: 10, expectedOk: true},
+		{name: "delegated", pc: 5, tag: 1},
+		{name: "unmatched", pc: 12, tag: 0},
+		{name: "outside", pc: 16, tag: 1},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			_, c, ok := FindExceptionCatch(handlers, tc.pc, func(tag wasm.Index) bool { return tag == tc.tag })
+			require.Equal(t, tc.expectedOk, ok)
+			if ok {
+				require.Equal(t, tc.expectedTarget, c.Target)
+			}
+		})
+	}
+}
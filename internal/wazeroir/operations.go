@@ -415,6 +415,48 @@ func (o OperationKind) String() (ret string) {
 		ret = "V128ITruncSatFromF"
 	case OperationKindBuiltinFunctionCheckExitCode:
 		ret = "BuiltinFunctionCheckExitCode"
+	case OperationKindConsumeFuel:
+		ret = "ConsumeFuel"
+	case OperationKindCheckEpoch:
+		ret = "CheckEpoch"
+	case OperationKindAtomicMemoryWait:
+		ret = "AtomicMemoryWait"
+	case OperationKindAtomicMemoryNotify:
+		ret = "AtomicMemoryNotify"
+	case OperationKindAtomicFence:
+		ret = "AtomicFence"
+	case OperationKindAtomicLoad:
+		ret = "AtomicLoad"
+	case OperationKindAtomicStore:
+		ret = "AtomicStore"
+	case OperationKindAtomicRMW:
+		ret = "AtomicRMW"
+	case OperationKindAtomicRMWCmpxchg:
+		ret = "AtomicRMWCmpxchg"
+	case OperationKindTailCall:
+		ret = "TailCall"
+	case OperationKindTailCallIndirect:
+		ret = "TailCallIndirect"
+	case OperationKindSelectMemory:
+		ret = "SelectMemory"
+	case OperationKindTry:
+		ret = "Try"
+	case OperationKindCatch:
+		ret = "Catch"
+	case OperationKindThrow:
+		ret = "Throw"
+	case OperationKindRethrow:
+		ret = "Rethrow"
+	case OperationKindThrowRef:
+		ret = "ThrowRef"
+	case OperationKindRefAsNonNull:
+		ret = "RefAsNonNull"
+	case OperationKindCallRef:
+		ret = "CallRef"
+	case OperationKindV128RelaxedMadd:
+		ret = "V128RelaxedMadd"
+	case OperationKindV128RelaxedDot:
+		ret = "V128RelaxedDot"
 	default:
 		panic(fmt.Errorf("unknown operation %d", o))
 	}
@@ -705,6 +747,56 @@ const (
 	// OperationKindBuiltinFunctionCheckExitCode is the Kind for NewOperationBuiltinFunctionCheckExitCode.
 	OperationKindBuiltinFunctionCheckExitCode
 
+	// OperationKindConsumeFuel is the Kind for NewOperationConsumeFuel.
+	OperationKindConsumeFuel
+
+	// OperationKindCheckEpoch is the Kind for NewOperationCheckEpoch.
+	OperationKindCheckEpoch
+
+	// OperationKindAtomicMemoryWait is the Kind for NewOperationAtomicMemoryWait.
+	OperationKindAtomicMemoryWait
+	// OperationKindAtomicMemoryNotify is the Kind for NewOperationAtomicMemoryNotify.
+	OperationKindAtomicMemoryNotify
+	// OperationKindAtomicFence is the Kind for NewOperationAtomicFence.
+	OperationKindAtomicFence
+	// OperationKindAtomicLoad is the Kind for NewOperationAtomicLoad.
+	OperationKindAtomicLoad
+	// OperationKindAtomicStore is the Kind for NewOperationAtomicStore.
+	OperationKindAtomicStore
+	// OperationKindAtomicRMW is the Kind for NewOperationAtomicRMW.
+	OperationKindAtomicRMW
+	// OperationKindAtomicRMWCmpxchg is the Kind for NewOperationAtomicRMWCmpxchg.
+	OperationKindAtomicRMWCmpxchg
+
+	// OperationKindTailCall is the Kind for NewOperationTailCall.
+	OperationKindTailCall
+	// OperationKindTailCallIndirect is the Kind for NewOperationTailCallIndirect.
+	OperationKindTailCallIndirect
+
+	// OperationKindSelectMemory is the Kind for NewOperationSelectMemory.
+	OperationKindSelectMemory
+
+	// OperationKindTry is the Kind for NewOperationTry.
+	OperationKindTry
+	// OperationKindCatch is the Kind for NewOperationCatch.
+	OperationKindCatch
+	// OperationKindThrow is the Kind for NewOperationThrow.
+	OperationKindThrow
+	// OperationKindRethrow is the Kind for NewOperationRethrow.
+	OperationKindRethrow
+	// OperationKindThrowRef is the Kind for NewOperationThrowRef.
+	OperationKindThrowRef
+
+	// OperationKindRefAsNonNull is the Kind for NewOperationRefAsNonNull.
+	OperationKindRefAsNonNull
+	// OperationKindCallRef is the Kind for NewOperationCallRef.
+	OperationKindCallRef
+
+	// OperationKindV128RelaxedMadd is the Kind for NewOperationV128RelaxedMadd.
+	OperationKindV128RelaxedMadd
+	// OperationKindV128RelaxedDot is the Kind for NewOperationV128RelaxedDot.
+	OperationKindV128RelaxedDot
+
 	// operationKindEnd is always placed at the bottom of this iota definition to be used in the test.
 	operationKindEnd
 )
@@ -717,6 +809,136 @@ func NewOperationBuiltinFunctionCheckExitCode() UnionOperation {
 	return UnionOperation{Kind: OperationKindBuiltinFunctionCheckExitCode}
 }
 
+// NewOperationConsumeFuel is a constructor for UnionOperation with Kind OperationKindConsumeFuel.
+//
+// OperationConsumeFuel corresponds to the instruction to subtract the cost of the following block of operations from
+// the fuel of the api.Module, or trap with wasmruntime.ErrRuntimeOutOfFuel when the fuel is insufficient.
+//
+// The cost is stored as U1.
+func NewOperationConsumeFuel(cost uint64) UnionOperation {
+	return UnionOperation{Kind: OperationKindConsumeFuel, U1: cost}
+}
+
+// NewOperationCheckEpoch is a constructor for UnionOperation with Kind OperationKindCheckEpoch.
+//
+// OperationCheckEpoch corresponds to the instruction to trap with wasmruntime.ErrRuntimeEpochInterrupted when the
+// epoch of the store has reached the deadline of the current call. Unlike OperationBuiltinFunctionCheckExitCode,
+// this doesn't close the api.Module.
+func NewOperationCheckEpoch() UnionOperation {
+	return UnionOperation{Kind: OperationKindCheckEpoch}
+}
+
+// AtomicArithmeticOp is the arithmetic operation of OperationKindAtomicRMW.
+type AtomicArithmeticOp byte
+
+const (
+	AtomicArithmeticOpAdd AtomicArithmeticOp = iota
+	AtomicArithmeticOpSub
+	AtomicArithmeticOpAnd
+	AtomicArithmeticOpOr
+	AtomicArithmeticOpXor
+	// AtomicArithmeticOpXchg replaces the value regardless of the old one.
+	AtomicArithmeticOpXchg
+)
+
+// String implements fmt.Stringer.
+func (o AtomicArithmeticOp) String() (ret string) {
+	switch o {
+	case AtomicArithmeticOpAdd:
+		ret = "add"
+	case AtomicArithmeticOpSub:
+		ret = "sub"
+	case AtomicArithmeticOpAnd:
+		ret = "and"
+	case AtomicArithmeticOpOr:
+		ret = "or"
+	case AtomicArithmeticOpXor:
+		ret = "xor"
+	case AtomicArithmeticOpXchg:
+		ret = "xchg"
+	}
+	return
+}
+
+// Apply returns the new value computed from the old value in the memory and the operand v.
+func (o AtomicArithmeticOp) Apply(old, v uint64) uint64 {
+	switch o {
+	case AtomicArithmeticOpAdd:
+		return old + v
+	case AtomicArithmeticOpSub:
+		return old - v
+	case AtomicArithmeticOpAnd:
+		return old & v
+	case AtomicArithmeticOpOr:
+		return old | v
+	case AtomicArithmeticOpXor:
+		return old ^ v
+	default: // AtomicArithmeticOpXchg
+		return v
+	}
+}
+
+// NewOperationAtomicMemoryWait is a constructor for UnionOperation with Kind OperationKindAtomicMemoryWait.
+//
+// This corresponds to wasm.OpcodeAtomicMemoryWait32Name wasm.OpcodeAtomicMemoryWait64Name, where unsignedType is
+// the type of the expected value.
+//
+// The engines are expected to block until notified or timed out, and push the i32 result of the instruction.
+func NewOperationAtomicMemoryWait(unsignedType UnsignedType, arg MemoryArg) UnionOperation {
+	return UnionOperation{Kind: OperationKindAtomicMemoryWait, B1: byte(unsignedType), U1: uint64(arg.Alignment), U2: arg.Offset, B3: arg.Memory64}
+}
+
+// NewOperationAtomicMemoryNotify is a constructor for UnionOperation with Kind OperationKindAtomicMemoryNotify.
+//
+// This corresponds to wasm.OpcodeAtomicMemoryNotifyName.
+func NewOperationAtomicMemoryNotify(arg MemoryArg) UnionOperation {
+	return UnionOperation{Kind: OperationKindAtomicMemoryNotify, U1: uint64(arg.Alignment), U2: arg.Offset, B3: arg.Memory64}
+}
+
+// NewOperationAtomicFence is a constructor for UnionOperation with Kind OperationKindAtomicFence.
+//
+// This corresponds to wasm.OpcodeAtomicFenceName.
+func NewOperationAtomicFence() UnionOperation {
+	return UnionOperation{Kind: OperationKindAtomicFence}
+}
+
+// NewOperationAtomicLoad is a constructor for UnionOperation with Kind OperationKindAtomicLoad.
+//
+// This corresponds to all the atomic loads, e.g. wasm.OpcodeAtomicI32LoadName wasm.OpcodeAtomicI64Load8UName.
+// Since atomic instructions require the natural alignment, the access width in bytes is 1<<arg.Alignment, and the
+// loaded value is zero-extended to unsignedType.
+//
+// The engines are expected to trap on the unaligned or out-of-bounds access.
+func NewOperationAtomicLoad(unsignedType UnsignedType, arg MemoryArg) UnionOperation {
+	return UnionOperation{Kind: OperationKindAtomicLoad, B1: byte(unsignedType), U1: uint64(arg.Alignment), U2: arg.Offset, B3: arg.Memory64}
+}
+
+// NewOperationAtomicStore is a constructor for UnionOperation with Kind OperationKindAtomicStore.
+//
+// This corresponds to all the atomic stores, e.g. wasm.OpcodeAtomicI32StoreName wasm.OpcodeAtomicI64Store8Name.
+// The access width in bytes is 1<<arg.Alignment as in NewOperationAtomicLoad.
+func NewOperationAtomicStore(unsignedType UnsignedType, arg MemoryArg) UnionOperation {
+	return UnionOperation{Kind: OperationKindAtomicStore, B1: byte(unsignedType), U1: uint64(arg.Alignment), U2: arg.Offset, B3: arg.Memory64}
+}
+
+// NewOperationAtomicRMW is a constructor for UnionOperation with Kind OperationKindAtomicRMW.
+//
+// This corresponds to all the atomic read-modify-write instructions except cmpxchg, e.g.
+// wasm.OpcodeAtomicI32RmwAddName wasm.OpcodeAtomicI64Rmw8XchgUName. The access width in bytes is 1<<arg.Alignment as
+// in NewOperationAtomicLoad, and the old value is pushed as the result.
+func NewOperationAtomicRMW(unsignedType UnsignedType, arg MemoryArg, op AtomicArithmeticOp) UnionOperation {
+	return UnionOperation{Kind: OperationKindAtomicRMW, B1: byte(unsignedType), B2: byte(op), U1: uint64(arg.Alignment), U2: arg.Offset, B3: arg.Memory64}
+}
+
+// NewOperationAtomicRMWCmpxchg is a constructor for UnionOperation with Kind OperationKindAtomicRMWCmpxchg.
+//
+// This corresponds to all the atomic compare-and-exchange instructions, e.g. wasm.OpcodeAtomicI32RmwCmpxchgName
+// wasm.OpcodeAtomicI64Rmw32CmpxchgUName. The access width in bytes is 1<<arg.Alignment as in NewOperationAtomicLoad,
+// and the old value is pushed as the result.
+func NewOperationAtomicRMWCmpxchg(unsignedType UnsignedType, arg MemoryArg) UnionOperation {
+	return UnionOperation{Kind: OperationKindAtomicRMWCmpxchg, B1: byte(unsignedType), U1: uint64(arg.Alignment), U2: arg.Offset, B3: arg.Memory64}
+}
+
 // Label is the unique identifier for each block in a single function in wazeroir
 // where "block" consists of multiple operations, and must End with branching operations
 // (e.g. OperationKindBr or OperationKindBrIf).
@@ -828,9 +1050,16 @@ func (o UnionOperation) String() string {
 		OperationKindTableSize,
 		OperationKindTableGrow,
 		OperationKindTableFill,
-		OperationKindBuiltinFunctionCheckExitCode:
+		OperationKindBuiltinFunctionCheckExitCode,
+		OperationKindCheckEpoch,
+		OperationKindAtomicFence,
+		OperationKindRefAsNonNull,
+		OperationKindThrowRef:
 		return o.Kind.String()
 
+	case OperationKindConsumeFuel:
+		return fmt.Sprintf("%s %d", o.Kind, o.U1)
+
 	case OperationKindCall,
 		OperationKindGlobalGet,
 		OperationKindGlobalSet:
@@ -859,9 +1088,25 @@ func (o UnionOperation) String() string {
 		}
 		return fmt.Sprintf("%s [%s] %s", o.Kind, strings.Join(targets, ","), defaultLabel)
 
-	case OperationKindCallIndirect:
+	case OperationKindTailCall, OperationKindSelectMemory, OperationKindTry, OperationKindThrow, OperationKindRethrow:
+		return fmt.Sprintf("%s %d", o.Kind, o.U1)
+
+	case OperationKindCatch:
+		var ref string
+		if ExceptionRef(o.B2) == ExceptionRefExnref {
+			ref = ", ref"
+		}
+		if o.B1 == 1 {
+			return fmt.Sprintf("%s: handler=%d, all%s", o.Kind, o.U1, ref)
+		}
+		return fmt.Sprintf("%s: handler=%d, tag=%d%s", o.Kind, o.U1, o.U2, ref)
+
+	case OperationKindCallIndirect, OperationKindTailCallIndirect:
 		return fmt.Sprintf("%s: type=%d, table=%d", o.Kind, o.U1, o.U2)
 
+	case OperationKindCallRef:
+		return fmt.Sprintf("%s: type=%d", o.Kind, o.U1)
+
 	case OperationKindDrop:
 		start := int64(o.U1)
 		end := int64(o.U2)
@@ -870,9 +1115,16 @@ func (o UnionOperation) String() string {
 	case OperationKindPick, OperationKindSet:
 		return fmt.Sprintf("%s %d (is_vector=%v)", o.Kind, o.U1, o.B3)
 
-	case OperationKindLoad, OperationKindStore:
+	case OperationKindLoad, OperationKindStore,
+		OperationKindAtomicLoad, OperationKindAtomicStore, OperationKindAtomicRMWCmpxchg, OperationKindAtomicMemoryWait:
 		return fmt.Sprintf("%s.%s (align=%d, offset=%d)", UnsignedType(o.B1), o.Kind, o.U1, o.U2)
 
+	case OperationKindAtomicRMW:
+		return fmt.Sprintf("%s.%s.%s (align=%d, offset=%d)", UnsignedType(o.B1), o.Kind, AtomicArithmeticOp(o.B2), o.U1, o.U2)
+
+	case OperationKindAtomicMemoryNotify:
+		return fmt.Sprintf("%s (align=%d, offset=%d)", o.Kind, o.U1, o.U2)
+
 	case OperationKindLoad8,
 		OperationKindLoad16:
 		return fmt.Sprintf("%s.%s (align=%d, offset=%d)", SignedType(o.B1), o.Kind, o.U1, o.U2)
@@ -1010,6 +1262,11 @@ func (o UnionOperation) String() string {
 		OperationKindV128Narrow:
 		return o.Kind.String()
 
+	case OperationKindV128RelaxedMadd:
+		return fmt.Sprintf("%s (shape=%s, negate=%v)", o.Kind, shapeName(o.B1), o.B3)
+	case OperationKindV128RelaxedDot:
+		return fmt.Sprintf("%s (add=%v)", o.Kind, o.B3)
+
 	case OperationKindV128ITruncSatFromF:
 		if o.B3 {
 			return fmt.Sprintf("%s.%sS", o.Kind, shapeName(o.B1))
@@ -1101,6 +1358,35 @@ func NewOperationCallIndirect(typeIndex, tableIndex uint32) UnionOperation {
 	return UnionOperation{Kind: OperationKindCallIndirect, U1: uint64(typeIndex), U2: uint64(tableIndex)}
 }
 
+// NewOperationTailCall is a constructor for UnionOperation with OperationKindTailCall.
+//
+// This corresponds to wasm.OpcodeReturnCallName. Like OperationKindCall, engines are expected to enter into the
+// function whose index equals U1, except that the callee replaces the frame of the current function: the arguments
+// on top of the stack are moved to where the parameters of the current function are, and the callee returns its
+// results directly to the caller of the current function.
+//
+// Note: All the values on the stack of the current function are discarded, so compilers don't emit OperationDrop.
+func NewOperationTailCall(functionIndex uint32) UnionOperation {
+	return UnionOperation{Kind: OperationKindTailCall, U1: uint64(functionIndex)}
+}
+
+// NewOperationTailCallIndirect is a constructor for UnionOperation with OperationKindTailCallIndirect.
+//
+// This corresponds to wasm.OpcodeReturnCallIndirectName, and is the same as OperationKindCallIndirect except the
+// callee replaces the frame of the current function as OperationKindTailCall does.
+func NewOperationTailCallIndirect(typeIndex, tableIndex uint32) UnionOperation {
+	return UnionOperation{Kind: OperationKindTailCallIndirect, U1: uint64(typeIndex), U2: uint64(tableIndex)}
+}
+
+// NewOperationSelectMemory is a constructor for UnionOperation with OperationKindSelectMemory.
+//
+// This is emitted before a memory instruction whose memory index immediate is non-zero, which is only valid when the
+// multi-memory feature is enabled, and again with zero after it. The engines are expected to access the memory at
+// memoryIndex in ModuleInstance.Memories for the memory operations in between, instead of the memory at index zero.
+func NewOperationSelectMemory(memoryIndex uint32) UnionOperation {
+	return UnionOperation{Kind: OperationKindSelectMemory, U1: uint64(memoryIndex)}
+}
+
 // InclusiveRange is the range which spans across the value stack starting from the top to the bottom, and
 // both boundary are included in the range.
 type InclusiveRange struct {
@@ -1199,7 +1485,13 @@ type MemoryArg struct {
 
 	// Offset is the address offset added to the instruction's dynamic address operand, yielding a 33-bit effective
 	// address that is the zero-based index at which the memory is accessed. Default to zero.
-	Offset uint32
+	//
+	// This is only larger than math.MaxUint32 when Memory64 is true.
+	Offset uint64
+
+	// Memory64 is true when the accessed memory is 64-bit, introduced in api.CoreFeatureMemory64. When true, the
+	// dynamic address operand is an i64, and the effective address is 65-bit.
+	Memory64 bool
 }
 
 // NewOperationLoad is a constructor for UnionOperation with OperationKindLoad.
@@ -1209,7 +1501,7 @@ type MemoryArg struct {
 // The engines are expected to check the boundary of memory length, and exit the execution if this exceeds the boundary,
 // otherwise load the corresponding value following the semantics of the corresponding WebAssembly instruction.
 func NewOperationLoad(unsignedType UnsignedType, arg MemoryArg) UnionOperation {
-	return UnionOperation{Kind: OperationKindLoad, B1: byte(unsignedType), U1: uint64(arg.Alignment), U2: uint64(arg.Offset)}
+	return UnionOperation{Kind: OperationKindLoad, B1: byte(unsignedType), U1: uint64(arg.Alignment), U2: arg.Offset, B3: arg.Memory64}
 }
 
 // NewOperationLoad8 is a constructor for UnionOperation with OperationKindLoad8.
@@ -1219,7 +1511,7 @@ func NewOperationLoad(unsignedType UnsignedType, arg MemoryArg) UnionOperation {
 // The engines are expected to check the boundary of memory length, and exit the execution if this exceeds the boundary,
 // otherwise load the corresponding value following the semantics of the corresponding WebAssembly instruction.
 func NewOperationLoad8(signedInt SignedInt, arg MemoryArg) UnionOperation {
-	return UnionOperation{Kind: OperationKindLoad8, B1: byte(signedInt), U1: uint64(arg.Alignment), U2: uint64(arg.Offset)}
+	return UnionOperation{Kind: OperationKindLoad8, B1: byte(signedInt), U1: uint64(arg.Alignment), U2: arg.Offset, B3: arg.Memory64}
 }
 
 // NewOperationLoad16 is a constructor for UnionOperation with OperationKindLoad16.
@@ -1229,7 +1521,7 @@ func NewOperationLoad8(signedInt SignedInt, arg MemoryArg) UnionOperation {
 // The engines are expected to check the boundary of memory length, and exit the execution if this exceeds the boundary,
 // otherwise load the corresponding value following the semantics of the corresponding WebAssembly instruction.
 func NewOperationLoad16(signedInt SignedInt, arg MemoryArg) UnionOperation {
-	return UnionOperation{Kind: OperationKindLoad16, B1: byte(signedInt), U1: uint64(arg.Alignment), U2: uint64(arg.Offset)}
+	return UnionOperation{Kind: OperationKindLoad16, B1: byte(signedInt), U1: uint64(arg.Alignment), U2: arg.Offset, B3: arg.Memory64}
 }
 
 // NewOperationLoad32 is a constructor for UnionOperation with OperationKindLoad32.
@@ -1243,7 +1535,7 @@ func NewOperationLoad32(signed bool, arg MemoryArg) UnionOperation {
 	if signed {
 		sigB = 1
 	}
-	return UnionOperation{Kind: OperationKindLoad32, B1: sigB, U1: uint64(arg.Alignment), U2: uint64(arg.Offset)}
+	return UnionOperation{Kind: OperationKindLoad32, B1: sigB, U1: uint64(arg.Alignment), U2: arg.Offset, B3: arg.Memory64}
 }
 
 // NewOperationStore is a constructor for UnionOperation with OperationKindStore.
@@ -1253,7 +1545,7 @@ func NewOperationLoad32(signed bool, arg MemoryArg) UnionOperation {
 // The engines are expected to check the boundary of memory length, and exit the execution if this exceeds the boundary,
 // otherwise store the corresponding value following the semantics of the corresponding WebAssembly instruction.
 func NewOperationStore(unsignedType UnsignedType, arg MemoryArg) UnionOperation {
-	return UnionOperation{Kind: OperationKindStore, B1: byte(unsignedType), U1: uint64(arg.Alignment), U2: uint64(arg.Offset)}
+	return UnionOperation{Kind: OperationKindStore, B1: byte(unsignedType), U1: uint64(arg.Alignment), U2: arg.Offset, B3: arg.Memory64}
 }
 
 // NewOperationStore8 is a constructor for UnionOperation with OperationKindStore8.
@@ -1263,7 +1555,7 @@ func NewOperationStore(unsignedType UnsignedType, arg MemoryArg) UnionOperation
 // The engines are expected to check the boundary of memory length, and exit the execution if this exceeds the boundary,
 // otherwise store the corresponding value following the semantics of the corresponding WebAssembly instruction.
 func NewOperationStore8(arg MemoryArg) UnionOperation {
-	return UnionOperation{Kind: OperationKindStore8, U1: uint64(arg.Alignment), U2: uint64(arg.Offset)}
+	return UnionOperation{Kind: OperationKindStore8, U1: uint64(arg.Alignment), U2: arg.Offset, B3: arg.Memory64}
 }
 
 // NewOperationStore16 is a constructor for UnionOperation with OperationKindStore16.
@@ -1273,7 +1565,7 @@ func NewOperationStore8(arg MemoryArg) UnionOperation {
 // The engines are expected to check the boundary of memory length, and exit the execution if this exceeds the boundary,
 // otherwise store the corresponding value following the semantics of the corresponding WebAssembly instruction.
 func NewOperationStore16(arg MemoryArg) UnionOperation {
-	return UnionOperation{Kind: OperationKindStore16, U1: uint64(arg.Alignment), U2: uint64(arg.Offset)}
+	return UnionOperation{Kind: OperationKindStore16, U1: uint64(arg.Alignment), U2: arg.Offset, B3: arg.Memory64}
 }
 
 // NewOperationStore32 is a constructor for UnionOperation with OperationKindStore32.
@@ -1283,16 +1575,16 @@ func NewOperationStore16(arg MemoryArg) UnionOperation {
 // The engines are expected to check the boundary of memory length, and exit the execution if this exceeds the boundary,
 // otherwise store the corresponding value following the semantics of the corresponding WebAssembly instruction.
 func NewOperationStore32(arg MemoryArg) UnionOperation {
-	return UnionOperation{Kind: OperationKindStore32, U1: uint64(arg.Alignment), U2: uint64(arg.Offset)}
+	return UnionOperation{Kind: OperationKindStore32, U1: uint64(arg.Alignment), U2: arg.Offset, B3: arg.Memory64}
 }
 
 // NewOperationMemorySize is a constructor for UnionOperation with OperationKindMemorySize.
 //
 // This corresponds to wasm.OpcodeMemorySize.
 //
-// The engines are expected to push the current page size of the memory onto the stack.
-func NewOperationMemorySize() UnionOperation {
-	return UnionOperation{Kind: OperationKindMemorySize}
+// The engines are expected to push the current page size of the memory onto the stack, as an i64 if memory64 is true.
+func NewOperationMemorySize(memory64 bool) UnionOperation {
+	return UnionOperation{Kind: OperationKindMemorySize, B3: memory64}
 }
 
 // NewOperationMemoryGrow is a constructor for UnionOperation with OperationKindMemoryGrow.
@@ -1301,9 +1593,9 @@ func NewOperationMemorySize() UnionOperation {
 //
 // The engines are expected to pop one value from the top of the stack, then
 // execute wasm.MemoryInstance Grow with the value, and push the previous
-// page size of the memory onto the stack.
-func NewOperationMemoryGrow() UnionOperation {
-	return UnionOperation{Kind: OperationKindMemoryGrow}
+// page size of the memory onto the stack. Both values are i64 if memory64 is true.
+func NewOperationMemoryGrow(memory64 bool) UnionOperation {
+	return UnionOperation{Kind: OperationKindMemoryGrow, B3: memory64}
 }
 
 // NewOperationConstI32 is a constructor for UnionOperation with OperationConstI32.
@@ -1785,9 +2077,10 @@ func NewOperationSignExtend64From32() UnionOperation {
 // This corresponds to wasm.OpcodeMemoryInitName.
 //
 // dataIndex is the index of the data instance in ModuleInstance.DataInstances
-// by which this operation instantiates a part of the memory.
-func NewOperationMemoryInit(dataIndex uint32) UnionOperation {
-	return UnionOperation{Kind: OperationKindMemoryInit, U1: uint64(dataIndex)}
+// by which this operation instantiates a part of the memory. memory64 is true
+// when the destination address is an i64.
+func NewOperationMemoryInit(dataIndex uint32, memory64 bool) UnionOperation {
+	return UnionOperation{Kind: OperationKindMemoryInit, U1: uint64(dataIndex), B3: memory64}
 }
 
 // NewOperationDataDrop implements Operation.
@@ -1803,13 +2096,22 @@ func NewOperationDataDrop(dataIndex uint32) UnionOperation {
 // NewOperationMemoryCopy is a consuctor for UnionOperation with OperationKindMemoryCopy.
 //
 // This corresponds to wasm.OpcodeMemoryCopyName.
-func NewOperationMemoryCopy() UnionOperation {
-	return UnionOperation{Kind: OperationKindMemoryCopy}
+//
+// destinationMemoryIndex and sourceMemoryIndex are the indexes of the memories in ModuleInstance.Memories, which are
+// only non-zero when the multi-memory feature is enabled. Unlike other memory operations, this doesn't use the memory
+// selected by OperationKindSelectMemory.
+//
+// memory64 is true when either memory is 64-bit. In that case, each address is an i64 if its memory is 64-bit, and the
+// size is an i64 only if both memories are 64-bit.
+func NewOperationMemoryCopy(destinationMemoryIndex, sourceMemoryIndex uint32, memory64 bool) UnionOperation {
+	return UnionOperation{Kind: OperationKindMemoryCopy, U1: uint64(destinationMemoryIndex), U2: uint64(sourceMemoryIndex), B3: memory64}
 }
 
 // NewOperationMemoryFill is a consuctor for UnionOperation with OperationKindMemoryFill.
-func NewOperationMemoryFill() UnionOperation {
-	return UnionOperation{Kind: OperationKindMemoryFill}
+//
+// memory64 is true when the address and the size are i64.
+func NewOperationMemoryFill(memory64 bool) UnionOperation {
+	return UnionOperation{Kind: OperationKindMemoryFill, B3: memory64}
 }
 
 // NewOperationTableInit is a constructor for UnionOperation with OperationKindTableInit.
@@ -1980,7 +2282,7 @@ const (
 //	wasm.OpcodeVecV128Load32SplatName wasm.OpcodeVecV128Load64SplatName wasm.OpcodeVecV128Load32zeroName
 //	wasm.OpcodeVecV128Load64zeroName
 func NewOperationV128Load(loadType V128LoadType, arg MemoryArg) UnionOperation {
-	return UnionOperation{Kind: OperationKindV128Load, B1: loadType, U1: uint64(arg.Alignment), U2: uint64(arg.Offset)}
+	return UnionOperation{Kind: OperationKindV128Load, B1: loadType, U1: uint64(arg.Alignment), U2: arg.Offset, B3: arg.Memory64}
 }
 
 // NewOperationV128LoadLane is a constructor for UnionOperation with OperationKindV128LoadLane.
@@ -1992,7 +2294,7 @@ func NewOperationV128Load(loadType V128LoadType, arg MemoryArg) UnionOperation {
 // laneIndex is >=0 && <(128/LaneSize).
 // laneSize is either 8, 16, 32, or 64.
 func NewOperationV128LoadLane(laneIndex, laneSize byte, arg MemoryArg) UnionOperation {
-	return UnionOperation{Kind: OperationKindV128LoadLane, B1: laneSize, B2: laneIndex, U1: uint64(arg.Alignment), U2: uint64(arg.Offset)}
+	return UnionOperation{Kind: OperationKindV128LoadLane, B1: laneSize, B2: laneIndex, U1: uint64(arg.Alignment), U2: arg.Offset, B3: arg.Memory64}
 }
 
 // NewOperationV128Store is a constructor for UnionOperation with OperationKindV128Store.
@@ -2004,7 +2306,8 @@ func NewOperationV128Store(arg MemoryArg) UnionOperation {
 	return UnionOperation{
 		Kind: OperationKindV128Store,
 		U1:   uint64(arg.Alignment),
-		U2:   uint64(arg.Offset),
+		U2:   arg.Offset,
+		B3:   arg.Memory64,
 	}
 }
 
@@ -2022,7 +2325,8 @@ func NewOperationV128StoreLane(laneIndex byte, laneSize byte, arg MemoryArg) Uni
 		B1:   laneSize,
 		B2:   laneIndex,
 		U1:   uint64(arg.Alignment),
-		U2:   uint64(arg.Offset),
+		U2:   arg.Offset,
+		B3:   arg.Memory64,
 	}
 }
 
@@ -2565,3 +2869,98 @@ func NewOperationV128Narrow(originShape Shape, signed bool) UnionOperation {
 func NewOperationV128ITruncSatFromF(originShape Shape, signed bool) UnionOperation {
 	return UnionOperation{Kind: OperationKindV128ITruncSatFromF, B1: originShape, B3: signed}
 }
+
+// NewOperationTry is a constructor for UnionOperation with OperationKindTry.
+//
+// This corresponds to wasm.OpcodeTryName, and marks the beginning of the body of the try block whose handler is at
+// handlerIndex in CompilationResult.ExceptionHandlers. Interpreters can ignore this, while compilers are expected to
+// record the state of the stack below the block, which is restored when entering OperationKindCatch of the handler.
+func NewOperationTry(handlerIndex int) UnionOperation {
+	return UnionOperation{Kind: OperationKindTry, U1: uint64(handlerIndex)}
+}
+
+// NewOperationCatch is a constructor for UnionOperation with OperationKindCatch.
+//
+// This corresponds to wasm.OpcodeCatchName or wasm.OpcodeCatchAllName when catchAll is true, or a catch clause of
+// wasm.OpcodeTryTableName, and is the entry of ExceptionCatch of the handler at handlerIndex. This is never reached by
+// the preceding operation, but only by the engine catching an exception: engines are expected to truncate the stack
+// to ExceptionHandler.StackHeight, and push the values of the exception unless catchAll is true, along with the
+// reference to it as documented on ExceptionRef.
+//
+// The handler index is stored as U1, the tag index as U2, B1 is 1 for catch_all, and B2 is the ExceptionRef.
+func NewOperationCatch(handlerIndex int, tagIndex uint32, catchAll bool, ref ExceptionRef) UnionOperation {
+	op := UnionOperation{Kind: OperationKindCatch, U1: uint64(handlerIndex), U2: uint64(tagIndex), B2: byte(ref)}
+	if catchAll {
+		op.B1 = 1
+	}
+	return op
+}
+
+// NewOperationThrow is a constructor for UnionOperation with OperationKindThrow.
+//
+// This corresponds to wasm.OpcodeThrowName, and throws an exception of the tag whose index equals U1, with the values
+// of the parameters of the tag popped from the stack.
+func NewOperationThrow(tagIndex uint32) UnionOperation {
+	return UnionOperation{Kind: OperationKindThrow, U1: uint64(tagIndex)}
+}
+
+// NewOperationRethrow is a constructor for UnionOperation with OperationKindRethrow.
+//
+// This corresponds to wasm.OpcodeRethrowName, and throws the exception whose handle, pushed by OperationKindCatch, is
+// at the given depth from the top of the stack.
+func NewOperationRethrow(depth int) UnionOperation {
+	return UnionOperation{Kind: OperationKindRethrow, U1: uint64(depth)}
+}
+
+// NewOperationThrowRef is a constructor for UnionOperation with OperationKindThrowRef.
+//
+// This corresponds to wasm.OpcodeThrowRefName, and throws the exception referenced by the wasm.ValueTypeExnref popped
+// from the stack, which was pushed by OperationKindCatch. This traps with wasmruntime.ErrRuntimeNullReference if the
+// reference is null.
+func NewOperationThrowRef() UnionOperation {
+	return UnionOperation{Kind: OperationKindThrowRef}
+}
+
+// NewOperationRefAsNonNull is a constructor for UnionOperation with OperationKindRefAsNonNull.
+//
+// This corresponds to wasm.OpcodeRefAsNonNullName, and traps with wasmruntime.ErrRuntimeNullReference if the
+// reference (opaque pointer) on the top of the stack is null. Otherwise, the stack is left unchanged.
+func NewOperationRefAsNonNull() UnionOperation {
+	return UnionOperation{Kind: OperationKindRefAsNonNull}
+}
+
+// NewOperationCallRef is a constructor for UnionOperation with OperationKindCallRef.
+//
+// This corresponds to wasm.OpcodeCallRefName, and engines are expected to pop the function reference (opaque pointer)
+// from the top of the stack, and call the function with the arguments on the stack as OperationKindCallIndirect.
+// The call traps with wasmruntime.ErrRuntimeNullFunctionReference if the reference is null, or with
+// wasmruntime.ErrRuntimeIndirectCallTypeMismatch if the type of the function doesn't match the type at U1.
+func NewOperationCallRef(typeIndex uint32) UnionOperation {
+	return UnionOperation{Kind: OperationKindCallRef, U1: uint64(typeIndex)}
+}
+
+// NewOperationV128RelaxedMadd is a constructor for UnionOperation with OperationKindV128RelaxedMadd.
+//
+// This corresponds to
+//
+//	wasm.OpcodeVecF32x4RelaxedMaddName wasm.OpcodeVecF32x4RelaxedNmaddName
+//	wasm.OpcodeVecF64x2RelaxedMaddName wasm.OpcodeVecF64x2RelaxedNmaddName.
+//
+// This pops c, b and a from the stack, and pushes a*b+c, or -(a*b)+c if negate is true, where shape is either
+// ShapeF32x4 or ShapeF64x2. The product is rounded before the addition, so that the result doesn't depend on
+// whether the platform has fused multiply-add instructions.
+func NewOperationV128RelaxedMadd(shape Shape, negate bool) UnionOperation {
+	return UnionOperation{Kind: OperationKindV128RelaxedMadd, B1: shape, B3: negate}
+}
+
+// NewOperationV128RelaxedDot is a constructor for UnionOperation with OperationKindV128RelaxedDot.
+//
+// This corresponds to wasm.OpcodeVecI16x8RelaxedDotI8x16I7x16SName, and to
+// wasm.OpcodeVecI32x4RelaxedDotI8x16I7x16AddSName if add is true.
+//
+// This multiplies the signed 8-bit lanes of two vectors, and adds adjacent pairs of the products into 16-bit lanes
+// with signed saturation. If add is true, adjacent pairs of the 16-bit lanes are further added into 32-bit lanes,
+// and the result is added to a third vector popped first from the stack.
+func NewOperationV128RelaxedDot(add bool) UnionOperation {
+	return UnionOperation{Kind: OperationKindV128RelaxedDot, B3: add}
+}
@@ -3,6 +3,7 @@ package wazeroir
 import (
 	"fmt"
 
+	"github.com/tetratelabs/wazero/internal/leb128"
 	"github.com/tetratelabs/wazero/internal/wasm"
 )
 
@@ -156,6 +157,26 @@ var (
 		in:  []UnsignedType{UnsignedTypeF64, UnsignedTypeF64},
 		out: []UnsignedType{UnsignedTypeF64},
 	}
+	signature_I32I64_I64 = &signature{
+		in:  []UnsignedType{UnsignedTypeI32, UnsignedTypeI64},
+		out: []UnsignedType{UnsignedTypeI64},
+	}
+	signature_I32I32I32_I32 = &signature{
+		in:  []UnsignedType{UnsignedTypeI32, UnsignedTypeI32, UnsignedTypeI32},
+		out: []UnsignedType{UnsignedTypeI32},
+	}
+	signature_I32I64I64_I64 = &signature{
+		in:  []UnsignedType{UnsignedTypeI32, UnsignedTypeI64, UnsignedTypeI64},
+		out: []UnsignedType{UnsignedTypeI64},
+	}
+	signature_I32I32I64_I32 = &signature{
+		in:  []UnsignedType{UnsignedTypeI32, UnsignedTypeI32, UnsignedTypeI64},
+		out: []UnsignedType{UnsignedTypeI32},
+	}
+	signature_I32I64I64_I32 = &signature{
+		in:  []UnsignedType{UnsignedTypeI32, UnsignedTypeI64, UnsignedTypeI64},
+		out: []UnsignedType{UnsignedTypeI32},
+	}
 	signature_I32I32I32_None = &signature{
 		in: []UnsignedType{UnsignedTypeI32, UnsignedTypeI32, UnsignedTypeI32},
 	}
@@ -248,14 +269,27 @@ func (c *Compiler) wasmOpcodeSignature(op wasm.Opcode, index uint32) (*signature
 		return signature_I32_None, nil
 	case wasm.OpcodeElse, wasm.OpcodeEnd, wasm.OpcodeBr:
 		return signature_None_None, nil
+	case wasm.OpcodeTry, wasm.OpcodeCatch, wasm.OpcodeCatchAll, wasm.OpcodeDelegate, wasm.OpcodeRethrow,
+		wasm.OpcodeTryTable:
+		// The stack of catches is manipulated by the compiler as that of else.
+		return signature_None_None, nil
+	case wasm.OpcodeThrowRef:
+		// throw_ref pops the exnref, which is an opaque handle of i64 at wazeroir layer.
+		return signature_I64_None, nil
+	case wasm.OpcodeThrow:
+		return c.funcTypeToSigs.get(c.tags[index], false /* direct */), nil
 	case wasm.OpcodeBrIf, wasm.OpcodeBrTable:
 		return signature_I32_None, nil
-	case wasm.OpcodeReturn:
+	case wasm.OpcodeReturn, wasm.OpcodeReturnCall, wasm.OpcodeReturnCallIndirect:
+		// The arguments of tail calls are left on the stack, as the engines move them into the frame of the current
+		// function.
 		return signature_None_None, nil
 	case wasm.OpcodeCall:
 		return c.funcTypeToSigs.get(c.funcs[index], false /* direct */), nil
 	case wasm.OpcodeCallIndirect:
 		return c.funcTypeToSigs.get(index, true /* call_indirect */), nil
+	case wasm.OpcodeCallRef:
+		return c.funcTypeToSigs.getRef(index), nil
 	case wasm.OpcodeDrop:
 		return signature_Unknown_None, nil
 	case wasm.OpcodeSelect, wasm.OpcodeTypedSelect:
@@ -452,6 +486,16 @@ func (c *Compiler) wasmOpcodeSignature(op wasm.Opcode, index uint32) (*signature
 	case wasm.OpcodeRefNull:
 		// ref.null is translated as i64.const 0.
 		return signature_None_I64, nil
+	case wasm.OpcodeRefAsNonNull:
+		// ref.as_non_null traps if the opaque pointer on the top of the stack is zero, and leaves it as-is otherwise.
+		return signature_I64_I64, nil
+	case wasm.OpcodeBrOnNull:
+		// The reference is left on the stack when not branching, so the stack is manipulated by the compiler.
+		return signature_None_None, nil
+	case wasm.OpcodeBrOnNonNull:
+		// The reference is carried to the target only when branching, so the compiler pushes it back while
+		// emitting the branch.
+		return signature_I64_None, nil
 	case wasm.OpcodeMiscPrefix:
 		switch miscOp := c.body[c.pc+1]; miscOp {
 		case wasm.OpcodeMiscI32TruncSatF32S, wasm.OpcodeMiscI32TruncSatF32U:
@@ -476,7 +520,63 @@ func (c *Compiler) wasmOpcodeSignature(op wasm.Opcode, index uint32) (*signature
 		default:
 			return nil, fmt.Errorf("unsupported misc instruction in wazeroir: 0x%x", op)
 		}
+	case wasm.OpcodeAtomicPrefix:
+		switch atomicOp := c.body[c.pc+1]; {
+		case atomicOp == wasm.OpcodeAtomicFence:
+			return signature_None_None, nil
+		case atomicOp == wasm.OpcodeAtomicMemoryNotify:
+			return signature_I32I32_I32, nil
+		case atomicOp == wasm.OpcodeAtomicMemoryWait32:
+			return signature_I32I32I64_I32, nil
+		case atomicOp == wasm.OpcodeAtomicMemoryWait64:
+			return signature_I32I64I64_I32, nil
+		case atomicOp >= wasm.OpcodeAtomicI32Load && atomicOp <= wasm.OpcodeAtomicI64Rmw32CmpxchgU:
+			i64 := atomicValueType(atomicOp) == UnsignedTypeI64
+			switch {
+			case atomicOp <= wasm.OpcodeAtomicI64Load32U:
+				if i64 {
+					return signature_I32_I64, nil
+				}
+				return signature_I32_I32, nil
+			case atomicOp <= wasm.OpcodeAtomicI64Store32:
+				if i64 {
+					return signature_I32I64_None, nil
+				}
+				return signature_I32I32_None, nil
+			case atomicOp < wasm.OpcodeAtomicI32RmwCmpxchg:
+				if i64 {
+					return signature_I32I64_I64, nil
+				}
+				return signature_I32I32_I32, nil
+			default:
+				if i64 {
+					return signature_I32I64I64_I64, nil
+				}
+				return signature_I32I32I32_I32, nil
+			}
+		default:
+			return nil, fmt.Errorf("unsupported atomic instruction in wazeroir: 0x%x", atomicOp)
+		}
 	case wasm.OpcodeVecPrefix:
+		if wasm.IsVecRelaxed(c.body, c.pc+1) {
+			switch relaxedOp := c.body[c.pc+1]; relaxedOp {
+			case wasm.OpcodeVecI32x4RelaxedTruncF32x4S, wasm.OpcodeVecI32x4RelaxedTruncF32x4U,
+				wasm.OpcodeVecI32x4RelaxedTruncF64x2SZero, wasm.OpcodeVecI32x4RelaxedTruncF64x2UZero:
+				return signature_V128_V128, nil
+			case wasm.OpcodeVecI8x16RelaxedSwizzle, wasm.OpcodeVecF32x4RelaxedMin, wasm.OpcodeVecF32x4RelaxedMax,
+				wasm.OpcodeVecF64x2RelaxedMin, wasm.OpcodeVecF64x2RelaxedMax, wasm.OpcodeVecI16x8RelaxedQ15mulrS,
+				wasm.OpcodeVecI16x8RelaxedDotI8x16I7x16S:
+				return signature_V128V128_V128, nil
+			case wasm.OpcodeVecF32x4RelaxedMadd, wasm.OpcodeVecF32x4RelaxedNmadd,
+				wasm.OpcodeVecF64x2RelaxedMadd, wasm.OpcodeVecF64x2RelaxedNmadd,
+				wasm.OpcodeVecI8x16RelaxedLaneselect, wasm.OpcodeVecI16x8RelaxedLaneselect,
+				wasm.OpcodeVecI32x4RelaxedLaneselect, wasm.OpcodeVecI64x2RelaxedLaneselect,
+				wasm.OpcodeVecI32x4RelaxedDotI8x16I7x16AddS:
+				return signature_V128V128V128_V32, nil
+			default:
+				return nil, fmt.Errorf("unsupported relaxed vector instruction in wazeroir: %s", wasm.RelaxedVectorInstructionName(relaxedOp))
+			}
+		}
 		switch vecOp := c.body[c.pc+1]; vecOp {
 		case wasm.OpcodeVecV128Const:
 			return signature_None_V128, nil
@@ -591,14 +691,108 @@ func (c *Compiler) wasmOpcodeSignature(op wasm.Opcode, index uint32) (*signature
 	}
 }
 
+// memory64Signature returns the signature of the current instruction when it accesses a 64-bit memory, introduced in
+// api.CoreFeatureMemory64, or s as is otherwise. In that case, addresses as well as sizes and page counts, where
+// applicable, are i64 instead of i32.
+func (c *Compiler) memory64Signature(op wasm.Opcode, s *signature) *signature {
+	switch {
+	case wasm.OpcodeI32Load <= op && op <= wasm.OpcodeI64Store32:
+		if c.memory64(c.peekMemoryArgMemoryIndex(c.pc + 1)) {
+			return withI64Address(s)
+		}
+	case op == wasm.OpcodeMemorySize:
+		if c.memory64(c.peekMemoryIndex(c.pc + 1)) {
+			return signature_None_I64
+		}
+	case op == wasm.OpcodeMemoryGrow:
+		if c.memory64(c.peekMemoryIndex(c.pc + 1)) {
+			return signature_I64_I64
+		}
+	case op == wasm.OpcodeMiscPrefix:
+		pos := c.pc + 2
+		switch c.body[c.pc+1] {
+		case wasm.OpcodeMiscMemoryInit:
+			_, num, _ := leb128.LoadUint32(c.body[pos:]) // Skips the data index.
+			if c.memory64(c.peekMemoryIndex(pos + num)) {
+				return withI64Address(s)
+			}
+		case wasm.OpcodeMiscMemoryFill:
+			if c.memory64(c.peekMemoryIndex(pos)) {
+				return &signature{in: []UnsignedType{UnsignedTypeI64, UnsignedTypeI32, UnsignedTypeI64}}
+			}
+		case wasm.OpcodeMiscMemoryCopy:
+			dst, num, _ := leb128.LoadUint32(c.body[pos:])
+			src, _, _ := leb128.LoadUint32(c.body[pos+num:])
+			dst64, src64 := c.memory64(dst), c.memory64(src)
+			if dst64 || src64 {
+				in := []UnsignedType{UnsignedTypeI32, UnsignedTypeI32, UnsignedTypeI32}
+				if dst64 {
+					in[0] = UnsignedTypeI64
+				}
+				if src64 {
+					in[1] = UnsignedTypeI64
+				}
+				if dst64 && src64 {
+					in[2] = UnsignedTypeI64
+				}
+				return &signature{in: in}
+			}
+		}
+	case op == wasm.OpcodeAtomicPrefix:
+		if c.body[c.pc+1] != wasm.OpcodeAtomicFence && c.memory64(c.peekMemoryArgMemoryIndex(c.pc+2)) {
+			return withI64Address(s)
+		}
+	case op == wasm.OpcodeVecPrefix:
+		vecOp := c.body[c.pc+1]
+		if vecOp <= wasm.OpcodeVecV128Store || (wasm.OpcodeVecV128Load8Lane <= vecOp && vecOp <= wasm.OpcodeVecV128Load64zero) {
+			if c.memory64(c.peekMemoryArgMemoryIndex(c.pc + 2)) {
+				return withI64Address(s)
+			}
+		}
+	}
+	return s
+}
+
+// withI64Address returns a copy of the signature of a memory instruction where the address, the first input, is i64.
+func withI64Address(s *signature) *signature {
+	in := make([]UnsignedType, len(s.in))
+	copy(in, s.in)
+	in[0] = UnsignedTypeI64
+	return &signature{in: in, out: s.out}
+}
+
 // funcTypeToIRSignatures is the central cache for a module to get the *signature
 // for function calls.
 type funcTypeToIRSignatures struct {
 	directCalls   []*signature
 	indirectCalls []*signature
+	refCalls      []*signature
 	wasmTypes     []wasm.FunctionType
 }
 
+// getRef returns the *signature for call_ref against functions whose type is at `typeIndex`.
+func (f *funcTypeToIRSignatures) getRef(typeIndex wasm.Index) *signature {
+	if sig := f.refCalls[typeIndex]; sig != nil {
+		return sig
+	}
+
+	tp := &f.wasmTypes[typeIndex]
+	sig := &signature{
+		in:  make([]UnsignedType, 0, len(tp.Params)+1), // +1 to reserve space for the function reference.
+		out: make([]UnsignedType, 0, len(tp.Results)),
+	}
+	for _, vt := range tp.Params {
+		sig.in = append(sig.in, wasmValueTypeToUnsignedType(vt))
+	}
+	for _, vt := range tp.Results {
+		sig.out = append(sig.out, wasmValueTypeToUnsignedType(vt))
+	}
+	// Function references are opaque pointers, which are i64 at wazeroir layer.
+	sig.in = append(sig.in, UnsignedTypeI64)
+	f.refCalls[typeIndex] = sig
+	return sig
+}
+
 // get returns the *signature for the direct or indirect function call against functions whose type is at `typeIndex`.
 func (f *funcTypeToIRSignatures) get(typeIndex wasm.Index, indirect bool) *signature {
 	var sig *signature
@@ -646,7 +840,7 @@ func wasmValueTypeToUnsignedType(vt wasm.ValueType) UnsignedType {
 		return UnsignedTypeI32
 	case wasm.ValueTypeI64,
 		// From wazeroir layer, ref type values are opaque 64-bit pointers.
-		wasm.ValueTypeExternref, wasm.ValueTypeFuncref:
+		wasm.ValueTypeExternref, wasm.ValueTypeFuncref, wasm.ValueTypeExnref:
 		return UnsignedTypeI64
 	case wasm.ValueTypeF32:
 		return UnsignedTypeF32
@@ -664,7 +858,7 @@ func wasmValueTypeToUnsignedOutSignature(vt wasm.ValueType) *signature {
 		return signature_None_I32
 	case wasm.ValueTypeI64,
 		// From wazeroir layer, ref type values are opaque 64-bit pointers.
-		wasm.ValueTypeExternref, wasm.ValueTypeFuncref:
+		wasm.ValueTypeExternref, wasm.ValueTypeFuncref, wasm.ValueTypeExnref:
 		return signature_None_I64
 	case wasm.ValueTypeF32:
 		return signature_None_F32
@@ -682,7 +876,7 @@ func wasmValueTypeToUnsignedInSignature(vt wasm.ValueType) *signature {
 		return signature_I32_None
 	case wasm.ValueTypeI64,
 		// From wazeroir layer, ref type values are opaque 64-bit pointers.
-		wasm.ValueTypeExternref, wasm.ValueTypeFuncref:
+		wasm.ValueTypeExternref, wasm.ValueTypeFuncref, wasm.ValueTypeExnref:
 		return signature_I64_None
 	case wasm.ValueTypeF32:
 		return signature_F32_None
@@ -700,7 +894,7 @@ func wasmValueTypeToUnsignedInOutSignature(vt wasm.ValueType) *signature {
 		return signature_I32_I32
 	case wasm.ValueTypeI64,
 		// From wazeroir layer, ref type values are opaque 64-bit pointers.
-		wasm.ValueTypeExternref, wasm.ValueTypeFuncref:
+		wasm.ValueTypeExternref, wasm.ValueTypeFuncref, wasm.ValueTypeExnref:
 		return signature_I64_I64
 	case wasm.ValueTypeF32:
 		return signature_F32_F32
@@ -711,3 +905,16 @@ func wasmValueTypeToUnsignedInOutSignature(vt wasm.ValueType) *signature {
 	}
 	panic("unreachable")
 }
+
+// atomicValueType returns the type of the value loaded, stored or modified by the atomic instruction,
+// which must be one of the loads, stores or read-modify-write instructions.
+func atomicValueType(op wasm.OpcodeAtomic) UnsignedType {
+	// Loads, stores and each kind of read-modify-write instructions consist of seven instructions
+	// in the order of i32, i64, i32 8-bit, i32 16-bit, i64 8-bit, i64 16-bit and i64 32-bit.
+	switch (op - wasm.OpcodeAtomicI32Load) % 7 {
+	case 1, 4, 5, 6:
+		return UnsignedTypeI64
+	default:
+		return UnsignedTypeI32
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/tetratelabs/wazero/api"
 	experimentalapi "github.com/tetratelabs/wazero/experimental"
 	internalclose "github.com/tetratelabs/wazero/internal/close"
+	"github.com/tetratelabs/wazero/internal/snapshot"
 	internalsock "github.com/tetratelabs/wazero/internal/sock"
 	internalsys "github.com/tetratelabs/wazero/internal/sys"
 	"github.com/tetratelabs/wazero/internal/wasm"
@@ -136,6 +137,18 @@ type Runtime interface {
 	// Module returns an instantiated module in this runtime or nil if there aren't any.
 	Module(moduleName string) api.Module
 
+	// Modules returns all modules instantiated in this runtime and not yet
+	// closed, in the order they were instantiated. This includes host modules
+	// and modules instantiated without a name.
+	//
+	// Here's an example:
+	//	for _, mod := range r.Modules() {
+	//		fmt.Println(mod.Name())
+	//	}
+	//
+	// See experimental.Stats for the resources held by each module.
+	Modules() []api.Module
+
 	// Closer closes all compiled code by delegating to CloseWithExitCode with an exit code of zero.
 	api.Closer
 }
@@ -159,6 +172,9 @@ func NewRuntimeWithConfig(ctx context.Context, rConfig RuntimeConfig) Runtime {
 		engine = config.newEngine(ctx, config.enabledFeatures, nil)
 	}
 	store := wasm.NewStore(config.enabledFeatures, engine)
+	store.MemoryAllocator = config.memoryAllocator
+	store.MaxCallStackDepth = config.maxCallStackDepth
+	store.MaxStackBytes = config.maxStackBytes
 	return &runtime{
 		cache:                 cacheImpl,
 		store:                 store,
@@ -168,6 +184,7 @@ func NewRuntimeWithConfig(ctx context.Context, rConfig RuntimeConfig) Runtime {
 		dwarfDisabled:         config.dwarfDisabled,
 		storeCustomSections:   config.storeCustomSections,
 		ensureTermination:     config.ensureTermination,
+		compilationWorkers:    config.compilationWorkers,
 	}
 }
 
@@ -190,6 +207,8 @@ type runtime struct {
 	closed atomic.Uint64
 
 	ensureTermination bool
+	// compilationWorkers is the count of goroutines compiling functions, see wasm.WithCompilationWorkers.
+	compilationWorkers int
 }
 
 // Module implements Runtime.Module.
@@ -200,6 +219,11 @@ func (r *runtime) Module(moduleName string) api.Module {
 	return r.store.Module(moduleName)
 }
 
+// Modules implements Runtime.Modules
+func (r *runtime) Modules() []api.Module {
+	return r.store.Modules()
+}
+
 // CompileModule implements Runtime.CompileModule
 func (r *runtime) CompileModule(ctx context.Context, binary []byte) (CompiledModule, error) {
 	if err := r.failIfClosed(); err != nil {
@@ -233,8 +257,11 @@ func (r *runtime) CompileModule(ctx context.Context, binary []byte) (CompiledMod
 	if err != nil {
 		return nil, err
 	}
-	internal.AssignModuleID(binary, len(listeners) > 0, r.ensureTermination)
-	if err = r.store.Engine.CompileModule(ctx, internal, listeners, r.ensureTermination); err != nil {
+	meterFuel, _ := ctx.Value(experimentalapi.FuelMeteringKey{}).(bool)
+	checkEpoch, _ := ctx.Value(experimentalapi.EpochInterruptionKey{}).(bool)
+	internal.AssignModuleID(binary, len(listeners) > 0, r.ensureTermination, meterFuel, checkEpoch)
+	compileCtx := wasm.WithCompilationWorkers(ctx, r.compilationWorkers)
+	if err = r.store.Engine.CompileModule(compileCtx, internal, listeners, r.ensureTermination, meterFuel, checkEpoch); err != nil {
 		return nil, err
 	}
 	return c, nil
@@ -263,6 +290,16 @@ func (r *runtime) failIfClosed() error {
 	return nil
 }
 
+// IncrementEpoch implements experimental.EpochCounter.
+func (r *runtime) IncrementEpoch() {
+	r.store.Epoch.Add(1)
+}
+
+// Epoch implements experimental.EpochCounter.
+func (r *runtime) Epoch() uint64 {
+	return r.store.Epoch.Load()
+}
+
 // Instantiate implements Runtime.Instantiate
 func (r *runtime) Instantiate(ctx context.Context, binary []byte) (api.Module, error) {
 	return r.InstantiateWithConfig(ctx, binary, NewModuleConfig())
@@ -309,7 +346,7 @@ func (r *runtime) InstantiateModule(
 	}
 
 	// Instantiate the module.
-	mod, err = r.store.Instantiate(ctx, code.module, name, sysCtx, code.typeIDs)
+	mod, err = r.store.Instantiate(ctx, code.module, name, sysCtx, code.typeIDs, config.importResolver)
 	if err != nil {
 		// If there was an error, don't leak the compiled module.
 		if code.closeWithModule {
@@ -321,6 +358,7 @@ func (r *runtime) InstantiateModule(
 	if closeNotifier, ok := ctx.Value(internalclose.NotifierKey{}).(internalclose.Notifier); ok {
 		mod.(*wasm.ModuleInstance).CloseNotifier = closeNotifier
 	}
+	mod.(*wasm.ModuleInstance).MaxThreads = config.maxThreads
 
 	// Attach the code closer so that anything afterward closes the compiled
 	// code when closing the module.
@@ -328,6 +366,11 @@ func (r *runtime) InstantiateModule(
 		mod.(*wasm.ModuleInstance).CodeCloser = code
 	}
 
+	// Skip start functions when restoring from a snapshot, as they already ran.
+	if snapshot.FromContext(ctx) != nil {
+		return
+	}
+
 	// Now, invoke any start functions, failing at first error.
 	for _, fn := range config.startFunctions {
 		start := mod.ExportedFunction(fn)
@@ -4,9 +4,11 @@ import (
 	"context"
 	_ "embed"
 	"errors"
+	"fmt"
 	"sync"
 	"testing"
 	"time"
+	"unsafe"
 
 	"github.com/tetratelabs/wazero/api"
 	"github.com/tetratelabs/wazero/experimental"
@@ -16,6 +18,7 @@ import (
 	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
 	"github.com/tetratelabs/wazero/internal/testing/require"
 	"github.com/tetratelabs/wazero/internal/wasm"
+	"github.com/tetratelabs/wazero/internal/wasmruntime"
 	"github.com/tetratelabs/wazero/sys"
 )
 
@@ -96,7 +99,7 @@ func TestRuntime_CompileModule(t *testing.T) {
 		{
 			name: "MemorySection, but not exported",
 			wasm: &wasm.Module{
-				MemorySection: &wasm.Memory{Min: 2, Max: 3, IsMaxEncoded: true},
+				MemorySection: []wasm.Memory{{Min: 2, Max: 3, IsMaxEncoded: true}},
 			},
 			expected: func(compiled CompiledModule) {
 				require.Nil(t, compiled.ImportedMemories())
@@ -106,7 +109,7 @@ func TestRuntime_CompileModule(t *testing.T) {
 		{
 			name: "MemorySection exported",
 			wasm: &wasm.Module{
-				MemorySection: &wasm.Memory{Min: 2, Max: 3, IsMaxEncoded: true},
+				MemorySection: []wasm.Memory{{Min: 2, Max: 3, IsMaxEncoded: true}},
 				ExportSection: []wasm.Export{{
 					Type:  wasm.ExternTypeMemory,
 					Name:  "memory",
@@ -168,7 +171,7 @@ func TestRuntime_CompileModule_Errors(t *testing.T) {
 		},
 		{
 			name:        "memory has too many pages",
-			wasm:        binaryencoding.EncodeModule(&wasm.Module{MemorySection: &wasm.Memory{Min: 2, Cap: 2, Max: 70000, IsMaxEncoded: true}}),
+			wasm:        binaryencoding.EncodeModule(&wasm.Module{MemorySection: []wasm.Memory{{Min: 2, Cap: 2, Max: 70000, IsMaxEncoded: true}}}),
 			expectedErr: "section memory: max 70000 pages (4 Gi) over limit of 65536 pages (4 Gi)",
 		},
 	}
@@ -201,7 +204,7 @@ func TestModule_Memory(t *testing.T) {
 		{
 			name: "memory exported, one page",
 			wasm: binaryencoding.EncodeModule(&wasm.Module{
-				MemorySection: &wasm.Memory{Min: 1},
+				MemorySection: []wasm.Memory{{Min: 1}},
 				ExportSection: []wasm.Export{{Name: "memory", Type: api.ExternTypeMemory}},
 			}),
 			expected:    true,
@@ -235,6 +238,354 @@ func TestModule_Memory(t *testing.T) {
 	}
 }
 
+// TestModule_Table only covers a couple cases to avoid duplication of internal/wasm/table_test.go
+func TestModule_Table(t *testing.T) {
+	r := NewRuntime(testCtx)
+	defer r.Close(testCtx)
+
+	max := uint32(3)
+	bin := binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection:     []wasm.FunctionType{{Results: []wasm.ValueType{wasm.ValueTypeI32}}},
+		FunctionSection: []wasm.Index{0, 0, 0},
+		CodeSection: []wasm.Code{
+			{Body: []byte{wasm.OpcodeI32Const, 1, wasm.OpcodeEnd}},
+			{Body: []byte{wasm.OpcodeI32Const, 2, wasm.OpcodeEnd}},
+			{Body: []byte{wasm.OpcodeI32Const, 0, wasm.OpcodeCallIndirect, 0, 0, wasm.OpcodeEnd}},
+		},
+		TableSection: []wasm.Table{{Min: 2, Max: &max, Type: wasm.RefTypeFuncref}},
+		ElementSection: []wasm.ElementSegment{
+			{
+				OffsetExpr: wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: []byte{0}},
+				Init:       []wasm.Index{0, 1},
+				Type:       wasm.RefTypeFuncref,
+			},
+		},
+		ExportSection: []wasm.Export{
+			{Name: "table", Type: api.ExternTypeTable, Index: 0},
+			{Name: "call", Type: api.ExternTypeFunc, Index: 2},
+		},
+	})
+
+	module, err := r.Instantiate(testCtx, bin)
+	require.NoError(t, err)
+
+	require.Nil(t, module.ExportedTable("call"))
+
+	defs := module.ExportedTableDefinitions()
+	require.Equal(t, 1, len(defs))
+	def := defs["table"]
+	require.Equal(t, api.RefTypeFuncref, def.Type())
+	require.Equal(t, uint32(2), def.Min())
+	defMax, ok := def.Max()
+	require.True(t, ok)
+	require.Equal(t, max, defMax)
+
+	table := module.ExportedTable("table")
+	require.Equal(t, uint32(2), table.Size())
+
+	call := module.ExportedFunction("call")
+	results, err := call.Call(testCtx)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), results[0])
+
+	// Swap the first element with the second, which changes call_indirect.
+	ref, ok := table.Get(1)
+	require.True(t, ok)
+	require.NotEqual(t, uint64(0), ref)
+	require.True(t, table.Set(0, ref))
+
+	results, err = call.Call(testCtx)
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), results[0])
+
+	_, ok = table.Get(2)
+	require.False(t, ok)
+	require.False(t, table.Set(2, ref))
+
+	// Funcrefs not produced by the runtime are rejected, as call_indirect would crash on them.
+	require.False(t, table.Set(0, ref+1))
+	require.False(t, table.Set(0, 0xdeadbeef))
+	_, ok = table.Grow(1, 0xdeadbeef)
+	require.False(t, ok)
+	require.True(t, table.Set(1, 0))
+	require.True(t, table.Set(1, ref))
+
+	prev, ok := table.Grow(1, ref)
+	require.True(t, ok)
+	require.Equal(t, uint32(2), prev)
+	require.Equal(t, uint32(3), table.Size())
+	grown, ok := table.Get(2)
+	require.True(t, ok)
+	require.Equal(t, ref, grown)
+
+	_, ok = table.Grow(1, 0)
+	require.False(t, ok)
+
+	// A funcref of another module in the same runtime is accepted.
+	other, err := r.Instantiate(testCtx, bin)
+	require.NoError(t, err)
+	otherTable := other.ExportedTable("table")
+	otherRef, _ := otherTable.Get(0)
+	require.True(t, otherTable.Set(0, ref))
+	results, err = other.ExportedFunction("call").Call(testCtx)
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), results[0])
+	// Once that module is closed, its funcrefs are no longer accepted.
+	require.NoError(t, other.Close(testCtx))
+	require.False(t, table.Set(0, otherRef))
+
+	fn, err := table.Function(0, nil, []api.ValueType{api.ValueTypeI32})
+	require.NoError(t, err)
+	results, err = fn.Call(testCtx)
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), results[0])
+
+	_, err = table.Function(0, []api.ValueType{api.ValueTypeI32}, nil)
+	require.EqualError(t, err, "indirect call type mismatch")
+	_, err = table.Function(3, nil, []api.ValueType{api.ValueTypeI32})
+	require.EqualError(t, err, "invalid table access")
+}
+
+// TestModule_Table_Callback shows how a host function can call a function
+// the guest passed as an index into its (unexported) table.
+func TestModule_Table_Callback(t *testing.T) {
+	r := NewRuntime(testCtx)
+	defer r.Close(testCtx)
+
+	var callbackErr error
+	_, err := r.NewHostModuleBuilder("env").
+		NewFunctionBuilder().
+		WithFunc(func(ctx context.Context, m api.Module, callback, arg uint32) uint32 {
+			im := m.(experimental.InternalModule)
+			require.Equal(t, 1, im.NumTable())
+
+			// Resolve the table slot, checking its signature.
+			table := im.Table(0)
+			fn, err := table.Function(callback, []api.ValueType{api.ValueTypeI32}, []api.ValueType{api.ValueTypeI32})
+			if err != nil {
+				callbackErr = err
+				return 0
+			}
+			results, err := fn.Call(ctx, api.EncodeU32(arg))
+			require.NoError(t, err)
+
+			// A funcref read from the table resolves to the same function.
+			ref, _ := table.Get(callback)
+			fn, err = im.LookupFunction(ref, []api.ValueType{api.ValueTypeI32}, []api.ValueType{api.ValueTypeI32})
+			require.NoError(t, err)
+			again, err := fn.Call(ctx, api.EncodeU32(arg))
+			require.NoError(t, err)
+			require.Equal(t, results, again)
+
+			// Funcrefs not produced by the runtime are rejected instead of dereferenced.
+			_, err = im.LookupFunction(ref+1, []api.ValueType{api.ValueTypeI32}, []api.ValueType{api.ValueTypeI32})
+			require.EqualError(t, err, fmt.Sprintf("funcref %#x was not produced by this runtime", ref+1))
+
+			_, callbackErr = im.LookupFunction(0, []api.ValueType{api.ValueTypeI32}, []api.ValueType{api.ValueTypeI32})
+			return api.DecodeU32(results[0])
+		}).Export("call").
+		Instantiate(testCtx)
+	require.NoError(t, err)
+
+	i32 := wasm.ValueTypeI32
+	bin := binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection: []wasm.FunctionType{
+			{Params: []wasm.ValueType{i32, i32}, Results: []wasm.ValueType{i32}},
+			{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32}},
+		},
+		ImportSection:   []wasm.Import{{Type: wasm.ExternTypeFunc, Module: "env", Name: "call", DescFunc: 0}},
+		FunctionSection: []wasm.Index{1, 1},
+		CodeSection: []wasm.Code{
+			// (func $double (param i32) (result i32) local.get 0 local.get 0 i32.add)
+			{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 0, wasm.OpcodeI32Add, wasm.OpcodeEnd}},
+			// (func (export "run") (param i32) (result i32) (call $call (i32.const 1) local.get 0))
+			{Body: []byte{wasm.OpcodeI32Const, 1, wasm.OpcodeLocalGet, 0, wasm.OpcodeCall, 0, wasm.OpcodeEnd}},
+		},
+		TableSection: []wasm.Table{{Min: 2, Type: wasm.RefTypeFuncref}},
+		ElementSection: []wasm.ElementSegment{
+			{
+				OffsetExpr: wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: []byte{1}},
+				Init:       []wasm.Index{1},
+				Type:       wasm.RefTypeFuncref,
+			},
+		},
+		ExportSection: []wasm.Export{{Name: "run", Type: api.ExternTypeFunc, Index: 2}},
+	})
+
+	module, err := r.Instantiate(testCtx, bin)
+	require.NoError(t, err)
+
+	results, err := module.ExportedFunction("run").Call(testCtx, 21)
+	require.NoError(t, err)
+	require.Equal(t, uint64(42), results[0])
+	require.EqualError(t, callbackErr, "invalid table access")
+}
+
+// TestModule_Table_Externref ensures host objects round-trip through an externref table.
+func TestModule_Table_Externref(t *testing.T) {
+	r := NewRuntime(testCtx)
+	defer r.Close(testCtx)
+
+	externref := wasm.ValueTypeExternref
+	bin := binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection: []wasm.FunctionType{
+			{Results: []wasm.ValueType{externref}},
+			{Params: []wasm.ValueType{externref}},
+		},
+		FunctionSection: []wasm.Index{0, 1},
+		CodeSection: []wasm.Code{
+			// (func (export "get") (result externref) (table.get 0 (i32.const 0)))
+			{Body: []byte{wasm.OpcodeI32Const, 0, wasm.OpcodeTableGet, 0, wasm.OpcodeEnd}},
+			// (func (export "set") (param externref) (table.set 0 (i32.const 0) (local.get 0)))
+			{Body: []byte{wasm.OpcodeI32Const, 0, wasm.OpcodeLocalGet, 0, wasm.OpcodeTableSet, 0, wasm.OpcodeEnd}},
+		},
+		TableSection: []wasm.Table{{Min: 1, Type: wasm.RefTypeExternref}},
+		ExportSection: []wasm.Export{
+			{Name: "externs", Type: api.ExternTypeTable, Index: 0},
+			{Name: "get", Type: api.ExternTypeFunc, Index: 0},
+			{Name: "set", Type: api.ExternTypeFunc, Index: 1},
+		},
+	})
+	module, err := r.Instantiate(testCtx, bin)
+	require.NoError(t, err)
+
+	table := module.ExportedTable("externs")
+	require.Equal(t, api.RefTypeExternref, table.Definition().Type())
+
+	obj := &struct{ name string }{name: "host object"}
+	require.True(t, table.Set(0, api.EncodeExternref(uintptr(unsafe.Pointer(obj)))))
+
+	ref, ok := table.Get(0)
+	require.True(t, ok)
+	require.Equal(t, uintptr(unsafe.Pointer(obj)), api.DecodeExternref(ref))
+
+	// The guest reads the same externref the host stored.
+	results, err := module.ExportedFunction("get").Call(testCtx)
+	require.NoError(t, err)
+	require.Equal(t, uintptr(unsafe.Pointer(obj)), api.DecodeExternref(results[0]))
+
+	// The host reads the same externref the guest stored.
+	other := &struct{ name string }{name: "other host object"}
+	_, err = module.ExportedFunction("set").Call(testCtx, api.EncodeExternref(uintptr(unsafe.Pointer(other))))
+	require.NoError(t, err)
+	ref, ok = table.Get(0)
+	require.True(t, ok)
+	require.Equal(t, uintptr(unsafe.Pointer(other)), api.DecodeExternref(ref))
+
+	_, err = table.Function(0, nil, nil)
+	require.EqualError(t, err, "invalid table access")
+}
+
+func TestRuntime_InstantiateModule_Snapshot(t *testing.T) {
+	r := NewRuntime(testCtx)
+	defer r.Close(testCtx)
+
+	increment := []byte{
+		wasm.OpcodeGlobalGet, 0, wasm.OpcodeI32Const, 1, wasm.OpcodeI32Add, wasm.OpcodeGlobalSet, 0,
+	}
+	start := append(append([]byte{}, increment...),
+		wasm.OpcodeI32Const, 0, wasm.OpcodeI32Const, 42, wasm.OpcodeI32Store8, 0, 0, wasm.OpcodeEnd)
+	max := uint32(2)
+	one := uint32(1)
+	bin := binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection:     []wasm.FunctionType{{}, {Results: []wasm.ValueType{wasm.ValueTypeI32}}},
+		FunctionSection: []wasm.Index{0, 0, 1, 1},
+		CodeSection: []wasm.Code{
+			{Body: start},
+			{Body: append(append([]byte{}, increment...), wasm.OpcodeEnd)},
+			{Body: []byte{wasm.OpcodeI32Const, 1, wasm.OpcodeEnd}},
+			{Body: []byte{wasm.OpcodeI32Const, 0, wasm.OpcodeCallIndirect, 1, 0, wasm.OpcodeEnd}},
+		},
+		MemorySection: []wasm.Memory{{Min: 1, Cap: 1, Max: max, IsMaxEncoded: true}},
+		GlobalSection: []wasm.Global{{
+			Type: wasm.GlobalType{ValType: wasm.ValueTypeI32, Mutable: true},
+			Init: wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: []byte{0}},
+		}},
+		TableSection: []wasm.Table{{Min: 1, Max: &one, Type: wasm.RefTypeFuncref}},
+		ElementSection: []wasm.ElementSegment{
+			{
+				OffsetExpr: wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: []byte{0}},
+				Init:       []wasm.Index{2},
+				Type:       wasm.RefTypeFuncref,
+			},
+		},
+		StartSection: &[]wasm.Index{0}[0],
+		ExportSection: []wasm.Export{
+			{Name: "init", Type: api.ExternTypeFunc, Index: 1},
+			{Name: "call", Type: api.ExternTypeFunc, Index: 3},
+			{Name: "counter", Type: api.ExternTypeGlobal, Index: 0},
+			{Name: "table", Type: api.ExternTypeTable, Index: 0},
+		},
+	})
+
+	compiled, err := r.CompileModule(testCtx, bin)
+	require.NoError(t, err)
+
+	config := NewModuleConfig().WithStartFunctions("init")
+	module, err := r.InstantiateModule(testCtx, compiled, config.WithName("original"))
+	require.NoError(t, err)
+
+	// Both the start section and the start function ran.
+	counter := module.ExportedGlobal("counter").(api.MutableGlobal)
+	require.Equal(t, uint64(2), counter.Get())
+
+	// Change the state, so that it differs from what instantiation produces.
+	counter.Set(10)
+	mem := module.Memory()
+	_, ok := mem.Grow(1)
+	require.True(t, ok)
+	require.True(t, mem.WriteByte(wasm.MemoryPageSize, 7))
+	results, err := module.ExportedFunction("call").Call(testCtx)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), results[0])
+	require.True(t, module.ExportedTable("table").Set(0, 0))
+
+	snap, err := experimental.Snapshot(module)
+	require.NoError(t, err)
+
+	// Round-trip the snapshot through its serialized form.
+	b, err := snap.MarshalBinary()
+	require.NoError(t, err)
+	var restored experimental.ModuleSnapshot
+	require.NoError(t, restored.UnmarshalBinary(b))
+
+	// Start functions would increment the counter, so it shows they didn't run.
+	ctx := experimental.WithSnapshot(testCtx, &restored)
+	clone, err := r.InstantiateModule(ctx, compiled, config.WithName("clone"))
+	require.NoError(t, err)
+
+	require.Equal(t, uint64(10), clone.ExportedGlobal("counter").Get())
+	require.Equal(t, uint32(2*wasm.MemoryPageSize), clone.Memory().Size())
+	b0, _ := clone.Memory().ReadByte(0)
+	require.Equal(t, byte(42), b0)
+	b1, _ := clone.Memory().ReadByte(wasm.MemoryPageSize)
+	require.Equal(t, byte(7), b1)
+
+	// The table was restored, rather than initialized by the element segment.
+	_, err = clone.ExportedFunction("call").Call(testCtx)
+	require.ErrorIs(t, err, wasmruntime.ErrRuntimeInvalidTableAccess)
+	ref, ok := clone.ExportedTable("table").Get(0)
+	require.True(t, ok)
+	require.Equal(t, uint64(0), ref)
+
+	// Instances don't share state.
+	counter.Set(11)
+	require.Equal(t, uint64(10), clone.ExportedGlobal("counter").Get())
+
+	t.Run("different module", func(t *testing.T) {
+		other, err := r.CompileModule(testCtx, binaryNamedZero)
+		require.NoError(t, err)
+		_, err = r.InstantiateModule(ctx, other, NewModuleConfig())
+		require.EqualError(t, err, "restore snapshot: snapshot was taken from a different module")
+	})
+
+	t.Run("closed module", func(t *testing.T) {
+		require.NoError(t, clone.Close(testCtx))
+		_, err := experimental.Snapshot(clone)
+		require.EqualError(t, err, "module closed with exit_code(0)")
+	})
+}
+
 // TestModule_Global only covers a couple cases to avoid duplication of internal/wasm/global_test.go
 func TestModule_Global(t *testing.T) {
 	globalVal := int64(100) // intentionally a value that differs in signed vs unsigned encoding
@@ -301,7 +652,7 @@ func TestModule_Global(t *testing.T) {
 
 			code := &compiledModule{module: tc.module}
 
-			err := r.store.Engine.CompileModule(testCtx, code.module, nil, false)
+			err := r.store.Engine.CompileModule(testCtx, code.module, nil, false, false, false)
 			require.NoError(t, err)
 
 			// Instantiate the module and get the export of the above global
@@ -373,7 +724,7 @@ func TestRuntime_Instantiate_DoesntEnforce_Start(t *testing.T) {
 	defer r.Close(testCtx)
 
 	binary := binaryencoding.EncodeModule(&wasm.Module{
-		MemorySection: &wasm.Memory{Min: 1},
+		MemorySection: []wasm.Memory{{Min: 1}},
 		ExportSection: []wasm.Export{{Name: "memory", Type: wasm.ExternTypeMemory, Index: 0}},
 	})
 
@@ -467,6 +818,83 @@ func TestRuntime_InstantiateModule_WithName(t *testing.T) {
 	require.Nil(t, ret)
 }
 
+func TestRuntime_Modules(t *testing.T) {
+	r := NewRuntime(testCtx)
+	defer r.Close(testCtx)
+	require.Nil(t, r.Modules())
+
+	host, err := r.NewHostModuleBuilder("host").Instantiate(testCtx)
+	require.NoError(t, err)
+
+	base, err := r.CompileModule(testCtx, binaryNamedZero)
+	require.NoError(t, err)
+	m1, err := r.InstantiateModule(testCtx, base, NewModuleConfig().WithName("1"))
+	require.NoError(t, err)
+	anonymous, err := r.InstantiateModule(testCtx, base, NewModuleConfig().WithName(""))
+	require.NoError(t, err)
+
+	// Modules are in instantiation order, including host and anonymous ones.
+	require.Equal(t, []api.Module{host, m1, anonymous}, r.Modules())
+
+	// Closed modules are no longer listed.
+	require.NoError(t, m1.Close(testCtx))
+	require.Equal(t, []api.Module{host, anonymous}, r.Modules())
+}
+
+func TestRuntime_InstantiateModule_WithImportResolver(t *testing.T) {
+	r := NewRuntime(testCtx)
+	defer r.Close(testCtx)
+
+	// Each tenant has its own "env" module.
+	for i, tenant := range []string{"a", "b"} {
+		tenantID := uint32(i)
+		_, err := r.NewHostModuleBuilder("env-" + tenant).
+			NewFunctionBuilder().WithFunc(func() uint32 { return tenantID }).Export("tenant").
+			Instantiate(testCtx)
+		require.NoError(t, err)
+	}
+
+	guest, err := r.CompileModule(testCtx, binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection:     []wasm.FunctionType{{Results: []wasm.ValueType{wasm.ValueTypeI32}}},
+		ImportSection:   []wasm.Import{{Module: "env", Name: "tenant", Type: wasm.ExternTypeFunc, DescFunc: 0}},
+		FunctionSection: []wasm.Index{0},
+		CodeSection:     []wasm.Code{{Body: []byte{wasm.OpcodeCall, 0, wasm.OpcodeEnd}}},
+		ExportSection:   []wasm.Export{{Name: "tenant", Type: wasm.ExternTypeFunc, Index: 1}},
+	}))
+	require.NoError(t, err)
+
+	for i, tenant := range []string{"a", "b"} {
+		tenant := tenant
+		mod, err := r.InstantiateModule(testCtx, guest, NewModuleConfig().WithName(tenant).
+			WithImportResolver(func(moduleName, name string, kind api.ExternType) api.Module {
+				require.Equal(t, "env", moduleName)
+				require.Equal(t, "tenant", name)
+				require.Equal(t, api.ExternTypeFunc, kind)
+				return r.Module("env-" + tenant)
+			}))
+		require.NoError(t, err)
+
+		res, err := mod.ExportedFunction("tenant").Call(testCtx)
+		require.NoError(t, err)
+		require.Equal(t, uint64(i), res[0])
+	}
+
+	// Without a resolver, "env" is looked up by name.
+	_, err = r.InstantiateModule(testCtx, guest, NewModuleConfig().WithName("c"))
+	require.EqualError(t, err, "module[env] not instantiated")
+
+	// The resolved module must be in the same runtime.
+	other := NewRuntime(testCtx)
+	defer other.Close(testCtx)
+	otherEnv, err := other.NewHostModuleBuilder("env").
+		NewFunctionBuilder().WithFunc(func() uint32 { return 0 }).Export("tenant").
+		Instantiate(testCtx)
+	require.NoError(t, err)
+	_, err = r.InstantiateModule(testCtx, guest, NewModuleConfig().WithName("d").
+		WithImportResolver(func(string, string, api.ExternType) api.Module { return otherEnv }))
+	require.EqualError(t, err, "import func[env.tenant]: resolved module is not instantiated in the same runtime")
+}
+
 func TestRuntime_InstantiateModule_ExitError(t *testing.T) {
 	r := NewRuntime(testCtx)
 	defer r.Close(testCtx)
@@ -780,7 +1208,7 @@ type mockEngine struct {
 }
 
 // CompileModule implements the same method as documented on wasm.Engine.
-func (e *mockEngine) CompileModule(_ context.Context, module *wasm.Module, _ []experimental.FunctionListener, _ bool) error {
+func (e *mockEngine) CompileModule(_ context.Context, module *wasm.Module, _ []experimental.FunctionListener, _, _, _ bool) error {
 	e.cachedModules[module] = struct{}{}
 	return nil
 }